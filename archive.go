@@ -0,0 +1,300 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/compressutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// archivedVersionPrefix is the prefix where the data of archived versions is
+// stored. The "archive/" prefix holds the archived key policy.
+const archivedVersionPrefix = "archived-versions/"
+
+// lastReadInterval is how often reads of a version update its last read
+// time. Recording every read would turn each read into a write.
+const lastReadInterval = 24 * time.Hour
+
+// validateArchiveCompression returns an error if compression is not a
+// supported compression type for archived version data. An empty type
+// stores the data uncompressed.
+func validateArchiveCompression(compression string) error {
+	switch compression {
+	case "", compressutil.CompressionTypeGzip, compressutil.CompressionTypeSnappy, compressutil.CompressionTypeLZ4:
+		return nil
+	default:
+		return fmt.Errorf("unsupported archive_compression %q, must be %q, %q or %q", compression,
+			compressutil.CompressionTypeGzip, compressutil.CompressionTypeSnappy, compressutil.CompressionTypeLZ4)
+	}
+}
+
+// getArchivedVersionKey returns the storage key of the archived data of a
+// version, which mirrors the storage key of its data.
+func (b *versionedKVBackend) getArchivedVersionKey(ctx context.Context, key string, version uint64, s logical.Storage) (string, error) {
+	salt, err := b.Salt(ctx, s)
+	if err != nil {
+		return "", err
+	}
+
+	salted := salt.SaltID(fmt.Sprintf("%s|%d", key, version))
+
+	return path.Join(b.storagePrefix, archivedVersionPrefix, salted[0:3], salted[3:]), nil
+}
+
+// getVersionEntry returns the stored entry of a version, decompressed if it
+// was archived, along with its storage key. A nil entry is returned if the
+// version has no stored entry.
+func (b *versionedKVBackend) getVersionEntry(ctx context.Context, s logical.Storage, key string, version uint64) (*logical.StorageEntry, string, error) {
+	versionKey, err := b.getVersionKey(ctx, key, version, s)
+	if err != nil {
+		return nil, "", err
+	}
+
+	raw, err := s.Get(ctx, versionKey)
+	if err != nil || raw != nil {
+		return raw, versionKey, err
+	}
+
+	archivedKey, err := b.getArchivedVersionKey(ctx, key, version, s)
+	if err != nil {
+		return nil, "", err
+	}
+
+	raw, err = s.Get(ctx, archivedKey)
+	if err != nil || raw == nil {
+		return nil, "", err
+	}
+
+	value, err := decompressArchivedValue(raw.Value)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decompress archived version data: %w", err)
+	}
+
+	return &logical.StorageEntry{Key: archivedKey, Value: value}, archivedKey, nil
+}
+
+// decompressArchivedValue returns the archived value decompressed, or as is
+// if it was archived without compression.
+func decompressArchivedValue(value []byte) ([]byte, error) {
+	decompressed, notCompressed, err := compressutil.Decompress(value)
+	if err != nil {
+		return nil, err
+	}
+	if notCompressed {
+		return value, nil
+	}
+	return decompressed, nil
+}
+
+// lastAccessTime returns when the version was last read, or created if it was
+// not read since last read times are recorded.
+func (vm *VersionMetadata) lastAccessTime() time.Time {
+	t := vm.GetLastReadTime()
+	if t == nil {
+		t = vm.GetCreatedTime()
+	}
+
+	ts, err := ptypes.Timestamp(t)
+	if err != nil {
+		return time.Time{}
+	}
+	return ts
+}
+
+// archivableVersions returns the versions of the key that were not accessed
+// within archiveAfter of now and are not archived yet.
+func (k *KeyMetadata) archivableVersions(archiveAfter time.Duration, now time.Time) []uint64 {
+	if archiveAfter <= 0 {
+		return nil
+	}
+
+	var versions []uint64
+	for verNum, vm := range k.Versions {
+		if vm.Archived || vm.Destroyed {
+			continue
+		}
+		if !vm.lastAccessTime().Add(archiveAfter).After(now) {
+			versions = append(versions, verNum)
+		}
+	}
+	return versions
+}
+
+// archiveVersionData copies the stored entry of a version to the archive,
+// compressed with the configured compression. It returns false if the
+// version has no stored entry to archive. The entry is not deleted, so the
+// data stays readable until the key metadata records the version as
+// archived.
+func (b *versionedKVBackend) archiveVersionData(ctx context.Context, s logical.Storage, config *Configuration, key string, version uint64) (bool, error) {
+	versionKey, err := b.getVersionKey(ctx, key, version, s)
+	if err != nil {
+		return false, err
+	}
+
+	raw, err := s.Get(ctx, versionKey)
+	if err != nil || raw == nil {
+		return false, err
+	}
+
+	value := raw.Value
+	if config.ArchiveCompression != "" {
+		value, err = compressutil.Compress(value, &compressutil.CompressionConfig{
+			Type: config.ArchiveCompression,
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	archivedKey, err := b.getArchivedVersionKey(ctx, key, version, s)
+	if err != nil {
+		return false, err
+	}
+
+	return true, s.Put(ctx, &logical.StorageEntry{
+		Key:   archivedKey,
+		Value: value,
+	})
+}
+
+// rehydrateVersionData moves the data of an archived version back to its
+// storage key. The archived entry is deleted by the caller once the key
+// metadata no longer records the version as archived.
+func (b *versionedKVBackend) rehydrateVersionData(ctx context.Context, s logical.Storage, key string, version uint64) (string, error) {
+	entry, storageKey, err := b.getVersionEntry(ctx, s, key, version)
+	if err != nil || entry == nil {
+		return "", err
+	}
+
+	versionKey, err := b.getVersionKey(ctx, key, version, s)
+	if err != nil {
+		return "", err
+	}
+	if storageKey == versionKey {
+		return "", nil
+	}
+
+	return storageKey, s.Put(ctx, &logical.StorageEntry{
+		Key:   versionKey,
+		Value: entry.Value,
+	})
+}
+
+// versionReadsContextKey is the context key of the versionReads of a
+// request.
+type versionReadsContextKey struct{}
+
+// versionRead is a read of the data of a version of a key.
+type versionRead struct {
+	key     string
+	version uint64
+}
+
+// versionReads holds the reads of version data by a request, which are
+// recorded once the request succeeded.
+type versionReads struct {
+	reads []versionRead
+}
+
+// queueVersionRead records that the request read the data of a version of
+// the key, so that it is recorded by recordVersionReads once the request
+// succeeded.
+func queueVersionRead(ctx context.Context, key string, version uint64) {
+	if r, ok := ctx.Value(versionReadsContextKey{}).(*versionReads); ok {
+		r.reads = append(r.reads, versionRead{key: key, version: version})
+	}
+}
+
+// recordVersionReads wraps a handler returning version data. Reads of
+// version data noted by the handler are counted, see countVersionRead. If
+// archiving is enabled, they also update the last read time of the version at
+// most once per lastReadInterval, and move the data of archived versions back
+// out of the archive. Nothing is recorded for reads of the healthcheck key,
+// or while the read_tracking feature is disabled. Failures are logged, as the
+// read itself succeeded.
+func (b *versionedKVBackend) recordVersionReads(op framework.OperationFunc) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		reads := &versionReads{}
+		resp, err := op(context.WithValue(ctx, versionReadsContextKey{}, reads), req, data)
+		if err != nil || resp == nil || resp.IsError() || len(reads.reads) == 0 {
+			return resp, err
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			requestLogger(ctx, b.Logger()).Warn("failed to record version reads", "error", err)
+			return resp, nil
+		}
+		if !config.featureEnabled(featureReadTracking) {
+			return resp, nil
+		}
+
+		now := b.now()
+		for _, r := range reads.reads {
+			if config.isHealthcheckPath(r.key) {
+				continue
+			}
+
+			b.countVersionRead(r.key, r.version, now)
+
+			if err := b.recordVersionRead(ctx, req.Storage, config, r.key, r.version, now); err != nil && err != logical.ErrReadOnly {
+				requestLogger(ctx, b.Logger()).Warn("failed to record version read", "key", r.key, "version", r.version, "error", err)
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+// recordVersionRead updates the last read time of a version and rehydrates
+// it if it is archived.
+func (b *versionedKVBackend) recordVersionRead(ctx context.Context, s logical.Storage, config *Configuration, key string, version uint64, now time.Time) error {
+	if durationOrZero(config.GetArchiveAfter()) <= 0 {
+		return nil
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil || meta == nil {
+		return err
+	}
+	vm := meta.Versions[version]
+	if vm == nil || (!vm.Archived && vm.lastAccessTime().Add(lastReadInterval).After(now)) {
+		return nil
+	}
+
+	var archivedKey string
+	if vm.Archived {
+		archivedKey, err = b.rehydrateVersionData(ctx, s, key, version)
+		if err != nil {
+			return err
+		}
+		vm.Archived = false
+	}
+
+	vm.LastReadTime, err = ptypes.TimestampProto(now)
+	if err != nil {
+		return err
+	}
+
+	if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+		return err
+	}
+
+	if archivedKey != "" {
+		return s.Delete(ctx, archivedKey)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Archive(t *testing.T) {
+	for _, compression := range []string{"", "gzip", "snappy", "lz4"} {
+		t.Run("compression="+compression, func(t *testing.T) {
+			testVersionedKVArchive(t, compression)
+		})
+	}
+}
+
+func testVersionedKVArchive(t *testing.T, compression string) {
+	b, storage, events := getBackendWithEvents(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"expiry_scan_interval": "1m",
+				"archive_after":        "720h",
+				"archive_compression":  compression,
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	versionKey, err := kv.getVersionKey(ctx, "foo", 1, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archivedKey, err := kv.getArchivedVersionKey(ctx, "foo", 1, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first scan only establishes the start of the window
+	start := time.Now()
+	if err := kv.expiryScan(ctx, storage, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.expiryScan(ctx, storage, start.Add(31*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Versions[1].Archived {
+		t.Fatalf("expected version to be archived, meta: %#v", meta)
+	}
+	if entry, err := storage.Get(ctx, versionKey); err != nil || entry != nil {
+		t.Fatalf("expected version data to be moved, err: %s, entry %#v", err, entry)
+	}
+	if entry, err := storage.Get(ctx, archivedKey); err != nil || entry == nil {
+		t.Fatalf("expected archived version data, err: %s, entry %#v", err, entry)
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if archived := resp.Data["versions"].(map[string]interface{})["1"].(map[string]interface{})["archived"]; archived != true {
+		t.Fatalf("expected the version to be reported as archived, got %v", archived)
+	}
+
+	// Reading the version rehydrates it
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("unexpected data: %#v", resp.Data["data"])
+	}
+
+	meta, err = kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Versions[1].Archived || meta.Versions[1].LastReadTime == nil {
+		t.Fatalf("expected version to be rehydrated with its read recorded, meta: %#v", meta)
+	}
+	if entry, err := storage.Get(ctx, versionKey); err != nil || entry == nil {
+		t.Fatalf("expected rehydrated version data, err: %s, entry %#v", err, entry)
+	}
+	if entry, err := storage.Get(ctx, archivedKey); err != nil || entry != nil {
+		t.Fatalf("expected archived version data to be deleted, err: %s, entry %#v", err, entry)
+	}
+
+	// Deleting the metadata deletes archived version data
+	if _, err := kv.archiveVersionData(ctx, storage, &Configuration{ArchiveCompression: compression}, "foo", 1); err != nil {
+		t.Fatal(err)
+	}
+	req = &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata DeleteOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	for _, k := range []string{versionKey, archivedKey} {
+		if entry, err := storage.Get(ctx, k); err != nil || entry != nil {
+			t.Fatalf("expected %s to be deleted, err: %s, entry %#v", k, err, entry)
+		}
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", "config", "config"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/archive", "data/foo", ""},
+		{"kv-v2/metadata-delete", "metadata/foo", ""},
+	})
+}
+
+func TestVersionedKV_Archive_InvalidConfig(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for _, data := range []map[string]interface{}{
+		{"archive_after": "-1h"},
+		{"archive_compression": "zstd"},
+	} {
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   storage,
+			Data:      data,
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected config %v to be rejected, err: %s, resp %#v", data, err, resp)
+		}
+	}
+}
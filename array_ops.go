@@ -0,0 +1,170 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/mitchellh/mapstructure"
+)
+
+// The operations of the array_ops patch option
+const (
+	arrayOpAppend      = "append"
+	arrayOpRemove      = "remove"
+	arrayOpRemoveIndex = "remove_index"
+)
+
+// arrayOp is an operation on an array field of the data of a key, applied by
+// a patch after the JSON merge patch of its data.
+type arrayOp struct {
+	// Op is the operation, one of append, remove or remove_index.
+	Op string `mapstructure:"op"`
+
+	// Field is the path of the array field, its segments separated by
+	// slashes to reach the fields of nested objects.
+	Field string `mapstructure:"field"`
+
+	// Value is the value appended, or removed from the array.
+	Value interface{} `mapstructure:"value"`
+
+	// Index is the index of the element removed by remove_index.
+	Index *int `mapstructure:"index"`
+}
+
+// parseArrayOps parses the array_ops option of a patch.
+func parseArrayOps(raw interface{}) ([]*arrayOp, error) {
+	rawOps, ok := raw.([]interface{})
+	if !ok {
+		return nil, errors.New("error parsing array_ops parameter: must be a list of operations")
+	}
+
+	ops := make([]*arrayOp, 0, len(rawOps))
+	for i, rawOp := range rawOps {
+		op := &arrayOp{}
+		if err := mapstructure.WeakDecode(rawOp, op); err != nil {
+			return nil, fmt.Errorf("error parsing array operation %d: %w", i, err)
+		}
+
+		switch {
+		case op.Field == "":
+			return nil, fmt.Errorf("array operation %d: missing field", i)
+		case op.Op == arrayOpAppend || op.Op == arrayOpRemove:
+			if op.Value == nil {
+				return nil, fmt.Errorf("array operation %d: %s requires a value", i, op.Op)
+			}
+		case op.Op == arrayOpRemoveIndex:
+			if op.Index == nil || *op.Index < 0 {
+				return nil, fmt.Errorf("array operation %d: %s requires a non-negative index", i, op.Op)
+			}
+		default:
+			return nil, fmt.Errorf("array operation %d: unsupported operation %q, must be %q, %q or %q", i, op.Op, arrayOpAppend, arrayOpRemove, arrayOpRemoveIndex)
+		}
+
+		// Values are compared to the stored data, which decodes numbers as
+		// json.Number
+		if op.Value != nil {
+			value, err := normalizeJSONValue(op.Value)
+			if err != nil {
+				return nil, fmt.Errorf("array operation %d: %w", i, err)
+			}
+			op.Value = value
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// applyArrayOps applies the array operations in order to the encoded data of
+// a key and returns the encoded result.
+func applyArrayOps(encoded []byte, ops []*arrayOp) ([]byte, error) {
+	if len(ops) == 0 {
+		return encoded, nil
+	}
+
+	data := map[string]interface{}{}
+	if err := jsonutil.DecodeJSON(encoded, &data); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		if err := op.apply(data); err != nil {
+			return nil, fmt.Errorf("array operation %d on field %q: %w", i, op.Field, err)
+		}
+	}
+
+	return json.Marshal(data)
+}
+
+// apply applies the operation to the data. Appending to a missing field
+// creates the array, and removing a value the array does not contain is a
+// no-op, so that the operations can be retried.
+func (op *arrayOp) apply(data map[string]interface{}) error {
+	segments := strings.Split(op.Field, "/")
+	parent := data
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := parent[segment].(map[string]interface{})
+		if !ok {
+			if _, exists := parent[segment]; exists || op.Op != arrayOpAppend {
+				return fmt.Errorf("%q is not an object", segment)
+			}
+			next = map[string]interface{}{}
+			parent[segment] = next
+		}
+		parent = next
+	}
+	name := segments[len(segments)-1]
+
+	var array []interface{}
+	if existing, ok := parent[name]; ok {
+		if array, ok = existing.([]interface{}); !ok {
+			return errors.New("the field is not an array")
+		}
+	} else if op.Op != arrayOpAppend {
+		return errors.New("the field does not exist")
+	}
+
+	switch op.Op {
+	case arrayOpAppend:
+		array = append(array, op.Value)
+	case arrayOpRemove:
+		remaining := make([]interface{}, 0, len(array))
+		for _, element := range array {
+			if !reflect.DeepEqual(element, op.Value) {
+				remaining = append(remaining, element)
+			}
+		}
+		array = remaining
+	case arrayOpRemoveIndex:
+		if *op.Index >= len(array) {
+			return fmt.Errorf("index %d is out of range for an array of %d elements", *op.Index, len(array))
+		}
+		array = append(array[:*op.Index:*op.Index], array[*op.Index+1:]...)
+	}
+
+	parent[name] = array
+	return nil
+}
+
+// normalizeJSONValue returns the value as decoded from its JSON encoding,
+// with numbers as json.Number.
+func normalizeJSONValue(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized interface{}
+	if err := jsonutil.DecodeJSON(encoded, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
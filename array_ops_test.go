@@ -0,0 +1,140 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestParseArrayOps(t *testing.T) {
+	ops, err := parseArrayOps([]interface{}{
+		map[string]interface{}{"op": "append", "field": "ips", "value": 1},
+		map[string]interface{}{"op": "remove_index", "field": "ips", "index": json.Number("0")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[0].Value != json.Number("1") || *ops[1].Index != 0 {
+		t.Fatalf("unexpected operations %#v", ops)
+	}
+
+	for _, raw := range []interface{}{
+		"append",
+		[]interface{}{map[string]interface{}{"op": "append", "value": "a"}},
+		[]interface{}{map[string]interface{}{"op": "append", "field": "ips"}},
+		[]interface{}{map[string]interface{}{"op": "remove_index", "field": "ips", "index": -1}},
+		[]interface{}{map[string]interface{}{"op": "insert", "field": "ips", "value": "a"}},
+	} {
+		if _, err := parseArrayOps(raw); err == nil {
+			t.Fatalf("expected array_ops %#v to be rejected", raw)
+		}
+	}
+}
+
+func TestVersionedKV_Patch_ArrayOps(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"ips":  []interface{}{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+				"ssh":  map[string]interface{}{"keys": []interface{}{"a", "b"}},
+				"name": "foo",
+			},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("write failed, err: %s, resp %#v", err, resp)
+	}
+
+	patch := func(data map[string]interface{}, ops ...map[string]interface{}) (*logical.Response, error) {
+		rawOps := make([]interface{}, len(ops))
+		for i, op := range ops {
+			rawOps[i] = op
+		}
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.PatchOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data":    data,
+				"options": map[string]interface{}{"array_ops": rawOps},
+			},
+		})
+	}
+
+	resp, err = patch(map[string]interface{}{"name": "bar"},
+		map[string]interface{}{"op": "append", "field": "ips", "value": "10.0.0.4"},
+		map[string]interface{}{"op": "remove", "field": "ips", "value": "10.0.0.1"},
+		map[string]interface{}{"op": "remove", "field": "ips", "value": "10.0.0.9"},
+		map[string]interface{}{"op": "remove_index", "field": "ssh/keys", "index": 0},
+		map[string]interface{}{"op": "append", "field": "ports", "value": 22},
+	)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("patch failed, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read failed, err: %s, resp %#v", err, resp)
+	}
+	expected := map[string]interface{}{
+		"ips":   []interface{}{"10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		"ssh":   map[string]interface{}{"keys": []interface{}{"b"}},
+		"ports": []interface{}{json.Number("22")},
+		"name":  "bar",
+	}
+	if !reflect.DeepEqual(resp.Data["data"], expected) {
+		t.Fatalf("unexpected data after patch: %#v", resp.Data["data"])
+	}
+
+	// Operations on fields that are not arrays fail without writing a version
+	for _, op := range []map[string]interface{}{
+		{"op": "append", "field": "name", "value": "a"},
+		{"op": "remove", "field": "missing", "value": "a"},
+		{"op": "remove_index", "field": "ips", "index": 3},
+	} {
+		resp, err = patch(map[string]interface{}{}, op)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected patch with %v to fail, err: %s, resp %#v", op, err, resp)
+		}
+	}
+
+	// The option is only accepted on patches
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"ips": []interface{}{}},
+			"options": map[string]interface{}{"array_ops": []interface{}{
+				map[string]interface{}{"op": "append", "field": "ips", "value": "a"},
+			}},
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected write with array_ops to fail, err: %s, resp %#v", err, resp)
+	}
+
+	meta, err := b.(*versionedKVBackend).getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.CurrentVersion != 2 {
+		t.Fatalf("expected failed requests not to write versions, current version %d", meta.CurrentVersion)
+	}
+}
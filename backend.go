@@ -10,12 +10,19 @@ import (
 	"net/http"
 	"path"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
 	"github.com/hashicorp/vault/sdk/helper/keysutil"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/helper/salt"
@@ -67,10 +74,29 @@ type versionedKVBackend struct {
 	// through the backend config.
 	storagePrefix string
 
+	// metadataOnly is set by the metadata_only mount option, in which case
+	// the secret data of the mount cannot be read.
+	metadataOnly bool
+
 	// upgrading is an atomic value denoting if the backend is in the process of
 	// upgrading its data.
 	upgrading *uint32
 
+	// upgradeKeysTotal and upgradeKeysProcessed are atomic values counting
+	// the keys of the upgrade run by this instance.
+	upgradeKeysTotal     *uint64
+	upgradeKeysProcessed *uint64
+
+	// upgradeBatchSize and upgradeRateLimit are set by the upgrade mount
+	// options, see upgrade_options.go.
+	upgradeBatchSize int
+	upgradeRateLimit float64
+
+	// sealWrapMismatch is an atomic value denoting if stored version data
+	// failed to decode, which happens when seal wrapped data is read by a
+	// server without seal wrap support.
+	sealWrapMismatch *uint32
+
 	// globalConfig is a cached value for fast lookup
 	globalConfig     *Configuration
 	globalConfigLock *sync.RWMutex
@@ -78,6 +104,56 @@ type versionedKVBackend struct {
 	// upgradeCancelFunc is used to be able to shut down the upgrade checking
 	// goroutine from cleanup
 	upgradeCancelFunc context.CancelFunc
+
+	// upgradeOwnerID identifies this instance in the upgrade canary so that
+	// concurrent upgrades by other instances can be detected.
+	upgradeOwnerID string
+
+	// lastExpiryScan is the time the periodic expiry scan last completed,
+	// protected by expiryScanLock.
+	lastExpiryScan time.Time
+	expiryScanLock sync.Mutex
+
+	// lastIntegrityCheck is the time the periodic integrity check last
+	// completed, protected by integrityCheckLock.
+	lastIntegrityCheck time.Time
+	integrityCheckLock sync.Mutex
+
+	// lastTidy is the time the periodic tidy last completed, protected by
+	// tidyLock. tidyLock is also held by the tidy endpoint, so that a
+	// single tidy runs at a time.
+	lastTidy time.Time
+	tidyLock sync.Mutex
+
+	// pendingReads holds the reads of version data counted since they were
+	// last persisted by flushReadStats, protected by pendingReadsLock.
+	pendingReads     map[string]map[uint64]*pendingRead
+	pendingReadsLock sync.Mutex
+
+	// jobCancels holds the cancel functions of the jobs running on this
+	// instance, protected by jobsLock.
+	jobCancels map[string]context.CancelFunc
+	jobsLock   sync.Mutex
+
+	// concurrencyLimiters holds the limiter of each prefix of the
+	// concurrency_limits of the config, protected by concurrencyLock.
+	concurrencyLimiters map[string]*concurrencyLimiter
+	concurrencyLock     sync.Mutex
+
+	// missingKeys caches the keys found to have no metadata, if the
+	// missing_key_cache_ttl of the config is set.
+	missingKeys *missingKeyCache
+
+	// load tracks the background tasks and their storage operations, see
+	// backpressure.go.
+	load backgroundLoad
+
+	// usage tracks the storage used by the version data of the mount, see
+	// storage_emergency.go.
+	usage storageUsage
+
+	// clock is the source of the current time, see clock.go.
+	clock clock
 }
 
 // Factory will return a logical backend of type versionedKVBackend or
@@ -105,25 +181,71 @@ func VersionedKVFactory(ctx context.Context, conf *logical.BackendConfig) (logic
 	upgradeCtx, upgradeCancelFunc := context.WithCancel(ctx)
 
 	b := &versionedKVBackend{
-		upgrading:         new(uint32),
-		globalConfigLock:  new(sync.RWMutex),
-		upgradeCancelFunc: upgradeCancelFunc,
+		upgrading:            new(uint32),
+		upgradeKeysTotal:     new(uint64),
+		upgradeKeysProcessed: new(uint64),
+		sealWrapMismatch:     new(uint32),
+		globalConfigLock:     new(sync.RWMutex),
+		upgradeCancelFunc:    upgradeCancelFunc,
+		jobCancels:           map[string]context.CancelFunc{},
+		pendingReads:         map[string]map[uint64]*pendingRead{},
+		missingKeys:          newMissingKeyCache(),
+		clock:                systemClock{},
+
+		concurrencyLimiters: map[string]*concurrencyLimiter{},
 	}
 	if conf.BackendUUID == "" {
 		return nil, errors.New("could not initialize versioned K/V Store, no UUID was provided")
 	}
 	b.storagePrefix = conf.BackendUUID
+	b.metadataOnly = conf.Config[mountOptionMetadataOnly] == "true"
+
+	batchSize, rateLimit, err := parseUpgradeOptions(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+	b.upgradeBatchSize, b.upgradeRateLimit = batchSize, rateLimit
+
+	ownerID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	b.upgradeOwnerID = ownerID
 
 	b.Backend = &framework.Backend{
-		BackendType: logical.TypeLogical,
-		Help:        backendHelp,
-		Invalidate:  b.Invalidate,
+		BackendType:  logical.TypeLogical,
+		Help:         backendHelp,
+		Invalidate:   b.Invalidate,
+		PeriodicFunc: b.periodicFunc,
 
 		PathsSpecial: &logical.Paths{
+			Root: []string{
+				"repair/*",
+				"debug/*",
+				"tidy",
+				"export/*",
+				"reports/*",
+				"search/*",
+				"migrate/*",
+				"convert/*",
+				"copy/*",
+				"move/*",
+				"transaction",
+				"transaction/",
+				"by-id",
+				"by-id/",
+			},
+
 			SealWrapStorage: []string{
 				// Seal wrap the versioned data
 				path.Join(b.storagePrefix, versionPrefix) + "/",
 
+				// Seal wrap the archived versioned data
+				path.Join(b.storagePrefix, archivedVersionPrefix) + "/",
+
+				// Seal wrap the versioned data of soft deleted keys
+				path.Join(b.storagePrefix, trashDataPrefix) + "/",
+
 				// Seal wrap the key policy
 				path.Join(b.storagePrefix, "policy") + "/",
 
@@ -135,12 +257,41 @@ func VersionedKVFactory(ctx context.Context, conf *logical.BackendConfig) (logic
 		Paths: framework.PathAppend(
 			[]*framework.Path{
 				pathConfig(b),
+				pathConfigFeatures(b),
 				pathData(b),
 				pathMetadata(b),
 				pathDestroy(b),
 				pathSubkeys(b),
+				pathDiff(b),
+				pathV1Data(b),
+				pathRestore(b),
+				pathImport(b),
+				pathExport(b),
+				pathRollback(b),
+				pathProvision(b),
+				pathTransaction(b),
+				pathBatchExists(b),
+				pathTidy(b),
+				pathCapabilitiesProbe(b),
+				pathMirrorStatus(b),
+				pathRepairRebuildIndex(b),
+				pathUpgradeStatus(b),
+				pathDebugGenerate(b),
+				pathChangelog(b),
+				pathReportsOldestVersions(b),
+				pathReportsSchemaVersions(b),
+				pathSearchMetadata(b),
+				pathMigrateSchema(b),
+				pathBackgroundLoad(b),
+				pathConvertV1(b),
+				pathMetadataUndelete(b),
 			},
 			pathsDelete(b),
+			pathsCopy(b),
+			pathApproval(b),
+			pathJobs(b),
+			pathTemplates(b),
+			pathsByID(b),
 
 			// Make sure this stays at the end so that the valid paths are
 			// processed first.
@@ -170,6 +321,23 @@ func VersionedKVFactory(ctx context.Context, conf *logical.BackendConfig) (logic
 
 func pathInvalid(b *versionedKVBackend) []*framework.Path {
 	handler := func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		// Keys under the v1_prefixes of the config are served as KV v1
+		// entries at their path
+		if req.Storage != nil {
+			config, err := b.config(ctx, req.Storage)
+			if err != nil {
+				return nil, err
+			}
+
+			path := req.Path
+			if req.Operation == logical.ListOperation && !strings.HasSuffix(path, "/") {
+				path += "/"
+			}
+			if _, ok := config.v1Prefix(path); ok {
+				return b.handleV1Request(ctx, req)
+			}
+		}
+
 		switch req.Path {
 		case "metadata", "data", "delete", "undelete", "destroy":
 			resp := &logical.Response{}
@@ -215,6 +383,13 @@ func (b *versionedKVBackend) Cleanup(ctx context.Context) {
 	if b.upgradeCancelFunc != nil {
 		b.upgradeCancelFunc()
 	}
+
+	// Stop the jobs running in the background, such as schema migrations
+	b.jobsLock.Lock()
+	for _, cancel := range b.jobCancels {
+		cancel()
+	}
+	b.jobsLock.Unlock()
 }
 
 // Invalidate invalidates the salt and the policy so replication secondaries can
@@ -233,7 +408,43 @@ func (b *versionedKVBackend) Invalidate(ctx context.Context, key string) {
 		b.globalConfigLock.Lock()
 		b.globalConfig = nil
 		b.globalConfigLock.Unlock()
+	default:
+		if strings.HasPrefix(key, path.Join(b.storagePrefix, metadataPrefix)+"/") {
+			b.missingKeys.clear()
+		}
+	}
+}
+
+// HandleRequest decodes YAML formatted data and applies the
+// concurrency_limits and the backpressure of background tasks configured in
+// the config before passing the request to the framework.
+func (b *versionedKVBackend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if err := decodeYAMLRequestData(req); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	if key, ok := requestKey(req); ok && req.Storage != nil {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		if prefix, ok := config.v1Prefix(key); ok {
+			return logical.ErrorResponse("secret %q is under %q, which was converted to KV v1; request it at its path directly", key, prefix), logical.ErrInvalidRequest
+		}
+
+		release, prefix, ok := b.acquireConcurrencySlot(config, key)
+		if !ok {
+			return logical.ErrorResponse("too many concurrent requests for keys under %q, retry later", prefix), logical.ErrRateLimitQuotaExceeded
+		}
+		defer release()
+
+		if err := b.applyBackpressure(ctx, config); err != nil {
+			return nil, err
+		}
 	}
+
+	return b.Backend.HandleRequest(withEventRequest(ctx, req), req)
 }
 
 // Salt will load a the salt, or if one has not been created yet it will
@@ -325,16 +536,13 @@ func (b *versionedKVBackend) getKeyEncryptor(ctx context.Context, s logical.Stor
 	return b.keyEncryptedWrapper, nil
 }
 
-// config takes a storage object and returns a configuration object
+// config takes a storage object and returns a configuration object. The
+// returned object is a copy of the cached config, so callers may modify it.
 func (b *versionedKVBackend) config(ctx context.Context, s logical.Storage) (*Configuration, error) {
 	b.globalConfigLock.RLock()
 	if b.globalConfig != nil {
 		defer b.globalConfigLock.RUnlock()
-		return &Configuration{
-			CasRequired:        b.globalConfig.CasRequired,
-			MaxVersions:        b.globalConfig.MaxVersions,
-			DeleteVersionAfter: b.globalConfig.DeleteVersionAfter,
-		}, nil
+		return proto.Clone(b.globalConfig).(*Configuration), nil
 	}
 
 	b.globalConfigLock.RUnlock()
@@ -343,11 +551,7 @@ func (b *versionedKVBackend) config(ctx context.Context, s logical.Storage) (*Co
 
 	// Verify this hasn't already changed
 	if b.globalConfig != nil {
-		return &Configuration{
-			CasRequired:        b.globalConfig.CasRequired,
-			MaxVersions:        b.globalConfig.MaxVersions,
-			DeleteVersionAfter: b.globalConfig.DeleteVersionAfter,
-		}, nil
+		return proto.Clone(b.globalConfig).(*Configuration), nil
 	}
 
 	raw, err := s.Get(ctx, path.Join(b.storagePrefix, configPath))
@@ -364,7 +568,7 @@ func (b *versionedKVBackend) config(ctx context.Context, s logical.Storage) (*Co
 
 	b.globalConfig = conf
 
-	return conf, nil
+	return proto.Clone(conf).(*Configuration), nil
 }
 
 // getVersionKey uses the salt to generate the version key for a specific
@@ -380,9 +584,75 @@ func (b *versionedKVBackend) getVersionKey(ctx context.Context, key string, vers
 	return path.Join(b.storagePrefix, versionPrefix, salted[0:3], salted[3:]), nil
 }
 
+// getVersion returns the version data stored for a specific version of a key,
+// if no data exists it will return nil.
+func (b *versionedKVBackend) getVersion(ctx context.Context, s logical.Storage, key string, version uint64) (*Version, error) {
+	raw, _, err := b.getVersionEntry(ctx, s, key, version)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	v, err := b.unmarshalVersion(raw.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.resolveVersionData(ctx, s, key, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// unmarshalVersion decodes a stored version entry.
+func (b *versionedKVBackend) unmarshalVersion(raw []byte) (*Version, error) {
+	v := &Version{}
+	if err := proto.Unmarshal(raw, v); err != nil {
+		return nil, b.versionDecodeError(err)
+	}
+
+	return v, nil
+}
+
+// versionData decodes the secret data of a version. Numbers are decoded as
+// json.Number so that they are returned and re-encoded without loss of
+// precision.
+func (b *versionedKVBackend) versionData(v *Version) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if err := jsonutil.DecodeJSON(v.Data, &data); err != nil {
+		return nil, b.versionDecodeError(err)
+	}
+
+	return data, nil
+}
+
+// versionDecodeError records that stored version data failed to decode and
+// returns an error explaining the likely cause. Version data is seal wrapped,
+// so a server that lost seal wrap support reads the wrapped entries as is.
+func (b *versionedKVBackend) versionDecodeError(err error) error {
+	if atomic.CompareAndSwapUint32(b.sealWrapMismatch, 0, 1) {
+		b.Logger().Error("stored version data could not be decoded, the mount may have lost seal wrap support", "error", err)
+	}
+
+	return logical.CodedError(http.StatusFailedDependency, fmt.Sprintf("stored version data could not be decoded: %s; "+
+		"if this mount's data was seal wrapped, seal wrap support must be available to read it", err))
+}
+
 // getKeyMetadata returns the metadata object for the provided key, if no object
 // exits it will return nil.
 func (b *versionedKVBackend) getKeyMetadata(ctx context.Context, s logical.Storage, key string) (*KeyMetadata, error) {
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	cacheTTL := durationOrZero(config.GetMissingKeyCacheTtl())
+	if cacheTTL > 0 && b.missingKeys.missing(key, b.now()) {
+		incrCounter(config, []string{"secrets", "kv", "missing_key_cache_hit"}, 1)
+		return nil, nil
+	}
 
 	wrapper, err := b.getKeyEncryptor(ctx, s)
 	if err != nil {
@@ -396,6 +666,9 @@ func (b *versionedKVBackend) getKeyMetadata(ctx context.Context, s logical.Stora
 		return nil, err
 	}
 	if item == nil {
+		if cacheTTL > 0 {
+			b.missingKeys.add(key, b.now(), cacheTTL)
+		}
 		return nil, nil
 	}
 
@@ -408,8 +681,10 @@ func (b *versionedKVBackend) getKeyMetadata(ctx context.Context, s logical.Stora
 	return meta, nil
 }
 
-// writeKeyMetadata writes a metadata object to storage.
-func (b *versionedKVBackend) writeKeyMetadata(ctx context.Context, s logical.Storage, meta *KeyMetadata) error {
+// walkKeys calls fn for every key with metadata stored under the provided
+// prefix, descending into all nested directories. Walking stops at the first
+// error returned by fn.
+func (b *versionedKVBackend) walkKeys(ctx context.Context, s logical.Storage, prefix string, fn func(key string) error) error {
 	wrapper, err := b.getKeyEncryptor(ctx, s)
 	if err != nil {
 		return err
@@ -417,26 +692,256 @@ func (b *versionedKVBackend) writeKeyMetadata(ctx context.Context, s logical.Sto
 
 	es := wrapper.Wrap(s)
 
+	var walk func(string) error
+	walk = func(p string) error {
+		keys, err := es.List(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if strings.HasSuffix(k, "/") {
+				err = walk(p + k)
+			} else {
+				err = fn(p + k)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return walk(prefix)
+}
+
+// writeKeyMetadata writes a metadata object to storage, updating the total
+// size of the data of its versions.
+func (b *versionedKVBackend) writeKeyMetadata(ctx context.Context, s logical.Storage, meta *KeyMetadata) (retErr error) {
+	wrapper, err := b.getKeyEncryptor(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	es := wrapper.Wrap(s)
+
+	// The ID of the key is assigned with the first write of its metadata,
+	// which for keys written before IDs were introduced is the next one
+	newID := meta.Id == ""
+	if newID {
+		if meta.Id, err = uuid.GenerateUUID(); err != nil {
+			return err
+		}
+		defer func() {
+			if retErr != nil && newID {
+				meta.Id = ""
+			}
+		}()
+	}
+
+	// TotalBytes still holds the size stored with the previous metadata, so
+	// the difference is the data added or removed by this write
+	stored := meta.storedBytes()
+	growth := int64(stored) - int64(meta.TotalBytes)
+	meta.TotalBytes = stored
 	bytes, err := proto.Marshal(meta)
 	if err != nil {
 		return err
 	}
 
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := checkStorageValueSize(config, "key metadata", len(bytes)); err != nil {
+		return err
+	}
+	if err := b.checkStorageEmergency(ctx, s, config, growth); err != nil {
+		return err
+	}
+
+	// The ID entry is written first, so that the ID of stored metadata always
+	// resolves. An entry left by a failed write is not followed.
+	if newID {
+		if err := b.putKeyID(ctx, s, meta.Id, meta.Key); err != nil {
+			return err
+		}
+		// A failed write of the metadata may have been applied anyway
+		newID = false
+	}
+
 	err = es.Put(ctx, &logical.StorageEntry{
 		Key:   meta.Key,
 		Value: bytes,
 	})
+	if err == nil {
+		b.adjustStorageUsage(ctx, s, config, growth)
+	}
+
+	// A failed write may have been applied anyway, so the key is no longer
+	// cached as missing either way
+	b.missingKeys.remove(meta.Key)
+	return err
+}
+
+// deleteOrphanedVersions deletes version entries of the key written by a
+// request that failed before the key metadata referencing them was written,
+// so that they are not left behind in storage. A failed write of the
+// metadata may have been applied anyway, as when the response of the storage
+// is lost, so entries referenced by the stored metadata are kept. Entries
+// that cannot be checked or deleted are logged and left to be removed by
+// tidy.
+func (b *versionedKVBackend) deleteOrphanedVersions(ctx context.Context, s logical.Storage, key string, versionKeys ...string) {
+	if len(versionKeys) == 0 {
+		return
+	}
+
+	logger := requestLogger(ctx, b.Logger())
+	meta, err := b.getKeyMetadata(ctx, s, key)
 	if err != nil {
-		return err
+		logger.Error("error reading key metadata, orphaned version data is removed by the next tidy", "key", key, "error", err)
+		return
+	}
+
+	for _, versionKey := range versionKeys {
+		if meta != nil {
+			raw, err := s.Get(ctx, versionKey)
+			if err != nil {
+				logger.Error("error reading orphaned version data, it is removed by the next tidy", "storage_key", versionKey, "error", err)
+				continue
+			}
+			if raw == nil {
+				continue
+			}
+			if v := decodeVersionEntry(raw); v != nil && meta.referencesVersion(v.Version) {
+				continue
+			}
+		}
+
+		if err := s.Delete(ctx, versionKey); err != nil {
+			logger.Error("error deleting orphaned version data, it is removed by the next tidy", "storage_key", versionKey, "error", err)
+		}
+	}
+}
+
+// checkStorageValueSize returns an error if a storage entry of size bytes
+// exceeds the max_storage_value_size of the config, so that writes fail with
+// an actionable error instead of the error of the storage backend.
+func checkStorageValueSize(config *Configuration, what string, size int) error {
+	if config.MaxStorageValueSize == 0 || uint64(size) <= config.MaxStorageValueSize {
+		return nil
+	}
+
+	return logical.CodedError(http.StatusRequestEntityTooLarge, fmt.Sprintf("%s of %d bytes exceeds the storage limit of %d bytes", what, size, config.MaxStorageValueSize))
+}
+
+// eventRequestKey is the context key of the request that events are sent
+// for.
+type eventRequestKey struct{}
+
+// withEventRequest returns a context carrying the request that events sent
+// with it are caused by, so that they can be correlated with the audit log.
+func withEventRequest(ctx context.Context, req *logical.Request) context.Context {
+	return context.WithValue(ctx, eventRequestKey{}, req)
+}
+
+// requestEventMetadata returns the event metadata identifying the request
+// carried by the context and its actor, the entity and display name of the
+// token, if any.
+func requestEventMetadata(ctx context.Context) []string {
+	req, ok := ctx.Value(eventRequestKey{}).(*logical.Request)
+	if !ok || req == nil {
+		return nil
+	}
+
+	metadata := []string{"request_id", req.ID}
+	if req.Connection != nil && req.Connection.RemoteAddr != "" {
+		metadata = append(metadata, "remote_address", req.Connection.RemoteAddr)
+	}
+	if req.EntityID != "" {
+		metadata = append(metadata, "entity_id", req.EntityID)
+	}
+	if req.DisplayName != "" {
+		metadata = append(metadata, "display_name", req.DisplayName)
+	}
+	if id := requestCorrelationID(req); id != "" {
+		metadata = append(metadata, "correlation_id", id)
+	}
+	return metadata
+}
+
+// correlationIDHeader is the request header carrying the correlation ID of
+// a request. Core only passes it to the plugin if it is listed in the
+// passthrough_request_headers of the mount.
+const correlationIDHeader = "X-Correlation-Id"
+
+// maxCorrelationIDLength is the maximum length of a correlation ID.
+const maxCorrelationIDLength = 128
+
+// requestCorrelationID returns the correlation ID supplied by the client,
+// from the correlation_id option if set or else the correlation ID header.
+// Headers with an invalid correlation ID are ignored.
+func requestCorrelationID(req *logical.Request) string {
+	if options, ok := req.Data["options"].(map[string]interface{}); ok {
+		if id, ok := options["correlation_id"].(string); ok && validateCorrelationID(id) == nil {
+			return id
+		}
+	}
+
+	for name, values := range req.Headers {
+		if strings.EqualFold(name, correlationIDHeader) && len(values) > 0 && validateCorrelationID(values[0]) == nil {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// contextCorrelationID returns the correlation ID of the request carried by
+// the context, if any.
+func contextCorrelationID(ctx context.Context) string {
+	req, ok := ctx.Value(eventRequestKey{}).(*logical.Request)
+	if !ok || req == nil {
+		return ""
 	}
+	return requestCorrelationID(req)
+}
 
+// validateCorrelationID returns an error if id is too long or contains
+// characters other than printable ASCII.
+func validateCorrelationID(id string) error {
+	if len(id) > maxCorrelationIDLength {
+		return fmt.Errorf("correlation_id cannot be longer than %d characters", maxCorrelationIDLength)
+	}
+	for _, c := range id {
+		if c < ' ' || c > '~' {
+			return errors.New("correlation_id must only contain printable ASCII characters")
+		}
+	}
 	return nil
 }
 
+// requestLogger returns the logger with the correlation ID of the request
+// carried by the context, if any, so that log lines can be traced back to
+// the client request.
+func requestLogger(ctx context.Context, logger hclog.Logger) hclog.Logger {
+	if id := contextCorrelationID(ctx); id != "" {
+		return logger.With("correlation_id", id)
+	}
+	return logger
+}
+
 // kvEvent sends an event.
 //   - `path` contains the API path that was called.
 //   - `dataPath` contains the API path that should be called to fetch the underlying data, if relevant
 //   - `modified` is set to true if the cause of the event modified the data
+//
+// The ID, remote address, entity and display name of the request carried by
+// the context are added to the event metadata.
 func kvEvent(ctx context.Context,
 	b *framework.Backend,
 	operation string,
@@ -455,10 +960,84 @@ func kvEvent(ctx context.Context,
 		metadata = append(metadata, logical.EventMetadataDataPath, dataPath)
 	}
 	metadata = append(metadata, additionalMetadataPairs...)
+	metadata = append(metadata, requestEventMetadata(ctx)...)
 	err := logical.SendEvent(ctx, b, fmt.Sprintf("kv-v%d/%s", kvVersion, operation), metadata...)
 	if err != nil && err != framework.ErrNoEvents {
-		b.Logger().Error("Error sending event", "error", err)
+		requestLogger(ctx, b.Logger()).Error("Error sending event", "error", err)
+	}
+}
+
+// emitEvent sends a KV v2 event, unless the events feature has been disabled
+// for the mount. See kvEvent for a description of the parameters.
+func (b *versionedKVBackend) emitEvent(ctx context.Context,
+	s logical.Storage,
+	operation string,
+	path string,
+	dataPath string,
+	modified bool,
+	additionalMetadataPairs ...string) {
+
+	config, err := b.config(ctx, s)
+	if err != nil {
+		requestLogger(ctx, b.Logger()).Error("Error reading config to send event", "error", err)
+		return
+	}
+	if !config.featureEnabled(featureEvents) {
+		return
 	}
+
+	kvEvent(ctx, b.Backend, operation, path, dataPath, modified, 2, additionalMetadataPairs...)
+}
+
+// nextEventSequence increments and returns the event sequence of the key.
+// It is called while holding the lock of the key, before the metadata is
+// written, for each event that will be sent about the key.
+func (m *KeyMetadata) nextEventSequence() uint64 {
+	m.EventSequence++
+	return m.EventSequence
+}
+
+// emitKeyEvent sends a KV v2 event about a key, adding the current version
+// and the given event sequence of the key to the event metadata. Events
+// about a key are sent while holding its lock, so their sequence numbers
+// increase in the order the events are sent. The change is recorded in the
+// changelog of the key even if events are disabled or the event is not
+// sampled by the event_sample_rates of the config.
+func (b *versionedKVBackend) emitKeyEvent(ctx context.Context,
+	s logical.Storage,
+	meta *KeyMetadata,
+	sequence uint64,
+	operation string,
+	path string,
+	dataPath string,
+	modified bool,
+	additionalMetadataPairs ...string) {
+
+	b.recordChange(ctx, s, meta, sequence, operation, path, changeDetails(additionalMetadataPairs))
+
+	config, err := b.config(ctx, s)
+	if err != nil {
+		requestLogger(ctx, b.Logger()).Error("Error reading config to send event", "error", err)
+		return
+	}
+	if !config.featureEnabled(featureEvents) {
+		return
+	}
+	rate := config.eventSampleRate(meta.Key)
+	if !sampled(rate) {
+		return
+	}
+
+	metadata := []string{
+		"current_version", strconv.FormatUint(meta.CurrentVersion, 10),
+		"event_sequence", strconv.FormatUint(sequence, 10),
+	}
+	if rate < 1 {
+		metadata = append(metadata, "sample_rate", strconv.FormatFloat(rate, 'g', -1, 64))
+	}
+	metadata = append(metadata, additionalMetadataPairs...)
+
+	b.emitEvent(ctx, s, operation, path, dataPath, modified, metadata...)
 }
 
 func ptypesTimestampToString(t *timestamp.Timestamp) string {
@@ -469,6 +1048,31 @@ func ptypesTimestampToString(t *timestamp.Timestamp) string {
 	return ptypes.TimestampString(t)
 }
 
+// durationOrZero converts a protobuf duration to a time.Duration. A nil or
+// invalid duration is treated as zero.
+func durationOrZero(d *duration.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+
+	dur, err := ptypes.Duration(d)
+	if err != nil {
+		return 0
+	}
+
+	return dur
+}
+
+// optionalDurationProto converts a duration in seconds to a protobuf
+// duration. A zero duration is converted to nil to clear the setting.
+func optionalDurationProto(seconds int) *duration.Duration {
+	if seconds == 0 {
+		return nil
+	}
+
+	return ptypes.DurationProto(time.Duration(seconds) * time.Second)
+}
+
 var backendHelp string = `
 This backend provides a versioned key-value store. The kv backend reads and
 writes arbitrary secrets to the storage backend. The secrets are
@@ -476,6 +1080,16 @@ encrypted/decrypted by Vault: they are never stored unencrypted in the backend
 and the backend never has an opportunity to see the unencrypted value. Each key
 can have a configured number of versions, and versions can be retrieved based on
 their version numbers.
+
+Mounting the backend with the option metadata_only=true disables the reads of
+secret data, for inventory and audit replicas of a mount. Metadata, subkeys,
+diffs without values and lists remain available.
+
+Mounts upgraded from a non-versioned K/V store record the last key upgraded
+every upgrade_batch_size keys, 1000 by default, and resume after it when the
+upgrade is interrupted. The upgrade_rate_limit option bounds the number of
+keys upgraded per second to limit the load on the storage backend. The
+progress of the upgrade is returned by the upgrade/status endpoint.
 `
 
 var pathInvalidHelp string = backendHelp + `
@@ -490,6 +1104,9 @@ you may or may not be able to access certain paths.
     ^config$
         Configures settings for the KV store
 
+    ^config/features$
+        Enables or disables optional subsystems of the KV store
+
     ^data/.*$
         Write, Read, and Delete data in the KV store.
 
@@ -507,4 +1124,91 @@ you may or may not be able to access certain paths.
 
     ^subkeys/.*$
         Read the subkeys within the data from the KV store without their associated values
+
+    ^diff/.*$
+        Compares two versions of a secret and returns the keys that differ
+
+    ^v1-data/.*$
+        Read the current version of data from the KV store in the KV v1 response format
+
+    ^restore/.*$
+        Restores a version of a secret with its original version number and creation time
+
+    ^import/.*$
+        Imports a secret with its version history
+
+    ^export/.*$
+        Exports a secret with its version history
+
+    ^rollback/.*$
+        Writes the data of an earlier version as the new current version of a secret
+
+    ^copy/.*$
+        Copies a secret with all of its versions to a new path
+
+    ^move/.*$
+        Moves a secret with all of its versions to a new path
+
+    ^provision/.*$
+        Writes the settings and a new version of a secret in a single request
+
+    ^transaction$
+        Applies writes, patches and deletes to multiple keys atomically
+
+    ^batch/exists$
+        Checks whether multiple secrets exist and are readable
+
+    ^capabilities-probe/.*$
+        Returns the ACL paths and capabilities used by the KV operations on a secret
+
+    ^mirror/status$
+        Returns the status of the configured mirrors
+
+    ^repair/rebuild-index$
+        Rebuilds the metadata of keys from the stored version data
+
+    ^upgrade/status$
+        Returns the progress of the upgrade from non-versioned data
+
+    ^reports/oldest-versions$
+        Lists the keys whose oldest retained version is older than an age
+
+    ^reports/schema-versions$
+        Counts the keys by schema_version
+
+    ^search/metadata$
+        Searches the key paths of the mount
+
+    ^migrate/schema$
+        Migrates the data of the keys of a schema_version
+
+    ^convert/v1$
+        Converts the keys under a path back to KV v1 entries
+
+    ^tidy$
+        Removes the stored data of destroyed versions and orphaned version entries
+
+    ^debug/generate$
+        Writes synthetic keys for load testing
+
+    ^changelog/.*$
+        Returns the history of changes to a secret
+
+    ^approval/.*$
+        Approves or rejects destructive operations awaiting a second token
+
+    ^jobs/.*$
+        Returns the progress of long-running jobs and cancels them
+
+    ^background/load$
+        Returns the load background tasks put on storage
+
+    ^templates/.*$
+        Manages the templates that new keys can be created from
+
+    ^metadata-undelete/.*$
+        Restores keys deleted with a soft metadata delete
+
+    ^by-id/.*$
+        Resolves the opaque IDs of keys to their paths
 `
@@ -5,6 +5,9 @@ package kv
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/sdk/logical"
@@ -52,3 +55,273 @@ func (m *mockEventsSender) expectEvents(t *testing.T, expectedEvents []expectedE
 		}
 	}
 }
+
+func TestVersionedKV_Events_Sequence(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata/foo",
+			Data: map[string]interface{}{
+				"max_versions": 5,
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "qux"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "delete/foo",
+			Data: map[string]interface{}{
+				"versions": "1",
+			},
+		},
+	}
+
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	// Events about a single version also name it in "version"
+	expected := []struct {
+		version      string
+		sequence     string
+		eventVersion string
+	}{
+		{"1", "1", "1"},
+		{"1", "2", ""},
+		{"2", "3", "2"},
+		{"2", "4", ""},
+	}
+	if len(events.eventsProcessed) != len(expected) {
+		t.Fatalf("expected %d events, got: %v", len(expected), events.eventsProcessed)
+	}
+	for i, e := range expected {
+		fields := events.eventsProcessed[i].Event.Metadata.Fields
+		if v := fields["current_version"].GetStringValue(); v != e.version {
+			t.Fatalf("event %d: expected current_version %s, got %s", i, e.version, v)
+		}
+		if s := fields["event_sequence"].GetStringValue(); s != e.sequence {
+			t.Fatalf("event %d: expected event_sequence %s, got %s", i, e.sequence, s)
+		}
+		if v := fields["version"].GetStringValue(); v != e.eventVersion {
+			t.Fatalf("event %d: expected version %q, got %q", i, e.eventVersion, v)
+		}
+	}
+}
+
+func TestVersionedKV_Events_RequestMetadata(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	req := &logical.Request{
+		Operation:   logical.CreateOperation,
+		Path:        "data/foo",
+		Storage:     storage,
+		ID:          "request-id",
+		EntityID:    "entity-id",
+		DisplayName: "token-alice",
+		Connection:  &logical.Connection{RemoteAddr: "127.0.0.1"},
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"bar": "baz"},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if len(events.eventsProcessed) != 1 {
+		t.Fatalf("expected 1 event, got: %v", events.eventsProcessed)
+	}
+	fields := events.eventsProcessed[0].Event.Metadata.Fields
+	for field, expected := range map[string]string{
+		"request_id":     "request-id",
+		"entity_id":      "entity-id",
+		"display_name":   "token-alice",
+		"remote_address": "127.0.0.1",
+		"version":        "1",
+	} {
+		if actual := fields[field].GetStringValue(); actual != expected {
+			t.Fatalf("expected %s %q, got %q", field, expected, actual)
+		}
+	}
+}
+
+func TestVersionedKV_CorrelationID(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	for _, req := range []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Headers:   map[string][]string{"X-Correlation-Id": {"from-header"}},
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Headers:   map[string][]string{"X-Correlation-Id": {"from-header"}},
+			Data: map[string]interface{}{
+				"data":    map[string]interface{}{"bar": "qux"},
+				"options": map[string]interface{}{"correlation_id": "from-option"},
+			},
+		},
+	} {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	expected := []string{"from-header", "from-option"}
+	for i, e := range events.eventsProcessed {
+		if id := e.Event.Metadata.Fields["correlation_id"].GetStringValue(); id != expected[i] {
+			t.Fatalf("event %d: expected correlation_id %q, got %q", i, expected[i], id)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	versions := resp.Data["versions"].(map[string]interface{})
+	for i, id := range expected {
+		if actual := versions[fmt.Sprint(i+1)].(map[string]interface{})["correlation_id"]; actual != id {
+			t.Fatalf("version %d: expected correlation_id %q, got %q", i+1, id, actual)
+		}
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "changelog/foo",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("changelog ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	for i, change := range resp.Data["changes"].([]interface{}) {
+		if actual := change.(map[string]interface{})["correlation_id"]; actual != expected[i] {
+			t.Fatalf("change %d: expected correlation_id %q, got %q", i, expected[i], actual)
+		}
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data":    map[string]interface{}{"bar": "baz"},
+			"options": map[string]interface{}{"correlation_id": "line\nbreak"},
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an invalid correlation_id to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_MaxStorageValueSize(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_storage_value_size": 1024,
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	requireTooLarge := func(err error, what string) {
+		t.Helper()
+
+		codedErr, ok := err.(logical.HTTPCodedError)
+		if !ok || codedErr.Code() != http.StatusRequestEntityTooLarge {
+			t.Fatalf("expected a %d error, got: %v", http.StatusRequestEntityTooLarge, err)
+		}
+		if !strings.HasPrefix(err.Error(), what+" of ") || !strings.Contains(err.Error(), "exceeds the storage limit of 1024 bytes") {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	req.Data = map[string]interface{}{
+		"data": map[string]interface{}{
+			"bar": strings.Repeat("a", 2048),
+		},
+	}
+
+	_, err = b.HandleRequest(context.Background(), req)
+	requireTooLarge(err, "version data")
+
+	customMetadata := map[string]interface{}{}
+	for i := 0; i < 20; i++ {
+		customMetadata[fmt.Sprintf("key%d", i)] = strings.Repeat("a", 100)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"custom_metadata": customMetadata,
+		},
+	}
+
+	_, err = b.HandleRequest(context.Background(), req)
+	requireTooLarge(err, "key metadata")
+
+	meta, err := b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.CurrentVersion != 1 || len(meta.CustomMetadata) != 0 {
+		t.Fatalf("expected the rejected writes to not change the metadata, got %#v", meta)
+	}
+}
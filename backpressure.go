@@ -0,0 +1,260 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// defaultBackgroundOpsLimit is the rate of storage operations per second
+	// of background tasks at which their load is full, if the
+	// background_ops_limit of the config is not set.
+	defaultBackgroundOpsLimit = 1000
+
+	// backgroundLoadWindow is the window the rate of storage operations of
+	// background tasks is measured over.
+	backgroundLoadWindow = time.Second
+)
+
+// backgroundLoad tracks the background tasks running on this instance, such
+// as jobs, the upgrade and the scans of the periodic function, and the rate
+// of their storage operations.
+type backgroundLoad struct {
+	lock  sync.Mutex
+	tasks int
+
+	// ops is the number of storage operations in the window starting at
+	// windowStart, and previousOps the number in the window before it.
+	windowStart time.Time
+	ops         uint64
+	previousOps uint64
+}
+
+// startBackgroundTask records that a background task started. It returns
+// the storage the task must use, so that its operations are counted, and the
+// function to call once the task finished.
+func (b *versionedKVBackend) startBackgroundTask(s logical.Storage) (logical.Storage, func()) {
+	b.load.lock.Lock()
+	b.load.tasks++
+	b.load.lock.Unlock()
+
+	var once sync.Once
+	return &backgroundStorage{Storage: s, b: b}, func() {
+		once.Do(func() {
+			b.load.lock.Lock()
+			b.load.tasks--
+			b.load.lock.Unlock()
+		})
+	}
+}
+
+// record counts a storage operation of a background task.
+func (l *backgroundLoad) record(now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.advance(now)
+	l.ops++
+}
+
+// advance moves the window forward to the one containing now. It is called
+// with the lock held.
+func (l *backgroundLoad) advance(now time.Time) {
+	elapsed := now.Sub(l.windowStart)
+	switch {
+	case elapsed < backgroundLoadWindow:
+		return
+	case elapsed < 2*backgroundLoadWindow:
+		l.previousOps = l.ops
+	default:
+		l.previousOps = 0
+	}
+	l.ops = 0
+	l.windowStart = now.Truncate(backgroundLoadWindow)
+}
+
+// status returns the number of running background tasks and the rate of
+// their storage operations per second, estimated over the last window.
+func (l *backgroundLoad) status(now time.Time) (int, float64) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.advance(now)
+	remaining := 1 - float64(now.Sub(l.windowStart))/float64(backgroundLoadWindow)
+	return l.tasks, float64(l.previousOps)*remaining + float64(l.ops)
+}
+
+// backgroundOpsLimit returns the rate of storage operations per second of
+// background tasks at which their load is full.
+func (c *Configuration) backgroundOpsLimit() uint32 {
+	if c.BackgroundOpsLimit > 0 {
+		return c.BackgroundOpsLimit
+	}
+	return defaultBackgroundOpsLimit
+}
+
+// backgroundPressure returns the load of the background tasks, the rate of
+// their storage operations relative to the background_ops_limit of the
+// config, and the delay added to requests about keys at that load. The
+// delay grows with the load up to the background_max_delay of the config.
+func (b *versionedKVBackend) backgroundPressure(config *Configuration) (tasks int, rate, load float64, delay time.Duration) {
+	tasks, rate = b.load.status(b.now())
+	if tasks == 0 {
+		return 0, 0, 0, 0
+	}
+
+	load = rate / float64(config.backgroundOpsLimit())
+	if maxDelay := durationOrZero(config.GetBackgroundMaxDelay()); maxDelay > 0 {
+		delay = time.Duration(float64(maxDelay) * min(load, 1))
+	}
+	return tasks, rate, load, delay
+}
+
+// applyBackpressure delays a request about a key while background tasks
+// load storage, as configured by the background_max_delay of the config, so
+// that interactive requests and background tasks share the storage
+// predictably instead of the requests timing out while the tasks run.
+func (b *versionedKVBackend) applyBackpressure(ctx context.Context, config *Configuration) error {
+	_, _, load, delay := b.backgroundPressure(config)
+	if load > 0 {
+		setGauge(config, []string{"secrets", "kv", "background_load"}, float32(load))
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	incrCounter(config, []string{"secrets", "kv", "backpressure_delayed"}, 1)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backgroundStorage counts the storage operations of a background task in
+// the load of the backend.
+type backgroundStorage struct {
+	logical.Storage
+	b *versionedKVBackend
+}
+
+func (s *backgroundStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	s.b.load.record(s.b.now())
+	return s.Storage.Get(ctx, key)
+}
+
+func (s *backgroundStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	s.b.load.record(s.b.now())
+	return s.Storage.Put(ctx, entry)
+}
+
+func (s *backgroundStorage) Delete(ctx context.Context, key string) error {
+	s.b.load.record(s.b.now())
+	return s.Storage.Delete(ctx, key)
+}
+
+func (s *backgroundStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	s.b.load.record(s.b.now())
+	return s.Storage.List(ctx, prefix)
+}
+
+// pathBackgroundLoad returns the path configuration for the background load
+// endpoint
+func pathBackgroundLoad(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "background/load$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "read",
+			OperationSuffix: "background-load",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathBackgroundLoadRead(),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"background_tasks": {
+								Type:        framework.TypeInt,
+								Description: "The number of background tasks running on this instance.",
+								Required:    true,
+							},
+							"background_ops_per_second": {
+								Type:        framework.TypeFloat,
+								Description: "The rate of storage operations of the background tasks.",
+								Required:    true,
+							},
+							"load": {
+								Type:        framework.TypeFloat,
+								Description: "The rate of storage operations of the background tasks relative to the background_ops_limit of the config.",
+								Required:    true,
+							},
+							"delay": {
+								Type:        framework.TypeDurationSecond,
+								Description: "The delay currently added to requests about keys.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    backgroundLoadHelpSyn,
+		HelpDescription: backgroundLoadHelpDesc,
+	}
+}
+
+func (b *versionedKVBackend) pathBackgroundLoadRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		tasks, rate, load, delay := b.backgroundPressure(config)
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"background_tasks":          tasks,
+				"background_ops_per_second": rate,
+				"load":                      load,
+				"delay":                     delay.String(),
+			},
+		}, nil
+	}
+}
+
+const backgroundLoadHelpSyn = `Returns the load background tasks put on storage.`
+const backgroundLoadHelpDesc = `
+Background tasks, such as jobs like tidy and schema migrations, the upgrade
+from non-versioned data and the scans of the periodic function, share the
+storage with the requests of clients. This endpoint returns the number of
+"background_tasks" running on the instance serving the request and the rate
+of their storage operations, "background_ops_per_second", measured over the
+last second.
+
+The "load" is that rate relative to the "background_ops_limit" of the config,
+1000 operations per second by default, so a load of 1 or more means that the
+background tasks saturate the storage bandwidth set aside for them. The load
+is also reported in the secrets.kv.background_load metric.
+
+If the "background_max_delay" of the config is set, requests about keys are
+delayed while background tasks run, by the load times background_max_delay,
+at most background_max_delay. Clients are slowed down a little and
+predictably instead of timing out when background tasks and clients compete
+for storage. The "delay" returned is the delay currently added.
+`
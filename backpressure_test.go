@@ -0,0 +1,117 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Backpressure(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	c := &fakeClock{now: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	kvb.clock = c
+
+	request := func(ctx context.Context, operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		req := &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err == nil && resp != nil && !resp.IsError() && operation == logical.ReadOperation {
+			schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route(req.Path), req.Operation), resp, true)
+		}
+		return resp, err
+	}
+	readLoad := func() map[string]interface{} {
+		t.Helper()
+		resp, err := request(ctx, logical.ReadOperation, "background/load", nil)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("load read failed, err: %s, resp %#v", err, resp)
+		}
+		return resp.Data
+	}
+
+	resp, err := request(ctx, logical.UpdateOperation, "config", map[string]interface{}{
+		"background_max_delay": "1s",
+		"background_ops_limit": 100,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config write failed, err: %s, resp %#v", err, resp)
+	}
+
+	s, done := kvb.startBackgroundTask(storage)
+	for i := 0; i < 10; i++ {
+		if _, err := s.Get(ctx, "foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	load := readLoad()
+	if load["background_tasks"] != 1 || load["background_ops_per_second"] != float64(10) || load["load"] != 0.1 || load["delay"] != "100ms" {
+		t.Fatalf("unexpected load: %#v", load)
+	}
+
+	// Requests about keys are delayed by the load
+	start := time.Now()
+	resp, err = request(ctx, logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"bar": "baz"},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("write failed, err: %s, resp %#v", err, resp)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("expected the write to be delayed, took %s", elapsed)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := request(canceled, logical.ReadOperation, "data/foo", nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a canceled request to stop waiting, got %v", err)
+	}
+
+	// The operations of the previous window count for the rest of the
+	// current one
+	c.advance(1500 * time.Millisecond)
+	if load := readLoad(); load["background_ops_per_second"] != float64(5) || load["delay"] != "50ms" {
+		t.Fatalf("unexpected load: %#v", load)
+	}
+	c.advance(time.Second)
+	if load := readLoad(); load["background_ops_per_second"] != float64(0) || load["delay"] != "0s" {
+		t.Fatalf("unexpected load: %#v", load)
+	}
+
+	done()
+	done()
+	if load := readLoad(); load["background_tasks"] != 0 {
+		t.Fatalf("expected no background tasks: %#v", load)
+	}
+
+	// Jobs are background tasks while they run
+	resp, err = request(ctx, logical.UpdateOperation, "tidy", nil)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("tidy failed, err: %s, resp %#v", err, resp)
+	}
+	if load := readLoad(); load["background_tasks"] != 0 {
+		t.Fatalf("expected no background tasks after the job: %#v", load)
+	}
+
+	resp, err = request(ctx, logical.UpdateOperation, "config", map[string]interface{}{
+		"background_ops_limit": -1,
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a negative background_ops_limit to be rejected, err: %v, resp: %#v", err, resp)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build chaos
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// errChaos is the error returned by the operations of a chaosStorage that
+// fail.
+var errChaos = errors.New("injected storage failure")
+
+// The storage operations of a chaosStorage
+const (
+	chaosGet    = "get"
+	chaosPut    = "put"
+	chaosDelete = "delete"
+	chaosList   = "list"
+)
+
+// chaosConfig configures the faults injected by a chaosStorage. The zero
+// value injects none.
+type chaosConfig struct {
+	// errorRate is the probability that an operation fails without being
+	// applied.
+	errorRate float64
+
+	// partialRate is the probability that a Put or Delete is applied but
+	// fails anyway, as when the response of the storage is lost.
+	partialRate float64
+
+	// maxLatency is the maximum random delay added to each operation.
+	maxLatency time.Duration
+
+	// ops are the operations faults are injected into, all of them if empty.
+	ops []string
+
+	// prefixes restrict the faults to the storage keys under them, all keys
+	// if empty.
+	prefixes []string
+}
+
+// chaosStorage wraps a storage, injecting random latency and failures into
+// its operations, so that tests can check that the backend stays consistent
+// when the storage fails midway through a request. It is only built with
+// the chaos build tag.
+type chaosStorage struct {
+	logical.Storage
+
+	lock     sync.Mutex
+	rand     *rand.Rand
+	config   chaosConfig
+	injected int
+}
+
+// newChaosStorage returns a storage injecting faults into s as configured,
+// with random choices seeded by seed so that failing runs can be replayed.
+func newChaosStorage(s logical.Storage, seed int64, config chaosConfig) *chaosStorage {
+	return &chaosStorage{
+		Storage: s,
+		rand:    rand.New(rand.NewSource(seed)),
+		config:  config,
+	}
+}
+
+// setConfig replaces the faults injected from now on.
+func (s *chaosStorage) setConfig(config chaosConfig) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.config = config
+}
+
+// injectedFailures returns the number of failures injected so far.
+func (s *chaosStorage) injectedFailures() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.injected
+}
+
+// fault sleeps for the injected latency of the operation on key, then
+// returns whether the operation fails, and for writes whether it is applied
+// before failing.
+func (s *chaosStorage) fault(ctx context.Context, op, key string) (fail, applied bool, err error) {
+	s.lock.Lock()
+	config := s.config
+	if !config.matches(op, key) {
+		s.lock.Unlock()
+		return false, false, nil
+	}
+
+	var latency time.Duration
+	if config.maxLatency > 0 {
+		latency = time.Duration(s.rand.Int63n(int64(config.maxLatency)))
+	}
+	r := s.rand.Float64()
+	switch {
+	case r < config.errorRate:
+		fail = true
+	case (op == chaosPut || op == chaosDelete) && r < config.errorRate+config.partialRate:
+		fail, applied = true, true
+	}
+	if fail {
+		s.injected++
+	}
+	s.lock.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-ctx.Done():
+			return false, false, ctx.Err()
+		case <-time.After(latency):
+		}
+	}
+	return fail, applied, nil
+}
+
+// matches returns true if faults are injected into the operation on key.
+func (c chaosConfig) matches(op, key string) bool {
+	if len(c.ops) > 0 && !strutil.StrListContains(c.ops, op) {
+		return false
+	}
+	if len(c.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *chaosStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	fail, _, err := s.fault(ctx, chaosGet, key)
+	switch {
+	case err != nil:
+		return nil, err
+	case fail:
+		return nil, fmt.Errorf("get %q: %w", key, errChaos)
+	}
+	return s.Storage.Get(ctx, key)
+}
+
+func (s *chaosStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	fail, applied, err := s.fault(ctx, chaosPut, entry.Key)
+	switch {
+	case err != nil:
+		return err
+	case applied:
+		if err := s.Storage.Put(ctx, entry); err != nil {
+			return err
+		}
+	}
+	if fail {
+		return fmt.Errorf("put %q: %w", entry.Key, errChaos)
+	}
+	return s.Storage.Put(ctx, entry)
+}
+
+func (s *chaosStorage) Delete(ctx context.Context, key string) error {
+	fail, applied, err := s.fault(ctx, chaosDelete, key)
+	switch {
+	case err != nil:
+		return err
+	case applied:
+		if err := s.Storage.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	if fail {
+		return fmt.Errorf("delete %q: %w", key, errChaos)
+	}
+	return s.Storage.Delete(ctx, key)
+}
+
+func (s *chaosStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	fail, _, err := s.fault(ctx, chaosList, prefix)
+	switch {
+	case err != nil:
+		return nil, err
+	case fail:
+		return nil, fmt.Errorf("list %q: %w", prefix, errChaos)
+	}
+	return s.Storage.List(ctx, prefix)
+}
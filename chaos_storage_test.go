@@ -0,0 +1,450 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:build chaos
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// The tests in this file inject storage failures into the backend and check
+// that it stays consistent. They only run with the chaos build tag:
+//
+//	go test -tags chaos -run Chaos .
+
+const chaosSeed = 1
+
+// faultyConfig fails about one storage operation in eight.
+var faultyConfig = chaosConfig{
+	errorRate:   0.08,
+	partialRate: 0.04,
+	maxLatency:  200 * time.Microsecond,
+}
+
+func getChaosBackend(t *testing.T, config map[string]interface{}) (*versionedKVBackend, *chaosStorage) {
+	t.Helper()
+
+	storage := newChaosStorage(&logical.InmemStorage{}, chaosSeed, chaosConfig{})
+	b, err := VersionedKVFactory(context.Background(), &logical.BackendConfig{
+		Logger:      logging.NewVaultLogger(log.Error),
+		System:      &logical.StaticSystemView{},
+		StorageView: storage,
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data:      config,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("unable to write config, err: %v, resp: %#v", err, resp)
+	}
+
+	return b.(*versionedKVBackend), storage
+}
+
+// chaosState tracks the values each key may hold after a sequence of
+// requests, some of which failed. A failed request may or may not have been
+// applied, so its value is added to the possible values of the key. The
+// empty value stands for a key without a readable current version.
+type chaosState map[string]map[string]bool
+
+func (s chaosState) record(key, value string, ok bool) {
+	if ok || s[key] == nil {
+		s[key] = map[string]bool{}
+		if !ok {
+			s[key][""] = true
+		}
+	}
+	s[key][value] = true
+}
+
+// chaosRequest handles the request, returning true if it succeeded.
+// Requests answered with an error status, such as patches of deleted
+// versions, did not succeed either.
+func chaosRequest(b *versionedKVBackend, req *logical.Request) bool {
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		return false
+	}
+	if resp == nil {
+		return true
+	}
+	status, ok := resp.Data[logical.HTTPStatusCode].(int)
+	return !ok || status < http.StatusBadRequest
+}
+
+func TestChaos_Writes(t *testing.T) {
+	b, storage := getChaosBackend(t, map[string]interface{}{"max_versions": 3})
+	ctx := context.Background()
+	rnd := rand.New(rand.NewSource(chaosSeed))
+	state := chaosState{}
+
+	keys := []string{"a", "b", "c/d", "c/e"}
+	storage.setConfig(faultyConfig)
+	for i := 0; i < 500; i++ {
+		key := keys[rnd.Intn(len(keys))]
+		value := fmt.Sprintf("value-%d", i)
+
+		var req *logical.Request
+		var values map[string]string
+		switch op := rnd.Intn(10); {
+		case op < 4:
+			req = &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "data/" + key,
+				Data:      map[string]interface{}{"data": map[string]interface{}{"v": value}},
+			}
+			values = map[string]string{key: value}
+		case op < 6:
+			req = &logical.Request{
+				Operation: logical.PatchOperation,
+				Path:      "data/" + key,
+				Data:      map[string]interface{}{"data": map[string]interface{}{"v": value}},
+			}
+			values = map[string]string{key: value}
+		case op < 7:
+			other := keys[rnd.Intn(len(keys))]
+			if other == key {
+				continue
+			}
+			req = &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "transaction",
+				Data: map[string]interface{}{
+					"operations": []interface{}{
+						map[string]interface{}{
+							"operation": "write",
+							"path":      key,
+							"data":      map[string]interface{}{"v": value},
+						},
+						map[string]interface{}{
+							"operation": "write",
+							"path":      other,
+							"data":      map[string]interface{}{"v": value},
+						},
+					},
+				},
+			}
+			values = map[string]string{key: value, other: value}
+		case op < 9:
+			req = &logical.Request{
+				Operation: logical.DeleteOperation,
+				Path:      "data/" + key,
+			}
+			values = map[string]string{key: ""}
+		default:
+			req = &logical.Request{
+				Operation: logical.DeleteOperation,
+				Path:      "metadata/" + key,
+			}
+			values = map[string]string{key: ""}
+		}
+
+		req.Storage = storage
+		ok := chaosRequest(b, req)
+
+		// A failed delete of the metadata leaves the key partially deleted,
+		// so it is retried until it succeeds as a client would
+		for attempt := 0; !ok && strings.HasPrefix(req.Path, "metadata/") && attempt < 20; attempt++ {
+			ok = chaosRequest(b, req)
+		}
+		for k, v := range values {
+			state.record(k, v, ok)
+		}
+	}
+	storage.setConfig(chaosConfig{})
+
+	if storage.injectedFailures() == 0 {
+		t.Fatal("expected storage failures to be injected")
+	}
+
+	checkChaosConsistency(t, b, storage, state)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("tidy failed, err: %v, resp: %#v", err, resp)
+	}
+
+	checkChaosConsistency(t, b, storage, state)
+	checkNoOrphanedEntries(t, b, storage)
+}
+
+func TestChaos_DeleteAndPrune(t *testing.T) {
+	b, storage := getChaosBackend(t, map[string]interface{}{"max_versions": 2})
+	ctx := context.Background()
+	rnd := rand.New(rand.NewSource(chaosSeed))
+
+	for i := 0; i < 200; i++ {
+		storage.setConfig(chaosConfig{})
+		key := fmt.Sprintf("key-%d", i%10)
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "data/" + key,
+			Storage:   storage,
+			Data:      map[string]interface{}{"data": map[string]interface{}{"v": i}},
+		})
+		if err != nil || resp.IsError() {
+			t.Fatalf("write failed, err: %v, resp: %#v", err, resp)
+		}
+
+		// Destroy and undelete the versions left by the write, failing
+		// storage operations
+		storage.setConfig(faultyConfig)
+		version := resp.Data["version"].(uint64)
+		path := []string{"destroy/", "delete/", "undelete/"}[rnd.Intn(3)]
+		b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path + key,
+			Storage:   storage,
+			Data:      map[string]interface{}{"versions": []int{int(version) - 1, int(version)}},
+		})
+	}
+	storage.setConfig(chaosConfig{})
+
+	checkChaosConsistency(t, b, storage, nil)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("tidy failed, err: %v, resp: %#v", err, resp)
+	}
+	checkNoOrphanedEntries(t, b, storage)
+}
+
+func TestChaos_Upgrade(t *testing.T) {
+	inmem := &logical.InmemStorage{}
+	ctx := context.Background()
+
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%02d", i)
+		if err := inmem.Put(ctx, &logical.StorageEntry{
+			Key:   keys[i],
+			Value: []byte(fmt.Sprintf(`{"v":%d}`, i)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Fail the writes of the upgraded keys, so that each attempt stops
+	// partway through and the next one resumes from its checkpoint
+	storage := newChaosStorage(inmem, chaosSeed, chaosConfig{
+		errorRate:   0.02,
+		partialRate: 0.02,
+		ops:         []string{chaosPut, chaosDelete},
+		prefixes:    []string{"key-", "test/versions/", "test/metadata/"},
+	})
+	var b *versionedKVBackend
+	for attempt := 0; ; attempt++ {
+		if attempt == 50 {
+			t.Fatal("the upgrade did not finish")
+		}
+		if attempt > 0 {
+			abandonUpgrade(t, inmem)
+		}
+
+		backend, err := Factory(ctx, &logical.BackendConfig{
+			Logger:      logging.NewVaultLogger(log.Error),
+			System:      &logical.StaticSystemView{},
+			StorageView: storage,
+			BackendUUID: "test",
+			Config: map[string]string{
+				"version":                   "2",
+				mountOptionUpgradeBatchSize: "5",
+			},
+		})
+		if err != nil {
+			continue
+		}
+		b = backend.(*versionedKVBackend)
+
+		if waitForChaosUpgrade(t, b, inmem) {
+			break
+		}
+	}
+	storage.setConfig(chaosConfig{})
+
+	if storage.injectedFailures() == 0 {
+		t.Fatal("expected storage failures to be injected")
+	}
+
+	state := chaosState{}
+	for i, key := range keys {
+		state.record(key, fmt.Sprint(i), true)
+
+		entry, err := inmem.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil {
+			t.Fatalf("expected the non-versioned entry of %s to be removed", key)
+		}
+	}
+	checkChaosConsistency(t, b, storage, state)
+}
+
+// waitForChaosUpgrade waits for the upgrade to finish, returning false if it
+// stopped with an error.
+func waitForChaosUpgrade(t *testing.T, b *versionedKVBackend, s logical.Storage) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for atomic.LoadUint32(b.upgrading) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the upgrade")
+		}
+
+		info, err := b.upgradeInfo(context.Background(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.LastError != "" {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+// abandonUpgrade makes the upgrade canary look like the canary of an upgrade
+// whose instance stopped, so that the next instance resumes it at once.
+func abandonUpgrade(t *testing.T, s logical.Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	entry, err := s.Get(ctx, "test/upgrading")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		return
+	}
+
+	info := &UpgradeInfo{}
+	if err := proto.Unmarshal(entry.Value, info); err != nil {
+		t.Fatal(err)
+	}
+	info.HeartbeatTime, err = ptypes.TimestampProto(time.Now().Add(-2 * upgradeLeaseTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info.LastError = ""
+
+	buf, err := proto.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, &logical.StorageEntry{Key: entry.Key, Value: buf}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkChaosConsistency checks that the metadata of every key decodes and
+// that the stored entry of every version that is not destroyed is valid. If
+// state is set, it also checks that the current version of each key holds
+// one of its possible values.
+func checkChaosConsistency(t *testing.T, b *versionedKVBackend, s logical.Storage, state chaosState) {
+	t.Helper()
+	ctx := context.Background()
+
+	err := b.walkKeys(ctx, s, "", func(key string) error {
+		meta, err := b.getKeyMetadata(ctx, s, key)
+		if err != nil {
+			t.Fatalf("metadata of %s cannot be read: %v", key, err)
+		}
+		if meta == nil {
+			return nil
+		}
+
+		for verNum, vm := range meta.Versions {
+			if vm.Destroyed {
+				continue
+			}
+			if reason := b.versionIntegrityError(ctx, s, key, verNum, vm); reason != "" {
+				t.Fatalf("version %d of %s is inconsistent: %s", verNum, key, reason)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, values := range state {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "data/" + key,
+			Storage:   s,
+		})
+		if err != nil {
+			t.Fatalf("read of %s failed: %v", key, err)
+		}
+
+		var value string
+		if resp != nil && resp.Data["data"] != nil {
+			value = fmt.Sprint(resp.Data["data"].(map[string]interface{})["v"])
+		}
+		if !values[value] {
+			t.Fatalf("unexpected value %q of %s, expected one of %v", value, key, values)
+		}
+	}
+}
+
+// checkNoOrphanedEntries checks that every version entry of a key with
+// metadata is listed in the metadata, retained, or holds data shared with a
+// version that is.
+func checkNoOrphanedEntries(t *testing.T, b *versionedKVBackend, s logical.Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	err := b.walkVersionEntries(ctx, s, func(storageKey string, v *Version) error {
+		if v == nil || v.Key == "" {
+			return nil
+		}
+
+		meta, err := b.getKeyMetadata(ctx, s, v.Key)
+		if err != nil {
+			return err
+		}
+		if meta == nil {
+			return nil
+		}
+		if meta.referencesVersion(v.Version) {
+			return nil
+		}
+
+		t.Fatalf("orphaned entry %s of version %d of %s", strings.TrimPrefix(storageKey, b.storagePrefix), v.Version, v.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// clock is the source of the current time of the backend. Handlers read the
+// time from the clock of the backend rather than from the time package, so
+// that tests can control it.
+type clock interface {
+	Now() time.Time
+}
+
+// systemClock is the clock of the backend outside of tests, returning the
+// system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// now returns the current time of the backend's clock.
+func (b *versionedKVBackend) now() time.Time {
+	return b.clock.Now()
+}
+
+// timestampNow returns the current time of the backend's clock as a
+// protobuf timestamp, like ptypes.TimestampNow. If the clock's time can not
+// be represented as a timestamp, the wall clock time is returned instead.
+func (b *versionedKVBackend) timestampNow() *timestamp.Timestamp {
+	ts, err := ptypes.TimestampProto(b.now())
+	if err != nil {
+		return ptypes.TimestampNow()
+	}
+	return ts
+}
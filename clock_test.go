@@ -0,0 +1,138 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// fakeClock is a clock whose time only changes when advanced.
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestVersionedKV_Clock(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	c := &fakeClock{now: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b.(*versionedKVBackend).clock = c
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data":    map[string]interface{}{"bar": "baz"},
+			"options": map[string]interface{}{"delete_version_after": "1h"},
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("write failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["created_time"] != "2030-01-01T00:00:00Z" || resp.Data["deletion_time"] != "2030-01-01T01:00:00Z" {
+		t.Fatalf("expected the times of the version to come from the clock: %#v", resp.Data)
+	}
+
+	read := func() *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+		})
+		if err != nil {
+			t.Fatalf("read failed, err: %s, resp %#v", err, resp)
+		}
+		return resp
+	}
+
+	c.advance(59 * time.Minute)
+	if resp := read(); resp == nil || resp.Data["data"] == nil {
+		t.Fatalf("expected the version to be readable before its deletion time: %#v", resp)
+	}
+
+	c.advance(2 * time.Minute)
+	if resp := read(); resp == nil || resp.Data[logical.HTTPStatusCode] != http.StatusNotFound {
+		t.Fatalf("expected the version to be deleted after its deletion time: %#v", resp)
+	}
+}
+
+func TestVersionedKV_Clock_RetainPrunedVersions(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	// The clock is well in the past of the wall clock, so that the deletion
+	// time of the pruned version is only in the future of the clock.
+	kvb.clock = &fakeClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_versions":           1,
+			"delete_version_after":   "1h",
+			"retain_pruned_versions": true,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config write failed, err: %s, resp %#v", err, resp)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("write failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	meta, err := kvb.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := meta.RetainedVersions[1]; !ok {
+		t.Fatalf("expected version 1 to be retained by the time of the clock, retained: %#v", meta.RetainedVersions)
+	}
+}
+
+func TestVersionedKV_Clock_InvalidTimestamp(t *testing.T) {
+	b, _ := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+
+	// Timestamps can not represent times after the year 9999.
+	kvb.clock = &fakeClock{now: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	if _, err := ptypes.Timestamp(kvb.timestampNow()); err != nil {
+		t.Fatalf("expected a valid timestamp, err: %s", err)
+	}
+}
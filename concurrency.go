@@ -0,0 +1,102 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// keyPaths are the first path segments of the endpoints handling requests
+// about a single key, which is the rest of the path.
+var keyPaths = []string{
+	"data", "metadata", "delete", "undelete", "destroy", "subkeys", "v1-data",
+	"provision", "restore", "rollback", "changelog", "copy", "move", "diff",
+	"import", "export",
+}
+
+// concurrencyLimiter bounds the number of requests about keys under a prefix
+// handled at the same time.
+type concurrencyLimiter struct {
+	slots chan struct{}
+}
+
+// parseConcurrencyLimits validates the concurrency_limits setting of the
+// config. Each limit must be a positive integer.
+func parseConcurrencyLimits(raw map[string]interface{}) (map[string]uint32, error) {
+	limits := make(map[string]uint32, len(raw))
+	for prefix, v := range raw {
+		limit, err := strconv.ParseUint(fmt.Sprint(v), 10, 32)
+		if err != nil || limit == 0 {
+			return nil, fmt.Errorf("invalid concurrency_limits for prefix %q: must be a positive integer", prefix)
+		}
+		limits[prefix] = uint32(limit)
+	}
+
+	return limits, nil
+}
+
+// concurrencyLimit returns the most specific prefix of the
+// concurrency_limits matching the key and its limit. False is returned if
+// requests about the key are not limited.
+func (c *Configuration) concurrencyLimit(key string) (string, uint32, bool) {
+	var matched string
+	var limit uint32
+	for prefix, l := range c.ConcurrencyLimits {
+		if strings.HasPrefix(key, prefix) && (limit == 0 || len(prefix) > len(matched)) {
+			matched, limit = prefix, l
+		}
+	}
+
+	return matched, limit, limit > 0
+}
+
+// requestKey returns the key a request is about, or false if the request is
+// not about a single key.
+func requestKey(req *logical.Request) (string, bool) {
+	segment, key, ok := strings.Cut(req.Path, "/")
+	if !ok {
+		return "", false
+	}
+
+	for _, p := range keyPaths {
+		if segment == p {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// acquireConcurrencySlot reserves a slot to handle a request about the key
+// under the concurrency_limits of the config. It returns the function
+// releasing the slot, or false along with the matching prefix if the limit
+// of the prefix is reached.
+func (b *versionedKVBackend) acquireConcurrencySlot(config *Configuration, key string) (func(), string, bool) {
+	prefix, limit, ok := config.concurrencyLimit(key)
+	if !ok {
+		return func() {}, "", true
+	}
+
+	// Changing the limit of a prefix replaces its limiter, requests holding
+	// a slot of the previous limiter release it there.
+	b.concurrencyLock.Lock()
+	l := b.concurrencyLimiters[prefix]
+	if l == nil || cap(l.slots) != int(limit) {
+		l = &concurrencyLimiter{slots: make(chan struct{}, limit)}
+		b.concurrencyLimiters[prefix] = l
+	}
+	b.concurrencyLock.Unlock()
+
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, prefix, true
+	default:
+		incrCounter(config, []string{"secrets", "kv", "concurrency_throttled"}, 1, metrics.Label{Name: "prefix", Value: prefix})
+		return nil, prefix, false
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_ConcurrencyLimits(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"concurrency_limits": map[string]interface{}{
+				"tenant-a/":       1,
+				"tenant-a/batch/": 2,
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	config, err := kv.config(context.Background(), storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hold the only slot of tenant-a/
+	release, _, ok := kv.acquireConcurrencySlot(config, "tenant-a/foo")
+	if !ok {
+		t.Fatal("expected a slot to be available")
+	}
+
+	for path, limited := range map[string]bool{
+		"data/tenant-a/foo":       true,
+		"metadata/tenant-a/bar":   true,
+		"data/tenant-a/batch/foo": false,
+		"data/tenant-b/foo":       false,
+	} {
+		req = &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		}
+
+		resp, err = b.HandleRequest(context.Background(), req)
+		if limited {
+			if err != logical.ErrRateLimitQuotaExceeded || resp == nil || !resp.IsError() {
+				t.Fatalf("expected request to %s to be throttled, err: %s, resp %#v", path, err, resp)
+			}
+		} else if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("request to %s failed, err: %s, resp %#v", path, err, resp)
+		}
+	}
+
+	release()
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/tenant-a/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"bar": "baz"},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed after the slot was released, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"concurrency_limits": map[string]interface{}{
+				"tenant-a/": 0,
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a zero limit to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
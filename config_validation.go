@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// changedConfigFields returns the sorted names of the settings whose value
+// differs between the config data before and after a write, as returned by
+// configData.
+func changedConfigFields(before, after map[string]interface{}) []string {
+	changed := []string{}
+	for name, value := range after {
+		if !reflect.DeepEqual(before[name], value) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// validateCombinations returns an error if settings of the config that
+// depend on each other are inconsistent. Only the combinations including a
+// changed setting are checked, so that a config written before a check was
+// added can still be changed in other ways.
+func (c *Configuration) validateCombinations(changed []string) error {
+	isChanged := make(map[string]bool, len(changed))
+	for _, name := range changed {
+		isChanged[name] = true
+	}
+	anyChanged := func(names ...string) bool {
+		for _, name := range names {
+			if isChanged[name] {
+				return true
+			}
+		}
+		return false
+	}
+
+	if anyChanged("min_versions", "max_versions") && c.MaxVersions > 0 && c.MinVersions > c.MaxVersions {
+		return fmt.Errorf("min_versions (%d) cannot be greater than max_versions (%d)", c.MinVersions, c.MaxVersions)
+	}
+
+	if anyChanged("delete_version_after", "destroy_version_after") {
+		dva, dvf := deleteVersionAfter(c), durationOrZero(c.GetDestroyVersionAfter())
+		if dva > 0 && dvf > 0 && dvf < dva {
+			return fmt.Errorf("destroy_version_after (%s) cannot be shorter than delete_version_after (%s), versions would be destroyed before they are deleted", dvf, dva)
+		}
+	}
+
+	// These settings are applied by the expiry scan only
+	if durationOrZero(c.GetExpiryScanInterval()) <= 0 {
+		if anyChanged("max_version_age", "expiry_scan_interval") && durationOrZero(c.GetMaxVersionAge()) > 0 {
+			return fmt.Errorf("max_version_age requires expiry_scan_interval to be set")
+		}
+		if anyChanged("archive_after", "expiry_scan_interval") && durationOrZero(c.GetArchiveAfter()) > 0 {
+			return fmt.Errorf("archive_after requires expiry_scan_interval to be set")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// consistencyTokenPrefix prefixes the consistency tokens, so that the format
+// of the tokens can change.
+const consistencyTokenPrefix = "kvc1."
+
+// consistencyToken returns the token returned by writes of a version of a
+// key. It encodes the version and a hash of the key, so that reads can
+// require the version to be visible and tokens are not mixed up between keys.
+func consistencyToken(key string, version uint64) string {
+	sum := sha256.Sum256([]byte(key))
+	payload := strconv.FormatUint(version, 10) + ":" + hex.EncodeToString(sum[:8])
+	return consistencyTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(payload))
+}
+
+// parseConsistencyToken returns the version of the key encoded in a token
+// returned by consistencyToken.
+func parseConsistencyToken(key, token string) (uint64, error) {
+	invalid := errors.New("invalid consistency_token")
+
+	encoded, ok := strings.CutPrefix(token, consistencyTokenPrefix)
+	if !ok {
+		return 0, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, invalid
+	}
+	versionRaw, keyHash, ok := strings.Cut(string(payload), ":")
+	if !ok {
+		return 0, invalid
+	}
+	version, err := strconv.ParseUint(versionRaw, 10, 64)
+	if err != nil {
+		return 0, invalid
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	if keyHash != hex.EncodeToString(sum[:8]) {
+		return 0, errors.New("the consistency_token was returned by a write of another secret")
+	}
+	return version, nil
+}
+
+// requiredMinVersion returns the version a read requires the current version
+// of the key to have reached, from its min_version and consistency_token
+// parameters. Zero is returned if the read requires none.
+func requiredMinVersion(key string, data *framework.FieldData) (uint64, error) {
+	minVersion := data.Get("min_version").(int)
+	if minVersion < 0 {
+		return 0, errors.New("min_version cannot be negative")
+	}
+
+	required := uint64(minVersion)
+	if token := data.Get("consistency_token").(string); token != "" {
+		version, err := parseConsistencyToken(key, token)
+		if err != nil {
+			return 0, err
+		}
+		if version > required {
+			required = version
+		}
+	}
+	return required, nil
+}
+
+// minVersionNotReached returns the 412 response of a read requiring a version
+// of the key this server does not have yet, such as a performance replica
+// that has not caught up with a write to the primary. The client should retry
+// the read.
+func minVersionNotReached(config *Configuration, req *logical.Request, key string, version uint64) (*logical.Response, error) {
+	incrCounter(config, []string{"secrets", "kv", "min_version_not_reached"}, 1)
+
+	resp := &logical.Response{}
+	resp.AddWarning(fmt.Sprintf("version %d of secret %q is not available on this server yet, retry the read", version, key))
+	return logical.RespondWithStatusCode(resp, req, http.StatusPreconditionFailed)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestConsistencyToken(t *testing.T) {
+	token := consistencyToken("foo/bar", 42)
+	version, err := parseConsistencyToken("foo/bar", token)
+	if err != nil || version != 42 {
+		t.Fatalf("expected version 42, got %d, err: %v", version, err)
+	}
+
+	if _, err := parseConsistencyToken("foo/baz", token); err == nil {
+		t.Fatal("expected a token of another key to be rejected")
+	}
+	for _, token := range []string{"", "42", consistencyTokenPrefix + "!!", consistencyTokenPrefix + "NDI"} {
+		if _, err := parseConsistencyToken("foo/bar", token); err == nil {
+			t.Fatalf("expected token %q to be rejected", token)
+		}
+	}
+}
+
+func TestVersionedKV_Data_MinVersion(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	write := func(operation logical.Operation, data map[string]interface{}) string {
+		t.Helper()
+		req := &logical.Request{
+			Operation: operation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data:      map[string]interface{}{"data": data},
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s failed, err: %s, resp %#v", operation, err, resp)
+		}
+		schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route(req.Path), req.Operation), resp, true)
+		return resp.Data["consistency_token"].(string)
+	}
+	read := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	expectNotReached := func(resp *logical.Response, err error) {
+		t.Helper()
+		if err != nil || resp == nil || resp.Data[logical.HTTPStatusCode] != http.StatusPreconditionFailed {
+			t.Fatalf("expected a 412 error, err: %v, resp: %#v", err, resp)
+		}
+	}
+
+	// A key not written yet has not reached any version
+	expectNotReached(read("data/foo", map[string]interface{}{"min_version": 1}))
+
+	token := write(logical.UpdateOperation, map[string]interface{}{"bar": "baz"})
+	resp, err := read("data/foo", map[string]interface{}{"consistency_token": token})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("unexpected data: %#v", resp.Data)
+	}
+
+	// A token of a later version, as returned by a write to another server,
+	// fails the read until the version is written
+	expectNotReached(read("data/foo", map[string]interface{}{"consistency_token": consistencyToken("foo", 2)}))
+	expectNotReached(read("data/foo", map[string]interface{}{"min_version": 2, "consistency_token": token}))
+
+	patchToken := write(logical.PatchOperation, map[string]interface{}{"bar": "qux"})
+	if patchToken != consistencyToken("foo", 2) {
+		t.Fatalf("unexpected patch consistency_token: %q", patchToken)
+	}
+	resp, err = read("data/foo", map[string]interface{}{"min_version": 2, "version": 1})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("read failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("expected version 1 to be read: %#v", resp.Data)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"min_version": -1},
+		{"consistency_token": "garbage"},
+		{"consistency_token": consistencyToken("other", 1)},
+	} {
+		resp, err := read("data/foo", data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an invalid request for %v, err: %v, resp: %#v", data, err, resp)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	dataFormatJSON = "json"
+	dataFormatYAML = "yaml"
+)
+
+// knownDataOptions is the list of option keys recognized by the data write
+// and patch endpoints.
+var knownDataOptions = []string{"cas", "format", "delete_version_after", "correlation_id", "template", "array_ops"}
+
+// dataOptions holds the parsed contents of the options map provided to the
+// data write and patch endpoints.
+type dataOptions struct {
+	// raw is the options map as provided in the request.
+	raw map[string]interface{}
+
+	// cas is the check-and-set version, only valid if casSet is true.
+	cas    uint64
+	casSet bool
+
+	// format is the encoding of the data provided in the request.
+	format string
+
+	// deleteVersionAfter overrides the delete_version_after of the key and
+	// the mount for the new version, nil if not set.
+	deleteVersionAfter *duration.Duration
+
+	// correlationID is the correlation ID supplied for the write, if any.
+	correlationID string
+
+	// template is the name of the template the key is created from, if
+	// any. Only the first data write of a key can set it.
+	template string
+
+	// arrayOps are the operations on array fields applied by a patch after
+	// the merge patch of the data.
+	arrayOps []*arrayOp
+
+	// unknown is the sorted list of option keys that are not recognized.
+	unknown []string
+}
+
+// parseDataOptions parses the options map of a data request. An error is
+// returned if a recognized option has an invalid value. Unrecognized options
+// are recorded rather than rejected so that the caller can decide how to
+// surface them.
+func parseDataOptions(data *framework.FieldData) (*dataOptions, error) {
+	opts := &dataOptions{
+		raw: map[string]interface{}{},
+	}
+
+	if optionsRaw, ok := data.GetOk("options"); ok {
+		opts.raw = optionsRaw.(map[string]interface{})
+	}
+
+	if casRaw, ok := opts.raw["cas"]; ok {
+		var cas int
+		if err := mapstructure.WeakDecode(casRaw, &cas); err != nil {
+			return nil, errors.New("error parsing check-and-set parameter")
+		}
+		opts.cas = uint64(cas)
+		opts.casSet = true
+	}
+
+	if formatRaw, ok := opts.raw["format"]; ok {
+		if err := mapstructure.WeakDecode(formatRaw, &opts.format); err != nil {
+			return nil, errors.New("error parsing format parameter")
+		}
+		if err := validateDataFormat(opts.format); err != nil {
+			return nil, err
+		}
+	}
+
+	if dvaRaw, ok := opts.raw["delete_version_after"]; ok {
+		dva, err := parseDurationSecond(dvaRaw)
+		if err != nil {
+			return nil, errors.New("error parsing delete_version_after parameter")
+		}
+		if dva < 0 {
+			return nil, errors.New("delete_version_after cannot be negative")
+		}
+		opts.deleteVersionAfter = ptypes.DurationProto(dva)
+	}
+
+	if idRaw, ok := opts.raw["correlation_id"]; ok {
+		if err := mapstructure.WeakDecode(idRaw, &opts.correlationID); err != nil {
+			return nil, errors.New("error parsing correlation_id parameter")
+		}
+		if err := validateCorrelationID(opts.correlationID); err != nil {
+			return nil, err
+		}
+	}
+
+	if templateRaw, ok := opts.raw["template"]; ok {
+		if err := mapstructure.WeakDecode(templateRaw, &opts.template); err != nil {
+			return nil, errors.New("error parsing template parameter")
+		}
+	}
+
+	if arrayOpsRaw, ok := opts.raw["array_ops"]; ok {
+		arrayOps, err := parseArrayOps(arrayOpsRaw)
+		if err != nil {
+			return nil, err
+		}
+		opts.arrayOps = arrayOps
+	}
+
+	for option := range opts.raw {
+		if !strutil.StrListContains(knownDataOptions, option) {
+			opts.unknown = append(opts.unknown, option)
+		}
+	}
+	sort.Strings(opts.unknown)
+
+	return opts, nil
+}
+
+// versionCorrelationID returns the correlation ID to record for the new
+// version, from the options if set or else from the request carried by the
+// context.
+func (o *dataOptions) versionCorrelationID(ctx context.Context) string {
+	if o.correlationID != "" {
+		return o.correlationID
+	}
+	return contextCorrelationID(ctx)
+}
+
+// checkNoTemplate returns an error if the template option is set on a request
+// that cannot create a key from a template.
+func (o *dataOptions) checkNoTemplate() error {
+	if o.template != "" {
+		return errors.New("the template option can only be set on the first data write of a key")
+	}
+	return nil
+}
+
+// checkNoArrayOps returns an error if the array_ops option is set on a
+// request that does not patch the data of a key.
+func (o *dataOptions) checkNoArrayOps() error {
+	if len(o.arrayOps) > 0 {
+		return errors.New("the array_ops option can only be set on patches")
+	}
+	return nil
+}
+
+// checkUnknown returns a warning describing the unrecognized options, or an
+// error if the engine's config enables strict_options. Both are empty if all
+// options were recognized.
+func (o *dataOptions) checkUnknown(config *Configuration) (string, error) {
+	if len(o.unknown) == 0 {
+		return "", nil
+	}
+
+	msg := fmt.Sprintf("unrecognized options: %s", strings.Join(o.unknown, ", "))
+	if config.StrictOptions {
+		return "", errors.New(msg)
+	}
+
+	return msg + "; these options were ignored", nil
+}
+
+// validateDataFormat returns an error if format is not a supported encoding
+// for secret data. An empty format is the default JSON encoding.
+func validateDataFormat(format string) error {
+	switch format {
+	case "", dataFormatJSON, dataFormatYAML:
+		return nil
+	default:
+		return fmt.Errorf("unsupported format %q, must be %q or %q", format, dataFormatJSON, dataFormatYAML)
+	}
+}
+
+// decodeYAMLRequestData replaces the data of a data write or patch request
+// with the document it encodes if the data is a string and the "format"
+// option is set to yaml. This must happen before the framework parses the
+// request, as the data field only accepts a map.
+func decodeYAMLRequestData(req *logical.Request) error {
+	if !strings.HasPrefix(req.Path, "data/") || req.Data == nil {
+		return nil
+	}
+
+	switch req.Operation {
+	case logical.CreateOperation, logical.UpdateOperation, logical.PatchOperation:
+	default:
+		return nil
+	}
+
+	options, ok := req.Data["options"].(map[string]interface{})
+	if !ok || options["format"] != dataFormatYAML {
+		return nil
+	}
+
+	dataYAML, ok := req.Data["data"].(string)
+	if !ok {
+		return nil
+	}
+
+	decoded, err := yamlToMap(dataYAML)
+	if err != nil {
+		return err
+	}
+
+	reqData := make(map[string]interface{}, len(req.Data))
+	for k, v := range req.Data {
+		reqData[k] = v
+	}
+	reqData["data"] = decoded
+	req.Data = reqData
+
+	return nil
+}
+
+// yamlToMap decodes a YAML mapping. The result is round tripped through JSON
+// so that it holds the same types as data provided in a JSON request, with
+// numbers as json.Number.
+func yamlToMap(data string) (map[string]interface{}, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, fmt.Errorf("error parsing YAML data: %w", err)
+	}
+
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("YAML data cannot be represented as JSON: %w", err)
+	}
+
+	var result map[string]interface{}
+	if err := jsonutil.DecodeJSON(buf, &result); err != nil || result == nil {
+		return nil, errors.New("YAML data must be a mapping")
+	}
+
+	return result, nil
+}
+
+// yamlValue returns the secret data value v with its json.Number values
+// replaced by YAML scalar nodes, so that they are encoded as numbers with
+// the precision they were stored with rather than as strings.
+func yamlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = yamlValue(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = yamlValue(e)
+		}
+		return l
+	default:
+		return v
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// contentHash returns the hash used to find versions of a key that hold the
+// same data.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sharedDataVersion returns the most recent version of the key that holds
+// its own copy of data with the given hash, or zero if there is none. A new
+// version with the same data stores a reference to that version instead of
+// another copy of the data.
+func (m *KeyMetadata) sharedDataVersion(hash string) uint64 {
+	var shared uint64
+	for verNum, vm := range m.Versions {
+		if vm.Destroyed || vm.DataVersion != 0 || vm.ContentHash != hash {
+			continue
+		}
+		if verNum > shared {
+			shared = verNum
+		}
+	}
+	return shared
+}
+
+// dataReferenced returns true if a version of the key that is not destroyed
+// references the stored data of version.
+func (m *KeyMetadata) dataReferenced(version uint64) bool {
+	for _, vm := range m.Versions {
+		if !vm.Destroyed && vm.DataVersion == version {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveVersionData loads the data of a version that references the data
+// of another version of the key.
+func (b *versionedKVBackend) resolveVersionData(ctx context.Context, s logical.Storage, key string, v *Version) error {
+	if v.DataVersion == 0 {
+		return nil
+	}
+
+	shared, err := b.getVersion(ctx, s, key, v.DataVersion)
+	if err != nil {
+		return err
+	}
+	if shared == nil {
+		return errors.New("could not find shared version data")
+	}
+
+	v.Data = shared.Data
+	v.DataVersion = 0
+	return nil
+}
+
+// deleteVersionData deletes the stored entry of a version that was destroyed
+// or pruned from the key metadata. The entry is kept while other versions
+// reference its data. If the version referenced the data of a version that
+// is no longer readable, that data is deleted once it is unreferenced.
+func (b *versionedKVBackend) deleteVersionData(ctx context.Context, s logical.Storage, key string, meta *KeyMetadata, version uint64) error {
+	if meta.dataReferenced(version) {
+		return nil
+	}
+
+	raw, storageKey, err := b.getVersionEntry(ctx, s, key, version)
+	if err != nil || raw == nil {
+		return err
+	}
+
+	if err := s.Delete(ctx, storageKey); err != nil {
+		return err
+	}
+
+	v := &Version{}
+	if err := proto.Unmarshal(raw.Value, v); err != nil || v.DataVersion == 0 {
+		return nil
+	}
+
+	if vm, ok := meta.Versions[v.DataVersion]; ok && !vm.Destroyed {
+		return nil
+	}
+	if _, ok := meta.RetainedVersions[v.DataVersion]; ok {
+		return nil
+	}
+
+	return b.deleteVersionData(ctx, s, key, meta, v.DataVersion)
+}
@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Data_Put_DeduplicateVersionData(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"deduplicate_version_data": true,
+			"max_versions":             2,
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	write := func(value string) {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": value,
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	stored := func(version uint64) *Version {
+		versionKey, err := b.(*versionedKVBackend).getVersionKey(context.Background(), "foo", version, storage)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := storage.Get(context.Background(), versionKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw == nil {
+			return nil
+		}
+
+		v, err := b.(*versionedKVBackend).unmarshalVersion(raw.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	write("one")
+	write("two")
+	write("one")
+
+	if v := stored(3); v == nil || v.Data != nil || v.DataVersion != 1 {
+		t.Fatalf("expected version 3 to reference the data of version 1, got: %#v", v)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "one" {
+		t.Fatalf("expected shared data to be read, got: %#v", resp.Data)
+	}
+
+	// Version 1 is pruned, but its data is kept for version 3
+	write("three")
+
+	if v := stored(1); v == nil {
+		t.Fatal("expected the data of version 1 to be kept while referenced")
+	}
+	if v := stored(2); v != nil {
+		t.Fatalf("expected version 2 to be cleaned up, got: %#v", v)
+	}
+
+	// Pruning version 3 removes the last reference to version 1
+	write("four")
+
+	for _, version := range []uint64{1, 3} {
+		if v := stored(version); v != nil {
+			t.Fatalf("expected version %d to be cleaned up, got: %#v", version, v)
+		}
+	}
+}
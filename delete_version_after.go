@@ -4,16 +4,28 @@
 package kv
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/sdk/framework"
 )
 
 // deletionTime returns the time of creation plus the duration of the
 // minimum non-zero value of mount or meta. If mount and meta are zero,
 // false is returned.
 func deletionTime(creation time.Time, mount, meta time.Duration) (time.Time, bool) {
+	// Negative values mean disabled at the mount, which callers check
+	// first, and are rejected for keys; either way they never apply.
+	if mount < 0 {
+		mount = 0
+	}
+	if meta < 0 {
+		meta = 0
+	}
 	if mount == 0 && meta == 0 {
 		return time.Time{}, false
 	}
@@ -27,6 +39,26 @@ func deletionTime(creation time.Time, mount, meta time.Duration) (time.Time, boo
 	return creation.Add(min), true
 }
 
+// versionDeletionTime returns the deletion time of a version of the key,
+// counted from creation. If override is set, it replaces the
+// delete_version_after of the mount and the key for the version, and an
+// override of zero keeps the version until it is deleted explicitly. If the
+// version is not deleted automatically, false is returned.
+func versionDeletionTime(creation time.Time, config *Configuration, meta *KeyMetadata, override *duration.Duration) (time.Time, bool) {
+	if override != nil {
+		dva, err := ptypes.Duration(override)
+		if err != nil || dva == 0 {
+			return time.Time{}, false
+		}
+		return creation.Add(dva), true
+	}
+
+	if config.IsDeleteVersionAfterDisabled() {
+		return time.Time{}, false
+	}
+	return deletionTime(creation, deleteVersionAfter(config), deleteVersionAfter(meta))
+}
+
 type deleteVersionAfterGetter interface {
 	GetDeleteVersionAfter() *duration.Duration
 }
@@ -42,17 +74,106 @@ func deleteVersionAfter(v deleteVersionAfterGetter) time.Duration {
 	return dva
 }
 
+// effectiveDeleteVersionAfter returns the delete_version_after that applies
+// to new versions of the key, taking the mount's config into account. Zero is
+// returned if new versions of the key are not deleted automatically.
+func effectiveDeleteVersionAfter(config *Configuration, meta *KeyMetadata) time.Duration {
+	if config.IsDeleteVersionAfterDisabled() {
+		return 0
+	}
+
+	var zero time.Time
+	dtime, ok := deletionTime(zero, deleteVersionAfter(config), deleteVersionAfter(meta))
+	if !ok {
+		return 0
+	}
+
+	return dtime.Sub(zero)
+}
+
 const (
 	disabled time.Duration = -1 * time.Second
 )
 
+// maxDeleteVersionAfter is the largest delete_version_after accepted if the
+// mount does not configure a lower max_delete_version_after. It keeps
+// deletion times well within the range of time.Duration.
+const maxDeleteVersionAfter = 100 * 365 * 24 * time.Hour
+
 // IsDeleteVersionAfterDisabled returns true if DeleteVersionAfter is
-// disabled.
+// disabled. Any negative value disables it, although only -1s is written.
 func (c *Configuration) IsDeleteVersionAfterDisabled() bool {
-	if deleteVersionAfter(c) == disabled {
-		return true
+	return deleteVersionAfter(c) < 0
+}
+
+// deleteVersionAfterLimit returns the largest delete_version_after accepted
+// for the mount, its keys and single versions.
+func (c *Configuration) deleteVersionAfterLimit() time.Duration {
+	if limit := durationOrZero(c.GetMaxDeleteVersionAfter()); limit > 0 && limit < maxDeleteVersionAfter {
+		return limit
+	}
+	return maxDeleteVersionAfter
+}
+
+// validateDeleteVersionAfter returns an error if the delete_version_after
+// named by field exceeds the limit of the mount.
+func (c *Configuration) validateDeleteVersionAfter(field string, dva time.Duration) error {
+	if limit := c.deleteVersionAfterLimit(); dva > limit {
+		return fmt.Errorf("%s of %s exceeds the maximum of %s", field, dva, limit)
+	}
+	return nil
+}
+
+// validateDeleteVersionAfter returns an error if the delete_version_after
+// option exceeds the limit of the mount.
+func (o *dataOptions) validateDeleteVersionAfter(config *Configuration) error {
+	if o.deleteVersionAfter == nil {
+		return nil
+	}
+	return config.validateDeleteVersionAfter("delete_version_after", durationOrZero(o.deleteVersionAfter))
+}
+
+// parseDurationSecond parses a duration with parseutil.ParseDurationSecond,
+// as the framework does for TypeDurationSecond fields, but returns an error
+// instead of a wrapped around duration if a number of seconds or days does
+// not fit in a time.Duration. Go duration strings that do not fit are already
+// rejected by parseutil.
+func parseDurationSecond(raw interface{}) (time.Duration, error) {
+	d, err := parseutil.ParseDurationSecond(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	unit, number := time.Second, raw
+	switch v := raw.(type) {
+	case string:
+		if strings.HasSuffix(v, "d") {
+			unit, number = 24*time.Hour, strings.TrimSuffix(v, "d")
+		}
+	case float64:
+		number = int64(v)
+	}
+	if n, err := parseutil.ParseInt(number); err == nil && int64(d/unit) != n {
+		return 0, fmt.Errorf("duration %v is out of range", raw)
+	}
+	return d, nil
+}
+
+// checkDurationFields returns an error if one of the duration fields of the
+// request does not fit in a time.Duration. The framework parses such values
+// without error, wrapping them around to unrelated and possibly negative
+// durations.
+func checkDurationFields(data *framework.FieldData, fields ...string) error {
+	for _, field := range fields {
+		raw, ok := data.Raw[field]
+		if !ok {
+			continue
+		}
+		if _, err := parseDurationSecond(raw); err != nil {
+			return fmt.Errorf("%s of %v exceeds the maximum of %s", field, raw, maxDeleteVersionAfter)
+		}
 	}
-	return false
+	return nil
 }
 
 // DisableDeleteVersionAfter disables DeleteVersionAfter.
@@ -6,6 +6,7 @@ package kv
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -125,7 +126,7 @@ func TestDeleteVersionAfter(t *testing.T) {
 				Data:      data,
 			}
 			resp, err := b.HandleRequest(context.Background(), req)
-			wantNoResponse(t, resp, err)
+			wantResponse(t, resp, err)
 
 			req = &logical.Request{
 				Operation: logical.ReadOperation,
@@ -163,6 +164,13 @@ func TestDeleteVersionAfter(t *testing.T) {
 			if want != got {
 				t.Fatalf("want delete_version_after: %v, got %v", want, got)
 			}
+			want, got = tt.want.String(), resp.Data["effective_delete_version_after"]
+			if want != got {
+				t.Fatalf("want effective_delete_version_after: %v, got %v", want, got)
+			}
+			if want, got := tt.mount == nd, resp.Data["delete_version_after_disabled"]; want != got {
+				t.Fatalf("want delete_version_after_disabled: %v, got %v", want, got)
+			}
 
 			data = map[string]interface{}{
 				"data": map[string]interface{}{
@@ -259,3 +267,293 @@ func TestDeleteVersionAfter(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteVersionAfter_WriteOption(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"delete_version_after": "720h",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	wantResponse(t, resp, err)
+
+	var tests = []struct {
+		option           interface{}
+		want             time.Duration
+		wantDeletionTime bool
+	}{
+		{nil, 720 * time.Hour, true},
+		{"10m", 10 * time.Minute, true},
+		{60, time.Minute, true},
+		{"0s", 0, false},
+	}
+	for i, tt := range tests {
+		data := map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		}
+		if tt.option != nil {
+			data["options"] = map[string]interface{}{
+				"delete_version_after": tt.option,
+			}
+		}
+		req = &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data:      data,
+		}
+		resp, err = b.HandleRequest(context.Background(), req)
+		wantResponse(t, resp, err)
+		if resp.Data["version"] != uint64(i+1) {
+			t.Fatalf("unexpected version %v", resp.Data["version"])
+		}
+		if !tt.wantDeletionTime {
+			if dtv := resp.Data["deletion_time"].(string); dtv != "" {
+				t.Fatalf("option %v: deletion_time %#v, want no deletion_time", tt.option, dtv)
+			}
+		} else if got := lifetime(t, resp.Data); got != tt.want {
+			t.Fatalf("option %v: diff between deletion_time and created_time %v, want %v", tt.option, got, tt.want)
+		}
+	}
+
+	// Undeleting a version keeps its override
+	for _, path := range []string{"delete/foo", "undelete/foo"} {
+		req = &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"versions": "2",
+			},
+		}
+		undeleteTime := time.Now()
+		resp, err = b.HandleRequest(context.Background(), req)
+		wantNoResponse(t, resp, err)
+
+		if path == "undelete/foo" {
+			req = &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      "metadata/foo",
+				Storage:   storage,
+			}
+			resp, err = b.HandleRequest(context.Background(), req)
+			wantResponse(t, resp, err)
+			got := untilDeletion(t, undeleteTime, resp.Data["versions"].(map[string]interface{})["2"].(map[string]interface{}))
+			if got < 10*time.Minute-time.Second || got > 10*time.Minute+time.Second {
+				t.Fatalf("after undelete, time until deletion %v, want %v", got, 10*time.Minute)
+			}
+		}
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+			"options": map[string]interface{}{
+				"delete_version_after": "-1h",
+			},
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a negative delete_version_after to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestDeleteVersionAfter_ProvisionOption(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"delete_version_after":     "720h",
+			"max_delete_version_after": "1000h",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	wantResponse(t, resp, err)
+
+	var tests = []struct {
+		option           interface{}
+		want             time.Duration
+		wantDeletionTime bool
+	}{
+		{nil, 720 * time.Hour, true},
+		{"10m", 10 * time.Minute, true},
+		{"0s", 0, false},
+	}
+	for _, tt := range tests {
+		data := map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		}
+		if tt.option != nil {
+			data["options"] = map[string]interface{}{
+				"delete_version_after": tt.option,
+			}
+		}
+		req = &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "provision/foo",
+			Storage:   storage,
+			Data:      data,
+		}
+		resp, err = b.HandleRequest(context.Background(), req)
+		wantResponse(t, resp, err)
+		if !tt.wantDeletionTime {
+			if dtv := resp.Data["deletion_time"].(string); dtv != "" {
+				t.Fatalf("option %v: deletion_time %#v, want no deletion_time", tt.option, dtv)
+			}
+		} else if got := lifetime(t, resp.Data); got != tt.want {
+			t.Fatalf("option %v: diff between deletion_time and created_time %v, want %v", tt.option, got, tt.want)
+		}
+	}
+
+	// The override is kept in the version metadata, so undeleting the
+	// version applies it again
+	for _, path := range []string{"delete/foo", "undelete/foo"} {
+		req = &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"versions": "2",
+			},
+		}
+		resp, err = b.HandleRequest(context.Background(), req)
+		wantNoResponse(t, resp, err)
+	}
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	wantResponse(t, resp, err)
+	if got := lifetime(t, resp.Data["versions"].(map[string]interface{})["2"].(map[string]interface{})); got > 10*time.Minute+time.Second {
+		t.Fatalf("after undelete, diff between deletion_time and created_time %v, want at most %v", got, 10*time.Minute)
+	}
+
+	for _, option := range []interface{}{"-1h", "2000h"} {
+		req = &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "provision/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": "baz",
+				},
+				"options": map[string]interface{}{
+					"delete_version_after": option,
+				},
+			},
+		}
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected delete_version_after %v to be rejected, err: %s, resp %#v", option, err, resp)
+		}
+	}
+}
+
+func TestDeleteVersionAfter_MaxBound(t *testing.T) {
+	b, storage := getBackend(t)
+
+	handle := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	// The framework itself rejects the fields of the metadata path that
+	// wrap around to a negative duration, returning no error with its
+	// error response
+	wantRejected := func(path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := handle(path, data)
+		if (err != nil && err != logical.ErrInvalidRequest) || resp == nil || !resp.IsError() {
+			t.Fatalf("expected write to %s with %v to be rejected, err: %s, resp %#v", path, data, err, resp)
+		}
+	}
+
+	// 300 years does not fit in a duration, nor does it pass the built-in
+	// bound in any unit
+	for _, dva := range []interface{}{"109500d", 9467280000, "2628000h", "1000000h"} {
+		wantRejected("config", map[string]interface{}{"delete_version_after": dva})
+		wantRejected("metadata/foo", map[string]interface{}{"delete_version_after": dva})
+	}
+
+	// 584 years in days wraps around to about 25 minutes, which only the
+	// checks of the paths catch
+	for path, data := range map[string]map[string]interface{}{
+		"config":        {"max_delete_version_after": "213504d"},
+		"metadata/foo":  {"delete_version_after": "213504d"},
+		"provision/foo": {"delete_version_after": "213504d", "data": map[string]interface{}{"bar": "baz"}},
+		"data/foo":      {"options": map[string]interface{}{"delete_version_after": "213504d"}, "data": map[string]interface{}{"bar": "baz"}},
+		"rollback/foo":  {"options": map[string]interface{}{"delete_version_after": 18446745600}, "version": 1},
+	} {
+		resp, err := handle(path, data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "delete_version_after") {
+			t.Fatalf("expected write to %s with %v to be rejected, err: %s, resp %#v", path, data, err, resp)
+		}
+	}
+
+	resp, err := handle("config", map[string]interface{}{
+		"delete_version_after":     "24h",
+		"max_delete_version_after": "720h",
+	})
+	wantResponse(t, resp, err)
+
+	wantRejected("config", map[string]interface{}{"delete_version_after": "1000h"})
+	wantRejected("config", map[string]interface{}{"max_delete_version_after": "12h"})
+	wantRejected("config", map[string]interface{}{"max_delete_version_after": "1000000h"})
+	wantRejected("metadata/foo", map[string]interface{}{"delete_version_after": "1000h"})
+	wantRejected("data/foo", map[string]interface{}{
+		"data":    map[string]interface{}{"bar": "baz"},
+		"options": map[string]interface{}{"delete_version_after": "1000h"},
+	})
+
+	resp, err = handle("metadata/foo", map[string]interface{}{"delete_version_after": "700h"})
+	wantNoResponse(t, resp, err)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	})
+	wantResponse(t, resp, err)
+	if resp.Data["max_delete_version_after"] != "720h0m0s" || resp.Data["delete_version_after_disabled"] != false {
+		t.Fatalf("unexpected config: %#v", resp.Data)
+	}
+
+	// Any negative duration disables delete_version_after and reads back
+	// the same way
+	resp, err = handle("config", map[string]interface{}{"delete_version_after": "-5h"})
+	wantResponse(t, resp, err)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	})
+	wantResponse(t, resp, err)
+	if resp.Data["delete_version_after"] != disabled.String() || resp.Data["delete_version_after_disabled"] != true {
+		t.Fatalf("unexpected config: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// validateReplacementPath returns an error if path cannot replace key.
+func validateReplacementPath(key, path string) error {
+	switch {
+	case path == "":
+		return nil
+	case strings.HasSuffix(path, "/"):
+		return errors.New("replacement_path must not end with a slash")
+	case path == key:
+		return errors.New("replacement_path must differ from the path of the secret")
+	}
+	return nil
+}
+
+// deprecationWarning returns the warning added to reads of a deprecated key.
+func (k *KeyMetadata) deprecationWarning() string {
+	if k.ReplacementPath == "" {
+		return fmt.Sprintf("secret %q is deprecated", k.Key)
+	}
+	return fmt.Sprintf("secret %q is deprecated, read %q instead", k.Key, k.ReplacementPath)
+}
+
+// noteDeprecatedRead adds the deprecation warning to the response of a read
+// of a deprecated key and sends a deprecated-read event. The event carries
+// the entity and address of the request, so the consumers still reading the
+// key can be found.
+func (b *versionedKVBackend) noteDeprecatedRead(ctx context.Context, s logical.Storage, meta *KeyMetadata, resp *logical.Response, path string) {
+	if !meta.Deprecated {
+		return
+	}
+
+	resp.AddWarning(meta.deprecationWarning())
+	b.emitEvent(ctx, s, "deprecated-read", path, "data/"+meta.Key, false,
+		"replacement_path", meta.ReplacementPath,
+	)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Deprecated(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/old",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata/old",
+			Data: map[string]interface{}{
+				"deprecated":       true,
+				"replacement_path": "new",
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	// A key cannot be its own replacement
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/old",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"replacement_path": "old",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected replacement_path naming the key itself to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/old",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("expected the data of the deprecated key, resp: %#v", resp)
+	}
+	if len(resp.Warnings) != 1 || resp.Warnings[0] != `secret "old" is deprecated, read "new" instead` {
+		t.Fatalf("unexpected warnings: %v", resp.Warnings)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/old",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["deprecated"] != true || resp.Data["replacement_path"] != "new" {
+		t.Fatalf("unexpected metadata: %#v", resp.Data)
+	}
+
+	req = &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "metadata/old",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"deprecated": false,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata PatchOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/old",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if len(resp.Warnings) != 0 {
+		t.Fatalf("expected no warnings once no longer deprecated, got %v", resp.Warnings)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/data-write", "data/old", "data/old"},
+		{"kv-v2/metadata-write", "metadata/old", "metadata/old"},
+		{"kv-v2/deprecated-read", "data/old", "data/old"},
+		{"kv-v2/metadata-patch", "metadata/old", "metadata/old"},
+	})
+	if got := events.eventsProcessed[2].Event.Metadata.Fields["replacement_path"].GetStringValue(); got != "new" {
+		t.Fatalf("expected the replacement_path in the event metadata, got %v", got)
+	}
+}
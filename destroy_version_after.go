@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// versionDestroyDue returns true if the version is not destroyed yet, but
+// the destroy_version_after of the mount or the key, counted from its
+// creation, has passed. Like for delete_version_after, the lower non-zero
+// value of the mount and the key applies.
+func versionDestroyDue(config *Configuration, meta *KeyMetadata, vm *VersionMetadata, now time.Time) bool {
+	if vm.Destroyed {
+		return false
+	}
+
+	created, err := ptypes.Timestamp(vm.CreatedTime)
+	if err != nil {
+		return false
+	}
+
+	destroyTime, ok := deletionTime(created, durationOrZero(config.GetDestroyVersionAfter()), durationOrZero(meta.GetDestroyVersionAfter()))
+	return ok && !destroyTime.After(now)
+}
+
+// dueDestroyVersions returns the sorted versions of the key that are due to
+// be destroyed by destroy_version_after.
+func (m *KeyMetadata) dueDestroyVersions(config *Configuration, now time.Time) []uint64 {
+	var due []uint64
+	for verNum, vm := range m.Versions {
+		if versionDestroyDue(config, m, vm, now) {
+			due = append(due, verNum)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i] < due[j] })
+	return due
+}
+
+// destroyDueVersions destroys the versions of the key that are due to be
+// destroyed by destroy_version_after. It is called while holding the lock of
+// the key, by writes before they add a version, and by the expiry scan.
+func (b *versionedKVBackend) destroyDueVersions(ctx context.Context, s logical.Storage, config *Configuration, meta *KeyMetadata, now time.Time) error {
+	return b.destroyVersions(ctx, s, meta, meta.dueDestroyVersions(config, now))
+}
+
+// destroyVersions marks the sorted versions of the key as destroyed, writing
+// the metadata before removing their data and sending a destroy event. It is
+// called while holding the lock of the key.
+func (b *versionedKVBackend) destroyVersions(ctx context.Context, s logical.Storage, meta *KeyMetadata, due []uint64) error {
+	if len(due) == 0 {
+		return nil
+	}
+
+	for _, verNum := range due {
+		meta.Versions[verNum].Destroyed = true
+	}
+
+	sequence := meta.nextEventSequence()
+	if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+		return err
+	}
+
+	for _, verNum := range due {
+		if err := b.deleteVersionData(ctx, s, meta.Key, meta, verNum); err != nil {
+			return err
+		}
+	}
+
+	marshaledVersions, err := json.Marshal(&due)
+	if err != nil {
+		return err
+	}
+	b.emitKeyEvent(ctx, s, meta, sequence, "destroy", "data/"+meta.Key, "", true,
+		"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+		"destroyed_versions", string(marshaledVersions),
+	)
+
+	return nil
+}
+
+// destroyDueVersionsOfKey takes the lock of the key and destroys its versions
+// that are due to be destroyed. Reads call it once they released their read
+// lock, after finding that the version they read is due.
+func (b *versionedKVBackend) destroyDueVersionsOfKey(ctx context.Context, s logical.Storage, key string, now time.Time) error {
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil || meta == nil {
+		return err
+	}
+
+	return b.destroyDueVersions(ctx, s, config, meta, now)
+}
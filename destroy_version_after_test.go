@@ -0,0 +1,229 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_DestroyVersionAfter(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata/foo",
+			Data: map[string]interface{}{
+				"destroy_version_after": "1h",
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "v1"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "v2"},
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	// Age both versions past the destroy_version_after
+	meta, err := kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := ptypes.TimestampProto(time.Now().Add(-2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.Versions[1].CreatedTime = created
+	meta.Versions[2].CreatedTime = created
+	if err := kv.writeKeyMetadata(ctx, storage, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	// A read reports the version as destroyed and destroys the due versions
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"version": 1,
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data[logical.HTTPStatusCode] != http.StatusNotFound {
+		t.Fatalf("expected a 404 response, got %#v", resp.Data)
+	}
+	if resp.Data["data"] != nil {
+		t.Fatalf("expected no data, got %#v", resp.Data["data"])
+	}
+
+	meta, err = kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, verNum := range []uint64{1, 2} {
+		if !meta.Versions[verNum].Destroyed {
+			t.Fatalf("expected version %d to be destroyed", verNum)
+		}
+		raw, _, err := kv.getVersionEntry(ctx, storage, "foo", verNum)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw != nil {
+			t.Fatalf("expected the data of version %d to be removed", verNum)
+		}
+	}
+
+	// New versions are kept until their own time passes
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"bar": "v3"},
+		},
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	// The expiry scan destroys versions that are not read or written
+	if err := kv.expireKey(ctx, storage, &Configuration{}, "foo", time.Now(), time.Now().Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	meta, err = kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Versions[3].Destroyed {
+		t.Fatal("expected version 3 to be destroyed by the expiry scan")
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/metadata-write", "metadata/foo", "metadata/foo"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/destroy", "data/foo", ""},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/destroy", "data/foo", ""},
+	})
+}
+
+func TestVersionedKV_DestroyVersionAfter_Config(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for _, path := range []string{"config", "metadata/foo"} {
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"destroy_version_after": "-1s",
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected a negative destroy_version_after to be rejected by %s, err: %s, resp %#v", path, err, resp)
+		}
+
+		req.Data["destroy_version_after"] = "24h"
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s UpdateOperation request failed, err: %s, resp %#v", path, err, resp)
+		}
+
+		req = &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+			Storage:   storage,
+		}
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s ReadOperation request failed, err: %s, resp %#v", path, err, resp)
+		}
+		if resp.Data["destroy_version_after"] != "24h0m0s" {
+			t.Fatalf("unexpected destroy_version_after read from %s: %v", path, resp.Data["destroy_version_after"])
+		}
+	}
+}
+
+// writeDestroyDueVersion writes two versions of foo under a mount
+// destroy_version_after of an hour, and advances the clock of the backend
+// until only version 1 is due to be destroyed.
+func writeDestroyDueVersion(t *testing.T, b logical.Backend, s logical.Storage, config map[string]interface{}) {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b.(*versionedKVBackend).clock = clock
+
+	configData := map[string]interface{}{"destroy_version_after": "1h"}
+	for k, v := range config {
+		configData[k] = v
+	}
+	requests := []*logical.Request{
+		{Operation: logical.UpdateOperation, Path: "config", Data: configData},
+		{Operation: logical.UpdateOperation, Path: "data/foo", Data: map[string]interface{}{"data": map[string]interface{}{"bar": "v1"}}},
+		{Operation: logical.UpdateOperation, Path: "data/foo", Data: map[string]interface{}{"data": map[string]interface{}{"bar": "v2"}}},
+	}
+	for i, req := range requests {
+		req.Storage = s
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+		if i == 1 {
+			clock.advance(30 * time.Minute)
+		}
+	}
+	clock.advance(45 * time.Minute)
+}
+
+// expectVersionDestroyed fails the test unless the version of the key is
+// destroyed and its data removed.
+func expectVersionDestroyed(t *testing.T, b logical.Backend, s logical.Storage, key string, verNum uint64) {
+	t.Helper()
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	meta, err := kv.getKeyMetadata(ctx, s, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta == nil || meta.Versions[verNum] == nil || !meta.Versions[verNum].Destroyed {
+		t.Fatalf("expected version %d of %s to be destroyed", verNum, key)
+	}
+	raw, _, err := kv.getVersionEntry(ctx, s, key, verNum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != nil {
+		t.Fatalf("expected the data of version %d of %s to be removed", verNum, key)
+	}
+}
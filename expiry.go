@@ -0,0 +1,266 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// periodicFunc is called by Vault core on a regular interval to run the
+// backend's background tasks.
+func (b *versionedKVBackend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	if atomic.LoadUint32(b.upgrading) == 1 {
+		return nil
+	}
+
+	s, done := b.startBackgroundTask(req.Storage)
+	defer done()
+
+	// A failing task does not keep the later tasks from running, their
+	// errors are returned together.
+	var errs *multierror.Error
+
+	// The integrity check only reads storage, so it also runs on
+	// performance secondaries, whose replicated storage can be corrupted
+	// independently of the primary.
+	now := b.now()
+	if err := b.integrityCheck(ctx, s, now); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	// The other background tasks modify storage, so only run them where
+	// writes are possible.
+	if b.perfSecondaryCheck() || b.System().ReplicationState().HasState(consts.ReplicationDRSecondary) {
+		return errs.ErrorOrNil()
+	}
+
+	if err := b.flushReadStats(ctx, s); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := b.expiryScan(ctx, s, now); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := b.storageUsageScan(ctx, s, now); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := b.periodicTidy(ctx, s, now); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// expiryScan walks every key in the mount once the configured
+// expiry_scan_interval has elapsed. Versions whose deletion time passed since
+// the previous scan have an expire event sent for them, so consumers learn of
+// the expiry even if the version is never read. Expired versions older than
+// the destroy_expired_after grace period and versions older than the
+// destroy_version_after are destroyed, versions older than
+// the max_version_age are pruned, and versions not read within the
+// archive_after are archived.
+//
+// The first scan after the backend is initialized only establishes the start
+// of the window; versions that expired before it are not reported. Keys that
+// fail to be processed are logged and skipped.
+func (b *versionedKVBackend) expiryScan(ctx context.Context, s logical.Storage, now time.Time) error {
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	interval := durationOrZero(config.GetExpiryScanInterval())
+	if interval <= 0 {
+		return nil
+	}
+
+	b.expiryScanLock.Lock()
+	defer b.expiryScanLock.Unlock()
+
+	since := b.lastExpiryScan
+	if since.IsZero() {
+		b.lastExpiryScan = now
+		return nil
+	}
+	if now.Sub(since) < interval {
+		return nil
+	}
+
+	err = b.walkKeys(ctx, s, "", func(key string) error {
+		// A key that cannot be processed, such as one whose metadata is
+		// corrupt, does not stop the scan of the other keys
+		if err := b.expireKey(ctx, s, config, key, since, now); err != nil {
+			b.Logger().Error("expiry scan skipped key", "key", key, "error", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("expiry scan failed: %w", err)
+	}
+
+	b.lastExpiryScan = now
+	return nil
+}
+
+// expireKey processes the versions of a single key for the expiry scan.
+func (b *versionedKVBackend) expireKey(ctx context.Context, s logical.Storage, config *Configuration, key string, since, now time.Time) error {
+	grace := durationOrZero(config.GetDestroyExpiredAfter())
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		return nil
+	}
+
+	var expired, destroyed []uint64
+	for verNum, vm := range meta.Versions {
+		if versionDestroyDue(config, meta, vm, now) {
+			vm.Destroyed = true
+			destroyed = append(destroyed, verNum)
+			continue
+		}
+		if vm.DeletionTime == nil || vm.Destroyed {
+			continue
+		}
+
+		deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+		if err != nil {
+			return err
+		}
+
+		if deletionTime.After(since) && !deletionTime.After(now) {
+			expired = append(expired, verNum)
+		}
+
+		if grace > 0 && !deletionTime.Add(grace).After(now) {
+			vm.Destroyed = true
+			destroyed = append(destroyed, verNum)
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool { return expired[i] < expired[j] })
+	sort.Slice(destroyed, func(i, j int) bool { return destroyed[i] < destroyed[j] })
+
+	oldestVersion := meta.OldestVersion
+	prunedTo := meta.pruneAgedVersions(maxVersionAge(config, meta), minVersions(config, meta), now, config.RetainPrunedVersions)
+
+	// The archived copies are written before the metadata, so the data
+	// stays readable if writing the metadata fails.
+	var archived []uint64
+	for _, verNum := range meta.archivableVersions(durationOrZero(config.GetArchiveAfter()), now) {
+		ok, err := b.archiveVersionData(ctx, s, config, key, verNum)
+		if err != nil {
+			return err
+		}
+		if ok {
+			meta.Versions[verNum].Archived = true
+			archived = append(archived, verNum)
+		}
+	}
+	sort.Slice(archived, func(i, j int) bool { return archived[i] < archived[j] })
+
+	var expireSequence, destroySequence, pruneSequence, archiveSequence uint64
+	if len(expired) > 0 {
+		expireSequence = meta.nextEventSequence()
+	}
+	if len(destroyed) > 0 {
+		destroySequence = meta.nextEventSequence()
+	}
+	if prunedTo > 0 {
+		pruneSequence = meta.nextEventSequence()
+	}
+	if len(archived) > 0 {
+		archiveSequence = meta.nextEventSequence()
+	}
+
+	if len(expired) > 0 || len(destroyed) > 0 || prunedTo > 0 || len(archived) > 0 {
+		// Write the metadata key before deleting the versions
+		if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+			return err
+		}
+
+		for _, verNum := range destroyed {
+			if err := b.deleteVersionData(ctx, s, key, meta, verNum); err != nil {
+				return err
+			}
+		}
+
+		for _, verNum := range archived {
+			versionKey, err := b.getVersionKey(ctx, key, verNum, s)
+			if err != nil {
+				return err
+			}
+			if err := s.Delete(ctx, versionKey); err != nil {
+				return err
+			}
+		}
+	}
+
+	if prunedTo > 0 {
+		if warning := b.cleanupOldVersions(ctx, s, key, meta, prunedTo); warning != "" {
+			b.Logger().Warn("failed to delete pruned versions", "key", key, "warning", warning)
+		}
+	}
+
+	if len(expired) > 0 {
+		marshaledVersions, err := json.Marshal(&expired)
+		if err != nil {
+			return err
+		}
+		b.emitKeyEvent(ctx, s, meta, expireSequence, "expire", "data/"+key, "", false,
+			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+			"expired_versions", string(marshaledVersions),
+		)
+	}
+
+	if len(destroyed) > 0 {
+		marshaledVersions, err := json.Marshal(&destroyed)
+		if err != nil {
+			return err
+		}
+		b.emitKeyEvent(ctx, s, meta, destroySequence, "destroy", "data/"+key, "", true,
+			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+			"destroyed_versions", string(marshaledVersions),
+		)
+	}
+
+	if prunedTo > 0 {
+		b.emitKeyEvent(ctx, s, meta, pruneSequence, "prune", "data/"+key, "", true,
+			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+			"previous_oldest_version", fmt.Sprintf("%d", oldestVersion),
+		)
+	}
+
+	if len(archived) > 0 {
+		marshaledVersions, err := json.Marshal(&archived)
+		if err != nil {
+			return err
+		}
+		b.emitKeyEvent(ctx, s, meta, archiveSequence, "archive", "data/"+key, "", false,
+			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+			"archived_versions", string(marshaledVersions),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_ExpiryScan(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"expiry_scan_interval":  "1m",
+				"destroy_expired_after": "1h",
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/nested/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	// The first scan only establishes the start of the window
+	start := time.Now()
+	if err := kv.expiryScan(ctx, storage, start); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "data/nested/foo",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data DeleteOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	// Scans within the interval are skipped
+	if err := kv.expiryScan(ctx, storage, start.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kv.expiryScan(ctx, storage, start.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kv.expiryScan(ctx, storage, start.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := kv.getKeyMetadata(ctx, storage, "nested/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Versions[1].Destroyed {
+		t.Fatalf("expected expired version to be destroyed, meta: %#v", meta)
+	}
+
+	meta, err = kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Versions[1].Destroyed {
+		t.Fatalf("expected live version to be retained, meta: %#v", meta)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", "config", "config"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/data-write", "data/nested/foo", "data/nested/foo"},
+		{"kv-v2/data-delete", "data/nested/foo", ""},
+		{"kv-v2/expire", "data/nested/foo", ""},
+		{"kv-v2/destroy", "data/nested/foo", ""},
+	})
+}
+
+func TestVersionedKV_ExpiryScan_MaxVersionAge(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"expiry_scan_interval": "1m",
+				"max_version_age":      "24h",
+				"min_versions":         2,
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata/keep",
+			Data: map[string]interface{}{
+				"min_versions": 4,
+			},
+		},
+	}
+	for _, key := range []string{"foo", "keep"} {
+		for i := 0; i < 4; i++ {
+			requests = append(requests, &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "data/" + key,
+				Data: map[string]interface{}{
+					"data": map[string]interface{}{"bar": i},
+				},
+			})
+		}
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	start := time.Now()
+	if err := kv.expiryScan(ctx, storage, start); err != nil {
+		t.Fatal(err)
+	}
+
+	// Versions younger than max_version_age are kept
+	if err := kv.expiryScan(ctx, storage, start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.Versions) != 4 {
+		t.Fatalf("expected 4 versions, got: %#v", meta.Versions)
+	}
+
+	if err := kv.expiryScan(ctx, storage, start.Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err = kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.Versions) != 2 || meta.Versions[3] == nil || meta.Versions[4] == nil || meta.OldestVersion != 3 {
+		t.Fatalf("expected versions 3 and 4 to be kept, got: %#v", meta)
+	}
+
+	for verNum := uint64(1); verNum <= 4; verNum++ {
+		versionKey, err := kv.getVersionKey(ctx, "foo", verNum, storage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := storage.Get(ctx, versionKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pruned := verNum < 3; pruned != (entry == nil) {
+			t.Fatalf("expected version %d data to be deleted: %t, got: %#v", verNum, pruned, entry)
+		}
+	}
+
+	meta, err = kv.getKeyMetadata(ctx, storage, "keep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.Versions) != 4 {
+		t.Fatalf("expected the min_versions of the key to keep 4 versions, got: %#v", meta.Versions)
+	}
+}
+
+func TestVersionedKV_ExpiryScan_CorruptKey(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"expiry_scan_interval":  "1m",
+				"destroy_expired_after": "1h",
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.DeleteOperation,
+			Path:      "data/foo",
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	// Metadata that cannot be decoded, sorted before the other key
+	wrapper, err := kv.getKeyEncryptor(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wrapper.Wrap(storage).Put(ctx, &logical.StorageEntry{Key: "a-corrupt", Value: []byte{0xff}}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(-time.Minute)
+	if err := kv.expiryScan(ctx, storage, start); err != nil {
+		t.Fatal(err)
+	}
+	end := start.Add(2 * time.Hour)
+	if err := kv.expiryScan(ctx, storage, end); err != nil {
+		t.Fatalf("expected the corrupt key to be skipped, err: %s", err)
+	}
+	if !kv.lastExpiryScan.Equal(end) {
+		t.Fatalf("expected the scan to complete, last scan %s", kv.lastExpiryScan)
+	}
+
+	meta, err := kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Versions[1].Destroyed {
+		t.Fatalf("expected the expired version to be destroyed despite the corrupt key, meta: %#v", meta)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// defaultIntegritySampleRate is the fraction of keys verified by each
+// integrity check if the config does not set integrity_sample_rate.
+const defaultIntegritySampleRate = 0.01
+
+// integritySampleRate returns the fraction of keys verified by each integrity
+// check.
+func (c *Configuration) integritySampleRate() float64 {
+	if c.IntegritySampleRate > 0 {
+		return c.IntegritySampleRate
+	}
+	return defaultIntegritySampleRate
+}
+
+// integrityCheck verifies the stored versions of a random sample of the keys
+// once the configured integrity_check_interval has elapsed, so that storage
+// corruption is found before a client reads the corrupted version. Failures
+// are reported by integrityFailure and do not stop the check.
+func (b *versionedKVBackend) integrityCheck(ctx context.Context, s logical.Storage, now time.Time) error {
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	interval := durationOrZero(config.GetIntegrityCheckInterval())
+	if interval <= 0 {
+		return nil
+	}
+
+	b.integrityCheckLock.Lock()
+	defer b.integrityCheckLock.Unlock()
+
+	if !b.lastIntegrityCheck.IsZero() && now.Sub(b.lastIntegrityCheck) < interval {
+		return nil
+	}
+
+	rate := config.integritySampleRate()
+	err = b.walkKeys(ctx, s, "", func(key string) error {
+		if !sampled(rate) {
+			return nil
+		}
+		return b.checkKeyIntegrity(ctx, s, config, key)
+	})
+	if err != nil {
+		return fmt.Errorf("integrity check failed: %w", err)
+	}
+
+	b.lastIntegrityCheck = now
+	return nil
+}
+
+// checkKeyIntegrity verifies that the metadata of the key decodes, and that
+// the stored entry of each version that is not destroyed exists, decodes,
+// belongs to the version and holds data matching its content hash. Versions
+// written before content hashes were recorded are only checked to decode.
+func (b *versionedKVBackend) checkKeyIntegrity(ctx context.Context, s logical.Storage, config *Configuration, key string) error {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil {
+		b.integrityFailure(ctx, s, config, key, 0, err.Error())
+		return nil
+	}
+	if meta == nil {
+		return nil
+	}
+
+	versions := make([]uint64, 0, len(meta.Versions))
+	for verNum, vm := range meta.Versions {
+		if !vm.Destroyed {
+			versions = append(versions, verNum)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	for _, verNum := range versions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if reason := b.versionIntegrityError(ctx, s, key, verNum, meta.Versions[verNum]); reason != "" {
+			b.integrityFailure(ctx, s, config, key, verNum, reason)
+		}
+	}
+
+	return nil
+}
+
+// versionIntegrityError returns why the stored entry of the version fails
+// verification, or an empty string if it passes.
+func (b *versionedKVBackend) versionIntegrityError(ctx context.Context, s logical.Storage, key string, version uint64, vm *VersionMetadata) string {
+	raw, _, err := b.getVersionEntry(ctx, s, key, version)
+	if err != nil {
+		return fmt.Sprintf("failed to read version entry: %s", err)
+	}
+	if raw == nil {
+		return "version entry is missing"
+	}
+
+	v := &Version{}
+	if err := proto.Unmarshal(raw.Value, v); err != nil {
+		return fmt.Sprintf("version entry could not be decoded: %s", err)
+	}
+	if v.Key != "" && (v.Key != key || v.Version != version) {
+		return fmt.Sprintf("version entry belongs to version %d of %q", v.Version, v.Key)
+	}
+
+	if v.DataVersion != 0 {
+		if err := b.resolveVersionData(ctx, s, key, v); err != nil {
+			return fmt.Sprintf("shared version data could not be read: %s", err)
+		}
+	}
+
+	data := map[string]interface{}{}
+	if err := jsonutil.DecodeJSON(v.Data, &data); err != nil {
+		return fmt.Sprintf("version data could not be decoded: %s", err)
+	}
+
+	if vm.ContentHash != "" && contentHash(v.Data) != vm.ContentHash {
+		return "version data does not match its content hash"
+	}
+
+	return ""
+}
+
+// integrityFailure reports a key or version that failed the integrity check
+// in the logs, the secrets.kv.integrity_failure metric and an
+// integrity-alert event. A version of zero reports the key metadata.
+func (b *versionedKVBackend) integrityFailure(ctx context.Context, s logical.Storage, config *Configuration, key string, version uint64, reason string) {
+	b.Logger().Error("integrity check failed", "key", key, "version", version, "reason", reason)
+	incrCounter(config, []string{"secrets", "kv", "integrity_failure"}, 1)
+
+	b.emitEvent(ctx, s, "integrity-alert", "data/"+key, "data/"+key, false,
+		"version", strconv.FormatUint(version, 10),
+		"reason", reason,
+	)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_IntegrityCheck(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"integrity_check_interval": "1m",
+				"integrity_sample_rate":    1,
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/nested/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	// Intact versions pass the check
+	start := time.Now()
+	if err := kv.integrityCheck(ctx, storage, start); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change the stored data of nested/foo without updating its content hash
+	versionKey, err := kv.getVersionKey(ctx, "nested/foo", 1, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := kv.getVersion(ctx, storage, "nested/foo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Data = []byte(`{"bar":"corrupted"}`)
+	buf, err := proto.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(ctx, &logical.StorageEntry{Key: versionKey, Value: buf}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Checks within the interval are skipped
+	if err := kv.integrityCheck(ctx, storage, start.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kv.integrityCheck(ctx, storage, start.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", configPath, configPath},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/data-write", "data/nested/foo", "data/nested/foo"},
+		{"kv-v2/integrity-alert", "data/nested/foo", "data/nested/foo"},
+	})
+	metadata := events.eventsProcessed[3].Event.Metadata.Fields
+	if got := metadata["version"].GetStringValue(); got != "1" {
+		t.Fatalf("expected the corrupted version in the event metadata, got %v", got)
+	}
+	if got := metadata["reason"].GetStringValue(); got != "version data does not match its content hash" {
+		t.Fatalf("unexpected reason: %v", got)
+	}
+}
+
+func TestVersionedKV_IntegrityCheck_Config(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"integrity_sample_rate": 2,
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an integrity_sample_rate over 1 to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("config ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["integrity_check_interval"] != "0s" || resp.Data["integrity_sample_rate"] != defaultIntegritySampleRate {
+		t.Fatalf("unexpected config: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"fmt"
+)
+
+// storedBytes returns the total size of the data stored for the versions of
+// the key that are not destroyed. Versions referencing the data of another
+// version share its stored entry, so that data is counted once. Retained
+// versions are no longer listed in the metadata and are not counted.
+func (k *KeyMetadata) storedBytes() uint64 {
+	var total uint64
+	counted := map[uint64]bool{}
+	for verNum, vm := range k.Versions {
+		if vm.Destroyed {
+			continue
+		}
+
+		dataVersion, size := verNum, vm.DataBytes
+		if vm.DataVersion != 0 {
+			dataVersion = vm.DataVersion
+			if shared, ok := k.Versions[vm.DataVersion]; ok && shared.DataBytes != 0 {
+				size = shared.DataBytes
+			}
+		}
+		if counted[dataVersion] {
+			continue
+		}
+		counted[dataVersion] = true
+		total += size
+	}
+	return total
+}
+
+// maxKeyBytes returns the max_key_bytes that applies to the key, the lower
+// non-zero value of the key and the mount, or zero if neither sets one.
+func maxKeyBytes(config *Configuration, meta *KeyMetadata) uint64 {
+	switch {
+	case meta.MaxKeyBytes == 0:
+		return config.MaxKeyBytes
+	case config.MaxKeyBytes == 0:
+		return meta.MaxKeyBytes
+	case meta.MaxKeyBytes < config.MaxKeyBytes:
+		return meta.MaxKeyBytes
+	default:
+		return config.MaxKeyBytes
+	}
+}
+
+// checkKeyBytes returns an error if the versions of the key hold more data
+// than its max_key_bytes. It is called after a new version is added to the
+// metadata, and the versions pruned by max_versions removed from it, but
+// before anything is written, so that the write can still be rejected.
+func checkKeyBytes(config *Configuration, meta *KeyMetadata) error {
+	limit := maxKeyBytes(config, meta)
+	if limit == 0 {
+		return nil
+	}
+
+	if total := meta.storedBytes(); total > limit {
+		return fmt.Errorf("the versions of the key would hold %d bytes of data, exceeding the max_key_bytes of %d", total, limit)
+	}
+	return nil
+}
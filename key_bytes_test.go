@@ -0,0 +1,159 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestKeyMetadata_StoredBytes(t *testing.T) {
+	meta := &KeyMetadata{
+		Versions: map[uint64]*VersionMetadata{
+			1: {DataBytes: 10},
+			2: {DataBytes: 20, Destroyed: true},
+			3: {DataBytes: 10, DataVersion: 1},
+			4: {DataVersion: 5},
+			5: {DataBytes: 30},
+		},
+	}
+
+	// Version 2 is destroyed, and versions 3 and 4 share the data of
+	// versions 1 and 5
+	if total := meta.storedBytes(); total != 40 {
+		t.Fatalf("unexpected stored bytes: %d", total)
+	}
+
+	for _, tc := range []struct {
+		config, key, expected uint64
+	}{
+		{0, 0, 0},
+		{100, 0, 100},
+		{0, 50, 50},
+		{100, 50, 50},
+		{50, 100, 50},
+	} {
+		meta := &KeyMetadata{MaxKeyBytes: tc.key}
+		if limit := maxKeyBytes(&Configuration{MaxKeyBytes: tc.config}, meta); limit != tc.expected {
+			t.Fatalf("expected limit %d for config %d and key %d, got %d", tc.expected, tc.config, tc.key, limit)
+		}
+	}
+}
+
+func TestVersionedKV_MaxKeyBytes(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	handle := func(req *logical.Request) (*logical.Response, error) {
+		t.Helper()
+		req.Storage = storage
+		return b.HandleRequest(ctx, req)
+	}
+	mustHandle := func(req *logical.Request) *logical.Response {
+		t.Helper()
+		resp, err := handle(req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+		return resp
+	}
+	// Each value is stored as 13 bytes of JSON
+	write := func(value string) *logical.Request {
+		return &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": value},
+			},
+		}
+	}
+	assertTotal := func(expected uint64) {
+		t.Helper()
+		resp := mustHandle(&logical.Request{Operation: logical.ReadOperation, Path: "metadata/foo"})
+		if resp.Data["total_bytes"] != expected {
+			t.Fatalf("expected total_bytes %d, got %v", expected, resp.Data["total_bytes"])
+		}
+	}
+
+	mustHandle(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Data: map[string]interface{}{
+			"max_key_bytes": 30,
+		},
+	})
+
+	mustHandle(write("one"))
+	mustHandle(write("two"))
+	assertTotal(26)
+
+	resp, err := handle(write("six"))
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the write to be rejected, err: %s, resp %#v", err, resp)
+	}
+	resp = mustHandle(&logical.Request{Operation: logical.ReadOperation, Path: "metadata/foo"})
+	if resp.Data["current_version"] != uint64(2) {
+		t.Fatalf("expected the rejected write not to add a version: %#v", resp.Data)
+	}
+
+	// Destroying a version frees its bytes
+	mustHandle(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "destroy/foo",
+		Data: map[string]interface{}{
+			"versions": []int{1},
+		},
+	})
+	assertTotal(13)
+	mustHandle(write("six"))
+	assertTotal(26)
+
+	// The lower limit of the key applies
+	mustHandle(&logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "metadata/foo",
+		Data: map[string]interface{}{
+			"max_key_bytes": 20,
+			"max_versions":  1,
+		},
+	})
+	resp = mustHandle(&logical.Request{Operation: logical.ReadOperation, Path: "metadata/foo"})
+	if resp.Data["max_key_bytes"] != uint64(20) {
+		t.Fatalf("unexpected max_key_bytes: %#v", resp.Data["max_key_bytes"])
+	}
+
+	// Versions pruned by max_versions do not count against the limit
+	mustHandle(write("ten"))
+	assertTotal(13)
+
+	resp, err = handle(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"bar": "a value longer than the limit"},
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the write to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	// Restored versions count against the limit as well
+	resp, err = handle(&logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "restore/baz",
+		Data: map[string]interface{}{
+			"version": 1,
+			"data":    map[string]interface{}{"bar": "a value longer than the limit"},
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the restore to be rejected, err: %s, resp %#v", err, resp)
+	}
+	resp, err = handle(&logical.Request{Operation: logical.ReadOperation, Path: "metadata/baz"})
+	if err != nil || resp != nil {
+		t.Fatalf("expected the rejected restore not to create the key, err: %s, resp %#v", err, resp)
+	}
+}
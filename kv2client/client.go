@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package kv2client is a typed client for the endpoints of a KV version 2
+// secrets engine, built on the Vault API client. It is maintained along with
+// the plugin, and its contract tests run it against the handlers of the
+// plugin, so that its requests and response types match the plugin of the
+// same release.
+package kv2client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/mapstructure"
+)
+
+// ErrNotFound is returned when the secret, or the version of the secret,
+// does not exist.
+var ErrNotFound = errors.New("secret not found")
+
+// ErrVersionNotAvailable is returned by reads requiring a version of the
+// secret that the server does not have yet, such as a performance replica
+// that has not caught up with a write, once the retries of the API client
+// are exhausted.
+var ErrVersionNotAvailable = errors.New("version not available yet")
+
+// Client calls the endpoints of a KV version 2 secrets engine mounted at a
+// path.
+type Client struct {
+	c     *api.Client
+	mount string
+}
+
+// New returns a client for the KV version 2 secrets engine mounted at mount,
+// using c to make the requests.
+func New(c *api.Client, mount string) *Client {
+	return &Client{
+		c:     c,
+		mount: strings.Trim(mount, "/"),
+	}
+}
+
+// path returns the API path of an endpoint of the mount for a secret path.
+func (c *Client) path(endpoint, secretPath string) string {
+	p := c.mount + "/" + endpoint
+	if secretPath = strings.Trim(secretPath, "/"); secretPath != "" {
+		p += "/" + secretPath
+	}
+	return p
+}
+
+// read reads an endpoint with the query parameters. It returns ErrNotFound
+// if the endpoint returned no data.
+func (c *Client) read(ctx context.Context, p string, query url.Values) (*api.Secret, error) {
+	secret, err := c.c.Logical().ReadWithDataWithContext(ctx, p, query)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, ErrNotFound
+	}
+	return secret, nil
+}
+
+// list lists an endpoint with the query parameters. A listing without keys
+// returns a nil secret.
+func (c *Client) list(ctx context.Context, p string, query url.Values) (*api.Secret, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("list", "true")
+	return c.c.Logical().ReadWithDataWithContext(ctx, p+"/", query)
+}
+
+// write writes the data to an endpoint, returning the data of the response
+// if any.
+func (c *Client) write(ctx context.Context, p string, data map[string]interface{}) (map[string]interface{}, error) {
+	secret, err := c.c.Logical().WriteWithContext(ctx, p, data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, nil
+	}
+	return secret.Data, nil
+}
+
+// decode decodes the data of a response into out, a pointer to one of the
+// response types of this package. Durations and times are decoded from
+// their string form, and an empty time as the zero time.
+func decode(data map[string]interface{}, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			stringToTimeHook,
+			mapstructure.StringToTimeDurationHookFunc(),
+		),
+		WeaklyTypedInput: true,
+		TagName:          "json",
+		Result:           out,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(data); err != nil {
+		return fmt.Errorf("error decoding response: %w", err)
+	}
+	return nil
+}
+
+// stringToTimeHook decodes the RFC 3339 times of responses, which are empty
+// if not set.
+func stringToTimeHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(time.Time{}) {
+		return data, nil
+	}
+	if data.(string) == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, data.(string))
+}
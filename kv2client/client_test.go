@@ -0,0 +1,569 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv2client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	kv "github.com/hashicorp/vault-plugin-secrets-kv"
+	"github.com/hashicorp/vault-plugin-secrets-kv/kv2client"
+)
+
+// newTestClient returns a client calling a KV version 2 backend mounted at
+// "secret" through an HTTP server translating requests as Vault does.
+func newTestClient(t *testing.T) *kv2client.Client {
+	t.Helper()
+
+	config := &logical.BackendConfig{
+		System:      &logical.StaticSystemView{},
+		StorageView: &logical.InmemStorage{},
+		BackendUUID: "test",
+	}
+	b, err := kv.VersionedKVFactory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("unable to create backend: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveBackend(t, b, config.StorageView, w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	apiConfig := api.DefaultConfig()
+	apiConfig.Address = server.URL
+	// Errors of the backend are not transient, retrying them only slows the
+	// tests down
+	apiConfig.MaxRetries = 0
+	c, err := api.NewClient(apiConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetToken("root")
+
+	return kv2client.New(c, "secret")
+}
+
+// serveBackend handles an HTTP request of the API client with the backend,
+// translating the request and its response like the logical handler of
+// Vault.
+func serveBackend(t *testing.T, b logical.Backend, s logical.Storage, w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/secret/")
+	query := r.URL.Query()
+
+	req := &logical.Request{
+		Path:       path,
+		Storage:    s,
+		MountPoint: "secret/",
+		Data:       map[string]interface{}{},
+	}
+	switch r.Method {
+	case http.MethodGet:
+		req.Operation = logical.ReadOperation
+		if query.Get("list") == "true" {
+			req.Operation = logical.ListOperation
+			query.Del("list")
+			if !strings.HasSuffix(req.Path, "/") {
+				req.Path += "/"
+			}
+		}
+	case http.MethodPost, http.MethodPut:
+		req.Operation = logical.UpdateOperation
+	case http.MethodPatch:
+		req.Operation = logical.PatchOperation
+	case http.MethodDelete:
+		req.Operation = logical.DeleteOperation
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	for k, v := range query {
+		if len(v) == 1 {
+			req.Data[k] = v[0]
+		} else {
+			req.Data[k] = v
+		}
+	}
+	body, err := io.ReadAll(r.Body)
+	if err == nil && len(body) > 0 {
+		err = json.Unmarshal(body, &req.Data)
+	}
+	if err != nil {
+		t.Errorf("error reading request body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp, err := b.HandleRequest(r.Context(), req)
+	if status, err := logical.RespondErrorCommon(req, resp, err); status != 0 {
+		var errs []string
+		if err != nil {
+			errs = []string{err.Error()}
+		}
+		writeJSON(t, w, status, map[string]interface{}{"errors": errs})
+		return
+	}
+
+	switch {
+	case resp == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case resp.Data[logical.HTTPStatusCode] != nil:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(resp.Data[logical.HTTPStatusCode].(int))
+		switch body := resp.Data[logical.HTTPRawBody].(type) {
+		case []byte:
+			w.Write(body)
+		case string:
+			io.WriteString(w, body)
+		}
+	default:
+		writeJSON(t, w, http.StatusOK, logical.LogicalResponseToHTTPResponse(resp))
+	}
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		t.Errorf("error writing response: %v", err)
+	}
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestClient_Data(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "foo", kv2client.GetOptions{}); !errors.Is(err, kv2client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	written, err := c.Put(ctx, "foo", map[string]interface{}{
+		"bar":  "baz",
+		"tags": []interface{}{"a"},
+	}, kv2client.WriteOptions{CAS: intPtr(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.Version != 1 || written.CreatedTime.IsZero() || !written.DeletionTime.IsZero() || written.ConsistencyToken == "" {
+		t.Fatalf("unexpected write result: %#v", written)
+	}
+	if _, err := c.Get(ctx, "foo", kv2client.GetOptions{ConsistencyToken: written.ConsistencyToken}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "foo", kv2client.GetOptions{MinVersion: 2}); !errors.Is(err, kv2client.ErrVersionNotAvailable) {
+		t.Fatalf("expected ErrVersionNotAvailable, got %v", err)
+	}
+
+	if _, err := c.Put(ctx, "foo", map[string]interface{}{"bar": "qux"}, kv2client.WriteOptions{CAS: intPtr(0)}); err == nil {
+		t.Fatal("expected a check-and-set error")
+	}
+
+	patched, err := c.Patch(ctx, "foo", map[string]interface{}{"bar": "qux"}, kv2client.PatchOptions{
+		ArrayOps: []kv2client.ArrayOp{{Op: "append", Field: "tags", Value: "b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Version != 2 {
+		t.Fatalf("expected version 2, got %d", patched.Version)
+	}
+
+	secret, err := c.Get(ctx, "foo", kv2client.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"bar":  "qux",
+		"tags": []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(secret.Data, expected) {
+		t.Fatalf("expected data %v, got %v", expected, secret.Data)
+	}
+	if secret.Metadata.Version != 2 {
+		t.Fatalf("expected version 2, got %d", secret.Metadata.Version)
+	}
+
+	subkeys, err := c.Subkeys(ctx, "foo", kv2client.SubkeysOptions{Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(subkeys.Subkeys, map[string]interface{}{"bar": nil, "tags": nil}) {
+		t.Fatalf("unexpected subkeys: %v", subkeys.Subkeys)
+	}
+	subkeys, err = c.Subkeys(ctx, "foo", kv2client.SubkeysOptions{Keys: []string{"tags", "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(subkeys.Subkeys, map[string]interface{}{"tags": nil}) {
+		t.Fatalf("unexpected subkeys: %v", subkeys.Subkeys)
+	}
+	subkeys, err = c.Subkeys(ctx, "foo", kv2client.SubkeysOptions{Keys: []string{"bar"}, IncludeTypes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(subkeys.Subkeys, map[string]interface{}{"bar": map[string]interface{}{"type": "string", "bytes": json.Number("3")}}) {
+		t.Fatalf("unexpected subkeys: %v", subkeys.Subkeys)
+	}
+
+	if err := c.DeleteVersions(ctx, "foo", []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err = c.Get(ctx, "foo", kv2client.GetOptions{Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data != nil || secret.Metadata.DeletionTime.IsZero() {
+		t.Fatalf("expected a deleted version, got %#v", secret)
+	}
+
+	if err := c.Undelete(ctx, "foo", []int{1}, false); err != nil {
+		t.Fatal(err)
+	}
+	rolledBack, err := c.Rollback(ctx, "foo", 1, kv2client.WriteOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolledBack.Version != 3 {
+		t.Fatalf("expected version 3, got %d", rolledBack.Version)
+	}
+
+	if err := c.Destroy(ctx, "foo", []int{1}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err = c.Get(ctx, "foo", kv2client.GetOptions{Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data != nil || !secret.Metadata.Destroyed {
+		t.Fatalf("expected a destroyed version, got %#v", secret)
+	}
+}
+
+func TestClient_Metadata(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	maxVersions := 5
+	deleteAfter := time.Hour
+	err := c.WriteMetadata(ctx, "app/foo", kv2client.MetadataParams{
+		MaxVersions:        &maxVersions,
+		DeleteVersionAfter: &deleteAfter,
+		CustomMetadata:     map[string]string{"env": "prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Put(ctx, "app/foo", map[string]interface{}{"bar": "baz"}, kv2client.WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.PatchMetadata(ctx, "app/foo", kv2client.MetadataParams{
+		CustomMetadata: map[string]string{"team": "a"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := c.ReadMetadata(ctx, "app/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.MaxVersions != 5 || meta.DeleteVersionAfter != time.Hour || meta.CurrentVersion != 1 {
+		t.Fatalf("unexpected metadata: %#v", meta)
+	}
+	if !reflect.DeepEqual(meta.CustomMetadata, map[string]string{"env": "prod", "team": "a"}) {
+		t.Fatalf("unexpected custom metadata: %v", meta.CustomMetadata)
+	}
+	if v, ok := meta.Versions[1]; !ok || v.CreatedTime.IsZero() {
+		t.Fatalf("expected the metadata of version 1, got %v", meta.Versions)
+	}
+
+	byID, err := c.ReadByID(ctx, meta.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byID.Path != "app/foo" || byID.CurrentVersion != 1 {
+		t.Fatalf("unexpected key by ID: %#v", byID)
+	}
+
+	if _, err := c.ReadMetadata(ctx, "app/missing"); !errors.Is(err, kv2client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := c.DeleteMetadata(ctx, "app/foo", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadMetadata(ctx, "app/foo"); !errors.Is(err, kv2client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := c.ReadByID(ctx, meta.ID); !errors.Is(err, kv2client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClient_SoftDelete(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	if _, err := c.WriteConfig(ctx, map[string]interface{}{"trash_retention": "24h"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Put(ctx, "app/foo", map[string]interface{}{"bar": "baz"}, kv2client.WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SoftDeleteMetadata(ctx, "app/foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadMetadata(ctx, "app/foo"); !errors.Is(err, kv2client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := c.UndeleteMetadata(ctx, "app/foo"); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := c.Get(ctx, "app/foo", kv2client.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["bar"] != "baz" {
+		t.Fatalf("unexpected data: %#v", secret.Data)
+	}
+
+	if err := c.UndeleteMetadata(ctx, "app/missing"); !errors.Is(err, kv2client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c", "d/e"} {
+		if _, err := c.Put(ctx, key, map[string]interface{}{"bar": "baz"}, kv2client.WriteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.WriteMetadata(ctx, "b", kv2client.MetadataParams{
+		CustomMetadata: map[string]string{"env": "prod"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := c.List(ctx, "", kv2client.ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(page.Keys, []string{"a", "b"}) || page.NextAfter != "b" {
+		t.Fatalf("unexpected first page: %#v", page)
+	}
+	page, err = c.List(ctx, "", kv2client.ListOptions{Limit: 2, After: page.NextAfter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(page.Keys, []string{"c", "d/"}) || page.NextAfter != "" {
+		t.Fatalf("unexpected second page: %#v", page)
+	}
+
+	page, err = c.List(ctx, "", kv2client.ListOptions{Filter: map[string]string{"env": "prod"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(page.Keys, []string{"b"}) {
+		t.Fatalf("unexpected filtered keys: %v", page.Keys)
+	}
+
+	page, err = c.List(ctx, "missing", kv2client.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Keys) != 0 {
+		t.Fatalf("expected no keys, got %v", page.Keys)
+	}
+
+	page, err = c.Search(ctx, kv2client.SearchOptions{Glob: "**e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(page.Keys, []string{"d/e"}) {
+		t.Fatalf("unexpected search result: %v", page.Keys)
+	}
+}
+
+func TestClient_ExportImport(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	for _, v := range []string{"one", "two"} {
+		if _, err := c.Put(ctx, "foo", map[string]interface{}{"bar": v}, kv2client.WriteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.DeleteVersions(ctx, "foo", []int{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := c.Export(ctx, "foo", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.CurrentVersion != 2 || len(export.Versions) != 2 {
+		t.Fatalf("unexpected export: %#v", export)
+	}
+	if export.Versions[0].DeletionTime.IsZero() {
+		t.Fatalf("expected version 1 to be deleted: %#v", export.Versions[0])
+	}
+
+	imported, err := c.Import(ctx, "bar", export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.CurrentVersion != 2 || imported.ImportedVersions != 2 {
+		t.Fatalf("unexpected import result: %#v", imported)
+	}
+
+	secret, err := c.Get(ctx, "bar", kv2client.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["bar"] != "two" || !secret.Metadata.CreatedTime.Equal(export.Versions[1].CreatedTime) {
+		t.Fatalf("unexpected imported secret: %#v", secret)
+	}
+
+	copied, err := c.Copy(ctx, "bar", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.Destination != "baz" || copied.CurrentVersion != 2 {
+		t.Fatalf("unexpected copy result: %#v", copied)
+	}
+
+	changes, err := c.Changelog(ctx, "foo", kv2client.ChangelogOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Changes) == 0 || changes.Changes[0].Operation == "" {
+		t.Fatalf("unexpected changelog: %#v", changes)
+	}
+}
+
+func TestClient_SchemaMigration(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := c.Put(ctx, key, map[string]interface{}{"user": key}, kv2client.WriteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.WriteMetadata(ctx, key, kv2client.MetadataParams{SchemaVersion: intPtr(1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := c.SchemaVersions(ctx, kv2client.SchemaVersionsOptions{SchemaVersion: intPtr(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(report.Counts, map[int]int{1: 2}) || !reflect.DeepEqual(report.Keys, []string{"a", "b"}) {
+		t.Fatalf("unexpected report: %#v", report)
+	}
+
+	jobID, err := c.MigrateSchema(ctx, kv2client.SchemaMigration{
+		FromSchemaVersion: 1,
+		ToSchemaVersion:   2,
+		Transform: []kv2client.TransformOp{
+			{Op: "rename", Field: "user", To: "credentials/user"},
+			{Op: "set", Field: "enabled", Value: false},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		job, err := c.ReadJob(ctx, jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status == "completed" {
+			break
+		}
+		if job.Status != "running" || time.Now().After(deadline) {
+			t.Fatalf("unexpected job: %#v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	secret, err := c.Get(ctx, "a", kv2client.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"credentials": map[string]interface{}{"user": "a"},
+		"enabled":     false,
+	}
+	if !reflect.DeepEqual(secret.Data, expected) || secret.Metadata.Version != 2 {
+		t.Fatalf("unexpected migrated secret: %#v", secret)
+	}
+	meta, err := c.ReadMetadata(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.SchemaVersion != 2 {
+		t.Fatalf("expected schema_version 2, got %d", meta.SchemaVersion)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := c.Search(ctx, kv2client.SearchOptions{})
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a bad request error, got %v", err)
+	}
+
+	if _, err := c.Put(ctx, "foo", map[string]interface{}{"bar": "baz"}, kv2client.WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Put(ctx, "foo", map[string]interface{}{"bar": "baz"}, kv2client.WriteOptions{CAS: intPtr(5)})
+	if !errors.As(err, &respErr) {
+		t.Fatalf("expected a response error, got %v", err)
+	}
+
+	_, err = c.WriteConfig(ctx, map[string]interface{}{"max_versions": 2, "min_versions": 3})
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a bad request error, got %v", err)
+	}
+	config, err := c.WriteConfig(ctx, map[string]interface{}{"max_versions": 3, "min_versions": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config["max_versions"] != json.Number("3") {
+		t.Fatalf("unexpected config: %#v", config)
+	}
+
+	status, err := c.UpgradeStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Upgrading {
+		t.Fatalf("unexpected upgrade status: %#v", status)
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv2client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// VersionMetadata is the metadata of a version of a secret returned by data
+// reads and writes.
+type VersionMetadata struct {
+	Version        int               `json:"version"`
+	CreatedTime    time.Time         `json:"created_time"`
+	DeletionTime   time.Time         `json:"deletion_time"`
+	Destroyed      bool              `json:"destroyed"`
+	CustomMetadata map[string]string `json:"custom_metadata"`
+}
+
+// Secret is a version of a secret returned by a data read.
+type Secret struct {
+	// Data is the data of the version, nil if the version is deleted or
+	// destroyed.
+	Data map[string]interface{} `json:"data"`
+
+	Metadata VersionMetadata `json:"metadata"`
+
+	// Warnings are the warnings returned with the secret.
+	Warnings []string `json:"-"`
+}
+
+// WriteResult is the metadata of the version written by a data write or
+// patch, or a rollback.
+type WriteResult struct {
+	Version        int               `json:"version"`
+	CreatedTime    time.Time         `json:"created_time"`
+	DeletionTime   time.Time         `json:"deletion_time"`
+	Destroyed      bool              `json:"destroyed"`
+	CustomMetadata map[string]string `json:"custom_metadata"`
+
+	// PrunedVersions are the versions removed from the metadata because the
+	// secret exceeded its max_versions. Not returned by rollbacks.
+	PrunedVersions []int `json:"pruned_versions"`
+
+	// ConsistencyToken can be passed to reads to require the written
+	// version. Not returned by rollbacks.
+	ConsistencyToken string `json:"consistency_token"`
+
+	// Warnings are the warnings returned with the write, such as
+	// unrecognized options.
+	Warnings []string `json:"-"`
+}
+
+// GetOptions are the options of a data read.
+type GetOptions struct {
+	// Version is the version to read, the current version if zero.
+	Version int
+
+	// Passphrase is the read passphrase of the secret, if it requires one.
+	Passphrase string
+
+	// MinVersion fails the read with ErrVersionNotAvailable if the current
+	// version of the secret on the server is lower.
+	MinVersion int
+
+	// ConsistencyToken is the ConsistencyToken of a write of the secret. Like
+	// MinVersion, it fails the read with ErrVersionNotAvailable if the
+	// written version is not available on the server yet.
+	ConsistencyToken string
+}
+
+// WriteOptions are the options of a data write or patch.
+type WriteOptions struct {
+	// CAS is the check-and-set version: if set, the write only succeeds if
+	// it is the current version of the secret, or if it is 0 and the secret
+	// does not exist.
+	CAS *int
+
+	// DeleteVersionAfter overrides the delete_version_after of the secret
+	// for the new version if set.
+	DeleteVersionAfter *time.Duration
+
+	// CorrelationID is recorded in the version metadata and the changelog.
+	CorrelationID string
+
+	// Template is the name of the template the secret is created from, on
+	// its first write only.
+	Template string
+}
+
+// ArrayOp is an operation on an array field of the data of a secret, applied
+// by a patch after its data is merged.
+type ArrayOp struct {
+	// Op is "append", "remove" or "remove_index".
+	Op string `json:"op"`
+
+	// Field is the path of the array field, with slashes separating the
+	// fields of nested objects.
+	Field string `json:"field"`
+
+	// Value is the value appended, or removed from the array.
+	Value interface{} `json:"value,omitempty"`
+
+	// Index is the index of the element removed by "remove_index".
+	Index *int `json:"index,omitempty"`
+}
+
+// PatchOptions are the options of a data patch.
+type PatchOptions struct {
+	WriteOptions
+
+	// ArrayOps are applied in order after the data is merged.
+	ArrayOps []ArrayOp
+}
+
+// SubkeysOptions are the options of a subkeys read.
+type SubkeysOptions struct {
+	// Version is the version to read, the current version if zero.
+	Version int
+
+	// Depth is the depth of the returned subkeys, unlimited if zero.
+	Depth int
+
+	// Keys are the top-level keys to return, all of them if empty.
+	Keys []string
+
+	// IncludeTypes sets the leaf keys to the JSON type and size in bytes of
+	// their value, as a map with "type" and "bytes" entries, instead of nil.
+	IncludeTypes bool
+}
+
+// Subkeys is the structure of the data of a secret, without its values.
+type Subkeys struct {
+	// Subkeys holds the keys of the data, where the values that are not
+	// nested objects are nil.
+	Subkeys map[string]interface{} `json:"subkeys"`
+
+	Metadata VersionMetadata `json:"metadata"`
+}
+
+// options returns the options map of a write request.
+func (o WriteOptions) options() map[string]interface{} {
+	options := map[string]interface{}{}
+	if o.CAS != nil {
+		options["cas"] = *o.CAS
+	}
+	if o.DeleteVersionAfter != nil {
+		options["delete_version_after"] = o.DeleteVersionAfter.String()
+	}
+	if o.CorrelationID != "" {
+		options["correlation_id"] = o.CorrelationID
+	}
+	if o.Template != "" {
+		options["template"] = o.Template
+	}
+	return options
+}
+
+// Get reads a version of the secret at path. If the version is deleted or
+// destroyed, the data of the returned secret is nil. ErrNotFound is returned
+// if the secret or the version does not exist.
+func (c *Client) Get(ctx context.Context, path string, opts GetOptions) (*Secret, error) {
+	query := url.Values{}
+	if opts.Version > 0 {
+		query.Set("version", strconv.Itoa(opts.Version))
+	}
+	if opts.Passphrase != "" {
+		query.Set("passphrase", opts.Passphrase)
+	}
+	if opts.MinVersion > 0 {
+		query.Set("min_version", strconv.Itoa(opts.MinVersion))
+	}
+	if opts.ConsistencyToken != "" {
+		query.Set("consistency_token", opts.ConsistencyToken)
+	}
+
+	raw, err := c.read(ctx, c.path("data", path), query)
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusPreconditionFailed {
+		return nil, ErrVersionNotAvailable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &Secret{}
+	if err := decode(raw.Data, secret); err != nil {
+		return nil, err
+	}
+	secret.Warnings = raw.Warnings
+	return secret, nil
+}
+
+// Put writes a new version of the secret at path.
+func (c *Client) Put(ctx context.Context, path string, data map[string]interface{}, opts WriteOptions) (*WriteResult, error) {
+	return c.writeVersion(ctx, c.path("data", path), map[string]interface{}{
+		"data":    data,
+		"options": opts.options(),
+	})
+}
+
+// Patch merges data into the current version of the secret at path, as a
+// JSON merge patch, and writes the result as a new version.
+func (c *Client) Patch(ctx context.Context, path string, data map[string]interface{}, opts PatchOptions) (*WriteResult, error) {
+	options := opts.options()
+	if len(opts.ArrayOps) > 0 {
+		ops := make([]interface{}, len(opts.ArrayOps))
+		for i, op := range opts.ArrayOps {
+			ops[i] = op
+		}
+		options["array_ops"] = ops
+	}
+
+	raw, err := c.c.Logical().JSONMergePatch(ctx, c.path("data", path), map[string]interface{}{
+		"data":    data,
+		"options": options,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, ErrNotFound
+	}
+
+	result := &WriteResult{}
+	if err := decode(raw.Data, result); err != nil {
+		return nil, err
+	}
+	result.Warnings = raw.Warnings
+	return result, nil
+}
+
+// writeVersion writes a request creating a version and decodes its result.
+func (c *Client) writeVersion(ctx context.Context, p string, data map[string]interface{}) (*WriteResult, error) {
+	raw, err := c.c.Logical().WriteWithContext(ctx, p, data)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, ErrNotFound
+	}
+
+	result := &WriteResult{}
+	if err := decode(raw.Data, result); err != nil {
+		return nil, err
+	}
+	result.Warnings = raw.Warnings
+	return result, nil
+}
+
+// Delete soft deletes the current version of the secret at path.
+func (c *Client) Delete(ctx context.Context, path string) error {
+	_, err := c.c.Logical().DeleteWithContext(ctx, c.path("data", path))
+	return err
+}
+
+// DeleteVersions soft deletes the versions of the secret at path.
+func (c *Client) DeleteVersions(ctx context.Context, path string, versions []int) error {
+	_, err := c.write(ctx, c.path("delete", path), map[string]interface{}{
+		"versions": versions,
+	})
+	return err
+}
+
+// Undelete restores the soft deleted versions of the secret at path. Confirm
+// must be set for secrets under the undelete_confirm_prefixes of the mount.
+func (c *Client) Undelete(ctx context.Context, path string, versions []int, confirm bool) error {
+	_, err := c.write(ctx, c.path("undelete", path), map[string]interface{}{
+		"versions": versions,
+		"confirm":  confirm,
+	})
+	return err
+}
+
+// Destroy permanently removes the data of the versions of the secret at
+// path.
+func (c *Client) Destroy(ctx context.Context, path string, versions []int) error {
+	_, err := c.write(ctx, c.path("destroy", path), map[string]interface{}{
+		"versions": versions,
+	})
+	return err
+}
+
+// Rollback writes the data of a previous version of the secret at path as a
+// new version.
+func (c *Client) Rollback(ctx context.Context, path string, version int, opts WriteOptions) (*WriteResult, error) {
+	return c.writeVersion(ctx, c.path("rollback", path), map[string]interface{}{
+		"version": version,
+		"options": opts.options(),
+	})
+}
+
+// Subkeys reads the structure of the data of a version of the secret at
+// path, without its values.
+func (c *Client) Subkeys(ctx context.Context, path string, opts SubkeysOptions) (*Subkeys, error) {
+	query := url.Values{}
+	if opts.Version > 0 {
+		query.Set("version", strconv.Itoa(opts.Version))
+	}
+	if opts.Depth > 0 {
+		query.Set("depth", strconv.Itoa(opts.Depth))
+	}
+	if len(opts.Keys) > 0 {
+		query.Set("keys", strings.Join(opts.Keys, ","))
+	}
+	if opts.IncludeTypes {
+		query.Set("include_types", "true")
+	}
+
+	raw, err := c.read(ctx, c.path("subkeys", path), query)
+	if err != nil {
+		return nil, err
+	}
+
+	subkeys := &Subkeys{}
+	if err := decode(raw.Data, subkeys); err != nil {
+		return nil, err
+	}
+	return subkeys, nil
+}
@@ -0,0 +1,222 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv2client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyMetadata is the metadata of a secret returned by a metadata read.
+type KeyMetadata struct {
+	Versions           map[int]KeyVersionMetadata `json:"versions"`
+	CurrentVersion     int                        `json:"current_version"`
+	OldestVersion      int                        `json:"oldest_version"`
+	CreatedTime        time.Time                  `json:"created_time"`
+	UpdatedTime        time.Time                  `json:"updated_time"`
+	MaxVersions        int                        `json:"max_versions"`
+	CASRequired        bool                       `json:"cas_required"`
+	DeleteVersionAfter time.Duration              `json:"delete_version_after"`
+	CustomMetadata     map[string]string          `json:"custom_metadata"`
+	CreationSource     string                     `json:"creation_source"`
+	Owner              string                     `json:"owner"`
+	Deprecated         bool                       `json:"deprecated"`
+	ReplacementPath    string                     `json:"replacement_path"`
+	Locked             bool                       `json:"locked"`
+	MetadataVersion    int                        `json:"metadata_version"`
+	TotalBytes         int                        `json:"total_bytes"`
+	SchemaVersion      int                        `json:"schema_version"`
+	ID                 string                     `json:"id"`
+}
+
+// KeyVersionMetadata is the metadata of a version in the metadata of a
+// secret.
+type KeyVersionMetadata struct {
+	CreatedTime   time.Time `json:"created_time"`
+	DeletionTime  time.Time `json:"deletion_time"`
+	Destroyed     bool      `json:"destroyed"`
+	Archived      bool      `json:"archived"`
+	CorrelationID string    `json:"correlation_id"`
+	DataBytes     int       `json:"data_bytes"`
+}
+
+// MetadataParams are the parameters of a metadata write or patch. Parameters
+// that are not set are left unchanged.
+type MetadataParams struct {
+	MaxVersions        *int
+	CASRequired        *bool
+	DeleteVersionAfter *time.Duration
+	CustomMetadata     map[string]string
+	Owner              *string
+	Deprecated         *bool
+	ReplacementPath    *string
+	Locked             *bool
+	SchemaVersion      *int
+
+	// MetadataCAS is the check-and-set metadata_version: if set, the write
+	// only succeeds if it is the current metadata_version of the secret.
+	MetadataCAS *int
+}
+
+// ListOptions are the options of a metadata list.
+type ListOptions struct {
+	// Depth lists the keys and folders down to this many levels below the
+	// path. Only the direct children of the path are listed if zero.
+	Depth int
+
+	// Limit is the maximum number of keys of the page, all the keys if
+	// zero.
+	Limit int
+
+	// After returns the keys sorted after this key, the NextAfter of the
+	// previous page.
+	After string
+
+	// Filter only returns the keys whose custom metadata has these values.
+	Filter map[string]string
+}
+
+// ListPage is a page of a metadata list.
+type ListPage struct {
+	Keys []string `json:"keys"`
+
+	// NextAfter is the After of the next page, empty on the last page.
+	NextAfter string `json:"next_after"`
+}
+
+// data returns the data of a metadata request.
+func (p MetadataParams) data() map[string]interface{} {
+	data := map[string]interface{}{}
+	if p.MaxVersions != nil {
+		data["max_versions"] = *p.MaxVersions
+	}
+	if p.CASRequired != nil {
+		data["cas_required"] = *p.CASRequired
+	}
+	if p.DeleteVersionAfter != nil {
+		data["delete_version_after"] = p.DeleteVersionAfter.String()
+	}
+	if p.CustomMetadata != nil {
+		data["custom_metadata"] = p.CustomMetadata
+	}
+	if p.Owner != nil {
+		data["owner"] = *p.Owner
+	}
+	if p.Deprecated != nil {
+		data["deprecated"] = *p.Deprecated
+	}
+	if p.ReplacementPath != nil {
+		data["replacement_path"] = *p.ReplacementPath
+	}
+	if p.Locked != nil {
+		data["locked"] = *p.Locked
+	}
+	if p.SchemaVersion != nil {
+		data["schema_version"] = *p.SchemaVersion
+	}
+	if p.MetadataCAS != nil {
+		data["metadata_cas"] = *p.MetadataCAS
+	}
+	return data
+}
+
+// ReadMetadata reads the metadata of the secret at path.
+func (c *Client) ReadMetadata(ctx context.Context, path string) (*KeyMetadata, error) {
+	raw, err := c.read(ctx, c.path("metadata", path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &KeyMetadata{}
+	if err := decode(raw.Data, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// WriteMetadata writes the metadata of the secret at path, creating the
+// secret without versions if it does not exist.
+func (c *Client) WriteMetadata(ctx context.Context, path string, params MetadataParams) error {
+	_, err := c.write(ctx, c.path("metadata", path), params.data())
+	return err
+}
+
+// PatchMetadata updates the metadata of the existing secret at path. Custom
+// metadata keys are merged into the existing ones.
+func (c *Client) PatchMetadata(ctx context.Context, path string, params MetadataParams) error {
+	_, err := c.c.Logical().JSONMergePatch(ctx, c.path("metadata", path), params.data())
+	return err
+}
+
+// DeleteMetadata deletes the secret at path with its metadata and all of
+// its versions. If requireDestroyed is set, it is only deleted if all of its
+// versions are destroyed.
+func (c *Client) DeleteMetadata(ctx context.Context, path string, requireDestroyed bool) error {
+	var query url.Values
+	if requireDestroyed {
+		query = url.Values{"require_destroyed": {"true"}}
+	}
+	_, err := c.c.Logical().DeleteWithDataWithContext(ctx, c.path("metadata", path), query)
+	return err
+}
+
+// SoftDeleteMetadata moves the secret at path with its metadata and all of
+// its versions to the trash, from where UndeleteMetadata restores it until
+// the trash_retention of the config passes.
+func (c *Client) SoftDeleteMetadata(ctx context.Context, path string) error {
+	_, err := c.c.Logical().DeleteWithDataWithContext(ctx, c.path("metadata", path), url.Values{"soft": {"true"}})
+	return err
+}
+
+// UndeleteMetadata restores the secret most recently soft deleted from path.
+// It returns ErrNotFound if there is none in the trash.
+func (c *Client) UndeleteMetadata(ctx context.Context, path string) error {
+	data, err := c.write(ctx, c.path("metadata-undelete", path), nil)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List lists the keys and folders under the folder at path. Folders end with
+// a slash.
+func (c *Client) List(ctx context.Context, path string, opts ListOptions) (*ListPage, error) {
+	query := url.Values{}
+	if opts.Depth > 0 {
+		query.Set("depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.After != "" {
+		query.Set("after", opts.After)
+	}
+	if len(opts.Filter) > 0 {
+		selectors := make([]string, 0, len(opts.Filter))
+		for k, v := range opts.Filter {
+			selectors = append(selectors, "custom_metadata."+k+"="+v)
+		}
+		query.Set("filter", strings.Join(selectors, ","))
+	}
+
+	raw, err := c.list(ctx, c.path("metadata", path), query)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil || raw.Data == nil {
+		return &ListPage{Keys: []string{}}, nil
+	}
+
+	page := &ListPage{}
+	if err := decode(raw.Data, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
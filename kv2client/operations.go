@@ -0,0 +1,516 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv2client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CopyResult is the result of a copy or move of a secret.
+type CopyResult struct {
+	Destination    string `json:"destination"`
+	CurrentVersion int    `json:"current_version"`
+	CopiedVersions int    `json:"copied_versions"`
+}
+
+// ChangelogOptions are the options of a changelog read.
+type ChangelogOptions struct {
+	// After returns the changes with a greater sequence number, the
+	// NextAfter of the previous page.
+	After int
+
+	// Limit is the maximum number of changes of the page, 100 if zero.
+	Limit int
+
+	// Operations only returns the changes made by these operations.
+	Operations []string
+}
+
+// Change is a change to a secret recorded in its changelog.
+type Change struct {
+	Sequence       int               `json:"sequence"`
+	Operation      string            `json:"operation"`
+	Path           string            `json:"path"`
+	Time           time.Time         `json:"time"`
+	CurrentVersion int               `json:"current_version"`
+	Details        map[string]string `json:"details"`
+	CorrelationID  string            `json:"correlation_id"`
+}
+
+// ChangelogPage is a page of the changelog of a secret.
+type ChangelogPage struct {
+	Changes []Change `json:"changes"`
+
+	// NextAfter is the After of the next page, zero on the last page.
+	NextAfter int `json:"next_after"`
+}
+
+// Export is the bundle of a secret with its version history returned by the
+// export endpoint, which the import endpoint accepts.
+type Export struct {
+	Format         string            `json:"format"`
+	FormatVersion  int               `json:"format_version"`
+	Key            string            `json:"key"`
+	CurrentVersion int               `json:"current_version"`
+	OldestVersion  int               `json:"oldest_version"`
+	CustomMetadata map[string]string `json:"custom_metadata"`
+	Versions       []ExportVersion   `json:"versions"`
+}
+
+// ExportVersion is a version of an exported secret.
+type ExportVersion struct {
+	Version      int       `json:"version"`
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime time.Time `json:"deletion_time"`
+	Destroyed    bool      `json:"destroyed"`
+
+	// Data is the data of the version, nil if it is destroyed.
+	Data map[string]interface{} `json:"data"`
+}
+
+// ImportResult is the result of an import of a secret.
+type ImportResult struct {
+	CurrentVersion   int               `json:"current_version"`
+	OldestVersion    int               `json:"oldest_version"`
+	ImportedVersions int               `json:"imported_versions"`
+	CustomMetadata   map[string]string `json:"custom_metadata"`
+}
+
+// SearchOptions are the options of a metadata search. One of Glob, Regex or
+// CustomMetadata must be set.
+type SearchOptions struct {
+	// Glob matches the key paths, where "*" matches any characters but "/"
+	// and "**" any characters.
+	Glob string
+
+	// Regex matches the key paths.
+	Regex string
+
+	// CustomMetadata only returns the keys whose custom metadata has these
+	// values.
+	CustomMetadata map[string]string
+
+	// Path only searches the keys under this folder.
+	Path string
+
+	// Limit is the maximum number of keys of the page, 100 if zero.
+	Limit int
+
+	// After returns the keys sorted after this key, the NextAfter of the
+	// previous page.
+	After string
+}
+
+// OldestVersionsOptions are the options of the oldest versions report.
+type OldestVersionsOptions struct {
+	// MinAge is the age of the oldest retained version above which keys are
+	// reported.
+	MinAge time.Duration
+
+	// Path only reports the keys under this folder.
+	Path string
+
+	// Limit is the maximum number of keys of the page, 100 if zero.
+	Limit int
+
+	// After reports the keys sorted after this key, the NextAfter of the
+	// previous page.
+	After string
+}
+
+// OldestVersion is a key reported by the oldest versions report.
+type OldestVersion struct {
+	Key         string    `json:"key"`
+	Version     int       `json:"version"`
+	CreatedTime time.Time `json:"created_time"`
+
+	// Age is the age of the version in seconds.
+	Age int `json:"age"`
+}
+
+// OldestVersionsPage is a page of the oldest versions report.
+type OldestVersionsPage struct {
+	Keys []OldestVersion `json:"keys"`
+
+	// NextAfter is the After of the next page, empty on the last page.
+	NextAfter string `json:"next_after"`
+}
+
+// SchemaVersionsOptions are the options of the schema versions report.
+type SchemaVersionsOptions struct {
+	// Path only reports the keys under this folder.
+	Path string
+
+	// SchemaVersion lists the keys of this schema_version along with the
+	// counts, if set.
+	SchemaVersion *int
+
+	// Limit is the maximum number of keys of the page, 100 if zero.
+	Limit int
+
+	// After lists the keys sorted after this key, the NextAfter of the
+	// previous page.
+	After string
+}
+
+// SchemaVersionsReport is the schema versions report.
+type SchemaVersionsReport struct {
+	// Counts is the number of keys of each schema_version, keys without one
+	// counted under 0.
+	Counts map[int]int `json:"counts"`
+
+	// Keys are the keys of the requested schema_version.
+	Keys []string `json:"keys"`
+
+	// NextAfter is the After of the next page, empty on the last page.
+	NextAfter string `json:"next_after"`
+}
+
+// SchemaMigration is a migration of the data of the keys of a
+// schema_version.
+type SchemaMigration struct {
+	// Path only migrates the keys under this folder.
+	Path string
+
+	FromSchemaVersion int
+	ToSchemaVersion   int
+
+	// Transform is the list of operations applied in order to the data of
+	// each key.
+	Transform []TransformOp
+}
+
+// TransformOp is an operation of the transform of a schema migration.
+type TransformOp struct {
+	// Op is one of set, set_default, rename, copy or remove.
+	Op string `json:"op"`
+
+	// Field is the path of the field, its segments separated by slashes.
+	Field string `json:"field"`
+
+	// To is the field renamed or copied to.
+	To string `json:"to,omitempty"`
+
+	// Value is the value set by set and set_default.
+	Value interface{} `json:"value"`
+}
+
+// Job is the state of a long-running job, such as a schema migration.
+type Job struct {
+	ID              string    `json:"id"`
+	Type            string    `json:"type"`
+	Status          string    `json:"status"`
+	Processed       int       `json:"processed"`
+	Total           int       `json:"total"`
+	Error           string    `json:"error"`
+	CancelRequested bool      `json:"cancel_requested"`
+	CreatedTime     time.Time `json:"created_time"`
+	UpdatedTime     time.Time `json:"updated_time"`
+}
+
+// UpgradeStatus is the progress of the upgrade of the mount from
+// non-versioned data.
+type UpgradeStatus struct {
+	Upgrading              bool      `json:"upgrading"`
+	Done                   bool      `json:"done"`
+	StartedTime            time.Time `json:"started_time"`
+	HeartbeatTime          time.Time `json:"heartbeat_time"`
+	KeysTotal              int       `json:"keys_total"`
+	KeysProcessed          int       `json:"keys_processed"`
+	EstimatedTimeRemaining string    `json:"estimated_time_remaining"`
+	LastError              string    `json:"last_error"`
+}
+
+// Copy copies the secret at path with all of its versions to destination.
+func (c *Client) Copy(ctx context.Context, path, destination string) (*CopyResult, error) {
+	return c.copy(ctx, "copy", path, destination)
+}
+
+// Move moves the secret at path with all of its versions to destination.
+func (c *Client) Move(ctx context.Context, path, destination string) (*CopyResult, error) {
+	return c.copy(ctx, "move", path, destination)
+}
+
+func (c *Client) copy(ctx context.Context, operation, path, destination string) (*CopyResult, error) {
+	data, err := c.write(ctx, c.path(operation, path), map[string]interface{}{
+		"destination": destination,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CopyResult{}
+	if err := decode(data, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Changelog reads a page of the changes of the secret at path.
+func (c *Client) Changelog(ctx context.Context, path string, opts ChangelogOptions) (*ChangelogPage, error) {
+	query := url.Values{}
+	if opts.After > 0 {
+		query.Set("after", strconv.Itoa(opts.After))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if len(opts.Operations) > 0 {
+		query.Set("operations", strings.Join(opts.Operations, ","))
+	}
+
+	raw, err := c.read(ctx, c.path("changelog", path), query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ChangelogPage{}
+	if err := decode(raw.Data, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Export exports the secret at path with its version history. It requires
+// sudo capability. passphrase is the read passphrase of the secret, if it
+// requires one.
+func (c *Client) Export(ctx context.Context, path, passphrase string) (*Export, error) {
+	var query url.Values
+	if passphrase != "" {
+		query = url.Values{"passphrase": {passphrase}}
+	}
+
+	raw, err := c.read(ctx, c.path("export", path), query)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &Export{}
+	if err := decode(raw.Data, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Import writes the versions of an exported secret to the secret at path,
+// which must not have versions.
+func (c *Client) Import(ctx context.Context, path string, export *Export) (*ImportResult, error) {
+	versions := make([]interface{}, len(export.Versions))
+	for i, v := range export.Versions {
+		version := map[string]interface{}{
+			"version":      v.Version,
+			"created_time": v.CreatedTime.Format(time.RFC3339Nano),
+			"destroyed":    v.Destroyed,
+		}
+		if !v.DeletionTime.IsZero() {
+			version["deletion_time"] = v.DeletionTime.Format(time.RFC3339Nano)
+		}
+		if v.Data != nil {
+			version["data"] = v.Data
+		}
+		versions[i] = version
+	}
+
+	data, err := c.write(ctx, c.path("import", path), map[string]interface{}{
+		"versions":        versions,
+		"custom_metadata": export.CustomMetadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	if err := decode(data, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Search returns a page of the key paths matching the search. It requires
+// sudo capability.
+func (c *Client) Search(ctx context.Context, opts SearchOptions) (*ListPage, error) {
+	query := url.Values{}
+	if opts.Glob != "" {
+		query.Set("glob", opts.Glob)
+	}
+	if opts.Regex != "" {
+		query.Set("regex", opts.Regex)
+	}
+	for k, v := range opts.CustomMetadata {
+		query.Add("custom_metadata", k+"="+v)
+	}
+	if opts.Path != "" {
+		query.Set("path", opts.Path)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.After != "" {
+		query.Set("after", opts.After)
+	}
+
+	raw, err := c.read(ctx, c.path("search/metadata", ""), query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ListPage{}
+	if err := decode(raw.Data, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// OldestVersions returns a page of the keys whose oldest retained version
+// is older than MinAge. It requires sudo capability.
+func (c *Client) OldestVersions(ctx context.Context, opts OldestVersionsOptions) (*OldestVersionsPage, error) {
+	query := url.Values{
+		"min_age": {strconv.Itoa(int(opts.MinAge.Seconds()))},
+	}
+	if opts.Path != "" {
+		query.Set("path", opts.Path)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.After != "" {
+		query.Set("after", opts.After)
+	}
+
+	raw, err := c.read(ctx, c.path("reports/oldest-versions", ""), query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &OldestVersionsPage{}
+	if err := decode(raw.Data, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// SchemaVersions reads the number of keys of each schema_version.
+func (c *Client) SchemaVersions(ctx context.Context, opts SchemaVersionsOptions) (*SchemaVersionsReport, error) {
+	query := url.Values{}
+	if opts.Path != "" {
+		query.Set("path", opts.Path)
+	}
+	if opts.SchemaVersion != nil {
+		query.Set("schema_version", strconv.Itoa(*opts.SchemaVersion))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.After != "" {
+		query.Set("after", opts.After)
+	}
+
+	raw, err := c.read(ctx, c.path("reports/schema-versions", ""), query)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SchemaVersionsReport{}
+	if err := decode(raw.Data, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// MigrateSchema starts a job migrating the data of the keys of a
+// schema_version, and returns the ID of the job.
+func (c *Client) MigrateSchema(ctx context.Context, migration SchemaMigration) (string, error) {
+	transform := make([]interface{}, 0, len(migration.Transform))
+	for _, op := range migration.Transform {
+		transform = append(transform, op)
+	}
+
+	data, err := c.write(ctx, c.path("migrate/schema", ""), map[string]interface{}{
+		"path":                migration.Path,
+		"from_schema_version": migration.FromSchemaVersion,
+		"to_schema_version":   migration.ToSchemaVersion,
+		"transform":           transform,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	jobID, _ := data["job_id"].(string)
+	return jobID, nil
+}
+
+// ReadJob reads the state of the job with the given ID.
+func (c *Client) ReadJob(ctx context.Context, id string) (*Job, error) {
+	raw, err := c.read(ctx, c.path("jobs", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{}
+	if err := decode(raw.Data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// KeyByID is the secret an opaque ID resolves to.
+type KeyByID struct {
+	ID             string    `json:"id"`
+	Path           string    `json:"path"`
+	CurrentVersion int       `json:"current_version"`
+	CreatedTime    time.Time `json:"created_time"`
+	UpdatedTime    time.Time `json:"updated_time"`
+}
+
+// ReadByID resolves the opaque ID of a secret, as returned in its
+// KeyMetadata, to its current path. It returns ErrNotFound if no secret has
+// the ID.
+func (c *Client) ReadByID(ctx context.Context, id string) (*KeyByID, error) {
+	raw, err := c.read(ctx, c.path("by-id", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &KeyByID{}
+	if err := decode(raw.Data, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// UpgradeStatus reads the progress of the upgrade of the mount from
+// non-versioned data.
+func (c *Client) UpgradeStatus(ctx context.Context) (*UpgradeStatus, error) {
+	raw, err := c.read(ctx, c.path("upgrade/status", ""), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &UpgradeStatus{}
+	if err := decode(raw.Data, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ReadConfig reads the config of the mount. The config has many parameters
+// that change between releases, so it is returned as is.
+func (c *Client) ReadConfig(ctx context.Context) (map[string]interface{}, error) {
+	raw, err := c.read(ctx, c.path("config", ""), nil)
+	if err != nil {
+		return nil, err
+	}
+	return raw.Data, nil
+}
+
+// WriteConfig writes the parameters of the config of the mount and returns
+// the resulting config, as returned by ReadConfig. Parameters that are not
+// set are left unchanged.
+func (c *Client) WriteConfig(ctx context.Context, params map[string]interface{}) (map[string]interface{}, error) {
+	return c.write(ctx, c.path("config", ""), params)
+}
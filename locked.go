@@ -0,0 +1,22 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// isLocked returns true if the versions of the key must not be changed. Keys
+// without metadata are never locked.
+func (k *KeyMetadata) isLocked() bool {
+	return k != nil && k.Locked
+}
+
+// lockedDenied returns the response to a request changing the versions of a
+// locked key.
+func lockedDenied(key, operation string) (*logical.Response, error) {
+	return logical.ErrorResponse(fmt.Sprintf("%s is not permitted while secret %q is locked; unset locked in its metadata first", operation, key)), logical.ErrInvalidRequest
+}
@@ -0,0 +1,157 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Locked(t *testing.T) {
+	b, storage := getBackend(t)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata/foo",
+			Data: map[string]interface{}{
+				"locked": true,
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	rejected := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "qux"},
+			},
+		},
+		{
+			Operation: logical.PatchOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "qux"},
+			},
+		},
+		{
+			Operation: logical.DeleteOperation,
+			Path:      "data/foo",
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "delete/foo",
+			Data: map[string]interface{}{
+				"versions": []int{1},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "destroy/foo",
+			Data: map[string]interface{}{
+				"versions": []int{1},
+			},
+		},
+		{
+			Operation: logical.DeleteOperation,
+			Path:      "metadata/foo",
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "copy/foo",
+			Data: map[string]interface{}{
+				"destination": "bar",
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "move/foo",
+			Data: map[string]interface{}{
+				"destination": "bar",
+			},
+		},
+	}
+	for _, req := range rejected {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected %s request to %s to be rejected, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("expected the data to be unchanged, resp: %#v", resp)
+	}
+	if resp.Data["metadata"].(map[string]interface{})["version"] != uint64(1) {
+		t.Fatalf("expected no new version, resp: %#v", resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["locked"] != true {
+		t.Fatalf("expected the key to be locked, resp: %#v", resp.Data)
+	}
+
+	req = &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"locked": false,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata PatchOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"bar": "qux"},
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("expected the write to succeed once unlocked, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["version"] != uint64(2) {
+		t.Fatalf("unexpected version: %#v", resp.Data)
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// mountOptionMetadataOnly is the mount option that disables the reads of
+// secret data on the mount. As a mount option it can only be changed by tuning
+// the mount, not by writes to the config of the mount.
+const mountOptionMetadataOnly = "metadata_only"
+
+// valueReadDenied returns the response to reads of secret data on a mount in
+// metadata-only mode. Such mounts serve inventory and audit replicas, where
+// secret values must not be readable whatever the policies of the token.
+func valueReadDenied() (*logical.Response, error) {
+	return logical.ErrorResponse("the mount is in metadata-only mode, secret data cannot be read"), logical.ErrPermissionDenied
+}
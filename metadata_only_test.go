@@ -0,0 +1,82 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_MetadataOnly(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+
+	b, err := VersionedKVFactory(ctx, &logical.BackendConfig{
+		Logger:      logging.NewVaultLogger(log.Trace),
+		System:      &logical.StaticSystemView{},
+		StorageView: storage,
+		BackendUUID: "test",
+		Config: map[string]string{
+			mountOptionMetadataOnly: "true",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"v1_data_enabled": true,
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "qux"},
+			},
+		},
+		{Operation: logical.ReadOperation, Path: "metadata/foo"},
+		{Operation: logical.ListOperation, Path: "metadata/"},
+		{Operation: logical.ReadOperation, Path: "subkeys/foo"},
+		{Operation: logical.ReadOperation, Path: "diff/foo"},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	for _, req := range []*logical.Request{
+		{Operation: logical.ReadOperation, Path: "data/foo"},
+		{Operation: logical.ReadOperation, Path: "data/foo", Data: map[string]interface{}{"version": 1}},
+		{Operation: logical.ReadOperation, Path: "v1-data/foo"},
+		{Operation: logical.ReadOperation, Path: "diff/foo", Data: map[string]interface{}{"include_values": true}},
+	} {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != logical.ErrPermissionDenied || resp == nil || !resp.IsError() {
+			t.Fatalf("expected %s request to %s to be denied, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+		if _, ok := resp.Data["data"]; ok {
+			t.Fatalf("unexpected data in response to %s: %#v", req.Path, resp.Data)
+		}
+	}
+}
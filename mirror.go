@@ -0,0 +1,350 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// parseMirrors validates the mirrors setting of the config. Prefixes may not
+// overlap, so that a mirrored write is never mirrored again.
+func parseMirrors(raw map[string]interface{}) (map[string]string, error) {
+	mirrors := make(map[string]string, len(raw))
+	for source, targetRaw := range raw {
+		target, ok := targetRaw.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid mirror target for prefix %q: must be a string", source)
+		}
+		if source == "" || target == "" {
+			return nil, errors.New("mirror source and target prefixes cannot be empty")
+		}
+		mirrors[source] = target
+	}
+
+	var prefixes []string
+	for source, target := range mirrors {
+		prefixes = append(prefixes, source, target)
+	}
+	for i, a := range prefixes {
+		for j, b := range prefixes {
+			if i != j && strings.HasPrefix(a, b) {
+				return nil, fmt.Errorf("mirror prefixes %q and %q overlap", a, b)
+			}
+		}
+	}
+
+	return mirrors, nil
+}
+
+// mirrorTarget returns the key that writes to key are mirrored to, or an
+// empty string if key is not under a mirror source prefix.
+func (c *Configuration) mirrorTarget(key string) string {
+	for source, target := range c.Mirrors {
+		if strings.HasPrefix(key, source) {
+			return target + strings.TrimPrefix(key, source)
+		}
+	}
+	return ""
+}
+
+// mirrorQueueContextKey is the context key of the mirrorQueue of a request.
+type mirrorQueueContextKey struct{}
+
+// mirrorQueue holds the keys a request wrote new versions of, which are
+// mirrored once the request succeeded.
+type mirrorQueue struct {
+	keys []string
+}
+
+// queueMirror records that the request wrote a new version of the key, so
+// that it is mirrored by mirrorWrites once the request succeeded.
+func queueMirror(ctx context.Context, key string) {
+	if q, ok := ctx.Value(mirrorQueueContextKey{}).(*mirrorQueue); ok {
+		q.keys = append(q.keys, key)
+	}
+}
+
+// mirrorWrites wraps a handler writing versions, mirroring the current
+// version of each key it wrote to its target once the request succeeded.
+// Keys are mirrored after the handler returned, so that the locks it held
+// are released.
+func (b *versionedKVBackend) mirrorWrites(op framework.OperationFunc) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		queue := &mirrorQueue{}
+		resp, err := op(context.WithValue(ctx, mirrorQueueContextKey{}, queue), req, data)
+		if err != nil || resp == nil || resp.IsError() || len(queue.keys) == 0 {
+			return resp, err
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range queue.keys {
+			target := config.mirrorTarget(key)
+			if target == "" {
+				continue
+			}
+
+			warning, err := b.mirrorKey(ctx, req.Storage, config, key, target)
+			if err != nil {
+				return nil, err
+			}
+			if warning != "" {
+				resp.AddWarning(warning)
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+// mirrorKey writes the current version of the source key as a new version of
+// the target key. If the target was modified since it was last mirrored, it
+// is left unchanged, a mirror-conflict event is emitted and a warning is
+// returned.
+//
+// The locks of the two keys are held one after the other, not together,
+// since both keys may map to the same lock.
+func (b *versionedKVBackend) mirrorKey(ctx context.Context, s logical.Storage, config *Configuration, source, target string) (string, error) {
+	version, err := b.currentMirrorVersion(ctx, s, source)
+	if err != nil || version == nil {
+		return "", err
+	}
+
+	lock := locksutil.LockForKey(b.locks, target)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, target)
+	if err != nil {
+		return "", err
+	}
+	if meta == nil {
+		now := b.timestampNow()
+		meta = &KeyMetadata{
+			Key:            target,
+			Versions:       map[uint64]*VersionMetadata{},
+			CreatedTime:    now,
+			UpdatedTime:    now,
+			CreationSource: creationSourceMirror,
+		}
+	}
+
+	if meta.CurrentVersion != meta.MirroredVersion {
+		sequence := meta.nextEventSequence()
+		if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+			return "", err
+		}
+
+		b.emitKeyEvent(ctx, s, meta, sequence, "mirror-conflict", "data/"+source, "data/"+target, false,
+			"mirrored_version", fmt.Sprintf("%d", meta.MirroredVersion),
+		)
+		return fmt.Sprintf("write was not mirrored to %q, the key was modified since it was last mirrored", target), nil
+	}
+
+	versionKey, err := b.getVersionKey(ctx, target, meta.CurrentVersion+1, s)
+	if err != nil {
+		return "", err
+	}
+	version.CreatedTime = b.timestampNow()
+	version.DeletionTime = nil
+	version.Key = target
+	version.Version = meta.CurrentVersion + 1
+
+	ctime, err := ptypes.Timestamp(version.CreatedTime)
+	if err != nil {
+		return "", err
+	}
+	if dtime, ok := versionDeletionTime(ctime, config, meta, nil); ok {
+		version.DeletionTime, err = ptypes.TimestampProto(dtime)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	buf, err := proto.Marshal(version)
+	if err != nil {
+		return "", err
+	}
+	if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+		return "", err
+	}
+	if err := s.Put(ctx, &logical.StorageEntry{
+		Key:   versionKey,
+		Value: buf,
+	}); err != nil {
+		return "", err
+	}
+
+	vm, versionToDelete := meta.addVersionAt(meta.CurrentVersion+1, version.CreatedTime, version.DeletionTime, config.MaxVersions, config.RetainPrunedVersions, b.now())
+	vm.CorrelationId = contextCorrelationID(ctx)
+	vm.DataBytes = uint64(len(version.Data))
+	meta.MirroredVersion = meta.CurrentVersion
+	sequence := meta.nextEventSequence()
+
+	if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+		b.deleteOrphanedVersions(ctx, s, meta.Key, versionKey)
+		return "", err
+	}
+
+	warning := b.cleanupOldVersions(ctx, s, target, meta, versionToDelete)
+
+	b.emitKeyEvent(ctx, s, meta, sequence, "mirror-write", "data/"+source, "data/"+target, true,
+		"version", fmt.Sprintf("%d", meta.CurrentVersion),
+		"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+	)
+	return warning, nil
+}
+
+// currentMirrorVersion returns the current version of the source key, or nil
+// if the key does not exist or its current version is deleted or destroyed.
+func (b *versionedKVBackend) currentMirrorVersion(ctx context.Context, s logical.Storage, key string) (*Version, error) {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil || meta == nil {
+		return nil, err
+	}
+
+	vm := meta.Versions[meta.CurrentVersion]
+	if vm == nil || vm.Destroyed {
+		return nil, nil
+	}
+	if vm.DeletionTime != nil {
+		deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+		if err != nil {
+			return nil, err
+		}
+		if deletionTime.Before(b.now()) {
+			return nil, nil
+		}
+	}
+
+	return b.getVersion(ctx, s, key, meta.CurrentVersion)
+}
+
+// pathMirrorStatus returns the path configuration for the mirror status
+// endpoint
+func pathMirrorStatus(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "mirror/status",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "read",
+			OperationSuffix: "mirror-status",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathMirrorStatusRead()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"mirrors": {
+								Type:        framework.TypeMap,
+								Description: "A map of the configured source prefixes to the status of their mirror.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    mirrorStatusHelpSyn,
+		HelpDescription: mirrorStatusHelpDesc,
+	}
+}
+
+// pathMirrorStatusRead walks the keys under each mirror target prefix and
+// reports the keys that were modified independently of their source.
+func (b *versionedKVBackend) pathMirrorStatusRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		mirrors := make(map[string]interface{}, len(config.Mirrors))
+		for source, target := range config.Mirrors {
+			var mirrored int
+			conflicts := []string{}
+			// Key paths are encrypted by segment, so the walk starts at the
+			// folder containing the target prefix.
+			folder := target[:strings.LastIndex(target, "/")+1]
+			err := b.walkKeys(ctx, req.Storage, folder, func(key string) error {
+				if !strings.HasPrefix(key, target) {
+					return nil
+				}
+
+				meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+				if err != nil || meta == nil {
+					return err
+				}
+
+				if meta.CurrentVersion != meta.MirroredVersion {
+					conflicts = append(conflicts, key)
+				} else {
+					mirrored++
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(conflicts)
+
+			mirrors[source] = map[string]interface{}{
+				"target":    target,
+				"mirrored":  mirrored,
+				"conflicts": conflicts,
+			}
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"mirrors": mirrors,
+			},
+		}, nil
+	}
+}
+
+const mirrorStatusHelpSyn = `Returns the status of the configured mirrors.`
+const mirrorStatusHelpDesc = `
+The "mirrors" setting of the config endpoint maps source key prefixes to target
+key prefixes. Every new version of a key under a source prefix, whether
+written, patched, provisioned, rolled back, restored, imported, copied or
+moved there, or written by a transaction, is written as a new version of the
+same key under the target prefix, and a "mirror-write" event is emitted.
+
+Versions written by schema migrations and v1 conversions are not mirrored:
+they rewrite the existing data of keys in the background rather than write new
+data. Deletes, undeletes and destroys are not mirrored either.
+
+If the target key was modified since it was last mirrored, for example by a
+direct write, the mirror does not overwrite it. A "mirror-conflict" event is
+emitted and the write returns a warning. The target key is mirrored again once
+the conflict is resolved by deleting its metadata.
+
+This endpoint returns, for each source prefix, the target prefix, the number
+of keys under the target that are in sync with their source, and the list of
+keys that are in conflict.
+`
@@ -0,0 +1,204 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Mirror(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"mirrors": map[string]interface{}{
+				"blue/": "green/",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	write := func(path string, value string) *logical.Response {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": value,
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		return resp
+	}
+
+	read := func(path string) map[string]interface{} {
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+			Storage:   storage,
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		return resp.Data
+	}
+
+	mirrorStatus := func() map[string]interface{} {
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "mirror/status",
+			Storage:   storage,
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("mirror status ReadOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		return resp.Data["mirrors"].(map[string]interface{})["blue/"].(map[string]interface{})
+	}
+
+	write("data/blue/foo", "one")
+	write("data/blue/foo", "two")
+
+	data := read("data/green/foo")
+	if data["data"].(map[string]interface{})["bar"] != "two" {
+		t.Fatalf("expected mirrored data, got: %#v", data)
+	}
+	if v := data["metadata"].(map[string]interface{})["version"]; v != uint64(2) {
+		t.Fatalf("expected mirrored version 2, got: %#v", v)
+	}
+
+	status := mirrorStatus()
+	if status["mirrored"] != 1 || len(status["conflicts"].([]string)) != 0 {
+		t.Fatalf("unexpected mirror status: %#v", status)
+	}
+
+	// A direct write to the target puts the key in conflict
+	write("data/green/foo", "direct")
+
+	resp = write("data/blue/foo", "three")
+	if len(resp.Warnings) != 1 {
+		t.Fatalf("expected a conflict warning, got: %#v", resp.Warnings)
+	}
+
+	data = read("data/green/foo")
+	if data["data"].(map[string]interface{})["bar"] != "direct" {
+		t.Fatalf("expected conflicting key to be left unchanged, got: %#v", data)
+	}
+
+	status = mirrorStatus()
+	if status["mirrored"] != 0 || !reflect.DeepEqual(status["conflicts"], []string{"green/foo"}) {
+		t.Fatalf("unexpected mirror status: %#v", status)
+	}
+}
+
+func TestVersionedKV_Config_MirrorsOverlap(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"mirrors": map[string]interface{}{
+				"blue/": "blue/green/",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected overlapping mirror prefixes to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Mirror_Endpoints(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	mustRequest := func(path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("request to %s failed, err: %s, resp %#v", path, err, resp)
+		}
+	}
+	assertMirrored := func(key, expected string) {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "data/green/" + key,
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("expected green/%s to be mirrored, err: %s, resp %#v", key, err, resp)
+		}
+		if got := resp.Data["data"].(map[string]interface{})["bar"]; got != expected {
+			t.Fatalf("expected green/%s to hold %q, got %q", key, expected, got)
+		}
+	}
+
+	mustRequest("config", map[string]interface{}{
+		"mirrors": map[string]interface{}{"blue/": "green/"},
+	})
+	mustRequest("data/blue/foo", map[string]interface{}{"data": map[string]interface{}{"bar": "one"}})
+	mustRequest("data/blue/foo", map[string]interface{}{"data": map[string]interface{}{"bar": "two"}})
+	assertMirrored("foo", "two")
+
+	mustRequest("rollback/blue/foo", map[string]interface{}{"version": 1})
+	assertMirrored("foo", "one")
+
+	mustRequest("provision/blue/provisioned", map[string]interface{}{
+		"max_versions": 2,
+		"data":         map[string]interface{}{"bar": "provisioned"},
+	})
+	assertMirrored("provisioned", "provisioned")
+
+	mustRequest("restore/blue/restored", map[string]interface{}{
+		"version": 3,
+		"data":    map[string]interface{}{"bar": "restored"},
+	})
+	assertMirrored("restored", "restored")
+
+	mustRequest("import/blue/imported", map[string]interface{}{
+		"versions": []interface{}{
+			map[string]interface{}{
+				"version":      1,
+				"created_time": "2020-01-01T00:00:00Z",
+				"data":         map[string]interface{}{"bar": "imported"},
+			},
+		},
+	})
+	assertMirrored("imported", "imported")
+
+	mustRequest("copy/blue/foo", map[string]interface{}{"destination": "blue/copied"})
+	assertMirrored("copied", "one")
+
+	mustRequest("move/blue/copied", map[string]interface{}{"destination": "blue/moved"})
+	assertMirrored("moved", "one")
+}
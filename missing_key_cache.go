@@ -0,0 +1,82 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"sync"
+	"time"
+)
+
+// maxMissingKeyCacheEntries bounds the memory used by the missing key cache
+// when clients read many distinct missing keys.
+const maxMissingKeyCacheEntries = 10000
+
+// missingKeyCache remembers the keys found to have no metadata until their
+// entry expires, so that storms of reads of a missing key do not each look
+// up the key in storage.
+type missingKeyCache struct {
+	lock    sync.Mutex
+	expires map[string]time.Time
+}
+
+func newMissingKeyCache() *missingKeyCache {
+	return &missingKeyCache{
+		expires: make(map[string]time.Time),
+	}
+}
+
+// missing returns true if the key was found to have no metadata and its
+// entry has not expired.
+func (c *missingKeyCache) missing(key string, now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	expires, ok := c.expires[key]
+	if !ok {
+		return false
+	}
+	if !now.Before(expires) {
+		delete(c.expires, key)
+		return false
+	}
+
+	return true
+}
+
+// add records that the key has no metadata for ttl. Expired entries are
+// dropped once the cache is full, and the whole cache if none has expired.
+func (c *missingKeyCache) add(key string, now time.Time, ttl time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.expires) >= maxMissingKeyCacheEntries {
+		for k, expires := range c.expires {
+			if !now.Before(expires) {
+				delete(c.expires, k)
+			}
+		}
+		if len(c.expires) >= maxMissingKeyCacheEntries {
+			c.expires = make(map[string]time.Time)
+		}
+	}
+
+	c.expires[key] = now.Add(ttl)
+}
+
+// remove forgets that the key has no metadata, once it is written.
+func (c *missingKeyCache) remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.expires, key)
+}
+
+// clear forgets every missing key. The keys of metadata storage entries are
+// encrypted, so an invalidation cannot be mapped to the key it is about.
+func (c *missingKeyCache) clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.expires = make(map[string]time.Time)
+}
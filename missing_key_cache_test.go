@@ -0,0 +1,119 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// countingStorage counts the reads of storage entries.
+type countingStorage struct {
+	logical.Storage
+	gets int
+}
+
+func (s *countingStorage) Get(ctx context.Context, key string) (*logical.StorageEntry, error) {
+	s.gets++
+	return s.Storage.Get(ctx, key)
+}
+
+func TestMissingKeyCache(t *testing.T) {
+	c := newMissingKeyCache()
+	now := time.Now()
+
+	c.add("foo", now, time.Minute)
+	if !c.missing("foo", now) || c.missing("bar", now) {
+		t.Fatal("expected only foo to be cached")
+	}
+	if c.missing("foo", now.Add(time.Minute)) {
+		t.Fatal("expected the entry of foo to expire")
+	}
+
+	c.add("foo", now, time.Minute)
+	c.remove("foo")
+	if c.missing("foo", now) {
+		t.Fatal("expected foo to be removed")
+	}
+
+	for i := 0; i < maxMissingKeyCacheEntries+1; i++ {
+		c.add(fmt.Sprintf("key%d", i), now, time.Minute)
+	}
+	if len(c.expires) > maxMissingKeyCacheEntries {
+		t.Fatalf("expected the cache to be bounded, got %d entries", len(c.expires))
+	}
+}
+
+func TestVersionedKV_MissingKeyCache(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+	s := &countingStorage{Storage: storage}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"missing_key_cache_ttl": "1m",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config write failed, err: %s, resp %#v", err, resp)
+	}
+
+	read := func() *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "data/foo",
+			Storage:   s,
+		})
+		if err != nil {
+			t.Fatalf("read failed, err: %s, resp %#v", err, resp)
+		}
+		return resp
+	}
+
+	if resp := read(); resp != nil {
+		t.Fatalf("expected no data, got %#v", resp)
+	}
+	gets := s.gets
+	for i := 0; i < 10; i++ {
+		if resp := read(); resp != nil {
+			t.Fatalf("expected no data, got %#v", resp)
+		}
+	}
+	if s.gets != gets {
+		t.Fatalf("expected the reads of the missing key to be cached, got %d storage reads", s.gets-gets)
+	}
+
+	// A write is visible immediately
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"bar": "baz"},
+		},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("write failed, err: %s, resp %#v", err, resp)
+	}
+	if resp := read(); resp == nil || resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("unexpected read, resp %#v", resp)
+	}
+
+	// Invalidations of key metadata written by another node clear the cache
+	kv := b.(*versionedKVBackend)
+	kv.missingKeys.add("bar", time.Now(), time.Minute)
+	kv.Invalidate(ctx, path.Join(kv.storagePrefix, metadataPrefix, "abc"))
+	if kv.missingKeys.missing("bar", time.Now()) {
+		t.Fatal("expected the invalidation to clear the cache")
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	metrics "github.com/armon/go-metrics"
+)
+
+// incrCounter increments the metric unless the observations feature of the
+// config is disabled.
+func incrCounter(config *Configuration, key []string, val float32, labels ...metrics.Label) {
+	if !config.featureEnabled(featureObservations) {
+		return
+	}
+
+	if len(labels) == 0 {
+		metrics.IncrCounter(key, val)
+		return
+	}
+	metrics.IncrCounterWithLabels(key, val, labels)
+}
+
+// setGauge sets the metric unless the observations feature of the config is
+// disabled.
+func setGauge(config *Configuration, key []string, val float32) {
+	if !config.featureEnabled(featureObservations) {
+		return
+	}
+
+	metrics.SetGauge(key, val)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_OrphanedVersionsDeleted(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"foo", "bar"} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": key},
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("write of %s failed, err: %s, resp %#v", key, err, resp)
+		}
+	}
+
+	storagePrefix := b.(*versionedKVBackend).storagePrefix
+	versionEntries := func() []string {
+		t.Helper()
+		keys, err := logical.CollectKeysWithPrefix(ctx, storage, path.Join(storagePrefix, versionPrefix)+"/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+	before := versionEntries()
+	if len(before) != 2 {
+		t.Fatalf("expected 2 version entries, got %v", before)
+	}
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "new"},
+			},
+		},
+		{
+			Operation: logical.PatchOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"baz": "new"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "import/baz",
+			Data: map[string]interface{}{
+				"versions": []interface{}{
+					map[string]interface{}{"version": 1, "created_time": "2020-01-01T00:00:00Z", "data": map[string]interface{}{"a": "1"}},
+					map[string]interface{}{"version": 2, "created_time": "2020-01-02T00:00:00Z", "data": map[string]interface{}{"a": "2"}},
+				},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "copy/bar",
+			Data: map[string]interface{}{
+				"destination": "qux",
+			},
+		},
+	}
+	for _, req := range requests {
+		// Fail the write of the key metadata after the version data is written
+		req.Storage = &failingMetadataStorage{
+			Storage: storage,
+			prefix:  path.Join(storagePrefix, metadataPrefix),
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected %s request to %s to fail, resp %#v", req.Operation, req.Path, resp)
+		}
+
+		if after := versionEntries(); len(after) != len(before) {
+			t.Fatalf("expected the version data written by the failed %s request to %s to be deleted, got %v", req.Operation, req.Path, after)
+		}
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() || resp.Data["data"].(map[string]interface{})["bar"] != "foo" {
+		t.Fatalf("expected the first version to be unchanged, err: %s, resp %#v", err, resp)
+	}
+}
+
+// lostResponseStorage applies the writes of key metadata under prefix but
+// fails them, as when the response of the storage is lost.
+type lostResponseStorage struct {
+	logical.Storage
+	prefix string
+}
+
+func (s *lostResponseStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if err := s.Storage.Put(ctx, entry); err != nil {
+		return err
+	}
+	if strings.HasPrefix(entry.Key, s.prefix) {
+		return errors.New("injected failure")
+	}
+	return nil
+}
+
+func TestVersionedKV_OrphanedVersionsKeptIfReferenced(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage: &lostResponseStorage{
+			Storage: storage,
+			prefix:  path.Join(b.(*versionedKVBackend).storagePrefix, metadataPrefix),
+		},
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"bar": "baz"},
+		},
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected the write to fail, resp %#v", resp)
+	}
+
+	// The metadata was written despite the error, so the version data it
+	// references is kept
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() || resp.Data["data"] == nil {
+		t.Fatalf("expected the written version to be readable, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("unexpected data %#v", resp.Data["data"])
+	}
+}
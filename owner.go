@@ -0,0 +1,43 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// ownerPermitted returns true if the request may delete and destroy the
+// versions of the key, change its owner, and change it in a transaction.
+// Unless the config enforces ownership, and for keys without an owner, every
+// request is permitted.
+// Otherwise only requests from the owner entity, or with a token that has one
+// of the configured admin policies, are permitted.
+func (c *Configuration) ownerPermitted(req *logical.Request, meta *KeyMetadata) bool {
+	if !c.OwnerEnforced || meta == nil || meta.Owner == "" {
+		return true
+	}
+
+	if req.EntityID != "" && req.EntityID == meta.Owner {
+		return true
+	}
+
+	if te := req.TokenEntry(); te != nil {
+		for _, policy := range te.Policies {
+			if strutil.StrListContains(c.OwnerAdminPolicies, policy) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ownerDenied returns the response to a request that is not permitted by the
+// owner of the key.
+func ownerDenied(operation string) (*logical.Response, error) {
+	return logical.ErrorResponse(fmt.Sprintf("%s is restricted to the owner of the key", operation)), logical.ErrPermissionDenied
+}
@@ -0,0 +1,195 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Owner(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"owner_enforced":       true,
+			"owner_admin_policies": "kv-admin",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"owner": "alice",
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["owner"] != "alice" {
+		t.Fatalf("expected owner alice, got %v", resp.Data["owner"])
+	}
+
+	for _, r := range []*logical.Request{
+		{Operation: logical.DeleteOperation, Path: "data/foo"},
+		{Operation: logical.UpdateOperation, Path: "delete/foo", Data: map[string]interface{}{"versions": "1"}},
+		{Operation: logical.UpdateOperation, Path: "destroy/foo", Data: map[string]interface{}{"versions": "1"}},
+		{Operation: logical.DeleteOperation, Path: "metadata/foo"},
+		{Operation: logical.UpdateOperation, Path: "metadata/foo", Data: map[string]interface{}{"owner": "bob"}},
+		{Operation: logical.PatchOperation, Path: "metadata/foo", Data: map[string]interface{}{"owner": "bob"}},
+		{Operation: logical.UpdateOperation, Path: "copy/foo", Data: map[string]interface{}{"destination": "bar"}},
+		{Operation: logical.UpdateOperation, Path: "move/foo", Data: map[string]interface{}{"destination": "bar"}},
+	} {
+		r.Storage = storage
+		r.EntityID = "bob"
+
+		resp, err = b.HandleRequest(context.Background(), r)
+		if err != logical.ErrPermissionDenied || resp == nil || !resp.IsError() {
+			t.Fatalf("expected %s %s by another entity to be denied, err: %s, resp %#v", r.Operation, r.Path, err, resp)
+		}
+	}
+
+	// Changes to other settings are not restricted
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		EntityID:  "bob",
+		Data: map[string]interface{}{
+			"owner":        "alice",
+			"max_versions": 5,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "delete/foo",
+		Storage:   storage,
+		EntityID:  "alice",
+		Data: map[string]interface{}{
+			"versions": "1",
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("delete UpdateOperation request by the owner failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "destroy/foo",
+		Storage:   storage,
+		EntityID:  "carol",
+		Data: map[string]interface{}{
+			"versions": "1",
+		},
+	}
+	req.SetTokenEntry(&logical.TokenEntry{Policies: []string{"default", "kv-admin"}})
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("destroy UpdateOperation request by an admin failed, err: %s, resp %#v", err, resp)
+	}
+
+	meta, err := b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Versions[1].Destroyed {
+		t.Fatal("expected version 1 to be destroyed by an admin")
+	}
+}
+
+func TestVersionedKV_Owner_NotEnforced(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"owner": "alice",
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		EntityID:  "bob",
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata DeleteOperation request failed, err: %s, resp %#v", err, resp)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// readPassphraseSaltSize is the size in bytes of the random key of the HMAC
+// of a read passphrase.
+const readPassphraseSaltSize = 32
+
+// setReadPassphrase requires the passphrase to read the data of the key,
+// storing only its HMAC under a new random key. An empty passphrase removes
+// the requirement.
+func (k *KeyMetadata) setReadPassphrase(passphrase string) error {
+	if passphrase == "" {
+		k.ReadPassphraseHmac, k.ReadPassphraseSalt = nil, nil
+		return nil
+	}
+
+	salt := make([]byte, readPassphraseSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	k.ReadPassphraseSalt = salt
+	k.ReadPassphraseHmac = readPassphraseHMAC(salt, passphrase)
+	return nil
+}
+
+// readPassphraseRequired returns true if reading the data of the key requires
+// a passphrase.
+func (k *KeyMetadata) readPassphraseRequired() bool {
+	return len(k.ReadPassphraseHmac) > 0
+}
+
+// readPassphrasePermitted returns true if the passphrase permits reading the
+// data of the key, or if the key does not require one.
+func (k *KeyMetadata) readPassphrasePermitted(passphrase string) bool {
+	if !k.readPassphraseRequired() {
+		return true
+	}
+
+	return passphrase != "" && hmac.Equal(readPassphraseHMAC(k.ReadPassphraseSalt, passphrase), k.ReadPassphraseHmac)
+}
+
+func readPassphraseHMAC(salt []byte, passphrase string) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(passphrase))
+	return mac.Sum(nil)
+}
+
+// readPassphraseDenied returns the response to a read of a key that requires
+// a passphrase, without the correct passphrase.
+func readPassphraseDenied() (*logical.Response, error) {
+	return logical.ErrorResponse("reading this secret requires its passphrase in the passphrase parameter"), logical.ErrPermissionDenied
+}
@@ -0,0 +1,164 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_ReadPassphrase(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"v1_data_enabled": true,
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"read_passphrase": "open sesame",
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	meta, err := b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.ReadPassphraseSalt) != readPassphraseSaltSize || len(meta.ReadPassphraseHmac) == 0 {
+		t.Fatalf("expected the passphrase HMAC to be stored, got %#v", meta)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["read_passphrase_required"] != true {
+		t.Fatalf("expected read_passphrase_required, got %v", resp.Data["read_passphrase_required"])
+	}
+
+	for _, r := range []*logical.Request{
+		{Operation: logical.ReadOperation, Path: "data/foo"},
+		{Operation: logical.ReadOperation, Path: "data/foo", Data: map[string]interface{}{"passphrase": "wrong"}},
+		{Operation: logical.ReadOperation, Path: "v1-data/foo"},
+	} {
+		r.Storage = storage
+
+		resp, err = b.HandleRequest(context.Background(), r)
+		if err != logical.ErrPermissionDenied || resp == nil || !resp.IsError() {
+			t.Fatalf("expected read of %s with %v to be denied, err: %s, resp %#v", r.Path, r.Data, err, resp)
+		}
+	}
+
+	// Metadata only reads do not load the data
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"metadata_only": true,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata only ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"passphrase": "open sesame",
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request with the passphrase failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("unexpected data: %#v", resp.Data["data"])
+	}
+
+	// Patching other fields keeps the passphrase, and patching it with an
+	// empty string removes it.
+	for _, patch := range []map[string]interface{}{
+		{"max_versions": 5},
+		{"read_passphrase": ""},
+	} {
+		req = &logical.Request{
+			Operation: logical.PatchOperation,
+			Path:      "metadata/foo",
+			Storage:   storage,
+			Data:      patch,
+		}
+
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("metadata PatchOperation request failed, err: %s, resp %#v", err, resp)
+		}
+
+		meta, err = b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, cleared := patch["read_passphrase"]; meta.readPassphraseRequired() == cleared {
+			t.Fatalf("unexpected passphrase requirement %t after patch %v", meta.readPassphraseRequired(), patch)
+		}
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "v1-data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("v1-data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/vault/sdk/framework"
@@ -39,6 +40,7 @@ func LeaseSwitchedPassthroughBackendFactory(ctx context.Context, conf *logical.B
 	backend := &framework.Backend{
 		BackendType: logical.TypeLogical,
 		Help:        strings.TrimSpace(passthroughHelp),
+		Invalidate:  b.invalidate,
 
 		PathsSpecial: &logical.Paths{
 			SealWrapStorage: []string{
@@ -47,6 +49,7 @@ func LeaseSwitchedPassthroughBackendFactory(ctx context.Context, conf *logical.B
 		},
 
 		Paths: []*framework.Path{
+			pathPassthroughConfig(b),
 			{
 				Pattern: framework.MatchAllRegex("path"),
 
@@ -143,6 +146,7 @@ func LeaseSwitchedPassthroughBackendFactory(ctx context.Context, conf *logical.B
 	if conf == nil {
 		return nil, fmt.Errorf("configuration passed into backend is nil")
 	}
+	b.storagePrefix = conf.BackendUUID
 	backend.Setup(ctx, conf)
 	b.Backend = backend
 
@@ -156,6 +160,14 @@ func LeaseSwitchedPassthroughBackendFactory(ctx context.Context, conf *logical.B
 type PassthroughBackend struct {
 	*framework.Backend
 	generateLeases bool
+
+	// storagePrefix is the UUID of the mount, below which the config is
+	// stored.
+	storagePrefix string
+
+	// cachedConfig is the config of the mount, protected by configLock.
+	cachedConfig *passthroughConfig
+	configLock   sync.RWMutex
 }
 
 func (b *PassthroughBackend) handleExistenceCheck() framework.ExistenceFunc {
@@ -178,6 +190,10 @@ func (b *PassthroughBackend) handleExistenceCheck() framework.ExistenceFunc {
 // this for this function, but for consistency we also use req.Path throughout the other handlers for this path pattern.
 func (b *PassthroughBackend) handleReadOrRenew() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		if b.reservedPath(req.Path) {
+			return nil, nil
+		}
+
 		// Read the path
 		out, err := req.Storage.Get(ctx, req.Path)
 		if err != nil {
@@ -232,6 +248,20 @@ func (b *PassthroughBackend) handleReadOrRenew() framework.OperationFunc {
 			if b.generateLeases {
 				resp.Secret.Renewable = true
 			}
+		} else {
+			// Fall back to the default TTL configured for the prefix of the
+			// entry, if any
+			config, err := b.config(ctx, req.Storage)
+			if err != nil {
+				return nil, err
+			}
+			if dur, ok := config.defaultTTL(req.Path); ok {
+				ttlDuration = dur
+
+				if b.generateLeases {
+					resp.Secret.Renewable = true
+				}
+			}
 		}
 
 		resp.Secret.TTL = ttlDuration
@@ -245,6 +275,9 @@ func (b *PassthroughBackend) handleWrite() framework.OperationFunc {
 		if req.Path == "" {
 			return logical.ErrorResponse("missing path"), nil
 		}
+		if b.reservedPath(req.Path) {
+			return logical.ErrorResponse("cannot write to a path reserved by the backend"), logical.ErrInvalidRequest
+		}
 
 		// Check that some fields are given
 		if len(req.Data) == 0 {
@@ -266,7 +299,7 @@ func (b *PassthroughBackend) handleWrite() framework.OperationFunc {
 			return nil, fmt.Errorf("failed to write: %w", err)
 		}
 
-		kvEvent(ctx, b.Backend, "write", req.Path, req.Path, true, 1)
+		kvEvent(withEventRequest(ctx, req), b.Backend, "write", req.Path, req.Path, true, 1)
 
 		return nil, nil
 	}
@@ -274,12 +307,16 @@ func (b *PassthroughBackend) handleWrite() framework.OperationFunc {
 
 func (b *PassthroughBackend) handleDelete() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		if b.reservedPath(req.Path) {
+			return logical.ErrorResponse("cannot delete a path reserved by the backend"), logical.ErrInvalidRequest
+		}
+
 		// Delete the key at the request path
 		if err := req.Storage.Delete(ctx, req.Path); err != nil {
 			return nil, err
 		}
 
-		kvEvent(ctx, b.Backend, "delete", req.Path, "", true, 1)
+		kvEvent(withEventRequest(ctx, req), b.Backend, "delete", req.Path, "", true, 1)
 
 		return nil, nil
 	}
@@ -301,6 +338,17 @@ func (b *PassthroughBackend) handleList() framework.OperationFunc {
 			return nil, err
 		}
 
+		// Hide the entries the backend stores below the UUID of the mount
+		if path == "" && b.storagePrefix != "" {
+			filtered := keys[:0]
+			for _, key := range keys {
+				if key != b.storagePrefix+"/" {
+					filtered = append(filtered, key)
+				}
+			}
+			keys = filtered
+		}
+
 		// Generate the response
 		return logical.ListResponse(keys), nil
 	}
@@ -314,7 +362,8 @@ see the unencrypted value.
 
 TTLs can be set on a per-secret basis. These TTLs will be sent down
 when that secret is read, and it is assumed that some outside process will
-revoke and/or replace the secret at that path.
+revoke and/or replace the secret at that path. Default TTLs for secrets
+without one can be set by key prefix through the config path.
 `
 
 const passthroughHelpSynopsis = `
@@ -331,5 +380,6 @@ duration of leases returned by this backend will be set to this value. This
 can be used as a hint from the writer of a secret to the consumer of a secret
 that the consumer should re-read the value before the TTL has expired.
 However, any revocation must be handled by the user of this backend; the lease
-duration does not affect the provided data in any way.
+duration does not affect the provided data in any way. Reads of secrets without
+a "ttl" field use the default_ttls of the config path for their prefix, if set.
 `
@@ -0,0 +1,246 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// passthroughConfigKey is the location of the config of a KV v1 mount, below
+// the UUID of the mount. The upgrade to KV v2 skips keys below the UUID, so
+// the config is not mistaken for a secret.
+const passthroughConfigKey = "passthrough/config"
+
+// passthroughConfig is the config of a KV v1 mount.
+type passthroughConfig struct {
+	// DefaultTTLs maps key prefixes to the TTL of reads of the entries under
+	// that prefix that have no ttl or lease field.
+	DefaultTTLs map[string]time.Duration `json:"default_ttls"`
+}
+
+// defaultTTL returns the default TTL of reads of the entry at key, from the
+// longest matching prefix of the default_ttls.
+func (c *passthroughConfig) defaultTTL(key string) (time.Duration, bool) {
+	var ttl time.Duration
+	var matched string
+	var ok bool
+	for prefix, d := range c.DefaultTTLs {
+		if strings.HasPrefix(key, prefix) && len(prefix) >= len(matched) {
+			ttl, matched, ok = d, prefix, true
+		}
+	}
+	return ttl, ok
+}
+
+// pathPassthroughConfig returns the path configuration for the config
+// endpoint of a KV v1 mount. It must be added before the path matching every
+// key, which would otherwise serve it.
+func pathPassthroughConfig(b *PassthroughBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv1,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"default_ttls": {
+				Type: framework.TypeMap,
+				Description: `
+A map of key prefixes to the TTL of reads of the entries under that prefix that
+have no ttl or lease field of their own. The longest matching prefix applies.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.handleConfigRead(),
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "read",
+					OperationSuffix: "configuration",
+				},
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"default_ttls": {
+								Type:        framework.TypeMap,
+								Description: "A map of key prefixes to the TTL of reads of the entries under that prefix without a ttl field.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.handleConfigWrite(),
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb: "configure",
+				},
+				Responses: map[int][]framework.Response{
+					http.StatusNoContent: {{
+						Description: http.StatusText(http.StatusNoContent),
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    strings.TrimSpace(passthroughConfigHelpSyn),
+		HelpDescription: strings.TrimSpace(passthroughConfigHelpDesc),
+	}
+}
+
+// configStorageKey returns the storage key of the config, or an empty string
+// if the mount has no UUID to store it under.
+func (b *PassthroughBackend) configStorageKey() string {
+	if b.storagePrefix == "" {
+		return ""
+	}
+	return path.Join(b.storagePrefix, passthroughConfigKey)
+}
+
+// reservedPath returns true if key is below the UUID of the mount, where the
+// backend stores its own entries rather than secrets.
+func (b *PassthroughBackend) reservedPath(key string) bool {
+	return b.storagePrefix != "" && strings.HasPrefix(key, b.storagePrefix+"/")
+}
+
+// config returns the config of the mount, read from storage once and cached
+// until it is written or invalidated.
+func (b *PassthroughBackend) config(ctx context.Context, s logical.Storage) (*passthroughConfig, error) {
+	b.configLock.RLock()
+	if b.cachedConfig != nil {
+		defer b.configLock.RUnlock()
+		return b.cachedConfig, nil
+	}
+	b.configLock.RUnlock()
+
+	b.configLock.Lock()
+	defer b.configLock.Unlock()
+
+	if b.cachedConfig != nil {
+		return b.cachedConfig, nil
+	}
+
+	config := &passthroughConfig{}
+	if key := b.configStorageKey(); key != "" {
+		raw, err := s.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if raw != nil {
+			if err := jsonutil.DecodeJSON(raw.Value, config); err != nil {
+				return nil, fmt.Errorf("failed to decode config: %w", err)
+			}
+		}
+	}
+
+	b.cachedConfig = config
+	return config, nil
+}
+
+// invalidate clears the cached config when it is written by another node.
+func (b *PassthroughBackend) invalidate(ctx context.Context, key string) {
+	if key != "" && key == b.configStorageKey() {
+		b.configLock.Lock()
+		b.cachedConfig = nil
+		b.configLock.Unlock()
+	}
+}
+
+func (b *PassthroughBackend) handleConfigRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		defaultTTLs := make(map[string]interface{}, len(config.DefaultTTLs))
+		for prefix, ttl := range config.DefaultTTLs {
+			defaultTTLs[prefix] = ttl.String()
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"default_ttls": defaultTTLs,
+			},
+		}, nil
+	}
+}
+
+func (b *PassthroughBackend) handleConfigWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := b.configStorageKey()
+		if key == "" {
+			return nil, errors.New("could not configure the K/V store, no UUID was provided")
+		}
+
+		raw, ok := data.GetOk("default_ttls")
+		if !ok {
+			return nil, nil
+		}
+
+		defaultTTLs := make(map[string]time.Duration)
+		for prefix, ttlRaw := range raw.(map[string]interface{}) {
+			if b.reservedPath(prefix) {
+				return logical.ErrorResponse("invalid default_ttls prefix %q", prefix), logical.ErrInvalidRequest
+			}
+			ttl, err := parseutil.ParseDurationSecond(ttlRaw)
+			if err != nil {
+				return logical.ErrorResponse("invalid default_ttls value for prefix %q: %s", prefix, err), logical.ErrInvalidRequest
+			}
+			if ttl < 0 {
+				return logical.ErrorResponse("default_ttls value for prefix %q cannot be negative", prefix), logical.ErrInvalidRequest
+			}
+			defaultTTLs[prefix] = ttl
+		}
+
+		config := &passthroughConfig{DefaultTTLs: defaultTTLs}
+		buf, err := jsonutil.EncodeJSON(config)
+		if err != nil {
+			return nil, err
+		}
+
+		b.configLock.Lock()
+		defer b.configLock.Unlock()
+
+		if err := req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   key,
+			Value: buf,
+		}); err != nil {
+			return nil, err
+		}
+
+		b.cachedConfig = config
+		return nil, nil
+	}
+}
+
+const passthroughConfigHelpSyn = `
+Configures the default TTLs of the K/V store.
+`
+
+const passthroughConfigHelpDesc = `
+The "default_ttls" map sets the TTL of reads of entries that have no "ttl" or
+"lease" field of their own, by key prefix, so that lease durations of existing
+entries can be managed without rewriting them. The longest matching prefix
+applies, and entries matching no prefix keep the default lease TTL of the
+mount. If the mount was enabled with leased_passthrough, the leases of reads
+using a default TTL are renewable, like those of entries with a ttl field.
+
+Writing "default_ttls" replaces the current map; an empty map removes all
+defaults. Because this path serves the config, an entry stored at the key
+"config" cannot be read or written through the API.
+`
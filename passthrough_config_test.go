@@ -0,0 +1,130 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPassthroughBackend_DefaultTTLs(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	b, err := LeasedPassthroughBackendFactory(ctx, &logical.BackendConfig{
+		System: logical.StaticSystemView{
+			DefaultLeaseTTLVal: time.Hour * 24,
+			MaxLeaseTTLVal:     time.Hour * 24 * 32,
+		},
+		StorageView: storage,
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"default_ttls": map[string]interface{}{
+					"legacy/":      "1h",
+					"legacy/slow/": 7200,
+				},
+			},
+		},
+		{Operation: logical.UpdateOperation, Path: "legacy/foo", Data: map[string]interface{}{"bar": "baz"}},
+		{Operation: logical.UpdateOperation, Path: "legacy/slow/foo", Data: map[string]interface{}{"bar": "baz"}},
+		{Operation: logical.UpdateOperation, Path: "legacy/ttl", Data: map[string]interface{}{"bar": "baz", "ttl": "5m"}},
+		{Operation: logical.UpdateOperation, Path: "other", Data: map[string]interface{}{"bar": "baz"}},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	for _, tc := range []struct {
+		path      string
+		ttl       time.Duration
+		renewable bool
+	}{
+		{"legacy/foo", time.Hour, true},
+		{"legacy/slow/foo", 2 * time.Hour, true},
+		{"legacy/ttl", 5 * time.Minute, true},
+		{"other", 24 * time.Hour, false},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      tc.path,
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("read of %s failed, err: %s, resp %#v", tc.path, err, resp)
+		}
+		if resp.Secret.TTL != tc.ttl || resp.Secret.Renewable != tc.renewable {
+			t.Fatalf("unexpected lease of %s: ttl %s, renewable %t", tc.path, resp.Secret.TTL, resp.Secret.Renewable)
+		}
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("config read failed, err: %s, resp %#v", err, resp)
+	}
+	if ttls := resp.Data["default_ttls"].(map[string]interface{}); ttls["legacy/"] != "1h0m0s" || ttls["legacy/slow/"] != "2h0m0s" {
+		t.Fatalf("unexpected default_ttls: %#v", ttls)
+	}
+
+	// The stored config is neither listed nor writable as a secret
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("list failed, err: %s, resp %#v", err, resp)
+	}
+	for _, key := range resp.Data["keys"].([]string) {
+		if key == "test/" {
+			t.Fatalf("unexpected key in list: %v", resp.Data["keys"])
+		}
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "test/" + passthroughConfigKey,
+		Storage:   storage,
+		Data:      map[string]interface{}{"default_ttls": "invalid"},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the write to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	// A new backend reads the stored config
+	b, err = LeasedPassthroughBackendFactory(ctx, &logical.BackendConfig{
+		System:      logical.StaticSystemView{DefaultLeaseTTLVal: time.Hour * 24},
+		StorageView: storage,
+		BackendUUID: "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "legacy/foo",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.Secret.TTL != time.Hour {
+		t.Fatalf("unexpected read of legacy/foo, err: %s, resp %#v", err, resp)
+	}
+}
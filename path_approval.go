@@ -0,0 +1,367 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// approvalPrefix is the prefix where pending approval requests are
+	// stored.
+	approvalPrefix string = "approvals/"
+
+	// defaultApprovalTTL is how long a pending approval request can be
+	// approved unless configured otherwise.
+	defaultApprovalTTL = 24 * time.Hour
+)
+
+// approvedContextKey marks the context of an operation that runs because its
+// approval request was approved.
+type approvedContextKey struct{}
+
+// approvalTTL returns how long a pending approval request can be approved.
+func approvalTTL(config *Configuration) time.Duration {
+	if ttl := durationOrZero(config.GetApprovalTtl()); ttl > 0 {
+		return ttl
+	}
+	return defaultApprovalTTL
+}
+
+// approvalRequired returns true if destructive operations on key must be
+// approved by a second token.
+func (c *Configuration) approvalRequired(key string) bool {
+	for _, prefix := range c.ApprovalRequiredPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// approvalCheck wraps the handler of a destructive operation. Under the
+// approval_required_prefixes of the config, the request is stored as a
+// pending approval request instead of running the operation, which runs
+// once a second token approves it.
+func (b *versionedKVBackend) approvalCheck(operation string, op framework.OperationFunc) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		if ctx.Value(approvedContextKey{}) != nil {
+			return op(ctx, req, data)
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		key := data.Get("path").(string)
+		if !config.approvalRequired(key) {
+			return op(ctx, req, data)
+		}
+
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		now := b.now()
+		expireTime, err := ptypes.TimestampProto(now.Add(approvalTTL(config)))
+		if err != nil {
+			return nil, err
+		}
+
+		approval := &ApprovalRequest{
+			Id:                id,
+			Operation:         operation,
+			Key:               key,
+			RequesterEntityId: req.EntityID,
+			RequesterAccessor: req.ClientTokenAccessor,
+			CreatedTime:       b.timestampNow(),
+			ExpireTime:        expireTime,
+		}
+		if raw, ok := data.GetOk("versions"); ok {
+			approval.Versions = raw.([]string)
+		}
+		if raw, ok := data.GetOk("require_destroyed"); ok {
+			approval.RequireDestroyed = raw.(bool)
+		}
+
+		buf, err := proto.Marshal(approval)
+		if err != nil {
+			return nil, err
+		}
+		if err := req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   path.Join(b.storagePrefix, approvalPrefix, id),
+			Value: buf,
+		}); err != nil {
+			return nil, err
+		}
+
+		resp := &logical.Response{
+			Data: approvalResponseData(approval),
+		}
+		resp.AddWarning(fmt.Sprintf("%s of keys under this prefix requires approval by a second token, see approval/%s", operation, id))
+		return logical.RespondWithStatusCode(resp, req, http.StatusAccepted)
+	}
+}
+
+// pathApproval returns the path configuration for the approval endpoints
+func pathApproval(b *versionedKVBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "approval/" + framework.GenericNameRegex("id"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixKVv2,
+				OperationSuffix: "approval",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: "The ID of the approval request.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathApprovalRead()),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "read",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: http.StatusText(http.StatusOK),
+							Fields:      approvalResponseFields,
+						}},
+					},
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathApprovalApprove()),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "approve",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: http.StatusText(http.StatusNoContent),
+						}},
+					},
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathApprovalDelete()),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "reject",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: http.StatusText(http.StatusNoContent),
+						}},
+					},
+				},
+			},
+
+			HelpSynopsis:    approvalHelpSyn,
+			HelpDescription: approvalHelpDesc,
+		},
+		{
+			Pattern: "approval/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixKVv2,
+				OperationVerb:   "list",
+				OperationSuffix: "approvals",
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathApprovalList()),
+				},
+			},
+
+			HelpSynopsis:    approvalHelpSyn,
+			HelpDescription: approvalHelpDesc,
+		},
+	}
+}
+
+var approvalResponseFields = map[string]*framework.FieldSchema{
+	"id": {
+		Type:     framework.TypeString,
+		Required: true,
+	},
+	"operation": {
+		Type:     framework.TypeString,
+		Required: true,
+	},
+	"path": {
+		Type:     framework.TypeString,
+		Required: true,
+	},
+	"versions": {
+		Type:     framework.TypeCommaStringSlice,
+		Required: true,
+	},
+	"created_time": {
+		Type:     framework.TypeTime,
+		Required: true,
+	},
+	"expire_time": {
+		Type:     framework.TypeTime,
+		Required: true,
+	},
+}
+
+// approvalResponseData returns the response data describing an approval
+// request.
+func approvalResponseData(approval *ApprovalRequest) map[string]interface{} {
+	versions := approval.Versions
+	if versions == nil {
+		versions = []string{}
+	}
+
+	return map[string]interface{}{
+		"id":           approval.Id,
+		"operation":    approval.Operation,
+		"path":         approval.Key,
+		"versions":     versions,
+		"created_time": ptypesTimestampToString(approval.CreatedTime),
+		"expire_time":  ptypesTimestampToString(approval.ExpireTime),
+	}
+}
+
+// getApproval returns the pending approval request with the given ID. Expired
+// requests are deleted and not returned.
+func (b *versionedKVBackend) getApproval(ctx context.Context, s logical.Storage, id string) (*ApprovalRequest, error) {
+	storageKey := path.Join(b.storagePrefix, approvalPrefix, id)
+
+	raw, err := s.Get(ctx, storageKey)
+	if err != nil || raw == nil {
+		return nil, err
+	}
+
+	approval := &ApprovalRequest{}
+	if err := proto.Unmarshal(raw.Value, approval); err != nil {
+		return nil, fmt.Errorf("failed to decode approval request from storage: %w", err)
+	}
+
+	expireTime, err := ptypes.Timestamp(approval.ExpireTime)
+	if err != nil {
+		return nil, err
+	}
+	if !expireTime.After(b.now()) {
+		return nil, s.Delete(ctx, storageKey)
+	}
+
+	return approval, nil
+}
+
+func (b *versionedKVBackend) pathApprovalRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		approval, err := b.getApproval(ctx, req.Storage, data.Get("id").(string))
+		if err != nil || approval == nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: approvalResponseData(approval),
+		}, nil
+	}
+}
+
+// pathApprovalApprove approves a pending request and runs its operation. The
+// token approving the request must differ from the token that made it.
+func (b *versionedKVBackend) pathApprovalApprove() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		id := data.Get("id").(string)
+
+		approval, err := b.getApproval(ctx, req.Storage, id)
+		if err != nil {
+			return nil, err
+		}
+		if approval == nil {
+			return logical.ErrorResponse("no pending approval request with ID %q", id), logical.ErrInvalidRequest
+		}
+
+		// Tokens without an entity could approve their own requests with a
+		// second token, so the approver must have an entity of its own
+		if req.EntityID == "" {
+			return logical.ErrorResponse("the request must be approved by a token with an entity"), logical.ErrPermissionDenied
+		}
+		if approval.RequesterEntityId == req.EntityID ||
+			(approval.RequesterAccessor != "" && approval.RequesterAccessor == req.ClientTokenAccessor) {
+			return logical.ErrorResponse("the request must be approved by a different entity than the one that made it"), logical.ErrPermissionDenied
+		}
+
+		raw := map[string]interface{}{
+			"path": approval.Key,
+		}
+
+		var fields map[string]*framework.FieldSchema
+		var op framework.OperationFunc
+		switch approval.Operation {
+		case "destroy":
+			fields, op = pathDestroy(b).Fields, b.pathDestroyWrite()
+			raw["versions"] = approval.Versions
+		case "metadata-delete":
+			fields, op = pathMetadata(b).Fields, b.pathMetadataDelete()
+			raw["require_destroyed"] = approval.RequireDestroyed
+		default:
+			return nil, fmt.Errorf("unknown operation %q in approval request", approval.Operation)
+		}
+
+		// Delete the request before running the operation so that it cannot
+		// be approved twice
+		if err := req.Storage.Delete(ctx, path.Join(b.storagePrefix, approvalPrefix, id)); err != nil {
+			return nil, err
+		}
+
+		return op(context.WithValue(ctx, approvedContextKey{}, true), req, &framework.FieldData{
+			Raw:    raw,
+			Schema: fields,
+		})
+	}
+}
+
+func (b *versionedKVBackend) pathApprovalDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		return nil, req.Storage.Delete(ctx, path.Join(b.storagePrefix, approvalPrefix, data.Get("id").(string)))
+	}
+}
+
+func (b *versionedKVBackend) pathApprovalList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		ids, err := req.Storage.List(ctx, path.Join(b.storagePrefix, approvalPrefix)+"/")
+		return logical.ListResponse(ids), err
+	}
+}
+
+const approvalHelpSyn = `Approves or rejects destructive operations awaiting a second token.`
+const approvalHelpDesc = `
+Destroy requests, and metadata delete requests, on keys under the prefixes
+listed in the approval_required_prefixes config parameter are not run when
+they are made. Instead they are stored as pending approval requests, and the
+response contains the ID of the request.
+
+Reading approval/<id> returns the pending request. Writing to approval/<id>
+approves the request and runs its operation; the request must be approved by a
+token with an entity, other than the entity and token that made it, before the
+approval_ttl of the config passes. Deleting approval/<id> rejects the request.
+Listing approval/ returns the IDs of the pending requests.
+
+Approvers need the "update" capability on approval/* and the "read" capability
+to review the request first. The operation runs when it is approved, without a
+check of the ACL of the approver on the destroy or metadata path of the key, so
+the capability on approval/* should only be granted to the entities allowed to
+run the operations awaiting approval.
+`
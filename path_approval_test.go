@@ -0,0 +1,115 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Approval_Destroy(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"approval_required_prefixes": "prod/",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/prod/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "destroy/prod/foo",
+		Storage:   storage,
+		EntityID:  "requester",
+		Data: map[string]interface{}{
+			"versions": "1",
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.Data[logical.HTTPStatusCode] != http.StatusAccepted {
+		t.Fatalf("expected destroy to await approval, err: %s, resp %#v", err, resp)
+	}
+
+	// Use of logical.RespondWithStatusCode in handler will
+	// serialize the JSON response body as a string
+	respBody := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(resp.Data[logical.HTTPRawBody].(string)), &respBody); err != nil {
+		t.Fatal(err)
+	}
+	id := respBody["data"].(map[string]interface{})["id"].(string)
+
+	destroyed := func() bool {
+		meta, err := b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "prod/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return meta.Versions[1].Destroyed
+	}
+	if destroyed() {
+		t.Fatal("expected version to not be destroyed before approval")
+	}
+
+	approve := func(entityID string) (*logical.Response, error) {
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "approval/" + id,
+			Storage:   storage,
+			EntityID:  entityID,
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	resp, err = approve("requester")
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected approval by the requester to be denied, err: %s, resp %#v", err, resp)
+	}
+
+	// A token without an entity could be a second token of the requester
+	resp, err = approve("")
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected approval without an entity to be denied, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = approve("approver")
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("approval request failed, err: %s, resp %#v", err, resp)
+	}
+	if !destroyed() {
+		t.Fatal("expected version to be destroyed after approval")
+	}
+
+	resp, err = approve("approver")
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected request to not be approved twice, err: %s, resp %#v", err, resp)
+	}
+}
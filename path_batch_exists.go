@@ -0,0 +1,148 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// maxBatchExistsPaths is the maximum number of paths checked by a single
+// batch/exists request.
+const maxBatchExistsPaths = 256
+
+// pathBatchExists returns the path configuration for the batch/exists
+// endpoint
+func pathBatchExists(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "batch/exists/?$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "check",
+			OperationSuffix: "exists",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"paths": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: fmt.Sprintf("The paths of the secrets to check, at most %d.", maxBatchExistsPaths),
+				Required:    true,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathBatchExistsWrite()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"results": {
+								Type:        framework.TypeSlice,
+								Description: "The path, whether metadata exists, whether the current version is readable and the current version of each secret, in order.",
+								Required:    true,
+							},
+							"all_readable": {
+								Type:        framework.TypeBool,
+								Description: "True if the current version of every secret is readable.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    batchExistsHelpSyn,
+		HelpDescription: batchExistsHelpDesc,
+	}
+}
+
+// pathBatchExistsWrite handles update commands checking the existence of
+// multiple keys. Only the key metadata is read.
+func (b *versionedKVBackend) pathBatchExistsWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		paths := data.Get("paths").([]string)
+		switch {
+		case len(paths) == 0:
+			return logical.ErrorResponse("no paths provided"), logical.ErrInvalidRequest
+		case len(paths) > maxBatchExistsPaths:
+			return logical.ErrorResponse("cannot check more than %d paths", maxBatchExistsPaths), logical.ErrInvalidRequest
+		}
+		for _, key := range paths {
+			if key == "" {
+				return logical.ErrorResponse("paths cannot be empty"), logical.ErrInvalidRequest
+			}
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		now := b.now()
+		results := make([]interface{}, 0, len(paths))
+		allReadable := true
+		for _, key := range paths {
+			meta, err := b.keyMetadataWithReadLock(ctx, req.Storage, key)
+			if err != nil {
+				return nil, err
+			}
+
+			readable := meta != nil && currentVersionReadable(config, meta, now)
+			allReadable = allReadable && readable
+			results = append(results, map[string]interface{}{
+				"path":            key,
+				"exists":          meta != nil,
+				"readable":        readable,
+				"current_version": meta.GetCurrentVersion(),
+			})
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"results":      results,
+				"all_readable": allReadable,
+			},
+		}, nil
+	}
+}
+
+// keyMetadataWithReadLock returns the metadata of the key, read while holding
+// the read lock of the key.
+func (b *versionedKVBackend) keyMetadataWithReadLock(ctx context.Context, s logical.Storage, key string) (*KeyMetadata, error) {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	return b.getKeyMetadata(ctx, s, key)
+}
+
+// currentVersionReadable returns true if a read of the key would return the
+// data of its current version, which must neither be deleted nor destroyed.
+func currentVersionReadable(config *Configuration, meta *KeyMetadata, now time.Time) bool {
+	vm := meta.Versions[meta.CurrentVersion]
+	return vm != nil && !vm.Destroyed && !versionExpired(vm, now) && !versionDestroyDue(config, meta, vm, now)
+}
+
+const batchExistsHelpSyn = `Checks whether multiple secrets exist and are readable.`
+const batchExistsHelpDesc = `
+This endpoint takes a list of "paths" and returns, for each in order, whether
+metadata "exists" for the path, whether its current version is "readable",
+that is neither deleted nor destroyed, and its "current_version". The
+"all_readable" field is true if every secret is readable, so that a preflight
+check for the secrets required by a deployment takes a single request.
+
+Only the metadata of the secrets is read, never their data, and a secret
+requiring a read passphrase is reported as readable. The result reveals which
+paths exist regardless of the policies on them, so grant access to this
+endpoint as to listing the mount.
+`
@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_BatchExists(t *testing.T) {
+	b, storage := getBackend(t)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/app/db",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"password": "secret"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/app/api",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"token": "secret"},
+			},
+		},
+		{
+			Operation: logical.DeleteOperation,
+			Path:      "data/app/api",
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "metadata/app/pending",
+			Data: map[string]interface{}{
+				"max_versions": 2,
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "batch/exists",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"paths": "app/db,app/api,app/pending,app/missing",
+		},
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("batch/exists UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{"path": "app/db", "exists": true, "readable": true, "current_version": uint64(1)},
+		map[string]interface{}{"path": "app/api", "exists": true, "readable": false, "current_version": uint64(1)},
+		map[string]interface{}{"path": "app/pending", "exists": true, "readable": false, "current_version": uint64(0)},
+		map[string]interface{}{"path": "app/missing", "exists": false, "readable": false, "current_version": uint64(0)},
+	}
+	if !reflect.DeepEqual(resp.Data["results"], expected) {
+		t.Fatalf("unexpected results, expected %#v, got %#v", expected, resp.Data["results"])
+	}
+	if resp.Data["all_readable"] != false {
+		t.Fatalf("expected all_readable to be false, resp: %#v", resp.Data)
+	}
+
+	req.Data = map[string]interface{}{
+		"paths": []string{"app/db"},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("batch/exists UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["all_readable"] != true {
+		t.Fatalf("expected all_readable to be true, resp: %#v", resp.Data)
+	}
+
+	req.Data = map[string]interface{}{
+		"paths": []string{},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a request without paths to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// keyIDPrefix is the prefix where the path of each key is stored under its
+// opaque ID.
+const keyIDPrefix string = "key-ids/"
+
+// pathsByID returns the path configurations for resolving keys by their
+// opaque ID.
+func pathsByID(b *versionedKVBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "by-id/" + framework.GenericNameRegex("id"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixKVv2,
+				OperationVerb:   "read",
+				OperationSuffix: "by-id",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: "The opaque ID of the key.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathByIDRead()),
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: http.StatusText(http.StatusOK),
+							Fields: map[string]*framework.FieldSchema{
+								"id": {
+									Type:        framework.TypeString,
+									Description: "The opaque ID of the key.",
+									Required:    true,
+								},
+								"path": {
+									Type:        framework.TypeString,
+									Description: "The current path of the key.",
+									Required:    true,
+								},
+								"current_version": {
+									Type:        framework.TypeInt64, // uint64
+									Description: "The current version of the key.",
+									Required:    true,
+								},
+								"created_time": {
+									Type:        framework.TypeTime,
+									Description: "When the key was created.",
+									Required:    true,
+								},
+								"updated_time": {
+									Type:        framework.TypeTime,
+									Description: "When the metadata of the key was last written.",
+									Required:    true,
+								},
+							},
+						}},
+					},
+				},
+			},
+
+			HelpSynopsis:    byIDHelpSyn,
+			HelpDescription: byIDHelpDesc,
+		},
+		{
+			Pattern: "by-id/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixKVv2,
+				OperationVerb:   "list",
+				OperationSuffix: "by-id",
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathByIDList()),
+				},
+			},
+
+			HelpSynopsis:    byIDHelpSyn,
+			HelpDescription: byIDHelpDesc,
+		},
+	}
+}
+
+// putKeyID stores the path of the key with the given ID.
+func (b *versionedKVBackend) putKeyID(ctx context.Context, s logical.Storage, id, key string) error {
+	buf, err := proto.Marshal(&KeyIDEntry{Key: key})
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, &logical.StorageEntry{
+		Key:   path.Join(b.storagePrefix, keyIDPrefix, id),
+		Value: buf,
+	})
+}
+
+// getKeyID returns the path stored for the given ID, or an empty string if
+// there is none.
+func (b *versionedKVBackend) getKeyID(ctx context.Context, s logical.Storage, id string) (string, error) {
+	raw, err := s.Get(ctx, path.Join(b.storagePrefix, keyIDPrefix, id))
+	if err != nil {
+		return "", err
+	}
+	if raw == nil {
+		return "", nil
+	}
+
+	entry := &KeyIDEntry{}
+	if err := proto.Unmarshal(raw.Value, entry); err != nil {
+		return "", fmt.Errorf("failed to decode key ID entry: %w", err)
+	}
+	return entry.Key, nil
+}
+
+// deleteKeyID deletes the entry of the ID of the key, unless the ID now
+// belongs to another path, as when the key was moved.
+func (b *versionedKVBackend) deleteKeyID(ctx context.Context, s logical.Storage, meta *KeyMetadata) error {
+	if meta.Id == "" {
+		return nil
+	}
+
+	key, err := b.getKeyID(ctx, s, meta.Id)
+	if err != nil {
+		return err
+	}
+	if key != meta.Key {
+		return nil
+	}
+	return s.Delete(ctx, path.Join(b.storagePrefix, keyIDPrefix, meta.Id))
+}
+
+// pathByIDRead resolves an ID to the path and a summary of the metadata of
+// the key. The secret data is not returned, so that it stays governed by the
+// policies on the path of the key.
+func (b *versionedKVBackend) pathByIDRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		id := data.Get("id").(string)
+
+		key, err := b.getKeyID(ctx, req.Storage, id)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			return nil, nil
+		}
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.RLock()
+		defer lock.RUnlock()
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		// An entry left behind for metadata rebuilt with another ID is not
+		// followed
+		if meta == nil || meta.Id != id {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"id":              id,
+				"path":            key,
+				"current_version": meta.CurrentVersion,
+				"created_time":    ptypesTimestampToString(meta.CreatedTime),
+				"updated_time":    ptypesTimestampToString(meta.UpdatedTime),
+			},
+		}, nil
+	}
+}
+
+// pathByIDList lists the IDs of all keys with their paths. It is a root
+// path, as the paths are returned regardless of the policies on them.
+func (b *versionedKVBackend) pathByIDList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		ids, err := req.Storage.List(ctx, path.Join(b.storagePrefix, keyIDPrefix)+"/")
+		if err != nil {
+			return nil, err
+		}
+
+		keyInfo := make(map[string]interface{}, len(ids))
+		for _, id := range ids {
+			key, err := b.getKeyID(ctx, req.Storage, id)
+			if err != nil {
+				return nil, err
+			}
+			if key == "" {
+				continue
+			}
+
+			keyInfo[id] = map[string]interface{}{
+				"path": key,
+			}
+		}
+
+		return logical.ListResponseWithInfo(ids, keyInfo), nil
+	}
+}
+
+const byIDHelpSyn = `Resolves the opaque IDs of secrets to their paths.`
+const byIDHelpDesc = `
+Each secret is assigned an opaque ID, a UUID returned as "id" by metadata
+reads, when its metadata is first written. Secrets written before IDs were
+introduced are assigned one with their next write. The ID is kept when the
+secret is moved, and a copy is assigned a new ID, so external systems can
+reference a secret by its ID across moves.
+
+Reading by-id/<id> returns the current path of the secret with that ID and a
+summary of its metadata. The secret data is not returned: read it at the
+returned path, so that the policies on the path of the secret still apply.
+Listing by-id/ returns the IDs of the secrets, with their paths in the key
+info. As the listing reveals the path of every secret of the mount, which
+ACL policies on metadata/ cannot restrict, it requires sudo.
+`
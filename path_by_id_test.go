@@ -0,0 +1,135 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_ByID(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	mustRequest := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		req := &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", operation, path, err, resp)
+		}
+		if resp != nil && operation == logical.ReadOperation {
+			schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route(req.Path), req.Operation), resp, true)
+		}
+		return resp
+	}
+	readID := func(path string) string {
+		t.Helper()
+		id := mustRequest(logical.ReadOperation, "metadata/"+path, nil).Data["id"].(string)
+		if id == "" {
+			t.Fatalf("expected %s to have an id", path)
+		}
+		return id
+	}
+	expectPath := func(id, path string) {
+		t.Helper()
+		resp := mustRequest(logical.ReadOperation, "by-id/"+id, nil)
+		if path == "" {
+			if resp != nil {
+				t.Fatalf("expected no key for %s, got %#v", id, resp.Data)
+			}
+			return
+		}
+		if resp == nil || resp.Data["path"] != path || resp.Data["id"] != id {
+			t.Fatalf("expected %s to resolve to %s, got %#v", id, path, resp)
+		}
+		if _, ok := resp.Data["data"]; ok {
+			t.Fatal("expected no secret data in the response")
+		}
+	}
+
+	mustRequest(logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"bar": "baz"},
+	})
+	id := readID("foo")
+	expectPath(id, "foo")
+
+	// Later writes keep the ID
+	mustRequest(logical.UpdateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"bar": "qux"},
+	})
+	mustRequest(logical.UpdateOperation, "metadata/foo", map[string]interface{}{"max_versions": 5})
+	if got := readID("foo"); got != id {
+		t.Fatalf("expected the id %s to be kept, got %s", id, got)
+	}
+	resp := mustRequest(logical.ReadOperation, "by-id/"+id, nil)
+	if resp.Data["current_version"] != uint64(2) {
+		t.Fatalf("unexpected response: %#v", resp.Data)
+	}
+
+	// A copy is assigned a new ID, a moved key keeps its ID
+	mustRequest(logical.UpdateOperation, "copy/foo", map[string]interface{}{"destination": "copied"})
+	copiedID := readID("copied")
+	if copiedID == id {
+		t.Fatal("expected the copy to be assigned a new id")
+	}
+	expectPath(copiedID, "copied")
+	expectPath(id, "foo")
+
+	mustRequest(logical.UpdateOperation, "move/foo", map[string]interface{}{"destination": "moved"})
+	if got := readID("moved"); got != id {
+		t.Fatalf("expected the moved key to keep the id %s, got %s", id, got)
+	}
+	expectPath(id, "moved")
+
+	if !strutil.StrListContains(b.SpecialPaths().Root, "by-id/") {
+		t.Fatalf("expected listing IDs to require sudo: %v", b.SpecialPaths().Root)
+	}
+	resp = mustRequest(logical.ListOperation, "by-id/", nil)
+	keyInfo := resp.Data["key_info"].(map[string]interface{})
+	if len(resp.Data["keys"].([]string)) != 2 || len(keyInfo) != 2 {
+		t.Fatalf("unexpected list response: %#v", resp.Data)
+	}
+	if keyInfo[id].(map[string]interface{})["path"] != "moved" || keyInfo[copiedID].(map[string]interface{})["path"] != "copied" {
+		t.Fatalf("unexpected key info: %#v", keyInfo)
+	}
+
+	// Deleting the metadata deletes the ID
+	mustRequest(logical.DeleteOperation, "metadata/moved", nil)
+	expectPath(id, "")
+	expectPath("unknown", "")
+	resp = mustRequest(logical.ListOperation, "by-id/", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != copiedID {
+		t.Fatalf("unexpected list response: %#v", resp.Data)
+	}
+
+	// A key written before IDs were introduced is assigned one with its next
+	// write
+	meta, err := kvb.getKeyMetadata(ctx, storage, "copied")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kvb.deleteKeyID(ctx, storage, meta); err != nil {
+		t.Fatal(err)
+	}
+	meta.Id = ""
+	if err := kvb.writeKeyMetadata(ctx, storage, meta); err != nil {
+		t.Fatal(err)
+	}
+	newID := readID("copied")
+	if newID == copiedID {
+		t.Fatal("expected a new id to be assigned")
+	}
+	expectPath(newID, "copied")
+}
@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// probeOperation describes the API path and the ACL capabilities required
+// for a KV operation on a secret.
+type probeOperation struct {
+	prefix       string
+	capabilities []string
+}
+
+// probeOperations maps the KV operations on a secret to the path prefix and
+// capabilities a policy must grant for them.
+var probeOperations = map[string]probeOperation{
+	"read":            {"data/", []string{"read"}},
+	"write":           {"data/", []string{"create", "update"}},
+	"patch":           {"data/", []string{"patch"}},
+	"delete":          {"data/", []string{"delete"}},
+	"delete_versions": {"delete/", []string{"update"}},
+	"undelete":        {"undelete/", []string{"update"}},
+	"destroy":         {"destroy/", []string{"update"}},
+	"subkeys":         {"subkeys/", []string{"read"}},
+	"metadata_read":   {"metadata/", []string{"read"}},
+	"metadata_write":  {"metadata/", []string{"create", "update"}},
+	"metadata_patch":  {"metadata/", []string{"patch"}},
+	"metadata_delete": {"metadata/", []string{"delete"}},
+	"list":            {"metadata/", []string{"list"}},
+}
+
+// pathCapabilitiesProbe returns the path configuration for the
+// capabilities-probe endpoint
+func pathCapabilitiesProbe(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "capabilities-probe/" + matchAllNoTrailingSlashRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "read",
+			OperationSuffix: "capabilities-probe",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathCapabilitiesProbeRead(),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"operations": {
+								Type:        framework.TypeMap,
+								Description: "A map of KV operations to the path and capabilities a policy must grant for them.",
+								Required:    true,
+							},
+							"paths": {
+								Type:        framework.TypeStringSlice,
+								Description: "The paths used by the KV operations, suitable for the sys/capabilities-self endpoint.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    capabilitiesProbeHelpSyn,
+		HelpDescription: capabilitiesProbeHelpDesc,
+	}
+}
+
+// pathCapabilitiesProbeRead returns the ACL paths and capabilities used by
+// each KV operation on the secret at the requested path.
+func (b *versionedKVBackend) pathCapabilitiesProbeRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := data.Get("path").(string)
+
+		operations := make(map[string]interface{}, len(probeOperations))
+		paths := map[string]struct{}{}
+		for name, op := range probeOperations {
+			p := req.MountPoint + op.prefix + key
+			operations[name] = map[string]interface{}{
+				"path":         p,
+				"capabilities": op.capabilities,
+			}
+			paths[p] = struct{}{}
+		}
+
+		pathList := make([]string, 0, len(paths))
+		for p := range paths {
+			pathList = append(pathList, p)
+		}
+		sort.Strings(pathList)
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"operations": operations,
+				"paths":      pathList,
+			},
+		}, nil
+	}
+}
+
+const capabilitiesProbeHelpSyn = `Returns the ACL paths and capabilities used by the KV operations on a secret.`
+const capabilitiesProbeHelpDesc = `
+The operations on a versioned secret are served by several API paths, and a
+policy must grant capabilities on the matching path for each operation; for
+example, reading the secret "foo" requires the "read" capability on
+"data/foo", while listing it requires the "list" capability on "metadata/foo".
+
+This endpoint returns, for each KV operation on the secret at the requested
+path, the full API path including the mount and the capabilities a policy
+must grant on it. The returned "paths" can be passed to the
+sys/capabilities-self endpoint to check which of the operations are allowed
+for the calling token. Plugins cannot evaluate the policies of a token, so
+this endpoint does not report whether an operation is allowed itself.
+`
@@ -0,0 +1,56 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_CapabilitiesProbe(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation:  logical.ReadOperation,
+		Path:       "capabilities-probe/foo/bar",
+		MountPoint: "secret/",
+		Storage:    storage,
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("capabilities-probe request failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(
+		t,
+		schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+		resp,
+		true,
+	)
+
+	expectedPaths := []string{
+		"secret/data/foo/bar",
+		"secret/delete/foo/bar",
+		"secret/destroy/foo/bar",
+		"secret/metadata/foo/bar",
+		"secret/subkeys/foo/bar",
+		"secret/undelete/foo/bar",
+	}
+	if diff := deep.Equal(resp.Data["paths"], expectedPaths); len(diff) > 0 {
+		t.Fatalf("unexpected paths, diff: %#v", diff)
+	}
+
+	operations := resp.Data["operations"].(map[string]interface{})
+	expected := map[string]interface{}{
+		"path":         "secret/metadata/foo/bar",
+		"capabilities": []string{"list"},
+	}
+	if diff := deep.Equal(operations["list"], expected); len(diff) > 0 {
+		t.Fatalf("unexpected list operation, diff: %#v", diff)
+	}
+}
@@ -0,0 +1,389 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// changelogPrefix is the prefix where the change records of keys are
+	// stored.
+	changelogPrefix string = "changelog/"
+
+	// defaultChangelogLimit is the number of changes returned by a changelog
+	// read unless a limit is requested.
+	defaultChangelogLimit = 100
+)
+
+// pathChangelog returns the path configuration for the changelog endpoint
+func pathChangelog(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "changelog/" + matchAllNoTrailingSlashRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "read",
+			OperationSuffix: "changelog",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+			"after": {
+				Type:        framework.TypeInt,
+				Description: "If set, only changes with a greater sequence number are returned.",
+				Query:       true,
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Description: "The maximum number of changes to return. Defaults to 100.",
+				Query:       true,
+			},
+			"operations": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "If set, only changes made by these operations are returned.",
+				Query:       true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathChangelogRead()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"changes": {
+								Type:        framework.TypeSlice,
+								Description: "The changes of the key in chronological order.",
+								Required:    true,
+							},
+							"next_after": {
+								Type:        framework.TypeInt64,
+								Description: "The after parameter to read the next page of changes, if there are more changes.",
+								Required:    false,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    changelogHelpSyn,
+		HelpDescription: changelogHelpDesc,
+	}
+}
+
+// pathChangelogRead returns a page of the changes of a key.
+func (b *versionedKVBackend) pathChangelogRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := data.Get("path").(string)
+
+		after := data.Get("after").(int)
+		if after < 0 {
+			return logical.ErrorResponse("after cannot be negative"), logical.ErrInvalidRequest
+		}
+
+		limit := data.Get("limit").(int)
+		switch {
+		case limit < 0:
+			return logical.ErrorResponse("limit cannot be negative"), logical.ErrInvalidRequest
+		case limit == 0:
+			limit = defaultChangelogLimit
+		}
+
+		var operations map[string]struct{}
+		if ops := data.Get("operations").([]string); len(ops) > 0 {
+			operations = make(map[string]struct{}, len(ops))
+			for _, op := range ops {
+				operations[op] = struct{}{}
+			}
+		}
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.RLock()
+		defer lock.RUnlock()
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			return nil, nil
+		}
+
+		records, err := b.changeRecords(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+
+		// Changes reconstructed from the metadata are returned on the first
+		// page, in addition to the limit
+		changes := []interface{}{}
+		if after == 0 {
+			changes = append(changes, unrecordedChanges(meta, records, operations)...)
+		}
+
+		var count int
+		var nextAfter uint64
+		for _, r := range records {
+			if r.Sequence <= uint64(after) {
+				continue
+			}
+			if _, ok := operations[r.Operation]; operations != nil && !ok {
+				continue
+			}
+
+			if count == limit {
+				nextAfter = changes[len(changes)-1].(map[string]interface{})["sequence"].(uint64)
+				break
+			}
+			changes = append(changes, map[string]interface{}{
+				"sequence":        r.Sequence,
+				"operation":       r.Operation,
+				"path":            r.Path,
+				"time":            ptypesTimestampToString(r.Time),
+				"current_version": r.CurrentVersion,
+				"details":         r.Details,
+				"correlation_id":  r.CorrelationId,
+			})
+			count++
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"changes": changes,
+			},
+		}
+		if nextAfter != 0 {
+			resp.Data["next_after"] = nextAfter
+		}
+
+		return resp, nil
+	}
+}
+
+// versionOperations are the operations that write a new version of a key.
+var versionOperations = map[string]struct{}{
+	"data-write":     {},
+	"data-patch":     {},
+	"provision":      {},
+	"restore":        {},
+	"import":         {},
+	"rollback":       {},
+	"mirror-write":   {},
+	"schema-migrate": {},
+}
+
+// unrecordedChanges reconstructs the creation of the versions of the key
+// that were written before changes were recorded, from the key metadata.
+func unrecordedChanges(meta *KeyMetadata, records []*ChangeRecord, operations map[string]struct{}) []interface{} {
+	if _, ok := operations["data-write"]; operations != nil && !ok {
+		return nil
+	}
+
+	recorded := map[uint64]struct{}{}
+	for _, r := range records {
+		if _, ok := versionOperations[r.Operation]; ok {
+			recorded[r.CurrentVersion] = struct{}{}
+		}
+	}
+
+	var versions []uint64
+	for verNum := range meta.Versions {
+		if _, ok := recorded[verNum]; !ok {
+			versions = append(versions, verNum)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	changes := make([]interface{}, 0, len(versions))
+	for _, verNum := range versions {
+		changes = append(changes, map[string]interface{}{
+			"sequence":        uint64(0),
+			"operation":       "data-write",
+			"path":            "data/" + meta.Key,
+			"time":            ptypesTimestampToString(meta.Versions[verNum].CreatedTime),
+			"current_version": verNum,
+			"details":         map[string]string{"reconstructed": "true"},
+			"correlation_id":  meta.Versions[verNum].CorrelationId,
+		})
+	}
+	return changes
+}
+
+// getChangelogPrefix uses the salt to generate the storage prefix of the
+// change records of a key.
+func (b *versionedKVBackend) getChangelogPrefix(ctx context.Context, key string, s logical.Storage) (string, error) {
+	salt, err := b.Salt(ctx, s)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(b.storagePrefix, changelogPrefix, salt.SaltID(key)) + "/", nil
+}
+
+// recordChange persists a change record for an event about a key. Like
+// sending the event, recording the change is best effort and failures are
+// logged.
+func (b *versionedKVBackend) recordChange(ctx context.Context, s logical.Storage, meta *KeyMetadata, sequence uint64, operation, apiPath string, details map[string]string) {
+	prefix, err := b.getChangelogPrefix(ctx, meta.Key, s)
+	if err != nil {
+		requestLogger(ctx, b.Logger()).Error("error recording change", "error", err)
+		return
+	}
+
+	buf, err := proto.Marshal(&ChangeRecord{
+		Sequence:       sequence,
+		Operation:      operation,
+		Path:           apiPath,
+		Time:           b.timestampNow(),
+		CurrentVersion: meta.CurrentVersion,
+		Details:        details,
+		CorrelationId:  contextCorrelationID(ctx),
+	})
+	if err != nil {
+		requestLogger(ctx, b.Logger()).Error("error recording change", "error", err)
+		return
+	}
+
+	if err := s.Put(ctx, &logical.StorageEntry{
+		Key:   prefix + fmt.Sprintf("%020d", sequence),
+		Value: buf,
+	}); err != nil {
+		requestLogger(ctx, b.Logger()).Error("error recording change", "error", err)
+	}
+}
+
+// changeRecords returns the change records of a key ordered by sequence.
+func (b *versionedKVBackend) changeRecords(ctx context.Context, s logical.Storage, key string) ([]*ChangeRecord, error) {
+	prefix, err := b.getChangelogPrefix(ctx, key, s)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*ChangeRecord, 0, len(entries))
+	for _, e := range entries {
+		raw, err := s.Get(ctx, prefix+e)
+		if err != nil {
+			return nil, err
+		}
+		if raw == nil {
+			continue
+		}
+
+		r := &ChangeRecord{}
+		if err := proto.Unmarshal(raw.Value, r); err != nil {
+			return nil, fmt.Errorf("failed to decode change record from storage: %w", err)
+		}
+		records = append(records, r)
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Sequence < records[j].Sequence })
+	return records, nil
+}
+
+// deleteChangelog deletes the change records of a key.
+func (b *versionedKVBackend) deleteChangelog(ctx context.Context, s logical.Storage, key string) error {
+	prefix, err := b.getChangelogPrefix(ctx, key, s)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := s.Delete(ctx, prefix+e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyChangelog copies the change records of a key to another key, keeping
+// their sequence numbers.
+func (b *versionedKVBackend) copyChangelog(ctx context.Context, s logical.Storage, src, dst string) error {
+	srcPrefix, err := b.getChangelogPrefix(ctx, src, s)
+	if err != nil {
+		return err
+	}
+	dstPrefix, err := b.getChangelogPrefix(ctx, dst, s)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.List(ctx, srcPrefix)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		raw, err := s.Get(ctx, srcPrefix+e)
+		if err != nil {
+			return err
+		}
+		if raw == nil {
+			continue
+		}
+
+		if err := s.Put(ctx, &logical.StorageEntry{
+			Key:   dstPrefix + e,
+			Value: raw.Value,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changeDetails converts event metadata pairs to the details of a change
+// record.
+func changeDetails(pairs []string) map[string]string {
+	details := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		details[pairs[i]] = pairs[i+1]
+	}
+	return details
+}
+
+const changelogHelpSyn = `Returns the history of changes to a secret.`
+const changelogHelpDesc = `
+This endpoint returns the changes made to a secret in chronological order, such
+as new versions, deleted, undeleted and destroyed versions, and metadata
+changes. Each change has the "sequence" of the event sent for it, the
+"operation" and API "path" that made the change, its "time", the
+"current_version" of the secret after the change, operation specific
+"details", such as the affected versions, and the "correlation_id" supplied
+by the client that made the change, if any.
+
+Changes are recorded for every event about the secret, regardless of whether
+events are enabled. Versions written before changes were recorded are returned
+first as "data-write" changes with a sequence of 0, reconstructed from the key
+metadata, on the first page. Deleting the metadata of the secret deletes its
+history.
+
+The changes are paginated: at most "limit" changes are returned, and
+"next_after" is returned if there are more, to be passed as the "after"
+parameter to read the next page. The "operations" parameter filters the
+changes by operation.
+`
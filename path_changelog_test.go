@@ -0,0 +1,117 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Changelog(t *testing.T) {
+	b, storage := getBackend(t)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "qux"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "delete/foo",
+			Data: map[string]interface{}{
+				"versions": "1",
+			},
+		},
+	}
+
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	changelog := func(data map[string]interface{}) *logical.Response {
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "changelog/foo",
+			Storage:   storage,
+			Data:      data,
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("changelog ReadOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		return resp
+	}
+
+	operations := func(resp *logical.Response) []string {
+		var ops []string
+		for _, c := range resp.Data["changes"].([]interface{}) {
+			ops = append(ops, c.(map[string]interface{})["operation"].(string))
+		}
+		return ops
+	}
+
+	resp := changelog(nil)
+	if ops := operations(resp); len(ops) != 3 || ops[0] != "data-write" || ops[1] != "data-write" || ops[2] != "delete" {
+		t.Fatalf("unexpected changes: %v", ops)
+	}
+	if _, ok := resp.Data["next_after"]; ok {
+		t.Fatalf("expected no next page, got: %#v", resp.Data)
+	}
+	details := resp.Data["changes"].([]interface{})[2].(map[string]interface{})["details"].(map[string]string)
+	if details["deleted_versions"] != "[1]" {
+		t.Fatalf("unexpected delete details: %#v", details)
+	}
+
+	resp = changelog(map[string]interface{}{"limit": 2})
+	if ops := operations(resp); len(ops) != 2 || resp.Data["next_after"] != uint64(2) {
+		t.Fatalf("unexpected first page: %v, %#v", ops, resp.Data)
+	}
+
+	resp = changelog(map[string]interface{}{"after": 2})
+	if ops := operations(resp); len(ops) != 1 || ops[0] != "delete" {
+		t.Fatalf("unexpected second page: %v", ops)
+	}
+
+	resp = changelog(map[string]interface{}{"operations": "delete"})
+	if ops := operations(resp); len(ops) != 1 || ops[0] != "delete" {
+		t.Fatalf("unexpected filtered changes: %v", ops)
+	}
+
+	// Deleting the metadata deletes the history
+	req := &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata DeleteOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	records, err := b.(*versionedKVBackend).changeRecords(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("expected change records to be deleted, got: %v", records)
+	}
+}
@@ -5,12 +5,18 @@ package kv
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"path"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -41,6 +47,249 @@ If set, the length of time before a version is deleted. A negative duration
 disables the use of delete_version_after on all keys. A zero duration
 clears the current setting. Accepts a Go duration format string.`,
 			},
+			"version_ttl": {
+				Type:        framework.TypeSignedDurationSecond,
+				Description: "Deprecated alias of delete_version_after.",
+				Deprecated:  true,
+			},
+			"healthcheck_path": {
+				Type: framework.TypeString,
+				Description: `
+If set, the key reserved for synthetic monitoring probes. Writes to this key do
+not emit events, only retain a single version, and are skipped when the data is
+unchanged. An empty string clears the current setting.`,
+			},
+			"allowed_options": {
+				Type: framework.TypeMap,
+				Description: `
+A map of key prefixes to the list of write options permitted for keys under
+that prefix. The longest matching prefix applies; keys matching no prefix may
+use any option. An empty map clears the current setting.`,
+			},
+			"v1_data_enabled": {
+				Type:        framework.TypeBool,
+				Description: "If true, the read-only v1-data path will serve the current version of keys in the KV v1 response format",
+			},
+			"expiry_scan_interval": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, how often a background scan processes versions whose deletion time has
+passed, emitting events for them even if they are never read. A zero duration
+disables the scan. Accepts a Go duration format string.`,
+			},
+			"destroy_expired_after": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, the grace period after a version's deletion time once which the
+background expiry scan permanently destroys the version. A zero duration
+disables destroying expired versions. Accepts a Go duration format string.`,
+			},
+			"destroy_version_after": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, how long after its creation a version is permanently destroyed,
+removing its data from storage. A zero duration disables it. Accepts a Go
+duration format string.`,
+			},
+			"max_version_age": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, the age after which the background expiry scan prunes versions, except
+for the newest min_versions versions of the key. A zero duration disables
+pruning by age. Accepts a Go duration format string.`,
+			},
+			"min_versions": {
+				Type:        framework.TypeInt,
+				Description: "The number of newest versions of each key that are kept regardless of max_version_age",
+			},
+			"event_sample_rates": {
+				Type: framework.TypeMap,
+				Description: `
+A map of key prefixes to the fraction, between 0 and 1, of events about keys
+under that prefix that are sent. The longest matching prefix applies; events
+about keys matching no prefix are always sent. An empty map clears the current
+setting.`,
+			},
+			"archive_after": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, how long after a version was last read the background expiry scan moves
+its data to the archive storage prefix. Archived versions are moved back on
+their next read. A zero duration disables archiving. Accepts a Go duration
+format string.`,
+			},
+			"archive_compression": {
+				Type:        framework.TypeString,
+				Description: `The compression of archived version data, one of "gzip", "snappy" or "lz4". Empty stores it uncompressed.`,
+			},
+			"debug_generate_enabled": {
+				Type:        framework.TypeBool,
+				Description: "If true, the debug/generate endpoint can be used to write synthetic keys for load testing",
+			},
+			"max_delete_version_after": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, the largest delete_version_after accepted for the mount, for keys and
+for single versions. Cannot be greater than 100 years, which also applies if
+not set. A zero duration clears the current setting. Accepts a Go duration
+format string.`,
+			},
+			"integrity_check_interval": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, how often a background check verifies that the stored versions of a
+sample of the keys decode and match their content hashes, sending
+integrity-alert events for failures. A zero duration disables the check.
+Accepts a Go duration format string.`,
+			},
+			"integrity_sample_rate": {
+				Type:        framework.TypeFloat,
+				Description: "The fraction, between 0 and 1, of keys verified by each integrity check. Defaults to 0.01.",
+			},
+			"tidy_interval": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, how often a background tidy removes the stored data of destroyed
+versions and orphaned version entries. A zero duration disables the periodic
+tidy. Accepts a Go duration format string.`,
+			},
+			"missing_key_cache_ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, how long a lookup of a key that does not exist is cached, so that
+repeated reads of a missing key do not reach storage. A zero duration
+disables the cache. Accepts a Go duration format string.`,
+			},
+			"concurrency_limits": {
+				Type: framework.TypeMap,
+				Description: `
+A map of key prefixes to the maximum number of requests about keys under that
+prefix that are handled at the same time. Requests over the limit are rejected
+with a 429 status code. The longest matching prefix applies; keys matching no
+prefix are not limited. An empty map clears the current setting.`,
+			},
+			"undelete_confirm_prefixes": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `
+A list of key prefixes under which undelete requests must set the confirm
+parameter to true. An empty list clears the current setting.`,
+			},
+			"strict_options": {
+				Type:        framework.TypeBool,
+				Description: "If true, writes that set unrecognized options are rejected instead of returning a warning",
+			},
+			"retain_pruned_versions": {
+				Type:        framework.TypeBool,
+				Description: "If true, the data of versions pruned by max_versions is retained until their deletion time passes",
+			},
+			"wrap_required_prefixes": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `
+A list of key prefixes under which reads of secret data must be
+response-wrapped. An empty list clears the current setting.`,
+			},
+			"unwrapped_read_denied_prefixes": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `
+A list of key prefixes under which reads of secret data that are not
+response-wrapped by the client are rejected, even if wrap_ttl is set. An empty
+list clears the current setting.`,
+			},
+			"read_event_prefixes": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `
+A list of key prefixes under which reads of secret data send a kv-v2/data-read
+event for each version read, through the data, v1-data and export paths, and
+diff with include_values. Reads of subkeys send a kv-v2/subkeys-read event.
+An empty list clears the current setting.`,
+			},
+			"wrap_ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, the maximum wrap TTL for reads under the wrap_required_prefixes, also
+used to wrap reads that did not request wrapping. If not set, reads that are
+not wrapped are rejected. Accepts a Go duration format string.`,
+			},
+			"mirrors": {
+				Type: framework.TypeMap,
+				Description: `
+A map of source key prefixes to target key prefixes. Writes to keys under a
+source prefix are mirrored to the same key under the target prefix. An empty
+map clears the current setting.`,
+			},
+			"deduplicate_version_data": {
+				Type:        framework.TypeBool,
+				Description: "If true, a new version with the same data as an earlier version of the key references the stored data of that version instead of storing another copy",
+			},
+			"approval_required_prefixes": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `
+A list of key prefixes under which destroy and metadata delete requests must be
+approved by a second token. An empty list clears the current setting.`,
+			},
+			"approval_ttl": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, how long a pending approval request can be approved. Defaults to 24
+hours. Accepts a Go duration format string.`,
+			},
+			"owner_enforced": {
+				Type:        framework.TypeBool,
+				Description: "If true, only the owner of a key and tokens with one of the owner_admin_policies may delete and destroy versions of keys that have an owner",
+			},
+			"owner_admin_policies": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `
+A list of policies whose tokens may delete and destroy versions of keys
+regardless of their owner. An empty list clears the current setting.`,
+			},
+			"max_storage_value_size": {
+				Type: framework.TypeInt,
+				Description: `
+The maximum size in bytes of the version data and key metadata written to
+storage, matching the value size limit of the storage backend. Larger writes
+are rejected before reaching storage. A value of 0 disables the check.`,
+			},
+			"max_versions_per_request": {
+				Type:        framework.TypeInt,
+				Description: "The largest number of versions a single delete, undelete or destroy request can name. Defaults to 1000.",
+			},
+			"max_key_bytes": {
+				Type: framework.TypeInt,
+				Description: `
+The largest total size in bytes of the data of the versions of a key. Writes
+that would exceed it are rejected. Keys can set a lower limit. A value of 0
+disables the limit.`,
+			},
+			"trash_retention": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+How long keys deleted with a soft metadata delete are kept in the trash, where
+they can be restored with metadata-undelete, before tidy purges them. A zero
+duration disables soft deletes. Accepts a Go duration format string.`,
+			},
+			"disable_events": {
+				Type:        framework.TypeBool,
+				Description: "If true, the mount sends no events. Equivalent to disabling the events feature of config/features.",
+			},
+			"background_max_delay": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+If set, the largest delay added to requests about keys while background tasks,
+such as tidy, jobs and the upgrade, load storage. The delay grows with the
+load. A value of 0 disables the delay.`,
+			},
+			"background_ops_limit": {
+				Type:        framework.TypeInt,
+				Description: "The rate of storage operations per second of background tasks at which their load is full. Defaults to 1000.",
+			},
+			"storage_emergency_threshold": {
+				Type: framework.TypeInt,
+				Description: `
+The total size in bytes of the version data of the mount over which writes of
+new versions are rejected, while reads, deletes and destroys still work. A
+value of 0 disables the threshold.`,
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -51,8 +300,9 @@ clears the current setting. Accepts a Go duration format string.`,
 				},
 				Summary: "Configure backend level settings that are applied to every key in the key-value store.",
 				Responses: map[int][]framework.Response{
-					http.StatusNoContent: {{
-						Description: http.StatusText(http.StatusNoContent),
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields:      configResponseFields,
 					}},
 				},
 			},
@@ -66,23 +316,7 @@ clears the current setting. Accepts a Go duration format string.`,
 				Responses: map[int][]framework.Response{
 					http.StatusOK: {{
 						Description: http.StatusText(http.StatusOK),
-						Fields: map[string]*framework.FieldSchema{
-							"max_versions": {
-								Type:        framework.TypeInt,
-								Description: "The number of versions to keep for each key.",
-								Required:    true,
-							},
-							"cas_required": {
-								Type:        framework.TypeBool,
-								Description: "If true, the backend will require the cas parameter to be set for each write",
-								Required:    true,
-							},
-							"delete_version_after": {
-								Type:        framework.TypeSignedDurationSecond,
-								Description: "The length of time before a version is deleted.",
-								Required:    true,
-							},
-						},
+						Fields:      configResponseFields,
 					}},
 				},
 			},
@@ -93,6 +327,246 @@ clears the current setting. Accepts a Go duration format string.`,
 	}
 }
 
+// configResponseFields are the fields of the responses of config reads and
+// writes, which both return the effective configuration.
+var configResponseFields = map[string]*framework.FieldSchema{
+	"max_versions": {
+		Type:        framework.TypeInt,
+		Description: "The number of versions to keep for each key.",
+		Required:    true,
+	},
+	"cas_required": {
+		Type:        framework.TypeBool,
+		Description: "If true, the backend will require the cas parameter to be set for each write",
+		Required:    true,
+	},
+	"delete_version_after": {
+		Type:        framework.TypeSignedDurationSecond,
+		Description: "The length of time before a version is deleted.",
+		Required:    true,
+	},
+	"healthcheck_path": {
+		Type:        framework.TypeString,
+		Description: "The key reserved for synthetic monitoring probes.",
+		Required:    true,
+	},
+	"allowed_options": {
+		Type:        framework.TypeMap,
+		Description: "A map of key prefixes to the list of write options permitted for keys under that prefix.",
+		Required:    true,
+	},
+	"v1_data_enabled": {
+		Type:        framework.TypeBool,
+		Description: "If true, the read-only v1-data path will serve the current version of keys in the KV v1 response format",
+		Required:    true,
+	},
+	"expiry_scan_interval": {
+		Type:        framework.TypeDurationSecond,
+		Description: "How often a background scan processes versions whose deletion time has passed.",
+		Required:    true,
+	},
+	"destroy_expired_after": {
+		Type:        framework.TypeDurationSecond,
+		Description: "The grace period after a version's deletion time once which the expiry scan destroys the version.",
+		Required:    true,
+	},
+	"destroy_version_after": {
+		Type:        framework.TypeDurationSecond,
+		Description: "How long after its creation a version is destroyed.",
+		Required:    true,
+	},
+	"max_version_age": {
+		Type:        framework.TypeDurationSecond,
+		Description: "The age after which the expiry scan prunes versions, except for the newest min_versions versions of each key.",
+		Required:    true,
+	},
+	"min_versions": {
+		Type:        framework.TypeInt64,
+		Description: "The number of newest versions of each key that are kept regardless of max_version_age.",
+		Required:    true,
+	},
+	"event_sample_rates": {
+		Type:        framework.TypeMap,
+		Description: "A map of key prefixes to the fraction of events about keys under that prefix that are sent.",
+		Required:    true,
+	},
+	"archive_after": {
+		Type:        framework.TypeDurationSecond,
+		Description: "How long after a version was last read the expiry scan archives its data.",
+		Required:    true,
+	},
+	"archive_compression": {
+		Type:        framework.TypeString,
+		Description: "The compression of archived version data.",
+		Required:    true,
+	},
+	"debug_generate_enabled": {
+		Type:        framework.TypeBool,
+		Description: "If true, the debug/generate endpoint can be used to write synthetic keys for load testing.",
+		Required:    true,
+	},
+	"max_delete_version_after": {
+		Type:        framework.TypeDurationSecond,
+		Description: "The largest delete_version_after accepted for the mount, keys and single versions.",
+		Required:    true,
+	},
+	"integrity_check_interval": {
+		Type:        framework.TypeDurationSecond,
+		Description: "How often a background check verifies the stored versions of a sample of the keys.",
+		Required:    true,
+	},
+	"integrity_sample_rate": {
+		Type:        framework.TypeFloat,
+		Description: "The fraction of keys verified by each integrity check.",
+		Required:    true,
+	},
+	"tidy_interval": {
+		Type:        framework.TypeDurationSecond,
+		Description: "How often a background tidy removes the stored data of destroyed versions and orphaned version entries.",
+		Required:    true,
+	},
+	"missing_key_cache_ttl": {
+		Type:        framework.TypeDurationSecond,
+		Description: "How long a lookup of a key that does not exist is cached.",
+		Required:    true,
+	},
+	"delete_version_after_disabled": {
+		Type:        framework.TypeBool,
+		Description: "If true, delete_version_after is disabled for all keys.",
+		Required:    true,
+	},
+	"concurrency_limits": {
+		Type:        framework.TypeMap,
+		Description: "A map of key prefixes to the maximum number of requests about keys under that prefix that are handled at the same time.",
+		Required:    true,
+	},
+	"undelete_confirm_prefixes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "A list of key prefixes under which undelete requests must set the confirm parameter to true.",
+		Required:    true,
+	},
+	"strict_options": {
+		Type:        framework.TypeBool,
+		Description: "If true, writes that set unrecognized options are rejected instead of returning a warning",
+		Required:    true,
+	},
+	"retain_pruned_versions": {
+		Type:        framework.TypeBool,
+		Description: "If true, the data of versions pruned by max_versions is retained until their deletion time passes",
+		Required:    true,
+	},
+	"wrap_required_prefixes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "A list of key prefixes under which reads of secret data must be response-wrapped.",
+		Required:    true,
+	},
+	"unwrapped_read_denied_prefixes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "A list of key prefixes under which reads of secret data that are not response-wrapped by the client are rejected.",
+		Required:    true,
+	},
+	"read_event_prefixes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "A list of key prefixes under which reads of secret data or subkeys send a read event.",
+		Required:    true,
+	},
+	"v1_prefixes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "The key prefixes converted back to KV v1 entries by the convert/v1 endpoint.",
+		Required:    true,
+	},
+	"wrap_ttl": {
+		Type:        framework.TypeDurationSecond,
+		Description: "The maximum wrap TTL for reads under the wrap_required_prefixes.",
+		Required:    true,
+	},
+	"mirrors": {
+		Type:        framework.TypeMap,
+		Description: "A map of source key prefixes to the target key prefixes that writes under them are mirrored to.",
+		Required:    true,
+	},
+	"deduplicate_version_data": {
+		Type:        framework.TypeBool,
+		Description: "If true, a new version with the same data as an earlier version of the key references the stored data of that version instead of storing another copy",
+		Required:    true,
+	},
+	"approval_required_prefixes": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "A list of key prefixes under which destroy and metadata delete requests must be approved by a second token.",
+		Required:    true,
+	},
+	"approval_ttl": {
+		Type:        framework.TypeDurationSecond,
+		Description: "How long a pending approval request can be approved.",
+		Required:    true,
+	},
+	"owner_enforced": {
+		Type:        framework.TypeBool,
+		Description: "If true, only the owner of a key and tokens with one of the owner_admin_policies may delete and destroy versions of keys that have an owner",
+		Required:    true,
+	},
+	"owner_admin_policies": {
+		Type:        framework.TypeCommaStringSlice,
+		Description: "A list of policies whose tokens may delete and destroy versions of keys regardless of their owner.",
+		Required:    true,
+	},
+	"max_storage_value_size": {
+		Type:        framework.TypeInt64,
+		Description: "The maximum size in bytes of the version data and key metadata written to storage.",
+		Required:    true,
+	},
+	"max_versions_per_request": {
+		Type:        framework.TypeInt64,
+		Description: "The largest number of versions a single delete, undelete or destroy request can name.",
+		Required:    true,
+	},
+	"max_key_bytes": {
+		Type:        framework.TypeInt64,
+		Description: "The largest total size in bytes of the data of the versions of a key.",
+		Required:    true,
+	},
+	"trash_retention": {
+		Type:        framework.TypeDurationSecond,
+		Description: "How long keys deleted with a soft metadata delete are kept in the trash before tidy purges them.",
+		Required:    true,
+	},
+	"disable_events": {
+		Type:        framework.TypeBool,
+		Description: "If true, the mount sends no events.",
+		Required:    true,
+	},
+	"background_max_delay": {
+		Type:        framework.TypeDurationSecond,
+		Description: "The largest delay added to requests about keys while background tasks load storage.",
+		Required:    true,
+	},
+	"background_ops_limit": {
+		Type:        framework.TypeInt64,
+		Description: "The rate of storage operations per second of background tasks at which their load is full.",
+		Required:    true,
+	},
+	"storage_emergency_threshold": {
+		Type:        framework.TypeInt64,
+		Description: "The total size in bytes of the version data of the mount over which writes of new versions are rejected.",
+		Required:    true,
+	},
+	"storage_usage_bytes": {
+		Type:        framework.TypeInt64,
+		Description: "The total size in bytes of the version data of the mount, as counted by this server.",
+		Required:    true,
+	},
+	"storage_emergency": {
+		Type:        framework.TypeBool,
+		Description: "If true, the storage usage exceeds the storage_emergency_threshold and writes of new versions are rejected.",
+		Required:    true,
+	},
+	"seal_wrap_mismatch": {
+		Type:        framework.TypeBool,
+		Description: "If true, stored version data failed to decode since the backend started, which indicates the mount lost seal wrap support.",
+		Required:    true,
+	},
+}
+
 // pathConfigWrite handles create and update commands to the config
 func (b *versionedKVBackend) pathConfigRead() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -101,19 +575,10 @@ func (b *versionedKVBackend) pathConfigRead() framework.OperationFunc {
 			return nil, err
 		}
 
-		rdata := map[string]interface{}{
-			"max_versions": config.MaxVersions,
-			"cas_required": config.CasRequired,
-		}
-
-		var deleteVersionAfter time.Duration
-		if config.GetDeleteVersionAfter() != nil {
-			deleteVersionAfter, err = ptypes.Duration(config.GetDeleteVersionAfter())
-			if err != nil {
-				return nil, err
-			}
+		rdata, err := b.configResponseData(config)
+		if err != nil {
+			return nil, err
 		}
-		rdata["delete_version_after"] = deleteVersionAfter.String()
 
 		return &logical.Response{
 			Data: rdata,
@@ -121,62 +586,493 @@ func (b *versionedKVBackend) pathConfigRead() framework.OperationFunc {
 	}
 }
 
+// configResponseData returns the data of the responses of config reads and
+// writes: the settings of the config, and the runtime state of the backend.
+func (b *versionedKVBackend) configResponseData(config *Configuration) (map[string]interface{}, error) {
+	rdata, err := configData(config)
+	if err != nil {
+		return nil, err
+	}
+
+	rdata["seal_wrap_mismatch"] = atomic.LoadUint32(b.sealWrapMismatch) == 1
+	rdata["storage_usage_bytes"], rdata["storage_emergency"] = b.usageState()
+	return rdata, nil
+}
+
+// configData returns the settings of the config as returned by config reads.
+func configData(config *Configuration) (map[string]interface{}, error) {
+	rdata := map[string]interface{}{
+		"max_versions":     config.MaxVersions,
+		"cas_required":     config.CasRequired,
+		"healthcheck_path": config.HealthcheckPath,
+		"v1_data_enabled":  config.V1DataEnabled,
+		"strict_options":   config.StrictOptions,
+
+		"retain_pruned_versions":   config.RetainPrunedVersions,
+		"deduplicate_version_data": config.DeduplicateVersionData,
+		"max_storage_value_size":   config.MaxStorageValueSize,
+		"disable_events":           !config.featureEnabled(featureEvents),
+	}
+
+	allowedOptions := make(map[string][]string, len(config.AllowedOptions))
+	for prefix, list := range config.AllowedOptions {
+		allowedOptions[prefix] = list.GetOptions()
+	}
+	rdata["allowed_options"] = allowedOptions
+
+	var deleteVersionAfter time.Duration
+	if config.GetDeleteVersionAfter() != nil {
+		var err error
+		deleteVersionAfter, err = ptypes.Duration(config.GetDeleteVersionAfter())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.IsDeleteVersionAfterDisabled() {
+		deleteVersionAfter = disabled
+	}
+	rdata["delete_version_after"] = deleteVersionAfter.String()
+	rdata["delete_version_after_disabled"] = config.IsDeleteVersionAfterDisabled()
+	rdata["max_delete_version_after"] = durationOrZero(config.GetMaxDeleteVersionAfter()).String()
+	rdata["expiry_scan_interval"] = durationOrZero(config.GetExpiryScanInterval()).String()
+	rdata["destroy_expired_after"] = durationOrZero(config.GetDestroyExpiredAfter()).String()
+	rdata["destroy_version_after"] = durationOrZero(config.GetDestroyVersionAfter()).String()
+	rdata["max_version_age"] = durationOrZero(config.GetMaxVersionAge()).String()
+	rdata["min_versions"] = config.MinVersions
+
+	eventSampleRates := config.EventSampleRates
+	if eventSampleRates == nil {
+		eventSampleRates = map[string]float64{}
+	}
+	rdata["event_sample_rates"] = eventSampleRates
+	rdata["archive_after"] = durationOrZero(config.GetArchiveAfter()).String()
+	rdata["archive_compression"] = config.ArchiveCompression
+	rdata["debug_generate_enabled"] = config.DebugGenerateEnabled
+	rdata["integrity_check_interval"] = durationOrZero(config.GetIntegrityCheckInterval()).String()
+	rdata["integrity_sample_rate"] = config.integritySampleRate()
+	rdata["tidy_interval"] = durationOrZero(config.GetTidyInterval()).String()
+	rdata["max_versions_per_request"] = config.maxVersionsPerRequest()
+	rdata["max_key_bytes"] = config.MaxKeyBytes
+	rdata["missing_key_cache_ttl"] = durationOrZero(config.GetMissingKeyCacheTtl()).String()
+	rdata["trash_retention"] = durationOrZero(config.GetTrashRetention()).String()
+	rdata["background_max_delay"] = durationOrZero(config.GetBackgroundMaxDelay()).String()
+	rdata["background_ops_limit"] = config.backgroundOpsLimit()
+	rdata["storage_emergency_threshold"] = config.StorageEmergencyThreshold
+
+	concurrencyLimits := config.ConcurrencyLimits
+	if concurrencyLimits == nil {
+		concurrencyLimits = map[string]uint32{}
+	}
+	rdata["concurrency_limits"] = concurrencyLimits
+
+	undeleteConfirmPrefixes := config.UndeleteConfirmPrefixes
+	if undeleteConfirmPrefixes == nil {
+		undeleteConfirmPrefixes = []string{}
+	}
+	rdata["undelete_confirm_prefixes"] = undeleteConfirmPrefixes
+
+	wrapRequiredPrefixes := config.WrapRequiredPrefixes
+	if wrapRequiredPrefixes == nil {
+		wrapRequiredPrefixes = []string{}
+	}
+	rdata["wrap_required_prefixes"] = wrapRequiredPrefixes
+
+	unwrappedReadDeniedPrefixes := config.UnwrappedReadDeniedPrefixes
+	if unwrappedReadDeniedPrefixes == nil {
+		unwrappedReadDeniedPrefixes = []string{}
+	}
+	rdata["unwrapped_read_denied_prefixes"] = unwrappedReadDeniedPrefixes
+
+	readEventPrefixes := config.ReadEventPrefixes
+	if readEventPrefixes == nil {
+		readEventPrefixes = []string{}
+	}
+	rdata["read_event_prefixes"] = readEventPrefixes
+
+	v1Prefixes := config.V1Prefixes
+	if v1Prefixes == nil {
+		v1Prefixes = []string{}
+	}
+	rdata["v1_prefixes"] = v1Prefixes
+	rdata["wrap_ttl"] = durationOrZero(config.GetWrapTtl()).String()
+
+	approvalRequiredPrefixes := config.ApprovalRequiredPrefixes
+	if approvalRequiredPrefixes == nil {
+		approvalRequiredPrefixes = []string{}
+	}
+	rdata["approval_required_prefixes"] = approvalRequiredPrefixes
+	rdata["approval_ttl"] = approvalTTL(config).String()
+
+	ownerAdminPolicies := config.OwnerAdminPolicies
+	if ownerAdminPolicies == nil {
+		ownerAdminPolicies = []string{}
+	}
+	rdata["owner_enforced"] = config.OwnerEnforced
+	rdata["owner_admin_policies"] = ownerAdminPolicies
+
+	mirrors := config.Mirrors
+	if mirrors == nil {
+		mirrors = map[string]string{}
+	}
+	rdata["mirrors"] = mirrors
+	return rdata, nil
+}
+
+// keyPrefixFields are the settings of the config that apply to keys by
+// prefix: lists of key prefixes, and maps of key prefixes to a setting.
+var keyPrefixFields = []string{
+	"allowed_options",
+	"undelete_confirm_prefixes",
+	"wrap_required_prefixes",
+	"unwrapped_read_denied_prefixes",
+	"read_event_prefixes",
+	"v1_prefixes",
+	"mirrors",
+	"approval_required_prefixes",
+	"event_sample_rates",
+	"concurrency_limits",
+}
+
+// keyPrefixSetting returns what the setting of the config data with the
+// given name applies to the key: for a list of prefixes whether the key is
+// under one of them, for a map the value of the most specific prefix
+// matching the key, or nil if none does.
+func keyPrefixSetting(settings map[string]interface{}, name, key string) interface{} {
+	if prefixes, ok := settings[name].([]string); ok {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var setting interface{}
+	matched := -1
+	iter := reflect.ValueOf(settings[name]).MapRange()
+	for iter.Next() {
+		prefix := iter.Key().String()
+		if strings.HasPrefix(key, prefix) && len(prefix) > matched {
+			setting, matched = iter.Value().Interface(), len(prefix)
+		}
+	}
+	return setting
+}
+
+// configField describes a field of config writes. validate, if set, checks
+// the value of the field before the config is loaded, and set sets it in the
+// config. Errors of both are returned to the client as invalid requests.
+type configField struct {
+	name     string
+	validate func(name string, raw interface{}) error
+	set      func(config *Configuration, raw interface{}) error
+}
+
+// configFields are the fields of config writes, in the order they are
+// validated and set.
+var configFields = []configField{
+	{"max_versions", nil, uint32Setter(func(c *Configuration) *uint32 { return &c.MaxVersions })},
+	{"cas_required", nil, boolSetter(func(c *Configuration) *bool { return &c.CasRequired })},
+	{"delete_version_after", nil, setDeleteVersionAfter},
+	{"healthcheck_path", nil, func(c *Configuration, raw interface{}) error {
+		c.HealthcheckPath = strings.Trim(raw.(string), "/")
+		return nil
+	}},
+	{"allowed_options", nil, setAllowedOptions},
+	{"v1_data_enabled", nil, boolSetter(func(c *Configuration) *bool { return &c.V1DataEnabled })},
+	{"expiry_scan_interval", nil, durationSetter(func(c *Configuration) **duration.Duration { return &c.ExpiryScanInterval })},
+	{"destroy_expired_after", nil, durationSetter(func(c *Configuration) **duration.Duration { return &c.DestroyExpiredAfter })},
+	{"undelete_confirm_prefixes", nil, stringsSetter(func(c *Configuration) *[]string { return &c.UndeleteConfirmPrefixes })},
+	{"strict_options", nil, boolSetter(func(c *Configuration) *bool { return &c.StrictOptions })},
+	{"retain_pruned_versions", nil, boolSetter(func(c *Configuration) *bool { return &c.RetainPrunedVersions })},
+	{"wrap_required_prefixes", nil, stringsSetter(func(c *Configuration) *[]string { return &c.WrapRequiredPrefixes })},
+	{"unwrapped_read_denied_prefixes", nil, stringsSetter(func(c *Configuration) *[]string { return &c.UnwrappedReadDeniedPrefixes })},
+	{"read_event_prefixes", nil, stringsSetter(func(c *Configuration) *[]string { return &c.ReadEventPrefixes })},
+	{"wrap_ttl", nil, durationSetter(func(c *Configuration) **duration.Duration { return &c.WrapTtl })},
+	{"mirrors", nil, func(c *Configuration, raw interface{}) error {
+		mirrors, err := parseMirrors(raw.(map[string]interface{}))
+		if err != nil {
+			return err
+		}
+		c.Mirrors = mirrors
+		return nil
+	}},
+	{"deduplicate_version_data", nil, boolSetter(func(c *Configuration) *bool { return &c.DeduplicateVersionData })},
+	{"approval_required_prefixes", nil, stringsSetter(func(c *Configuration) *[]string { return &c.ApprovalRequiredPrefixes })},
+	{"approval_ttl", nil, durationSetter(func(c *Configuration) **duration.Duration { return &c.ApprovalTtl })},
+	{"owner_enforced", nil, boolSetter(func(c *Configuration) *bool { return &c.OwnerEnforced })},
+	{"owner_admin_policies", nil, stringsSetter(func(c *Configuration) *[]string { return &c.OwnerAdminPolicies })},
+	{"max_storage_value_size", nonNegative, uint64Setter(func(c *Configuration) *uint64 { return &c.MaxStorageValueSize })},
+	{"max_version_age", nonNegative, durationSetter(func(c *Configuration) **duration.Duration { return &c.MaxVersionAge })},
+	{"min_versions", nonNegative, uint32Setter(func(c *Configuration) *uint32 { return &c.MinVersions })},
+	{"event_sample_rates", nil, func(c *Configuration, raw interface{}) error {
+		eventSampleRates, err := parseEventSampleRates(raw.(map[string]interface{}))
+		if err != nil {
+			return err
+		}
+		c.EventSampleRates = eventSampleRates
+		return nil
+	}},
+	{"archive_after", nonNegative, durationSetter(func(c *Configuration) **duration.Duration { return &c.ArchiveAfter })},
+	{"archive_compression", func(_ string, raw interface{}) error {
+		return validateArchiveCompression(raw.(string))
+	}, func(c *Configuration, raw interface{}) error {
+		c.ArchiveCompression = raw.(string)
+		return nil
+	}},
+	{"debug_generate_enabled", nil, boolSetter(func(c *Configuration) *bool { return &c.DebugGenerateEnabled })},
+	{"concurrency_limits", nil, func(c *Configuration, raw interface{}) error {
+		concurrencyLimits, err := parseConcurrencyLimits(raw.(map[string]interface{}))
+		if err != nil {
+			return err
+		}
+		c.ConcurrencyLimits = concurrencyLimits
+		return nil
+	}},
+	{"max_delete_version_after", func(name string, raw interface{}) error {
+		if time.Duration(raw.(int))*time.Second > maxDeleteVersionAfter {
+			return fmt.Errorf("%s cannot be greater than %s", name, maxDeleteVersionAfter)
+		}
+		return nil
+	}, durationSetter(func(c *Configuration) **duration.Duration { return &c.MaxDeleteVersionAfter })},
+	{"integrity_check_interval", nonNegative, durationSetter(func(c *Configuration) **duration.Duration { return &c.IntegrityCheckInterval })},
+	{"integrity_sample_rate", func(name string, raw interface{}) error {
+		if rate := raw.(float64); rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+		return nil
+	}, func(c *Configuration, raw interface{}) error {
+		c.IntegritySampleRate = raw.(float64)
+		return nil
+	}},
+	{"destroy_version_after", nonNegative, durationSetter(func(c *Configuration) **duration.Duration { return &c.DestroyVersionAfter })},
+	{"tidy_interval", nonNegative, durationSetter(func(c *Configuration) **duration.Duration { return &c.TidyInterval })},
+	{"max_versions_per_request", nonNegative, uint32Setter(func(c *Configuration) *uint32 { return &c.MaxVersionsPerRequest })},
+	{"max_key_bytes", nonNegative, uint64Setter(func(c *Configuration) *uint64 { return &c.MaxKeyBytes })},
+	{"missing_key_cache_ttl", nonNegative, durationSetter(func(c *Configuration) **duration.Duration { return &c.MissingKeyCacheTtl })},
+	{"trash_retention", nonNegative, durationSetter(func(c *Configuration) **duration.Duration { return &c.TrashRetention })},
+	{"disable_events", nil, func(c *Configuration, raw interface{}) error {
+		c.setFeature(featureEvents, !raw.(bool))
+		return nil
+	}},
+	{"background_max_delay", nonNegative, durationSetter(func(c *Configuration) **duration.Duration { return &c.BackgroundMaxDelay })},
+	{"background_ops_limit", nonNegative, uint32Setter(func(c *Configuration) *uint32 { return &c.BackgroundOpsLimit })},
+	{"storage_emergency_threshold", nonNegative, uint64Setter(func(c *Configuration) *uint64 { return &c.StorageEmergencyThreshold })},
+}
+
+// nonNegative validates that the value of an integer field is not negative.
+func nonNegative(name string, raw interface{}) error {
+	if raw.(int) < 0 {
+		return fmt.Errorf("%s cannot be negative", name)
+	}
+	return nil
+}
+
+// boolSetter returns the set function of a bool field of the config.
+func boolSetter(field func(*Configuration) *bool) func(*Configuration, interface{}) error {
+	return func(c *Configuration, raw interface{}) error {
+		*field(c) = raw.(bool)
+		return nil
+	}
+}
+
+// uint32Setter returns the set function of a uint32 field of the config.
+func uint32Setter(field func(*Configuration) *uint32) func(*Configuration, interface{}) error {
+	return func(c *Configuration, raw interface{}) error {
+		*field(c) = uint32(raw.(int))
+		return nil
+	}
+}
+
+// uint64Setter returns the set function of a uint64 field of the config.
+func uint64Setter(field func(*Configuration) *uint64) func(*Configuration, interface{}) error {
+	return func(c *Configuration, raw interface{}) error {
+		*field(c) = uint64(raw.(int))
+		return nil
+	}
+}
+
+// durationSetter returns the set function of a duration field of the config,
+// which is unset by a value of zero.
+func durationSetter(field func(*Configuration) **duration.Duration) func(*Configuration, interface{}) error {
+	return func(c *Configuration, raw interface{}) error {
+		*field(c) = optionalDurationProto(raw.(int))
+		return nil
+	}
+}
+
+// stringsSetter returns the set function of a string list field of the
+// config.
+func stringsSetter(field func(*Configuration) *[]string) func(*Configuration, interface{}) error {
+	return func(c *Configuration, raw interface{}) error {
+		*field(c) = raw.([]string)
+		return nil
+	}
+}
+
+// setDeleteVersionAfter sets delete_version_after in the config. A negative
+// value disables it and zero resets it to the default.
+func setDeleteVersionAfter(c *Configuration, raw interface{}) error {
+	dva := raw.(int)
+	switch {
+	case dva < 0:
+		c.DisableDeleteVersionAfter()
+	case dva == 0:
+		c.ResetDeleteVersionAfter()
+	default:
+		c.DeleteVersionAfter = ptypes.DurationProto(time.Duration(dva) * time.Second)
+	}
+	return nil
+}
+
+// setAllowedOptions sets the allowed_options of the config from a map of
+// prefixes to comma separated option lists.
+func setAllowedOptions(c *Configuration, raw interface{}) error {
+	allowedOptions := make(map[string]*OptionList)
+	for prefix, raw := range raw.(map[string]interface{}) {
+		options, err := parseutil.ParseCommaStringSlice(raw)
+		if err != nil {
+			return fmt.Errorf("invalid allowed_options for prefix %q: %w", prefix, err)
+		}
+		allowedOptions[prefix] = &OptionList{Options: options}
+	}
+	c.AllowedOptions = allowedOptions
+	return nil
+}
+
 // pathConfigWrite handles create and update commands to the config
 func (b *versionedKVBackend) pathConfigWrite() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-		maxRaw, mOk := data.GetOk("max_versions")
-		casRaw, cOk := data.GetOk("cas_required")
-		dvaRaw, dvaOk := data.GetOk("delete_version_after")
+		if err := checkDurationFields(data, "delete_version_after", "version_ttl", "max_delete_version_after"); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		values := make(map[string]interface{})
+		for _, field := range configFields {
+			if raw, ok := data.GetOk(field.name); ok {
+				values[field.name] = raw
+			}
+		}
+
+		// version_ttl is the name used for delete_version_after by the vkv
+		// plugin, accept it so existing automation keeps working.
+		var resp *logical.Response
+		if vttlRaw, vttlOk := data.GetOk("version_ttl"); vttlOk {
+			if dvaRaw, dvaOk := values["delete_version_after"]; dvaOk && dvaRaw.(int) != vttlRaw.(int) {
+				return logical.ErrorResponse("version_ttl is a deprecated alias of delete_version_after and cannot be set to a different value"), logical.ErrInvalidRequest
+			}
+			values["delete_version_after"] = vttlRaw
+
+			resp = &logical.Response{}
+			resp.AddWarning("\"version_ttl\" is deprecated, use \"delete_version_after\" instead.")
+		}
 
 		// Fast path validation
-		if !mOk && !cOk && !dvaOk {
-			return nil, nil
+		if len(values) == 0 {
+			config, err := b.config(ctx, req.Storage)
+			if err != nil {
+				return nil, err
+			}
+			rdata, err := b.configResponseData(config)
+			if err != nil {
+				return nil, err
+			}
+			return &logical.Response{Data: rdata}, nil
+		}
+
+		for _, field := range configFields {
+			raw, ok := values[field.name]
+			if !ok || field.validate == nil {
+				continue
+			}
+			if err := field.validate(field.name, raw); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
 		}
 
 		config, err := b.config(ctx, req.Storage)
 		if err != nil {
 			return nil, err
 		}
-
-		if mOk {
-			config.MaxVersions = uint32(maxRaw.(int))
+		before, err := configData(config)
+		if err != nil {
+			return nil, err
 		}
-		if cOk {
-			config.CasRequired = casRaw.(bool)
+
+		for _, field := range configFields {
+			if raw, ok := values[field.name]; ok {
+				if err := field.set(config, raw); err != nil {
+					return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+				}
+			}
 		}
+		_, dvaOk := values["delete_version_after"]
+		_, mdvaOk := values["max_delete_version_after"]
+		_, setOk := values["storage_emergency_threshold"]
 
-		if dvaOk {
-			dva := dvaRaw.(int)
-			switch {
-			case dva < 0:
-				config.DisableDeleteVersionAfter()
-			case dva == 0:
-				config.ResetDeleteVersionAfter()
-			default:
-				config.DeleteVersionAfter = ptypes.DurationProto(time.Duration(dva) * time.Second)
+		// The bound is checked against the resulting config, so that
+		// lowering it below the current delete_version_after is rejected
+		// as well.
+		if dva := deleteVersionAfter(config); (dvaOk || mdvaOk) && dva > 0 {
+			if err := config.validateDeleteVersionAfter("delete_version_after", dva); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 			}
 		}
 
-		bytes, err := proto.Marshal(config)
+		after, err := configData(config)
 		if err != nil {
 			return nil, err
 		}
+		changed := changedConfigFields(before, after)
+		if err := config.validateCombinations(changed); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		if err := b.writeConfig(ctx, req.Storage, config); err != nil {
+			return nil, err
+		}
+
+		b.emitEvent(ctx, req.Storage, "config-write", configPath, configPath, len(changed) > 0, "changed_fields", strings.Join(changed, ","))
+		if setOk {
+			// Recount the usage with the next periodic function, and end
+			// the storage emergency if the threshold was raised over it
+			b.resetStorageUsageScan()
+			b.updateStorageEmergency(ctx, req.Storage, config)
+		}
 
-		err = req.Storage.Put(ctx, &logical.StorageEntry{
-			Key:   path.Join(b.storagePrefix, configPath),
-			Value: bytes,
-		})
+		rdata, err := b.configResponseData(config)
 		if err != nil {
 			return nil, err
 		}
+		if resp == nil {
+			resp = &logical.Response{}
+		}
+		resp.Data = rdata
+		return resp, nil
+	}
+}
 
-		b.globalConfigLock.Lock()
-		defer b.globalConfigLock.Unlock()
+// writeConfig writes the configuration to storage and updates the cached
+// copy.
+func (b *versionedKVBackend) writeConfig(ctx context.Context, s logical.Storage, config *Configuration) error {
+	bytes, err := proto.Marshal(config)
+	if err != nil {
+		return err
+	}
 
-		b.globalConfig = config
-		kvEvent(ctx, b.Backend, "config-write", configPath, configPath, true, 2)
-		return nil, nil
+	err = s.Put(ctx, &logical.StorageEntry{
+		Key:   path.Join(b.storagePrefix, configPath),
+		Value: bytes,
+	})
+	if err != nil {
+		return err
 	}
+
+	b.globalConfigLock.Lock()
+	defer b.globalConfigLock.Unlock()
+
+	b.globalConfig = config
+	return nil
 }
 
 const (
@@ -194,6 +1090,233 @@ key-value store. This parameter accepts:
 	* delete_version_after (duration) - If set, the length of time before a
 	  version is deleted. A negative duration disables the use of
 	  delete_version_after on all keys. A zero duration clears the current
-	  setting. Accepts a Go duration format string.
+	  setting. Accepts a Go duration format string. The deprecated
+	  version_ttl parameter is accepted as an alias.
+
+	* healthcheck_path (string) - If set, the key reserved for synthetic
+	  monitoring probes. Writes to this key do not emit events, only retain a
+	  single version, and are skipped when the data is unchanged.
+
+	* allowed_options (map) - A map of key prefixes to the list of write
+	  options permitted for keys under that prefix. The longest matching
+	  prefix applies; keys matching no prefix may use any option.
+
+	* v1_data_enabled (bool) - If true, the read-only v1-data path will serve
+	  the current version of keys in the KV v1 response format
+
+	* expiry_scan_interval (duration) - If set, how often a background scan
+	  processes versions whose deletion time has passed. A zero duration
+	  disables the scan.
+
+	* destroy_expired_after (duration) - If set, the grace period after a
+	  version's deletion time once which the background expiry scan
+	  permanently destroys the version. A zero duration disables destroying
+	  expired versions.
+
+	* destroy_version_after (duration) - If set, how long after its creation
+	  a version is permanently destroyed and its data removed from storage,
+	  unlike delete_version_after, which only marks it deleted. Keys can set
+	  their own destroy_version_after, and the lower non-zero value applies.
+	  Reads report versions past it as destroyed and destroy them, writes
+	  destroy them before adding a version, and the expiry scan destroys the
+	  rest if expiry_scan_interval is set.
+
+	* max_version_age (duration) - If set, the age after which the background
+	  expiry scan prunes versions, like max_versions prunes them on write.
+	  The current version is never pruned. Requires expiry_scan_interval.
+
+	* min_versions (int) - The number of newest versions of each key that are
+	  kept regardless of max_version_age. Combined with max_version_age this
+	  keeps at least min_versions versions and at most max_version_age of
+	  history; combined with max_versions, versions are pruned once either
+	  limit is exceeded.
+
+	* undelete_confirm_prefixes (list) - A list of key prefixes under which
+	  undelete requests must set the confirm parameter to true.
+
+	* strict_options (bool) - If true, writes that set unrecognized options
+	  are rejected instead of returning a warning.
+
+	* retain_pruned_versions (bool) - If true, the data of versions pruned
+	  because the key exceeded max_versions is retained until their deletion
+	  time passes. Retained versions cannot be read.
+
+	* wrap_required_prefixes (list) - A list of key prefixes under which
+	  reads of secret data must be response-wrapped.
+
+	* wrap_ttl (duration) - If set, the maximum wrap TTL for reads under the
+	  wrap_required_prefixes, also used to wrap reads that did not request
+	  wrapping. If not set, reads that are not wrapped are rejected.
+
+	* unwrapped_read_denied_prefixes (list) - A list of key prefixes under
+	  which reads of secret data that the client did not request to be
+	  response-wrapped are rejected, even if wrap_ttl is set, for secrets
+	  that must never appear in the response bodies recorded by audit
+	  devices. Plugins cannot see how audit devices are configured, so the
+	  rejection applies whether or not raw responses are logged. The wrap_ttl
+	  maximum applies to wrapped reads under these prefixes as well.
+
+	* read_event_prefixes (list) - A list of key prefixes under which reads
+	  of secret data send a kv-v2/data-read event naming the version read
+	  and the entity and address of the request, so that unexpected access
+	  to the most sensitive secrets is seen without waiting for the audit
+	  log pipeline. Reads of subkeys send a kv-v2/subkeys-read event, and
+	  reads of metadata only do not send an event. Defaults to an empty
+	  list, sending no read events.
+
+	* mirrors (map) - A map of source key prefixes to target key prefixes.
+	  Writes to keys under a source prefix are mirrored to the same key under
+	  the target prefix, see the mirror/status path.
+
+	* deduplicate_version_data (bool) - If true, a new version with the same
+	  data as an earlier version of the key references the stored data of
+	  that version instead of storing another copy. The shared data is
+	  deleted once no version references it.
+
+	* approval_required_prefixes (list) - A list of key prefixes under which
+	  destroy and metadata delete requests must be approved by a second
+	  token, see the approval path.
+
+	* approval_ttl (duration) - If set, how long a pending approval request
+	  can be approved. Defaults to 24 hours.
+
+	* owner_enforced (bool) - If true, only the owner entity of a key and
+	  tokens with one of the owner_admin_policies may delete and destroy
+	  versions of the key, delete its metadata or change its owner. Keys
+	  without an owner are not restricted.
+
+	* owner_admin_policies (list) - A list of policies whose tokens may
+	  delete and destroy versions of keys regardless of their owner.
+
+	* max_storage_value_size (int) - The maximum size in bytes of the version
+	  data and key metadata written to storage, such as the 512KB value limit
+	  of Consul. Writes over the limit are rejected with an error naming the
+	  sizes instead of failing in the storage backend. Defaults to 0, which
+	  disables the check.
+
+	* max_versions_per_request (int) - The largest number of versions a
+	  single delete, undelete or destroy request can name. Larger requests
+	  are rejected. Requests naming more than 100 versions are processed in
+	  chunks of 100, releasing the lock of the key between chunks. Defaults
+	  to 1000, which also applies if set to 0.
+
+	* disable_events (bool) - If true, the mount sends no events, skipping
+	  the cost of sending them on mounts with many writes. Changes are still
+	  recorded in the changelog. This is the events feature of the
+	  config/features path, and setting either changes both. Defaults to
+	  false.
+
+	* background_max_delay (duration) - If set, the largest delay added to
+	  requests about keys while background tasks, such as tidy, jobs, the
+	  upgrade and the scans of the periodic function, run. The delay is the
+	  load of the background tasks times background_max_delay, so that
+	  clients are slowed down a little and predictably rather than timing
+	  out while background tasks saturate storage. See the background/load
+	  path. Defaults to 0, which disables the delay.
+
+	* background_ops_limit (int) - The rate of storage operations per second
+	  of the background tasks at which their load is full. Defaults to 1000,
+	  which also applies if set to 0.
+
+	* storage_emergency_threshold (int) - The total size in bytes of the
+	  version data of the mount over which it enters a storage emergency:
+	  writes adding version data are rejected with a 507 error, while reads,
+	  deletes, destroys and metadata writes still work, so that storage can
+	  be freed. A "kv-v2/storage-emergency" event is sent when the emergency
+	  starts, with a "state" of "active", and when it ends, with a "state" of
+	  "cleared". The usage is recounted by the periodic function once an
+	  hour, and adjusted by the writes and deletes of each server in between.
+	  Defaults to 0, which disables the threshold.
+
+	* max_key_bytes (int) - The largest total size in bytes of the data of
+	  the versions of a key that are not destroyed, bounding the storage a
+	  runaway writer can use on a single key. Writes, patches, rollbacks and
+	  transactions that would exceed it are rejected; versions pruned by
+	  max_versions do not count. Keys can set their own max_key_bytes, and the
+	  lower non-zero value applies. Defaults to 0, which disables the limit.
+
+	* missing_key_cache_ttl (duration) - If set, how long a lookup of a key
+	  that does not exist is cached, so that clients repeatedly reading a
+	  missing path do not cause a storage lookup per request. Writes to the
+	  key clear the cache entry, also on other nodes of the cluster. Reads
+	  served from the cache are counted in the
+	  secrets.kv.missing_key_cache_hit metric. Defaults to 0, which disables
+	  the cache.
+
+	* trash_retention (duration) - How long keys deleted with a soft metadata
+	  delete, setting "soft" to true on a metadata delete, are kept in the
+	  trash. Until then, metadata-undelete restores the key with all of its
+	  versions; afterwards tidy purges it permanently. Defaults to 0, which
+	  disables soft deletes.
+
+	* event_sample_rates (map) - A map of key prefixes to the fraction,
+	  between 0 and 1, of events about keys under that prefix that are sent,
+	  such as 0.01 for a chatty scratch prefix. The longest matching prefix
+	  applies. Sampled events carry a "sample_rate" metadata field. Changes
+	  are recorded in the changelog regardless of sampling.
+
+	* archive_after (duration) - If set, how long after a version was last
+	  read, or written if it was never read, the background expiry scan moves
+	  its data to a separate archive storage prefix. The next read of an
+	  archived version moves it back. Reads record the last read time at
+	  most once a day. Requires expiry_scan_interval.
+
+	* archive_compression (string) - The compression of archived version
+	  data, one of "gzip", "snappy" or "lz4". Defaults to no compression.
+
+	* debug_generate_enabled (bool) - If true, the debug/generate endpoint
+	  can be used to write synthetic keys for load testing. Defaults to
+	  false.
+
+	* max_delete_version_after (duration) - If set, the largest
+	  delete_version_after accepted for the mount, in key metadata and in the
+	  delete_version_after write option. Values over it are rejected rather
+	  than capped. Keys already set to a larger value keep it. Cannot be
+	  greater than 100 years, the bound that applies if not set.
+
+	* concurrency_limits (map) - A map of key prefixes to the maximum number
+	  of requests about keys under that prefix that are handled at the same
+	  time, such as {"tenant-a/": 8}, so a burst of requests under one prefix
+	  cannot starve the others. The longest matching prefix applies, and each
+	  prefix has its own limit on each server. Requests over the limit are
+	  rejected with a 429 status code and counted in the
+	  secrets.kv.concurrency_throttled metric.
+
+	* integrity_check_interval (duration) - If set, how often a background
+	  check verifies the stored versions of a random sample of the keys:
+	  each version that is not destroyed must have a stored entry that
+	  decodes, belongs to the version and holds data matching the content
+	  hash recorded when it was written. Failures are logged, counted in the
+	  secrets.kv.integrity_failure metric and sent as kv-v2/integrity-alert
+	  events naming the version and the reason. The check only reads
+	  storage, so it also runs on performance secondaries.
+
+	* integrity_sample_rate (float) - The fraction, between 0 and 1, of keys
+	  verified by each integrity check. Defaults to 0.01.
+
+	* tidy_interval (duration) - If set, how often a background tidy runs
+	  the same cleanup as the tidy endpoint, removing the stored data of
+	  destroyed versions and orphaned version entries.
+
+Settings that depend on each other are validated together when one of them
+is written: min_versions cannot be greater than a non-zero max_versions,
+destroy_version_after cannot be shorter than delete_version_after, and
+max_version_age and archive_after require expiry_scan_interval. Writes return
+the resulting configuration, as read from this path, and send a
+kv-v2/config-write event whose "changed_fields" metadata lists the settings
+that changed.
+
+Reading the configuration also returns "seal_wrap_mismatch", which is true if
+stored version data failed to decode since the backend started. Version data
+is seal wrapped, so this indicates the mount lost seal wrap support. It also
+returns "v1_prefixes", the paths converted back to KV v1 entries by the
+convert/v1 endpoint, and "storage_usage_bytes" and "storage_emergency", the
+storage usage counted by this server and whether it is in a storage
+emergency.
 `
 )
+
+// isHealthcheckPath returns true if key is the configured healthcheck key.
+func (c *Configuration) isHealthcheckPath(key string) bool {
+	return c.HealthcheckPath != "" && c.HealthcheckPath == key
+}
@@ -0,0 +1,236 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// featureEvents controls whether the mount sends events.
+	featureEvents = "events"
+
+	// featureObservations controls whether the mount emits metrics.
+	featureObservations = "observations"
+
+	// featureReadTracking controls whether reads of version data are
+	// counted and update the last read time of the version.
+	featureReadTracking = "read_tracking"
+
+	// featureTidy controls whether the mount can be tidied, by the tidy
+	// endpoint and the periodic tidy.
+	featureTidy = "tidy"
+
+	// featurePagination controls whether metadata lists accept the limit
+	// and after parameters.
+	featurePagination = "pagination"
+)
+
+// defaultFeatures holds every optional subsystem that can be toggled with the
+// config/features endpoint, along with whether it is enabled by default.
+var defaultFeatures = map[string]bool{
+	featureEvents:       true,
+	featureObservations: true,
+	featureReadTracking: true,
+	featureTidy:         true,
+	featurePagination:   true,
+}
+
+// featureEnabled returns true if the named optional subsystem is enabled for
+// the mount.
+func (c *Configuration) featureEnabled(name string) bool {
+	if enabled, ok := c.GetFeatures()[name]; ok {
+		return enabled
+	}
+
+	return defaultFeatures[name]
+}
+
+// setFeature enables or disables the named optional subsystem. The features
+// map is copied rather than changed in place, as the config may be shared
+// with concurrent requests.
+func (c *Configuration) setFeature(name string, enabled bool) {
+	features := make(map[string]bool, len(c.Features)+1)
+	for n, e := range c.Features {
+		features[n] = e
+	}
+	features[name] = enabled
+	c.Features = features
+}
+
+// pathConfigFeatures returns the path configuration for reading and toggling
+// the optional subsystems of the backend.
+func pathConfigFeatures(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/features$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"features": {
+				Type:        framework.TypeMap,
+				Description: "A map of optional subsystem names to a boolean enabling or disabling the subsystem.",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathConfigFeaturesWrite()),
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "configure",
+					OperationSuffix: "features",
+				},
+				Summary: "Enable or disable optional subsystems of the key-value store.",
+				Responses: map[int][]framework.Response{
+					http.StatusNoContent: {{
+						Description: http.StatusText(http.StatusNoContent),
+					}},
+				},
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathConfigFeaturesRead()),
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb:   "read",
+					OperationSuffix: "features",
+				},
+				Summary: "Read which optional subsystems of the key-value store are enabled.",
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"features": {
+								Type:        framework.TypeMap,
+								Description: "A map of optional subsystem names to whether the subsystem is enabled.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    featuresHelpSyn,
+		HelpDescription: featuresHelpDesc,
+	}
+}
+
+func (b *versionedKVBackend) pathConfigFeaturesRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		features := make(map[string]bool, len(defaultFeatures))
+		for name := range defaultFeatures {
+			features[name] = config.featureEnabled(name)
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"features": features,
+			},
+		}, nil
+	}
+}
+
+func (b *versionedKVBackend) pathConfigFeaturesWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		featuresRaw, ok := data.GetOk("features")
+		if !ok {
+			return nil, nil
+		}
+
+		updates := make(map[string]bool)
+		for name, raw := range featuresRaw.(map[string]interface{}) {
+			if _, ok := defaultFeatures[name]; !ok {
+				return logical.ErrorResponse("unknown feature %q, must be one of: %s", name, strings.Join(knownFeatures(), ", ")), nil
+			}
+
+			enabled, err := parseutil.ParseBool(raw)
+			if err != nil {
+				return logical.ErrorResponse("invalid value for feature %q: %s", name, err), nil
+			}
+			updates[name] = enabled
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, enabled := range updates {
+			config.setFeature(name, enabled)
+		}
+
+		if err := b.writeConfig(ctx, req.Storage, config); err != nil {
+			return nil, err
+		}
+
+		b.emitEvent(ctx, req.Storage, "config-write", configPath+"/features", configPath+"/features", true)
+		return nil, nil
+	}
+}
+
+// knownFeatures returns the sorted names of all optional subsystems.
+func knownFeatures() []string {
+	names := make([]string, 0, len(defaultFeatures))
+	for name := range defaultFeatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+const featuresHelpSyn = `Enables or disables optional subsystems of the KV store.`
+
+const featuresHelpDesc = `
+This path enables or disables optional subsystems of the key-value store for
+this mount, allowing new capabilities to be rolled out gradually. Reading this
+path returns whether each subsystem is currently enabled.
+
+The "features" parameter is a map of subsystem names to booleans. Subsystems
+not included in the map keep their current setting. The following subsystems
+are supported:
+
+	* events (default: enabled) - Send events for operations on the mount,
+	  also set by the disable_events parameter of the config path
+
+	* observations (default: enabled) - Emit the secrets.kv metrics of the
+	  mount, such as secrets.kv.storage_usage_bytes
+
+	* read_tracking (default: enabled) - Count reads of version data for the
+	  read statistics, and record the last read time of versions that
+	  archive_after archives by. While disabled, reads of archived versions
+	  do not move their data back out of the archive
+
+	* tidy (default: enabled) - Remove the stored data of destroyed versions
+	  and purge expired trash with the tidy endpoint and the periodic tidy
+	  of tidy_interval. While disabled, tidy requests are rejected
+
+	* pagination (default: enabled) - Accept the "limit" and "after"
+	  parameters of metadata lists. While disabled, lists setting them are
+	  rejected
+
+Events about a key include the "current_version" of the key and an
+"event_sequence" that increases by one for every event about the key, in the
+order the events are sent. A gap in the sequence means an event was missed,
+for example while events were disabled. The sequence of a key restarts after
+its metadata is deleted.
+
+Events about a single version, such as data writes, patches and deletes,
+rollbacks and restores, also include the "version" they refer to. Events sent
+for a request include its "request_id", and the "entity_id" and
+"display_name" of the token that made it if set.
+`
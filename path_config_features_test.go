@@ -0,0 +1,308 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_ConfigFeatures(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config/features",
+		Storage:   storage,
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("features ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(
+		t,
+		schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+		resp,
+		true,
+	)
+
+	for name := range defaultFeatures {
+		if !resp.Data["features"].(map[string]bool)[name] {
+			t.Fatalf("expected %s to be enabled by default, resp: %#v", name, resp)
+		}
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/features",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"features": map[string]interface{}{
+				"bogus": true,
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected unknown feature to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	req.Data = map[string]interface{}{
+		"features": map[string]interface{}{
+			featureEvents: false,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("features UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	// Disabling events is itself not sent as an event
+	events.expectEvents(t, []expectedEvent{})
+}
+
+func TestVersionedKV_ConfigDisableEvents(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	ctx := context.Background()
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", operation, path, err, resp)
+		}
+		return resp
+	}
+
+	if request(logical.ReadOperation, "config", nil).Data["disable_events"] != false {
+		t.Fatal("expected events to be enabled by default")
+	}
+
+	request(logical.UpdateOperation, "config", map[string]interface{}{"disable_events": true})
+	request(logical.CreateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"bar": "baz"},
+	})
+	events.expectEvents(t, nil)
+
+	if request(logical.ReadOperation, "config", nil).Data["disable_events"] != true {
+		t.Fatal("expected events to be disabled")
+	}
+	if request(logical.ReadOperation, "config/features", nil).Data["features"].(map[string]bool)[featureEvents] {
+		t.Fatal("expected the events feature to be disabled")
+	}
+
+	// Enabling the feature enables events again
+	request(logical.UpdateOperation, "config/features", map[string]interface{}{
+		"features": map[string]interface{}{featureEvents: true},
+	})
+	if request(logical.ReadOperation, "config", nil).Data["disable_events"] != false {
+		t.Fatal("expected events to be enabled")
+	}
+	request(logical.CreateOperation, "data/foo", map[string]interface{}{
+		"data": map[string]interface{}{"bar": "baz"},
+	})
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", configPath + "/features", configPath + "/features"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+	})
+}
+
+// setFeatures toggles the features of the mount, failing the test if the
+// request fails.
+func setFeatures(t *testing.T, b logical.Backend, s logical.Storage, features map[string]interface{}) {
+	t.Helper()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/features",
+		Storage:   s,
+		Data:      map[string]interface{}{"features": features},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("features UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_ConfigFeatures_Observations(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	sink := metrics.NewInmemSink(time.Hour, time.Hour)
+	conf := metrics.DefaultConfig("")
+	conf.EnableHostname = false
+	conf.EnableRuntimeMetrics = false
+	if _, err := metrics.NewGlobal(conf, sink); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		metrics.NewGlobal(metrics.DefaultConfig(""), &metrics.BlackholeSink{})
+	})
+	cacheHits := func() int {
+		t.Helper()
+		var hits int
+		for _, interval := range sink.Data() {
+			interval.RLock()
+			for _, counter := range interval.Counters {
+				if strings.HasSuffix(counter.Name, "missing_key_cache_hit") {
+					hits += counter.Count
+				}
+			}
+			interval.RUnlock()
+		}
+		return hits
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data:      map[string]interface{}{"missing_key_cache_ttl": "1h"},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	read := func() {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "data/missing",
+			Storage:   storage,
+		})
+		if err != nil || resp != nil {
+			t.Fatalf("expected no response for a missing key, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	// The first read caches the missing key, the second hits the cache
+	read()
+	read()
+	if hits := cacheHits(); hits != 1 {
+		t.Fatalf("expected 1 cache hit to be counted, got %d", hits)
+	}
+
+	setFeatures(t, b, storage, map[string]interface{}{featureObservations: false})
+	read()
+	if hits := cacheHits(); hits != 1 {
+		t.Fatalf("expected no metrics while observations are disabled, got %d cache hits", hits)
+	}
+}
+
+func TestVersionedKV_ConfigFeatures_Tidy(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	tidy := func() (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "tidy",
+			Storage:   storage,
+		})
+	}
+
+	setFeatures(t, b, storage, map[string]interface{}{featureTidy: false})
+	resp, err := tidy()
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the tidy to be rejected, err: %v, resp %#v", err, resp)
+	}
+
+	// The periodic tidy does not start its interval either
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data:      map[string]interface{}{"tidy_interval": "1h"},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if err := kv.periodicTidy(ctx, storage, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if !kv.lastTidy.IsZero() {
+		t.Fatal("expected the periodic tidy to be skipped")
+	}
+
+	setFeatures(t, b, storage, map[string]interface{}{featureTidy: true})
+	if resp, err := tidy(); err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("tidy request failed, err: %s, resp %#v", err, resp)
+	}
+	if err := kv.periodicTidy(ctx, storage, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if kv.lastTidy.IsZero() {
+		t.Fatal("expected the periodic tidy to start its interval")
+	}
+}
+
+func TestVersionedKV_ConfigFeatures_Pagination(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/" + key,
+			Storage:   storage,
+			Data:      map[string]interface{}{"data": map[string]interface{}{"bar": "baz"}},
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+	list := func(data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "metadata/",
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+
+	setFeatures(t, b, storage, map[string]interface{}{featurePagination: false})
+	for _, data := range []map[string]interface{}{{"limit": 1}, {"after": "a"}} {
+		resp, err := list(data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected the paginated list to be rejected, err: %v, resp %#v", err, resp)
+		}
+	}
+	if resp, err := list(nil); err != nil || resp == nil || len(resp.Data["keys"].([]string)) != 2 {
+		t.Fatalf("expected unpaginated lists to work, err: %v, resp %#v", err, resp)
+	}
+
+	setFeatures(t, b, storage, map[string]interface{}{featurePagination: true})
+	resp, err := list(map[string]interface{}{"limit": 1})
+	if err != nil || resp == nil || resp.IsError() || resp.Data["next_after"] != "a" {
+		t.Fatalf("expected a page of the list, err: %v, resp %#v", err, resp)
+	}
+}
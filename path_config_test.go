@@ -6,9 +6,12 @@ package kv
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -41,6 +44,11 @@ func TestVersionedKV_Config(t *testing.T) {
 		true,
 	)
 
+	// The write returns the resulting config
+	if resp.Data["max_versions"] != uint32(4) || resp.Data["delete_version_after"] != d.String() {
+		t.Fatalf("Bad response: %#v", resp)
+	}
+
 	req = &logical.Request{
 		Operation: logical.ReadOperation,
 		Path:      "config",
@@ -73,6 +81,56 @@ func TestVersionedKV_Config(t *testing.T) {
 	events.expectEvents(t, []expectedEvent{
 		{"kv-v2/config-write", "config", "config"},
 	})
+	changed := events.eventsProcessed[0].Event.Metadata.Fields["changed_fields"].GetStringValue()
+	if changed != "cas_required,delete_version_after,max_versions" {
+		t.Fatalf("unexpected changed_fields: %q", changed)
+	}
+}
+
+func TestVersionedKV_Config_Combinations(t *testing.T) {
+	b, storage := getBackend(t)
+
+	write := func(data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	wantRejected := func(data map[string]interface{}) {
+		t.Helper()
+		resp, err := write(data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected %v to be rejected, err: %v, resp: %#v", data, err, resp)
+		}
+	}
+	wantAccepted := func(data map[string]interface{}) {
+		t.Helper()
+		resp, err := write(data)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("expected %v to be accepted, err: %v, resp: %#v", data, err, resp)
+		}
+	}
+
+	wantRejected(map[string]interface{}{"max_versions": 2, "min_versions": 3})
+	wantAccepted(map[string]interface{}{"max_versions": 3, "min_versions": 3})
+	wantRejected(map[string]interface{}{"max_versions": 2})
+	wantAccepted(map[string]interface{}{"max_versions": 0})
+
+	wantRejected(map[string]interface{}{"delete_version_after": "2h", "destroy_version_after": "1h"})
+	wantAccepted(map[string]interface{}{"delete_version_after": "1h", "destroy_version_after": "2h"})
+	wantRejected(map[string]interface{}{"delete_version_after": "3h"})
+	wantAccepted(map[string]interface{}{"delete_version_after": -1})
+
+	wantRejected(map[string]interface{}{"max_version_age": "24h"})
+	wantRejected(map[string]interface{}{"archive_after": "24h"})
+	wantAccepted(map[string]interface{}{"expiry_scan_interval": "1m", "max_version_age": "24h", "archive_after": "24h"})
+	wantRejected(map[string]interface{}{"expiry_scan_interval": 0})
+
+	// A write not changing any of the settings is accepted
+	wantAccepted(map[string]interface{}{"cas_required": true})
 }
 
 func getDuration(t *testing.T, in string) time.Duration {
@@ -140,7 +198,7 @@ func TestVersionedKV_Config_DeleteVersionAfter(t *testing.T) {
 				Data:      data,
 			}
 			resp, err = b.HandleRequest(context.Background(), req)
-			wantNoResponse(t, resp, err)
+			wantResponse(t, resp, err)
 			schema.ValidateResponse(
 				t,
 				schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
@@ -180,7 +238,7 @@ func TestVersionedKV_Config_DeleteVersionAfter(t *testing.T) {
 				Data:      data,
 			}
 			resp, err = b.HandleRequest(context.Background(), req)
-			wantNoResponse(t, resp, err)
+			wantResponse(t, resp, err)
 			schema.ValidateResponse(
 				t,
 				schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
@@ -210,3 +268,113 @@ func TestVersionedKV_Config_DeleteVersionAfter(t *testing.T) {
 		})
 	}
 }
+
+func TestVersionedKV_Config_VersionTTL(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"version_ttl": "1h",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if len(resp.Warnings) != 1 {
+		t.Fatalf("expected a deprecation warning, resp: %#v", resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("config ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["delete_version_after"] != time.Hour.String() {
+		t.Fatalf("expected delete_version_after to be set from version_ttl, resp: %#v", resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"version_ttl":          "1h",
+			"delete_version_after": "2h",
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected conflicting values to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Config_Fields(t *testing.T) {
+	b, _ := getBackend(t)
+
+	fields := make(map[string]bool, len(configFields))
+	for _, field := range configFields {
+		if fields[field.name] {
+			t.Fatalf("config field %q is listed twice", field.name)
+		}
+		fields[field.name] = true
+	}
+	for name := range pathConfig(b.(*versionedKVBackend)).Fields {
+		if name != "version_ttl" && !fields[name] {
+			t.Fatalf("config field %q is not in configFields", name)
+		}
+	}
+
+	// Settings applying to keys by prefix are lists of prefixes or maps
+	settings, err := configData(&Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range settings {
+		isMap := reflect.ValueOf(value).Kind() == reflect.Map
+		if (strings.HasSuffix(name, "_prefixes") || isMap) && !strutil.StrListContains(keyPrefixFields, name) {
+			t.Fatalf("config setting %q is not in keyPrefixFields", name)
+		}
+	}
+}
+
+func TestVersionedKV_Config_RejectedWrite(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	// The config is loaded from storage by the write, and the first field
+	// is set before the second is rejected, which must not change the
+	// cached config.
+	kvb.globalConfig = nil
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_versions": 5,
+			"mirrors":      map[string]interface{}{"": "other"},
+		},
+	})
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected the write to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	config, err := kvb.config(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MaxVersions != 0 || config.Mirrors != nil {
+		t.Fatalf("expected the rejected write to leave the config unchanged: %#v", config)
+	}
+}
@@ -0,0 +1,455 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathConvertV1 returns the path configuration for the endpoint converting
+// the keys under a prefix back to passthrough (KV v1) entries
+func pathConvertV1(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "convert/v1$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "convert",
+			OperationSuffix: "v1",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "The folder whose keys are converted to KV v1 entries.",
+				Required:    true,
+			},
+			"confirm": {
+				Type:        framework.TypeBool,
+				Description: "Confirms the conversion. Must be set to true unless preview is set.",
+			},
+			"preview": {
+				Type:        framework.TypeBool,
+				Description: "If true, the keys that would be converted are returned without changing anything.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathConvertV1Write()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"converted_keys": {
+								Type:        framework.TypeStringSlice,
+								Description: "The keys whose current data was written as a KV v1 entry, or would be if preview is set.",
+								Required:    true,
+							},
+							"removed_keys": {
+								Type:        framework.TypeStringSlice,
+								Description: "The keys without current data whose versions were removed, or would be if preview is set.",
+								Required:    true,
+							},
+							"blocked_keys": {
+								Type:        framework.TypeStringSlice,
+								Description: "The keys that are locked or require a read passphrase, which prevent the conversion.",
+								Required:    true,
+							},
+							"job_id": {
+								Type:        framework.TypeString,
+								Description: "The ID of the job that ran the conversion, see the jobs path.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    convertV1HelpSyn,
+		HelpDescription: convertV1HelpDesc,
+	}
+}
+
+// flattenOutcome is the outcome of flattening a key into a KV v1 entry.
+type flattenOutcome int
+
+const (
+	// flattenNone is returned for keys without metadata.
+	flattenNone flattenOutcome = iota
+
+	// flattenConverted is returned for keys whose current data is served
+	// as a KV v1 entry.
+	flattenConverted
+
+	// flattenRemoved is returned for keys without current data, which
+	// leave no KV v1 entry.
+	flattenRemoved
+
+	// flattenBlocked is returned for keys that are locked or require a
+	// read passphrase, which are not flattened.
+	flattenBlocked
+)
+
+// errConversionBlocked is returned when keys that are locked or require a
+// read passphrase prevent the conversion of a prefix to KV v1.
+var errConversionBlocked = errors.New("keys that are locked or require a read passphrase prevent the conversion")
+
+// v1Prefix returns the prefix of the v1_prefixes of the config the key is
+// under, or false if the key is served as a versioned key.
+func (c *Configuration) v1Prefix(key string) (string, bool) {
+	for _, prefix := range c.V1Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// pathConvertV1Write converts the keys under a prefix to KV v1 entries and
+// adds the prefix to the v1_prefixes of the config.
+func (b *versionedKVBackend) pathConvertV1Write() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		preview := data.Get("preview").(bool)
+		if !preview && !data.Get("confirm").(bool) {
+			return logical.ErrorResponse("converting keys to KV v1 requires the confirm parameter to be set to true"), logical.ErrInvalidRequest
+		}
+
+		prefix := strings.Trim(data.Get("path").(string), "/")
+		if prefix == "" {
+			return logical.ErrorResponse("a path must be given, the whole mount cannot be converted"), logical.ErrInvalidRequest
+		}
+		prefix += "/"
+
+		// KV v1 entries are served by the invalid path, which is the last
+		// path, so keys under the prefix must not be taken by another
+		// endpoint of the mount
+		if b.Route(prefix+"key") != b.Paths[len(b.Paths)-1] || strings.HasPrefix(prefix, b.storagePrefix+"/") {
+			return logical.ErrorResponse("the path %q is reserved by the mount and cannot be converted", prefix), logical.ErrInvalidRequest
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		run, ctx, err := b.startJob(ctx, req.Storage, "convert-v1")
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := b.convertV1(ctx, run.s, run, config, prefix, preview)
+		run.finish(err)
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			resp.AddWarning("the job was canceled before all keys were converted, convert the path again to resume")
+		case errors.Is(err, errConversionBlocked):
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		case err != nil:
+			return nil, err
+		}
+
+		resp.Data["job_id"] = run.job.Id
+		return resp, nil
+	}
+}
+
+// convertV1 flattens the keys under the prefix into KV v1 entries, recording
+// its progress in the job. The prefix is added to the v1_prefixes of the
+// config before any key is flattened, so versioned requests cannot recreate
+// keys that were already flattened. No key is flattened if some are blocked.
+func (b *versionedKVBackend) convertV1(ctx context.Context, s logical.Storage, run *jobRun, config *Configuration, prefix string, preview bool) (*logical.Response, error) {
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"converted_keys": []string{},
+			"removed_keys":   []string{},
+			"blocked_keys":   []string{},
+		},
+	}
+
+	var keys []string
+	if err := b.walkKeys(ctx, s, prefix, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}); err != nil {
+		return resp, err
+	}
+	sort.Strings(keys)
+
+	flatten := func(preview bool) error {
+		converted, removed, blocked := []string{}, []string{}, []string{}
+		for i, key := range keys {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			lock := locksutil.LockForKey(b.locks, key)
+			lock.Lock()
+			outcome, err := b.flattenKey(ctx, s, key, preview)
+			lock.Unlock()
+			if err != nil {
+				return fmt.Errorf("error converting %q: %w", key, err)
+			}
+
+			switch outcome {
+			case flattenConverted:
+				converted = append(converted, key)
+			case flattenRemoved:
+				removed = append(removed, key)
+			case flattenBlocked:
+				blocked = append(blocked, key)
+			}
+			run.progress(uint64(i+1), uint64(len(keys)))
+		}
+
+		resp.Data["converted_keys"] = converted
+		resp.Data["removed_keys"] = removed
+		resp.Data["blocked_keys"] = blocked
+		return nil
+	}
+
+	if err := flatten(true); err != nil || preview {
+		return resp, err
+	}
+	if blocked := resp.Data["blocked_keys"].([]string); len(blocked) > 0 {
+		return resp, fmt.Errorf("%w: %s", errConversionBlocked, strings.Join(blocked, ", "))
+	}
+
+	if _, ok := config.v1Prefix(prefix); !ok {
+		config.V1Prefixes = append(append([]string{}, config.V1Prefixes...), prefix)
+		if err := b.writeConfig(ctx, s, config); err != nil {
+			return resp, err
+		}
+		b.emitEvent(ctx, s, "config-write", configPath, configPath, true)
+	}
+
+	if err := flatten(false); err != nil {
+		return resp, err
+	}
+	if blocked := resp.Data["blocked_keys"].([]string); len(blocked) > 0 {
+		return resp, fmt.Errorf("%w, convert the path again once they are unlocked: %s", errConversionBlocked, strings.Join(blocked, ", "))
+	}
+	return resp, nil
+}
+
+// flattenKey writes the data of the current version of the key as a KV v1
+// entry at the key, then removes its metadata, versions and changelog. A KV
+// v1 entry already written to the key is newer and kept. Keys without
+// current data are removed without an entry. Keys that are locked or require
+// a read passphrase are not flattened. The caller must hold the lock of the
+// key.
+func (b *versionedKVBackend) flattenKey(ctx context.Context, s logical.Storage, key string, preview bool) (flattenOutcome, error) {
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil {
+		return flattenNone, err
+	}
+	if meta == nil {
+		return flattenNone, nil
+	}
+	if meta.isLocked() || meta.readPassphraseRequired() {
+		return flattenBlocked, nil
+	}
+
+	outcome := flattenRemoved
+	vm := meta.Versions[meta.CurrentVersion]
+	if vm != nil && !vm.Destroyed {
+		outcome = flattenConverted
+		if vm.DeletionTime != nil {
+			deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+			if err != nil {
+				return flattenNone, err
+			}
+			if deletionTime.Before(b.now()) {
+				outcome = flattenRemoved
+			}
+		}
+	}
+	if preview {
+		return outcome, nil
+	}
+
+	if outcome == flattenConverted {
+		existing, err := s.Get(ctx, key)
+		if err != nil {
+			return flattenNone, err
+		}
+		if existing == nil {
+			version, err := b.getVersion(ctx, s, key, meta.CurrentVersion)
+			if err != nil {
+				return flattenNone, err
+			}
+			if version == nil {
+				return flattenNone, errors.New("could not find version data")
+			}
+
+			if err := s.Put(ctx, &logical.StorageEntry{
+				Key:   key,
+				Value: version.Data,
+			}); err != nil {
+				return flattenNone, err
+			}
+		}
+	}
+
+	if err := b.deleteKeyData(ctx, s, meta); err != nil {
+		return flattenNone, err
+	}
+
+	b.emitKeyEvent(ctx, s, meta, meta.nextEventSequence(), "convert-v1", "metadata/"+key, key, true)
+	return outcome, b.deleteChangelog(ctx, s, key)
+}
+
+// handleV1Request serves a request to a key under the v1_prefixes of the
+// config the way a KV v1 mount does. A key that has not been flattened yet,
+// such as when the conversion was interrupted, is flattened first.
+func (b *versionedKVBackend) handleV1Request(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if req.Operation == logical.ListOperation {
+		path := req.Path
+		if !strings.HasSuffix(path, "/") {
+			path += "/"
+		}
+
+		keys, err := req.Storage.List(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return logical.ListResponse(keys), nil
+	}
+
+	key := req.Path
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	outcome, err := b.flattenKey(ctx, req.Storage, key, false)
+	if err != nil {
+		return nil, err
+	}
+	if outcome == flattenBlocked {
+		return logical.ErrorResponse("secret %q is locked or requires a read passphrase and has not been converted to KV v1", key), logical.ErrInvalidRequest
+	}
+
+	switch req.Operation {
+	case logical.ReadOperation:
+		if b.metadataOnly {
+			return valueReadDenied()
+		}
+
+		out, err := req.Storage.Get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("read failed: %w", err)
+		}
+		if out == nil {
+			return nil, nil
+		}
+
+		var rawData map[string]interface{}
+		if err := jsonutil.DecodeJSON(out.Value, &rawData); err != nil {
+			return nil, fmt.Errorf("json decoding failed: %w", err)
+		}
+		return &logical.Response{
+			Data: rawData,
+		}, nil
+
+	case logical.CreateOperation, logical.UpdateOperation:
+		if len(req.Data) == 0 {
+			return logical.ErrorResponse("missing data fields"), logical.ErrInvalidRequest
+		}
+
+		buf, err := json.Marshal(req.Data)
+		if err != nil {
+			return nil, fmt.Errorf("json encoding failed: %w", err)
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStorageValueSize(config, "secret data", len(buf)); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		if err := req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   key,
+			Value: buf,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to write: %w", err)
+		}
+
+		b.emitV1Event(ctx, req.Storage, "write", key, key)
+		return nil, nil
+
+	case logical.DeleteOperation:
+		if err := req.Storage.Delete(ctx, key); err != nil {
+			return nil, err
+		}
+
+		b.emitV1Event(ctx, req.Storage, "delete", key, "")
+		return nil, nil
+	}
+
+	return logical.ErrorResponse("%s is not supported for KV v1 secrets", req.Operation), logical.ErrUnsupportedOperation
+}
+
+// emitV1Event sends a KV v1 event about a key under the v1_prefixes of the
+// config, unless the events feature has been disabled for the mount.
+func (b *versionedKVBackend) emitV1Event(ctx context.Context, s logical.Storage, operation, path, dataPath string) {
+	config, err := b.config(ctx, s)
+	if err != nil {
+		requestLogger(ctx, b.Logger()).Error("Error reading config to send event", "error", err)
+		return
+	}
+	if !config.featureEnabled(featureEvents) {
+		return
+	}
+
+	kvEvent(ctx, b.Backend, operation, path, dataPath, true, 1)
+}
+
+const convertV1HelpSyn = `Converts the keys under a path back to KV v1 entries.`
+const convertV1HelpDesc = `
+Applications that turn out to be incompatible with the semantics of KV v2 can
+be rolled back to KV v1 one path at a time, without moving their secrets to
+another mount.
+
+This endpoint writes the data of the current version of each key under the
+given "path" as a plain KV v1 entry at the same path in the mount, removes the
+versions, metadata and changelog of the key, and adds the path to the
+"v1_prefixes" of the config. Keys whose current version is deleted, destroyed
+or expired are removed without an entry. The version history of the keys is
+lost, so the conversion cannot be undone.
+
+Once converted, secrets under the path are read, written, deleted and listed
+at their path directly, as on a KV v1 mount, for example with
+"vault read <mount>/<path>/<key>". Reads return the secret data without a
+lease. Requests to the data/, metadata/ and other versioned endpoints for
+keys under the path are rejected. The entries are not listed under the parent
+folders of the path.
+
+The "confirm" parameter must be set to true. If the "preview" parameter is set
+to true instead, the keys that would be converted and removed are returned
+and nothing is written. Keys that are locked or require a read passphrase
+prevent the conversion and are returned in "blocked_keys". The endpoint
+requires sudo capability.
+
+The conversion runs as a job, listed under jobs/ while the request runs, so
+its progress can be followed and it can be canceled. The path is added to the
+v1_prefixes before the keys are converted; a key that has not been converted
+yet, such as after the job was canceled, is converted when it is first
+requested, and the conversion of the path can be run again to resume it.
+`
@@ -0,0 +1,202 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_ConvertV1(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	if !strutil.StrListContains(b.SpecialPaths().Root, "convert/*") {
+		t.Fatalf("expected conversions to require sudo: %v", b.SpecialPaths().Root)
+	}
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", operation, path, err, resp)
+		}
+		return resp
+	}
+	convert := func(data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "convert/v1",
+			Storage:   storage,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err == nil && resp != nil && !resp.IsError() {
+			schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route(req.Path), req.Operation), resp, true)
+		}
+		return resp, err
+	}
+
+	for key, data := range map[string]map[string]interface{}{
+		"legacy/a":       {"foo": "bar"},
+		"legacy/sub/b":   {"foo": "b"},
+		"legacy/deleted": {"foo": "deleted"},
+		"legacy/locked":  {"foo": "locked"},
+		"other/c":        {"foo": "c"},
+	} {
+		request(logical.UpdateOperation, "data/"+key, map[string]interface{}{"data": data})
+	}
+	request(logical.UpdateOperation, "data/legacy/a", map[string]interface{}{"data": map[string]interface{}{"foo": "baz"}})
+	request(logical.DeleteOperation, "data/legacy/deleted", nil)
+	request(logical.UpdateOperation, "metadata/legacy/locked", map[string]interface{}{"locked": true})
+
+	for _, data := range []map[string]interface{}{
+		{"path": "legacy"},
+		{"path": "", "confirm": true},
+		{"path": "data", "confirm": true},
+		{"path": "test/versioned", "confirm": true},
+	} {
+		resp, err := convert(data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an invalid request for %v, err: %v, resp: %#v", data, err, resp)
+		}
+	}
+
+	resp, err := convert(map[string]interface{}{"path": "legacy", "preview": true})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("preview failed, err: %s, resp %#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["converted_keys"], []string{"legacy/a", "legacy/sub/b"}) ||
+		!reflect.DeepEqual(resp.Data["removed_keys"], []string{"legacy/deleted"}) ||
+		!reflect.DeepEqual(resp.Data["blocked_keys"], []string{"legacy/locked"}) {
+		t.Fatalf("unexpected preview: %#v", resp.Data)
+	}
+
+	// The locked key prevents the conversion
+	resp, err = convert(map[string]interface{}{"path": "legacy", "confirm": true})
+	if err != logical.ErrInvalidRequest || resp == nil || !strings.Contains(resp.Error().Error(), "legacy/locked") {
+		t.Fatalf("expected the locked key to prevent the conversion, err: %v, resp: %#v", err, resp)
+	}
+	request(logical.ReadOperation, "data/legacy/a", nil)
+
+	request(logical.UpdateOperation, "metadata/legacy/locked", map[string]interface{}{"locked": false})
+	resp, err = convert(map[string]interface{}{"path": "legacy/", "confirm": true})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("conversion failed, err: %s, resp %#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["converted_keys"], []string{"legacy/a", "legacy/locked", "legacy/sub/b"}) ||
+		!reflect.DeepEqual(resp.Data["removed_keys"], []string{"legacy/deleted"}) {
+		t.Fatalf("unexpected conversion: %#v", resp.Data)
+	}
+	if job := waitForJob(t, b, storage, resp.Data["job_id"].(string)); job["status"] != jobStatusCompleted {
+		t.Fatalf("unexpected job: %#v", job)
+	}
+
+	resp = request(logical.ReadOperation, "config", nil)
+	if got := resp.Data["v1_prefixes"].([]string); !reflect.DeepEqual(got, []string{"legacy/"}) {
+		t.Fatalf("unexpected v1_prefixes: %#v", got)
+	}
+
+	// Converted keys are served at their path as KV v1 entries
+	resp = request(logical.ReadOperation, "legacy/a", nil)
+	if !reflect.DeepEqual(resp.Data, map[string]interface{}{"foo": "baz"}) {
+		t.Fatalf("unexpected data: %#v", resp.Data)
+	}
+	if resp := request(logical.ReadOperation, "legacy/deleted", nil); resp != nil {
+		t.Fatalf("expected the deleted key to be removed: %#v", resp)
+	}
+	resp = request(logical.ListOperation, "legacy/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"a", "locked", "sub/"}) {
+		t.Fatalf("unexpected keys: %#v", resp.Data["keys"])
+	}
+
+	// and no longer through the versioned endpoints
+	for _, path := range []string{"data/legacy/a", "metadata/legacy/a", "subkeys/legacy/sub/b"} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+			Storage:   storage,
+		})
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected a request to %s to be rejected, err: %v, resp: %#v", path, err, resp)
+		}
+	}
+	if meta, err := kvb.getKeyMetadata(ctx, storage, "legacy/a"); err != nil || meta != nil {
+		t.Fatalf("expected the metadata to be removed, err: %v, meta: %#v", err, meta)
+	}
+
+	events.eventsProcessed = nil
+	request(logical.UpdateOperation, "legacy/new", map[string]interface{}{"x": "y"})
+	resp = request(logical.ReadOperation, "legacy/new", nil)
+	if !reflect.DeepEqual(resp.Data, map[string]interface{}{"x": "y"}) {
+		t.Fatalf("unexpected data: %#v", resp.Data)
+	}
+	request(logical.DeleteOperation, "legacy/a", nil)
+	if resp := request(logical.ReadOperation, "legacy/a", nil); resp != nil {
+		t.Fatalf("expected the key to be deleted: %#v", resp)
+	}
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v1/write", "legacy/new", "legacy/new"},
+		{"kv-v1/delete", "legacy/a", ""},
+	})
+
+	// Keys outside of the prefix are unchanged
+	resp = request(logical.ReadOperation, "data/other/c", nil)
+	if resp.Data["data"].(map[string]interface{})["foo"] != "c" {
+		t.Fatalf("unexpected data: %#v", resp.Data)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "other/c",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.Data[logical.HTTPStatusCode] != http.StatusNotFound {
+		t.Fatalf("expected keys outside of the prefix to not be served as KV v1, err: %v, resp: %#v", err, resp)
+	}
+
+	// A key left by an interrupted conversion is converted when requested
+	request(logical.UpdateOperation, "data/other/d", map[string]interface{}{"data": map[string]interface{}{"foo": "d"}})
+	config, err := kvb.config(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.V1Prefixes = append(config.V1Prefixes, "other/")
+	if err := kvb.writeConfig(ctx, storage, config); err != nil {
+		t.Fatal(err)
+	}
+	resp = request(logical.ReadOperation, "other/d", nil)
+	if !reflect.DeepEqual(resp.Data, map[string]interface{}{"foo": "d"}) {
+		t.Fatalf("unexpected data: %#v", resp.Data)
+	}
+	if meta, err := kvb.getKeyMetadata(ctx, storage, "other/d"); err != nil || meta != nil {
+		t.Fatalf("expected the metadata to be removed, err: %v, meta: %#v", err, meta)
+	}
+
+	// Converting the prefix again resumes the conversion
+	resp, err = convert(map[string]interface{}{"path": "other", "confirm": true})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("conversion failed, err: %s, resp %#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["converted_keys"], []string{"other/c"}) {
+		t.Fatalf("unexpected conversion: %#v", resp.Data)
+	}
+	resp = request(logical.ReadOperation, "config", nil)
+	if got := resp.Data["v1_prefixes"].([]string); !reflect.DeepEqual(got, []string{"legacy/", "other/"}) {
+		t.Fatalf("unexpected v1_prefixes: %#v", got)
+	}
+}
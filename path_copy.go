@@ -0,0 +1,311 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathsCopy returns the path configurations for the copy and move endpoints
+func pathsCopy(b *versionedKVBackend) []*framework.Path {
+	return []*framework.Path{
+		pathCopy(b, "copy", false, copyHelpSyn, copyHelpDesc),
+		pathCopy(b, "move", true, moveHelpSyn, moveHelpDesc),
+	}
+}
+
+func pathCopy(b *versionedKVBackend, operation string, move bool, helpSyn, helpDesc string) *framework.Path {
+	return &framework.Path{
+		Pattern: operation + "/" + matchAllNoTrailingSlashRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   operation,
+			OperationSuffix: "secret",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+			"destination": {
+				Type:        framework.TypeString,
+				Description: "The location of the new secret in the same mount. It must not exist, and it must fall under the same prefix settings of the config as the source.",
+				Required:    true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.mirrorWrites(b.pathCopyWrite(operation, move))),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"destination": {
+								Type:     framework.TypeString,
+								Required: true,
+							},
+							"current_version": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"copied_versions": {
+								Type:     framework.TypeInt,
+								Required: true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    helpSyn,
+		HelpDescription: helpDesc,
+	}
+}
+
+// pathCopyWrite copies a key with all of its versions to the destination,
+// deleting the source afterwards if move is set.
+func (b *versionedKVBackend) pathCopyWrite(operation string, move bool) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		src := data.Get("path").(string)
+		dst := data.Get("destination").(string)
+		switch {
+		case dst == "":
+			return logical.ErrorResponse("missing destination"), logical.ErrInvalidRequest
+		case strings.HasSuffix(dst, "/"):
+			return logical.ErrorResponse("destination must not end with a slash"), logical.ErrInvalidRequest
+		case dst == src:
+			return logical.ErrorResponse("destination must differ from the source"), logical.ErrInvalidRequest
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range []string{src, dst} {
+			if config.isHealthcheckPath(key) {
+				return logical.ErrorResponse("the healthcheck key %q cannot be copied or moved", key), logical.ErrInvalidRequest
+			}
+		}
+		// A copy must not lift the protection of the data, or change how it
+		// is handled, by moving it out from under a setting of the config
+		settings, err := configData(config)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range keyPrefixFields {
+			if !reflect.DeepEqual(keyPrefixSetting(settings, name, src), keyPrefixSetting(settings, name, dst)) {
+				return logical.ErrorResponse("the source and destination must fall under the same %s of the config", name), logical.ErrInvalidRequest
+			}
+		}
+		if move && config.approvalRequired(src) {
+			return logical.ErrorResponse("moving keys under the approval_required_prefixes is not supported, copy the key and delete its metadata instead"), logical.ErrInvalidRequest
+		}
+
+		// The locks are sorted, so copies between the same keys cannot
+		// deadlock
+		locks := locksutil.LocksForKeys(b.locks, []string{src, dst})
+		for _, lock := range locks {
+			lock.Lock()
+			defer lock.Unlock()
+		}
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, src)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			return logical.ErrorResponse("key %q does not exist", src), logical.ErrInvalidRequest
+		}
+		verb := "copying the key"
+		if move {
+			verb = "moving the key"
+		}
+		if !config.ownerPermitted(req, meta) {
+			return ownerDenied(verb)
+		}
+		if meta.isLocked() {
+			return lockedDenied(src, verb)
+		}
+
+		existing, err := b.getKeyMetadata(ctx, req.Storage, dst)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return logical.ErrorResponse("destination %q already exists", dst), logical.ErrInvalidRequest
+		}
+
+		dstMeta, copied, err := b.copyKey(ctx, req.Storage, config, meta, dst, move)
+		if err != nil {
+			return nil, err
+		}
+
+		if move {
+			if err := b.deleteKeyData(ctx, req.Storage, meta); err != nil {
+				return nil, err
+			}
+			if err := b.deleteChangelog(ctx, req.Storage, src); err != nil {
+				return nil, err
+			}
+		}
+		queueMirror(ctx, dst)
+
+		requestLogger(ctx, b.Logger()).Info("copied key", "operation", operation, "source", src, "destination", dst, "versions", copied)
+
+		b.emitKeyEvent(ctx, req.Storage, dstMeta, dstMeta.nextEventSequence(), operation, operation+"/"+src, "data/"+dst, true,
+			"source", src,
+			"destination", dst,
+		)
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"destination":     dst,
+				"current_version": dstMeta.CurrentVersion,
+				"copied_versions": copied,
+			},
+		}, nil
+	}
+}
+
+// copyKey writes the data of every stored version of the key under the
+// destination, including archived versions which are copied rehydrated, then
+// the key metadata and change records. Versions of the source due to be
+// destroyed by destroy_version_after are destroyed first, so that they are
+// copied as destroyed. A moved key keeps its ID, a copy is assigned a new
+// one. It returns the metadata of the destination and the number of versions
+// copied. If copying fails, the version data already written under the
+// destination is deleted.
+func (b *versionedKVBackend) copyKey(ctx context.Context, s logical.Storage, config *Configuration, meta *KeyMetadata, dst string, move bool) (_ *KeyMetadata, _ int, retErr error) {
+	if err := b.destroyDueVersions(ctx, s, config, meta, b.now()); err != nil {
+		return nil, 0, err
+	}
+
+	var versionsWritten []string
+	defer func() {
+		if retErr != nil {
+			b.deleteOrphanedVersions(ctx, s, dst, versionsWritten...)
+		}
+	}()
+
+	var copied int
+	for _, id := range meta.storedVersionIDs() {
+		raw, _, err := b.getVersionEntry(ctx, s, meta.Key, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		if raw == nil {
+			// The data of the version was destroyed
+			continue
+		}
+
+		version := &Version{}
+		if err := proto.Unmarshal(raw.Value, version); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode version data from storage: %w", err)
+		}
+		version.Key = dst
+
+		buf, err := proto.Marshal(version)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+			return nil, 0, err
+		}
+
+		versionKey, err := b.getVersionKey(ctx, dst, id, s)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := s.Put(ctx, &logical.StorageEntry{
+			Key:   versionKey,
+			Value: buf,
+		}); err != nil {
+			return nil, 0, err
+		}
+		versionsWritten = append(versionsWritten, versionKey)
+
+		copied++
+	}
+
+	dstMeta := proto.Clone(meta).(*KeyMetadata)
+	dstMeta.Key = dst
+	dstMeta.UpdatedTime = b.timestampNow()
+	dstMeta.MirroredVersion = 0
+	for _, vm := range dstMeta.Versions {
+		vm.Archived = false
+	}
+	if !move {
+		dstMeta.Id = ""
+	}
+
+	// The data of the destination is new to the storage usage, so that a
+	// copy is rejected like any other write in a storage emergency
+	dstMeta.TotalBytes = 0
+
+	if err := b.copyChangelog(ctx, s, meta.Key, dst); err != nil {
+		return nil, 0, err
+	}
+	if err := b.writeKeyMetadata(ctx, s, dstMeta); err != nil {
+		if err := b.deleteChangelog(ctx, s, dst); err != nil {
+			requestLogger(ctx, b.Logger()).Error("error deleting the change records of a failed copy", "key", dst, "error", err)
+		}
+		return nil, 0, err
+	}
+	if move && dstMeta.Id == meta.Id {
+		if err := b.putKeyID(ctx, s, dstMeta.Id, dst); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return dstMeta, copied, nil
+}
+
+const copyHelpSyn = `Copies a secret with all of its versions to a new path.`
+const copyHelpDesc = `
+This endpoint copies the metadata, custom_metadata and the data of every
+version of the secret to "destination", a new path in the same mount. Version
+numbers, creation and deletion times, the owner and the history of changes
+are kept, so the copy is indistinguishable from the original apart from its
+path. Versions whose data was destroyed, or that are due to be destroyed by
+destroy_version_after, are copied as destroyed, and archived versions are
+copied rehydrated. The copy is assigned a new ID, while a moved secret keeps
+its ID, so that it still resolves through by-id/.
+
+The destination must not exist. Both keys are locked for the duration of the
+copy. A copy event is sent for the destination once its metadata is written.
+
+As the destination is a parameter rather than part of the path, ACL policies
+cannot restrict it, so copying requires sudo, like exports. Copying a secret
+requires the same owner as deleting its metadata, and locked secrets cannot
+be copied. The source and destination must fall under the same settings of
+each config parameter that applies to keys by prefix, such as
+"read_event_prefixes", "wrap_required_prefixes" or "allowed_options", so
+that a copy cannot lift the protection of the data.
+`
+
+const moveHelpSyn = `Moves a secret with all of its versions to a new path.`
+const moveHelpDesc = `
+This endpoint copies the secret to "destination" like the copy endpoint, then
+permanently deletes the metadata and all versions of the source. The
+destination must not exist, and like copying, moving requires sudo and the
+destination must fall under the same prefix settings of the config as the
+source.
+
+Moving a secret requires the same owner as deleting its metadata. Secrets
+under the "approval_required_prefixes" config parameter cannot be moved;
+copy them and delete the metadata of the source through an approved request
+instead. A move event is sent for the destination.
+`
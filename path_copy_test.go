@@ -0,0 +1,282 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_CopyMove(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "1"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "2"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata/foo",
+			Data: map[string]interface{}{
+				"custom_metadata": map[string]interface{}{"team": "a"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "destroy/foo",
+			Data: map[string]interface{}{
+				"versions": []string{"1"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "copy/foo",
+			Data: map[string]interface{}{
+				"destination": "team/foo",
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	meta, err := kv.getKeyMetadata(ctx, storage, "team/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta == nil || meta.CurrentVersion != 2 || !meta.Versions[1].Destroyed || meta.CustomMetadata["team"] != "a" {
+		t.Fatalf("unexpected copied metadata: %#v", meta)
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/team/foo",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "2" {
+		t.Fatalf("unexpected copied data: %#v", resp.Data["data"])
+	}
+
+	// The destination must not exist
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "move/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"destination": "team/foo",
+		},
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected move over an existing key to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	req.Data["destination"] = "other/foo"
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("move UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["copied_versions"] != 1 {
+		t.Fatalf("expected only the undestroyed version to be copied, got %#v", resp.Data)
+	}
+
+	meta, err = kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil || meta != nil {
+		t.Fatalf("expected the source to be deleted, err: %s, meta %#v", err, meta)
+	}
+	versionKey, err := kv.getVersionKey(ctx, "foo", 2, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := storage.Get(ctx, versionKey); err != nil || entry != nil {
+		t.Fatalf("expected the source version data to be deleted, err: %s, entry %#v", err, entry)
+	}
+
+	// The history of changes moves with the key
+	records, err := kv.changeRecords(ctx, storage, "other/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var operations []string
+	for _, r := range records {
+		operations = append(operations, r.Operation)
+	}
+	if len(operations) != 5 || operations[0] != "data-write" || operations[4] != "move" {
+		t.Fatalf("unexpected change records: %v", operations)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/metadata-write", "metadata/foo", "metadata/foo"},
+		{"kv-v2/destroy", "destroy/foo", ""},
+		{"kv-v2/copy", "copy/foo", "data/team/foo"},
+		{"kv-v2/move", "move/foo", "data/other/foo"},
+	})
+}
+
+func TestVersionedKV_Copy_DestroyDue(t *testing.T) {
+	b, storage := getBackend(t)
+	writeDestroyDueVersion(t, b, storage, nil)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "copy/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"destination": "bar"},
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("copy request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["copied_versions"] != 1 {
+		t.Fatalf("expected only version 2 to be copied, got %v", resp.Data["copied_versions"])
+	}
+	expectVersionDestroyed(t, b, storage, "foo", 1)
+	expectVersionDestroyed(t, b, storage, "bar", 1)
+}
+
+func TestVersionedKV_Copy_PrefixSettings(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	for _, path := range []string{"copy/*", "move/*"} {
+		if !strutil.StrListContains(b.SpecialPaths().Root, path) {
+			t.Fatalf("expected %s to require sudo: %v", path, b.SpecialPaths().Root)
+		}
+	}
+
+	prefixes := []string{"protected/", "sensitive/"}
+	for field, value := range map[string]interface{}{
+		"read_event_prefixes":            prefixes,
+		"wrap_required_prefixes":         prefixes,
+		"unwrapped_read_denied_prefixes": prefixes,
+		"approval_required_prefixes":     prefixes,
+		"allowed_options":                map[string]interface{}{"protected/": "cas", "sensitive/": "cas"},
+		"event_sample_rates":             map[string]interface{}{"protected/": 0.5, "sensitive/": 0.5},
+		"concurrency_limits":             map[string]interface{}{"protected/": 10, "sensitive/": 10},
+	} {
+		t.Run(field, func(t *testing.T) {
+			data := map[string]interface{}{
+				"read_event_prefixes":            []string{},
+				"wrap_required_prefixes":         []string{},
+				"unwrapped_read_denied_prefixes": []string{},
+				"approval_required_prefixes":     []string{},
+				"allowed_options":                map[string]interface{}{},
+				"event_sample_rates":             map[string]interface{}{},
+				"concurrency_limits":             map[string]interface{}{},
+				"wrap_ttl":                       "1m",
+			}
+			data[field] = value
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "config",
+				Storage:   storage,
+				Data:      data,
+			})
+			if err != nil || (resp != nil && resp.IsError()) {
+				t.Fatalf("config write failed, err: %s, resp %#v", err, resp)
+			}
+
+			copyKey := func(operation, src, dst string) *logical.Response {
+				t.Helper()
+				resp, err := b.HandleRequest(ctx, &logical.Request{
+					Operation: logical.UpdateOperation,
+					Path:      operation + "/" + src,
+					Storage:   storage,
+					Data: map[string]interface{}{
+						"destination": dst,
+					},
+				})
+				if err != nil && err != logical.ErrInvalidRequest {
+					t.Fatalf("%s request failed, err: %s, resp %#v", operation, err, resp)
+				}
+				return resp
+			}
+
+			for _, tc := range []struct {
+				src, dst string
+				allowed  bool
+			}{
+				{"protected/" + field, "open/" + field, false},
+				{"open/" + field, "protected/" + field, false},
+				{"protected/" + field, "sensitive/" + field, true},
+				{"open/" + field, "other/" + field, true},
+			} {
+				if _, err := b.HandleRequest(ctx, &logical.Request{
+					Operation: logical.CreateOperation,
+					Path:      "data/" + tc.src,
+					Storage:   storage,
+					Data: map[string]interface{}{
+						"data": map[string]interface{}{"bar": "baz"},
+					},
+					WrapInfo: &logical.RequestWrapInfo{TTL: time.Minute},
+				}); err != nil {
+					t.Fatal(err)
+				}
+
+				resp := copyKey("copy", tc.src, tc.dst)
+				if allowed := resp != nil && !resp.IsError(); allowed != tc.allowed {
+					t.Fatalf("copy of %q to %q: expected allowed %t, resp %#v", tc.src, tc.dst, tc.allowed, resp)
+				}
+				if field == "approval_required_prefixes" {
+					continue
+				}
+				resp = copyKey("move", tc.src, tc.dst+"-moved")
+				if allowed := resp != nil && !resp.IsError(); allowed != tc.allowed {
+					t.Fatalf("move of %q to %q: expected allowed %t, resp %#v", tc.src, tc.dst, tc.allowed, resp)
+				}
+			}
+		})
+	}
+
+	// Keys under prefixes of a map with different settings cannot be
+	// copied between them either
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"event_sample_rates": map[string]interface{}{"protected/": 0.5, "sensitive/": 0.1},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config write failed, err: %s, resp %#v", err, resp)
+	}
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "copy/protected/event_sample_rates",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"destination": "sensitive/copy",
+		},
+	})
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected the copy to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
@@ -4,21 +4,26 @@
 package kv
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
 	"github.com/hashicorp/vault/sdk/logical"
-	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
 )
 
 func matchAllNoTrailingSlashRegex(name string) string {
@@ -52,6 +57,14 @@ func pathData(b *versionedKVBackend) *framework.Path {
 					Type:     framework.TypeMap,
 					Required: true,
 				},
+				"pruned_versions": {
+					Type:        framework.TypeSlice,
+					Description: "The versions removed from the metadata by max_versions as a result of the write.",
+				},
+				"consistency_token": {
+					Type:        framework.TypeString,
+					Description: "An opaque token for the written version that reads can pass to require it.",
+				},
 			},
 		}},
 	}
@@ -79,30 +92,90 @@ func pathData(b *versionedKVBackend) *framework.Path {
 Set the "cas" value to use a Check-And-Set operation. If not set the write will
 be allowed. If set to 0 a write will only be allowed if the key doesn’t exist.
 If the index is non-zero the write will only be allowed if the key’s current
-version matches the version specified in the cas parameter.`,
+version matches the version specified in the cas parameter.
+
+Set the "format" value to "yaml" to provide the data as a YAML string, which
+will be parsed and stored as a JSON document.
+
+Set the "delete_version_after" value to a duration to override the
+delete_version_after of the mount and the key for the new version only. A zero
+duration keeps the version until it is deleted. It cannot be greater than the
+max_delete_version_after of the mount.
+
+Set the "correlation_id" value to trace the write across systems. It is
+recorded in the version metadata and the changelog, and included in events and
+logs. If not set, the X-Correlation-Id request header is used, if passed through
+to the plugin.
+
+Set the "template" value on the first write of a key to create it from the
+named template of the templates/ path.
+
+Set the "array_ops" value on a patch to a list of operations on array fields,
+applied in order after the data is merged. Each operation has an "op" of
+"append" or "remove", with a "value" to append or to remove every occurrence
+of, or "remove_index" with an "index", and the "field" it applies to, with
+slashes separating the fields of nested objects.
+
+Unrecognized options are ignored with a warning, or rejected if the
+"strict_options" config parameter is set.`,
 			},
 			"data": {
 				Type:        framework.TypeMap,
 				Description: "The contents of the data map will be stored and returned on read.",
 			},
+			"format": {
+				Type:        framework.TypeString,
+				Description: `If set to "yaml" during a read, the data will be returned as a YAML string.`,
+			},
+			"metadata_only": {
+				Type:        framework.TypeBool,
+				Description: "If true during a read, only the version metadata will be returned and the data will not be loaded.",
+			},
+			"include_key_metadata": {
+				Type:        framework.TypeBool,
+				Description: "If true during a read, the metadata of the key, as returned by a metadata read, is returned in key_metadata.",
+			},
+			"offset": {
+				Type:        framework.TypeInt,
+				Description: "If provided during a read, the data is returned as a chunk of its base64 encoding starting at this offset.",
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Description: "If provided during a read, the data is returned as a chunk of its base64 encoding of at most this length. Defaults to 1048576 if offset is provided.",
+			},
+			"min_version": {
+				Type:        framework.TypeInt,
+				Description: "If provided during a read, the read fails with a 412 status if the current version of the secret is lower, such as on a replica that has not caught up yet.",
+			},
+			"consistency_token": {
+				Type:        framework.TypeString,
+				Description: "If provided during a read, the consistency_token returned by a write of the secret, which fails the read with a 412 status if the written version is not available yet.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase required to read the data of the secret, if one was set in its metadata.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
-				Callback: b.upgradeCheck(b.pathDataWrite()),
+				Callback: b.upgradeCheck(b.mirrorWrites(b.pathDataWrite())),
 				DisplayAttrs: &framework.DisplayAttributes{
 					OperationVerb: "write",
 				},
 				Responses: updateCreatePatchResponseSchema,
 			},
 			logical.CreateOperation: &framework.PathOperation{
-				Callback: b.upgradeCheck(b.pathDataWrite()),
+				Callback: b.upgradeCheck(b.mirrorWrites(b.pathDataWrite())),
 				DisplayAttrs: &framework.DisplayAttributes{
 					OperationVerb: "write",
 				},
 				Responses: updateCreatePatchResponseSchema,
 			},
 			logical.ReadOperation: &framework.PathOperation{
-				Callback: b.upgradeCheck(b.pathDataRead()),
+				Callback: b.upgradeCheck(b.recordVersionReads(b.pathDataRead())),
 				DisplayAttrs: &framework.DisplayAttributes{
 					OperationVerb: "read",
 				},
@@ -118,6 +191,26 @@ version matches the version specified in the cas parameter.`,
 								Type:     framework.TypeMap,
 								Required: true,
 							},
+							"key_metadata": {
+								Type:        framework.TypeMap,
+								Description: "The metadata of the key, if include_key_metadata was set.",
+								Required:    false,
+							},
+							"chunk": {
+								Type:        framework.TypeString,
+								Description: "The requested chunk of the base64 encoding of the data, if offset or limit was provided.",
+								Required:    false,
+							},
+							"total_length": {
+								Type:        framework.TypeInt,
+								Description: "The length of the base64 encoding of the data, if offset or limit was provided.",
+								Required:    false,
+							},
+							"next_offset": {
+								Type:        framework.TypeInt,
+								Description: "The offset of the next chunk, if the chunk does not reach the end of the data.",
+								Required:    false,
+							},
 						},
 					}},
 				},
@@ -134,7 +227,7 @@ version matches the version specified in the cas parameter.`,
 				},
 			},
 			logical.PatchOperation: &framework.PathOperation{
-				Callback: b.upgradeCheck(b.pathDataPatch()),
+				Callback: b.upgradeCheck(b.mirrorWrites(b.pathDataPatch())),
 				DisplayAttrs: &framework.DisplayAttributes{
 					OperationVerb: "patch",
 				},
@@ -149,6 +242,10 @@ version matches the version specified in the cas parameter.`,
 	}
 }
 
+// dataExistenceCheck reports whether a version of the key has been written, so
+// that the first write to a key requires the create capability and later
+// writes the update capability. Metadata written before the first version does
+// not make the key exist, while deleted and destroyed versions do.
 func (b *versionedKVBackend) dataExistenceCheck() framework.ExistenceFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
 		key := data.Get("path").(string)
@@ -165,15 +262,65 @@ func (b *versionedKVBackend) dataExistenceCheck() framework.ExistenceFunc {
 			return false, err
 		}
 
-		return meta != nil, nil
+		return meta != nil && meta.CurrentVersion > 0, nil
 	}
 }
 
 // pathDataRead handles read commands to a kv entry
 func (b *versionedKVBackend) pathDataRead() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		if b.metadataOnly {
+			return valueReadDenied()
+		}
+
 		key := data.Get("path").(string)
 
+		format := data.Get("format").(string)
+		if err := validateDataFormat(format); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		_, oOk := data.GetOk("offset")
+		_, lOk := data.GetOk("limit")
+		chunked := oOk || lOk
+		offset, limit := data.Get("offset").(int), data.Get("limit").(int)
+		switch {
+		case offset < 0 || limit < 0:
+			return logical.ErrorResponse("offset and limit cannot be negative"), logical.ErrInvalidRequest
+		case chunked && format == dataFormatYAML:
+			return logical.ErrorResponse("offset and limit cannot be used with the yaml format"), logical.ErrInvalidRequest
+		case chunked && limit == 0:
+			limit = defaultChunkLimit
+		}
+
+		minVersion, err := requiredMinVersion(key, data)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		wrapTTL, err := requiredWrapTTL(req, config, key)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+
+		// A version found due to be destroyed by destroy_version_after is
+		// destroyed after the read lock is released, as deferred calls run
+		// in reverse order.
+		var destroyDue bool
+		defer func() {
+			if !destroyDue {
+				return
+			}
+			if err := b.destroyDueVersionsOfKey(ctx, req.Storage, key, b.now()); err != nil && err != logical.ErrReadOnly {
+				requestLogger(ctx, b.Logger()).Warn("failed to destroy versions due to be destroyed", "key", key, "error", err)
+			}
+		}()
+
 		lock := locksutil.LockForKey(b.locks, key)
 		lock.RLock()
 		defer lock.RUnlock()
@@ -182,6 +329,9 @@ func (b *versionedKVBackend) pathDataRead() framework.OperationFunc {
 		if err != nil {
 			return nil, err
 		}
+		if minVersion > 0 && (meta == nil || meta.CurrentVersion < minVersion) {
+			return minVersionNotReached(config, req, key, minVersion)
+		}
 		if meta == nil {
 			return nil, nil
 		}
@@ -211,6 +361,14 @@ func (b *versionedKVBackend) pathDataRead() framework.OperationFunc {
 			},
 		}
 
+		if data.Get("include_key_metadata").(bool) {
+			keyMetadata, err := keyMetadataResponseData(config, meta)
+			if err != nil {
+				return nil, err
+			}
+			resp.Data["key_metadata"] = keyMetadata
+		}
+
 		// If the version has been deleted return metadata with a 404
 		if vm.DeletionTime != nil {
 			deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
@@ -218,7 +376,7 @@ func (b *versionedKVBackend) pathDataRead() framework.OperationFunc {
 				return nil, err
 			}
 
-			if deletionTime.Before(time.Now()) {
+			if deletionTime.Before(b.now()) {
 				return logical.RespondWithStatusCode(resp, req, http.StatusNotFound)
 			}
 		}
@@ -228,83 +386,220 @@ func (b *versionedKVBackend) pathDataRead() framework.OperationFunc {
 			return logical.RespondWithStatusCode(resp, req, http.StatusNotFound)
 		}
 
-		versionKey, err := b.getVersionKey(ctx, key, verNum, req.Storage)
-		if err != nil {
-			return nil, err
+		// Versions due to be destroyed are reported as destroyed
+		if versionDestroyDue(config, meta, vm, b.now()) {
+			destroyDue = true
+			resp.Data["metadata"].(map[string]interface{})["destroyed"] = true
+			return logical.RespondWithStatusCode(resp, req, http.StatusNotFound)
 		}
 
-		raw, err := req.Storage.Get(ctx, versionKey)
+		b.noteDeprecatedRead(ctx, req.Storage, meta, resp, "data/"+key)
+
+		// Avoid loading and decoding the version data if only the metadata
+		// was requested, as it can be large.
+		if data.Get("metadata_only").(bool) {
+			return resp, nil
+		}
+
+		if !meta.readPassphrasePermitted(data.Get("passphrase").(string)) {
+			return readPassphraseDenied()
+		}
+
+		version, err := b.getVersion(ctx, req.Storage, key, verNum)
 		if err != nil {
 			return nil, err
 		}
-		if raw == nil {
+		if version == nil {
 			return nil, errors.New("could not find version data")
 		}
 
-		version := &Version{}
-		if err := proto.Unmarshal(raw.Value, version); err != nil {
-			return nil, err
+		b.noteDataRead(ctx, req.Storage, config, "data/"+key, key, verNum)
+
+		if wrapTTL > 0 {
+			resp.WrapInfo = &wrapping.ResponseWrapInfo{
+				TTL: wrapTTL,
+			}
+		}
+
+		// Return a chunk of the stored data without decoding it, so that
+		// large values can be read in parts
+		if chunked {
+			chunk, total := base64Range(version.Data, offset, limit)
+			resp.Data["chunk"] = chunk
+			resp.Data["total_length"] = total
+			if next := offset + len(chunk); next < total {
+				resp.Data["next_offset"] = next
+			}
+			return resp, nil
 		}
 
-		vData := map[string]interface{}{}
-		if err := json.Unmarshal(version.Data, &vData); err != nil {
+		vData, err := b.versionData(version)
+		if err != nil {
 			return nil, err
 		}
 
 		resp.Data["data"] = vData
 
+		if format == dataFormatYAML {
+			dataYAML, err := yaml.Marshal(yamlValue(vData))
+			if err != nil {
+				return nil, err
+			}
+			resp.Data["data"] = string(dataYAML)
+		}
+
 		return resp, nil
 	}
 }
 
-// validateCheckAndSetOption will validate the cas flag from the options map
+// defaultChunkLimit is the length of the chunks of data returned by a read
+// with an offset but no limit.
+const defaultChunkLimit = 1 << 20
+
+// base64Range returns the characters in [offset, offset+limit) of the standard
+// base64 encoding of data, and the length of the whole encoding.
+func base64Range(data []byte, offset, limit int) (string, int) {
+	total := base64.StdEncoding.EncodedLen(len(data))
+	if offset >= total {
+		return "", total
+	}
+
+	end := offset + limit
+	if end > total || end < offset {
+		end = total
+	}
+
+	// Every 4 characters of the encoding encode 3 bytes of data, so only the
+	// bytes encoded by the requested characters need to be encoded.
+	first, last := offset/4, (end+3)/4
+	endByte := last * 3
+	if endByte > len(data) {
+		endByte = len(data)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data[first*3 : endByte])
+	return encoded[offset-first*4 : end-first*4], total
+}
+
+// validateCheckAndSetOption will validate the cas flag from the options
 // provided. The cas flag must be provided if required based on the engine's
 // config or the secret's key metadata. If provided, the cas value must match
 // the current version of the secret as denoted by its key metadata entry.
-func validateCheckAndSetOption(data *framework.FieldData, config *Configuration, meta *KeyMetadata) error {
-	var casRaw interface{}
-	var casOk bool
-	optionsRaw, ok := data.GetOk("options")
-	if ok {
-		options := optionsRaw.(map[string]interface{})
+func validateCheckAndSetOption(opts *dataOptions, config *Configuration, meta *KeyMetadata) error {
+	if opts.casSet {
+		if opts.cas != meta.CurrentVersion {
+			return errors.New("check-and-set parameter did not match the current version")
+		}
+	} else if config.CasRequired || meta.CasRequired {
+		return errors.New("check-and-set parameter required for this call")
+	}
+
+	return nil
+}
 
-		// Verify the CAS parameter is valid.
-		casRaw, casOk = options["cas"]
+// validateAllowedOptions will validate the options provided against the
+// allowed_options of the engine's config. The most specific prefix matching
+// the key determines which options are permitted. If no prefix matches the
+// key, all options are permitted.
+func validateAllowedOptions(opts *dataOptions, config *Configuration, key string) error {
+	if len(opts.raw) == 0 || len(config.AllowedOptions) == 0 {
+		return nil
 	}
 
-	if casOk {
-		var cas int
-		if err := mapstructure.WeakDecode(casRaw, &cas); err != nil {
-			return errors.New("error parsing check-and-set parameter")
+	var allowed *OptionList
+	var matched string
+	for prefix, list := range config.AllowedOptions {
+		if strings.HasPrefix(key, prefix) && (allowed == nil || len(prefix) > len(matched)) {
+			allowed, matched = list, prefix
 		}
-		if uint64(cas) != meta.CurrentVersion {
-			return errors.New("check-and-set parameter did not match the current version")
+	}
+	if allowed == nil {
+		return nil
+	}
+
+	for option := range opts.raw {
+		if !strutil.StrListContains(allowed.GetOptions(), option) {
+			return fmt.Errorf("option %q is not permitted for keys under prefix %q", option, matched)
 		}
-	} else if config.CasRequired || meta.CasRequired {
-		return errors.New("check-and-set parameter required for this call")
 	}
 
 	return nil
 }
 
+// requiredWrapTTL enforces the wrap_required_prefixes and
+// unwrapped_read_denied_prefixes of the engine's config for reads of secret
+// data. If the key is not under one of the prefixes, or the request is
+// already wrapped within the configured wrap_ttl, zero is returned. If the
+// request is not wrapped the configured wrap_ttl is returned so the response
+// can be wrapped with it, or an error if no wrap_ttl is configured or the
+// key is under one of the unwrapped_read_denied_prefixes.
+func requiredWrapTTL(req *logical.Request, config *Configuration, key string) (time.Duration, error) {
+	wrapped := req.WrapInfo != nil && req.WrapInfo.TTL > 0
+
+	var matched string
+	for _, prefix := range config.UnwrappedReadDeniedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			if !wrapped {
+				return 0, fmt.Errorf("reads of keys under prefix %q must be response-wrapped by the client, "+
+					"so that the secret data is not recorded in audit logs; request a wrap TTL to read the key", prefix)
+			}
+			matched = prefix
+			break
+		}
+	}
+	for _, prefix := range config.WrapRequiredPrefixes {
+		if matched != "" {
+			break
+		}
+		if strings.HasPrefix(key, prefix) {
+			matched = prefix
+		}
+	}
+	if matched == "" {
+		return 0, nil
+	}
+
+	wrapTTL := durationOrZero(config.GetWrapTtl())
+	if wrapped {
+		if wrapTTL > 0 && req.WrapInfo.TTL > wrapTTL {
+			return 0, fmt.Errorf("reads of keys under prefix %q must be wrapped with a TTL of at most %s", matched, wrapTTL)
+		}
+		return 0, nil
+	}
+
+	if wrapTTL == 0 {
+		return 0, fmt.Errorf("reads of keys under prefix %q must be response-wrapped", matched)
+	}
+
+	return wrapTTL, nil
+}
+
 // cleanupOldVersions is responsible for cleaning up old versions. Once a key
 // has more than the configured allowed versions the oldest version will be
 // permanently deleted. A list of version keys to delete will be created.
 // Indices will be ordered such that the oldest version is at the end of the
 // list. Deletes will be performed back-to-front. If there is an error deleting
 // one of the keys, the remaining keys will be deleted on the next go around.
-func (b *versionedKVBackend) cleanupOldVersions(ctx context.Context, storage logical.Storage, key string, versionToDelete uint64) string {
+//
+// Versions retained until their deletion time are skipped, and deleted once
+// that time has passed.
+func (b *versionedKVBackend) cleanupOldVersions(ctx context.Context, storage logical.Storage, key string, meta *KeyMetadata, versionToDelete uint64) string {
 	warningFormat := "error occurred when cleaning up old versions, these will be cleaned up on next write: %s"
 
-	var versionKeysToDelete []string
+	var versionsToDelete []uint64
 
 	for i := versionToDelete; i > 0; i-- {
-		versionKey, err := b.getVersionKey(ctx, key, i, storage)
-		if err != nil {
-			return fmt.Sprintf(warningFormat, err)
+		if _, ok := meta.RetainedVersions[i]; ok {
+			continue
 		}
 
-		v, err := storage.Get(ctx, versionKey)
+		// Versions whose data is referenced by newer versions are kept
+		// until the references are gone
+		if meta.dataReferenced(i) {
+			continue
+		}
+
+		v, _, err := b.getVersionEntry(ctx, storage, key, i)
 		if err != nil {
 			return fmt.Sprintf(warningFormat, err)
 		}
@@ -314,22 +609,55 @@ func (b *versionedKVBackend) cleanupOldVersions(ctx context.Context, storage log
 		}
 
 		// append to the end of the list
-		versionKeysToDelete = append(versionKeysToDelete, versionKey)
+		versionsToDelete = append(versionsToDelete, i)
 	}
 
 	// Walk the list backwards deleting the oldest versions first. This
 	// allows us to continue the cleanup on next write if an error
 	// occurs during one of the deletes.
-	for i := len(versionKeysToDelete) - 1; i >= 0; i-- {
-		err := storage.Delete(ctx, versionKeysToDelete[i])
+	for i := len(versionsToDelete) - 1; i >= 0; i-- {
+		err := b.deleteVersionData(ctx, storage, key, meta, versionsToDelete[i])
 		if err != nil {
 			return fmt.Sprintf(warningFormat, err)
 		}
 	}
 
+	if err := b.cleanupRetainedVersions(ctx, storage, key, meta, b.now()); err != nil {
+		return fmt.Sprintf(warningFormat, err)
+	}
+
 	return ""
 }
 
+// cleanupRetainedVersions deletes the data of retained versions whose
+// deletion time has passed and removes them from the key metadata.
+func (b *versionedKVBackend) cleanupRetainedVersions(ctx context.Context, storage logical.Storage, key string, meta *KeyMetadata, now time.Time) error {
+	var expired []uint64
+	for verNum, ts := range meta.RetainedVersions {
+		deletionTime, err := ptypes.Timestamp(ts)
+		if err != nil {
+			return err
+		}
+
+		if !deletionTime.After(now) {
+			expired = append(expired, verNum)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	for _, verNum := range expired {
+		delete(meta.RetainedVersions, verNum)
+
+		if err := b.deleteVersionData(ctx, storage, key, meta, verNum); err != nil {
+			return err
+		}
+	}
+
+	return b.writeKeyMetadata(ctx, storage, meta)
+}
+
 // pathDataWrite handles create and update commands to a kv entry
 func (b *versionedKVBackend) pathDataWrite() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -357,6 +685,21 @@ func (b *versionedKVBackend) pathDataWrite() framework.OperationFunc {
 			}
 		}
 
+		opts, err := parseDataOptions(data)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.checkNoArrayOps(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		optionsWarning, err := opts.checkUnknown(config)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.validateDeleteVersionAfter(config); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
 		lock := locksutil.LockForKey(b.locks, key)
 		lock.Lock()
 		defer lock.Unlock()
@@ -367,16 +710,83 @@ func (b *versionedKVBackend) pathDataWrite() framework.OperationFunc {
 		}
 		if meta == nil {
 			meta = &KeyMetadata{
-				Key:      key,
-				Versions: map[uint64]*VersionMetadata{},
+				Key:            key,
+				Versions:       map[uint64]*VersionMetadata{},
+				CreationSource: creationSourceAPI,
 			}
 		}
+		if meta.isLocked() {
+			return lockedDenied(key, "writing a new version")
+		}
+		if err := b.destroyDueVersions(ctx, req.Storage, config, meta, b.now()); err != nil {
+			return nil, err
+		}
 
-		err = validateCheckAndSetOption(data, config, meta)
+		err = validateAllowedOptions(opts, config, key)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+
+		err = validateCheckAndSetOption(opts, config, meta)
 		if err != nil {
 			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 		}
 
+		if opts.template != "" {
+			if meta.CurrentVersion != 0 {
+				return logical.ErrorResponse("the template option can only be set on the first data write of a key"), logical.ErrInvalidRequest
+			}
+
+			template, err := b.getTemplate(ctx, req.Storage, opts.template)
+			if err != nil {
+				return nil, err
+			}
+			if template == nil {
+				return logical.ErrorResponse("template %q does not exist", opts.template), logical.ErrInvalidRequest
+			}
+
+			instantiated, err := template.instantiate(data.Get("data").(map[string]interface{}))
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			marshaledData, err = json.Marshal(instantiated)
+			if err != nil {
+				return nil, err
+			}
+
+			meta.CustomMetadata = template.applyCustomMetadata(meta.CustomMetadata)
+			if err := validateCustomMetadata(meta.CustomMetadata); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			meta.Template = template.Name
+		}
+
+		// The healthcheck key only ever holds a single version, and writes
+		// of unchanged data do not create a new one.
+		maxVersions := config.MaxVersions
+		healthcheck := config.isHealthcheckPath(key)
+		if healthcheck {
+			vm, err := b.healthcheckUnchanged(ctx, req.Storage, meta, marshaledData)
+			if err != nil {
+				return nil, err
+			}
+			if vm != nil {
+				return &logical.Response{
+					Data: map[string]interface{}{
+						"version":           meta.CurrentVersion,
+						"created_time":      ptypesTimestampToString(vm.CreatedTime),
+						"deletion_time":     ptypesTimestampToString(vm.DeletionTime),
+						"destroyed":         vm.Destroyed,
+						"custom_metadata":   meta.CustomMetadata,
+						"consistency_token": consistencyToken(key, meta.CurrentVersion),
+					},
+				}, nil
+			}
+
+			meta.MaxVersions = 1
+			maxVersions = 1
+		}
+
 		// Create a version key for the new version
 		versionKey, err := b.getVersionKey(ctx, key, meta.CurrentVersion+1, req.Storage)
 		if err != nil {
@@ -384,7 +794,17 @@ func (b *versionedKVBackend) pathDataWrite() framework.OperationFunc {
 		}
 		version := &Version{
 			Data:        marshaledData,
-			CreatedTime: ptypes.TimestampNow(),
+			CreatedTime: b.timestampNow(),
+			Key:         key,
+			Version:     meta.CurrentVersion + 1,
+		}
+
+		// Reference an earlier version holding the same data instead of
+		// storing another copy, if enabled
+		hash := contentHash(marshaledData)
+		if shared := meta.sharedDataVersion(hash); shared != 0 && config.DeduplicateVersionData {
+			version.Data = nil
+			version.DataVersion = shared
 		}
 
 		ctime, err := ptypes.Timestamp(version.CreatedTime)
@@ -392,20 +812,35 @@ func (b *versionedKVBackend) pathDataWrite() framework.OperationFunc {
 			return logical.ErrorResponse("unexpected error converting %T(%v) to time.Time: %v", version.CreatedTime, version.CreatedTime, err), logical.ErrInvalidRequest
 		}
 
-		if !config.IsDeleteVersionAfterDisabled() {
-			if dtime, ok := deletionTime(ctime, deleteVersionAfter(config), deleteVersionAfter(meta)); ok {
-				dt, err := ptypes.TimestampProto(dtime)
-				if err != nil {
-					return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
-				}
-				version.DeletionTime = dt
+		if dtime, ok := versionDeletionTime(ctime, config, meta, opts.deleteVersionAfter); ok {
+			dt, err := ptypes.TimestampProto(dtime)
+			if err != nil {
+				return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
 			}
+			version.DeletionTime = dt
 		}
 
 		buf, err := proto.Marshal(version)
 		if err != nil {
 			return nil, err
 		}
+		if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+			return nil, err
+		}
+
+		// Add version to the key metadata and calculate version to delete
+		// based on the max_versions specified by either the secret's key
+		// metadata or the engine's config
+		vm, versionToDelete, pruned := meta.addVersionReportingPruned(meta.CurrentVersion+1, version.CreatedTime, version.DeletionTime, maxVersions, config.RetainPrunedVersions, b.now())
+		vm.ContentHash = hash
+		vm.DeleteVersionAfter = opts.deleteVersionAfter
+		vm.CorrelationId = opts.versionCorrelationID(ctx)
+		vm.DataVersion = version.DataVersion
+		vm.DataBytes = uint64(len(marshaledData))
+		if err := checkKeyBytes(config, meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		sequence := meta.nextEventSequence()
 
 		// Write the new version
 		if err := req.Storage.Put(ctx, &logical.StorageEntry{
@@ -415,41 +850,77 @@ func (b *versionedKVBackend) pathDataWrite() framework.OperationFunc {
 			return nil, err
 		}
 
-		// Add version to the key metadata and calculate version to delete
-		// based on the max_versions specified by either the secret's key
-		// metadata or the engine's config
-		vm, versionToDelete := meta.AddVersion(version.CreatedTime, version.DeletionTime, config.MaxVersions)
-
 		err = b.writeKeyMetadata(ctx, req.Storage, meta)
 		if err != nil {
+			b.deleteOrphanedVersions(ctx, req.Storage, meta.Key, versionKey)
 			return nil, err
 		}
+		queueMirror(ctx, key)
 
 		resp := &logical.Response{
 			Data: map[string]interface{}{
-				"version":         meta.CurrentVersion,
-				"created_time":    ptypesTimestampToString(vm.CreatedTime),
-				"deletion_time":   ptypesTimestampToString(vm.DeletionTime),
-				"destroyed":       vm.Destroyed,
-				"custom_metadata": meta.CustomMetadata,
+				"version":           meta.CurrentVersion,
+				"created_time":      ptypesTimestampToString(vm.CreatedTime),
+				"deletion_time":     ptypesTimestampToString(vm.DeletionTime),
+				"destroyed":         vm.Destroyed,
+				"custom_metadata":   meta.CustomMetadata,
+				"pruned_versions":   pruned,
+				"consistency_token": consistencyToken(key, meta.CurrentVersion),
 			},
 		}
 
-		warning := b.cleanupOldVersions(ctx, req.Storage, key, versionToDelete)
+		if optionsWarning != "" {
+			resp.AddWarning(optionsWarning)
+		}
+
+		warning := b.cleanupOldVersions(ctx, req.Storage, key, meta, versionToDelete)
 		if warning != "" {
 			// A failed attempt to clean up old versions will be retried on
 			// next write attempt, prefer a warning over an error resp
 			resp.AddWarning(warning)
 		}
 
-		kvEvent(ctx, b.Backend, "data-write", "data/"+key, "data/"+key, true, 2,
-			"current_version", fmt.Sprintf("%d", meta.CurrentVersion),
-			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
-		)
+		if !healthcheck {
+			b.emitKeyEvent(ctx, req.Storage, meta, sequence, "data-write", "data/"+key, "data/"+key, true,
+				"version", fmt.Sprintf("%d", meta.CurrentVersion),
+				"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+			)
+		}
 		return resp, nil
 	}
 }
 
+// healthcheckUnchanged returns the metadata of the current version of the
+// healthcheck key if that version is live and holds exactly the provided data.
+// Otherwise nil is returned and a new version should be written.
+func (b *versionedKVBackend) healthcheckUnchanged(ctx context.Context, s logical.Storage, meta *KeyMetadata, data []byte) (*VersionMetadata, error) {
+	vm := meta.Versions[meta.CurrentVersion]
+	if vm == nil || vm.Destroyed {
+		return nil, nil
+	}
+
+	if vm.DeletionTime != nil {
+		deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+		if err != nil {
+			return nil, err
+		}
+
+		if deletionTime.Before(b.now()) {
+			return nil, nil
+		}
+	}
+
+	version, err := b.getVersion(ctx, s, meta.Key, meta.CurrentVersion)
+	if err != nil {
+		return nil, err
+	}
+	if version == nil || !bytes.Equal(version.Data, data) {
+		return nil, nil
+	}
+
+	return vm, nil
+}
+
 // patchPreprocessor is passed to framework.HandlePatchOperation within the
 // pathDataPatch handler. The framework.HandlePatchOperation abstraction
 // expects only the resource data to be provided. The "data" key must be lifted
@@ -487,6 +958,14 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 			return logical.ErrorResponse("no data provided"), logical.ErrInvalidRequest
 		}
 
+		opts, err := parseDataOptions(data)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.checkNoTemplate(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
 		lock := locksutil.LockForKey(b.locks, key)
 		lock.Lock()
 		defer lock.Unlock()
@@ -499,13 +978,32 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 		if meta == nil {
 			return logical.RespondWithStatusCode(nil, req, http.StatusNotFound)
 		}
+		if meta.isLocked() {
+			return lockedDenied(key, "patching the data")
+		}
 
 		config, err := b.config(ctx, req.Storage)
 		if err != nil {
 			return nil, err
 		}
+		if err := b.destroyDueVersions(ctx, req.Storage, config, meta, b.now()); err != nil {
+			return nil, err
+		}
 
-		err = validateCheckAndSetOption(data, config, meta)
+		optionsWarning, err := opts.checkUnknown(config)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.validateDeleteVersionAfter(config); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		err = validateAllowedOptions(opts, config, key)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+
+		err = validateCheckAndSetOption(opts, config, meta)
 		if err != nil {
 			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 		}
@@ -537,7 +1035,7 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 				return nil, err
 			}
 
-			if deletionTime.Before(time.Now()) {
+			if deletionTime.Before(b.now()) {
 				return logical.RespondWithStatusCode(notFoundResp, req, http.StatusNotFound)
 			}
 		}
@@ -546,27 +1044,16 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 			return logical.RespondWithStatusCode(notFoundResp, req, http.StatusNotFound)
 		}
 
-		currentVersionKey, err := b.getVersionKey(ctx, key, currentVersion, req.Storage)
+		existingVersion, err := b.getVersion(ctx, req.Storage, key, currentVersion)
 		if err != nil {
 			return nil, err
 		}
-
-		raw, err := req.Storage.Get(ctx, currentVersionKey)
-		if err != nil {
-			return nil, err
-		}
-		if raw == nil {
+		if existingVersion == nil {
 			return nil, errors.New("could not find version data")
 		}
 
-		existingVersion := &Version{}
-
-		if err := proto.Unmarshal(raw.Value, existingVersion); err != nil {
-			return nil, err
-		}
-
-		var versionData map[string]interface{}
-		if err := json.Unmarshal(existingVersion.Data, &versionData); err != nil {
+		versionData, err := b.versionData(existingVersion)
+		if err != nil {
 			return nil, err
 		}
 
@@ -574,10 +1061,42 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 		if err != nil {
 			return nil, err
 		}
+		patchedBytes, err = applyArrayOps(patchedBytes, opts.arrayOps)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		maxVersions := config.MaxVersions
+		healthcheck := config.isHealthcheckPath(key)
+		if healthcheck {
+			if bytes.Equal(patchedBytes, existingVersion.Data) {
+				return &logical.Response{
+					Data: map[string]interface{}{
+						"version":           currentVersion,
+						"created_time":      ptypesTimestampToString(versionMetadata.CreatedTime),
+						"deletion_time":     ptypesTimestampToString(versionMetadata.DeletionTime),
+						"destroyed":         versionMetadata.Destroyed,
+						"custom_metadata":   meta.CustomMetadata,
+						"consistency_token": consistencyToken(key, currentVersion),
+					},
+				}, nil
+			}
+
+			meta.MaxVersions = 1
+			maxVersions = 1
+		}
 
 		newVersion := &Version{
 			Data:        patchedBytes,
-			CreatedTime: ptypes.TimestampNow(),
+			CreatedTime: b.timestampNow(),
+			Key:         key,
+			Version:     meta.CurrentVersion + 1,
+		}
+
+		hash := contentHash(patchedBytes)
+		if shared := meta.sharedDataVersion(hash); shared != 0 && config.DeduplicateVersionData {
+			newVersion.Data = nil
+			newVersion.DataVersion = shared
 		}
 
 		ctime, err := ptypes.Timestamp(newVersion.CreatedTime)
@@ -586,27 +1105,42 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 		}
 
 		// Set the deletion_time for the new version based on delete_version_after value if set
-		// on either the secret's key metadata or the engine's config
-		if !config.IsDeleteVersionAfterDisabled() {
-			if dtime, ok := deletionTime(ctime, deleteVersionAfter(config), deleteVersionAfter(meta)); ok {
-				dt, err := ptypes.TimestampProto(dtime)
-				if err != nil {
-					return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
-				}
-				newVersion.DeletionTime = dt
+		// in the write options, the secret's key metadata or the engine's config
+		if dtime, ok := versionDeletionTime(ctime, config, meta, opts.deleteVersionAfter); ok {
+			dt, err := ptypes.TimestampProto(dtime)
+			if err != nil {
+				return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
 			}
+			newVersion.DeletionTime = dt
 		}
 
 		buf, err := proto.Marshal(newVersion)
 		if err != nil {
 			return nil, err
 		}
+		if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+			return nil, err
+		}
 
 		newVersionKey, err := b.getVersionKey(ctx, key, meta.CurrentVersion+1, req.Storage)
 		if err != nil {
 			return nil, err
 		}
 
+		// Add version to the key metadata and calculate version to delete
+		// based on the max_versions specified by either the secret's key
+		// metadata or the engine's config
+		newVersionMetadata, versionToDelete, pruned := meta.addVersionReportingPruned(meta.CurrentVersion+1, newVersion.CreatedTime, newVersion.DeletionTime, maxVersions, config.RetainPrunedVersions, b.now())
+		newVersionMetadata.ContentHash = hash
+		newVersionMetadata.DeleteVersionAfter = opts.deleteVersionAfter
+		newVersionMetadata.CorrelationId = opts.versionCorrelationID(ctx)
+		newVersionMetadata.DataVersion = newVersion.DataVersion
+		newVersionMetadata.DataBytes = uint64(len(patchedBytes))
+		if err := checkKeyBytes(config, meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		sequence := meta.nextEventSequence()
+
 		if err := req.Storage.Put(ctx, &logical.StorageEntry{
 			Key:   newVersionKey,
 			Value: buf,
@@ -614,37 +1148,42 @@ func (b *versionedKVBackend) pathDataPatch() framework.OperationFunc {
 			return nil, err
 		}
 
-		// Add version to the key metadata and calculate version to delete
-		// based on the max_versions specified by either the secret's key
-		// metadata or the engine's config
-		newVersionMetadata, versionToDelete := meta.AddVersion(newVersion.CreatedTime, newVersion.DeletionTime, config.MaxVersions)
-
 		err = b.writeKeyMetadata(ctx, req.Storage, meta)
 		if err != nil {
+			b.deleteOrphanedVersions(ctx, req.Storage, meta.Key, newVersionKey)
 			return nil, err
 		}
+		queueMirror(ctx, key)
 
 		resp := &logical.Response{
 			Data: map[string]interface{}{
-				"version":         meta.CurrentVersion,
-				"created_time":    ptypesTimestampToString(newVersionMetadata.CreatedTime),
-				"deletion_time":   ptypesTimestampToString(newVersionMetadata.DeletionTime),
-				"destroyed":       newVersionMetadata.Destroyed,
-				"custom_metadata": meta.CustomMetadata,
+				"version":           meta.CurrentVersion,
+				"created_time":      ptypesTimestampToString(newVersionMetadata.CreatedTime),
+				"deletion_time":     ptypesTimestampToString(newVersionMetadata.DeletionTime),
+				"destroyed":         newVersionMetadata.Destroyed,
+				"custom_metadata":   meta.CustomMetadata,
+				"pruned_versions":   pruned,
+				"consistency_token": consistencyToken(key, meta.CurrentVersion),
 			},
 		}
 
-		warning := b.cleanupOldVersions(ctx, req.Storage, key, versionToDelete)
+		if optionsWarning != "" {
+			resp.AddWarning(optionsWarning)
+		}
+
+		warning := b.cleanupOldVersions(ctx, req.Storage, key, meta, versionToDelete)
 		if warning != "" {
 			// A failed attempt to clean up old versions will be retried on
 			// next patch attempt, prefer a warning over an error resp
 			resp.AddWarning(warning)
 		}
 
-		kvEvent(ctx, b.Backend, "data-patch", "data/"+key, "data/"+key, true, 2,
-			"current_version", fmt.Sprintf("%d", meta.CurrentVersion),
-			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
-		)
+		if !healthcheck {
+			b.emitKeyEvent(ctx, req.Storage, meta, sequence, "data-patch", "data/"+key, "data/"+key, true,
+				"version", fmt.Sprintf("%d", meta.CurrentVersion),
+				"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+			)
+		}
 		return resp, nil
 	}
 }
@@ -653,6 +1192,11 @@ func (b *versionedKVBackend) pathDataDelete() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		key := data.Get("path").(string)
 
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
 		lock := locksutil.LockForKey(b.locks, key)
 		lock.Lock()
 		defer lock.Unlock()
@@ -664,6 +1208,12 @@ func (b *versionedKVBackend) pathDataDelete() framework.OperationFunc {
 		if meta == nil {
 			return nil, nil
 		}
+		if !config.ownerPermitted(req, meta) {
+			return ownerDenied("deleting versions")
+		}
+		if meta.isLocked() {
+			return lockedDenied(key, "deleting versions")
+		}
 
 		// If there is no latest version, or the latest version is already
 		// deleted or destroyed return
@@ -678,20 +1228,21 @@ func (b *versionedKVBackend) pathDataDelete() framework.OperationFunc {
 				return nil, err
 			}
 
-			if deletionTime.Before(time.Now()) {
+			if deletionTime.Before(b.now()) {
 				return nil, nil
 			}
 		}
 
-		lv.DeletionTime = ptypes.TimestampNow()
+		lv.DeletionTime = b.timestampNow()
+		sequence := meta.nextEventSequence()
 
 		err = b.writeKeyMetadata(ctx, req.Storage, meta)
 		if err != nil {
 			return nil, err
 		}
 
-		kvEvent(ctx, b.Backend, "data-delete", "data/"+key, "", true, 2,
-			"current_version", fmt.Sprintf("%d", meta.CurrentVersion),
+		b.emitKeyEvent(ctx, req.Storage, meta, sequence, "data-delete", "data/"+key, "", true,
+			"version", fmt.Sprintf("%d", meta.CurrentVersion),
 			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
 		)
 		return nil, nil
@@ -702,6 +1253,14 @@ func (b *versionedKVBackend) pathDataDelete() framework.OperationFunc {
 // max versions. It returns the newly added version and the version to delete
 // from storage.
 func (k *KeyMetadata) AddVersion(createdTime, deletionTime *timestamp.Timestamp, configMaxVersions uint32) (*VersionMetadata, uint64) {
+	return k.addVersionAt(k.CurrentVersion+1, createdTime, deletionTime, configMaxVersions, false, time.Time{})
+}
+
+// addVersionAt adds a version with the provided version number to the key
+// metadata. The version number must be greater than the current version. If
+// retainPruned is true, pruned versions that have a deletion time after now
+// are recorded as retained, so their data is kept until that time.
+func (k *KeyMetadata) addVersionAt(version uint64, createdTime, deletionTime *timestamp.Timestamp, configMaxVersions uint32, retainPruned bool, now time.Time) (*VersionMetadata, uint64) {
 	if k.Versions == nil {
 		k.Versions = map[uint64]*VersionMetadata{}
 	}
@@ -711,7 +1270,7 @@ func (k *KeyMetadata) AddVersion(createdTime, deletionTime *timestamp.Timestamp,
 		DeletionTime: deletionTime,
 	}
 
-	k.CurrentVersion++
+	k.CurrentVersion = version
 	k.Versions[k.CurrentVersion] = vm
 	k.UpdatedTime = createdTime
 	if k.CreatedTime == nil {
@@ -731,6 +1290,9 @@ func (k *KeyMetadata) AddVersion(createdTime, deletionTime *timestamp.Timestamp,
 		// We need to do a loop here in the event that max versions has
 		// changed and we need to delete more than one entry.
 		for i := k.OldestVersion; i < versionToDelete+1; i++ {
+			if retainPruned {
+				k.retainVersion(i, now)
+			}
 			delete(k.Versions, i)
 		}
 
@@ -742,6 +1304,47 @@ func (k *KeyMetadata) AddVersion(createdTime, deletionTime *timestamp.Timestamp,
 	return vm, 0
 }
 
+// addVersionReportingPruned adds a version like addVersionAt, and also
+// returns the sorted versions that were removed from the metadata because
+// the key exceeded max_versions.
+func (k *KeyMetadata) addVersionReportingPruned(version uint64, createdTime, deletionTime *timestamp.Timestamp, configMaxVersions uint32, retainPruned bool, now time.Time) (*VersionMetadata, uint64, []uint64) {
+	previous := make([]uint64, 0, len(k.Versions))
+	for verNum := range k.Versions {
+		previous = append(previous, verNum)
+	}
+
+	vm, versionToDelete := k.addVersionAt(version, createdTime, deletionTime, configMaxVersions, retainPruned, now)
+
+	pruned := []uint64{}
+	for _, verNum := range previous {
+		if _, ok := k.Versions[verNum]; !ok {
+			pruned = append(pruned, verNum)
+		}
+	}
+	sort.Slice(pruned, func(i, j int) bool { return pruned[i] < pruned[j] })
+
+	return vm, versionToDelete, pruned
+}
+
+// retainVersion records the version as retained if it has not been destroyed
+// and its deletion time is after now.
+func (k *KeyMetadata) retainVersion(version uint64, now time.Time) {
+	vm := k.Versions[version]
+	if vm == nil || vm.Destroyed || vm.DeletionTime == nil {
+		return
+	}
+
+	deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+	if err != nil || !deletionTime.After(now) {
+		return
+	}
+
+	if k.RetainedVersions == nil {
+		k.RetainedVersions = map[uint64]*timestamp.Timestamp{}
+	}
+	k.RetainedVersions[version] = vm.DeletionTime
+}
+
 func max(a, b uint32) uint32 {
 	if b > a {
 		return b
@@ -759,16 +1362,47 @@ If a write operation is used the endpoint takes an options object and a data
 object. The options object is used to pass some options to the write command and
 the data object is encrypted and stored in the storage backend. Each write
 operation for a key creates a new version and does not overwrite the previous
-data.
+data. The response of writes and patches contains the "deletion_time" of the
+new version, computed from delete_version_after, and the "pruned_versions"
+removed from the metadata because the key exceeded max_versions.
 
 A patch operation must be performed on an existing secret. The secret must neither
 be deleted nor destroyed. Like a write operation, patch operations accept an
 options object and data object. The options object is used to pass some options to
 the patch command and the data object is used to perform a partial update on the
 current version of the secret and store the encrypted result in the storage backend. 
+As the data object replaces arrays wholesale, the "array_ops" option appends
+values to or removes values from array fields, such as lists of allowed IP
+addresses, without reading and writing the whole array. Appending to a missing
+field creates it, and removing a value the array does not contain leaves it
+unchanged.
+
+The first write of a key can set the "template" option to the name of a
+template of the templates/ path, to check the data against the fields of the
+template, generate its generated fields and add its custom metadata.
 
 A read operation will return the latest version for a key unless the "version"
-parameter is set, then it returns the version at that number.
+parameter is set, then it returns the version at that number. Set
+"include_key_metadata" to also return the metadata of the key, such as its
+custom_metadata, cas_required and max_versions, as "key_metadata", saving a
+separate metadata read. It is returned by any read permitted by the policy of
+the data path. Reads of the data of keys under the read_event_prefixes of the
+config send a kv-v2/data-read event. Reads are denied on mounts in
+metadata-only mode, whatever the policy of the token.
+
+The response of writes and patches also contains a "consistency_token" for
+the new version. Clients reading from performance replicas right after a write
+can pass it to reads in the "consistency_token" parameter, or the version in
+the "min_version" parameter: if the current version of the secret on the
+server is lower, such as when the replica has not caught up with the write
+yet, the read fails with a 412 status and should be retried.
+
+Large values can be read in chunks by setting the "offset" and "limit"
+parameters. The data is then returned as "chunk", the characters from offset
+to offset+limit of the base64 encoding of the JSON data, along with the
+"total_length" of the encoding and the "next_offset" to read the next chunk
+from, if any. Concatenating the chunks and decoding the result yields the JSON
+data.
 
 Delete operations are a soft delete. They will mark the latest version as
 deleted, but the underlying data will not be fully removed. Delete operations
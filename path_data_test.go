@@ -5,8 +5,10 @@ package kv
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"reflect"
 	"regexp"
 	"strings"
@@ -14,6 +16,7 @@ import (
 	"time"
 
 	"github.com/go-test/deep"
+	"github.com/golang/protobuf/ptypes"
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/helper/logging"
 	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
@@ -82,6 +85,16 @@ func expectedMetadataKeys() map[string]struct{} {
 	}
 }
 
+// expectedWriteResponseKeys returns the keys of the response of data writes
+// and patches, which add the pruned versions and the consistency token to the
+// version metadata.
+func expectedWriteResponseKeys() map[string]struct{} {
+	keys := expectedMetadataKeys()
+	keys["pruned_versions"] = struct{}{}
+	keys["consistency_token"] = struct{}{}
+	return keys
+}
+
 func TestVersionedKV_Data_Put(t *testing.T) {
 	b, storage, events := getBackendWithEvents(t)
 
@@ -130,7 +143,7 @@ func TestVersionedKV_Data_Put(t *testing.T) {
 		true,
 	)
 
-	if diff := deep.Equal(getKeySet(resp.Data), expectedMetadataKeys()); len(diff) > 0 {
+	if diff := deep.Equal(getKeySet(resp.Data), expectedWriteResponseKeys()); len(diff) > 0 {
 		t.Fatalf("metadata map keys mismatch, diff: %#v", diff)
 	}
 
@@ -169,7 +182,7 @@ func TestVersionedKV_Data_Put(t *testing.T) {
 		true,
 	)
 
-	if diff := deep.Equal(getKeySet(resp.Data), expectedMetadataKeys()); len(diff) > 0 {
+	if diff := deep.Equal(getKeySet(resp.Data), expectedWriteResponseKeys()); len(diff) > 0 {
 		t.Fatalf("metadata map keys mismatch, diff: %#v", diff)
 	}
 
@@ -237,6 +250,85 @@ func TestVersionedKV_Data_Put_ZeroCas(t *testing.T) {
 	}
 }
 
+func TestVersionedKV_Data_ExistenceCheck(t *testing.T) {
+	b, storage := getBackend(t)
+
+	exists := func() bool {
+		t.Helper()
+
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+		}
+
+		checkFound, exists, err := b.HandleExistenceCheck(context.Background(), req)
+		if err != nil || !checkFound {
+			t.Fatalf("existence check failed, found: %t, err: %s", checkFound, err)
+		}
+		return exists
+	}
+
+	if exists() {
+		t.Fatal("expected a new key not to exist")
+	}
+
+	// Configuring the metadata of a key before its first write does not
+	// make the first write an update.
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_versions": 2,
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if exists() {
+		t.Fatal("expected a key without versions not to exist")
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if !exists() {
+		t.Fatal("expected a written key to exist")
+	}
+
+	req = &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data DeleteOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if !exists() {
+		t.Fatal("expected a key with a deleted version to exist")
+	}
+}
+
 func TestVersionedKV_Data_Get(t *testing.T) {
 	b, storage := getBackend(t)
 
@@ -975,7 +1067,7 @@ func TestVersionedKV_Patch_Success(t *testing.T) {
 		true,
 	)
 
-	if diff := deep.Equal(getKeySet(resp.Data), expectedMetadataKeys()); len(diff) > 0 {
+	if diff := deep.Equal(getKeySet(resp.Data), expectedWriteResponseKeys()); len(diff) > 0 {
 		t.Fatalf("metadata map keys mismatch, diff: %#v", diff)
 	}
 
@@ -1015,7 +1107,7 @@ func TestVersionedKV_Patch_Success(t *testing.T) {
 		true,
 	)
 
-	if diff := deep.Equal(getKeySet(resp.Data), expectedMetadataKeys()); len(diff) > 0 {
+	if diff := deep.Equal(getKeySet(resp.Data), expectedWriteResponseKeys()); len(diff) > 0 {
 		t.Fatalf("metadata map keys mismatch, diff: %#v", diff)
 	}
 
@@ -1043,9 +1135,9 @@ func TestVersionedKV_Patch_Success(t *testing.T) {
 
 	expectedData := map[string]interface{}{
 		"bar": "baz",
-		"abc": float64(123),
+		"abc": json.Number("123"),
 		"quux": map[string]interface{}{
-			"def":  float64(456),
+			"def":  json.Number("456"),
 			"quuz": []interface{}{"1", "2", "3", "4"},
 		},
 	}
@@ -1328,3 +1420,996 @@ func TestRegex_AllNoTrailingSlash(t *testing.T) {
 		}
 	}
 }
+
+func TestVersionedKV_Data_Put_HealthcheckPath(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"healthcheck_path": "health/probe",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	write := func(value string) *logical.Response {
+		t.Helper()
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/health/probe",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"probe": value,
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+
+		return resp
+	}
+
+	if resp := write("a"); resp.Data["version"] != uint64(1) {
+		t.Fatalf("expected version to be 1, resp: %#v", resp)
+	}
+
+	// Unchanged data should not create a new version
+	if resp := write("a"); resp.Data["version"] != uint64(1) {
+		t.Fatalf("expected version to remain 1, resp: %#v", resp)
+	}
+
+	if resp := write("b"); resp.Data["version"] != uint64(2) {
+		t.Fatalf("expected version to be 2, resp: %#v", resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/health/probe",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if versions := resp.Data["versions"].(map[string]interface{}); len(versions) != 1 {
+		t.Fatalf("expected a single version to be retained, resp: %#v", resp)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", "config", "config"},
+	})
+}
+
+func TestVersionedKV_Data_Put_AllowedOptions(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"allowed_options": map[string]interface{}{
+				"apps/":            []interface{}{},
+				"apps/automation/": "cas",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"data/apps/foo", false},
+		{"data/apps/automation/foo", true},
+		{"data/other/foo", true},
+	}
+
+	for _, tc := range tests {
+		req = &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      tc.path,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": "baz",
+				},
+				"options": map[string]interface{}{
+					"cas": 0,
+				},
+			},
+		}
+
+		resp, err = b.HandleRequest(context.Background(), req)
+		if tc.allowed && (err != nil || resp.IsError()) {
+			t.Fatalf("expected write to %s to succeed, err: %s, resp %#v", tc.path, err, resp)
+		}
+		if !tc.allowed && err != logical.ErrPermissionDenied {
+			t.Fatalf("expected write to %s to be denied, err: %s, resp %#v", tc.path, err, resp)
+		}
+	}
+}
+
+func TestVersionedKV_Data_Put_UnknownOptions(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+			"options": map[string]interface{}{
+				"ca": 0,
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], `unrecognized options: ca`) {
+		t.Fatalf("expected an unrecognized option warning, got: %#v", resp.Warnings)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"strict_options": true,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+			"options": map[string]interface{}{
+				"ca": 1,
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected write with unknown option to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	req.Data["options"] = map[string]interface{}{
+		"cas": 1,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if len(resp.Warnings) != 0 {
+		t.Fatalf("expected no warnings, got: %#v", resp.Warnings)
+	}
+}
+
+func TestVersionedKV_Data_YAMLFormat(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": "bar: baz\nnested:\n  list:\n    - 1\n    - two\n",
+			"options": map[string]interface{}{
+				"format": "yaml",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	expected := map[string]interface{}{
+		"bar": "baz",
+		"nested": map[string]interface{}{
+			"list": []interface{}{json.Number("1"), "two"},
+		},
+	}
+	if diff := deep.Equal(resp.Data["data"], expected); len(diff) > 0 {
+		t.Fatalf("data response mismatch, diff: %#v", diff)
+	}
+
+	req.Data = map[string]interface{}{
+		"format": "yaml",
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	expectedYAML := "bar: baz\nnested:\n    list:\n        - 1\n        - two\n"
+	if resp.Data["data"] != expectedYAML {
+		t.Fatalf("expected YAML data %q, got %#v", expectedYAML, resp.Data["data"])
+	}
+
+	req.Data = map[string]interface{}{
+		"format": "xml",
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected read with unsupported format to fail, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": "- not\n- a mapping\n",
+			"options": map[string]interface{}{
+				"format": "yaml",
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected write of a YAML list to fail, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Data_NumberPrecision(t *testing.T) {
+	b, storage := getBackend(t)
+
+	// 2^53 + 1 is the smallest integer that cannot be represented as a
+	// float64, and the second value does not fit in an int64.
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"id": json.Number("9007199254740993"),
+				"nested": map[string]interface{}{
+					"ids": []interface{}{json.Number("12345678901234567891"), json.Number("1.5")},
+				},
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"other": json.Number("9007199254740995"),
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data PatchOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	expected := map[string]interface{}{
+		"id":    json.Number("9007199254740993"),
+		"other": json.Number("9007199254740995"),
+		"nested": map[string]interface{}{
+			"ids": []interface{}{json.Number("12345678901234567891"), json.Number("1.5")},
+		},
+	}
+	if diff := deep.Equal(resp.Data["data"], expected); len(diff) > 0 {
+		t.Fatalf("data response mismatch after patch, diff: %#v", diff)
+	}
+
+	req.Data = map[string]interface{}{
+		"format": "yaml",
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	expectedYAML := "id: 9007199254740993\nnested:\n    ids:\n        - 12345678901234567891\n        - 1.5\nother: 9007199254740995\n"
+	if resp.Data["data"] != expectedYAML {
+		t.Fatalf("expected YAML data %q, got %#v", expectedYAML, resp.Data["data"])
+	}
+
+	// Writing the YAML back preserves the numbers as well
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": expectedYAML,
+			"options": map[string]interface{}{
+				"format": "yaml",
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if diff := deep.Equal(resp.Data["data"], expected); len(diff) > 0 {
+		t.Fatalf("data response mismatch after YAML write, diff: %#v", diff)
+	}
+}
+
+func TestVersionedKV_Data_Put_RetainPrunedVersions(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_versions":           1,
+			"delete_version_after":   "1h",
+			"retain_pruned_versions": true,
+		},
+	}
+
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	write := func() {
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": "baz",
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data UpdateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		if len(resp.Warnings) > 0 {
+			t.Fatalf("unexpected warnings: %#v", resp.Warnings)
+		}
+	}
+
+	write()
+	write()
+
+	meta, err := kvb.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := meta.Versions[1]; ok {
+		t.Fatalf("expected version 1 to be pruned, versions: %#v", meta.Versions)
+	}
+	if _, ok := meta.RetainedVersions[1]; !ok {
+		t.Fatalf("expected version 1 to be retained, retained: %#v", meta.RetainedVersions)
+	}
+
+	version, err := kvb.getVersion(ctx, storage, "foo", 1)
+	if err != nil || version == nil {
+		t.Fatalf("expected retained version data to exist, err: %s", err)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"version": 1,
+		},
+	}
+
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp != nil {
+		t.Fatalf("expected retained version to not be readable, err: %s, resp %#v", err, resp)
+	}
+
+	// Once the deletion time has passed the data is removed on the next
+	// write
+	meta.RetainedVersions[1], _ = ptypes.TimestampProto(time.Now().Add(-time.Minute))
+	if err := kvb.writeKeyMetadata(ctx, storage, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	write()
+
+	meta, err = kvb.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := meta.RetainedVersions[1]; ok {
+		t.Fatalf("expected version 1 to no longer be retained, retained: %#v", meta.RetainedVersions)
+	}
+	if _, ok := meta.RetainedVersions[2]; !ok {
+		t.Fatalf("expected version 2 to be retained, retained: %#v", meta.RetainedVersions)
+	}
+
+	version, err = kvb.getVersion(ctx, storage, "foo", 1)
+	if err != nil || version != nil {
+		t.Fatalf("expected version 1 data to be deleted, err: %s, version: %#v", err, version)
+	}
+}
+
+func TestVersionedKV_Data_Get_MetadataOnly(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"metadata_only": true,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(
+		t,
+		schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+		resp,
+		true,
+	)
+
+	if resp.Data["data"] != nil {
+		t.Fatalf("expected no data, got: %#v", resp.Data["data"])
+	}
+	if resp.Data["metadata"].(map[string]interface{})["version"] != uint64(1) {
+		t.Fatalf("expected metadata for version 1, got: %#v", resp.Data["metadata"])
+	}
+}
+
+func TestVersionedKV_Data_Get_IncludeKeyMetadata(t *testing.T) {
+	b, storage := getBackend(t)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "metadata/foo",
+			Data: map[string]interface{}{
+				"max_versions":    5,
+				"cas_required":    true,
+				"custom_metadata": map[string]interface{}{"team": "db"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data":    map[string]interface{}{"bar": "baz"},
+				"options": map[string]interface{}{"cas": 0},
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if _, ok := resp.Data["key_metadata"]; ok {
+		t.Fatalf("expected no key_metadata unless requested, resp: %#v", resp.Data)
+	}
+
+	req.Data = map[string]interface{}{
+		"include_key_metadata": true,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(
+		t,
+		schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+		resp,
+		true,
+	)
+
+	keyMetadata := resp.Data["key_metadata"].(map[string]interface{})
+	if keyMetadata["max_versions"] != uint32(5) || keyMetadata["cas_required"] != true || keyMetadata["current_version"] != uint64(1) {
+		t.Fatalf("unexpected key_metadata: %#v", keyMetadata)
+	}
+	if !reflect.DeepEqual(keyMetadata["custom_metadata"], map[string]string{"team": "db"}) {
+		t.Fatalf("unexpected custom_metadata: %#v", keyMetadata["custom_metadata"])
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("expected the data, resp: %#v", resp.Data)
+	}
+}
+
+func TestVersionedKV_Data_Get_Chunked(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": strings.Repeat("baz", 100),
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	var encoded string
+	offset := 0
+	for i := 0; ; i++ {
+		if i > 100 {
+			t.Fatal("expected the chunks to reach the end of the data")
+		}
+
+		req = &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"offset": offset,
+				"limit":  50,
+			},
+		}
+
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		schema.ValidateResponse(
+			t,
+			schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+			resp,
+			true,
+		)
+
+		if resp.Data["data"] != nil {
+			t.Fatalf("expected no data, got: %#v", resp.Data["data"])
+		}
+
+		chunk := resp.Data["chunk"].(string)
+		if len(chunk) > 50 {
+			t.Fatalf("expected a chunk of at most 50 characters, got %d", len(chunk))
+		}
+		encoded += chunk
+
+		next, ok := resp.Data["next_offset"]
+		if !ok {
+			if len(encoded) != resp.Data["total_length"].(int) {
+				t.Fatalf("expected %d characters, got %d", resp.Data["total_length"], len(encoded))
+			}
+			break
+		}
+		offset = next.(int)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vData map[string]interface{}
+	if err := json.Unmarshal(decoded, &vData); err != nil {
+		t.Fatal(err)
+	}
+	if vData["bar"] != strings.Repeat("baz", 100) {
+		t.Fatalf("unexpected data: %#v", vData)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"limit":  50,
+			"format": "yaml",
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected chunked yaml read to fail, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestBase64Range(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	for offset := 0; offset <= len(encoded)+1; offset++ {
+		for limit := 1; limit <= len(encoded)+1; limit++ {
+			expected := ""
+			if offset < len(encoded) {
+				end := offset + limit
+				if end > len(encoded) {
+					end = len(encoded)
+				}
+				expected = encoded[offset:end]
+			}
+
+			chunk, total := base64Range(data, offset, limit)
+			if chunk != expected || total != len(encoded) {
+				t.Fatalf("offset %d, limit %d: expected %q (%d), got %q (%d)", offset, limit, expected, len(encoded), chunk, total)
+			}
+		}
+	}
+}
+
+func TestVersionedKV_Data_Get_WrapRequired(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"wrap_required_prefixes": "sensitive/",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	for _, path := range []string{"data/sensitive/foo", "data/other/foo"} {
+		req = &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": "baz",
+				},
+			},
+		}
+
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	read := func(path string, wrapTTL time.Duration) (*logical.Response, error) {
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+			Storage:   storage,
+		}
+		if wrapTTL > 0 {
+			req.WrapInfo = &logical.RequestWrapInfo{TTL: wrapTTL}
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	resp, err = read("data/other/foo", 0)
+	if err != nil || resp == nil || resp.IsError() || resp.WrapInfo != nil {
+		t.Fatalf("expected unwrapped read to succeed, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = read("data/sensitive/foo", 0)
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected unwrapped read to be denied, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = read("data/sensitive/foo", time.Hour)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("expected wrapped read to succeed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"wrap_ttl": "5m",
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = read("data/sensitive/foo", time.Hour)
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected read wrapped past wrap_ttl to be denied, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = read("data/sensitive/foo", 0)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("expected unwrapped read to succeed, err: %s, resp %#v", err, resp)
+	}
+	if resp.WrapInfo == nil || resp.WrapInfo.TTL != 5*time.Minute {
+		t.Fatalf("expected response to be wrapped with wrap_ttl, wrap info: %#v", resp.WrapInfo)
+	}
+}
+
+func TestVersionedKV_Data_Get_UnwrappedReadDenied(t *testing.T) {
+	b, storage := getBackend(t)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"unwrapped_read_denied_prefixes": "sensitive/",
+				"wrap_required_prefixes":         "other/",
+				"wrap_ttl":                       "5m",
+				"v1_data_enabled":                true,
+			},
+		},
+		{Operation: logical.CreateOperation, Path: "data/sensitive/foo", Data: map[string]interface{}{"data": map[string]interface{}{"bar": "baz"}}},
+		{Operation: logical.CreateOperation, Path: "data/other/foo", Data: map[string]interface{}{"data": map[string]interface{}{"bar": "baz"}}},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	read := func(path string, wrapTTL time.Duration) (*logical.Response, error) {
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+			Storage:   storage,
+		}
+		if wrapTTL > 0 {
+			req.WrapInfo = &logical.RequestWrapInfo{TTL: wrapTTL}
+		}
+		return b.HandleRequest(context.Background(), req)
+	}
+
+	// Unlike under the wrap_required_prefixes, unwrapped reads are not
+	// wrapped with wrap_ttl
+	for _, path := range []string{"data/sensitive/foo", "v1-data/sensitive/foo", "export/sensitive/foo"} {
+		resp, err := read(path, 0)
+		if err != logical.ErrPermissionDenied || resp == nil || !resp.IsError() {
+			t.Fatalf("expected unwrapped read of %s to be denied, err: %s, resp %#v", path, err, resp)
+		}
+	}
+	resp, err := read("data/other/foo", 0)
+	if err != nil || resp == nil || resp.WrapInfo == nil {
+		t.Fatalf("expected unwrapped read to be wrapped with wrap_ttl, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = read("data/sensitive/foo", time.Minute)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("expected wrapped read to succeed, err: %s, resp %#v", err, resp)
+	}
+	resp, err = read("data/sensitive/foo", time.Hour)
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected read wrapped past wrap_ttl to be denied, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Data_Get_Undecodable(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	// Simulate seal wrapped data read by a server without seal wrap support
+	versionKey, err := kvb.getVersionKey(ctx, "foo", 1, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(ctx, &logical.StorageEntry{
+		Key:   versionKey,
+		Value: []byte("\xff\xff\xff\xff"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(ctx, req)
+	codedErr, ok := err.(logical.HTTPCodedError)
+	if !ok || codedErr.Code() != http.StatusFailedDependency {
+		t.Fatalf("expected a failed dependency error, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("config ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["seal_wrap_mismatch"] != true {
+		t.Fatalf("expected seal_wrap_mismatch to be set, resp: %#v", resp)
+	}
+}
+
+func TestVersionedKV_Data_Put_PrunedVersions(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_versions":         2,
+			"delete_version_after": "1h",
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config request failed, err: %s, resp %#v", err, resp)
+	}
+
+	var pruned []interface{}
+	for i := 0; i < 3; i++ {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": fmt.Sprintf("%d", i)},
+			},
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		if resp.Data["deletion_time"] == "" {
+			t.Fatalf("expected the deletion_time of the new version, got: %#v", resp.Data)
+		}
+		pruned = append(pruned, resp.Data["pruned_versions"])
+	}
+
+	expected := []interface{}{[]uint64{}, []uint64{}, []uint64{1}}
+	if diff := deep.Equal(pruned, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	req = &logical.Request{
+		Operation: logical.PatchOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"baz": "qux"},
+		},
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data PatchOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if diff := deep.Equal(resp.Data["pruned_versions"], []uint64{2}); diff != nil {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// The limits of a single debug/generate request
+const (
+	maxGenerateKeys     = 10000
+	maxGenerateVersions = 100
+	maxGenerateSize     = 1024 * 1024
+)
+
+// pathDebugGenerate returns the path configuration for the endpoint writing
+// synthetic keys for load testing
+func pathDebugGenerate(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "debug/generate",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "generate",
+			OperationSuffix: "load",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"prefix": {
+				Type:        framework.TypeString,
+				Description: "The key prefix under which the keys are generated. None of the generated keys may exist.",
+				Required:    true,
+			},
+			"keys": {
+				Type:        framework.TypeInt,
+				Description: fmt.Sprintf("The number of keys to generate, at most %d.", maxGenerateKeys),
+				Default:     10,
+			},
+			"versions": {
+				Type:        framework.TypeInt,
+				Description: fmt.Sprintf("The number of versions to write to each key, at most %d.", maxGenerateVersions),
+				Default:     1,
+			},
+			"size": {
+				Type:        framework.TypeInt,
+				Description: fmt.Sprintf("The size in bytes of the random value stored in each version, at most %d.", maxGenerateSize),
+				Default:     64,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathDebugGenerateWrite()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"generated_keys": {
+								Type:        framework.TypeInt,
+								Description: "The number of keys generated.",
+								Required:    true,
+							},
+							"written_versions": {
+								Type:        framework.TypeInt,
+								Description: "The number of versions written.",
+								Required:    true,
+							},
+							"job_id": {
+								Type:        framework.TypeString,
+								Description: "The ID of the job that generated the keys, see the jobs path.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    debugGenerateHelpSyn,
+		HelpDescription: debugGenerateHelpDesc,
+	}
+}
+
+// pathDebugGenerateWrite writes the requested number of synthetic keys and
+// versions under the prefix, if enabled in the config.
+func (b *versionedKVBackend) pathDebugGenerateWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if !config.DebugGenerateEnabled {
+			return logical.ErrorResponse("the debug/generate path is not enabled on this mount"), logical.ErrUnsupportedPath
+		}
+
+		prefix := strings.Trim(data.Get("prefix").(string), "/")
+		numKeys := data.Get("keys").(int)
+		numVersions := data.Get("versions").(int)
+		size := data.Get("size").(int)
+		switch {
+		case prefix == "":
+			return logical.ErrorResponse("missing prefix"), logical.ErrInvalidRequest
+		case numKeys < 1 || numKeys > maxGenerateKeys:
+			return logical.ErrorResponse("keys must be between 1 and %d", maxGenerateKeys), logical.ErrInvalidRequest
+		case numVersions < 1 || numVersions > maxGenerateVersions:
+			return logical.ErrorResponse("versions must be between 1 and %d", maxGenerateVersions), logical.ErrInvalidRequest
+		case size < 0 || size > maxGenerateSize:
+			return logical.ErrorResponse("size must be between 0 and %d", maxGenerateSize), logical.ErrInvalidRequest
+		}
+
+		keys := make([]string, numKeys)
+		for i := range keys {
+			keys[i] = fmt.Sprintf("%s/key-%d", prefix, i)
+			if config.isHealthcheckPath(keys[i]) {
+				return logical.ErrorResponse("the healthcheck key %q cannot be generated", keys[i]), logical.ErrInvalidRequest
+			}
+
+			meta, err := b.getKeyMetadata(ctx, req.Storage, keys[i])
+			if err != nil {
+				return nil, err
+			}
+			if meta != nil {
+				return logical.ErrorResponse("key %q already exists", keys[i]), logical.ErrInvalidRequest
+			}
+		}
+
+		run, jobCtx, err := b.startJob(ctx, req.Storage, "debug-generate")
+		if err != nil {
+			return nil, err
+		}
+
+		var generated int
+		for _, key := range keys {
+			if err = jobCtx.Err(); err != nil {
+				break
+			}
+			if err = b.generateKey(jobCtx, run.s, config, key, numVersions, size); err != nil {
+				break
+			}
+
+			generated++
+			run.progress(uint64(generated), uint64(numKeys))
+		}
+		run.finish(err)
+
+		if generated > 0 {
+			requestLogger(ctx, b.Logger()).Info("generated synthetic keys", "prefix", prefix, "num_keys", generated, "versions", numVersions)
+			b.emitEvent(ctx, req.Storage, "debug-generate", "debug/generate", "", true,
+				"prefix", prefix,
+				"generated_keys", fmt.Sprintf("%d", generated),
+			)
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"generated_keys":   generated,
+				"written_versions": generated * numVersions,
+				"job_id":           run.job.Id,
+			},
+		}
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			resp.AddWarning("the job was canceled before all keys were generated")
+		case err != nil:
+			return nil, err
+		}
+
+		return resp, nil
+	}
+}
+
+// generateKey writes a new key with numVersions versions, each holding a
+// random value of size bytes. The versions are pruned according to the
+// max_versions of the mount like regular writes. If writing fails, the
+// version data already written is deleted.
+func (b *versionedKVBackend) generateKey(ctx context.Context, s logical.Storage, config *Configuration, key string, numVersions, size int) (retErr error) {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil {
+		return err
+	}
+	if meta != nil {
+		return fmt.Errorf("key %q already exists", key)
+	}
+
+	meta = &KeyMetadata{
+		Key:            key,
+		Versions:       map[uint64]*VersionMetadata{},
+		CreationSource: creationSourceGenerated,
+	}
+
+	var versionsWritten []string
+	defer func() {
+		if retErr != nil {
+			b.deleteOrphanedVersions(ctx, s, key, versionsWritten...)
+		}
+	}()
+
+	var versionToDelete uint64
+	for i := 0; i < numVersions; i++ {
+		value, err := randomValue(size)
+		if err != nil {
+			return err
+		}
+		marshaledData, err := json.Marshal(map[string]interface{}{"value": value})
+		if err != nil {
+			return err
+		}
+
+		version := &Version{
+			Data:        marshaledData,
+			CreatedTime: b.timestampNow(),
+			Key:         key,
+			Version:     meta.CurrentVersion + 1,
+		}
+
+		ctime, err := ptypes.Timestamp(version.CreatedTime)
+		if err != nil {
+			return err
+		}
+		if dtime, ok := versionDeletionTime(ctime, config, meta, nil); ok {
+			version.DeletionTime, err = ptypes.TimestampProto(dtime)
+			if err != nil {
+				return err
+			}
+		}
+
+		buf, err := proto.Marshal(version)
+		if err != nil {
+			return err
+		}
+		if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+			return err
+		}
+
+		versionKey, err := b.getVersionKey(ctx, key, version.Version, s)
+		if err != nil {
+			return err
+		}
+		if err := s.Put(ctx, &logical.StorageEntry{
+			Key:   versionKey,
+			Value: buf,
+		}); err != nil {
+			return err
+		}
+		versionsWritten = append(versionsWritten, versionKey)
+
+		var vm *VersionMetadata
+		vm, versionToDelete = meta.addVersionAt(version.Version, version.CreatedTime, version.DeletionTime, config.MaxVersions, config.RetainPrunedVersions, b.now())
+		vm.ContentHash = contentHash(marshaledData)
+		vm.DataBytes = uint64(len(marshaledData))
+	}
+
+	if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+		return err
+	}
+
+	if warning := b.cleanupOldVersions(ctx, s, key, meta, versionToDelete); warning != "" {
+		requestLogger(ctx, b.Logger()).Warn("failed to prune generated versions", "key", key, "warning", warning)
+	}
+
+	return nil
+}
+
+// randomValue returns a random hex string of size characters.
+func randomValue(size int) (string, error) {
+	buf := make([]byte, (size+1)/2)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf)[:size], nil
+}
+
+const debugGenerateHelpSyn = `Writes synthetic keys for load testing.`
+const debugGenerateHelpDesc = `
+This endpoint writes "keys" new keys under "prefix", named key-0, key-1 and so
+on, each with "versions" versions holding a random value of "size" bytes. It
+is meant for capacity testing and benchmarking a mount in a real deployment,
+without a client writing each version through the data endpoint.
+
+The endpoint is disabled unless the "debug_generate_enabled" config parameter
+is set to true, and requires sudo capability. None of the generated keys may
+exist; the request is rejected if any of them does. Generated keys have a
+creation_source of "generated" and are otherwise regular keys, pruned and
+deleted like any other key. Individual writes do not send events or record
+changelog entries; a single debug-generate event is sent for the request.
+
+The keys are generated by a job, listed under jobs/ while the request runs, so
+its progress can be followed and it can be canceled. Keys generated before a
+failure or cancellation are kept. The response contains the number of
+"generated_keys" and "written_versions", and the "job_id" of the job.
+`
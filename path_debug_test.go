@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Debug_Generate(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	generate := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "debug/generate",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"prefix":   "load/",
+			"keys":     3,
+			"versions": 4,
+			"size":     15,
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), generate)
+	if err != logical.ErrUnsupportedPath {
+		t.Fatalf("expected generate to be rejected while disabled, err: %s, resp %#v", err, resp)
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"debug_generate_enabled": true,
+			"max_versions":           2,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), generate)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("debug/generate UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["generated_keys"] != 3 || resp.Data["written_versions"] != 12 {
+		t.Fatalf("unexpected response: %#v", resp.Data)
+	}
+
+	meta, err := b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "load/key-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.CurrentVersion != 4 || meta.OldestVersion != 3 || meta.CreationSource != creationSourceGenerated {
+		t.Fatalf("unexpected generated key metadata: %#v", meta)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/load/key-2",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if value := resp.Data["data"].(map[string]interface{})["value"].(string); len(value) != 15 {
+		t.Fatalf("expected a value of 15 bytes, got %q", value)
+	}
+
+	// Existing keys are never overwritten
+	resp, err = b.HandleRequest(context.Background(), generate)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected generate over existing keys to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", "config", "config"},
+		{"kv-v2/debug-generate", "debug/generate", ""},
+	})
+}
@@ -8,7 +8,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/hashicorp/vault/sdk/framework"
@@ -34,8 +35,8 @@ func pathsDelete(b *versionedKVBackend) []*framework.Path {
 					Description: "Location of the secret.",
 				},
 				"versions": {
-					Type:        framework.TypeCommaIntSlice,
-					Description: "The versions to be archived. The versioned data will not be deleted, but it will no longer be returned in normal get requests.",
+					Type:        framework.TypeCommaStringSlice,
+					Description: `The versions to be archived. The versioned data will not be deleted, but it will no longer be returned in normal get requests. "latest" refers to the current version.`,
 				},
 			},
 
@@ -46,6 +47,15 @@ func pathsDelete(b *versionedKVBackend) []*framework.Path {
 						http.StatusNoContent: {{
 							Description: http.StatusText(http.StatusNoContent),
 						}},
+						http.StatusOK: {{
+							Description: http.StatusText(http.StatusOK),
+							Fields: map[string]*framework.FieldSchema{
+								"chunks": {
+									Type:        framework.TypeInt,
+									Description: "The number of chunks the versions were processed in, if more than one.",
+								},
+							},
+						}},
 					},
 				},
 			},
@@ -68,8 +78,12 @@ func pathsDelete(b *versionedKVBackend) []*framework.Path {
 					Description: "Location of the secret.",
 				},
 				"versions": {
-					Type:        framework.TypeCommaIntSlice,
-					Description: "The versions to unarchive. The versions will be restored and their data will be returned on normal get requests.",
+					Type:        framework.TypeCommaStringSlice,
+					Description: `The versions to unarchive. The versions will be restored and their data will be returned on normal get requests. "latest" refers to the current version.`,
+				},
+				"confirm": {
+					Type:        framework.TypeBool,
+					Description: "Confirms the undelete. Required for keys under the prefixes listed in the undelete_confirm_prefixes config parameter.",
 				},
 			},
 
@@ -80,6 +94,15 @@ func pathsDelete(b *versionedKVBackend) []*framework.Path {
 						http.StatusNoContent: {{
 							Description: http.StatusText(http.StatusNoContent),
 						}},
+						http.StatusOK: {{
+							Description: http.StatusText(http.StatusOK),
+							Fields: map[string]*framework.FieldSchema{
+								"chunks": {
+									Type:        framework.TypeInt,
+									Description: "The number of chunks the versions were processed in, if more than one.",
+								},
+							},
+						}},
 					},
 				},
 			},
@@ -90,13 +113,42 @@ func pathsDelete(b *versionedKVBackend) []*framework.Path {
 	}
 }
 
+// latestVersion is the value of the versions parameter that refers to the
+// current version of the key.
+const latestVersion = "latest"
+
+// parseVersions parses the versions parameter of the delete, undelete and
+// destroy endpoints. The returned bool reports whether "latest" was provided,
+// which callers resolve to the current version while holding the key lock.
+func parseVersions(data *framework.FieldData) ([]int, bool, error) {
+	var versions []int
+	var latest bool
+	for _, raw := range data.Get("versions").([]string) {
+		if raw == latestVersion {
+			latest = true
+			continue
+		}
+
+		verNum, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid version %q, must be a version number or %q", raw, latestVersion)
+		}
+		versions = append(versions, verNum)
+	}
+
+	return versions, latest, nil
+}
+
 // pathUndeleteWrite is used to undelete a set of versions
 func (b *versionedKVBackend) pathUndeleteWrite() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		key := data.Get("path").(string)
 
-		versions := data.Get("versions").([]int)
-		if len(versions) == 0 {
+		versions, latest, err := parseVersions(data)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if len(versions) == 0 && !latest {
 			return logical.ErrorResponse("No version number provided"), logical.ErrInvalidRequest
 		}
 
@@ -105,51 +157,71 @@ func (b *versionedKVBackend) pathUndeleteWrite() framework.OperationFunc {
 			return nil, err
 		}
 
-		lock := locksutil.LockForKey(b.locks, key)
-		lock.Lock()
-		defer lock.Unlock()
-
-		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
-		if err != nil {
-			return nil, err
-		}
-		if meta == nil {
-			return nil, nil
-		}
-
-		for _, verNum := range versions {
-			// If there is no version or the version is destroyed continue
-			lv := meta.Versions[uint64(verNum)]
-			if lv == nil || lv.Destroyed {
-				continue
-			}
-			lv.DeletionTime = nil
-
-			if !config.IsDeleteVersionAfterDisabled() {
-				if dtime, ok := deletionTime(time.Now(), deleteVersionAfter(config), deleteVersionAfter(meta)); ok {
-					dt, err := ptypes.TimestampProto(dtime)
-					if err != nil {
-						return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
-					}
-					lv.DeletionTime = dt
+		if !data.Get("confirm").(bool) {
+			for _, prefix := range config.UndeleteConfirmPrefixes {
+				if strings.HasPrefix(key, prefix) {
+					return logical.ErrorResponse("undelete of keys under prefix %q requires the confirm parameter to be set to true", prefix), logical.ErrInvalidRequest
 				}
 			}
 		}
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
-		if err != nil {
-			return nil, err
+
+		return processVersionChunks(ctx, config, versions, latest, func(versions []int, latest bool) (*logical.Response, error) {
+			return b.undeleteVersionChunk(ctx, req, config, key, versions, latest)
+		})
+	}
+}
+
+// undeleteVersionChunk undeletes a chunk of the versions of an undelete
+// request while holding the lock of the key.
+func (b *versionedKVBackend) undeleteVersionChunk(ctx context.Context, req *logical.Request, config *Configuration, key string, versions []int, latest bool) (*logical.Response, error) {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+	if meta.isLocked() {
+		return lockedDenied(key, "undeleting versions")
+	}
+	if latest {
+		versions = append(versions, int(meta.CurrentVersion))
+	}
+
+	for _, verNum := range versions {
+		// If there is no version or the version is destroyed continue
+		lv := meta.Versions[uint64(verNum)]
+		if lv == nil || lv.Destroyed {
+			continue
 		}
-		marshaledVersions, err := json.Marshal(&versions)
-		if err != nil {
-			return nil, err
+		lv.DeletionTime = nil
+
+		if dtime, ok := versionDeletionTime(b.now(), config, meta, lv.DeleteVersionAfter); ok {
+			dt, err := ptypes.TimestampProto(dtime)
+			if err != nil {
+				return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
+			}
+			lv.DeletionTime = dt
 		}
-		kvEvent(ctx, b.Backend, "undelete", "undelete/"+key, "data/"+key, true, 2,
-			"current_version", fmt.Sprintf("%d", meta.CurrentVersion),
-			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
-			"undeleted_versions", string(marshaledVersions),
-		)
-		return nil, nil
 	}
+	sequence := meta.nextEventSequence()
+	err = b.writeKeyMetadata(ctx, req.Storage, meta)
+	if err != nil {
+		return nil, err
+	}
+	marshaledVersions, err := json.Marshal(&versions)
+	if err != nil {
+		return nil, err
+	}
+	b.emitKeyEvent(ctx, req.Storage, meta, sequence, "undelete", "undelete/"+key, "data/"+key, true,
+		"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+		"undeleted_versions", string(marshaledVersions),
+	)
+	return nil, nil
 }
 
 // pathDeleteWrite is used to delete a set of versions.
@@ -157,60 +229,85 @@ func (b *versionedKVBackend) pathDeleteWrite() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		key := data.Get("path").(string)
 
-		versions := data.Get("versions").([]int)
-		if len(versions) == 0 {
+		versions, latest, err := parseVersions(data)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if len(versions) == 0 && !latest {
 			return logical.ErrorResponse("No version number provided"), logical.ErrInvalidRequest
 		}
 
-		lock := locksutil.LockForKey(b.locks, key)
-		lock.Lock()
-		defer lock.Unlock()
-
-		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		config, err := b.config(ctx, req.Storage)
 		if err != nil {
 			return nil, err
 		}
-		if meta == nil {
-			return nil, nil
-		}
 
-		for _, verNum := range versions {
-			// If there is no latest version, or the latest version is already
-			// deleted or destroyed continue
-			lv := meta.Versions[uint64(verNum)]
-			if lv == nil || lv.Destroyed {
-				continue
-			}
+		return processVersionChunks(ctx, config, versions, latest, func(versions []int, latest bool) (*logical.Response, error) {
+			return b.deleteVersionChunk(ctx, req, config, key, versions, latest)
+		})
+	}
+}
 
-			if lv.DeletionTime != nil {
-				deletionTime, err := ptypes.Timestamp(lv.DeletionTime)
-				if err != nil {
-					return nil, err
-				}
+// deleteVersionChunk deletes a chunk of the versions of a delete request
+// while holding the lock of the key.
+func (b *versionedKVBackend) deleteVersionChunk(ctx context.Context, req *logical.Request, config *Configuration, key string, versions []int, latest bool) (*logical.Response, error) {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
 
-				if deletionTime.Before(time.Now()) {
-					continue
-				}
-			}
+	meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+	if !config.ownerPermitted(req, meta) {
+		return ownerDenied("deleting versions")
+	}
+	if meta.isLocked() {
+		return lockedDenied(key, "deleting versions")
+	}
+	if latest {
+		versions = append(versions, int(meta.CurrentVersion))
+	}
 
-			lv.DeletionTime = ptypes.TimestampNow()
+	for _, verNum := range versions {
+		// If there is no latest version, or the latest version is already
+		// deleted or destroyed continue
+		lv := meta.Versions[uint64(verNum)]
+		if lv == nil || lv.Destroyed {
+			continue
 		}
 
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
-		if err != nil {
-			return nil, err
-		}
-		marshaledVersions, err := json.Marshal(&versions)
-		if err != nil {
-			return nil, err
+		if lv.DeletionTime != nil {
+			deletionTime, err := ptypes.Timestamp(lv.DeletionTime)
+			if err != nil {
+				return nil, err
+			}
+
+			if deletionTime.Before(b.now()) {
+				continue
+			}
 		}
-		kvEvent(ctx, b.Backend, "delete", "delete/"+key, "", true, 2,
-			"current_version", fmt.Sprintf("%d", meta.CurrentVersion),
-			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
-			"deleted_versions", string(marshaledVersions),
-		)
-		return nil, nil
+
+		lv.DeletionTime = b.timestampNow()
+	}
+
+	sequence := meta.nextEventSequence()
+	err = b.writeKeyMetadata(ctx, req.Storage, meta)
+	if err != nil {
+		return nil, err
 	}
+	marshaledVersions, err := json.Marshal(&versions)
+	if err != nil {
+		return nil, err
+	}
+	b.emitKeyEvent(ctx, req.Storage, meta, sequence, "delete", "delete/"+key, "", true,
+		"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+		"deleted_versions", string(marshaledVersions),
+	)
+	return nil, nil
 }
 
 const deleteHelpSyn = `Marks one or more versions as deleted in the KV store.`
@@ -218,10 +315,22 @@ const deleteHelpDesc = `
 Deletes the data for the provided version and path in the key-value store. The
 versioned data will not be fully removed, but marked as deleted and will no
 longer be returned in normal get requests. This operation can be undone.
+
+A request can name at most max_versions_per_request versions, 1000 by default.
+Requests naming more than 100 versions are processed in chunks of 100 versions,
+releasing the lock of the key between chunks, and return the number of
+"chunks". If a later chunk fails, the error names the versions processed by
+the chunks that completed.
 `
 
 const undeleteHelpSyn = `Undeletes one or more versions from the KV store.`
 const undeleteHelpDesc = `
 Undeletes the data for the provided version and path in the key-value store.
 This restores the data, allowing it to be returned on get requests.
+
+If the key is under one of the prefixes listed in the undelete_confirm_prefixes
+config parameter, the "confirm" parameter must be set to true.
+
+The limit on the number of versions and the chunked processing of large
+requests are the same as for delete.
 `
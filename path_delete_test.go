@@ -241,3 +241,150 @@ func TestVersionedKV_Undelete_Put(t *testing.T) {
 		{"kv-v2/undelete", "undelete/foo", "data/foo"},
 	})
 }
+
+func TestVersionedKV_Undelete_Confirm(t *testing.T) {
+	b, storage := getBackend(t)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"undelete_confirm_prefixes": "revoked/",
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/revoked/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "delete/revoked/foo",
+			Data: map[string]interface{}{
+				"versions": "1",
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "undelete/revoked/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"versions": "1",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected undelete without confirm to fail, err: %s, resp %#v", err, resp)
+	}
+
+	req.Data["confirm"] = true
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("undelete request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/revoked/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Delete_Latest(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for i := 0; i < 2; i++ {
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": "baz",
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data UpdateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	versionState := func(version uint64) (bool, bool) {
+		meta, err := b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		vm := meta.Versions[version]
+		return vm.DeletionTime != nil, vm.Destroyed
+	}
+
+	for _, path := range []string{"delete/foo", "undelete/foo", "destroy/foo"} {
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"versions": "latest",
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request failed, err: %s, resp %#v", path, err, resp)
+		}
+
+		deleted, destroyed := versionState(2)
+		switch path {
+		case "delete/foo":
+			if !deleted {
+				t.Fatal("expected version 2 to be deleted")
+			}
+		case "undelete/foo":
+			if deleted {
+				t.Fatal("expected version 2 to be undeleted")
+			}
+		case "destroy/foo":
+			if !destroyed {
+				t.Fatal("expected version 2 to be destroyed")
+			}
+		}
+
+		if deleted, destroyed := versionState(1); deleted || destroyed {
+			t.Fatalf("expected version 1 to be unchanged after %s", path)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "delete/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"versions": "newest",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid version to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
@@ -31,17 +31,26 @@ func pathDestroy(b *versionedKVBackend) *framework.Path {
 				Description: "Location of the secret.",
 			},
 			"versions": {
-				Type:        framework.TypeCommaIntSlice,
-				Description: "The versions to destroy. Their data will be permanently deleted.",
+				Type:        framework.TypeCommaStringSlice,
+				Description: `The versions to destroy. Their data will be permanently deleted. "latest" refers to the current version.`,
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
-				Callback: b.upgradeCheck(b.pathDestroyWrite()),
+				Callback: b.upgradeCheck(b.approvalCheck("destroy", b.pathDestroyWrite())),
 				Responses: map[int][]framework.Response{
 					http.StatusNoContent: {{
 						Description: http.StatusText(http.StatusNoContent),
 					}},
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"chunks": {
+								Type:        framework.TypeInt,
+								Description: "The number of chunks the versions were processed in, if more than one.",
+							},
+						},
+					}},
 				},
 			},
 		},
@@ -55,67 +64,94 @@ func (b *versionedKVBackend) pathDestroyWrite() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		key := data.Get("path").(string)
 
-		versions := data.Get("versions").([]int)
-		if len(versions) == 0 {
+		versions, latest, err := parseVersions(data)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if len(versions) == 0 && !latest {
 			return logical.ErrorResponse("no version number provided"), logical.ErrInvalidRequest
 		}
 
-		lock := locksutil.LockForKey(b.locks, key)
-		lock.Lock()
-		defer lock.Unlock()
-
-		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		config, err := b.config(ctx, req.Storage)
 		if err != nil {
 			return nil, err
 		}
-		if meta == nil {
-			return nil, nil
-		}
 
-		for _, verNum := range versions {
-			// If there is no version, or the version is already destroyed,
-			// continue
-			lv := meta.Versions[uint64(verNum)]
-			if lv == nil || lv.Destroyed {
-				continue
-			}
+		return processVersionChunks(ctx, config, versions, latest, func(versions []int, latest bool) (*logical.Response, error) {
+			return b.destroyVersionChunk(ctx, req, config, key, versions, latest)
+		})
+	}
+}
 
-			lv.Destroyed = true
-		}
+// destroyVersionChunk destroys a chunk of the versions of a destroy request
+// while holding the lock of the key.
+func (b *versionedKVBackend) destroyVersionChunk(ctx context.Context, req *logical.Request, config *Configuration, key string, versions []int, latest bool) (*logical.Response, error) {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
 
-		// Write the metadata key before deleting the versions
-		err = b.writeKeyMetadata(ctx, req.Storage, meta)
-		if err != nil {
-			return nil, err
-		}
+	meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, nil
+	}
+	if !config.ownerPermitted(req, meta) {
+		return ownerDenied("destroying versions")
+	}
+	if meta.isLocked() {
+		return lockedDenied(key, "destroying versions")
+	}
+	if latest {
+		versions = append(versions, int(meta.CurrentVersion))
+	}
 
-		for _, verNum := range versions {
-			// Delete versioned data
-			versionKey, err := b.getVersionKey(ctx, key, uint64(verNum), req.Storage)
-			if err != nil {
-				return nil, err
-			}
-
-			err = req.Storage.Delete(ctx, versionKey)
-			if err != nil {
-				return nil, err
-			}
+	for _, verNum := range versions {
+		// If there is no version, or the version is already destroyed,
+		// continue
+		lv := meta.Versions[uint64(verNum)]
+		if lv == nil || lv.Destroyed {
+			continue
 		}
-		marshaledVersions, err := json.Marshal(&versions)
+
+		lv.Destroyed = true
+	}
+
+	// Write the metadata key before deleting the versions
+	sequence := meta.nextEventSequence()
+	err = b.writeKeyMetadata(ctx, req.Storage, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, verNum := range versions {
+		// Delete versioned data
+		err = b.deleteVersionData(ctx, req.Storage, key, meta, uint64(verNum))
 		if err != nil {
 			return nil, err
 		}
-		kvEvent(ctx, b.Backend, "destroy", "destroy/"+key, "", true, 2,
-			"current_version", fmt.Sprintf("%d", meta.CurrentVersion),
-			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
-			"destroyed_versions", string(marshaledVersions),
-		)
-		return nil, nil
 	}
+	marshaledVersions, err := json.Marshal(&versions)
+	if err != nil {
+		return nil, err
+	}
+	b.emitKeyEvent(ctx, req.Storage, meta, sequence, "destroy", "destroy/"+key, "", true,
+		"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+		"destroyed_versions", string(marshaledVersions),
+	)
+	return nil, nil
 }
 
 const destroyHelpSyn = `Permanently removes one or more versions in the KV store`
 const destroyHelpDesc = `
 Permanently removes the specified version data for the provided key and version
 numbers from the key-value store.
+
+Under the approval_required_prefixes of the config, the request must be
+approved by a second token before it runs, see the approval path.
+
+A request can name at most max_versions_per_request versions. Like deletes,
+large requests are processed in chunks of 100 versions, each destroyed under a
+separate hold of the key lock with its own destroy event.
 `
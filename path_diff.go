@@ -0,0 +1,286 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathDiff returns the path configuration for the diff endpoint
+func pathDiff(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "diff/" + framework.MatchAllRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "read",
+			OperationSuffix: "diff",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+			"from": {
+				Type:        framework.TypeInt,
+				Description: "The version to compare from. Defaults to the version before the to version.",
+				Query:       true,
+			},
+			"to": {
+				Type:        framework.TypeInt,
+				Description: "The version to compare to. Defaults to the current version.",
+				Query:       true,
+			},
+			"include_values": {
+				Type:        framework.TypeBool,
+				Description: "If true, the values of the added, removed and changed keys are returned. Defaults to false.",
+				Query:       true,
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase required to read the data of the secret, if one was set in its metadata.",
+				Query:       true,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.recordVersionReads(b.pathDiffRead())),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"from": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"to": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"added": {
+								Type:        framework.TypeStringSlice,
+								Description: "The keys present in the to version only.",
+								Required:    true,
+							},
+							"removed": {
+								Type:        framework.TypeStringSlice,
+								Description: "The keys present in the from version only.",
+								Required:    true,
+							},
+							"changed": {
+								Type:        framework.TypeStringSlice,
+								Description: "The keys present in both versions with different values.",
+								Required:    true,
+							},
+							"values": {
+								Type:        framework.TypeMap,
+								Description: "The from and to values of each key that differs, if include_values is set.",
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    diffHelpSyn,
+		HelpDescription: diffHelpDesc,
+	}
+}
+
+// diffVersionData compares the top-level keys of the data of two versions,
+// returning the sorted keys only in to, only in from, and in both with
+// different values.
+func diffVersionData(from, to map[string]interface{}) (added, removed, changed []string) {
+	added, removed, changed = []string{}, []string{}, []string{}
+	for k, v := range to {
+		old, ok := from[k]
+		switch {
+		case !ok:
+			added = append(added, k)
+		case !reflect.DeepEqual(old, v):
+			changed = append(changed, k)
+		}
+	}
+	for k := range from {
+		if _, ok := to[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+// pathDiffRead handles read commands comparing two versions of a key
+func (b *versionedKVBackend) pathDiffRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := data.Get("path").(string)
+		includeValues := data.Get("include_values").(bool)
+		if includeValues && b.metadataOnly {
+			return valueReadDenied()
+		}
+
+		fromParam, toParam := data.Get("from").(int), data.Get("to").(int)
+		if fromParam < 0 || toParam < 0 {
+			return logical.ErrorResponse("from and to cannot be negative"), logical.ErrInvalidRequest
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		// Values are secret data, so they are subject to the same wrapping
+		// requirement as reads of the data
+		var wrapTTL time.Duration
+		if includeValues {
+			wrapTTL, err = requiredWrapTTL(req, config, key)
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+			}
+		}
+
+		// Versions found due to be destroyed by destroy_version_after are
+		// destroyed after the read lock is released, as in data reads.
+		var destroyDue bool
+		defer func() {
+			if !destroyDue {
+				return
+			}
+			if err := b.destroyDueVersionsOfKey(ctx, req.Storage, key, b.now()); err != nil && err != logical.ErrReadOnly {
+				requestLogger(ctx, b.Logger()).Warn("failed to destroy versions due to be destroyed", "key", key, "error", err)
+			}
+		}()
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.RLock()
+		defer lock.RUnlock()
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			return nil, nil
+		}
+
+		to := meta.CurrentVersion
+		if toParam > 0 {
+			to = uint64(toParam)
+		}
+		from := uint64(fromParam)
+		if from == 0 {
+			if to < 2 {
+				return logical.ErrorResponse("version %d has no earlier version to compare from", to), logical.ErrInvalidRequest
+			}
+			from = to - 1
+		}
+		if from == to {
+			return logical.ErrorResponse("from and to must be different versions"), logical.ErrInvalidRequest
+		}
+
+		if includeValues && !meta.readPassphrasePermitted(data.Get("passphrase").(string)) {
+			return readPassphraseDenied()
+		}
+
+		versions := make(map[uint64]map[string]interface{}, 2)
+		for _, verNum := range []uint64{from, to} {
+			vm := meta.Versions[verNum]
+			if vm == nil {
+				return logical.ErrorResponse("version %d does not exist", verNum), logical.ErrInvalidRequest
+			}
+			if versionDestroyDue(config, meta, vm, b.now()) {
+				destroyDue = true
+				return logical.ErrorResponse("version %d is deleted or destroyed", verNum), logical.ErrInvalidRequest
+			}
+			if vm.Destroyed || versionExpired(vm, b.now()) {
+				return logical.ErrorResponse("version %d is deleted or destroyed", verNum), logical.ErrInvalidRequest
+			}
+
+			version, err := b.getVersion(ctx, req.Storage, key, verNum)
+			if err != nil {
+				return nil, err
+			}
+			if version == nil {
+				return nil, errors.New("could not find version data")
+			}
+
+			versions[verNum], err = b.versionData(version)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		added, removed, changed := diffVersionData(versions[from], versions[to])
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"from":    from,
+				"to":      to,
+				"added":   added,
+				"removed": removed,
+				"changed": changed,
+			},
+		}
+
+		if includeValues {
+			for _, verNum := range []uint64{from, to} {
+				b.noteDataRead(ctx, req.Storage, config, "diff/"+key, key, verNum)
+			}
+
+			values := map[string]interface{}{}
+			for _, keys := range [][]string{added, removed, changed} {
+				for _, k := range keys {
+					value := map[string]interface{}{}
+					if v, ok := versions[from][k]; ok {
+						value["from"] = v
+					}
+					if v, ok := versions[to][k]; ok {
+						value["to"] = v
+					}
+					values[k] = value
+				}
+			}
+			resp.Data["values"] = values
+
+			if wrapTTL > 0 {
+				resp.WrapInfo = &wrapping.ResponseWrapInfo{
+					TTL: wrapTTL,
+				}
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+const diffHelpSyn = `Compare two versions of a secret in the KV store.`
+const diffHelpDesc = `
+This endpoint returns the top-level keys of the secret data that were added,
+removed or changed between the "from" and "to" versions, which default to the
+current version and the version before it. Both versions must not be deleted
+or destroyed. Values are compared in full, including nested values.
+
+Values are redacted unless "include_values" is true, in which case "values"
+maps each key that differs to its "from" and "to" values. Returning values is
+a read of the secret data: the "passphrase" of the secret is required if one
+is set, and the wrap_required_prefixes of the config apply. Use a policy with
+denied_parameters on include_values to allow comparing versions without
+reading them. On mounts in metadata-only mode, include_values is rejected.
+`
@@ -0,0 +1,123 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Diff(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for _, secret := range []map[string]interface{}{
+		{"user": "admin", "password": "old", "host": "db1"},
+		{"user": "admin", "password": "new", "port": 5432},
+	} {
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": secret,
+			},
+		}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data UpdateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "diff/foo",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("diff ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	expected := map[string]interface{}{
+		"from":    uint64(1),
+		"to":      uint64(2),
+		"added":   []string{"port"},
+		"removed": []string{"host"},
+		"changed": []string{"password"},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Fatalf("unexpected diff, expected %#v, got %#v", expected, resp.Data)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "diff/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"from":           2,
+			"to":             1,
+			"include_values": true,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("diff ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	expectedValues := map[string]interface{}{
+		"host":     map[string]interface{}{"to": "db1"},
+		"port":     map[string]interface{}{"from": json.Number("5432")},
+		"password": map[string]interface{}{"from": "new", "to": "old"},
+	}
+	if !reflect.DeepEqual(resp.Data["values"], expectedValues) {
+		t.Fatalf("unexpected values, expected %#v, got %#v", expectedValues, resp.Data["values"])
+	}
+
+	req = &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data DeleteOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{},
+		{"from": 1, "to": 3},
+		{"from": 1, "to": 1},
+	} {
+		req = &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "diff/foo",
+			Storage:   storage,
+			Data:      data,
+		}
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected diff with %v to be rejected, err: %s, resp %#v", data, err, resp)
+		}
+	}
+}
+
+func TestVersionedKV_Diff_DestroyDue(t *testing.T) {
+	b, storage := getBackend(t)
+	writeDestroyDueVersion(t, b, storage, nil)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "diff/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"include_values": true},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || resp.Data["values"] != nil {
+		t.Fatalf("expected the diff from a version due to be destroyed to fail, err: %v, resp %#v", err, resp)
+	}
+	expectVersionDestroyed(t, b, storage, "foo", 1)
+}
@@ -0,0 +1,231 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// The format of the bundles returned by the export endpoint. The format
+// version is increased on changes that the import endpoint of earlier
+// releases cannot read.
+const (
+	exportFormat        = "vault-kv-export"
+	exportFormatVersion = 1
+)
+
+// pathExport returns the path configuration for the export endpoint
+func pathExport(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "export/" + matchAllNoTrailingSlashRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "export",
+			OperationSuffix: "secret",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+			"passphrase": {
+				Type:        framework.TypeString,
+				Description: "The passphrase required to read the data of the secret, if one was set in its metadata.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.recordVersionReads(b.pathExportRead())),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"format": {
+								Type:        framework.TypeString,
+								Description: "The format of the bundle, always \"vault-kv-export\".",
+								Required:    true,
+							},
+							"format_version": {
+								Type:        framework.TypeInt,
+								Description: "The version of the format of the bundle.",
+								Required:    true,
+							},
+							"key": {
+								Type:     framework.TypeString,
+								Required: true,
+							},
+							"current_version": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"oldest_version": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"custom_metadata": {
+								Type:     framework.TypeMap,
+								Required: true,
+							},
+							"versions": {
+								Type:        framework.TypeSlice,
+								Description: "The versions of the secret in ascending order, as accepted by the import endpoint.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    exportHelpSyn,
+		HelpDescription: exportHelpDesc,
+	}
+}
+
+// pathExportRead returns every version of a key with its data and version
+// metadata, in the format accepted by the import endpoint.
+func (b *versionedKVBackend) pathExportRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		if b.metadataOnly {
+			return valueReadDenied()
+		}
+
+		key := data.Get("path").(string)
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		wrapTTL, err := requiredWrapTTL(req, config, key)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.RLock()
+		defer lock.RUnlock()
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			return nil, nil
+		}
+		if !meta.readPassphrasePermitted(data.Get("passphrase").(string)) {
+			return readPassphraseDenied()
+		}
+
+		verNums := make([]uint64, 0, len(meta.Versions))
+		for verNum := range meta.Versions {
+			verNums = append(verNums, verNum)
+		}
+		sort.Slice(verNums, func(i, j int) bool { return verNums[i] < verNums[j] })
+
+		now := b.now()
+		versions := make([]interface{}, 0, len(verNums))
+		for _, verNum := range verNums {
+			vm := meta.Versions[verNum]
+			v := map[string]interface{}{
+				"version":      verNum,
+				"created_time": ptypesTimestampToString(vm.CreatedTime),
+			}
+			if vm.DeletionTime != nil {
+				v["deletion_time"] = ptypesTimestampToString(vm.DeletionTime)
+			}
+
+			// Versions due to be destroyed are exported as destroyed
+			if vm.Destroyed || versionDestroyDue(config, meta, vm, now) {
+				v["destroyed"] = true
+				versions = append(versions, v)
+				continue
+			}
+
+			version, err := b.getVersion(ctx, req.Storage, key, verNum)
+			if err != nil {
+				return nil, err
+			}
+			if version == nil {
+				// The data of the version is missing, it cannot be imported
+				// with data
+				v["destroyed"] = true
+				versions = append(versions, v)
+				continue
+			}
+
+			vData, err := b.versionData(version)
+			if err != nil {
+				return nil, err
+			}
+			b.noteDataRead(ctx, req.Storage, config, "export/"+key, key, verNum)
+			v["data"] = vData
+			versions = append(versions, v)
+		}
+
+		customMetadata := meta.CustomMetadata
+		if customMetadata == nil {
+			customMetadata = map[string]string{}
+		}
+
+		requestLogger(ctx, b.Logger()).Info("exported key", "key", key, "versions", len(versions))
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"format":          exportFormat,
+				"format_version":  exportFormatVersion,
+				"key":             key,
+				"current_version": meta.CurrentVersion,
+				"oldest_version":  meta.OldestVersion,
+				"custom_metadata": customMetadata,
+				"versions":        versions,
+			},
+		}
+		if wrapTTL > 0 {
+			resp.WrapInfo = &wrapping.ResponseWrapInfo{
+				TTL: wrapTTL,
+			}
+		}
+
+		return resp, nil
+	}
+}
+
+const exportHelpSyn = `Exports a secret with its version history.`
+const exportHelpDesc = `
+This endpoint returns a bundle of the secret containing its custom_metadata
+and every version with its data, version number, creation and deletion times
+and destroyed flag. The bundle can be written as is to the import endpoint of
+another mount to migrate the secret, or stored to escrow it.
+
+The bundle is a JSON object with the fields:
+
+    format            Always "vault-kv-export".
+    format_version    The version of the format, currently 1.
+    key               The path of the exported secret.
+    current_version   The current version of the secret.
+    oldest_version    The oldest version of the secret.
+    custom_metadata   The custom_metadata of the secret.
+    versions          The versions of the secret in ascending order.
+
+Each version has the fields "version", "created_time" and, if set,
+"deletion_time". Versions whose data was destroyed, or is due to be
+destroyed, have "destroyed" set to true and no data; other versions have
+their "data", including deleted versions.
+
+This endpoint requires sudo capability, as it returns the data of every
+version of the secret, deleted or not. It is not available on mounts in
+metadata-only mode. If reading the data of the secret requires a passphrase,
+it must be provided in the "passphrase" parameter. Exports are subject to the
+same response wrapping requirements as reads of the data of the secret.
+`
@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Export(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	if !strutil.StrListContains(b.SpecialPaths().Root, "export/*") {
+		t.Fatalf("expected export to require sudo: %v", b.SpecialPaths().Root)
+	}
+
+	requests := []*logical.Request{
+		{Operation: logical.UpdateOperation, Path: "metadata/foo", Data: map[string]interface{}{"custom_metadata": map[string]interface{}{"team": "a"}}},
+		{Operation: logical.UpdateOperation, Path: "data/foo", Data: map[string]interface{}{"data": map[string]interface{}{"bar": "one"}}},
+		{Operation: logical.UpdateOperation, Path: "data/foo", Data: map[string]interface{}{"data": map[string]interface{}{"bar": "two"}}},
+		{Operation: logical.UpdateOperation, Path: "data/foo", Data: map[string]interface{}{"data": map[string]interface{}{"bar": "three"}}},
+		{Operation: logical.UpdateOperation, Path: "destroy/foo", Data: map[string]interface{}{"versions": []int{1}}},
+		{Operation: logical.UpdateOperation, Path: "delete/foo", Data: map[string]interface{}{"versions": []int{2}}},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "export/foo",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("export failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(t, schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation), resp, true)
+	if resp.Data["format"] != exportFormat || resp.Data["current_version"] != uint64(3) {
+		t.Fatalf("unexpected export: %#v", resp.Data)
+	}
+
+	versions := resp.Data["versions"].([]interface{})
+	if len(versions) != 3 {
+		t.Fatalf("unexpected versions: %#v", versions)
+	}
+	if v := versions[0].(map[string]interface{}); v["destroyed"] != true || v["data"] != nil {
+		t.Fatalf("expected version 1 to be exported as destroyed: %#v", v)
+	}
+	if v := versions[1].(map[string]interface{}); v["deletion_time"] == nil || v["data"] == nil {
+		t.Fatalf("expected version 2 to be exported deleted with its data: %#v", v)
+	}
+
+	// The bundle can be imported as is
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "import/copy",
+		Storage:   storage,
+		Data:      resp.Data,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("import failed, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "export/copy",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("export of the copy failed, err: %s, resp %#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["versions"], versions) {
+		t.Fatalf("expected the imported versions to match, got %#v, expected %#v", resp.Data["versions"], versions)
+	}
+	if !reflect.DeepEqual(resp.Data["custom_metadata"], map[string]string{"team": "a"}) {
+		t.Fatalf("unexpected custom_metadata: %#v", resp.Data["custom_metadata"])
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "export/missing",
+		Storage:   storage,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("expected no export of a missing key, err: %s, resp %#v", err, resp)
+	}
+}
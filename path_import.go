@@ -0,0 +1,349 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// importVersionSchema is the schema of each entry of the versions of an
+// import request.
+var importVersionSchema = map[string]*framework.FieldSchema{
+	"version":       {Type: framework.TypeInt},
+	"created_time":  {Type: framework.TypeTime},
+	"deletion_time": {Type: framework.TypeTime},
+	"destroyed":     {Type: framework.TypeBool},
+	"data":          {Type: framework.TypeMap},
+}
+
+// pathImport returns the path configuration for the import endpoint
+func pathImport(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "import/" + matchAllNoTrailingSlashRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "import",
+			OperationSuffix: "secret",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+			"versions": {
+				Type: framework.TypeSlice,
+				Description: `
+The versions of the secret, in ascending order of consecutive version numbers.
+Each version is a map with the "version" number, its "created_time" and
+optionally its "deletion_time", "destroyed" flag and the "data" of the
+version, which is required unless the version is destroyed.`,
+				Required: true,
+			},
+			"custom_metadata": {
+				Type: framework.TypeMap,
+				Description: `
+User-provided key-value pairs that are used to describe arbitrary and
+version-agnostic information about a secret.
+`,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.mirrorWrites(b.pathImportWrite())),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"current_version": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"oldest_version": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"imported_versions": {
+								Type:        framework.TypeInt,
+								Description: "The number of versions imported.",
+								Required:    true,
+							},
+							"custom_metadata": {
+								Type:     framework.TypeMap,
+								Required: true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    importHelpSyn,
+		HelpDescription: importHelpDesc,
+	}
+}
+
+// importVersion is a version of an import request.
+type importVersion struct {
+	version      uint64
+	createdTime  time.Time
+	deletionTime time.Time
+	destroyed    bool
+	data         []byte
+}
+
+// parseImportVersions validates the versions of an import request, which
+// must be consecutive and in ascending order.
+func parseImportVersions(raw []interface{}) ([]*importVersion, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("no versions provided")
+	}
+
+	versions := make([]*importVersion, 0, len(raw))
+	for i, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("version %d is not a map", i)
+		}
+
+		data := &framework.FieldData{
+			Raw:    m,
+			Schema: importVersionSchema,
+		}
+		if err := data.Validate(); err != nil {
+			return nil, fmt.Errorf("version %d: %w", i, err)
+		}
+
+		v := &importVersion{
+			destroyed: data.Get("destroyed").(bool),
+		}
+
+		verNum := data.Get("version").(int)
+		if verNum <= 0 {
+			return nil, fmt.Errorf("version %d: version must be a positive integer", i)
+		}
+		v.version = uint64(verNum)
+		if i > 0 && v.version != versions[i-1].version+1 {
+			return nil, fmt.Errorf("version %d: expected version %d, versions must be consecutive and in ascending order", i, versions[i-1].version+1)
+		}
+
+		ctRaw, ok := data.GetOk("created_time")
+		if !ok {
+			return nil, fmt.Errorf("version %d: missing created_time", i)
+		}
+		v.createdTime = ctRaw.(time.Time)
+		if dtRaw, ok := data.GetOk("deletion_time"); ok {
+			v.deletionTime = dtRaw.(time.Time)
+		}
+
+		dataRaw, ok := data.GetOk("data")
+		switch {
+		case v.destroyed && ok:
+			return nil, fmt.Errorf("version %d: a destroyed version cannot have data", i)
+		case !v.destroyed && !ok:
+			return nil, fmt.Errorf("version %d: no data provided", i)
+		case ok:
+			marshaledData, err := json.Marshal(dataRaw.(map[string]interface{}))
+			if err != nil {
+				return nil, err
+			}
+			v.data = marshaledData
+		}
+
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// pathImportWrite writes the versions of a key along with their version
+// numbers, creation and deletion times and destroyed flags. The key must not
+// have any versions.
+func (b *versionedKVBackend) pathImportWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := data.Get("path").(string)
+		if key == "" {
+			return logical.ErrorResponse("missing path"), logical.ErrInvalidRequest
+		}
+
+		versions, err := parseImportVersions(data.Get("versions").([]interface{}))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		customMetadataRaw, cmOk := data.GetOk("custom_metadata")
+		var customMetadataMap map[string]string
+		if cmOk {
+			customMetadataMap, err = parseCustomMetadata(customMetadataRaw.(map[string]interface{}), false)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("%s: %s", customMetadataValidationErrorPrefix, err.Error())), logical.ErrInvalidRequest
+			}
+
+			customMetadataErrs := validateCustomMetadata(customMetadataMap)
+			if customMetadataErrs != nil {
+				return logical.ErrorResponse(customMetadataErrs.Error()), logical.ErrInvalidRequest
+			}
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.Lock()
+		defer lock.Unlock()
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			meta = &KeyMetadata{
+				Key:            key,
+				Versions:       map[uint64]*VersionMetadata{},
+				CreationSource: creationSourceImport,
+			}
+		}
+		if meta.isLocked() {
+			return lockedDenied(key, "importing versions")
+		}
+		if meta.CurrentVersion != 0 {
+			return logical.ErrorResponse("key %q already has versions, only keys without versions can be imported", key), logical.ErrInvalidRequest
+		}
+
+		maxVersions := max(meta.MaxVersions, config.MaxVersions)
+		if maxVersions == 0 {
+			maxVersions = defaultMaxVersions
+		}
+		if len(versions) > int(maxVersions) {
+			return logical.ErrorResponse("cannot import %d versions, the key keeps at most %d versions", len(versions), maxVersions), logical.ErrInvalidRequest
+		}
+
+		if cmOk {
+			meta.CustomMetadata = customMetadataMap
+			meta.MetadataVersion++
+		}
+
+		// Every version is checked before any is written, so a rejected
+		// import leaves no version data behind.
+		meta.OldestVersion = versions[0].version - 1
+		entries := make([]*logical.StorageEntry, 0, len(versions))
+		for _, v := range versions {
+			ct, err := ptypes.TimestampProto(v.createdTime)
+			if err != nil {
+				return logical.ErrorResponse("error setting created_time of version %d: converting %v to protobuf: %v", v.version, v.createdTime, err), logical.ErrInvalidRequest
+			}
+			var dt *timestamp.Timestamp
+			if !v.deletionTime.IsZero() {
+				dt, err = ptypes.TimestampProto(v.deletionTime)
+				if err != nil {
+					return logical.ErrorResponse("error setting deletion_time of version %d: converting %v to protobuf: %v", v.version, v.deletionTime, err), logical.ErrInvalidRequest
+				}
+			}
+
+			vm, _ := meta.addVersionAt(v.version, ct, dt, config.MaxVersions, false, time.Time{})
+			if v.destroyed {
+				vm.Destroyed = true
+				continue
+			}
+			vm.DataBytes = uint64(len(v.data))
+
+			buf, err := proto.Marshal(&Version{
+				Data:         v.data,
+				CreatedTime:  ct,
+				DeletionTime: dt,
+				Key:          key,
+				Version:      v.version,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+				return nil, err
+			}
+
+			versionKey, err := b.getVersionKey(ctx, key, v.version, req.Storage)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, &logical.StorageEntry{
+				Key:   versionKey,
+				Value: buf,
+			})
+		}
+		if err := checkKeyBytes(config, meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if meta.CreatedTime == nil {
+			meta.CreatedTime = meta.Versions[versions[0].version].CreatedTime
+		}
+		sequence := meta.nextEventSequence()
+
+		var written []string
+		for _, entry := range entries {
+			if err := req.Storage.Put(ctx, entry); err != nil {
+				b.deleteOrphanedVersions(ctx, req.Storage, meta.Key, written...)
+				return nil, err
+			}
+			written = append(written, entry.Key)
+		}
+
+		if err := b.writeKeyMetadata(ctx, req.Storage, meta); err != nil {
+			b.deleteOrphanedVersions(ctx, req.Storage, meta.Key, written...)
+			return nil, err
+		}
+		queueMirror(ctx, key)
+
+		requestLogger(ctx, b.Logger()).Info("imported key", "key", key, "versions", len(versions))
+
+		b.emitKeyEvent(ctx, req.Storage, meta, sequence, "import", "import/"+key, "data/"+key, true,
+			"current_version", fmt.Sprintf("%d", meta.CurrentVersion),
+			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+		)
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"current_version":   meta.CurrentVersion,
+				"oldest_version":    meta.OldestVersion,
+				"imported_versions": len(versions),
+				"custom_metadata":   meta.CustomMetadata,
+			},
+		}, nil
+	}
+}
+
+const importHelpSyn = `Imports a secret with its version history.`
+const importHelpDesc = `
+This endpoint writes a secret exported from another KV mount or secret store
+along with its version history. The bundle returned by the export endpoint
+can be written as is. The "versions" parameter lists the versions
+of the secret in ascending order of consecutive version numbers; the version
+numbers, creation and deletion times and destroyed flags of each version are
+stored as provided, rather than renumbered from version 1. Destroyed versions
+have no data. The "custom_metadata" parameter replaces the custom metadata of
+the secret.
+
+Only secrets without versions can be imported, but their metadata may be
+written beforehand, for example to set "max_versions". The number of
+versions cannot be greater than the max versions of the secret. The versions
+are checked before any is written, and the metadata of the secret is written
+last, so a failed import does not leave a partially imported secret.
+
+Access to this endpoint should be restricted to privileged operators, as it
+allows writing the history of a secret.
+`
@@ -0,0 +1,132 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Import(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "import/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"versions": []interface{}{
+				map[string]interface{}{
+					"version":      3,
+					"created_time": "2020-01-01T00:00:00Z",
+					"destroyed":    true,
+				},
+				map[string]interface{}{
+					"version":       4,
+					"created_time":  "2020-02-01T00:00:00Z",
+					"deletion_time": "2020-03-01T00:00:00Z",
+					"data":          map[string]interface{}{"bar": "four"},
+				},
+				map[string]interface{}{
+					"version":      5,
+					"created_time": "2020-03-01T00:00:00Z",
+					"data":         map[string]interface{}{"bar": "five"},
+				},
+			},
+			"custom_metadata": map[string]interface{}{"source": "legacy"},
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("import failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(t, schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation), resp, true)
+	if resp.Data["current_version"] != uint64(5) || resp.Data["oldest_version"] != uint64(2) || resp.Data["imported_versions"] != 3 {
+		t.Fatalf("unexpected response: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata read failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["creation_source"] != creationSourceImport || resp.Data["created_time"].(string) != "2020-01-01T00:00:00Z" {
+		t.Fatalf("unexpected metadata: %#v", resp.Data)
+	}
+	if !reflect.DeepEqual(resp.Data["custom_metadata"], map[string]string{"source": "legacy"}) {
+		t.Fatalf("unexpected custom_metadata: %#v", resp.Data["custom_metadata"])
+	}
+	versions := resp.Data["versions"].(map[string]interface{})
+	if v := versions["3"].(map[string]interface{}); v["destroyed"] != true {
+		t.Fatalf("expected version 3 to be destroyed: %#v", v)
+	}
+	if v := versions["4"].(map[string]interface{}); v["deletion_time"] != "2020-03-01T00:00:00Z" {
+		t.Fatalf("unexpected deletion_time of version 4: %#v", v)
+	}
+
+	for version, expected := range map[int]interface{}{4: nil, 5: "five"} {
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data:      map[string]interface{}{"version": version},
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("read of version %d failed, err: %s, resp %#v", version, err, resp)
+		}
+		var value interface{}
+		if data, ok := resp.Data["data"].(map[string]interface{}); ok {
+			value = data["bar"]
+		}
+		if value != expected {
+			t.Fatalf("unexpected data of version %d: %#v", version, resp.Data)
+		}
+	}
+
+	// Only keys without versions can be imported
+	resp, err = b.HandleRequest(ctx, req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the import of an existing key to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	for _, versions := range [][]interface{}{
+		{},
+		// Not consecutive
+		{
+			map[string]interface{}{"version": 1, "created_time": "2020-01-01T00:00:00Z", "data": map[string]interface{}{}},
+			map[string]interface{}{"version": 3, "created_time": "2020-01-01T00:00:00Z", "data": map[string]interface{}{}},
+		},
+		// Missing data
+		{map[string]interface{}{"version": 1, "created_time": "2020-01-01T00:00:00Z"}},
+		// Missing created_time
+		{map[string]interface{}{"version": 1, "data": map[string]interface{}{}}},
+	} {
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "import/bar",
+			Storage:   storage,
+			Data:      map[string]interface{}{"versions": versions},
+		})
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected the import of %v to be rejected, err: %s, resp %#v", versions, err, resp)
+		}
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/bar",
+		Storage:   storage,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("expected the rejected imports not to create the key, err: %s, resp %#v", err, resp)
+	}
+}
@@ -0,0 +1,446 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// jobPrefix is the prefix where the state of jobs is stored.
+	jobPrefix string = "jobs/"
+
+	// jobSaveInterval is how often the progress of a running job is saved.
+	jobSaveInterval = 5 * time.Second
+
+	// jobStaleAfter is how long after its state was last saved a job running
+	// on another instance is considered interrupted.
+	jobStaleAfter = 3 * jobSaveInterval
+)
+
+// The values of Job.Status
+const (
+	jobStatusRunning     = "running"
+	jobStatusCompleted   = "completed"
+	jobStatusFailed      = "failed"
+	jobStatusCanceled    = "canceled"
+	jobStatusInterrupted = "interrupted"
+)
+
+// jobRun tracks a job running on this instance.
+type jobRun struct {
+	b        *versionedKVBackend
+	job      *Job
+	cancel   context.CancelFunc
+	lastSave time.Time
+
+	// s is the storage the job must use, counting its operations in the
+	// background load, and done ends the background task of the job.
+	s    logical.Storage
+	done func()
+}
+
+// startJob records a new running job of the given type. The returned context
+// is canceled when the job is canceled, and must be used to run the job with
+// the storage of the returned jobRun.
+func (b *versionedKVBackend) startJob(ctx context.Context, s logical.Storage, jobType string) (*jobRun, context.Context, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := b.timestampNow()
+	run := &jobRun{
+		b: b,
+		job: &Job{
+			Id:          id,
+			Type:        jobType,
+			Status:      jobStatusRunning,
+			OwnerId:     b.upgradeOwnerID,
+			CreatedTime: now,
+			UpdatedTime: now,
+		},
+		lastSave: b.now(),
+	}
+
+	if err := b.putJob(ctx, s, run.job); err != nil {
+		return nil, nil, err
+	}
+
+	run.s, run.done = b.startBackgroundTask(s)
+	ctx, run.cancel = context.WithCancel(ctx)
+
+	b.jobsLock.Lock()
+	b.jobCancels[id] = run.cancel
+	b.jobsLock.Unlock()
+
+	return run, ctx, nil
+}
+
+// progress records the number of items the job has processed, and the total
+// if known. The state is saved at most every jobSaveInterval, at which point
+// a cancellation requested through another instance is picked up.
+func (r *jobRun) progress(processed, total uint64) {
+	r.job.Processed, r.job.Total = processed, total
+	if r.b.now().Sub(r.lastSave) < jobSaveInterval {
+		return
+	}
+
+	// The job context may be canceled, so use a new one to save the state
+	ctx := context.Background()
+
+	stored, err := r.b.getJob(ctx, r.s, r.job.Id)
+	if err == nil && stored != nil && stored.CancelRequested {
+		r.job.CancelRequested = true
+		r.cancel()
+	}
+
+	r.save(ctx)
+}
+
+// finish records the final status of the job, based on the error it returned.
+func (r *jobRun) finish(err error) {
+	r.b.jobsLock.Lock()
+	delete(r.b.jobCancels, r.job.Id)
+	r.b.jobsLock.Unlock()
+	r.cancel()
+	r.done()
+
+	switch {
+	case err == nil:
+		r.job.Status = jobStatusCompleted
+	case errors.Is(err, context.Canceled):
+		r.job.Status = jobStatusCanceled
+	default:
+		r.job.Status = jobStatusFailed
+		r.job.Error = err.Error()
+	}
+
+	r.save(context.Background())
+}
+
+func (r *jobRun) save(ctx context.Context) {
+	r.job.UpdatedTime = r.b.timestampNow()
+	r.lastSave = r.b.now()
+
+	if err := r.b.putJob(ctx, r.s, r.job); err != nil {
+		r.b.Logger().Error("error saving job state", "job_id", r.job.Id, "error", err)
+	}
+}
+
+func (b *versionedKVBackend) putJob(ctx context.Context, s logical.Storage, job *Job) error {
+	buf, err := proto.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, &logical.StorageEntry{
+		Key:   path.Join(b.storagePrefix, jobPrefix, job.Id),
+		Value: buf,
+	})
+}
+
+// getJob returns the stored state of the job with the given ID, or nil if
+// there is no such job.
+func (b *versionedKVBackend) getJob(ctx context.Context, s logical.Storage, id string) (*Job, error) {
+	raw, err := s.Get(ctx, path.Join(b.storagePrefix, jobPrefix, id))
+	if err != nil || raw == nil {
+		return nil, err
+	}
+
+	job := &Job{}
+	if err := proto.Unmarshal(raw.Value, job); err != nil {
+		return nil, fmt.Errorf("failed to decode job from storage: %w", err)
+	}
+	return job, nil
+}
+
+// jobStatus returns the status of the job. A running job is reported as
+// interrupted if it is not running on this instance and its state has not
+// been saved recently, such as when the plugin restarted while it ran.
+func (b *versionedKVBackend) jobStatus(job *Job, now time.Time) string {
+	if job.Status != jobStatusRunning {
+		return job.Status
+	}
+
+	b.jobsLock.Lock()
+	_, local := b.jobCancels[job.Id]
+	b.jobsLock.Unlock()
+	if local {
+		return job.Status
+	}
+
+	updatedTime, err := ptypes.Timestamp(job.UpdatedTime)
+	if err != nil || now.Sub(updatedTime) > jobStaleAfter {
+		return jobStatusInterrupted
+	}
+	return job.Status
+}
+
+// pathJobs returns the path configurations for the jobs endpoints
+func pathJobs(b *versionedKVBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "jobs/" + framework.GenericNameRegex("id") + "/cancel$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixKVv2,
+				OperationVerb:   "cancel",
+				OperationSuffix: "job",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: "The ID of the job.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathJobCancel()),
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: http.StatusText(http.StatusNoContent),
+						}},
+					},
+				},
+			},
+
+			HelpSynopsis:    jobsHelpSyn,
+			HelpDescription: jobsHelpDesc,
+		},
+		{
+			Pattern: "jobs/" + framework.GenericNameRegex("id"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixKVv2,
+				OperationSuffix: "job",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"id": {
+					Type:        framework.TypeString,
+					Description: "The ID of the job.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathJobRead()),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "read",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: http.StatusText(http.StatusOK),
+							Fields:      jobResponseFields,
+						}},
+					},
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathJobDelete()),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "delete",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: http.StatusText(http.StatusNoContent),
+						}},
+					},
+				},
+			},
+
+			HelpSynopsis:    jobsHelpSyn,
+			HelpDescription: jobsHelpDesc,
+		},
+		{
+			Pattern: "jobs/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixKVv2,
+				OperationVerb:   "list",
+				OperationSuffix: "jobs",
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathJobList()),
+				},
+			},
+
+			HelpSynopsis:    jobsHelpSyn,
+			HelpDescription: jobsHelpDesc,
+		},
+	}
+}
+
+var jobResponseFields = map[string]*framework.FieldSchema{
+	"id": {
+		Type:     framework.TypeString,
+		Required: true,
+	},
+	"type": {
+		Type:     framework.TypeString,
+		Required: true,
+	},
+	"status": {
+		Type:        framework.TypeString,
+		Description: "One of running, completed, failed, canceled or interrupted.",
+		Required:    true,
+	},
+	"processed": {
+		Type:        framework.TypeInt64,
+		Description: "The number of items the job has processed.",
+		Required:    true,
+	},
+	"total": {
+		Type:        framework.TypeInt64,
+		Description: "The number of items the job has to process, or 0 if not known yet.",
+		Required:    true,
+	},
+	"error": {
+		Type:        framework.TypeString,
+		Description: "The error the job failed with.",
+		Required:    true,
+	},
+	"cancel_requested": {
+		Type:     framework.TypeBool,
+		Required: true,
+	},
+	"created_time": {
+		Type:     framework.TypeTime,
+		Required: true,
+	},
+	"updated_time": {
+		Type:     framework.TypeTime,
+		Required: true,
+	},
+}
+
+func (b *versionedKVBackend) pathJobRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		job, err := b.getJob(ctx, req.Storage, data.Get("id").(string))
+		if err != nil || job == nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"id":               job.Id,
+				"type":             job.Type,
+				"status":           b.jobStatus(job, b.now()),
+				"processed":        job.Processed,
+				"total":            job.Total,
+				"error":            job.Error,
+				"cancel_requested": job.CancelRequested,
+				"created_time":     ptypesTimestampToString(job.CreatedTime),
+				"updated_time":     ptypesTimestampToString(job.UpdatedTime),
+			},
+		}, nil
+	}
+}
+
+// pathJobCancel cancels a running job. The job stops once the instance
+// running it picks up the cancellation.
+func (b *versionedKVBackend) pathJobCancel() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		id := data.Get("id").(string)
+
+		job, err := b.getJob(ctx, req.Storage, id)
+		if err != nil {
+			return nil, err
+		}
+		if job == nil {
+			return logical.ErrorResponse("no job with ID %q", id), logical.ErrInvalidRequest
+		}
+		if b.jobStatus(job, b.now()) != jobStatusRunning {
+			return logical.ErrorResponse("job %q is not running", id), logical.ErrInvalidRequest
+		}
+
+		job.CancelRequested = true
+		if err := b.putJob(ctx, req.Storage, job); err != nil {
+			return nil, err
+		}
+
+		b.jobsLock.Lock()
+		if cancel, ok := b.jobCancels[id]; ok {
+			cancel()
+		}
+		b.jobsLock.Unlock()
+
+		return nil, nil
+	}
+}
+
+// pathJobDelete deletes the state of a job that is no longer running.
+func (b *versionedKVBackend) pathJobDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		id := data.Get("id").(string)
+
+		job, err := b.getJob(ctx, req.Storage, id)
+		if err != nil || job == nil {
+			return nil, err
+		}
+		if b.jobStatus(job, b.now()) == jobStatusRunning {
+			return logical.ErrorResponse("job %q is running and must be canceled first", id), logical.ErrInvalidRequest
+		}
+
+		return nil, req.Storage.Delete(ctx, path.Join(b.storagePrefix, jobPrefix, id))
+	}
+}
+
+func (b *versionedKVBackend) pathJobList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		ids, err := req.Storage.List(ctx, path.Join(b.storagePrefix, jobPrefix)+"/")
+		if err != nil {
+			return nil, err
+		}
+
+		now := b.now()
+		keyInfo := make(map[string]interface{}, len(ids))
+		for _, id := range ids {
+			job, err := b.getJob(ctx, req.Storage, id)
+			if err != nil {
+				return nil, err
+			}
+			if job == nil {
+				continue
+			}
+
+			keyInfo[id] = map[string]interface{}{
+				"type":   job.Type,
+				"status": b.jobStatus(job, now),
+			}
+		}
+
+		return logical.ListResponseWithInfo(ids, keyInfo), nil
+	}
+}
+
+const jobsHelpSyn = `Returns the progress of long-running jobs and cancels them.`
+const jobsHelpDesc = `
+Long-running administrative operations, such as repair/rebuild-index, run as
+jobs whose state is saved in storage while they run, so their progress can be
+followed from other requests and survives plugin restarts.
+
+Reading jobs/<id> returns the "type" and "status" of the job, the number of
+items it has "processed" and the "total" it has to process, if known, and the
+"error" it failed with. The status is one of running, completed, failed,
+canceled or interrupted; a job is interrupted if it stopped being updated
+while running, such as when the plugin restarted. Listing jobs/ returns the
+IDs of the jobs with their type and status.
+
+Writing to jobs/<id>/cancel cancels a running job. Deleting jobs/<id> deletes
+the state of a job that is no longer running.
+`
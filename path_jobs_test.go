@@ -0,0 +1,140 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Jobs(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "repair/rebuild-index",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"confirm": true,
+		},
+	}
+
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("rebuild request failed, err: %s, resp %#v", err, resp)
+	}
+	id := resp.Data["job_id"].(string)
+
+	readJob := func(id string) map[string]interface{} {
+		t.Helper()
+
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "jobs/" + id,
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("jobs ReadOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		return resp.Data
+	}
+
+	if status := readJob(id)["status"]; status != jobStatusCompleted {
+		t.Fatalf("expected completed job, got %q", status)
+	}
+
+	// A running job can be canceled
+	run, runCtx, err := kvb.startJob(ctx, storage, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "jobs/",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("jobs ListOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if len(resp.Data["keys"].([]string)) != 2 {
+		t.Fatalf("expected 2 jobs, got: %#v", resp.Data["keys"])
+	}
+	if info := resp.Data["key_info"].(map[string]interface{})[run.job.Id].(map[string]interface{}); info["status"] != jobStatusRunning {
+		t.Fatalf("expected running job, got: %#v", info)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "jobs/" + run.job.Id,
+		Storage:   storage,
+	})
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected deleting a running job to fail, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "jobs/" + run.job.Id + "/cancel",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("jobs cancel request failed, err: %s, resp %#v", err, resp)
+	}
+	if runCtx.Err() == nil {
+		t.Fatal("expected the job context to be canceled")
+	}
+	run.finish(runCtx.Err())
+
+	if status := readJob(run.job.Id)["status"]; status != jobStatusCanceled {
+		t.Fatalf("expected canceled job, got %q", status)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "jobs/" + run.job.Id + "/cancel",
+		Storage:   storage,
+	})
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected canceling a finished job to fail, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "jobs/" + run.job.Id,
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("jobs DeleteOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if job, err := kvb.getJob(ctx, storage, run.job.Id); err != nil || job != nil {
+		t.Fatalf("expected job to be deleted, err: %s, job: %#v", err, job)
+	}
+
+	// A job left running by an instance that stopped is interrupted
+	updatedTime, err := ptypes.TimestampProto(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kvb.putJob(ctx, storage, &Job{
+		Id:          "stale",
+		Type:        "test",
+		Status:      jobStatusRunning,
+		OwnerId:     "another-instance",
+		CreatedTime: updatedTime,
+		UpdatedTime: updatedTime,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := readJob("stale")["status"]; status != jobStatusInterrupted {
+		t.Fatalf("expected interrupted job, got %q", status)
+	}
+}
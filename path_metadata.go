@@ -6,8 +6,10 @@ package kv
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -52,8 +54,8 @@ version is used.`,
 				Description: `
 The length of time before a version is deleted. If not set, the backend's
 configured delete_version_after is used. Cannot be greater than the
-backend's delete_version_after. A zero duration clears the current setting.
-A negative duration will cause an error.
+backend's delete_version_after, nor than its max_delete_version_after. A zero
+duration clears the current setting. A negative duration will cause an error.
 `,
 			},
 			"custom_metadata": {
@@ -63,6 +65,128 @@ User-provided key-value pairs that are used to describe arbitrary and
 version-agnostic information about a secret.
 `,
 			},
+			"owner": {
+				Type: framework.TypeString,
+				Description: `
+The entity ID of the owner of the secret. If the backend's config enforces
+ownership, only the owner and tokens with one of the configured admin policies
+may delete and destroy versions of the secret, or change its owner. An empty
+string clears the owner.
+`,
+			},
+			"max_version_age": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+The age after which versions are pruned by the background expiry scan, except
+for the newest min_versions versions. If not set, the backend's configured
+max_version_age is used. A zero duration clears the current setting.
+`,
+			},
+			"destroy_version_after": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+How long after its creation a version is permanently destroyed, removing its
+data from storage. If the backend's configured destroy_version_after is lower,
+it is used instead. A zero duration clears the current setting.
+`,
+			},
+			"min_versions": {
+				Type: framework.TypeInt,
+				Description: `
+The number of newest versions that are kept regardless of max_version_age. If
+not set, the backend's configured min_versions is used.`,
+			},
+			"max_key_bytes": {
+				Type: framework.TypeInt,
+				Description: `
+The largest total size in bytes of the data of the versions of the secret.
+Writes that would exceed it are rejected. If the backend's configured
+max_key_bytes is lower, it is used instead. A value of 0 clears the setting.`,
+			},
+			"read_passphrase": {
+				Type: framework.TypeString,
+				Description: `
+A passphrase that must be provided in the passphrase parameter to read the data
+of the secret, in addition to being authorized to read it. Only an HMAC of the
+passphrase is stored. An empty string removes the requirement.
+`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+			"deprecated": {
+				Type: framework.TypeBool,
+				Description: `
+If true, the secret is deprecated. Reads of its data still succeed, with a
+warning naming the replacement_path, and send a deprecated-read event.`,
+			},
+			"replacement_path": {
+				Type: framework.TypeString,
+				Description: `
+The path of the secret that replaces this secret, included in the warnings and
+events of reads of a deprecated secret. An empty string clears it.`,
+			},
+			"locked": {
+				Type: framework.TypeBool,
+				Description: `
+If true, writes, patches, deletes, undeletes and destroys of the versions of
+the secret are rejected until it is set back to false.`,
+			},
+			"schema_version": {
+				Type: framework.TypeInt,
+				Description: `
+The version of the schema of the data of the secret, for consumers and
+migrations to tell its layout apart. A value of 0 clears the setting.`,
+			},
+			"metadata_cas": {
+				Type: framework.TypeInt,
+				Description: `
+If set during a write or patch, the metadata is only changed if its current
+metadata_version matches this value, so that concurrent writers of the
+metadata do not overwrite each other. Metadata that was never written has a
+metadata_version of 0.`,
+			},
+			"depth": {
+				Type: framework.TypeInt,
+				Description: `
+If set during a list, keys and folders are listed down to this many levels
+below the path, relative to it. Folders at the last level are returned
+unexpanded. Defaults to 1, listing only the direct children of the path.`,
+				Query: true,
+			},
+			"limit": {
+				Type: framework.TypeInt,
+				Description: `
+If set during a list, at most this many keys are returned in sorted order,
+along with "next_after" if there are more keys. Requires the pagination
+feature of config/features, as does "after".`,
+				Query: true,
+			},
+			"after": {
+				Type: framework.TypeString,
+				Description: `
+If set during a list, only keys sorted after this key are returned. Set it to
+the "next_after" of the previous page to read the next page.`,
+				Query: true,
+			},
+			"filter": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `
+If set during a list, only the keys whose custom_metadata matches every
+selector are returned, and folders are not. Selectors have the form
+"custom_metadata.<key>=<value>".`,
+				Query: true,
+			},
+			"require_destroyed": {
+				Type:        framework.TypeBool,
+				Description: "If true during a delete, the metadata is only deleted if every version of the secret has been destroyed.",
+				Query:       true,
+			},
+			"soft": {
+				Type:        framework.TypeBool,
+				Description: "If true during a delete, the secret is moved to the trash, from where it can be restored with metadata-undelete until the trash_retention of the config passes.",
+				Query:       true,
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -130,6 +254,106 @@ version-agnostic information about a secret.
 								Description: "User-provided key-value pairs that are used to describe arbitrary and version-agnostic information about a secret.",
 								Required:    true,
 							},
+							"creation_source": {
+								Type:        framework.TypeString,
+								Description: "How the key was created: \"api\", \"upgrade\", \"restore\" or \"import\". Empty if the key was created before this was recorded.",
+								Required:    true,
+							},
+							"owner": {
+								Type:        framework.TypeString,
+								Description: "The entity ID of the owner of the key.",
+								Required:    true,
+							},
+							"read_passphrase_required": {
+								Type:        framework.TypeBool,
+								Description: "Whether reading the data of the key requires a passphrase.",
+								Required:    true,
+							},
+							"max_version_age": {
+								Type:        framework.TypeDurationSecond,
+								Description: "The age after which versions are pruned, except for the newest min_versions versions.",
+								Required:    true,
+							},
+							"min_versions": {
+								Type:        framework.TypeInt64,
+								Description: "The number of newest versions that are kept regardless of max_version_age.",
+								Required:    true,
+							},
+							"destroy_version_after": {
+								Type:        framework.TypeDurationSecond,
+								Description: "How long after its creation a version is destroyed.",
+								Required:    true,
+							},
+							"retained_versions": {
+								Type:        framework.TypeMap,
+								Description: "The versions pruned by max_versions whose data is retained, mapped to their deletion time.",
+								Required:    true,
+							},
+							"custom_metadata_size": {
+								Type:        framework.TypeInt,
+								Description: "The combined length of the keys and values of custom_metadata.",
+								Required:    true,
+							},
+							"effective_delete_version_after": {
+								Type:        framework.TypeDurationSecond,
+								Description: "The length of time before a new version is deleted, taking the backend's configured delete_version_after into account.",
+								Required:    true,
+							},
+							"delete_version_after_disabled": {
+								Type:        framework.TypeBool,
+								Description: "If true, the backend's config disables delete_version_after for all keys.",
+								Required:    true,
+							},
+							"deprecated": {
+								Type:        framework.TypeBool,
+								Description: "If true, the secret is deprecated.",
+								Required:    true,
+							},
+							"replacement_path": {
+								Type:        framework.TypeString,
+								Description: "The path of the secret that replaces this secret.",
+								Required:    true,
+							},
+							"locked": {
+								Type:        framework.TypeBool,
+								Description: "If true, the versions of the secret cannot be changed.",
+								Required:    true,
+							},
+							"metadata_version": {
+								Type:        framework.TypeInt64, // uint64
+								Description: "The number of times the metadata has been written, checked by metadata_cas.",
+								Required:    true,
+							},
+							"last_read_time": {
+								Type:        framework.TypeString,
+								Description: "When the data of any version of the secret was last read, or an empty string if it was never read.",
+								Required:    true,
+							},
+							"total_bytes": {
+								Type:        framework.TypeInt64, // uint64
+								Description: "The total size in bytes of the data stored for the versions of the secret that are not destroyed.",
+								Required:    true,
+							},
+							"max_key_bytes": {
+								Type:        framework.TypeInt64, // uint64
+								Description: "The largest total size in bytes of the data of the versions of the secret.",
+								Required:    true,
+							},
+							"template": {
+								Type:        framework.TypeString,
+								Description: "The name of the template the secret was created from, if any.",
+								Required:    true,
+							},
+							"schema_version": {
+								Type:        framework.TypeInt64, // uint64
+								Description: "The version of the schema of the data of the secret, or 0 if not set.",
+								Required:    true,
+							},
+							"id": {
+								Type:        framework.TypeString,
+								Description: "The opaque ID of the secret, which resolves to its path through by-id/.",
+								Required:    true,
+							},
 						},
 					}},
 				},
@@ -138,7 +362,7 @@ version-agnostic information about a secret.
 				},
 			},
 			logical.DeleteOperation: &framework.PathOperation{
-				Callback: b.upgradeCheck(b.pathMetadataDelete()),
+				Callback: b.upgradeCheck(b.approvalCheck("metadata-delete", b.pathMetadataDelete())),
 				Responses: map[int][]framework.Response{
 					http.StatusNoContent: {{
 						Description: http.StatusText(http.StatusNoContent),
@@ -195,6 +419,32 @@ func (b *versionedKVBackend) pathMetadataList() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		key := data.Get("path").(string)
 
+		depth := data.Get("depth").(int)
+		if depth < 0 || depth > maxListDepth {
+			return logical.ErrorResponse("depth must be between 0 and %d", maxListDepth), logical.ErrInvalidRequest
+		}
+
+		limit := data.Get("limit").(int)
+		if limit < 0 {
+			return logical.ErrorResponse("limit cannot be negative"), logical.ErrInvalidRequest
+		}
+		after := data.Get("after").(string)
+
+		filter, err := parseCustomMetadataFilter(data.Get("filter").([]string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		if limit > 0 || after != "" {
+			config, err := b.config(ctx, req.Storage)
+			if err != nil {
+				return nil, err
+			}
+			if !config.featureEnabled(featurePagination) {
+				return logical.ErrorResponse("the pagination feature is disabled on this mount, limit and after cannot be set"), logical.ErrInvalidRequest
+			}
+		}
+
 		// Get an encrypted key storage object
 		wrapper, err := b.getKeyEncryptor(ctx, req.Storage)
 		if err != nil {
@@ -204,15 +454,159 @@ func (b *versionedKVBackend) pathMetadataList() framework.OperationFunc {
 		es := wrapper.Wrap(req.Storage)
 
 		// Use encrypted key storage to list the keys
-		keys, err := es.List(ctx, key)
-		return logical.ListResponse(keys), err
+		var keys []string
+		if depth <= 1 {
+			keys, err = es.List(ctx, key)
+		} else {
+			keys, err = listToDepth(ctx, es, key, depth)
+			if err == errListTooLarge {
+				return logical.ErrorResponse("listing %q to depth %d returns more than %d entries, use a lower depth", key, depth, maxListDepthEntries), logical.ErrInvalidRequest
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(filter) > 0 {
+			keys, err = b.filterListedKeys(ctx, req.Storage, key, keys, filter)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if limit == 0 && after == "" {
+			return logical.ListResponse(keys), nil
+		}
+
+		page, nextAfter := paginateKeys(keys, after, limit)
+		resp := logical.ListResponse(page)
+		if nextAfter != "" {
+			resp.Data["next_after"] = nextAfter
+		}
+		return resp, nil
+	}
+}
+
+// customMetadataSelectorPrefix is the prefix of the selectors of the filter
+// of metadata lists.
+const customMetadataSelectorPrefix = "custom_metadata."
+
+// parseCustomMetadataFilter returns the custom metadata values selected by
+// the filter of a metadata list.
+func parseCustomMetadataFilter(selectors []string) (map[string]string, error) {
+	filter := make(map[string]string, len(selectors))
+	for _, selector := range selectors {
+		field, value, ok := strings.Cut(selector, "=")
+		name := strings.TrimPrefix(field, customMetadataSelectorPrefix)
+		if !ok || name == field || name == "" {
+			return nil, fmt.Errorf("invalid filter selector %q, must be of the form %s<key>=<value>", selector, customMetadataSelectorPrefix)
+		}
+		filter[name] = value
+	}
+	return filter, nil
+}
+
+// filterListedKeys returns the keys listed under prefix whose custom
+// metadata matches the filter. Folders are not returned.
+func (b *versionedKVBackend) filterListedKeys(ctx context.Context, s logical.Storage, prefix string, keys []string, filter map[string]string) ([]string, error) {
+	filtered := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if strings.HasSuffix(k, "/") {
+			continue
+		}
+
+		meta, err := b.getKeyMetadata(ctx, s, prefix+k)
+		if err != nil {
+			return nil, err
+		}
+		if meta != nil && customMetadataMatches(meta.CustomMetadata, filter) {
+			filtered = append(filtered, k)
+		}
 	}
+	return filtered, nil
+}
+
+// paginateKeys returns at most limit of the keys sorted after the after key,
+// and the last returned key if there are more. A zero limit returns all the
+// remaining keys. The key names are encrypted in storage, so the keys are
+// sorted here rather than by the storage backend.
+func paginateKeys(keys []string, after string, limit int) ([]string, string) {
+	sort.Strings(keys)
+
+	start := sort.SearchStrings(keys, after)
+	if start < len(keys) && keys[start] == after {
+		start++
+	}
+	keys = keys[start:]
+
+	if limit == 0 || len(keys) <= limit {
+		return keys, ""
+	}
+	return keys[:limit], keys[limit-1]
+}
+
+const (
+	// maxListDepth is the maximum depth of a metadata list
+	maxListDepth = 32
+
+	// maxListDepthEntries is the maximum number of entries returned by a
+	// metadata list with a depth greater than 1
+	maxListDepthEntries = 10000
+)
+
+var errListTooLarge = errors.New("list exceeds the maximum number of entries")
+
+// listToDepth lists the keys and folders under prefix down to depth levels,
+// returning their paths relative to prefix in sorted order. Folders at the
+// last level are not descended into.
+func listToDepth(ctx context.Context, s logical.Storage, prefix string, depth int) ([]string, error) {
+	var result []string
+
+	var walk func(string, int) error
+	walk = func(p string, level int) error {
+		keys, err := s.List(ctx, prefix+p)
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if strings.HasSuffix(k, "/") && level < depth {
+				if err := walk(p+k, level+1); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if len(result) == maxListDepthEntries {
+				return errListTooLarge
+			}
+			result = append(result, p+k)
+		}
+
+		return nil
+	}
+
+	if err := walk("", 1); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(result)
+	return result, nil
 }
 
 func (b *versionedKVBackend) pathMetadataRead() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		key := data.Get("path").(string)
 
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
 		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
 		if err != nil {
 			return nil, err
@@ -221,42 +615,102 @@ func (b *versionedKVBackend) pathMetadataRead() framework.OperationFunc {
 			return nil, nil
 		}
 
-		versions := make(map[string]interface{}, len(meta.Versions))
-		for i, v := range meta.Versions {
-			versions[fmt.Sprintf("%d", i)] = map[string]interface{}{
-				"created_time":  ptypesTimestampToString(v.CreatedTime),
-				"deletion_time": ptypesTimestampToString(v.DeletionTime),
-				"destroyed":     v.Destroyed,
-			}
-		}
-
-		var deleteVersionAfter time.Duration
-		if meta.GetDeleteVersionAfter() != nil {
-			deleteVersionAfter, err = ptypes.Duration(meta.GetDeleteVersionAfter())
-			if err != nil {
-				return nil, err
-			}
+		rdata, err := keyMetadataResponseData(config, meta)
+		if err != nil {
+			return nil, err
 		}
 
 		return &logical.Response{
-			Data: map[string]interface{}{
-				"versions":             versions,
-				"current_version":      meta.CurrentVersion,
-				"oldest_version":       meta.OldestVersion,
-				"created_time":         ptypesTimestampToString(meta.CreatedTime),
-				"updated_time":         ptypesTimestampToString(meta.UpdatedTime),
-				"max_versions":         meta.MaxVersions,
-				"cas_required":         meta.CasRequired,
-				"delete_version_after": deleteVersionAfter.String(),
-				"custom_metadata":      meta.CustomMetadata,
-			},
+			Data: rdata,
 		}, nil
 	}
 }
 
+// keyMetadataResponseData returns the response data of a metadata read of the
+// key, which data reads also return if include_key_metadata is set.
+func keyMetadataResponseData(config *Configuration, meta *KeyMetadata) (map[string]interface{}, error) {
+	versions := make(map[string]interface{}, len(meta.Versions))
+	for i, v := range meta.Versions {
+		versions[fmt.Sprintf("%d", i)] = map[string]interface{}{
+			"created_time":   ptypesTimestampToString(v.CreatedTime),
+			"deletion_time":  ptypesTimestampToString(v.DeletionTime),
+			"destroyed":      v.Destroyed,
+			"archived":       v.Archived,
+			"correlation_id": v.CorrelationId,
+			"read_count":     v.ReadCount,
+			"last_read_time": ptypesTimestampToString(v.LastReadTime),
+			"data_bytes":     v.DataBytes,
+		}
+	}
+
+	retainedVersions := make(map[string]interface{}, len(meta.RetainedVersions))
+	for i, ts := range meta.RetainedVersions {
+		retainedVersions[fmt.Sprintf("%d", i)] = ptypesTimestampToString(ts)
+	}
+
+	var deleteVersionAfter time.Duration
+	if meta.GetDeleteVersionAfter() != nil {
+		var err error
+		deleteVersionAfter, err = ptypes.Duration(meta.GetDeleteVersionAfter())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]interface{}{
+		"versions":                 versions,
+		"current_version":          meta.CurrentVersion,
+		"oldest_version":           meta.OldestVersion,
+		"created_time":             ptypesTimestampToString(meta.CreatedTime),
+		"updated_time":             ptypesTimestampToString(meta.UpdatedTime),
+		"max_versions":             meta.MaxVersions,
+		"cas_required":             meta.CasRequired,
+		"delete_version_after":     deleteVersionAfter.String(),
+		"custom_metadata":          meta.CustomMetadata,
+		"custom_metadata_size":     customMetadataSize(meta.CustomMetadata),
+		"retained_versions":        retainedVersions,
+		"creation_source":          meta.CreationSource,
+		"owner":                    meta.Owner,
+		"read_passphrase_required": meta.readPassphraseRequired(),
+		"max_version_age":          durationOrZero(meta.GetMaxVersionAge()).String(),
+		"min_versions":             meta.MinVersions,
+		"destroy_version_after":    durationOrZero(meta.GetDestroyVersionAfter()).String(),
+		"deprecated":               meta.Deprecated,
+		"replacement_path":         meta.ReplacementPath,
+		"locked":                   meta.Locked,
+		"metadata_version":         meta.MetadataVersion,
+		"last_read_time":           ptypesTimestampToString(meta.LastReadTime),
+		"total_bytes":              meta.TotalBytes,
+		"max_key_bytes":            meta.MaxKeyBytes,
+		"template":                 meta.Template,
+		"schema_version":           meta.SchemaVersion,
+		"id":                       meta.Id,
+
+		"effective_delete_version_after": effectiveDeleteVersionAfter(config, meta).String(),
+		"delete_version_after_disabled":  config.IsDeleteVersionAfterDisabled(),
+	}, nil
+}
+
+// The values of KeyMetadata.CreationSource
+const (
+	creationSourceAPI       = "api"
+	creationSourceUpgrade   = "upgrade"
+	creationSourceRestore   = "restore"
+	creationSourceMirror    = "mirror"
+	creationSourceRecovered = "recovered"
+	creationSourceGenerated = "generated"
+	creationSourceImport    = "import"
+)
+
 const maxCustomMetadataKeys = 64
 const maxCustomMetadataKeyLength = 128
 const maxCustomMetadataValueLength = 512
+
+// maxCustomMetadataSize is the combined length of the largest custom_metadata
+// the key count and length limits allow, so that it rejects nothing those
+// limits accept.
+const maxCustomMetadataSize = maxCustomMetadataKeys * (maxCustomMetadataKeyLength + maxCustomMetadataValueLength)
+
 const customMetadataValidationErrorPrefix = "custom_metadata validation failed"
 
 // Perform input validation on custom_metadata field. If the key count
@@ -267,6 +721,9 @@ const customMetadataValidationErrorPrefix = "custom_metadata validation failed"
 //   - 0 < length of key <= maxCustomMetadataKeyLength
 //   - 0 < length of value <= maxCustomMetadataValueLength
 //   - keys and values cannot include unprintable characters
+//
+// The combined length of all keys and values must also not exceed
+// maxCustomMetadataSize.
 func validateCustomMetadata(customMetadata map[string]string) error {
 	var errs *multierror.Error
 
@@ -279,6 +736,13 @@ func validateCustomMetadata(customMetadata map[string]string) error {
 		return errs.ErrorOrNil()
 	}
 
+	if size := customMetadataSize(customMetadata); size > maxCustomMetadataSize {
+		errs = multierror.Append(errs, fmt.Errorf("%s: combined length of keys and values must be at most %d, provided %d",
+			customMetadataValidationErrorPrefix,
+			maxCustomMetadataSize,
+			size))
+	}
+
 	// Perform validation on each key and value and return ALL errors
 	for key, value := range customMetadata {
 		if keyLen := len(key); 0 == keyLen || keyLen > maxCustomMetadataKeyLength {
@@ -316,6 +780,17 @@ func validateCustomMetadata(customMetadata map[string]string) error {
 	return errs.ErrorOrNil()
 }
 
+// customMetadataSize returns the combined length of the keys and values of
+// custom_metadata.
+func customMetadataSize(customMetadata map[string]string) int {
+	var size int
+	for key, value := range customMetadata {
+		size += len(key) + len(value)
+	}
+
+	return size
+}
+
 // parseCustomMetadata is used to effectively convert the TypeMap
 // (map[string]interface{}) into a TypeKVPairs (map[string]string)
 // which is how custom_metadata is stored. Defining custom_metadata
@@ -341,28 +816,86 @@ func parseCustomMetadata(raw map[string]interface{}, filterNils bool) (map[strin
 	return customMetadata, nil
 }
 
+// validateMetadataCAS returns an error if the metadata_cas parameter is set
+// and does not match the metadata_version of the key.
+func validateMetadataCAS(data *framework.FieldData, meta *KeyMetadata) error {
+	casRaw, ok := data.GetOk("metadata_cas")
+	if !ok {
+		return nil
+	}
+
+	cas := casRaw.(int)
+	if cas < 0 {
+		return errors.New("metadata_cas cannot be negative")
+	}
+	if uint64(cas) != meta.MetadataVersion {
+		return fmt.Errorf("metadata check-and-set parameter did not match the current metadata version %d", meta.MetadataVersion)
+	}
+
+	return nil
+}
+
 func (b *versionedKVBackend) pathMetadataWrite() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		key := data.Get("path").(string)
 		if key == "" {
 			return logical.ErrorResponse("missing path"), nil
 		}
+		if err := checkDurationFields(data, "delete_version_after"); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
 
 		maxRaw, mOk := data.GetOk("max_versions")
 		casRaw, cOk := data.GetOk("cas_required")
 		deleteVersionAfterRaw, dvaOk := data.GetOk("delete_version_after")
 		customMetadataRaw, cmOk := data.GetOk("custom_metadata")
+		ownerRaw, oOk := data.GetOk("owner")
+		maxVersionAgeRaw, mvaOk := data.GetOk("max_version_age")
+		minVersionsRaw, minOk := data.GetOk("min_versions")
+		passphraseRaw, pOk := data.GetOk("read_passphrase")
+		deprecatedRaw, dOk := data.GetOk("deprecated")
+		replacementRaw, rOk := data.GetOk("replacement_path")
+		lockedRaw, lOk := data.GetOk("locked")
+		destroyVersionAfterRaw, dvfOk := data.GetOk("destroy_version_after")
+		maxKeyBytesRaw, mkbOk := data.GetOk("max_key_bytes")
+		schemaVersionRaw, svOk := data.GetOk("schema_version")
 
 		// Fast path validation
-		if !mOk && !cOk && !dvaOk && !cmOk {
+		if !mOk && !cOk && !dvaOk && !cmOk && !oOk && !mvaOk && !minOk && !pOk && !dOk && !rOk && !lOk && !dvfOk && !mkbOk && !svOk {
 			return nil, nil
 		}
 
+		if rOk {
+			if err := validateReplacementPath(key, replacementRaw.(string)); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
+		if (mvaOk && maxVersionAgeRaw.(int) < 0) || (minOk && minVersionsRaw.(int) < 0) {
+			return logical.ErrorResponse("max_version_age and min_versions cannot be negative"), logical.ErrInvalidRequest
+		}
+		if dvfOk && destroyVersionAfterRaw.(int) < 0 {
+			return logical.ErrorResponse("destroy_version_after cannot be negative"), logical.ErrInvalidRequest
+		}
+		if mkbOk && maxKeyBytesRaw.(int) < 0 {
+			return logical.ErrorResponse("max_key_bytes cannot be negative"), logical.ErrInvalidRequest
+		}
+		if svOk && schemaVersionRaw.(int) < 0 {
+			return logical.ErrorResponse("schema_version cannot be negative"), logical.ErrInvalidRequest
+		}
+
 		config, err := b.config(ctx, req.Storage)
 		if err != nil {
 			return nil, err
 		}
 
+		if dvaOk {
+			dva := time.Duration(deleteVersionAfterRaw.(int)) * time.Second
+			if err := config.validateDeleteVersionAfter("delete_version_after", dva); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
 		customMetadataMap := map[string]string{}
 
 		if cmOk {
@@ -393,14 +926,18 @@ func (b *versionedKVBackend) pathMetadataWrite() framework.OperationFunc {
 			return nil, err
 		}
 		if meta == nil {
-			now := ptypes.TimestampNow()
+			now := b.timestampNow()
 			meta = &KeyMetadata{
-				Key:         key,
-				Versions:    map[uint64]*VersionMetadata{},
-				CreatedTime: now,
-				UpdatedTime: now,
+				Key:            key,
+				Versions:       map[uint64]*VersionMetadata{},
+				CreatedTime:    now,
+				UpdatedTime:    now,
+				CreationSource: creationSourceAPI,
 			}
 		}
+		if err := validateMetadataCAS(data, meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
 
 		if mOk {
 			meta.MaxVersions = uint32(maxRaw.(int))
@@ -414,9 +951,46 @@ func (b *versionedKVBackend) pathMetadataWrite() framework.OperationFunc {
 		if cmOk {
 			meta.CustomMetadata = customMetadataMap
 		}
+		if mvaOk {
+			meta.MaxVersionAge = optionalDurationProto(maxVersionAgeRaw.(int))
+		}
+		if dvfOk {
+			meta.DestroyVersionAfter = optionalDurationProto(destroyVersionAfterRaw.(int))
+		}
+		if minOk {
+			meta.MinVersions = uint32(minVersionsRaw.(int))
+		}
+		if mkbOk {
+			meta.MaxKeyBytes = uint64(maxKeyBytesRaw.(int))
+		}
+		if svOk {
+			meta.SchemaVersion = uint64(schemaVersionRaw.(int))
+		}
+		if oOk && ownerRaw.(string) != meta.Owner {
+			if !config.ownerPermitted(req, meta) {
+				return ownerDenied("changing the owner")
+			}
+			meta.Owner = ownerRaw.(string)
+		}
+		if pOk {
+			if err := meta.setReadPassphrase(passphraseRaw.(string)); err != nil {
+				return nil, err
+			}
+		}
+		if dOk {
+			meta.Deprecated = deprecatedRaw.(bool)
+		}
+		if rOk {
+			meta.ReplacementPath = replacementRaw.(string)
+		}
+		if lOk {
+			meta.Locked = lockedRaw.(bool)
+		}
+		meta.MetadataVersion++
 
+		sequence := meta.nextEventSequence()
 		err = b.writeKeyMetadata(ctx, req.Storage, meta)
-		kvEvent(ctx, b.Backend, "metadata-write", "metadata/"+key, "metadata/"+key, true, 2)
+		b.emitKeyEvent(ctx, req.Storage, meta, sequence, "metadata-write", "metadata/"+key, "metadata/"+key, true)
 		return resp, err
 	}
 }
@@ -427,12 +1001,12 @@ func (b *versionedKVBackend) pathMetadataWrite() framework.OperationFunc {
 // and ensuring appropriate handling of data types not supported directly by FieldType.
 func metadataPatchPreprocessor() framework.PatchPreprocessorFunc {
 	return func(input map[string]interface{}) (map[string]interface{}, error) {
-		patchableKeys := []string{"max_versions", "cas_required", "delete_version_after", "custom_metadata"}
+		patchableKeys := []string{"max_versions", "cas_required", "delete_version_after", "custom_metadata", "owner", "max_version_age", "min_versions", "deprecated", "replacement_path", "locked", "destroy_version_after", "max_key_bytes", "schema_version"}
 		patchData := map[string]interface{}{}
 
 		for _, k := range patchableKeys {
 			if v, ok := input[k]; ok {
-				if k == "delete_version_after" {
+				if k == "delete_version_after" || k == "max_version_age" || k == "destroy_version_after" {
 					d := ptypes.DurationProto(time.Duration(v.(int)) * time.Second)
 
 					// underlying Seconds and Nanos fields in durationpb.Duration
@@ -464,6 +1038,10 @@ func (b *versionedKVBackend) pathMetadataPatch() framework.OperationFunc {
 			return logical.ErrorResponse("missing path"), nil
 		}
 
+		if err := checkDurationFields(data, "delete_version_after"); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
 		if cmRaw, cmOk := data.GetOk("custom_metadata"); cmOk {
 			customMetadataMap, err := parseCustomMetadata(cmRaw.(map[string]interface{}), true)
 			if err != nil {
@@ -477,11 +1055,39 @@ func (b *versionedKVBackend) pathMetadataPatch() framework.OperationFunc {
 			}
 		}
 
+		mvaRaw, mvaOk := data.GetOk("max_version_age")
+		minRaw, minOk := data.GetOk("min_versions")
+		if (mvaOk && mvaRaw.(int) < 0) || (minOk && minRaw.(int) < 0) {
+			return logical.ErrorResponse("max_version_age and min_versions cannot be negative"), logical.ErrInvalidRequest
+		}
+		if dvfRaw, dvfOk := data.GetOk("destroy_version_after"); dvfOk && dvfRaw.(int) < 0 {
+			return logical.ErrorResponse("destroy_version_after cannot be negative"), logical.ErrInvalidRequest
+		}
+		if mkbRaw, mkbOk := data.GetOk("max_key_bytes"); mkbOk && mkbRaw.(int) < 0 {
+			return logical.ErrorResponse("max_key_bytes cannot be negative"), logical.ErrInvalidRequest
+		}
+		if svRaw, svOk := data.GetOk("schema_version"); svOk && svRaw.(int) < 0 {
+			return logical.ErrorResponse("schema_version cannot be negative"), logical.ErrInvalidRequest
+		}
+
+		if rRaw, rOk := data.GetOk("replacement_path"); rOk {
+			if err := validateReplacementPath(key, rRaw.(string)); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
 		config, err := b.config(ctx, req.Storage)
 		if err != nil {
 			return nil, err
 		}
 
+		if dvaRaw, dvaOk := data.GetOk("delete_version_after"); dvaOk {
+			dva := time.Duration(dvaRaw.(int)) * time.Second
+			if err := config.validateDeleteVersionAfter("delete_version_after", dva); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
 		lock := locksutil.LockForKey(b.locks, key)
 		lock.Lock()
 		defer lock.Unlock()
@@ -494,6 +1100,9 @@ func (b *versionedKVBackend) pathMetadataPatch() framework.OperationFunc {
 		if meta == nil {
 			return logical.RespondWithStatusCode(nil, req, http.StatusNotFound)
 		}
+		if err := validateMetadataCAS(data, meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
 
 		var resp *logical.Response
 		casRaw, cOk := data.GetOk("cas_required")
@@ -525,11 +1134,33 @@ func (b *versionedKVBackend) pathMetadataPatch() framework.OperationFunc {
 			return nil, err
 		}
 
+		// The patch is validated on its own above, so validate the merged
+		// custom_metadata to prevent it from growing past the limits
+		// through repeated patches.
+		if customMetadataErrs := validateCustomMetadata(patchedMetadata.CustomMetadata); customMetadataErrs != nil {
+			return logical.ErrorResponse(customMetadataErrs.Error()), nil
+		}
+
+		if patchedMetadata.Owner != meta.Owner && !config.ownerPermitted(req, meta) {
+			return ownerDenied("changing the owner")
+		}
+
+		// The passphrase is not a field of the metadata, so it is not part
+		// of the patch document.
+		if passphraseRaw, ok := data.GetOk("read_passphrase"); ok {
+			if err := patchedMetadata.setReadPassphrase(passphraseRaw.(string)); err != nil {
+				return nil, err
+			}
+		}
+
+		patchedMetadata.MetadataVersion++
+
+		sequence := patchedMetadata.nextEventSequence()
 		if err = b.writeKeyMetadata(ctx, req.Storage, patchedMetadata); err != nil {
 			return nil, err
 		}
 
-		kvEvent(ctx, b.Backend, "metadata-patch", "metadata/"+key, "metadata/"+key, true, 2)
+		b.emitKeyEvent(ctx, req.Storage, patchedMetadata, sequence, "metadata-patch", "metadata/"+key, "metadata/"+key, true)
 		return resp, nil
 	}
 }
@@ -538,6 +1169,16 @@ func (b *versionedKVBackend) pathMetadataDelete() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		key := data.Get("path").(string)
 
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		soft := data.Get("soft").(bool)
+		if soft && durationOrZero(config.GetTrashRetention()) <= 0 {
+			return logical.ErrorResponse("soft deletes are disabled, set the trash_retention of the config to enable them"), logical.ErrInvalidRequest
+		}
+
 		lock := locksutil.LockForKey(b.locks, key)
 		lock.Lock()
 		defer lock.Unlock()
@@ -549,37 +1190,170 @@ func (b *versionedKVBackend) pathMetadataDelete() framework.OperationFunc {
 		if meta == nil {
 			return nil, nil
 		}
+		if !config.ownerPermitted(req, meta) {
+			return ownerDenied("deleting the metadata and all versions")
+		}
+		if meta.isLocked() {
+			return lockedDenied(key, "deleting the metadata and all versions")
+		}
 
-		// Delete each version.
-		for id, _ := range meta.Versions {
-			versionKey, err := b.getVersionKey(ctx, key, id, req.Storage)
-			if err != nil {
-				return nil, err
+		if data.Get("require_destroyed").(bool) {
+			if remaining := meta.undestroyedVersions(); len(remaining) > 0 {
+				return logical.ErrorResponse("the data of versions %v has not been destroyed", remaining), logical.ErrInvalidRequest
 			}
+		}
 
-			err = req.Storage.Delete(ctx, versionKey)
-			if err != nil {
-				return nil, err
+		if soft {
+			return nil, b.trashKey(ctx, req.Storage, meta)
+		}
+
+		if err := b.deleteKeyData(ctx, req.Storage, meta); err != nil {
+			return nil, err
+		}
+
+		b.emitKeyEvent(ctx, req.Storage, meta, meta.nextEventSequence(), "metadata-delete", "metadata/"+key, "", true)
+		return nil, b.deleteChangelog(ctx, req.Storage, key)
+	}
+}
+
+// storedVersionIDs returns the versions of the key that may have data in
+// storage, including versions retained after being pruned and pruned versions
+// whose data is still referenced.
+func (k *KeyMetadata) storedVersionIDs() []uint64 {
+	versionIDs := make([]uint64, 0, len(k.Versions)+len(k.RetainedVersions))
+	for id, vm := range k.Versions {
+		versionIDs = append(versionIDs, id)
+		if vm.DataVersion != 0 {
+			if _, ok := k.Versions[vm.DataVersion]; !ok {
+				versionIDs = append(versionIDs, vm.DataVersion)
 			}
 		}
+	}
+	for id := range k.RetainedVersions {
+		versionIDs = append(versionIDs, id)
+	}
 
-		// Get an encrypted key storage object
-		wrapper, err := b.getKeyEncryptor(ctx, req.Storage)
+	return versionIDs
+}
+
+// deleteKeyData deletes the data of every version of the key, archived or
+// not, and then the key metadata itself.
+func (b *versionedKVBackend) deleteKeyData(ctx context.Context, s logical.Storage, meta *KeyMetadata) error {
+	for _, id := range meta.storedVersionIDs() {
+		versionKey, err := b.getVersionKey(ctx, meta.Key, id, s)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		es := wrapper.Wrap(req.Storage)
+		err = s.Delete(ctx, versionKey)
+		if err != nil {
+			return err
+		}
 
-		// Use encrypted key storage to delete the key
-		err = es.Delete(ctx, key)
-		kvEvent(ctx, b.Backend, "metadata-delete", "metadata/"+key, "", true, 2)
-		return nil, err
+		archivedKey, err := b.getArchivedVersionKey(ctx, meta.Key, id, s)
+		if err != nil {
+			return err
+		}
+
+		err = s.Delete(ctx, archivedKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Get an encrypted key storage object
+	wrapper, err := b.getKeyEncryptor(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	es := wrapper.Wrap(s)
+
+	// Use encrypted key storage to delete the key
+	if err := es.Delete(ctx, meta.Key); err != nil {
+		return err
+	}
+	if err := b.deleteKeyID(ctx, s, meta); err != nil {
+		return err
 	}
+
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+	b.adjustStorageUsage(ctx, s, config, -int64(meta.storedBytes()))
+	return nil
+}
+
+// undestroyedVersions returns the versions of the key whose data still
+// exists, including versions retained after being pruned, in order.
+func (k *KeyMetadata) undestroyedVersions() []uint64 {
+	var versions []uint64
+	for id, vm := range k.Versions {
+		if !vm.Destroyed {
+			versions = append(versions, id)
+		}
+	}
+	for id := range k.RetainedVersions {
+		versions = append(versions, id)
+	}
+
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
 }
 
 const metadataHelpSyn = `Allows interaction with key metadata and settings in the KV store.`
 const metadataHelpDesc = `
 This endpoint allows for reading, information about a key in the key-value
 store, writing key settings, and permanently deleting a key and all versions. 
+
+If the "require_destroyed" parameter is set to true on delete, the key is only
+deleted if the data of all of its versions has already been destroyed.
+
+If the "soft" parameter is set to true on delete, the key is moved to the
+trash instead: it is hidden from reads and lists as if it was deleted, but can
+be restored with all of its versions through metadata-undelete until the
+trash_retention of the config passes and tidy purges it. Soft deletes require
+trash_retention to be set.
+
+Setting "deprecated" to true marks a key that consumers should migrate away
+from, optionally to the key given by "replacement_path". Data, subkeys and
+v1-data reads of a deprecated key succeed with a warning naming the
+replacement, and send a kv-v2/deprecated-read event carrying the
+replacement_path and the entity and address of the reader.
+
+Setting "locked" to true makes the key read-only: writes, patches, deletes,
+undeletes and destroys of its versions, and deleting or moving the key, are
+rejected until "locked" is set back to false. The metadata itself can still
+be written, so the key can be unlocked.
+
+Setting "destroy_version_after" permanently destroys versions of the key that
+long after their creation, removing their data from storage rather than only
+marking them deleted. The lower non-zero value of the key and the backend's
+config applies.
+
+Each write and patch of the metadata increments its "metadata_version". Set
+"metadata_cas" to the metadata_version last read to only apply the write or
+patch if no other writer changed the metadata since, for example when several
+controllers manage the custom_metadata of the same key. Metadata that was
+never written has a metadata_version of 0.
+
+Reads of the data of each version are counted, and the "read_count" and
+"last_read_time" of each version, along with the "last_read_time" of the key,
+are returned to find secrets nobody reads anymore. Reads are counted in memory
+and written to the metadata in batches by the periodic function, so the
+counts lag recent reads. Reads served by performance standbys and secondaries
+are not counted.
+
+The "total_bytes" of a key is the size of the data stored for its versions
+that are not destroyed, with the "data_bytes" of each version. Data shared by
+deduplicated versions is counted once. Setting "max_key_bytes" rejects writes
+that would take total_bytes over the limit; the lower non-zero value of the
+key and the backend's config applies.
+
+The "schema_version" of a key records the version of the layout of its data,
+for consumers to tell layouts apart while they change. The
+reports/schema-versions endpoint counts the keys by schema_version, and the
+migrate/schema endpoint rewrites the data of the keys of one schema_version
+into a new version of each key.
 `
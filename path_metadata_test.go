@@ -395,6 +395,86 @@ func TestVersionedKV_Metadata_Delete(t *testing.T) {
 	})
 }
 
+func TestVersionedKV_Metadata_Delete_RequireDestroyed(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for i := 0; i < 2; i++ {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": fmt.Sprintf("baz%d", i),
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "destroy/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"versions": "1",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("destroy UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	deleteReq := &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"require_destroyed": true,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), deleteReq)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected metadata delete with an undestroyed version to fail, err: %s, resp %#v", err, resp)
+	}
+	if !strings.Contains(resp.Error().Error(), "[2]") {
+		t.Fatalf("expected error to name version 2, got %q", resp.Error())
+	}
+
+	meta, err := b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta == nil {
+		t.Fatal("expected metadata to not be deleted")
+	}
+
+	req.Data["versions"] = "2"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("destroy UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), deleteReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata DeleteOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	meta, err = b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta != nil {
+		t.Fatalf("expected metadata to be deleted, got %#v", meta)
+	}
+}
+
 func TestVersionedKV_Metadata_Put_Bad_CustomMetadata(t *testing.T) {
 	b, storage := getBackend(t)
 
@@ -1304,6 +1384,80 @@ func TestVersionedKV_Metadata_Patch_CustomMetadata(t *testing.T) {
 	}
 }
 
+func TestVersionedKV_Metadata_Patch_CustomMetadataGrowth(t *testing.T) {
+	b, storage := getBackend(t)
+
+	value := strings.Repeat("a", maxCustomMetadataValueLength)
+
+	// Each patch is valid on its own, but together they exceed the limits.
+	// The largest custom_metadata the limits allow is accepted.
+	var resp *logical.Response
+	var err error
+	for i := 0; i <= maxCustomMetadataKeys; i++ {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "metadata/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"custom_metadata": map[string]interface{}{
+					fmt.Sprintf("%02d", i): value,
+				},
+			},
+		}
+		if i > 0 {
+			req.Operation = logical.PatchOperation
+		}
+
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("metadata request failed, err: %s, resp %#v", err, resp)
+		}
+		if resp != nil && resp.IsError() {
+			break
+		}
+	}
+
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected patch past the limits to fail, resp %#v", resp)
+	}
+	if !strings.Contains(resp.Error().Error(), fmt.Sprintf("payload must contain at most %d keys", maxCustomMetadataKeys)) {
+		t.Fatalf("expected custom_metadata key count error, resp %#v", resp)
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if size := resp.Data["custom_metadata_size"].(int); size != maxCustomMetadataKeys*(maxCustomMetadataValueLength+2) {
+		t.Fatalf("unexpected custom_metadata_size %d", size)
+	}
+
+	// Keys and values of the maximum length fit in the combined limit
+	largest := map[string]interface{}{}
+	for i := 0; i < maxCustomMetadataKeys; i++ {
+		largest[fmt.Sprintf("%0*d", maxCustomMetadataKeyLength, i)] = value
+	}
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "metadata/bar",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"custom_metadata": largest,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+}
+
 func TestVersionedKV_Metadata_Patch_Success(t *testing.T) {
 	t.Parallel()
 
@@ -1345,7 +1499,8 @@ func TestVersionedKV_Metadata_Patch_Success(t *testing.T) {
 				"delete_version_after": "0s",
 				"updated_time":         ignoreVal,
 			},
-			3,
+			// effective_delete_version_after follows delete_version_after
+			4,
 		},
 	}
 
@@ -1436,6 +1591,14 @@ func TestVersionedKV_Metadata_Patch_Success(t *testing.T) {
 
 			patchedMetadata := resp.Data
 
+			// Every patch increments the metadata_version, so it is checked
+			// separately from the patched fields
+			if patchedMetadata["metadata_version"] != initialMetadata["metadata_version"].(uint64)+1 {
+				t.Fatalf("expected the patch to increment metadata_version, got %v", patchedMetadata["metadata_version"])
+			}
+			delete(initialMetadata, "metadata_version")
+			delete(patchedMetadata, "metadata_version")
+
 			if diff := deep.Equal(initialMetadata, patchedMetadata); tc.expectedChanges != len(diff) {
 				t.Fatalf("incorrect number of changes to metadata, expected: %d, actual: %d, diff: %#v",
 					tc.expectedChanges,
@@ -1448,11 +1611,15 @@ func TestVersionedKV_Metadata_Patch_Success(t *testing.T) {
 
 				if inputVal, ok := tc.input[k]; ok && inputVal != nil && inputVal != ignoreVal {
 					expectedVal = inputVal
+				} else if k == "effective_delete_version_after" {
+					// The mount does not configure delete_version_after so
+					// the key's own setting is in effect
+					expectedVal = patchedMetadata["delete_version_after"]
 				} else {
 					expectedVal = initialMetadata[k]
 				}
 
-				if k == "custom_metadata" || k == "versions" {
+				if k == "custom_metadata" || k == "versions" || k == "retained_versions" {
 					if diff := deep.Equal(expectedVal, v); len(diff) > 0 {
 						t.Fatalf("patched %q mismatch, diff: %#v", k, diff)
 					}
@@ -1560,3 +1727,337 @@ func TestVersionedKV_Metadata_Patch_NilsUnset(t *testing.T) {
 		t.Fatalf("expected max_versions to be unset to zero value")
 	}
 }
+
+func TestVersionedKV_Metadata_CreationSource(t *testing.T) {
+	b, storage := getBackend(t)
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/written",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "metadata/configured",
+			Data: map[string]interface{}{
+				"max_versions": 2,
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "restore/restored",
+			Data: map[string]interface{}{
+				"version": 3,
+				"data":    map[string]interface{}{"bar": "baz"},
+			},
+		},
+	}
+
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request failed, err: %s, resp %#v", req.Path, err, resp)
+		}
+	}
+
+	expected := map[string]string{
+		"written":    creationSourceAPI,
+		"configured": creationSourceAPI,
+		"restored":   creationSourceRestore,
+	}
+
+	for key, source := range expected {
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "metadata/" + key,
+			Storage:   storage,
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+		}
+
+		if resp.Data["creation_source"] != source {
+			t.Fatalf("expected creation_source %q for %s, got %#v", source, key, resp.Data["creation_source"])
+		}
+	}
+}
+
+func TestVersionedKV_Metadata_List_Depth(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for _, key := range []string{"a", "b/c", "b/d/e", "b/d/f/g", "h/i/j/k"} {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": "baz",
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	for _, tc := range []struct {
+		path     string
+		depth    int
+		expected []string
+	}{
+		{"metadata/", 0, []string{"a", "b/", "h/"}},
+		{"metadata/", 1, []string{"a", "b/", "h/"}},
+		{"metadata/", 2, []string{"a", "b/c", "b/d/", "h/i/"}},
+		{"metadata/", 3, []string{"a", "b/c", "b/d/e", "b/d/f/", "h/i/j/"}},
+		{"metadata/b/", 2, []string{"c", "d/e", "d/f/"}},
+		{"metadata/", maxListDepth, []string{"a", "b/c", "b/d/e", "b/d/f/g", "h/i/j/k"}},
+	} {
+		req := &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      tc.path,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"depth": tc.depth,
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("metadata ListOperation request failed, err: %s, resp %#v", err, resp)
+		}
+
+		if diff := deep.Equal(resp.Data["keys"], tc.expected); len(diff) > 0 {
+			t.Fatalf("unexpected keys listing %s to depth %d, diff: %#v", tc.path, tc.depth, diff)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "metadata/",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"depth": maxListDepth + 1,
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected list with too large a depth to fail, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Metadata_List_Pagination(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for _, key := range []string{"e", "b", "d", "a", "c", "f/x"} {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": "baz",
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	var after string
+	for _, expected := range [][]string{{"a", "b"}, {"c", "d"}, {"e", "f/"}} {
+		req := &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "metadata/",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"limit": 2,
+				"after": after,
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("metadata ListOperation request failed, err: %s, resp %#v", err, resp)
+		}
+
+		if diff := deep.Equal(resp.Data["keys"], expected); len(diff) > 0 {
+			t.Fatalf("unexpected keys listing after %q, diff: %#v", after, diff)
+		}
+
+		next, _ := resp.Data["next_after"].(string)
+		if expected[1] == "f/" {
+			if next != "" {
+				t.Fatalf("expected no next_after on the last page, got %q", next)
+			}
+		} else if next != expected[1] {
+			t.Fatalf("expected next_after %q, got %q", expected[1], next)
+		}
+		after = next
+	}
+
+	req := &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "metadata/",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"limit": -1,
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected list with a negative limit to fail, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Metadata_List_Filter(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for key, team := range map[string]string{"a": "payments", "b": "search", "c": "payments", "d/x": "payments", "d/y": "search"} {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "metadata/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"custom_metadata": map[string]interface{}{
+					"team": team,
+					"env":  "prod",
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("metadata CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	cases := []struct {
+		path     string
+		data     map[string]interface{}
+		expected []string
+	}{
+		{"metadata/", map[string]interface{}{"filter": "custom_metadata.team=payments"}, []string{"a", "c"}},
+		{"metadata/", map[string]interface{}{"filter": "custom_metadata.team=payments,custom_metadata.env=prod", "limit": 1}, []string{"a"}},
+		{"metadata/", map[string]interface{}{"filter": "custom_metadata.team=payments", "depth": 2}, []string{"a", "c", "d/x"}},
+		{"metadata/d/", map[string]interface{}{"filter": "custom_metadata.team=search"}, []string{"y"}},
+		{"metadata/", map[string]interface{}{"filter": "custom_metadata.team=billing"}, []string{}},
+	}
+	for _, tc := range cases {
+		req := &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      tc.path,
+			Storage:   storage,
+			Data:      tc.data,
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("metadata ListOperation request failed, err: %s, resp %#v", err, resp)
+		}
+
+		keys, _ := resp.Data["keys"].([]string)
+		if len(keys) == 0 && len(tc.expected) == 0 {
+			continue
+		}
+		if diff := deep.Equal(keys, tc.expected); len(diff) > 0 {
+			t.Fatalf("unexpected keys listing %s with %v, diff: %#v", tc.path, tc.data, diff)
+		}
+	}
+
+	for _, filter := range []string{"team=payments", "custom_metadata.team", "custom_metadata.=payments"} {
+		req := &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "metadata/",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"filter": filter,
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != logical.ErrInvalidRequest {
+			t.Fatalf("expected list with filter %q to fail, err: %s, resp %#v", filter, err, resp)
+		}
+	}
+}
+
+func TestVersionedKV_Metadata_CAS(t *testing.T) {
+	b, storage := getBackend(t)
+
+	metadataRequest := func(op logical.Operation, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      "metadata/foo",
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+
+	// Metadata that was never written has a metadata_version of 0
+	resp, err := metadataRequest(logical.CreateOperation, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"owner": "controller-a"},
+		"metadata_cas":    0,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	// A second writer that read the metadata before the first write loses
+	resp, err = metadataRequest(logical.UpdateOperation, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"owner": "controller-b"},
+		"metadata_cas":    0,
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a write with a stale metadata_cas to fail, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = metadataRequest(logical.PatchOperation, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"team": "a"},
+		"metadata_cas":    1,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata PatchOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = metadataRequest(logical.PatchOperation, map[string]interface{}{
+		"custom_metadata": map[string]interface{}{"team": "b"},
+		"metadata_cas":    1,
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a patch with a stale metadata_cas to fail, err: %s, resp %#v", err, resp)
+	}
+
+	// Writes without metadata_cas still apply, and increment the version
+	resp, err = metadataRequest(logical.PatchOperation, map[string]interface{}{
+		"max_versions": 5,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata PatchOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = metadataRequest(logical.ReadOperation, nil)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["metadata_version"] != uint64(3) {
+		t.Fatalf("unexpected metadata_version: %#v", resp.Data["metadata_version"])
+	}
+	customMetadata := resp.Data["custom_metadata"].(map[string]string)
+	if customMetadata["owner"] != "controller-a" || customMetadata["team"] != "a" {
+		t.Fatalf("unexpected custom_metadata: %#v", customMetadata)
+	}
+}
@@ -0,0 +1,514 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+// The operations of the transform of a schema migration
+const (
+	transformOpSet        = "set"
+	transformOpSetDefault = "set_default"
+	transformOpRename     = "rename"
+	transformOpCopy       = "copy"
+	transformOpRemove     = "remove"
+)
+
+// maxFailedMigrationKeys is the number of keys that failed to migrate named
+// in the error of a schema migration job.
+const maxFailedMigrationKeys = 10
+
+// transformOp is an operation of the transform of a schema migration. The
+// transform is a list of operations on the fields of the data rather than an
+// expression language, so that it cannot do anything but rewrite the data.
+type transformOp struct {
+	// Op is the operation, one of set, set_default, rename, copy or remove.
+	Op string `mapstructure:"op"`
+
+	// Field is the path of the field, its segments separated by slashes to
+	// reach the fields of nested objects.
+	Field string `mapstructure:"field"`
+
+	// To is the path of the field renamed or copied to.
+	To string `mapstructure:"to"`
+
+	// Value is the value set by set and set_default.
+	Value interface{} `mapstructure:"value"`
+}
+
+// parseTransform parses the transform of a schema migration.
+func parseTransform(raw []interface{}) ([]*transformOp, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("transform must contain at least one operation")
+	}
+
+	ops := make([]*transformOp, 0, len(raw))
+	for i, rawOp := range raw {
+		op := &transformOp{}
+		if err := mapstructure.WeakDecode(rawOp, op); err != nil {
+			return nil, fmt.Errorf("error parsing transform operation %d: %w", i, err)
+		}
+
+		switch {
+		case op.Field == "":
+			return nil, fmt.Errorf("transform operation %d: missing field", i)
+		case op.Op == transformOpSet || op.Op == transformOpSetDefault:
+			if op.Value == nil {
+				return nil, fmt.Errorf("transform operation %d: %s requires a value", i, op.Op)
+			}
+		case op.Op == transformOpRename || op.Op == transformOpCopy:
+			if op.To == "" || op.To == op.Field {
+				return nil, fmt.Errorf("transform operation %d: %s requires a different to field", i, op.Op)
+			}
+		case op.Op == transformOpRemove:
+		default:
+			return nil, fmt.Errorf("transform operation %d: unsupported operation %q, must be %q, %q, %q, %q or %q", i, op.Op,
+				transformOpSet, transformOpSetDefault, transformOpRename, transformOpCopy, transformOpRemove)
+		}
+
+		if op.Value != nil {
+			value, err := normalizeJSONValue(op.Value)
+			if err != nil {
+				return nil, fmt.Errorf("transform operation %d: %w", i, err)
+			}
+			op.Value = value
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// applyTransform applies the operations in order to the data of a key.
+// Renaming, copying or removing a missing field is a no-op, so that the
+// transform applies to data that lacks optional fields.
+func applyTransform(data map[string]interface{}, ops []*transformOp) error {
+	for i, op := range ops {
+		if err := op.apply(data); err != nil {
+			return fmt.Errorf("transform operation %d on field %q: %w", i, op.Field, err)
+		}
+	}
+	return nil
+}
+
+func (op *transformOp) apply(data map[string]interface{}) error {
+	switch op.Op {
+	case transformOpSet, transformOpSetDefault:
+		parent, name, err := transformField(data, op.Field, true)
+		if err != nil {
+			return err
+		}
+		if _, exists := parent[name]; exists && op.Op == transformOpSetDefault {
+			return nil
+		}
+		// Objects set are copied, so that later operations on their fields
+		// do not change the value set on other keys
+		parent[name], err = normalizeJSONValue(op.Value)
+		return err
+	}
+
+	parent, name, err := transformField(data, op.Field, false)
+	if err != nil || parent == nil {
+		return err
+	}
+	value, exists := parent[name]
+	if !exists {
+		return nil
+	}
+
+	if op.Op == transformOpRename || op.Op == transformOpRemove {
+		delete(parent, name)
+	}
+	if op.Op == transformOpRename || op.Op == transformOpCopy {
+		to, toName, err := transformField(data, op.To, true)
+		if err != nil {
+			return err
+		}
+		if to[toName], err = normalizeJSONValue(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transformField returns the object holding the field at the path and the
+// name of the field. The missing objects along the path are created if
+// create is set, otherwise a nil object is returned.
+func transformField(data map[string]interface{}, field string, create bool) (map[string]interface{}, string, error) {
+	segments := strings.Split(field, "/")
+	parent := data
+	for _, segment := range segments[:len(segments)-1] {
+		next, ok := parent[segment].(map[string]interface{})
+		if !ok {
+			if _, exists := parent[segment]; exists {
+				return nil, "", fmt.Errorf("%q is not an object", segment)
+			}
+			if !create {
+				return nil, "", nil
+			}
+			next = map[string]interface{}{}
+			parent[segment] = next
+		}
+		parent = next
+	}
+	return parent, segments[len(segments)-1], nil
+}
+
+// pathMigrateSchema returns the path configuration for the schema migration
+// endpoint
+func pathMigrateSchema(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "migrate/schema$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "migrate",
+			OperationSuffix: "schema",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "If set, only the keys under this folder are migrated.",
+			},
+			"from_schema_version": {
+				Type:        framework.TypeInt,
+				Description: "The schema_version of the keys to migrate. Keys without a schema_version have 0.",
+				Required:    true,
+			},
+			"to_schema_version": {
+				Type:        framework.TypeInt,
+				Description: "The schema_version set on the migrated keys.",
+				Required:    true,
+			},
+			"transform": {
+				Type: framework.TypeSlice,
+				Description: `
+The operations applied in order to the data of each key. Each operation is an
+object with an "op" of set, set_default, rename, copy or remove, the "field"
+it applies to, the "value" of set and set_default, and the "to" field of
+rename and copy. Fields of nested objects are reached by separating the
+segments of the field with slashes.`,
+				Required: true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathMigrateSchemaWrite()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"job_id": {
+								Type:        framework.TypeString,
+								Description: "The ID of the job running the migration.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    migrateSchemaHelpSyn,
+		HelpDescription: migrateSchemaHelpDesc,
+	}
+}
+
+// schemaMigration is a migration of the keys of a schema_version run by a
+// job.
+type schemaMigration struct {
+	prefix    string
+	from, to  uint64
+	transform []*transformOp
+}
+
+func (b *versionedKVBackend) pathMigrateSchemaWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		from := data.Get("from_schema_version").(int)
+		to := data.Get("to_schema_version").(int)
+		switch {
+		case from < 0 || to < 0:
+			return logical.ErrorResponse("schema versions cannot be negative"), logical.ErrInvalidRequest
+		case from == to:
+			return logical.ErrorResponse("to_schema_version must differ from from_schema_version"), logical.ErrInvalidRequest
+		}
+
+		transform, err := parseTransform(data.Get("transform").([]interface{}))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		prefix := strings.Trim(data.Get("path").(string), "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+
+		migration := &schemaMigration{
+			prefix:    prefix,
+			from:      uint64(from),
+			to:        uint64(to),
+			transform: transform,
+		}
+
+		// The job outlives the request, so it does not run with its context
+		run, jobCtx, err := b.startJob(context.Background(), req.Storage, "schema-migration")
+		if err != nil {
+			return nil, err
+		}
+
+		go func() {
+			run.finish(b.migrateSchema(jobCtx, run.s, run, migration))
+		}()
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"job_id": run.job.Id,
+			},
+		}, nil
+	}
+}
+
+// migrateSchema migrates the keys of the schema_version of the migration.
+// Keys that fail to migrate are left unchanged and named in the returned
+// error once the other keys are migrated.
+func (b *versionedKVBackend) migrateSchema(ctx context.Context, s logical.Storage, run *jobRun, migration *schemaMigration) error {
+	var keys []string
+	if err := b.walkKeys(ctx, s, migration.prefix, func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}); err != nil {
+		return err
+	}
+	sort.Strings(keys)
+
+	var failed []string
+	for i, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := b.migrateKeySchema(ctx, s, key, migration); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+			b.Logger().Warn("error migrating the schema of key", "key", key, "error", err)
+			failed = append(failed, key)
+		}
+		run.progress(uint64(i+1), uint64(len(keys)))
+	}
+
+	if len(failed) > 0 {
+		named := failed
+		if len(named) > maxFailedMigrationKeys {
+			named = named[:maxFailedMigrationKeys]
+		}
+		return fmt.Errorf("%d keys could not be migrated: %s", len(failed), strings.Join(named, ", "))
+	}
+	return nil
+}
+
+// migrateKeySchema writes the transformed data of the current version of the
+// key as a new version and sets its schema_version. Keys of another
+// schema_version are left unchanged, as are keys whose current version is
+// deleted or destroyed. It returns an error if the key is locked.
+func (b *versionedKVBackend) migrateKeySchema(ctx context.Context, s logical.Storage, key string, migration *schemaMigration) error {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil {
+		return err
+	}
+	if meta == nil || meta.SchemaVersion != migration.from {
+		return nil
+	}
+	if meta.isLocked() {
+		return errors.New("the key is locked")
+	}
+
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := b.destroyDueVersions(ctx, s, config, meta, b.now()); err != nil {
+		return err
+	}
+
+	vm := meta.Versions[meta.CurrentVersion]
+	if vm == nil || vm.Destroyed {
+		return nil
+	}
+	if vm.DeletionTime != nil {
+		deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+		if err != nil {
+			return err
+		}
+		if deletionTime.Before(b.now()) {
+			return nil
+		}
+	}
+
+	version, err := b.getVersion(ctx, s, key, meta.CurrentVersion)
+	if err != nil {
+		return err
+	}
+	if version == nil {
+		return errors.New("the current version is missing")
+	}
+
+	previousData, err := b.versionData(version)
+	if err != nil {
+		return err
+	}
+	versionData, err := b.versionData(version)
+	if err != nil {
+		return err
+	}
+	if err := applyTransform(versionData, migration.transform); err != nil {
+		return err
+	}
+
+	meta.SchemaVersion = migration.to
+	meta.MetadataVersion++
+
+	// Data the transform left unchanged only has its schema_version set
+	if reflect.DeepEqual(versionData, previousData) {
+		sequence := meta.nextEventSequence()
+		if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+			return err
+		}
+		b.emitKeyEvent(ctx, s, meta, sequence, "metadata-write", "metadata/"+key, "metadata/"+key, true,
+			"from_schema_version", fmt.Sprintf("%d", migration.from),
+			"to_schema_version", fmt.Sprintf("%d", migration.to),
+		)
+		return nil
+	}
+
+	marshaledData, err := json.Marshal(versionData)
+	if err != nil {
+		return err
+	}
+
+	versionKey, err := b.getVersionKey(ctx, key, meta.CurrentVersion+1, s)
+	if err != nil {
+		return err
+	}
+	newVersion := &Version{
+		Data:        marshaledData,
+		CreatedTime: b.timestampNow(),
+		Key:         key,
+		Version:     meta.CurrentVersion + 1,
+	}
+
+	hash := contentHash(marshaledData)
+	if shared := meta.sharedDataVersion(hash); shared != 0 && config.DeduplicateVersionData {
+		newVersion.Data = nil
+		newVersion.DataVersion = shared
+	}
+
+	ctime, err := ptypes.Timestamp(newVersion.CreatedTime)
+	if err != nil {
+		return err
+	}
+	if dtime, ok := versionDeletionTime(ctime, config, meta, nil); ok {
+		newVersion.DeletionTime, err = ptypes.TimestampProto(dtime)
+		if err != nil {
+			return err
+		}
+	}
+
+	buf, err := proto.Marshal(newVersion)
+	if err != nil {
+		return err
+	}
+	if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+		return err
+	}
+
+	newVM, versionToDelete := meta.addVersionAt(meta.CurrentVersion+1, newVersion.CreatedTime, newVersion.DeletionTime, config.MaxVersions, config.RetainPrunedVersions, b.now())
+	newVM.ContentHash = hash
+	newVM.CorrelationId = contextCorrelationID(ctx)
+	newVM.DataVersion = newVersion.DataVersion
+	newVM.DataBytes = uint64(len(marshaledData))
+	if err := checkKeyBytes(config, meta); err != nil {
+		return err
+	}
+	sequence := meta.nextEventSequence()
+
+	if err := s.Put(ctx, &logical.StorageEntry{
+		Key:   versionKey,
+		Value: buf,
+	}); err != nil {
+		return err
+	}
+
+	if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+		b.deleteOrphanedVersions(ctx, s, meta.Key, versionKey)
+		return err
+	}
+
+	if warning := b.cleanupOldVersions(ctx, s, key, meta, versionToDelete); warning != "" {
+		b.Logger().Warn(warning, "key", key)
+	}
+
+	b.emitKeyEvent(ctx, s, meta, sequence, "schema-migrate", "data/"+key, "data/"+key, true,
+		"version", fmt.Sprintf("%d", meta.CurrentVersion),
+		"from_schema_version", fmt.Sprintf("%d", migration.from),
+		"to_schema_version", fmt.Sprintf("%d", migration.to),
+	)
+	return nil
+}
+
+const migrateSchemaHelpSyn = `Migrates the data of the keys of a schema_version.`
+const migrateSchemaHelpDesc = `
+This endpoint starts a job that rewrites the data of every key whose
+"schema_version" is "from_schema_version", writing the result as a new
+version of the key and setting its schema_version to "to_schema_version".
+Set "path" to only migrate the keys under a folder. The progress of the job is
+read from jobs/<job_id>, and the job can be canceled through it.
+
+The "transform" is a list of operations applied in order to the data of the
+current version of each key:
+
+  - set sets "field" to "value".
+  - set_default sets "field" to "value" if the field does not exist.
+  - rename moves "field" to the "to" field.
+  - copy copies "field" to the "to" field.
+  - remove removes "field".
+
+Renaming, copying or removing a field that does not exist does nothing. For
+example, this transform moves the "user" field into a "credentials" object
+and adds a "rotation" field:
+
+  [{"op": "rename", "field": "user", "to": "credentials/user"},
+   {"op": "set_default", "field": "rotation", "value": "manual"}]
+
+A key whose data the transform leaves unchanged only has its schema_version
+set, without a new version, and sends a kv-v2/metadata-write event. Keys whose current version is deleted or
+destroyed are left unchanged. Locked keys, and keys the transform fails on,
+such as when a field expected to be an object is not, are left unchanged and
+named in the error the job fails with once the other keys are migrated. Each
+migrated key sends a kv-v2/schema-migrate event.
+
+This endpoint requires sudo capability, as it rewrites keys regardless of the
+policies on their paths.
+`
@@ -0,0 +1,296 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestApplyTransform(t *testing.T) {
+	transform, err := parseTransform([]interface{}{
+		map[string]interface{}{"op": "rename", "field": "user", "to": "credentials/user"},
+		map[string]interface{}{"op": "copy", "field": "host", "to": "backup/host"},
+		map[string]interface{}{"op": "set_default", "field": "rotation", "value": "manual"},
+		map[string]interface{}{"op": "set_default", "field": "port", "value": 1},
+		map[string]interface{}{"op": "set", "field": "tags/env", "value": "prod"},
+		map[string]interface{}{"op": "remove", "field": "legacy"},
+		map[string]interface{}{"op": "remove", "field": "missing/field"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]interface{}{}
+	if err := jsonutil.DecodeJSON([]byte(`{"user": "admin", "host": "db", "port": 5432, "legacy": true}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyTransform(data, transform); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{}
+	if err := jsonutil.DecodeJSON([]byte(`{
+		"credentials": {"user": "admin"},
+		"host": "db",
+		"backup": {"host": "db"},
+		"rotation": "manual",
+		"port": 5432,
+		"tags": {"env": "prod"}
+	}`), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("unexpected transformed data: %#v", data)
+	}
+
+	// A field expected to be an object that is not fails the transform
+	data = map[string]interface{}{"tags": "none"}
+	if err := applyTransform(data, transform); err == nil || !strings.Contains(err.Error(), "is not an object") {
+		t.Fatalf("expected an error, got: %v", err)
+	}
+
+	for _, raw := range []map[string]interface{}{
+		{"op": "set", "field": "a"},
+		{"op": "rename", "field": "a"},
+		{"op": "copy", "field": "a", "to": "a"},
+		{"op": "remove"},
+		{"op": "eval", "field": "a"},
+	} {
+		if _, err := parseTransform([]interface{}{raw}); err == nil {
+			t.Fatalf("expected an error parsing %v", raw)
+		}
+	}
+	if _, err := parseTransform(nil); err == nil {
+		t.Fatal("expected an error parsing an empty transform")
+	}
+}
+
+func TestVersionedKV_MigrateSchema(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	if !strutil.StrListContains(b.SpecialPaths().Root, "migrate/*") {
+		t.Fatalf("expected migrations to require sudo: %v", b.SpecialPaths().Root)
+	}
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", operation, path, err, resp)
+		}
+		return resp
+	}
+
+	keys := map[string]map[string]interface{}{
+		"app/a":    {"user": "a"},
+		"app/b":    {"user": "b", "rotation": "auto"},
+		"app/c":    {"rotation": "manual"},
+		"app/d":    {"user": "d"},
+		"app/e":    {"user": "e"},
+		"app/f":    {"user": "f"},
+		"other/g":  {"user": "g"},
+		"app/done": {"credentials": map[string]interface{}{"user": "done"}},
+	}
+	for key, data := range keys {
+		request(logical.UpdateOperation, "data/"+key, map[string]interface{}{"data": data})
+		request(logical.UpdateOperation, "metadata/"+key, map[string]interface{}{"schema_version": 1})
+	}
+	request(logical.UpdateOperation, "metadata/app/done", map[string]interface{}{"schema_version": 2})
+	request(logical.UpdateOperation, "metadata/app/d", map[string]interface{}{"locked": true})
+	request(logical.DeleteOperation, "data/app/e", nil)
+	request(logical.UpdateOperation, "data/app/f", map[string]interface{}{"data": map[string]interface{}{"user": "f", "credentials": "none"}})
+
+	resp := request(logical.ReadOperation, "metadata/app/a", nil)
+	schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route("metadata/app/a"), logical.ReadOperation), resp, true)
+	if resp.Data["schema_version"] != uint64(1) {
+		t.Fatalf("expected schema_version 1, got: %#v", resp.Data["schema_version"])
+	}
+
+	events.eventsProcessed = nil
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "migrate/schema",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"path":                "app",
+			"from_schema_version": 1,
+			"to_schema_version":   2,
+			"transform": []interface{}{
+				map[string]interface{}{"op": "rename", "field": "user", "to": "credentials/user"},
+				map[string]interface{}{"op": "set_default", "field": "rotation", "value": "manual"},
+			},
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("migration failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route(req.Path), req.Operation), resp, true)
+
+	job := waitForJob(t, b, storage, resp.Data["job_id"].(string))
+	if job["status"] != jobStatusFailed {
+		t.Fatalf("expected the migration to fail on the locked key, got: %#v", job)
+	}
+	if errMsg := job["error"].(string); !strings.HasPrefix(errMsg, "2 keys could not be migrated: app/d, app/f") {
+		t.Fatalf("unexpected job error: %q", errMsg)
+	}
+	if job["processed"] != uint64(7) || job["total"] != uint64(7) {
+		t.Fatalf("unexpected job progress: %#v", job)
+	}
+
+	for key, expected := range map[string]struct {
+		data          string
+		version       int
+		schemaVersion uint64
+	}{
+		"app/a":    {`{"credentials": {"user": "a"}, "rotation": "manual"}`, 2, 2},
+		"app/b":    {`{"credentials": {"user": "b"}, "rotation": "auto"}`, 2, 2},
+		"app/c":    {`{"rotation": "manual"}`, 1, 2},
+		"app/d":    {`{"user": "d"}`, 1, 1},
+		"app/f":    {`{"credentials": "none", "user": "f"}`, 2, 1},
+		"other/g":  {`{"user": "g"}`, 1, 1},
+		"app/done": {`{"credentials": {"user": "done"}}`, 1, 2},
+	} {
+		resp := request(logical.ReadOperation, "data/"+key, nil)
+		var expectedData map[string]interface{}
+		if err := jsonutil.DecodeJSON([]byte(expected.data), &expectedData); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := json.Marshal(resp.Data["data"])
+		want, _ := json.Marshal(expectedData)
+		if string(got) != string(want) {
+			t.Fatalf("unexpected data of %s: %s", key, got)
+		}
+		if v := resp.Data["metadata"].(map[string]interface{})["version"]; v != uint64(expected.version) {
+			t.Fatalf("expected version %d of %s, got %v", expected.version, key, v)
+		}
+
+		resp = request(logical.ReadOperation, "metadata/"+key, nil)
+		if resp.Data["schema_version"] != expected.schemaVersion {
+			t.Fatalf("expected schema_version %d of %s, got %v", expected.schemaVersion, key, resp.Data["schema_version"])
+		}
+	}
+
+	// The deleted key is left unchanged
+	resp = request(logical.ReadOperation, "metadata/app/e", nil)
+	if resp.Data["schema_version"] != uint64(1) || resp.Data["current_version"] != uint64(1) {
+		t.Fatalf("expected the deleted key to be unchanged: %#v", resp.Data)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/schema-migrate", "data/app/a", "data/app/a"},
+		{"kv-v2/schema-migrate", "data/app/b", "data/app/b"},
+		{"kv-v2/metadata-write", "metadata/app/c", "metadata/app/c"},
+	})
+	metadata := events.eventsProcessed[0].Event.Metadata.Fields
+	if metadata["version"].GetStringValue() != "2" || metadata["to_schema_version"].GetStringValue() != "2" {
+		t.Fatalf("unexpected event metadata: %v", metadata)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"from_schema_version": 1, "to_schema_version": 1, "transform": []interface{}{map[string]interface{}{"op": "remove", "field": "a"}}},
+		{"from_schema_version": -1, "to_schema_version": 1, "transform": []interface{}{map[string]interface{}{"op": "remove", "field": "a"}}},
+		{"from_schema_version": 1, "to_schema_version": 2, "transform": []interface{}{}},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "migrate/schema",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an invalid request for %v, err: %v, resp: %#v", data, err, resp)
+		}
+	}
+}
+
+func TestVersionedKV_MetadataSchemaVersion(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	for _, op := range []logical.Operation{logical.UpdateOperation, logical.PatchOperation} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: op,
+			Path:      "metadata/foo",
+			Storage:   storage,
+			Data:      map[string]interface{}{"schema_version": -1},
+		})
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected a negative schema_version to be rejected by %s, err: %v, resp: %#v", op, err, resp)
+		}
+	}
+
+	// The update creates the key that the patch then changes
+	for _, tc := range []struct {
+		op            logical.Operation
+		schemaVersion int
+	}{
+		{logical.UpdateOperation, 3},
+		{logical.PatchOperation, 4},
+	} {
+		op, schemaVersion := tc.op, tc.schemaVersion
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: op,
+			Path:      "metadata/foo",
+			Storage:   storage,
+			Data:      map[string]interface{}{"schema_version": schemaVersion},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request failed, err: %s, resp %#v", op, err, resp)
+		}
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "metadata/foo",
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("metadata read failed, err: %s, resp %#v", err, resp)
+		}
+		if resp.Data["schema_version"] != uint64(schemaVersion) {
+			t.Fatalf("expected schema_version %d, got %v", schemaVersion, resp.Data["schema_version"])
+		}
+	}
+}
+
+// waitForJob waits for the job to stop running and returns its state.
+func waitForJob(t *testing.T, b logical.Backend, s logical.Storage, id string) map[string]interface{} {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "jobs/" + id,
+			Storage:   s,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("jobs ReadOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		if resp.Data["status"] != jobStatusRunning {
+			return resp.Data
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not finish", id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
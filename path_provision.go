@@ -0,0 +1,361 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathProvision returns the path configuration for the provision endpoint
+func pathProvision(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "provision/" + matchAllNoTrailingSlashRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "provision",
+			OperationSuffix: "secret",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+			"cas_required": {
+				Type: framework.TypeBool,
+				Description: `
+If true the key will require the cas parameter to be set on all write requests.
+If false, the backend’s configuration will be used.`,
+			},
+			"max_versions": {
+				Type: framework.TypeInt,
+				Description: `
+The number of versions to keep. If not set, the backend’s configured max
+version is used.`,
+			},
+			"delete_version_after": {
+				Type: framework.TypeDurationSecond,
+				Description: `
+The length of time before a version is deleted. If not set, the backend's
+configured delete_version_after is used. Cannot be greater than the
+backend's delete_version_after, nor than its max_delete_version_after. A zero
+duration clears the current setting. A negative duration will cause an error.
+`,
+			},
+			"custom_metadata": {
+				Type: framework.TypeMap,
+				Description: `
+User-provided key-value pairs that are used to describe arbitrary and
+version-agnostic information about a secret.
+`,
+			},
+			"options": {
+				Type: framework.TypeMap,
+				Description: `Options for writing a KV entry.
+
+Set the "cas" value to use a Check-And-Set operation. The check is made
+against the key metadata as it was before this request.
+
+Set the "delete_version_after" value to a duration to override the
+delete_version_after of the mount and the key for the new version only. A zero
+duration keeps the version until it is deleted. It cannot be greater than the
+max_delete_version_after of the mount.`,
+			},
+			"data": {
+				Type:        framework.TypeMap,
+				Description: "The contents of the data map will be stored and returned on read.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.mirrorWrites(b.pathProvisionWrite())),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"version": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"created_time": {
+								Type:     framework.TypeTime,
+								Required: true,
+							},
+							"deletion_time": {
+								Type:     framework.TypeString,
+								Required: true,
+							},
+							"destroyed": {
+								Type:     framework.TypeBool,
+								Required: true,
+							},
+							"custom_metadata": {
+								Type:     framework.TypeMap,
+								Required: true,
+							},
+							"max_versions": {
+								Type:        framework.TypeInt64, // uint32
+								Description: "The number of versions to keep",
+								Required:    true,
+							},
+							"cas_required": {
+								Type:     framework.TypeBool,
+								Required: true,
+							},
+							"delete_version_after": {
+								Type:        framework.TypeDurationSecond,
+								Description: "The length of time before a version is deleted.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    provisionHelpSyn,
+		HelpDescription: provisionHelpDesc,
+	}
+}
+
+// pathProvisionWrite writes the settings of a key's metadata and a new
+// version of its data while holding the key's lock, so that neither is
+// persisted if the request fails.
+func (b *versionedKVBackend) pathProvisionWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := data.Get("path").(string)
+		if key == "" {
+			return logical.ErrorResponse("missing path"), nil
+		}
+		if err := checkDurationFields(data, "delete_version_after"); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		dataRaw, ok := data.GetOk("data")
+		if !ok {
+			return logical.ErrorResponse("no data provided"), logical.ErrInvalidRequest
+		}
+		marshaledData, err := json.Marshal(dataRaw.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+
+		maxRaw, mOk := data.GetOk("max_versions")
+		casRaw, cOk := data.GetOk("cas_required")
+		deleteVersionAfterRaw, dvaOk := data.GetOk("delete_version_after")
+		customMetadataRaw, cmOk := data.GetOk("custom_metadata")
+
+		var customMetadataMap map[string]string
+		if cmOk {
+			customMetadataMap, err = parseCustomMetadata(customMetadataRaw.(map[string]interface{}), false)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("%s: %s", customMetadataValidationErrorPrefix, err.Error())), nil
+			}
+
+			customMetadataErrs := validateCustomMetadata(customMetadataMap)
+			if customMetadataErrs != nil {
+				return logical.ErrorResponse(customMetadataErrs.Error()), nil
+			}
+		}
+
+		opts, err := parseDataOptions(data)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.checkNoTemplate(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.checkNoArrayOps(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		optionsWarning, err := opts.checkUnknown(config)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.validateDeleteVersionAfter(config); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		if dvaOk {
+			dva := time.Duration(deleteVersionAfterRaw.(int)) * time.Second
+			if err := config.validateDeleteVersionAfter("delete_version_after", dva); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.Lock()
+		defer lock.Unlock()
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			now := b.timestampNow()
+			meta = &KeyMetadata{
+				Key:            key,
+				Versions:       map[uint64]*VersionMetadata{},
+				CreatedTime:    now,
+				UpdatedTime:    now,
+				CreationSource: creationSourceAPI,
+			}
+		}
+		if meta.isLocked() {
+			return lockedDenied(key, "provisioning a new version")
+		}
+
+		err = validateAllowedOptions(opts, config, key)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+
+		err = validateCheckAndSetOption(opts, config, meta)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		if mOk {
+			meta.MaxVersions = uint32(maxRaw.(int))
+		}
+		if cOk {
+			meta.CasRequired = casRaw.(bool)
+		}
+		if dvaOk {
+			meta.DeleteVersionAfter = ptypes.DurationProto(time.Duration(deleteVersionAfterRaw.(int)) * time.Second)
+		}
+		if cmOk {
+			meta.CustomMetadata = customMetadataMap
+		}
+		if mOk || cOk || dvaOk || cmOk {
+			meta.MetadataVersion++
+		}
+
+		versionKey, err := b.getVersionKey(ctx, key, meta.CurrentVersion+1, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		version := &Version{
+			Data:        marshaledData,
+			CreatedTime: b.timestampNow(),
+			Key:         key,
+			Version:     meta.CurrentVersion + 1,
+		}
+
+		ctime, err := ptypes.Timestamp(version.CreatedTime)
+		if err != nil {
+			return logical.ErrorResponse("unexpected error converting %T(%v) to time.Time: %v", version.CreatedTime, version.CreatedTime, err), logical.ErrInvalidRequest
+		}
+
+		if dtime, ok := versionDeletionTime(ctime, config, meta, opts.deleteVersionAfter); ok {
+			dt, err := ptypes.TimestampProto(dtime)
+			if err != nil {
+				return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
+			}
+			version.DeletionTime = dt
+		}
+
+		buf, err := proto.Marshal(version)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+			return nil, err
+		}
+
+		// The metadata settings and the new version are persisted together,
+		// the version data is not readable until the metadata is written.
+		vm, versionToDelete := meta.addVersionAt(meta.CurrentVersion+1, version.CreatedTime, version.DeletionTime, config.MaxVersions, config.RetainPrunedVersions, b.now())
+		vm.DeleteVersionAfter = opts.deleteVersionAfter
+		vm.CorrelationId = opts.versionCorrelationID(ctx)
+		vm.DataBytes = uint64(len(marshaledData))
+		if err := checkKeyBytes(config, meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		sequence := meta.nextEventSequence()
+
+		if err := req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   versionKey,
+			Value: buf,
+		}); err != nil {
+			return nil, err
+		}
+
+		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		if err != nil {
+			b.deleteOrphanedVersions(ctx, req.Storage, meta.Key, versionKey)
+			return nil, err
+		}
+		queueMirror(ctx, key)
+
+		var dva time.Duration
+		if meta.GetDeleteVersionAfter() != nil {
+			dva, err = ptypes.Duration(meta.GetDeleteVersionAfter())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"version":              meta.CurrentVersion,
+				"created_time":         ptypesTimestampToString(vm.CreatedTime),
+				"deletion_time":        ptypesTimestampToString(vm.DeletionTime),
+				"destroyed":            vm.Destroyed,
+				"custom_metadata":      meta.CustomMetadata,
+				"max_versions":         meta.MaxVersions,
+				"cas_required":         meta.CasRequired,
+				"delete_version_after": dva.String(),
+			},
+		}
+
+		if cOk && config.CasRequired && !casRaw.(bool) {
+			resp.AddWarning("\"cas_required\" set to false, but is mandated by backend config. This value will be ignored.")
+		}
+		if optionsWarning != "" {
+			resp.AddWarning(optionsWarning)
+		}
+
+		warning := b.cleanupOldVersions(ctx, req.Storage, key, meta, versionToDelete)
+		if warning != "" {
+			resp.AddWarning(warning)
+		}
+
+		b.emitKeyEvent(ctx, req.Storage, meta, sequence, "provision", "provision/"+key, "data/"+key, true,
+			"version", fmt.Sprintf("%d", meta.CurrentVersion),
+			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+		)
+		return resp, nil
+	}
+}
+
+const provisionHelpSyn = `Writes the settings and a new version of a secret in a single request.`
+const provisionHelpDesc = `
+This endpoint combines a write to the metadata endpoint with a write to the
+data endpoint. The key metadata settings ("cas_required", "max_versions",
+"delete_version_after" and "custom_metadata") are applied and a new version
+of the data is written while holding the lock of the key, so provisioning a
+secret cannot partially fail leaving the settings written without the data.
+
+Settings that are not provided are left unchanged. The "cas" option is
+checked against the key as it was before the request. The response contains
+the metadata of the new version along with the resulting key settings.
+`
@@ -0,0 +1,108 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Provision(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "provision/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_versions":         2,
+			"cas_required":         true,
+			"delete_version_after": "1h",
+			"custom_metadata": map[string]interface{}{
+				"owner": "team-a",
+			},
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("provision request failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(
+		t,
+		schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+		resp,
+		true,
+	)
+
+	expected := map[string]interface{}{
+		"version":              uint64(1),
+		"max_versions":         uint32(2),
+		"cas_required":         true,
+		"delete_version_after": "1h0m0s",
+		"custom_metadata":      map[string]string{"owner": "team-a"},
+	}
+	for k, v := range expected {
+		if diff := deep.Equal(resp.Data[k], v); len(diff) > 0 {
+			t.Fatalf("unexpected %s in provision response, diff: %#v", k, diff)
+		}
+	}
+	if resp.Data["deletion_time"] == "" {
+		t.Fatalf("expected deletion_time to be set, resp: %#v", resp)
+	}
+
+	// The key now requires cas, so provisioning without it must fail and
+	// leave the settings unchanged
+	req.Data["max_versions"] = 5
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected provision without cas to fail, err: %s, resp %#v", err, resp)
+	}
+
+	metaReq := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), metaReq)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata read failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["max_versions"] != uint32(2) || resp.Data["current_version"] != uint64(1) {
+		t.Fatalf("expected metadata to be unchanged, resp: %#v", resp)
+	}
+
+	req.Data["options"] = map[string]interface{}{
+		"cas": 1,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("provision request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["version"] != uint64(2) || resp.Data["max_versions"] != uint32(5) {
+		t.Fatalf("unexpected provision response: %#v", resp)
+	}
+
+	dataReq := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), dataReq)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data read failed, err: %s, resp %#v", err, resp)
+	}
+	if diff := deep.Equal(resp.Data["data"], map[string]interface{}{"bar": "baz"}); len(diff) > 0 {
+		t.Fatalf("unexpected data, diff: %#v", diff)
+	}
+}
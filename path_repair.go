@@ -0,0 +1,304 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathRepairRebuildIndex returns the path configuration for the endpoint
+// rebuilding the key metadata from the version entries
+func pathRepairRebuildIndex(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "repair/rebuild-index",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "rebuild",
+			OperationSuffix: "index",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"confirm": {
+				Type:        framework.TypeBool,
+				Description: "Confirms the rebuild. Must be set to true unless preview is set.",
+			},
+			"preview": {
+				Type:        framework.TypeBool,
+				Description: "If true, the keys whose metadata would be rebuilt are returned without changing anything.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathRepairRebuildIndexWrite()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"recovered_keys": {
+								Type:        framework.TypeStringSlice,
+								Description: "The keys whose metadata was rebuilt, or would be rebuilt if preview is set.",
+								Required:    true,
+							},
+							"job_id": {
+								Type:        framework.TypeString,
+								Description: "The ID of the job that ran the rebuild, see the jobs path.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    repairRebuildIndexHelpSyn,
+		HelpDescription: repairRebuildIndexHelpDesc,
+	}
+}
+
+// pathRepairRebuildIndexWrite scans the version entries and rebuilds the
+// metadata of the keys whose metadata is missing or cannot be decoded.
+func (b *versionedKVBackend) pathRepairRebuildIndexWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		preview := data.Get("preview").(bool)
+		if !preview && !data.Get("confirm").(bool) {
+			return logical.ErrorResponse("rebuilding the index requires the confirm parameter to be set to true"), logical.ErrInvalidRequest
+		}
+
+		run, ctx, err := b.startJob(ctx, req.Storage, "rebuild-index")
+		if err != nil {
+			return nil, err
+		}
+
+		recovered, err := b.rebuildIndex(ctx, run.s, run, preview)
+		run.finish(err)
+
+		if len(recovered) > 0 && !preview {
+			b.Logger().Warn("rebuilt key metadata from version storage", "num_keys", len(recovered))
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"recovered_keys": recovered,
+				"job_id":         run.job.Id,
+			},
+		}
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			resp.AddWarning("the job was canceled before all keys were processed")
+		case err != nil:
+			return nil, err
+		}
+
+		return resp, nil
+	}
+}
+
+// rebuildIndex rebuilds the metadata of the keys whose metadata is missing or
+// cannot be decoded, recording its progress in the job. It returns the keys
+// whose metadata was rebuilt, including when the job is canceled.
+func (b *versionedKVBackend) rebuildIndex(ctx context.Context, s logical.Storage, run *jobRun, preview bool) ([]string, error) {
+	versions, err := b.scanVersionEntries(ctx, s, func(scanned uint64) {
+		run.progress(scanned, 0)
+	})
+	if err != nil {
+		return []string{}, err
+	}
+
+	keys := make([]string, 0, len(versions))
+	for key := range versions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	recovered := []string{}
+	for i, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return recovered, err
+		}
+
+		ok, err := b.rebuildKeyMetadata(ctx, s, key, versions[key], preview)
+		if err != nil {
+			return recovered, err
+		}
+		if ok {
+			recovered = append(recovered, key)
+		}
+
+		run.progress(uint64(i+1), uint64(len(keys)))
+	}
+
+	return recovered, nil
+}
+
+// scanVersionEntries reads all version entries that record their key and
+// version, including archived entries, grouped by key. progress is called
+// with the number of entries read so far.
+func (b *versionedKVBackend) scanVersionEntries(ctx context.Context, s logical.Storage, progress func(uint64)) (map[string][]*Version, error) {
+	versions := map[string][]*Version{}
+
+	var scanned uint64
+	err := b.walkVersionEntries(ctx, s, func(storageKey string, v *Version) error {
+		scanned++
+		progress(scanned)
+
+		if v != nil && v.Key != "" && v.Version != 0 {
+			versions[v.Key] = append(versions[v.Key], v)
+		}
+		return nil
+	})
+
+	return versions, err
+}
+
+// walkVersionEntries calls fn with the storage key and the decoded value of
+// every version entry, including archived entries. The value is nil if the
+// entry cannot be decoded. Walking stops at the first error returned by fn.
+func (b *versionedKVBackend) walkVersionEntries(ctx context.Context, s logical.Storage, fn func(storageKey string, v *Version) error) error {
+	var scan func(string) error
+	scan = func(p string) error {
+		entries, err := s.List(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if strings.HasSuffix(e, "/") {
+				if err := scan(p + e); err != nil {
+					return err
+				}
+				continue
+			}
+
+			raw, err := s.Get(ctx, p+e)
+			if err != nil {
+				return err
+			}
+
+			if err := fn(p+e, decodeVersionEntry(raw)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := scan(path.Join(b.storagePrefix, versionPrefix) + "/"); err != nil {
+		return err
+	}
+	return scan(path.Join(b.storagePrefix, archivedVersionPrefix) + "/")
+}
+
+// decodeVersionEntry decodes a live or archived version entry, returning nil
+// if it is missing or cannot be decoded.
+func decodeVersionEntry(raw *logical.StorageEntry) *Version {
+	if raw == nil {
+		return nil
+	}
+
+	value, err := decompressArchivedValue(raw.Value)
+	if err != nil {
+		return nil
+	}
+
+	v := &Version{}
+	if err := proto.Unmarshal(value, v); err != nil {
+		return nil
+	}
+	return v
+}
+
+// rebuildKeyMetadata writes the metadata of a key from its version entries,
+// unless the key has decodable metadata. Returns true if the metadata was
+// rebuilt, or would be rebuilt if preview is true, in which case nothing is
+// written.
+func (b *versionedKVBackend) rebuildKeyMetadata(ctx context.Context, s logical.Storage, key string, versions []*Version, preview bool) (bool, error) {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	wrapper, err := b.getKeyEncryptor(ctx, s)
+	if err != nil {
+		return false, err
+	}
+
+	item, err := wrapper.Wrap(s).Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if item != nil && proto.Unmarshal(item.Value, &KeyMetadata{}) == nil {
+		return false, nil
+	}
+	if preview {
+		return true, nil
+	}
+
+	meta := &KeyMetadata{
+		Key:            key,
+		Versions:       map[uint64]*VersionMetadata{},
+		UpdatedTime:    b.timestampNow(),
+		CreationSource: creationSourceRecovered,
+	}
+
+	for _, v := range versions {
+		meta.Versions[v.Version] = &VersionMetadata{
+			CreatedTime:  v.CreatedTime,
+			DeletionTime: v.DeletionTime,
+			DataVersion:  v.DataVersion,
+			DataBytes:    uint64(len(v.Data)),
+		}
+
+		if v.Version > meta.CurrentVersion {
+			meta.CurrentVersion = v.Version
+		}
+		if meta.OldestVersion == 0 || v.Version < meta.OldestVersion {
+			meta.OldestVersion = v.Version
+			meta.CreatedTime = v.CreatedTime
+		}
+	}
+
+	if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+const repairRebuildIndexHelpSyn = `Rebuilds the metadata of keys from the stored version data.`
+const repairRebuildIndexHelpDesc = `
+The metadata of a key indexes its versions. If the metadata of a key is lost
+or corrupted, its versions cannot be read through the API even though their
+data is still stored.
+
+This endpoint scans the stored version data and rebuilds the metadata of every
+key whose metadata is missing or cannot be decoded. Rebuilt metadata has a
+creation_source of "recovered" and contains the versions found in storage,
+with their creation and deletion times. Key settings such as max_versions and
+custom_metadata cannot be recovered. Only versions written since the version
+data records its key can be recovered.
+
+The "confirm" parameter must be set to true. If the "preview" parameter is set
+to true instead, the keys whose metadata would be rebuilt are returned and
+nothing is written. The endpoint requires sudo capability, and scans the whole
+mount, so it should only be used to repair a damaged mount.
+
+The rebuild runs as a job, listed under jobs/ while the request runs, so its
+progress can be followed and it can be canceled. The response contains the
+"job_id" of the job.
+`
@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Repair_RebuildIndex(t *testing.T) {
+	b, storage := getBackend(t)
+
+	for _, value := range []string{"one", "two"} {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": value,
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	// Lose the metadata of the key without deleting its versions
+	kvb := b.(*versionedKVBackend)
+	wrapper, err := kvb.getKeyEncryptor(context.Background(), storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wrapper.Wrap(storage).Delete(context.Background(), "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "repair/rebuild-index",
+		Storage:   storage,
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected rebuild without confirm to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	req.Data = map[string]interface{}{
+		"preview": true,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("rebuild preview request failed, err: %s, resp %#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["recovered_keys"], []string{"foo"}) {
+		t.Fatalf("unexpected previewed keys: %#v", resp.Data["recovered_keys"])
+	}
+
+	meta, err := kvb.getKeyMetadata(context.Background(), storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta != nil {
+		t.Fatalf("expected preview to not rebuild the metadata, got: %#v", meta)
+	}
+
+	req.Data = map[string]interface{}{
+		"confirm": true,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("rebuild request failed, err: %s, resp %#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["recovered_keys"], []string{"foo"}) {
+		t.Fatalf("unexpected recovered keys: %#v", resp.Data["recovered_keys"])
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["creation_source"] != creationSourceRecovered || resp.Data["current_version"] != uint64(2) {
+		t.Fatalf("unexpected recovered metadata: %#v", resp.Data)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"version": 1,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "one" {
+		t.Fatalf("unexpected recovered data: %#v", resp.Data)
+	}
+
+	// Keys with metadata are left unchanged
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "repair/rebuild-index",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"confirm": true,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("rebuild request failed, err: %s, resp %#v", err, resp)
+	}
+	if len(resp.Data["recovered_keys"].([]string)) != 0 {
+		t.Fatalf("expected no keys to be recovered, got: %#v", resp.Data["recovered_keys"])
+	}
+}
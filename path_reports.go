@@ -0,0 +1,349 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// The page sizes of the oldest versions report
+const (
+	defaultOldestVersionsLimit = 100
+	maxOldestVersionsLimit     = 1000
+)
+
+// pathReportsOldestVersions returns the path configuration for the report
+// listing the keys whose oldest retained version exceeds an age
+func pathReportsOldestVersions(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "reports/oldest-versions$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "report",
+			OperationSuffix: "oldest-versions",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"min_age": {
+				Type:        framework.TypeDurationSecond,
+				Description: "The age of the oldest retained version above which keys are reported.",
+				Required:    true,
+				Query:       true,
+			},
+			"path": {
+				Type:        framework.TypeString,
+				Description: "If set, only the keys under this folder are reported.",
+				Query:       true,
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Description: "The maximum number of keys returned, at most 1000.",
+				Default:     defaultOldestVersionsLimit,
+				Query:       true,
+			},
+			"after": {
+				Type:        framework.TypeString,
+				Description: `If set, only keys sorted after this key are reported. Set it to the "next_after" of the previous page to read the next page.`,
+				Query:       true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathReportsOldestVersionsRead()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"keys": {
+								Type:        framework.TypeSlice,
+								Description: "The reported keys in sorted order, with their oldest retained version, its creation time and age in seconds.",
+								Required:    true,
+							},
+							"next_after": {
+								Type:        framework.TypeString,
+								Description: "The last reported key, if there may be more keys to report.",
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    reportsOldestVersionsHelpSyn,
+		HelpDescription: reportsOldestVersionsHelpDesc,
+	}
+}
+
+func (b *versionedKVBackend) pathReportsOldestVersionsRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		minAge := time.Duration(data.Get("min_age").(int)) * time.Second
+		if minAge <= 0 {
+			return logical.ErrorResponse("min_age must be positive"), logical.ErrInvalidRequest
+		}
+
+		limit := data.Get("limit").(int)
+		if limit < 1 || limit > maxOldestVersionsLimit {
+			return logical.ErrorResponse("limit must be between 1 and %d", maxOldestVersionsLimit), logical.ErrInvalidRequest
+		}
+
+		prefix := strings.Trim(data.Get("path").(string), "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+		after := data.Get("after").(string)
+
+		// The key names are encrypted in storage, so the keys are collected
+		// and sorted before the page is read
+		var keys []string
+		if err := b.walkKeys(ctx, req.Storage, prefix, func(key string) error {
+			if key > after {
+				keys = append(keys, key)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		sort.Strings(keys)
+
+		now := b.now()
+		cutoff := now.Add(-minAge)
+		reported := make([]interface{}, 0)
+		var nextAfter string
+		for _, key := range keys {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+			if err != nil {
+				return nil, err
+			}
+			if meta == nil {
+				continue
+			}
+
+			verNum, created, ok := meta.oldestRetainedVersion()
+			if !ok || created.After(cutoff) {
+				continue
+			}
+
+			reported = append(reported, map[string]interface{}{
+				"key":          key,
+				"version":      verNum,
+				"created_time": ptypesTimestampToString(meta.Versions[verNum].CreatedTime),
+				"age":          int64(now.Sub(created).Seconds()),
+			})
+			if len(reported) == limit {
+				nextAfter = key
+				break
+			}
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"keys": reported,
+			},
+		}
+		if nextAfter != "" && nextAfter != keys[len(keys)-1] {
+			resp.Data["next_after"] = nextAfter
+		}
+		return resp, nil
+	}
+}
+
+// pathReportsSchemaVersions returns the path configuration for the report
+// counting the keys by schema_version
+func pathReportsSchemaVersions(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "reports/schema-versions$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "report",
+			OperationSuffix: "schema-versions",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "If set, only the keys under this folder are reported.",
+				Query:       true,
+			},
+			"schema_version": {
+				Type:        framework.TypeInt,
+				Description: "If set, the keys of this schema_version are listed along with the counts.",
+				Query:       true,
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Description: "The maximum number of keys listed, at most 1000.",
+				Default:     defaultOldestVersionsLimit,
+				Query:       true,
+			},
+			"after": {
+				Type:        framework.TypeString,
+				Description: `If set, only keys sorted after this key are listed. Set it to the "next_after" of the previous page to read the next page.`,
+				Query:       true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathReportsSchemaVersionsRead()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"counts": {
+								Type:        framework.TypeMap,
+								Description: "The number of keys of each schema_version, keys without one counted under 0.",
+								Required:    true,
+							},
+							"keys": {
+								Type:        framework.TypeStringSlice,
+								Description: "The keys of the requested schema_version in sorted order.",
+							},
+							"next_after": {
+								Type:        framework.TypeString,
+								Description: "The last listed key, if there may be more keys to list.",
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    reportsSchemaVersionsHelpSyn,
+		HelpDescription: reportsSchemaVersionsHelpDesc,
+	}
+}
+
+func (b *versionedKVBackend) pathReportsSchemaVersionsRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		limit := data.Get("limit").(int)
+		if limit < 1 || limit > maxOldestVersionsLimit {
+			return logical.ErrorResponse("limit must be between 1 and %d", maxOldestVersionsLimit), logical.ErrInvalidRequest
+		}
+
+		schemaVersionRaw, listKeys := data.GetOk("schema_version")
+		if listKeys && schemaVersionRaw.(int) < 0 {
+			return logical.ErrorResponse("schema_version cannot be negative"), logical.ErrInvalidRequest
+		}
+
+		prefix := strings.Trim(data.Get("path").(string), "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+		after := data.Get("after").(string)
+
+		counts := map[string]interface{}{}
+		var keys []string
+		if err := b.walkKeys(ctx, req.Storage, prefix, func(key string) error {
+			meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+			if err != nil || meta == nil {
+				return err
+			}
+
+			version := strconv.FormatUint(meta.SchemaVersion, 10)
+			count, _ := counts[version].(int)
+			counts[version] = count + 1
+
+			if listKeys && meta.SchemaVersion == uint64(schemaVersionRaw.(int)) && key > after {
+				keys = append(keys, key)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"counts": counts,
+			},
+		}
+		if !listKeys {
+			return resp, nil
+		}
+
+		sort.Strings(keys)
+		if len(keys) > limit {
+			keys = keys[:limit]
+			resp.Data["next_after"] = keys[limit-1]
+		}
+		if keys == nil {
+			keys = []string{}
+		}
+		resp.Data["keys"] = keys
+		return resp, nil
+	}
+}
+
+// oldestRetainedVersion returns the oldest version of the key whose data has
+// not been destroyed, and its creation time. It returns false if the data of
+// every version has been destroyed.
+func (k *KeyMetadata) oldestRetainedVersion() (uint64, time.Time, bool) {
+	var oldest uint64
+	for verNum, vm := range k.Versions {
+		if vm == nil || vm.Destroyed {
+			continue
+		}
+		if oldest == 0 || verNum < oldest {
+			oldest = verNum
+		}
+	}
+	if oldest == 0 {
+		return 0, time.Time{}, false
+	}
+
+	created, err := ptypes.Timestamp(k.Versions[oldest].CreatedTime)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return oldest, created, true
+}
+
+const reportsOldestVersionsHelpSyn = `Lists the keys whose oldest retained version is older than an age.`
+const reportsOldestVersionsHelpDesc = `
+This endpoint lists the keys whose oldest version that has not been destroyed
+was created more than "min_age" ago, in sorted order. It helps finding the
+keys where retention policies, such as delete_version_after, max_versions or
+tidy, do not remove old data as expected.
+
+Each reported key is returned with the number of its oldest retained version,
+the creation time of that version and its age in seconds. Deleted versions
+are retained, as their data can be undeleted; destroyed versions are not.
+
+The report is paginated: at most "limit" keys are returned, 100 by default,
+along with "next_after" if there may be more keys to report, to be passed as
+the "after" of the next request. Set "path" to only report the keys under a
+folder.
+
+This endpoint requires sudo capability, as it lists keys regardless of the
+policies on their paths.
+`
+
+const reportsSchemaVersionsHelpSyn = `Counts the keys by schema_version.`
+const reportsSchemaVersionsHelpDesc = `
+This endpoint returns the number of keys of each "schema_version" set on their
+metadata, to follow the progress of migrations of the layout of their data.
+Keys without a schema_version are counted under 0.
+
+Set "schema_version" to also list the keys of that schema_version, in sorted
+order. The list is paginated: at most "limit" keys are returned, 100 by
+default, along with "next_after" if there may be more keys to list, to be
+passed as the "after" of the next request. Set "path" to only report the keys
+under a folder.
+
+This endpoint requires sudo capability, as it lists keys regardless of the
+policies on their paths.
+`
@@ -0,0 +1,192 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Reports_OldestVersions(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	if !strutil.StrListContains(b.SpecialPaths().Root, "reports/*") {
+		t.Fatalf("expected reports to require sudo: %v", b.SpecialPaths().Root)
+	}
+
+	for _, key := range []string{"a", "b", "c/d", "c/e", "f"} {
+		for i := 0; i < 2; i++ {
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.UpdateOperation,
+				Path:      "data/" + key,
+				Storage:   storage,
+				Data: map[string]interface{}{
+					"data": map[string]interface{}{"bar": i},
+				},
+			})
+			if err != nil || (resp != nil && resp.IsError()) {
+				t.Fatalf("write of %s failed, err: %s, resp %#v", key, err, resp)
+			}
+		}
+	}
+
+	// Age the first version of every key but f, and destroy it on b so that
+	// its oldest retained version is recent
+	kvb := b.(*versionedKVBackend)
+	old, err := ptypes.TimestampProto(time.Now().Add(-48 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c/d", "c/e"} {
+		meta, err := kvb.getKeyMetadata(ctx, storage, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta.Versions[1].CreatedTime = old
+		if err := kvb.writeKeyMetadata(ctx, storage, meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "destroy/b",
+		Storage:   storage,
+		Data:      map[string]interface{}{"versions": []int{1}},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("destroy failed, err: %s, resp %#v", err, resp)
+	}
+
+	report := func(data map[string]interface{}) ([]string, interface{}) {
+		t.Helper()
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "reports/oldest-versions",
+			Storage:   storage,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("report failed, err: %s, resp %#v", err, resp)
+		}
+		schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route(req.Path), req.Operation), resp, true)
+
+		var keys []string
+		for _, raw := range resp.Data["keys"].([]interface{}) {
+			entry := raw.(map[string]interface{})
+			if entry["version"] != uint64(1) || entry["age"].(int64) < 47*3600 {
+				t.Fatalf("unexpected report entry %#v", entry)
+			}
+			keys = append(keys, entry["key"].(string))
+		}
+		return keys, resp.Data["next_after"]
+	}
+
+	keys, next := report(map[string]interface{}{"min_age": "24h"})
+	if !strutil.EquivalentSlices(keys, []string{"a", "c/d", "c/e"}) || next != nil {
+		t.Fatalf("unexpected report %v, next_after %v", keys, next)
+	}
+
+	keys, next = report(map[string]interface{}{"min_age": "24h", "limit": 2})
+	if !strutil.EquivalentSlices(keys, []string{"a", "c/d"}) || next != "c/d" {
+		t.Fatalf("unexpected first page %v, next_after %v", keys, next)
+	}
+	keys, next = report(map[string]interface{}{"min_age": "24h", "limit": 2, "after": "c/d"})
+	if !strutil.EquivalentSlices(keys, []string{"c/e"}) || next != nil {
+		t.Fatalf("unexpected second page %v, next_after %v", keys, next)
+	}
+
+	keys, _ = report(map[string]interface{}{"min_age": "24h", "path": "c"})
+	if !strutil.EquivalentSlices(keys, []string{"c/d", "c/e"}) {
+		t.Fatalf("unexpected report under c %v", keys)
+	}
+
+	keys, _ = report(map[string]interface{}{"min_age": "72h"})
+	if len(keys) != 0 {
+		t.Fatalf("expected no keys older than 72h, got %v", keys)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{},
+		{"min_age": "24h", "limit": 0},
+		{"min_age": "24h", "limit": maxOldestVersionsLimit + 1},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "reports/oldest-versions",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected report with %v to be rejected, err: %s, resp %#v", data, err, resp)
+		}
+	}
+}
+
+func TestVersionedKV_Reports_SchemaVersions(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	for key, schemaVersion := range map[string]int{"a": 1, "b": 1, "c/d": 1, "c/e": 2, "f": 0} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata/" + key,
+			Storage:   storage,
+			Data:      map[string]interface{}{"schema_version": schemaVersion},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("metadata write of %s failed, err: %s, resp %#v", key, err, resp)
+		}
+	}
+
+	report := func(data map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "reports/schema-versions",
+			Storage:   storage,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("report failed, err: %s, resp %#v", err, resp)
+		}
+		schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route(req.Path), req.Operation), resp, true)
+		return resp.Data
+	}
+
+	data := report(nil)
+	if !reflect.DeepEqual(data["counts"], map[string]interface{}{"0": 1, "1": 3, "2": 1}) {
+		t.Fatalf("unexpected counts: %#v", data["counts"])
+	}
+	if _, ok := data["keys"]; ok {
+		t.Fatalf("expected no keys without a schema_version: %#v", data)
+	}
+
+	data = report(map[string]interface{}{"path": "c", "schema_version": 1})
+	if !reflect.DeepEqual(data["counts"], map[string]interface{}{"1": 1, "2": 1}) {
+		t.Fatalf("unexpected counts under c: %#v", data["counts"])
+	}
+	if keys := data["keys"].([]string); !reflect.DeepEqual(keys, []string{"c/d"}) {
+		t.Fatalf("unexpected keys under c: %v", keys)
+	}
+
+	data = report(map[string]interface{}{"schema_version": 1, "limit": 2})
+	if keys := data["keys"].([]string); !reflect.DeepEqual(keys, []string{"a", "b"}) || data["next_after"] != "b" {
+		t.Fatalf("unexpected first page: %#v", data)
+	}
+	data = report(map[string]interface{}{"schema_version": 1, "limit": 2, "after": "b"})
+	if keys := data["keys"].([]string); !reflect.DeepEqual(keys, []string{"c/d"}) || data["next_after"] != nil {
+		t.Fatalf("unexpected second page: %#v", data)
+	}
+}
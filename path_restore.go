@@ -0,0 +1,235 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathRestore returns the path configuration for the restore endpoint
+func pathRestore(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "restore/" + matchAllNoTrailingSlashRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "restore",
+			OperationSuffix: "version",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+			"version": {
+				Type:        framework.TypeInt,
+				Description: "The version number to restore the data as. Must be greater than the current version of the secret.",
+			},
+			"created_time": {
+				Type:        framework.TypeTime,
+				Description: "The original creation time of the version. If not provided, the current time will be used.",
+			},
+			"data": {
+				Type:        framework.TypeMap,
+				Description: "The contents of the data map will be stored and returned on read.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.mirrorWrites(b.pathRestoreWrite())),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"version": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"created_time": {
+								Type:     framework.TypeTime,
+								Required: true,
+							},
+							"deletion_time": {
+								Type:     framework.TypeString,
+								Required: true,
+							},
+							"destroyed": {
+								Type:     framework.TypeBool,
+								Required: true,
+							},
+							"custom_metadata": {
+								Type:     framework.TypeMap,
+								Required: true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    restoreHelpSyn,
+		HelpDescription: restoreHelpDesc,
+	}
+}
+
+// pathRestoreWrite writes a version of a key with an explicit version number
+// and creation time.
+func (b *versionedKVBackend) pathRestoreWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := data.Get("path").(string)
+
+		verParam := data.Get("version").(int)
+		if verParam <= 0 {
+			return logical.ErrorResponse("version must be a positive integer"), logical.ErrInvalidRequest
+		}
+		verNum := uint64(verParam)
+
+		createdTime := b.now()
+		if ctRaw, ok := data.GetOk("created_time"); ok {
+			createdTime = ctRaw.(time.Time)
+		}
+
+		dataRaw, ok := data.GetOk("data")
+		if !ok {
+			return logical.ErrorResponse("no data provided"), logical.ErrInvalidRequest
+		}
+		marshaledData, err := json.Marshal(dataRaw.(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.Lock()
+		defer lock.Unlock()
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			meta = &KeyMetadata{
+				Key:            key,
+				Versions:       map[uint64]*VersionMetadata{},
+				CreationSource: creationSourceRestore,
+			}
+		}
+		if meta.isLocked() {
+			return lockedDenied(key, "restoring versions")
+		}
+
+		// Versions of an existing key must be restored in order so that the
+		// sliding window of max versions can clean up the oldest ones.
+		switch {
+		case meta.CurrentVersion == 0:
+			meta.OldestVersion = verNum - 1
+		case verNum != meta.CurrentVersion+1:
+			return logical.ErrorResponse("version must be %d, the next version of the secret", meta.CurrentVersion+1), logical.ErrInvalidRequest
+		}
+
+		ct, err := ptypes.TimestampProto(createdTime)
+		if err != nil {
+			return logical.ErrorResponse("error setting created_time: converting %v to protobuf: %v", createdTime, err), logical.ErrInvalidRequest
+		}
+		version := &Version{
+			Data:        marshaledData,
+			CreatedTime: ct,
+			Key:         key,
+			Version:     verNum,
+		}
+
+		if dtime, ok := versionDeletionTime(createdTime, config, meta, nil); ok {
+			dt, err := ptypes.TimestampProto(dtime)
+			if err != nil {
+				return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
+			}
+			version.DeletionTime = dt
+		}
+
+		buf, err := proto.Marshal(version)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+			return nil, err
+		}
+
+		versionKey, err := b.getVersionKey(ctx, key, verNum, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		vm, versionToDelete := meta.addVersionAt(verNum, version.CreatedTime, version.DeletionTime, config.MaxVersions, config.RetainPrunedVersions, b.now())
+		vm.DataBytes = uint64(len(marshaledData))
+		if err := checkKeyBytes(config, meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		sequence := meta.nextEventSequence()
+
+		if err := req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   versionKey,
+			Value: buf,
+		}); err != nil {
+			return nil, err
+		}
+
+		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		if err != nil {
+			b.deleteOrphanedVersions(ctx, req.Storage, meta.Key, versionKey)
+			return nil, err
+		}
+		queueMirror(ctx, key)
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"version":         meta.CurrentVersion,
+				"created_time":    ptypesTimestampToString(vm.CreatedTime),
+				"deletion_time":   ptypesTimestampToString(vm.DeletionTime),
+				"destroyed":       vm.Destroyed,
+				"custom_metadata": meta.CustomMetadata,
+			},
+		}
+
+		warning := b.cleanupOldVersions(ctx, req.Storage, key, meta, versionToDelete)
+		if warning != "" {
+			resp.AddWarning(warning)
+		}
+
+		b.emitKeyEvent(ctx, req.Storage, meta, sequence, "restore", "restore/"+key, "data/"+key, true,
+			"version", fmt.Sprintf("%d", verNum),
+			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+		)
+		return resp, nil
+	}
+}
+
+const restoreHelpSyn = `Restores a version of a secret with its original version number and creation time.`
+const restoreHelpDesc = `
+This endpoint writes a version of a secret with an explicit version number and
+creation time, allowing keys that are imported, restored or copied from another
+mount to preserve their original version numbering and timeline.
+
+The "version" parameter is required. If the secret does not exist, any version
+number may be used; the versions of an existing secret must be restored in
+order, so the version must be the next version of the secret. The
+"created_time" parameter defaults to the current time.
+
+Access to this endpoint should be restricted to privileged operators, as it
+allows rewriting the history of a secret.
+`
@@ -0,0 +1,102 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Restore(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	created := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "restore/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"version":      5,
+			"created_time": created.Format(time.RFC3339),
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("restore request failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(
+		t,
+		schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+		resp,
+		true,
+	)
+
+	if resp.Data["version"] != uint64(5) {
+		t.Fatalf("expected version to be 5, resp: %#v", resp)
+	}
+
+	if resp.Data["created_time"] != created.Format(time.RFC3339Nano) {
+		t.Fatalf("expected created_time to be preserved, resp: %#v", resp)
+	}
+
+	// Skipping a version of an existing key is not allowed
+	req.Data["version"] = 7
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected out of order restore to fail, err: %s, resp %#v", err, resp)
+	}
+
+	// Subsequent writes continue the restored numbering
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz1",
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if resp.Data["version"] != uint64(6) {
+		t.Fatalf("expected version to be 6, resp: %#v", resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"version": 5,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("expected restored data, resp: %#v", resp)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/restore", "restore/foo", "data/foo"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+	})
+}
@@ -0,0 +1,283 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathRollback returns the path configuration for the rollback endpoint
+func pathRollback(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "rollback/" + matchAllNoTrailingSlashRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "rollback",
+			OperationSuffix: "secret",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+			"version": {
+				Type:        framework.TypeInt,
+				Description: "The version whose data is written as the new current version of the secret.",
+				Required:    true,
+			},
+			"options": {
+				Type: framework.TypeMap,
+				Description: `Options for writing a KV entry.
+
+Set the "cas" value to use a Check-And-Set operation. The write will only be
+allowed if the key’s current version matches the version specified in the cas
+parameter.`,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.mirrorWrites(b.pathRollbackWrite())),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"version": {
+								Type:     framework.TypeInt64,
+								Required: true,
+							},
+							"created_time": {
+								Type:     framework.TypeTime,
+								Required: true,
+							},
+							"deletion_time": {
+								Type:     framework.TypeString,
+								Required: true,
+							},
+							"destroyed": {
+								Type:     framework.TypeBool,
+								Required: true,
+							},
+							"custom_metadata": {
+								Type:     framework.TypeMap,
+								Required: true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    rollbackHelpSyn,
+		HelpDescription: rollbackHelpDesc,
+	}
+}
+
+// pathRollbackWrite writes a new version of a key holding the data of one of
+// its earlier versions, without the data leaving the server.
+func (b *versionedKVBackend) pathRollbackWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := data.Get("path").(string)
+
+		verParam := data.Get("version").(int)
+		if verParam <= 0 {
+			return logical.ErrorResponse("version must be a positive integer"), logical.ErrInvalidRequest
+		}
+		verNum := uint64(verParam)
+
+		opts, err := parseDataOptions(data)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.checkNoTemplate(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.checkNoArrayOps(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		optionsWarning, err := opts.checkUnknown(config)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := opts.validateDeleteVersionAfter(config); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		// The healthcheck key only ever holds a single version
+		if config.isHealthcheckPath(key) {
+			return logical.ErrorResponse("the healthcheck key cannot be rolled back"), logical.ErrInvalidRequest
+		}
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.Lock()
+		defer lock.Unlock()
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			return logical.RespondWithStatusCode(nil, req, http.StatusNotFound)
+		}
+		if meta.isLocked() {
+			return lockedDenied(key, "rolling back")
+		}
+		if err := b.destroyDueVersions(ctx, req.Storage, config, meta, b.now()); err != nil {
+			return nil, err
+		}
+
+		err = validateAllowedOptions(opts, config, key)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+
+		err = validateCheckAndSetOption(opts, config, meta)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		vm := meta.Versions[verNum]
+		switch {
+		case vm == nil:
+			return logical.ErrorResponse("version %d does not exist", verNum), logical.ErrInvalidRequest
+		case vm.Destroyed:
+			return logical.ErrorResponse("version %d is destroyed", verNum), logical.ErrInvalidRequest
+		}
+		if vm.DeletionTime != nil {
+			deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+			if err != nil {
+				return nil, err
+			}
+
+			if deletionTime.Before(b.now()) {
+				return logical.ErrorResponse("version %d is deleted, undelete it first", verNum), logical.ErrInvalidRequest
+			}
+		}
+
+		source, err := b.getVersion(ctx, req.Storage, key, verNum)
+		if err != nil {
+			return nil, err
+		}
+		if source == nil {
+			return nil, errors.New("could not find version data")
+		}
+
+		versionKey, err := b.getVersionKey(ctx, key, meta.CurrentVersion+1, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		version := &Version{
+			Data:        source.Data,
+			CreatedTime: b.timestampNow(),
+			Key:         key,
+			Version:     meta.CurrentVersion + 1,
+		}
+
+		hash := contentHash(source.Data)
+		if shared := meta.sharedDataVersion(hash); shared != 0 && config.DeduplicateVersionData {
+			version.Data = nil
+			version.DataVersion = shared
+		}
+
+		ctime, err := ptypes.Timestamp(version.CreatedTime)
+		if err != nil {
+			return logical.ErrorResponse("unexpected error converting %T(%v) to time.Time: %v", version.CreatedTime, version.CreatedTime, err), logical.ErrInvalidRequest
+		}
+
+		if dtime, ok := versionDeletionTime(ctime, config, meta, opts.deleteVersionAfter); ok {
+			dt, err := ptypes.TimestampProto(dtime)
+			if err != nil {
+				return logical.ErrorResponse("error setting deletion_time: converting %v to protobuf: %v", dtime, err), logical.ErrInvalidRequest
+			}
+			version.DeletionTime = dt
+		}
+
+		buf, err := proto.Marshal(version)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkStorageValueSize(config, "version data", len(buf)); err != nil {
+			return nil, err
+		}
+
+		newVersionMetadata, versionToDelete := meta.addVersionAt(meta.CurrentVersion+1, version.CreatedTime, version.DeletionTime, config.MaxVersions, config.RetainPrunedVersions, b.now())
+		newVersionMetadata.ContentHash = hash
+		newVersionMetadata.DeleteVersionAfter = opts.deleteVersionAfter
+		newVersionMetadata.CorrelationId = opts.versionCorrelationID(ctx)
+		newVersionMetadata.DataVersion = version.DataVersion
+		newVersionMetadata.DataBytes = uint64(len(source.Data))
+		if err := checkKeyBytes(config, meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		sequence := meta.nextEventSequence()
+
+		if err := req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   versionKey,
+			Value: buf,
+		}); err != nil {
+			return nil, err
+		}
+
+		err = b.writeKeyMetadata(ctx, req.Storage, meta)
+		if err != nil {
+			b.deleteOrphanedVersions(ctx, req.Storage, meta.Key, versionKey)
+			return nil, err
+		}
+		queueMirror(ctx, key)
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"version":         meta.CurrentVersion,
+				"created_time":    ptypesTimestampToString(newVersionMetadata.CreatedTime),
+				"deletion_time":   ptypesTimestampToString(newVersionMetadata.DeletionTime),
+				"destroyed":       newVersionMetadata.Destroyed,
+				"custom_metadata": meta.CustomMetadata,
+			},
+		}
+
+		if optionsWarning != "" {
+			resp.AddWarning(optionsWarning)
+		}
+
+		warning := b.cleanupOldVersions(ctx, req.Storage, key, meta, versionToDelete)
+		if warning != "" {
+			resp.AddWarning(warning)
+		}
+
+		b.emitKeyEvent(ctx, req.Storage, meta, sequence, "rollback", "rollback/"+key, "data/"+key, true,
+			"version", fmt.Sprintf("%d", meta.CurrentVersion),
+			"oldest_version", fmt.Sprintf("%d", meta.OldestVersion),
+			"source_version", fmt.Sprintf("%d", verNum),
+		)
+		return resp, nil
+	}
+}
+
+const rollbackHelpSyn = `Writes the data of an earlier version as the new current version of a secret.`
+const rollbackHelpDesc = `
+This endpoint creates a new version of the secret holding a copy of the data
+of the version given by the "version" parameter. The data is copied within
+the server, so rolling back does not require reading the secret data.
+
+The version must exist and must be neither deleted nor destroyed. The "cas"
+option is supported like in data writes, so a rollback can be made to only
+succeed if the secret did not change since it was inspected. The new version
+follows the max_versions and delete_version_after settings of the secret.
+`
@@ -0,0 +1,144 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_Rollback(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	for _, value := range []string{"baz1", "baz2"} {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": value,
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	// The cas option must match the current version
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rollback/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"version": 1,
+			"options": map[string]interface{}{
+				"cas": 1,
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected rollback with a stale cas to fail, err: %s, resp %#v", err, resp)
+	}
+
+	req.Data["options"] = map[string]interface{}{
+		"cas": 2,
+	}
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("rollback request failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(
+		t,
+		schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+		resp,
+		true,
+	)
+
+	if resp.Data["version"] != uint64(3) {
+		t.Fatalf("expected version to be 3, resp: %#v", resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz1" {
+		t.Fatalf("expected rolled back data, resp: %#v", resp)
+	}
+
+	// Destroyed versions cannot be rolled back to
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "destroy/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"versions": []int{2},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("destroy request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rollback/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"version": 2,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected rollback to a destroyed version to fail, err: %s, resp %#v", err, resp)
+	}
+
+	// Keys that do not exist cannot be rolled back
+	req.Path = "rollback/missing"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.Data["http_status_code"] != 404 {
+		t.Fatalf("expected rollback of a missing key to return 404, err: %s, resp %#v", err, resp)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/rollback", "rollback/foo", "data/foo"},
+		{"kv-v2/destroy", "destroy/foo", ""},
+	})
+}
+
+func TestVersionedKV_Rollback_DestroyDue(t *testing.T) {
+	b, storage := getBackend(t)
+	writeDestroyDueVersion(t, b, storage, nil)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rollback/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"version": 1},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || resp.Data["error"] != "version 1 is destroyed" {
+		t.Fatalf("expected the rollback to a version due to be destroyed to fail, err: %v, resp %#v", err, resp)
+	}
+	expectVersionDestroyed(t, b, storage, "foo", 1)
+}
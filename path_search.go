@@ -0,0 +1,249 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// The page sizes of metadata searches
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// maxSearchPatternLength is the maximum length of the glob or regex of a
+// metadata search
+const maxSearchPatternLength = 1024
+
+// pathSearchMetadata returns the path configuration for the endpoint
+// searching the key paths of the mount
+func pathSearchMetadata(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "search/metadata$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "search",
+			OperationSuffix: "metadata",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"glob": {
+				Type:        framework.TypeString,
+				Description: `A glob the key paths must match. "*" matches any characters but "/", "**" any characters and "?" one character but "/".`,
+				Query:       true,
+			},
+			"regex": {
+				Type:        framework.TypeString,
+				Description: "A regular expression the key paths must match, anywhere in the path unless anchored.",
+				Query:       true,
+			},
+			"custom_metadata": {
+				Type:        framework.TypeKVPairs,
+				Description: "If set, only the keys whose custom_metadata has these values are returned.",
+				Query:       true,
+			},
+			"path": {
+				Type:        framework.TypeString,
+				Description: "If set, only the keys under this folder are searched.",
+				Query:       true,
+			},
+			"limit": {
+				Type:        framework.TypeInt,
+				Description: "The maximum number of keys returned, at most 1000.",
+				Default:     defaultSearchLimit,
+				Query:       true,
+			},
+			"after": {
+				Type:        framework.TypeString,
+				Description: `If set, only keys sorted after this key are returned. Set it to the "next_after" of the previous page to read the next page.`,
+				Query:       true,
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathSearchMetadataRead()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"keys": {
+								Type:        framework.TypeStringSlice,
+								Description: "The matching key paths in sorted order.",
+								Required:    true,
+							},
+							"next_after": {
+								Type:        framework.TypeString,
+								Description: "The last returned key, if there may be more matching keys.",
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    searchMetadataHelpSyn,
+		HelpDescription: searchMetadataHelpDesc,
+	}
+}
+
+func (b *versionedKVBackend) pathSearchMetadataRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		glob := data.Get("glob").(string)
+		expr := data.Get("regex").(string)
+
+		var re *regexp.Regexp
+		var err error
+		switch {
+		case glob != "" && expr != "":
+			return logical.ErrorResponse("only one of glob or regex can be set"), logical.ErrInvalidRequest
+		case len(glob) > maxSearchPatternLength || len(expr) > maxSearchPatternLength:
+			return logical.ErrorResponse("the search pattern cannot be longer than %d characters", maxSearchPatternLength), logical.ErrInvalidRequest
+		case glob != "":
+			re, err = compileGlob(glob)
+		case expr != "":
+			re, err = regexp.Compile(expr)
+		}
+		if err != nil {
+			return logical.ErrorResponse("invalid search pattern: %s", err), logical.ErrInvalidRequest
+		}
+
+		customMetadata := data.Get("custom_metadata").(map[string]string)
+		if re == nil && len(customMetadata) == 0 {
+			return logical.ErrorResponse("one of glob, regex or custom_metadata must be set"), logical.ErrInvalidRequest
+		}
+
+		limit := data.Get("limit").(int)
+		if limit < 1 || limit > maxSearchLimit {
+			return logical.ErrorResponse("limit must be between 1 and %d", maxSearchLimit), logical.ErrInvalidRequest
+		}
+
+		prefix := strings.Trim(data.Get("path").(string), "/")
+		if prefix != "" {
+			prefix += "/"
+		}
+		after := data.Get("after").(string)
+
+		// The key names are encrypted in storage, so the matching keys are
+		// collected and sorted before the page is read
+		var keys []string
+		if err := b.walkKeys(ctx, req.Storage, prefix, func(key string) error {
+			if key > after && (re == nil || re.MatchString(key)) {
+				keys = append(keys, key)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		sort.Strings(keys)
+
+		matched := make([]string, 0)
+		var nextAfter string
+		for _, key := range keys {
+			if len(customMetadata) > 0 {
+				meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+				if err != nil {
+					return nil, err
+				}
+				if meta == nil || !customMetadataMatches(meta.CustomMetadata, customMetadata) {
+					continue
+				}
+			}
+
+			matched = append(matched, key)
+			if len(matched) == limit {
+				nextAfter = key
+				break
+			}
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"keys": matched,
+			},
+		}
+		if nextAfter != "" && nextAfter != keys[len(keys)-1] {
+			resp.Data["next_after"] = nextAfter
+		}
+		return resp, nil
+	}
+}
+
+// compileGlob returns the regular expression matching the key paths that
+// match the glob. "*" matches any characters but "/", "**" matches any
+// characters, so that "**/" matches any number of folders, and "?" matches
+// one character but "/".
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			expr.WriteString("[^/]*")
+		case glob[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("glob %q: %w", glob, err)
+	}
+	return re, nil
+}
+
+// customMetadataMatches returns true if the custom metadata has every value
+// of the filter.
+func customMetadataMatches(customMetadata, filter map[string]string) bool {
+	for k, v := range filter {
+		if value, ok := customMetadata[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+const searchMetadataHelpSyn = `Searches the key paths of the mount.`
+const searchMetadataHelpDesc = `
+This endpoint returns the paths of the keys matching a "glob" or a "regex",
+and the values of "custom_metadata" if set, anywhere under "path" or in the
+whole mount, in sorted order. For instance, the glob "**-prod" matches every
+key ending in "-prod" in any folder, while "*-prod" only matches the keys at
+the top of the mount or under "path".
+
+In globs, "*" matches any characters but "/", "**" matches any characters
+including "/", "**/" matches any number of folders and "?" matches a single
+character but "/". Regular expressions use the Go syntax and match anywhere
+in the path unless anchored with "^" and "$". Paths are matched relative to
+the root of the mount, including "path".
+
+Matching custom_metadata requires reading the metadata of every key whose
+path matches, so searches filtering on custom_metadata only should be limited
+to a folder with "path" on large mounts.
+
+The search is paginated: at most "limit" keys are returned, 100 by default,
+along with "next_after" if there may be more keys, to be passed as the "after"
+of the next request.
+
+This endpoint requires sudo capability, as it lists keys regardless of the
+policies on their paths.
+`
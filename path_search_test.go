@@ -0,0 +1,126 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCompileGlob(t *testing.T) {
+	cases := map[string]map[string]bool{
+		"*-prod":    {"db-prod": true, "a/db-prod": false, "db-dev": false},
+		"**-prod":   {"db-prod": true, "a/b/db-prod": true, "db-prod/x": false},
+		"**/db":     {"db": true, "a/db": true, "a/b/db": true, "adb": false},
+		"team/?/db": {"team/a/db": true, "team/ab/db": false, "team//db": false},
+		"a.b":       {"a.b": true, "axb": false},
+	}
+	for glob, paths := range cases {
+		re, err := compileGlob(glob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for p, expected := range paths {
+			if re.MatchString(p) != expected {
+				t.Fatalf("expected glob %q matching %q to be %t", glob, p, expected)
+			}
+		}
+	}
+}
+
+func TestVersionedKV_Search_Metadata(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	if !strutil.StrListContains(b.SpecialPaths().Root, "search/*") {
+		t.Fatalf("expected search to require sudo: %v", b.SpecialPaths().Root)
+	}
+
+	keys := map[string]string{
+		"db-prod":          "a",
+		"db-dev":           "a",
+		"team/api-prod":    "b",
+		"team/x/web-prod":  "a",
+		"team/x/web-stage": "b",
+	}
+	for key, owner := range keys {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"custom_metadata": map[string]interface{}{"team": owner},
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("metadata write of %s failed, err: %s, resp %#v", key, err, resp)
+		}
+	}
+
+	search := func(data map[string]interface{}) ([]string, interface{}) {
+		t.Helper()
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "search/metadata",
+			Storage:   storage,
+			Data:      data,
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("search failed, err: %s, resp %#v", err, resp)
+		}
+		schema.ValidateResponse(t, schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation), resp, true)
+		return resp.Data["keys"].([]string), resp.Data["next_after"]
+	}
+
+	cases := []struct {
+		data     map[string]interface{}
+		expected []string
+	}{
+		{map[string]interface{}{"glob": "**-prod"}, []string{"db-prod", "team/api-prod", "team/x/web-prod"}},
+		{map[string]interface{}{"glob": "*-prod"}, []string{"db-prod"}},
+		{map[string]interface{}{"glob": "**-prod", "path": "team/"}, []string{"team/api-prod", "team/x/web-prod"}},
+		{map[string]interface{}{"regex": "^team/.*-(prod|stage)$"}, []string{"team/api-prod", "team/x/web-prod", "team/x/web-stage"}},
+		{map[string]interface{}{"glob": "**-prod", "custom_metadata": map[string]interface{}{"team": "a"}}, []string{"db-prod", "team/x/web-prod"}},
+		{map[string]interface{}{"custom_metadata": map[string]interface{}{"team": "b"}}, []string{"team/api-prod", "team/x/web-stage"}},
+		{map[string]interface{}{"glob": "nothing"}, []string{}},
+	}
+	for _, tc := range cases {
+		found, next := search(tc.data)
+		if !reflect.DeepEqual(found, tc.expected) || next != nil {
+			t.Fatalf("search %v returned %v, next_after %v, expected %v", tc.data, found, next, tc.expected)
+		}
+	}
+
+	found, next := search(map[string]interface{}{"glob": "**-prod", "limit": 2})
+	if !reflect.DeepEqual(found, []string{"db-prod", "team/api-prod"}) || next != "team/api-prod" {
+		t.Fatalf("unexpected first page %v, next_after %v", found, next)
+	}
+	found, next = search(map[string]interface{}{"glob": "**-prod", "limit": 2, "after": next})
+	if !reflect.DeepEqual(found, []string{"team/x/web-prod"}) || next != nil {
+		t.Fatalf("unexpected second page %v, next_after %v", found, next)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{},
+		{"glob": "*", "regex": ".*"},
+		{"regex": "("},
+		{"glob": "*", "limit": 0},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "search/metadata",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected search %v to be rejected, err: %s, resp %#v", data, err, resp)
+		}
+	}
+}
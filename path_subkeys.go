@@ -9,9 +9,7 @@ import (
 	"errors"
 	"net/http"
 	"reflect"
-	"time"
 
-	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
@@ -37,6 +35,14 @@ func pathSubkeys(b *versionedKVBackend) *framework.Path {
 				Type:        framework.TypeInt,
 				Description: "The maximum depth to traverse. No limit will be imposed if not provided or if 0.",
 			},
+			"keys": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "If set, only these top-level keys of the secret are included.",
+			},
+			"include_types": {
+				Type:        framework.TypeBool,
+				Description: "If true, leaf keys are set to their JSON type and size in bytes instead of null.",
+			},
 			"version": {
 				Type:        framework.TypeInt,
 				Description: "Specifies which version to retrieve. If not provided, the current version will be used.",
@@ -75,6 +81,55 @@ func pathSubkeys(b *versionedKVBackend) *framework.Path {
 // to the input in-place. maxDepth will denote how deep to traverse. A maxDepth
 // of 0 is the equivalent of no limit.
 func removeValues(input map[string]interface{}, maxDepth int) {
+	replaceLeaves(input, maxDepth, func(interface{}) interface{} {
+		return nil
+	})
+}
+
+// describeValues is removeValues, except that leaf nodes are replaced with
+// their description by describeLeaf rather than nil.
+func describeValues(input map[string]interface{}, maxDepth int) {
+	replaceLeaves(input, maxDepth, describeLeaf)
+}
+
+// describeLeaf returns the JSON type and the size in bytes of a leaf value of
+// secret data, without the value. The size of a string is its length, the
+// size of other values the length of their JSON encoding.
+func describeLeaf(v interface{}) interface{} {
+	var typ string
+	var size int
+	switch t := v.(type) {
+	case nil:
+		typ = "null"
+	case string:
+		typ, size = "string", len(t)
+	case json.Number:
+		typ, size = "number", len(t)
+	case bool:
+		typ = "bool"
+	case []interface{}:
+		typ = "array"
+	case map[string]interface{}:
+		typ = "object"
+	default:
+		typ = "unknown"
+	}
+	if typ != "string" && typ != "number" {
+		encoded, err := json.Marshal(v)
+		if err == nil {
+			size = len(encoded)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":  typ,
+		"bytes": size,
+	}
+}
+
+// replaceLeaves walks the input as described by removeValues, replacing leaf
+// nodes with the result of leaf.
+func replaceLeaves(input map[string]interface{}, maxDepth int, leaf func(interface{}) interface{}) {
 	var walk func(interface{}, int)
 
 	walk = func(in interface{}, depth int) {
@@ -92,16 +147,16 @@ func removeValues(input map[string]interface{}, maxDepth int) {
 						// Only continue walking if we have not reached max depth
 						// and the underlying map has at least 1 key. The key is
 						// otherwise treated as a leaf node and thus set to nil.
-						// Setting to nil if the max depth is reached is crucial in
-						// that it prevents leaking secret data as the input map is
-						// being modified in-place
+						// Replacing the value if the max depth is reached is
+						// crucial in that it prevents leaking secret data as the
+						// input map is being modified in-place
 						if currentDepth := depth + 1; (maxDepth == 0 || currentDepth <= maxDepth) && len(t) > 0 {
 							walk(t, currentDepth)
 						} else {
-							m[k.String()] = nil
+							m[k.String()] = leaf(t)
 						}
 					default:
-						m[k.String()] = nil
+						m[k.String()] = leaf(t)
 					}
 				}
 			}
@@ -111,6 +166,18 @@ func removeValues(input map[string]interface{}, maxDepth int) {
 	walk(input, 1)
 }
 
+// filterTopLevelKeys returns the entries of the input whose key is one of
+// keys. Keys missing from the input are left out.
+func filterTopLevelKeys(input map[string]interface{}, keys []string) map[string]interface{} {
+	filtered := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		if v, ok := input[k]; ok {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
 // pathSubkeysRead handles ReadOperation requests for a specified path. Subkeys
 // that exist within the entry specified by the provided path will be retrieved.
 // This is done by stripping the secret data by replacing all underlying values of
@@ -119,6 +186,10 @@ func (b *versionedKVBackend) pathSubkeysRead() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		key := data.Get("path").(string)
 		depth := data.Get("depth").(int)
+		if depth < 0 {
+			return logical.ErrorResponse("depth cannot be negative"), logical.ErrInvalidRequest
+		}
+		keys := data.Get("keys").([]string)
 
 		lock := locksutil.LockForKey(b.locks, key)
 		lock.RLock()
@@ -163,7 +234,7 @@ func (b *versionedKVBackend) pathSubkeysRead() framework.OperationFunc {
 				return nil, err
 			}
 
-			if deletionTime.Before(time.Now()) {
+			if deletionTime.Before(b.now()) {
 				return logical.RespondWithStatusCode(resp, req, http.StatusNotFound)
 
 			}
@@ -174,32 +245,36 @@ func (b *versionedKVBackend) pathSubkeysRead() framework.OperationFunc {
 
 		}
 
-		versionKey, err := b.getVersionKey(ctx, key, versionNum, req.Storage)
+		version, err := b.getVersion(ctx, req.Storage, key, versionNum)
 		if err != nil {
 			return nil, err
 		}
-
-		raw, err := req.Storage.Get(ctx, versionKey)
-		if err != nil {
-			return nil, err
-		}
-		if raw == nil {
+		if version == nil {
 			return nil, errors.New("could not find version data")
 		}
 
-		version := &Version{}
-		if err := proto.Unmarshal(raw.Value, version); err != nil {
+		versionData, err := b.versionData(version)
+		if err != nil {
 			return nil, err
 		}
 
-		versionData := map[string]interface{}{}
-		if err := json.Unmarshal(version.Data, &versionData); err != nil {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
 			return nil, err
 		}
+		b.noteSubkeysRead(ctx, req.Storage, config, key, versionNum)
 
-		removeValues(versionData, depth)
+		if len(keys) > 0 {
+			versionData = filterTopLevelKeys(versionData, keys)
+		}
+		if data.Get("include_types").(bool) {
+			describeValues(versionData, depth)
+		} else {
+			removeValues(versionData, depth)
+		}
 		resp.Data["subkeys"] = versionData
 
+		b.noteDeprecatedRead(ctx, req.Storage, meta, resp, "subkeys/"+key)
 		return resp, nil
 	}
 }
@@ -216,5 +291,18 @@ generating the subkeys structure. If not provided, the current version will be u
 The "depth" parameter specifies the deepest nesting level to provide in the output.
 The default value 0 will not impose any limit. If non-zero, keys that reside at the
 specified depth value will be artificially treated as leaves and will thus be null
-even if further underlying subkeys exist.
+even if further underlying subkeys exist. It cannot be negative.
+
+The "keys" parameter is a comma-separated list of top-level keys to include in
+the output, so that callers that only need part of a large secret do not read
+the structure of all of it. Keys that do not exist in the secret are left out.
+If not provided, all keys are included.
+
+If the "include_types" parameter is true, leaf keys are set to an object
+describing their value instead of null, so that the shape and types of a
+secret can be shown without access to its values. The object holds the JSON
+"type" of the value, one of "string", "number", "bool", "array", "object" or
+"null", and its size in "bytes": the length of a string, or of the JSON
+encoding of other values. Objects are only leaves if they are empty or at the
+"depth" limit.
 `
@@ -245,8 +245,8 @@ func TestVersionedKV_Subkeys_VersionParamDoesNotExist(t *testing.T) {
 
 // TestVersionedKV_Subkeys_DepthParam verifies that the depth param
 // handling is correct. Failure to parse the value will result in an
-// error response. No limit will be imposed if the param is not
-// provided or its value is 0.
+// error response, as will a negative value. No limit will be imposed if
+// the param is not provided or its value is 0.
 func TestVersionedKV_Subkeys_DepthParam(t *testing.T) {
 	cases := []struct {
 		name      string
@@ -260,6 +260,12 @@ func TestVersionedKV_Subkeys_DepthParam(t *testing.T) {
 			expected:  nil,
 			expectErr: true,
 		},
+		{
+			name:      "negative",
+			depth:     -1,
+			expected:  nil,
+			expectErr: true,
+		},
 		{
 			name:  "not_provided",
 			depth: nil,
@@ -338,7 +344,7 @@ func TestVersionedKV_Subkeys_DepthParam(t *testing.T) {
 			}
 
 			resp, err = b.HandleRequest(context.Background(), req)
-			if err != nil || resp == nil {
+			if (err != nil && !tc.expectErr) || resp == nil {
 				t.Fatalf("subkeys ReadOperation request failed, err: %v, resp %#v", err, resp)
 			}
 
@@ -361,6 +367,160 @@ func TestVersionedKV_Subkeys_DepthParam(t *testing.T) {
 	}
 }
 
+// TestVersionedKV_Subkeys_KeysParam verifies that only the top-level keys
+// named by the keys param are returned, and that it combines with the depth
+// param
+func TestVersionedKV_Subkeys_KeysParam(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"foo": map[string]interface{}{
+					"bar": map[string]interface{}{
+						"baz": 123,
+					},
+				},
+				"qux":   "quux",
+				"large": map[string]interface{}{"a": 1, "b": 2},
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	cases := map[string]struct {
+		data     map[string]interface{}
+		expected map[string]interface{}
+	}{
+		"keys": {
+			data: map[string]interface{}{"keys": "foo,qux"},
+			expected: map[string]interface{}{
+				"foo": map[string]interface{}{
+					"bar": map[string]interface{}{
+						"baz": nil,
+					},
+				},
+				"qux": nil,
+			},
+		},
+		"keys_and_depth": {
+			data: map[string]interface{}{"keys": []string{"foo", "large"}, "depth": 1},
+			expected: map[string]interface{}{
+				"foo":   nil,
+				"large": nil,
+			},
+		},
+		"missing_key": {
+			data: map[string]interface{}{"keys": "qux,missing"},
+			expected: map[string]interface{}{
+				"qux": nil,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      "subkeys/foo",
+				Storage:   storage,
+				Data:      tc.data,
+			}
+
+			resp, err := b.HandleRequest(context.Background(), req)
+			if err != nil || resp == nil || resp.IsError() {
+				t.Fatalf("ReadOperation request failed, err: %v, resp %#v", err, resp)
+			}
+			schema.ValidateResponse(
+				t,
+				schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+				resp,
+				true,
+			)
+
+			if diff := deep.Equal(resp.Data["subkeys"], tc.expected); len(diff) > 0 {
+				t.Fatalf("resp and expected data mismatch, diff: %#v", diff)
+			}
+		})
+	}
+}
+
+// TestVersionedKV_Subkeys_IncludeTypes verifies that the include_types param
+// replaces leaf values with their JSON type and size rather than null
+func TestVersionedKV_Subkeys_IncludeTypes(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"foo": map[string]interface{}{
+					"bar": map[string]interface{}{
+						"baz": 123,
+					},
+				},
+				"string": "secret",
+				"bool":   true,
+				"array":  []interface{}{"a", 1},
+				"null":   nil,
+				"empty":  map[string]interface{}{},
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("CreateOperation request failed, err: %v, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "subkeys/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"include_types": true,
+			"depth":         2,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("ReadOperation request failed, err: %v, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(
+		t,
+		schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation),
+		resp,
+		true,
+	)
+
+	leaf := func(typ string, size int) map[string]interface{} {
+		return map[string]interface{}{"type": typ, "bytes": size}
+	}
+	expected := map[string]interface{}{
+		"foo": map[string]interface{}{
+			"bar": leaf("object", len(`{"baz":123}`)),
+		},
+		"string": leaf("string", len("secret")),
+		"bool":   leaf("bool", len("true")),
+		"array":  leaf("array", len(`["a",1]`)),
+		"null":   leaf("null", len("null")),
+		"empty":  leaf("object", len("{}")),
+	}
+	if diff := deep.Equal(resp.Data["subkeys"], expected); len(diff) > 0 {
+		t.Fatalf("resp and expected data mismatch, diff: %#v", diff)
+	}
+}
+
 // TestVersionedKV_Subkeys_EmptyData verifies that an empty map is
 // returned if the underlying data is also empty
 func TestVersionedKV_Subkeys_EmptyData(t *testing.T) {
@@ -0,0 +1,411 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// templatePrefix is the prefix where secret templates are stored.
+	templatePrefix string = "templates/"
+
+	// maxGeneratedFieldBytes is the largest number of random bytes of a
+	// generated field.
+	maxGeneratedFieldBytes = 1024
+)
+
+// pathTemplates returns the path configurations for the templates endpoints
+func pathTemplates(b *versionedKVBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "templates/" + framework.GenericNameRegex("name"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixKVv2,
+				OperationSuffix: "template",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "The name of the template.",
+				},
+				"fields": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "The fields the data of keys created from the template must contain.",
+				},
+				"generated_fields": {
+					Type: framework.TypeMap,
+					Description: `
+A map of fields to the random value generated for them if the data of the first
+write does not contain them: "hex:N" or "base64:N" for N random bytes in that
+encoding, or "uuid".`,
+				},
+				"custom_metadata": {
+					Type:        framework.TypeKVPairs,
+					Description: "Custom metadata set on keys created from the template, unless the key already sets the same custom metadata keys.",
+				},
+				"allow_additional_fields": {
+					Type:        framework.TypeBool,
+					Description: "If true, the data can contain fields that are neither in fields nor in generated_fields. Defaults to false.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathTemplateRead()),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "read",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: http.StatusText(http.StatusOK),
+							Fields:      templateResponseFields,
+						}},
+					},
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathTemplateWrite()),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "write",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: http.StatusText(http.StatusNoContent),
+						}},
+					},
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathTemplateDelete()),
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "delete",
+					},
+					Responses: map[int][]framework.Response{
+						http.StatusNoContent: {{
+							Description: http.StatusText(http.StatusNoContent),
+						}},
+					},
+				},
+			},
+
+			HelpSynopsis:    templatesHelpSyn,
+			HelpDescription: templatesHelpDesc,
+		},
+		{
+			Pattern: "templates/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixKVv2,
+				OperationVerb:   "list",
+				OperationSuffix: "templates",
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.upgradeCheck(b.pathTemplateList()),
+				},
+			},
+
+			HelpSynopsis:    templatesHelpSyn,
+			HelpDescription: templatesHelpDesc,
+		},
+	}
+}
+
+var templateResponseFields = map[string]*framework.FieldSchema{
+	"name": {
+		Type:     framework.TypeString,
+		Required: true,
+	},
+	"fields": {
+		Type:     framework.TypeStringSlice,
+		Required: true,
+	},
+	"generated_fields": {
+		Type:     framework.TypeMap,
+		Required: true,
+	},
+	"custom_metadata": {
+		Type:     framework.TypeMap,
+		Required: true,
+	},
+	"allow_additional_fields": {
+		Type:     framework.TypeBool,
+		Required: true,
+	},
+	"created_time": {
+		Type:     framework.TypeTime,
+		Required: true,
+	},
+	"updated_time": {
+		Type:     framework.TypeTime,
+		Required: true,
+	},
+}
+
+func (b *versionedKVBackend) templateStorageKey(name string) string {
+	return path.Join(b.storagePrefix, templatePrefix, name)
+}
+
+// getTemplate returns the template with the given name, or nil if there is
+// no such template.
+func (b *versionedKVBackend) getTemplate(ctx context.Context, s logical.Storage, name string) (*SecretTemplate, error) {
+	raw, err := s.Get(ctx, b.templateStorageKey(name))
+	if err != nil || raw == nil {
+		return nil, err
+	}
+
+	template := &SecretTemplate{}
+	if err := proto.Unmarshal(raw.Value, template); err != nil {
+		return nil, fmt.Errorf("failed to decode template from storage: %w", err)
+	}
+	return template, nil
+}
+
+// parseGeneratedFieldSpec returns the encoding and number of random bytes of
+// a generated field spec. The size of "uuid" is zero.
+func parseGeneratedFieldSpec(spec string) (string, int, error) {
+	if spec == "uuid" {
+		return spec, 0, nil
+	}
+
+	encoding, sizeRaw, ok := strings.Cut(spec, ":")
+	if !ok || (encoding != "hex" && encoding != "base64") {
+		return "", 0, fmt.Errorf("invalid generated field spec %q: must be \"hex:N\", \"base64:N\" or \"uuid\"", spec)
+	}
+	size, err := strconv.Atoi(sizeRaw)
+	if err != nil || size <= 0 || size > maxGeneratedFieldBytes {
+		return "", 0, fmt.Errorf("invalid generated field spec %q: the size must be between 1 and %d bytes", spec, maxGeneratedFieldBytes)
+	}
+	return encoding, size, nil
+}
+
+// generateFieldValue returns a new random value following a generated field
+// spec.
+func generateFieldValue(spec string) (string, error) {
+	encoding, size, err := parseGeneratedFieldSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	if encoding == "uuid" {
+		return uuid.GenerateUUID()
+	}
+
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	if encoding == "hex" {
+		return hex.EncodeToString(buf), nil
+	}
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
+
+// instantiate returns the data of the first version of a key created from the
+// template: the provided data with the missing generated fields added. An
+// error is returned if the data does not match the field layout.
+func (t *SecretTemplate) instantiate(data map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(data)+len(t.GeneratedFields))
+	for field, value := range data {
+		result[field] = value
+	}
+
+	for field, spec := range t.GeneratedFields {
+		if _, ok := result[field]; ok {
+			continue
+		}
+		value, err := generateFieldValue(spec)
+		if err != nil {
+			return nil, err
+		}
+		result[field] = value
+	}
+
+	var missing []string
+	for _, field := range t.Fields {
+		if _, ok := result[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("the data is missing the fields of template %q: %s", t.Name, strings.Join(missing, ", "))
+	}
+
+	if !t.AllowAdditionalFields {
+		var additional []string
+		for field := range result {
+			_, generated := t.GeneratedFields[field]
+			if !generated && !strutil.StrListContains(t.Fields, field) {
+				additional = append(additional, field)
+			}
+		}
+		if len(additional) > 0 {
+			sort.Strings(additional)
+			return nil, fmt.Errorf("the data has fields not in template %q: %s", t.Name, strings.Join(additional, ", "))
+		}
+	}
+
+	return result, nil
+}
+
+// applyCustomMetadata returns the custom metadata of a key created from the
+// template, the custom metadata of the template overridden by that already
+// set on the key.
+func (t *SecretTemplate) applyCustomMetadata(current map[string]string) map[string]string {
+	if len(t.CustomMetadata) == 0 {
+		return current
+	}
+
+	result := make(map[string]string, len(t.CustomMetadata)+len(current))
+	for k, v := range t.CustomMetadata {
+		result[k] = v
+	}
+	for k, v := range current {
+		result[k] = v
+	}
+	return result
+}
+
+func (b *versionedKVBackend) pathTemplateRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		template, err := b.getTemplate(ctx, req.Storage, data.Get("name").(string))
+		if err != nil || template == nil {
+			return nil, err
+		}
+
+		fields := template.Fields
+		if fields == nil {
+			fields = []string{}
+		}
+		generatedFields := make(map[string]interface{}, len(template.GeneratedFields))
+		for field, spec := range template.GeneratedFields {
+			generatedFields[field] = spec
+		}
+		customMetadata := template.CustomMetadata
+		if customMetadata == nil {
+			customMetadata = map[string]string{}
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"name":                    template.Name,
+				"fields":                  fields,
+				"generated_fields":        generatedFields,
+				"custom_metadata":         customMetadata,
+				"allow_additional_fields": template.AllowAdditionalFields,
+				"created_time":            ptypesTimestampToString(template.CreatedTime),
+				"updated_time":            ptypesTimestampToString(template.UpdatedTime),
+			},
+		}, nil
+	}
+}
+
+// pathTemplateWrite creates or replaces a template. Keys already created from
+// the template are not changed.
+func (b *versionedKVBackend) pathTemplateWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+
+		generatedFields := map[string]string{}
+		for field, specRaw := range data.Get("generated_fields").(map[string]interface{}) {
+			spec, ok := specRaw.(string)
+			if !ok {
+				return logical.ErrorResponse("invalid generated field spec for field %q: must be a string", field), logical.ErrInvalidRequest
+			}
+			if _, _, err := parseGeneratedFieldSpec(spec); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			generatedFields[field] = spec
+		}
+
+		customMetadata := data.Get("custom_metadata").(map[string]string)
+		if err := validateCustomMetadata(customMetadata); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		existing, err := b.getTemplate(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+
+		now := b.timestampNow()
+		template := &SecretTemplate{
+			Name:                  name,
+			Fields:                data.Get("fields").([]string),
+			GeneratedFields:       generatedFields,
+			CustomMetadata:        customMetadata,
+			AllowAdditionalFields: data.Get("allow_additional_fields").(bool),
+			CreatedTime:           now,
+			UpdatedTime:           now,
+		}
+		if existing != nil {
+			template.CreatedTime = existing.CreatedTime
+		}
+
+		buf, err := proto.Marshal(template)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   b.templateStorageKey(name),
+			Value: buf,
+		})
+	}
+}
+
+// pathTemplateDelete deletes a template. Keys created from the template keep
+// its name in their metadata.
+func (b *versionedKVBackend) pathTemplateDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		return nil, req.Storage.Delete(ctx, b.templateStorageKey(data.Get("name").(string)))
+	}
+}
+
+func (b *versionedKVBackend) pathTemplateList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		names, err := req.Storage.List(ctx, path.Join(b.storagePrefix, templatePrefix)+"/")
+		if err != nil {
+			return nil, err
+		}
+		return logical.ListResponse(names), nil
+	}
+}
+
+const templatesHelpSyn = `Manages the templates that new keys can be created from.`
+const templatesHelpDesc = `
+Templates describe the shape of a kind of secret, so that keys created by
+different teams are consistent. A template lists the "fields" the data must
+contain, the "generated_fields" filled with random values when the data does
+not provide them, and default "custom_metadata". Unless
+"allow_additional_fields" is true, the data cannot contain other fields.
+
+Set the "template" option of the first data write of a key to the name of a
+template to create the key from it. The data is checked against the template
+and completed with the generated fields, the custom metadata of the template
+is added to that of the key, and the template name is recorded as the
+"template" of the key metadata. Later writes of the key are not checked
+against the template, and changing or deleting a template does not change
+the keys created from it.
+
+Generated fields are specified as "hex:N" or "base64:N", for N random bytes in
+that encoding, or "uuid". The generated values are stored as the data of the
+key and can be read like any other field.
+`
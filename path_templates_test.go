@@ -0,0 +1,171 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestParseGeneratedFieldSpec(t *testing.T) {
+	for spec, valid := range map[string]bool{
+		"uuid":        true,
+		"hex:32":      true,
+		"base64:16":   true,
+		"hex:0":       false,
+		"hex:2048":    false,
+		"base32:16":   false,
+		"hex":         false,
+		"hex:sixteen": false,
+	} {
+		_, _, err := parseGeneratedFieldSpec(spec)
+		if (err == nil) != valid {
+			t.Fatalf("unexpected result for spec %q: %v", spec, err)
+		}
+	}
+}
+
+func TestVersionedKV_Templates(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "templates/db-credentials",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"fields":           []string{"username", "password", "host"},
+			"generated_fields": map[string]interface{}{"password": "hex:16"},
+			"custom_metadata":  map[string]interface{}{"kind": "db", "team": "platform"},
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("template write failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "templates/db-credentials",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("template read failed, err: %s, resp %#v", err, resp)
+	}
+	schema.ValidateResponse(t, schema.GetResponseSchema(t, b.(*versionedKVBackend).Route(req.Path), req.Operation), resp, true)
+	if !reflect.DeepEqual(resp.Data["fields"], []string{"username", "password", "host"}) {
+		t.Fatalf("unexpected fields: %#v", resp.Data["fields"])
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "templates/",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || !reflect.DeepEqual(resp.Data["keys"], []string{"db-credentials"}) {
+		t.Fatalf("unexpected template list, err: %s, resp %#v", err, resp)
+	}
+
+	// The metadata set before the first write takes precedence over the
+	// custom metadata of the template
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "metadata/app/db",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"custom_metadata": map[string]interface{}{"team": "payments"},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("metadata write failed, err: %s, resp %#v", err, resp)
+	}
+
+	write := func(key string, template string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "data/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"options": map[string]interface{}{"template": template},
+				"data":    data,
+			},
+		})
+	}
+
+	for _, data := range []map[string]interface{}{
+		// Missing host
+		{"username": "app"},
+		// Not in the template
+		{"username": "app", "host": "db", "port": "5432"},
+	} {
+		resp, err = write("app/db", "db-credentials", data)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("expected the write of %v to be rejected, err: %s, resp %#v", data, err, resp)
+		}
+	}
+
+	resp, err = write("app/other", "missing", map[string]interface{}{"username": "app"})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the write with a missing template to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = write("app/db", "db-credentials", map[string]interface{}{"username": "app", "host": "db"})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data write failed, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/app/db",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data read failed, err: %s, resp %#v", err, resp)
+	}
+	data := resp.Data["data"].(map[string]interface{})
+	password, _ := data["password"].(string)
+	if _, err := hex.DecodeString(password); err != nil || len(password) != 32 || data["username"] != "app" {
+		t.Fatalf("unexpected data: %#v", data)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "metadata/app/db",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("metadata read failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["template"] != "db-credentials" {
+		t.Fatalf("unexpected template: %#v", resp.Data["template"])
+	}
+	expected := map[string]string{"kind": "db", "team": "payments"}
+	if !reflect.DeepEqual(resp.Data["custom_metadata"], expected) {
+		t.Fatalf("unexpected custom_metadata: %#v", resp.Data["custom_metadata"])
+	}
+
+	// Only the first write can use a template
+	resp, err = write("app/db", "db-credentials", map[string]interface{}{"username": "app", "host": "db"})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the second templated write to be rejected, err: %s, resp %#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "templates/invalid",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"generated_fields": map[string]interface{}{"password": "random"},
+		},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the invalid template to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
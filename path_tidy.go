@@ -0,0 +1,390 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathTidy returns the path configuration for the tidy endpoint
+func pathTidy(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "tidy$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "tidy",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathTidyWrite()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"destroyed_versions": {
+								Type:        framework.TypeInt64,
+								Description: "The number of versions destroyed because their destroy_version_after or destroy_expired_after passed.",
+								Required:    true,
+							},
+							"deleted_entries": {
+								Type:        framework.TypeInt64,
+								Description: "The number of version entries removed from storage.",
+								Required:    true,
+							},
+							"purged_keys": {
+								Type:        framework.TypeInt64,
+								Description: "The number of soft deleted keys purged from the trash because their trash_retention passed.",
+								Required:    true,
+							},
+							"job_id": {
+								Type:        framework.TypeString,
+								Description: "The ID of the job that ran the tidy, see the jobs path.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    tidyHelpSyn,
+		HelpDescription: tidyHelpDesc,
+	}
+}
+
+// tidyResult counts the changes made by a tidy.
+type tidyResult struct {
+	destroyedVersions uint64
+	deletedEntries    uint64
+	purgedKeys        uint64
+}
+
+// pathTidyWrite handles update commands running a tidy of the mount
+func (b *versionedKVBackend) pathTidyWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		if !b.tidyLock.TryLock() {
+			return logical.ErrorResponse("a tidy is already running"), logical.ErrInvalidRequest
+		}
+		defer b.tidyLock.Unlock()
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if !config.featureEnabled(featureTidy) {
+			return logical.ErrorResponse("the tidy feature is disabled on this mount"), logical.ErrInvalidRequest
+		}
+
+		run, ctx, err := b.startJob(ctx, req.Storage, "tidy")
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := b.tidy(ctx, run.s, config, b.now(), func(processed uint64) {
+			run.progress(processed, 0)
+		})
+		run.finish(err)
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"destroyed_versions": result.destroyedVersions,
+				"deleted_entries":    result.deletedEntries,
+				"purged_keys":        result.purgedKeys,
+				"job_id":             run.job.Id,
+			},
+		}
+
+		switch {
+		case errors.Is(err, context.Canceled):
+			resp.AddWarning("the job was canceled before all keys were processed")
+		case err != nil:
+			return nil, err
+		}
+
+		return resp, nil
+	}
+}
+
+// periodicTidy runs a tidy once the configured tidy_interval has elapsed. Like
+// the expiry scan, the first call after the backend is initialized only
+// establishes the start of the interval. A periodic tidy is skipped while the
+// tidy endpoint runs one, and while the tidy feature is disabled.
+func (b *versionedKVBackend) periodicTidy(ctx context.Context, s logical.Storage, now time.Time) error {
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	interval := durationOrZero(config.GetTidyInterval())
+	if interval <= 0 || !config.featureEnabled(featureTidy) {
+		return nil
+	}
+
+	if !b.tidyLock.TryLock() {
+		return nil
+	}
+	defer b.tidyLock.Unlock()
+
+	if b.lastTidy.IsZero() {
+		b.lastTidy = now
+		return nil
+	}
+	if now.Sub(b.lastTidy) < interval {
+		return nil
+	}
+
+	result, err := b.tidy(ctx, s, config, now, func(uint64) {})
+	if err != nil {
+		return fmt.Errorf("tidy failed: %w", err)
+	}
+	if result.destroyedVersions > 0 || result.deletedEntries > 0 || result.purgedKeys > 0 {
+		b.Logger().Info("tidied version storage", "destroyed_versions", result.destroyedVersions, "deleted_entries", result.deletedEntries, "purged_keys", result.purgedKeys)
+	}
+
+	b.lastTidy = now
+	return nil
+}
+
+// tidy walks every key, destroying the versions due to be destroyed and
+// removing the stored data of destroyed versions, then walks the version
+// entries, removing the entries of versions missing from the metadata of
+// their key, and finally purges the soft deleted keys whose trash_retention
+// passed. progress is called with the number of keys and entries
+// processed so far. It must be called while holding tidyLock.
+func (b *versionedKVBackend) tidy(ctx context.Context, s logical.Storage, config *Configuration, now time.Time, progress func(uint64)) (tidyResult, error) {
+	var result tidyResult
+	var processed uint64
+
+	err := b.walkKeys(ctx, s, "", func(key string) error {
+		if err := b.tidyKey(ctx, s, config, key, now, &result); err != nil {
+			return err
+		}
+		processed++
+		progress(processed)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	entries := map[string][]tidyEntry{}
+	err = b.walkVersionEntries(ctx, s, func(storageKey string, v *Version) error {
+		processed++
+		progress(processed)
+
+		// Entries written before version data recorded its key cannot be
+		// matched to their metadata, so they are kept
+		if v != nil && v.Key != "" && v.Version != 0 {
+			entries[v.Key] = append(entries[v.Key], tidyEntry{storageKey: storageKey, version: v.Version})
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if err := b.tidyOrphanedEntries(ctx, s, key, entries[key], &result); err != nil {
+			return result, err
+		}
+	}
+
+	purged, err := b.purgeTrash(ctx, s, config, now)
+	result.purgedKeys = purged
+	if err != nil {
+		return result, fmt.Errorf("failed to purge the trash: %w", err)
+	}
+
+	return result, nil
+}
+
+// tidyKey destroys the versions of the key whose destroy_version_after or
+// destroy_expired_after passed, and removes the data left in storage by
+// destroyed versions and by retained versions whose deletion time passed.
+// Keys whose metadata cannot be read are skipped.
+func (b *versionedKVBackend) tidyKey(ctx context.Context, s logical.Storage, config *Configuration, key string, now time.Time, result *tidyResult) error {
+	grace := durationOrZero(config.GetDestroyExpiredAfter())
+
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil {
+		b.Logger().Warn("skipping key whose metadata cannot be read", "key", key, "error", err)
+		return nil
+	}
+	if meta == nil {
+		return nil
+	}
+
+	var due []uint64
+	for verNum, vm := range meta.Versions {
+		if versionDestroyDue(config, meta, vm, now) {
+			due = append(due, verNum)
+			continue
+		}
+		if vm.Destroyed || vm.DeletionTime == nil || grace <= 0 {
+			continue
+		}
+
+		deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+		if err != nil {
+			return err
+		}
+		if !deletionTime.Add(grace).After(now) {
+			due = append(due, verNum)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i] < due[j] })
+
+	// Destroying the versions removes their data, so they are not counted
+	// again below
+	for _, verNum := range due {
+		ok, err := b.versionEntryExists(ctx, s, key, verNum)
+		if err != nil {
+			return err
+		}
+		if ok && !meta.dataReferenced(verNum) {
+			result.deletedEntries++
+		}
+	}
+	if err := b.destroyVersions(ctx, s, meta, due); err != nil {
+		return err
+	}
+	result.destroyedVersions += uint64(len(due))
+
+	lingering := make([]uint64, 0, len(meta.Versions))
+	for verNum, vm := range meta.Versions {
+		if vm.Destroyed && !meta.dataReferenced(verNum) {
+			lingering = append(lingering, verNum)
+		}
+	}
+	sort.Slice(lingering, func(i, j int) bool { return lingering[i] < lingering[j] })
+
+	for _, verNum := range lingering {
+		ok, err := b.versionEntryExists(ctx, s, key, verNum)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := b.deleteVersionData(ctx, s, key, meta, verNum); err != nil {
+			return err
+		}
+		result.deletedEntries++
+	}
+
+	retained := uint64(len(meta.RetainedVersions))
+	if err := b.cleanupRetainedVersions(ctx, s, key, meta, now); err != nil {
+		return err
+	}
+	result.deletedEntries += retained - uint64(len(meta.RetainedVersions))
+
+	return nil
+}
+
+// tidyEntry is a version entry found in storage by the tidy.
+type tidyEntry struct {
+	storageKey string
+	version    uint64
+}
+
+// tidyOrphanedEntries removes the version entries of the key whose version is
+// neither in its metadata nor retained, and whose data is not referenced by a
+// version that is, such as the entries left by interrupted writes or failed
+// cleanups. Keys without metadata are skipped, so that their versions can
+// still be recovered with repair/rebuild-index.
+func (b *versionedKVBackend) tidyOrphanedEntries(ctx context.Context, s logical.Storage, key string, entries []tidyEntry, result *tidyResult) error {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil || meta == nil {
+		return nil
+	}
+
+	for _, entry := range entries {
+		if meta.referencesVersion(entry.version) {
+			continue
+		}
+
+		if err := s.Delete(ctx, entry.storageKey); err != nil {
+			return err
+		}
+		result.deletedEntries++
+	}
+
+	return nil
+}
+
+// referencesVersion returns true if the version is in the metadata, retained,
+// or holds data shared with a version that is, so that its stored entry must
+// be kept.
+func (k *KeyMetadata) referencesVersion(version uint64) bool {
+	if _, ok := k.Versions[version]; ok {
+		return true
+	}
+	if _, ok := k.RetainedVersions[version]; ok {
+		return true
+	}
+	return k.dataReferenced(version)
+}
+
+// versionEntryExists returns true if the live or archived entry of the
+// version is stored.
+func (b *versionedKVBackend) versionEntryExists(ctx context.Context, s logical.Storage, key string, version uint64) (bool, error) {
+	raw, _, err := b.getVersionEntry(ctx, s, key, version)
+	return raw != nil, err
+}
+
+const tidyHelpSyn = `Removes the stored data of destroyed versions and orphaned version entries.`
+const tidyHelpDesc = `
+Destroying a version marks it as destroyed in the metadata of its key before
+removing its data, so data can be left in storage if the removal fails.
+Versions written by interrupted writes, or pruned by a cleanup that failed,
+can likewise be left in storage without being listed in the metadata.
+
+This endpoint walks every key in the mount. Versions whose
+destroy_version_after has passed, and deleted versions whose
+destroy_expired_after grace period has passed, are destroyed and a destroy
+event is sent for them. The stored data of destroyed versions, and of retained
+versions whose deletion time has passed, is removed. It then walks the stored
+version entries and removes those whose version is not in the metadata of
+their key, unless the data is shared with a version that is. Entries of keys
+without metadata are kept so that repair/rebuild-index can recover them.
+Finally, keys soft deleted longer than the trash_retention of the config ago
+are purged from the trash permanently.
+
+The response contains the number of "destroyed_versions", of
+"deleted_entries" removed from storage and of "purged_keys". The tidy runs as
+a job, listed under jobs/ while the request runs, and only one tidy runs at a
+time. The endpoint requires sudo capability. Setting "tidy_interval" in the
+config runs the same tidy in the background. Disabling the tidy feature of
+config/features stops both.
+`
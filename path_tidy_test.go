@@ -0,0 +1,254 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// putOrphanedVersion stores a version entry of the key that is not listed in
+// its metadata, as left by an interrupted write.
+func putOrphanedVersion(t *testing.T, kv *versionedKVBackend, s logical.Storage, key string, version uint64) string {
+	t.Helper()
+
+	versionKey, err := kv.getVersionKey(context.Background(), key, version, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := proto.Marshal(&Version{
+		Data:        []byte(`{"bar":"orphaned"}`),
+		CreatedTime: ptypes.TimestampNow(),
+		Key:         key,
+		Version:     version,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(context.Background(), &logical.StorageEntry{Key: versionKey, Value: buf}); err != nil {
+		t.Fatal(err)
+	}
+	return versionKey
+}
+
+func TestVersionedKV_Tidy(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	for _, key := range []string{"foo", "foo", "bar"} {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	// Mark version 1 of foo destroyed without removing its data, as left by
+	// a failed destroy
+	meta, err := kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.Versions[1].Destroyed = true
+	if err := kv.writeKeyMetadata(ctx, storage, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	orphaned := putOrphanedVersion(t, kv, storage, "foo", 3)
+
+	// Lose the metadata of bar, whose versions must be kept for recovery
+	wrapper, err := kv.getKeyEncryptor(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wrapper.Wrap(storage).Delete(ctx, "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("tidy request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["deleted_entries"] != uint64(2) || resp.Data["destroyed_versions"] != uint64(0) {
+		t.Fatalf("unexpected tidy response: %#v", resp.Data)
+	}
+	if resp.Data["job_id"] == "" {
+		t.Fatal("expected a job ID")
+	}
+
+	for _, check := range []struct {
+		key     string
+		version uint64
+		exists  bool
+	}{
+		{"foo", 1, false},
+		{"foo", 2, true},
+		{"foo", 3, false},
+		{"bar", 1, true},
+	} {
+		ok, err := kv.versionEntryExists(ctx, storage, check.key, check.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != check.exists {
+			t.Fatalf("expected version %d of %s to exist: %t", check.version, check.key, check.exists)
+		}
+	}
+
+	raw, err := storage.Get(ctx, orphaned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != nil {
+		t.Fatal("expected the orphaned entry to be removed")
+	}
+
+	// A second tidy has nothing left to do
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("tidy request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["deleted_entries"] != uint64(0) {
+		t.Fatalf("unexpected tidy response: %#v", resp.Data)
+	}
+}
+
+func TestVersionedKV_Tidy_DestroyExpired(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"destroy_expired_after": "1h",
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	meta, err := kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.Versions[1].DeletionTime, _ = ptypes.TimestampProto(time.Now().Add(-2 * time.Hour))
+	if err := kv.writeKeyMetadata(ctx, storage, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "tidy",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("tidy request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["deleted_entries"] != uint64(1) || resp.Data["destroyed_versions"] != uint64(1) {
+		t.Fatalf("unexpected tidy response: %#v", resp.Data)
+	}
+
+	meta, err = kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Versions[1].Destroyed {
+		t.Fatal("expected version 1 to be destroyed")
+	}
+}
+
+func TestVersionedKV_Tidy_Periodic(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"tidy_interval": "1h",
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{"bar": "baz"},
+		},
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	orphaned := putOrphanedVersion(t, kv, storage, "foo", 2)
+
+	assertOrphaned := func(exists bool) {
+		t.Helper()
+		raw, err := storage.Get(ctx, orphaned)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (raw != nil) != exists {
+			t.Fatalf("expected the orphaned entry to exist: %t", exists)
+		}
+	}
+
+	// The first run only starts the interval
+	start := time.Now()
+	if err := kv.periodicTidy(ctx, storage, start); err != nil {
+		t.Fatal(err)
+	}
+	assertOrphaned(true)
+
+	if err := kv.periodicTidy(ctx, storage, start.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	assertOrphaned(true)
+
+	if err := kv.periodicTidy(ctx, storage, start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	assertOrphaned(false)
+}
@@ -0,0 +1,546 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// maxTransactionOperations is the maximum number of operations in a
+// transaction.
+const maxTransactionOperations = 128
+
+// The operations of a transaction
+const (
+	transactionWrite  = "write"
+	transactionPatch  = "patch"
+	transactionDelete = "delete"
+)
+
+// transactionOperationSchema is the schema of each entry of the operations
+// of a transaction, parsed like the fields of a request to the data path.
+var transactionOperationSchema = map[string]*framework.FieldSchema{
+	"operation": {Type: framework.TypeString},
+	"path":      {Type: framework.TypeString},
+	"data":      {Type: framework.TypeMap},
+	"options":   {Type: framework.TypeMap},
+}
+
+// pathTransaction returns the path configuration for the transaction endpoint
+func pathTransaction(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "transaction/?$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "apply",
+			OperationSuffix: "transaction",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"operations": {
+				Type: framework.TypeSlice,
+				Description: `
+The list of operations to apply. Each operation is a map with the "operation"
+("write", "patch" or "delete"), the "path" of the key and, for writes and
+patches, the "data" and "options" as accepted by the data endpoint.`,
+				Required: true,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.mirrorWrites(b.pathTransactionWrite())),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"transaction_id": {
+								Type:        framework.TypeString,
+								Description: "The ID of the transaction, included in the events it sends.",
+								Required:    true,
+							},
+							"results": {
+								Type:        framework.TypeSlice,
+								Description: "The path, operation and resulting current version of each operation, in order.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    transactionHelpSyn,
+		HelpDescription: transactionHelpDesc,
+	}
+}
+
+// transactionOperation is an operation of a transaction along with the state
+// of its key needed to apply it.
+type transactionOperation struct {
+	operation string
+	key       string
+	data      *framework.FieldData
+	opts      *dataOptions
+
+	// previous is the key metadata before the transaction, nil if the key
+	// did not exist.
+	previous *KeyMetadata
+	meta     *KeyMetadata
+
+	// version is the new version written by a write or patch
+	version    *Version
+	versionKey string
+	versionBuf []byte
+
+	versionToDelete uint64
+	sequence        uint64
+	modified        bool
+}
+
+// parseTransactionOperations validates the operations of a transaction
+// request. Each key may only be the subject of a single operation.
+func parseTransactionOperations(raw []interface{}, config *Configuration) ([]*transactionOperation, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("no operations provided")
+	}
+	if len(raw) > maxTransactionOperations {
+		return nil, fmt.Errorf("a transaction cannot have more than %d operations", maxTransactionOperations)
+	}
+
+	ops := make([]*transactionOperation, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for i, r := range raw {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operation %d is not a map", i)
+		}
+
+		data := &framework.FieldData{
+			Raw:    m,
+			Schema: transactionOperationSchema,
+		}
+		if err := data.Validate(); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+
+		op := &transactionOperation{
+			operation: data.Get("operation").(string),
+			key:       data.Get("path").(string),
+			data:      data,
+		}
+
+		switch {
+		case op.key == "":
+			return nil, fmt.Errorf("operation %d: missing path", i)
+		case seen[op.key]:
+			return nil, fmt.Errorf("operation %d: key %q is the subject of more than one operation", i, op.key)
+		case config.isHealthcheckPath(op.key):
+			return nil, fmt.Errorf("operation %d: the healthcheck key cannot be changed in a transaction", i)
+		}
+		seen[op.key] = true
+
+		switch op.operation {
+		case transactionWrite, transactionPatch:
+			if _, ok := data.GetOk("data"); !ok {
+				return nil, fmt.Errorf("operation %d: no data provided", i)
+			}
+
+			opts, err := parseDataOptions(data)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			if err := opts.checkNoTemplate(); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			if op.operation == transactionWrite {
+				if err := opts.checkNoArrayOps(); err != nil {
+					return nil, fmt.Errorf("operation %d: %w", i, err)
+				}
+			}
+			if len(opts.unknown) > 0 {
+				return nil, fmt.Errorf("operation %d: unrecognized options: %v", i, opts.unknown)
+			}
+			if err := opts.validateDeleteVersionAfter(config); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			op.opts = opts
+		case transactionDelete:
+		default:
+			return nil, fmt.Errorf("operation %d: unsupported operation %q, must be %q, %q or %q", i, op.operation, transactionWrite, transactionPatch, transactionDelete)
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// pathTransactionWrite applies the operations of a transaction. The locks of
+// all the keys are held while the operations are validated and applied, and
+// nothing is written unless every operation is valid. If writing to storage
+// fails, the keys that were already written are restored.
+func (b *versionedKVBackend) pathTransactionWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		ops, err := parseTransactionOperations(data.Get("operations").([]interface{}), config)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		keys := make([]string, len(ops))
+		for i, op := range ops {
+			keys[i] = op.key
+		}
+
+		// The locks are sorted, so transactions sharing keys cannot deadlock
+		locks := locksutil.LocksForKeys(b.locks, keys)
+		for _, lock := range locks {
+			lock.Lock()
+			defer lock.Unlock()
+		}
+
+		for i, op := range ops {
+			resp, err := b.prepareTransactionOperation(ctx, req, config, op)
+			if err != nil {
+				if resp != nil && resp.IsError() {
+					resp.Data["error"] = fmt.Sprintf("operation %d (%s %q): %s", i, op.operation, op.key, resp.Error())
+				}
+				return resp, err
+			}
+		}
+
+		if err := b.applyTransaction(ctx, req.Storage, ops); err != nil {
+			return nil, err
+		}
+
+		transactionID, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"transaction_id": transactionID,
+			},
+		}
+
+		results := make([]interface{}, 0, len(ops))
+		for _, op := range ops {
+			results = append(results, map[string]interface{}{
+				"path":      op.key,
+				"operation": op.operation,
+				"version":   op.meta.GetCurrentVersion(),
+			})
+
+			if !op.modified {
+				continue
+			}
+
+			if op.version != nil {
+				if warning := b.cleanupOldVersions(ctx, req.Storage, op.key, op.meta, op.versionToDelete); warning != "" {
+					resp.AddWarning(warning)
+				}
+				queueMirror(ctx, op.key)
+			}
+
+			b.emitKeyEvent(ctx, req.Storage, op.meta, op.sequence, "data-"+op.operation, "data/"+op.key, transactionEventDataPath(op), true,
+				"version", fmt.Sprintf("%d", op.meta.CurrentVersion),
+				"oldest_version", fmt.Sprintf("%d", op.meta.OldestVersion),
+				"transaction_id", transactionID,
+			)
+		}
+		resp.Data["results"] = results
+
+		return resp, nil
+	}
+}
+
+// transactionEventDataPath returns the data path of the event sent for an
+// operation, which like for the data endpoint is empty for deletes.
+func transactionEventDataPath(op *transactionOperation) string {
+	if op.operation == transactionDelete {
+		return ""
+	}
+	return "data/" + op.key
+}
+
+// prepareTransactionOperation validates an operation against the current
+// state of its key and computes the metadata and version data to write,
+// without writing anything. An error response is returned if the operation
+// cannot be applied.
+func (b *versionedKVBackend) prepareTransactionOperation(ctx context.Context, req *logical.Request, config *Configuration, op *transactionOperation) (*logical.Response, error) {
+	previous, err := b.getKeyMetadata(ctx, req.Storage, op.key)
+	if err != nil {
+		return nil, err
+	}
+	op.previous = previous
+
+	if previous != nil {
+		op.meta = proto.Clone(previous).(*KeyMetadata)
+	}
+	if op.meta.isLocked() {
+		return lockedDenied(op.key, fmt.Sprintf("the %s operation", op.operation))
+	}
+	if !config.ownerPermitted(req, op.meta) {
+		return ownerDenied(fmt.Sprintf("the %s operation", op.operation))
+	}
+
+	if op.operation == transactionDelete {
+		if op.meta == nil {
+			return nil, nil
+		}
+
+		lv := op.meta.Versions[op.meta.CurrentVersion]
+		if lv == nil || lv.Destroyed || versionExpired(lv, b.now()) {
+			return nil, nil
+		}
+
+		lv.DeletionTime = b.timestampNow()
+		op.sequence = op.meta.nextEventSequence()
+		op.modified = true
+		return nil, nil
+	}
+
+	if err := validateAllowedOptions(op.opts, config, op.key); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+	}
+
+	var marshaledData []byte
+	switch op.operation {
+	case transactionWrite:
+		if op.meta == nil {
+			op.meta = &KeyMetadata{
+				Key:            op.key,
+				Versions:       map[uint64]*VersionMetadata{},
+				CreationSource: creationSourceAPI,
+			}
+		}
+
+		if err := validateCheckAndSetOption(op.opts, config, op.meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		marshaledData, err = json.Marshal(op.data.Get("data").(map[string]interface{}))
+		if err != nil {
+			return nil, err
+		}
+	case transactionPatch:
+		if op.meta == nil {
+			return logical.ErrorResponse("key does not exist"), logical.ErrInvalidRequest
+		}
+
+		if err := validateCheckAndSetOption(op.opts, config, op.meta); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		lv := op.meta.Versions[op.meta.CurrentVersion]
+		if lv == nil || lv.Destroyed || versionExpired(lv, b.now()) {
+			return logical.ErrorResponse("the current version is deleted or destroyed"), logical.ErrInvalidRequest
+		}
+
+		existing, err := b.getVersion(ctx, req.Storage, op.key, op.meta.CurrentVersion)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, errors.New("could not find version data")
+		}
+
+		versionData, err := b.versionData(existing)
+		if err != nil {
+			return nil, err
+		}
+
+		marshaledData, err = framework.HandlePatchOperation(op.data, versionData, dataPatchPreprocessor())
+		if err != nil {
+			return nil, err
+		}
+		marshaledData, err = applyArrayOps(marshaledData, op.opts.arrayOps)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+	}
+
+	version := &Version{
+		Data:        marshaledData,
+		CreatedTime: b.timestampNow(),
+		Key:         op.key,
+		Version:     op.meta.CurrentVersion + 1,
+	}
+
+	hash := contentHash(marshaledData)
+	if shared := op.meta.sharedDataVersion(hash); shared != 0 && config.DeduplicateVersionData {
+		version.Data = nil
+		version.DataVersion = shared
+	}
+
+	ctime, err := ptypes.Timestamp(version.CreatedTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if dtime, ok := versionDeletionTime(ctime, config, op.meta, op.opts.deleteVersionAfter); ok {
+		dt, err := ptypes.TimestampProto(dtime)
+		if err != nil {
+			return nil, err
+		}
+		version.DeletionTime = dt
+	}
+
+	op.versionBuf, err = proto.Marshal(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStorageValueSize(config, "version data", len(op.versionBuf)); err != nil {
+		return nil, err
+	}
+
+	op.versionKey, err = b.getVersionKey(ctx, op.key, version.Version, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	op.version = version
+
+	vm, versionToDelete := op.meta.addVersionAt(version.Version, version.CreatedTime, version.DeletionTime, config.MaxVersions, config.RetainPrunedVersions, b.now())
+	vm.ContentHash = hash
+	vm.DeleteVersionAfter = op.opts.deleteVersionAfter
+	vm.CorrelationId = op.opts.versionCorrelationID(ctx)
+	vm.DataVersion = version.DataVersion
+	vm.DataBytes = uint64(len(marshaledData))
+	if err := checkKeyBytes(config, op.meta); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	op.versionToDelete = versionToDelete
+	op.sequence = op.meta.nextEventSequence()
+	op.modified = true
+
+	return nil, nil
+}
+
+// applyTransaction writes the version data and then the key metadata of the
+// prepared operations. New versions are not readable until the metadata of
+// their key is written, so if a write fails, the metadata already written is
+// restored and the new version data is deleted.
+func (b *versionedKVBackend) applyTransaction(ctx context.Context, s logical.Storage, ops []*transactionOperation) (retErr error) {
+	var versionsWritten, metadataWritten []*transactionOperation
+	defer func() {
+		if retErr == nil {
+			return
+		}
+
+		for _, op := range metadataWritten {
+			if err := b.restoreKeyMetadata(ctx, s, op); err != nil {
+				requestLogger(ctx, b.Logger()).Error("error restoring key metadata after a failed transaction", "key", op.key, "error", err)
+			}
+		}
+		for _, op := range versionsWritten {
+			if err := s.Delete(ctx, op.versionKey); err != nil {
+				requestLogger(ctx, b.Logger()).Error("error deleting version data after a failed transaction", "key", op.key, "error", err)
+			}
+		}
+	}()
+
+	for _, op := range ops {
+		if op.version == nil {
+			continue
+		}
+
+		if err := s.Put(ctx, &logical.StorageEntry{
+			Key:   op.versionKey,
+			Value: op.versionBuf,
+		}); err != nil {
+			return err
+		}
+		versionsWritten = append(versionsWritten, op)
+	}
+
+	for _, op := range ops {
+		if !op.modified {
+			continue
+		}
+
+		if err := b.writeKeyMetadata(ctx, s, op.meta); err != nil {
+			return err
+		}
+		metadataWritten = append(metadataWritten, op)
+	}
+
+	return nil
+}
+
+// restoreKeyMetadata restores the metadata of the key of an operation to its
+// state before the transaction.
+func (b *versionedKVBackend) restoreKeyMetadata(ctx context.Context, s logical.Storage, op *transactionOperation) error {
+	if op.previous != nil {
+		return b.writeKeyMetadata(ctx, s, op.previous)
+	}
+
+	wrapper, err := b.getKeyEncryptor(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	return wrapper.Wrap(s).Delete(ctx, op.key)
+}
+
+// versionExpired returns true if the deletion time of the version has passed
+// at now.
+func versionExpired(vm *VersionMetadata, now time.Time) bool {
+	if vm.DeletionTime == nil {
+		return false
+	}
+
+	deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+	return err == nil && deletionTime.Before(now)
+}
+
+const transactionHelpSyn = `Applies writes, patches and deletes to multiple keys atomically.`
+const transactionHelpDesc = `
+This endpoint applies a list of operations, each to a different key, as a
+single unit. Each operation is a map with these fields:
+
+	* operation (string) - "write", "patch" or "delete", with the same
+	  behavior as the corresponding request to the data endpoint.
+
+	* path (string) - The key the operation applies to.
+
+	* data (map) - The data to write, or to merge into the current version of
+	  the key for a patch.
+
+	* options (map) - The write options of the data endpoint. Set "cas" to
+	  make the transaction conditional on the current version of the key.
+
+The locks of all the keys are held while the operations are validated and
+applied. If any operation fails its checks, such as a check-and-set
+mismatch, patching a key that does not exist or a disallowed option, the
+transaction is rejected and no key is changed. If writing to storage fails,
+the keys that were already written are restored to their previous state.
+
+A transaction changes the keys without a request to their data paths, so
+ACL policies cannot restrict the keys it changes, and it requires sudo like
+exports. As for deletes, writes and patches of keys with an owner are
+restricted to the owner when the config enforces ownership. Transactions
+cannot change the healthcheck key. Writes and patches under a mirrored prefix
+are mirrored once the transaction is applied. The response contains the
+transaction ID, which is also included in the events sent for the changed
+keys, and the resulting current version of each key.
+`
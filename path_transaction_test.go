@@ -0,0 +1,341 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// failingMetadataStorage fails a single write of key metadata under prefix
+// once failAfter writes have succeeded.
+type failingMetadataStorage struct {
+	logical.Storage
+	prefix    string
+	failAfter int
+}
+
+func (s *failingMetadataStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if strings.HasPrefix(entry.Key, s.prefix) {
+		s.failAfter--
+		if s.failAfter == -1 {
+			return errors.New("injected failure")
+		}
+	}
+	return s.Storage.Put(ctx, entry)
+}
+
+func TestVersionedKV_Transaction(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	for _, key := range []string{"foo", "baz"} {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": "baz",
+				},
+			},
+		}
+
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "transaction",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"operations": []interface{}{
+				map[string]interface{}{
+					"operation": "patch",
+					"path":      "foo",
+					"data":      map[string]interface{}{"qux": "quux"},
+					"options":   map[string]interface{}{"cas": 1},
+				},
+				map[string]interface{}{
+					"operation": "write",
+					"path":      "new",
+					"data":      map[string]interface{}{"a": "b"},
+					"options":   map[string]interface{}{"cas": 0},
+				},
+				map[string]interface{}{
+					"operation": "delete",
+					"path":      "baz",
+				},
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("transaction UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	results := resp.Data["results"].([]interface{})
+	for i, version := range []uint64{2, 1, 1} {
+		if v := results[i].(map[string]interface{})["version"]; v != version {
+			t.Fatalf("expected version %d for operation %d, got %v", version, i, v)
+		}
+	}
+
+	transactionID := resp.Data["transaction_id"].(string)
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/data-write", "data/baz", "data/baz"},
+		{"kv-v2/data-patch", "data/foo", "data/foo"},
+		{"kv-v2/data-write", "data/new", "data/new"},
+		{"kv-v2/data-delete", "data/baz", ""},
+	})
+	for _, e := range events.eventsProcessed[2:] {
+		if id := e.Event.Metadata.Fields["transaction_id"].GetStringValue(); id != transactionID {
+			t.Fatalf("expected transaction_id %s in event, got %q", transactionID, id)
+		}
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if data := resp.Data["data"].(map[string]interface{}); data["bar"] != "baz" || data["qux"] != "quux" {
+		t.Fatalf("unexpected patched data: %#v", data)
+	}
+
+	req.Path = "data/baz"
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.Data["data"] != nil {
+		t.Fatalf("expected baz to be deleted, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Transaction_Rejected(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": map[string]interface{}{
+				"bar": "baz",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	newWrite := map[string]interface{}{
+		"operation": "write",
+		"path":      "new",
+		"data":      map[string]interface{}{"a": "b"},
+	}
+
+	for name, op := range map[string]map[string]interface{}{
+		"cas mismatch": {
+			"operation": "write",
+			"path":      "foo",
+			"data":      map[string]interface{}{"bar": "qux"},
+			"options":   map[string]interface{}{"cas": 0},
+		},
+		"patch missing key": {
+			"operation": "patch",
+			"path":      "missing",
+			"data":      map[string]interface{}{"bar": "qux"},
+		},
+		"duplicate key": newWrite,
+		"unknown operation": {
+			"operation": "destroy",
+			"path":      "foo",
+		},
+		"unknown option": {
+			"operation": "write",
+			"path":      "foo",
+			"data":      map[string]interface{}{"bar": "qux"},
+			"options":   map[string]interface{}{"unknown": true},
+		},
+	} {
+		req = &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "transaction",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"operations": []interface{}{newWrite, op},
+			},
+		}
+
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected transaction to be rejected, err: %s, resp %#v", name, err, resp)
+		}
+	}
+
+	// A failure writing the metadata of the second key restores the first
+	storagePrefix := b.(*versionedKVBackend).storagePrefix
+	failing := &failingMetadataStorage{
+		Storage:   storage,
+		prefix:    path.Join(storagePrefix, metadataPrefix),
+		failAfter: 1,
+	}
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "transaction",
+		Storage:   failing,
+		Data: map[string]interface{}{
+			"operations": []interface{}{
+				map[string]interface{}{
+					"operation": "write",
+					"path":      "foo",
+					"data":      map[string]interface{}{"bar": "qux"},
+				},
+				newWrite,
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err == nil {
+		t.Fatalf("expected transaction to fail, resp %#v", resp)
+	}
+
+	for key, version := range map[string]uint64{"foo": 1, "new": 0} {
+		meta, err := b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.GetCurrentVersion() != version {
+			t.Fatalf("expected %s to be at version %d after a failed transaction, got %#v", key, version, meta)
+		}
+	}
+
+	versionsPath := path.Join(storagePrefix, versionPrefix) + "/"
+	keys, err := storage.List(context.Background(), versionsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions int
+	for _, k := range keys {
+		entries, err := storage.List(context.Background(), versionsPath+k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions += len(entries)
+	}
+	if versions != 1 {
+		t.Fatalf("expected the version data of the failed transaction to be deleted, found %d versions", versions)
+	}
+}
+
+func TestVersionedKV_Transaction_OwnerAndMirror(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	if !strutil.StrListContains(b.SpecialPaths().Root, "transaction") {
+		t.Fatalf("expected transactions to require sudo: %v", b.SpecialPaths().Root)
+	}
+
+	for _, req := range []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"owner_enforced": true,
+				"mirrors":        map[string]interface{}{"src/": "dst/"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata/foo",
+			Data: map[string]interface{}{
+				"owner": "alice",
+			},
+		},
+	} {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	for _, operation := range []string{"write", "patch", "delete"} {
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "transaction",
+			Storage:   storage,
+			EntityID:  "bob",
+			Data: map[string]interface{}{
+				"operations": []interface{}{
+					map[string]interface{}{
+						"operation": operation,
+						"path":      "foo",
+						"data":      map[string]interface{}{"bar": "qux"},
+					},
+				},
+			},
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != logical.ErrPermissionDenied || resp == nil || !resp.IsError() {
+			t.Fatalf("expected a %s of another entity's key to be denied, err: %s, resp %#v", operation, err, resp)
+		}
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "transaction",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"operations": []interface{}{
+				map[string]interface{}{
+					"operation": "write",
+					"path":      "src/foo",
+					"data":      map[string]interface{}{"bar": "baz"},
+				},
+			},
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("transaction failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/dst/foo",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("expected the transaction write to be mirrored, resp: %#v", resp)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathUpgradeStatus returns the path configuration for the upgrade status
+// endpoint. Unlike the other endpoints it is served during the upgrade.
+func pathUpgradeStatus(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "upgrade/status$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "read",
+			OperationSuffix: "upgrade-status",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathUpgradeStatusRead(),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"upgrading": {
+								Type:        framework.TypeBool,
+								Description: "True if requests are rejected until the upgrade finishes.",
+								Required:    true,
+							},
+							"done": {
+								Type:        framework.TypeBool,
+								Description: "True if the upgrade finished.",
+								Required:    true,
+							},
+							"started_time": {
+								Type:     framework.TypeString,
+								Required: true,
+							},
+							"heartbeat_time": {
+								Type:        framework.TypeString,
+								Description: "The last time the instance running the upgrade recorded its progress.",
+								Required:    true,
+							},
+							"keys_total": {
+								Type:        framework.TypeInt64,
+								Description: "The number of keys to upgrade, zero until they are collected.",
+								Required:    true,
+							},
+							"keys_processed": {
+								Type:        framework.TypeInt64,
+								Description: "The number of keys upgraded.",
+								Required:    true,
+							},
+							"estimated_time_remaining": {
+								Type:        framework.TypeString,
+								Description: "The estimated time until the upgrade finishes, from its progress so far. Empty if unknown.",
+								Required:    true,
+							},
+							"last_error": {
+								Type:        framework.TypeString,
+								Description: "The error that stopped the upgrade, if any.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    upgradeStatusHelpSyn,
+		HelpDescription: upgradeStatusHelpDesc,
+	}
+}
+
+func (b *versionedKVBackend) pathUpgradeStatusRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		info, err := b.upgradeInfo(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+
+		// The canary holds the progress as of the last heartbeat, the
+		// instance running the upgrade knows the current progress.
+		total, processed := info.KeysTotal, info.KeysProcessed
+		if !info.Done && info.OwnerId == b.upgradeOwnerID {
+			total = atomic.LoadUint64(b.upgradeKeysTotal)
+			processed = atomic.LoadUint64(b.upgradeKeysProcessed)
+		}
+
+		var remaining string
+		if !info.Done && info.LastError == "" && processed > 0 && total > processed && info.StartedTime != nil {
+			started, err := ptypes.Timestamp(info.StartedTime)
+			if err == nil {
+				elapsed := b.now().Sub(started)
+				remaining = (elapsed / time.Duration(processed) * time.Duration(total-processed)).Round(time.Second).String()
+			}
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"upgrading":                atomic.LoadUint32(b.upgrading) == 1,
+				"done":                     info.Done,
+				"started_time":             ptypesTimestampToString(info.StartedTime),
+				"heartbeat_time":           ptypesTimestampToString(info.HeartbeatTime),
+				"keys_total":               total,
+				"keys_processed":           processed,
+				"estimated_time_remaining": remaining,
+				"last_error":               info.LastError,
+			},
+		}, nil
+	}
+}
+
+const upgradeStatusHelpSyn = `Returns the progress of the upgrade from non-versioned data.`
+const upgradeStatusHelpDesc = `
+This endpoint returns whether the mount is upgrading its data from a
+non-versioned K/V store, in which case other requests are rejected until the
+upgrade finishes, along with the progress of the upgrade: the number of keys
+to upgrade and upgraded so far, when the upgrade started and an estimate of
+the time remaining.
+
+The instance running the upgrade returns its current progress. Other
+instances, such as performance standbys, return the progress recorded by the
+instance running the upgrade with its heartbeat, every 30 seconds. If an error
+stopped the upgrade, it is returned in "last_error"; the upgrade is retried
+when the mount is next initialized.
+`
@@ -0,0 +1,166 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// pathV1Data returns the path configuration for the read-only v1-data
+// endpoint
+func pathV1Data(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "v1-data/" + matchAllNoTrailingSlashRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "read",
+			OperationSuffix: "v1-data",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+		},
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.recordVersionReads(b.pathV1DataRead())),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields:      nil, // dynamic fields
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    v1DataHelpSyn,
+		HelpDescription: v1DataHelpDesc,
+	}
+}
+
+// pathV1DataRead handles ReadOperation requests for a specified path. The
+// current version of the key is returned with the secret data as the top-level
+// response data, the same as a read from a KV v1 mount.
+func (b *versionedKVBackend) pathV1DataRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		if b.metadataOnly {
+			return valueReadDenied()
+		}
+
+		key := data.Get("path").(string)
+
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if !config.V1DataEnabled {
+			return logical.ErrorResponse("the v1-data path is not enabled on this mount"), logical.ErrUnsupportedPath
+		}
+
+		wrapTTL, err := requiredWrapTTL(req, config, key)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+		}
+
+		// A version found due to be destroyed by destroy_version_after is
+		// destroyed after the read lock is released, as in data reads.
+		var destroyDue bool
+		defer func() {
+			if !destroyDue {
+				return
+			}
+			if err := b.destroyDueVersionsOfKey(ctx, req.Storage, key, b.now()); err != nil && err != logical.ErrReadOnly {
+				requestLogger(ctx, b.Logger()).Warn("failed to destroy versions due to be destroyed", "key", key, "error", err)
+			}
+		}()
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.RLock()
+		defer lock.RUnlock()
+
+		meta, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			return nil, nil
+		}
+
+		// A KV v1 read cannot provide a passphrase
+		if meta.readPassphraseRequired() {
+			return readPassphraseDenied()
+		}
+
+		// A KV v1 mount has no notion of deleted or destroyed data, so those
+		// versions are simply not found.
+		vm := meta.Versions[meta.CurrentVersion]
+		if vm == nil || vm.Destroyed {
+			return nil, nil
+		}
+		if versionDestroyDue(config, meta, vm, b.now()) {
+			destroyDue = true
+			return nil, nil
+		}
+
+		if vm.DeletionTime != nil {
+			deletionTime, err := ptypes.Timestamp(vm.DeletionTime)
+			if err != nil {
+				return nil, err
+			}
+
+			if deletionTime.Before(b.now()) {
+				return nil, nil
+			}
+		}
+
+		version, err := b.getVersion(ctx, req.Storage, key, meta.CurrentVersion)
+		if err != nil {
+			return nil, err
+		}
+		if version == nil {
+			return nil, errors.New("could not find version data")
+		}
+
+		vData, err := b.versionData(version)
+		if err != nil {
+			return nil, err
+		}
+		b.noteDataRead(ctx, req.Storage, config, "v1-data/"+key, key, meta.CurrentVersion)
+
+		resp := &logical.Response{
+			Data: vData,
+		}
+		if wrapTTL > 0 {
+			resp.WrapInfo = &wrapping.ResponseWrapInfo{
+				TTL: wrapTTL,
+			}
+		}
+
+		b.noteDeprecatedRead(ctx, req.Storage, meta, resp, "v1-data/"+key)
+		return resp, nil
+	}
+}
+
+const v1DataHelpSyn = `Read data from the Key-Value store in the KV v1 response format.`
+const v1DataHelpDesc = `
+This endpoint returns the current version of the secret at the requested path
+using the KV v1 response format: the secret data is returned directly, without
+the nested "data" and "metadata" objects of the versioned data endpoint. It is
+intended to ease migrations for clients that can only parse KV v1 responses.
+
+Deleted and destroyed versions are treated as missing. This endpoint is
+read-only and must be enabled with the "v1_data_enabled" config parameter. It
+is unavailable on mounts in metadata-only mode.
+`
@@ -0,0 +1,133 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_V1Data_Disabled(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "v1-data/foo",
+		Storage:   storage,
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != logical.ErrUnsupportedPath {
+		t.Fatalf("expected v1-data read to be unsupported, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_V1Data_Read(t *testing.T) {
+	b, storage := getBackend(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"v1_data_enabled": true,
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "v1-data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp != nil {
+		t.Fatalf("expected no response for a missing key, err: %s, resp %#v", err, resp)
+	}
+
+	data := map[string]interface{}{
+		"bar": "baz",
+		"nested": map[string]interface{}{
+			"a": "b",
+		},
+	}
+
+	req = &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"data": data,
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "v1-data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("v1-data ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	if diff := deep.Equal(resp.Data, data); len(diff) > 0 {
+		t.Fatalf("v1-data response mismatch, diff: %#v", diff)
+	}
+
+	req = &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("data DeleteOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "v1-data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp != nil {
+		t.Fatalf("expected no response for a deleted key, err: %s, resp %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_V1Data_DestroyDue(t *testing.T) {
+	b, storage := getBackend(t)
+	writeDestroyDueVersion(t, b, storage, map[string]interface{}{"v1_data_enabled": true})
+
+	// Version 2 is due too once the clock passes its destroy time
+	b.(*versionedKVBackend).clock.(*fakeClock).advance(30 * time.Minute)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "v1-data/foo",
+		Storage:   storage,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("expected no response for a version due to be destroyed, err: %s, resp %#v", err, resp)
+	}
+	expectVersionDestroyed(t, b, storage, "foo", 2)
+}
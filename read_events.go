@@ -0,0 +1,55 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// readEventsEnabled returns true if the key is under one of the
+// read_event_prefixes of the config.
+func (c *Configuration) readEventsEnabled(key string) bool {
+	for _, prefix := range c.ReadEventPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// noteDataRead notes a read of the data of a version of a key through the
+// path of the request, which recordVersionReads records once the request
+// succeeded. If the key is under the read_event_prefixes of the config, a
+// data-read event is sent which, like other events, carries the entity and
+// address of the request. Reads of the healthcheck key send no event, as its
+// writes do not either.
+func (b *versionedKVBackend) noteDataRead(ctx context.Context, s logical.Storage, config *Configuration, path, key string, version uint64) {
+	queueVersionRead(ctx, key, version)
+
+	if config.isHealthcheckPath(key) || !config.readEventsEnabled(key) {
+		return
+	}
+
+	b.emitEvent(ctx, s, "data-read", path, "data/"+key, false,
+		"version", strconv.FormatUint(version, 10),
+	)
+}
+
+// noteSubkeysRead sends a subkeys-read event for a read of the subkeys of a
+// version of a key under the read_event_prefixes of the config, which
+// reveals the structure of its data. Reads of the healthcheck key send no
+// event.
+func (b *versionedKVBackend) noteSubkeysRead(ctx context.Context, s logical.Storage, config *Configuration, key string, version uint64) {
+	if config.isHealthcheckPath(key) || !config.readEventsEnabled(key) {
+		return
+	}
+
+	b.emitEvent(ctx, s, "subkeys-read", "subkeys/"+key, "data/"+key, false,
+		"version", strconv.FormatUint(version, 10),
+	)
+}
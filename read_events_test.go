@@ -0,0 +1,216 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_ReadEvents(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"read_event_prefixes": "sensitive/",
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/sensitive/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "baz"},
+			},
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "data/sensitive/foo",
+			EntityID:  "entity-id",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "data/sensitive/foo",
+			Data: map[string]interface{}{
+				"metadata_only": true,
+			},
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "data/foo",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "subkeys/sensitive/foo",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "subkeys/foo",
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", configPath, configPath},
+		{"kv-v2/data-write", "data/sensitive/foo", "data/sensitive/foo"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/data-read", "data/sensitive/foo", "data/sensitive/foo"},
+		{"kv-v2/subkeys-read", "subkeys/sensitive/foo", "data/sensitive/foo"},
+	})
+	metadata := events.eventsProcessed[3].Event.Metadata.Fields
+	if got := metadata["version"].GetStringValue(); got != "1" {
+		t.Fatalf("expected the version read in the event metadata, got %v", got)
+	}
+	if got := metadata["entity_id"].GetStringValue(); got != "entity-id" {
+		t.Fatalf("expected the entity of the read in the event metadata, got %v", got)
+	}
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("config ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if got := resp.Data["read_event_prefixes"].([]string); len(got) != 1 || got[0] != "sensitive/" {
+		t.Fatalf("unexpected read_event_prefixes: %#v", got)
+	}
+}
+
+func TestVersionedKV_ReadEvents_HealthcheckPath(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"read_event_prefixes": "health/",
+				"healthcheck_path":    "health/probe",
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/health/probe",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"probe": "a"},
+			},
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "data/health/probe",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "subkeys/health/probe",
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	// Probes of the healthcheck key send no events
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", configPath, configPath},
+	})
+}
+
+func TestVersionedKV_ReadEvents_Endpoints(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"read_event_prefixes": "sensitive/",
+				"v1_data_enabled":     true,
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/sensitive/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "one"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "data/sensitive/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "two"},
+			},
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "v1-data/sensitive/foo",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "export/sensitive/foo",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "diff/sensitive/foo",
+			Data: map[string]interface{}{
+				"include_values": true,
+			},
+		},
+		{
+			// The values are not returned, so nothing is read
+			Operation: logical.ReadOperation,
+			Path:      "diff/sensitive/foo",
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", configPath, configPath},
+		{"kv-v2/data-write", "data/sensitive/foo", "data/sensitive/foo"},
+		{"kv-v2/data-write", "data/sensitive/foo", "data/sensitive/foo"},
+		{"kv-v2/data-read", "v1-data/sensitive/foo", "data/sensitive/foo"},
+		{"kv-v2/data-read", "export/sensitive/foo", "data/sensitive/foo"},
+		{"kv-v2/data-read", "export/sensitive/foo", "data/sensitive/foo"},
+		{"kv-v2/data-read", "diff/sensitive/foo", "data/sensitive/foo"},
+		{"kv-v2/data-read", "diff/sensitive/foo", "data/sensitive/foo"},
+	})
+	for i, expected := range []string{"2", "1", "2", "1", "2"} {
+		metadata := events.eventsProcessed[3+i].Event.Metadata.Fields
+		if got := metadata["version"].GetStringValue(); got != expected {
+			t.Fatalf("expected version %s in the metadata of event %d, got %v", expected, 3+i, got)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// maxPendingReadKeys is the largest number of keys whose reads are counted
+// in memory between two flushes. Reads of further keys are not counted, so
+// that a burst of reads of many keys cannot grow the pending reads unbounded.
+const maxPendingReadKeys = 10000
+
+// pendingRead counts the reads of a version that were not persisted yet.
+type pendingRead struct {
+	count uint64
+	last  time.Time
+}
+
+// countVersionRead counts a read of the data of a version in memory, to be
+// persisted by the next flushReadStats. Reads are not counted where the
+// periodic function cannot write the counts, such as performance standbys
+// and secondaries.
+func (b *versionedKVBackend) countVersionRead(key string, version uint64, now time.Time) {
+	if b.perfSecondaryCheck() || b.System().ReplicationState().HasState(consts.ReplicationDRSecondary) {
+		return
+	}
+
+	b.pendingReadsLock.Lock()
+	defer b.pendingReadsLock.Unlock()
+
+	versions, ok := b.pendingReads[key]
+	if !ok {
+		if len(b.pendingReads) >= maxPendingReadKeys {
+			return
+		}
+		versions = map[uint64]*pendingRead{}
+		b.pendingReads[key] = versions
+	}
+
+	p, ok := versions[version]
+	if !ok {
+		p = &pendingRead{}
+		versions[version] = p
+	}
+	p.count++
+	p.last = now
+}
+
+// flushReadStats adds the reads counted since the previous flush to the
+// read_count and last_read_time of the versions and keys read. Batching the
+// reads avoids turning every read into a write of the key metadata. A key
+// whose metadata cannot be written keeps its reads for the next flush.
+func (b *versionedKVBackend) flushReadStats(ctx context.Context, s logical.Storage) error {
+	b.pendingReadsLock.Lock()
+	pending := b.pendingReads
+	b.pendingReads = map[string]map[uint64]*pendingRead{}
+	b.pendingReadsLock.Unlock()
+
+	keys := make([]string, 0, len(pending))
+	for key := range pending {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var firstErr error
+	for _, key := range keys {
+		if err := b.flushKeyReadStats(ctx, s, key, pending[key]); err != nil {
+			b.restorePendingReads(key, pending[key])
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// flushKeyReadStats persists the pending reads of the versions of a key.
+// Reads of versions that no longer exist are dropped.
+func (b *versionedKVBackend) flushKeyReadStats(ctx context.Context, s logical.Storage, key string, versions map[uint64]*pendingRead) error {
+	lock := locksutil.LockForKey(b.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	meta, err := b.getKeyMetadata(ctx, s, key)
+	if err != nil || meta == nil {
+		return err
+	}
+
+	var changed bool
+	for verNum, p := range versions {
+		vm := meta.Versions[verNum]
+		if vm == nil {
+			continue
+		}
+
+		vm.ReadCount += p.count
+		if vm.LastReadTime, err = laterTimestamp(vm.LastReadTime, p.last); err != nil {
+			return err
+		}
+		if meta.LastReadTime, err = laterTimestamp(meta.LastReadTime, p.last); err != nil {
+			return err
+		}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	return b.writeKeyMetadata(ctx, s, meta)
+}
+
+// restorePendingReads adds back the reads of a key that could not be
+// persisted, so that the next flush retries them.
+func (b *versionedKVBackend) restorePendingReads(key string, versions map[uint64]*pendingRead) {
+	b.pendingReadsLock.Lock()
+	defer b.pendingReadsLock.Unlock()
+
+	current, ok := b.pendingReads[key]
+	if !ok {
+		b.pendingReads[key] = versions
+		return
+	}
+
+	for verNum, p := range versions {
+		if c, ok := current[verNum]; ok {
+			c.count += p.count
+			if p.last.After(c.last) {
+				c.last = p.last
+			}
+			continue
+		}
+		current[verNum] = p
+	}
+}
+
+// laterTimestamp returns the later of the timestamp and t.
+func laterTimestamp(ts *timestamp.Timestamp, t time.Time) (*timestamp.Timestamp, error) {
+	if ts != nil {
+		current, err := ptypes.Timestamp(ts)
+		if err == nil && !current.Before(t) {
+			return ts, nil
+		}
+	}
+	return ptypes.TimestampProto(t)
+}
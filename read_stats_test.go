@@ -0,0 +1,235 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_ReadStats(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "one"},
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "two"},
+			},
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "data/foo",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "data/foo",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"version": 1,
+			},
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"metadata_only": true,
+			},
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	readMetadata := func() map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "metadata/foo",
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("metadata ReadOperation request failed, err: %s, resp %#v", err, resp)
+		}
+		return resp.Data
+	}
+
+	// Reads are only persisted by a flush
+	data := readMetadata()
+	if data["last_read_time"] != "" {
+		t.Fatalf("expected no persisted reads before the flush, got: %#v", data["last_read_time"])
+	}
+
+	if err := kv.flushReadStats(ctx, storage); err != nil {
+		t.Fatal(err)
+	}
+
+	data = readMetadata()
+	if data["last_read_time"] == "" {
+		t.Fatal("expected the last read time of the key to be set")
+	}
+	versions := data["versions"].(map[string]interface{})
+	for version, expected := range map[string]uint64{"1": 1, "2": 2} {
+		vm := versions[version].(map[string]interface{})
+		if vm["read_count"] != expected || vm["last_read_time"] == "" {
+			t.Fatalf("unexpected read stats of version %s: %#v", version, vm)
+		}
+	}
+
+	// A second flush without new reads changes nothing
+	if err := kv.flushReadStats(ctx, storage); err != nil {
+		t.Fatal(err)
+	}
+	versions = readMetadata()["versions"].(map[string]interface{})
+	if versions["2"].(map[string]interface{})["read_count"] != uint64(2) {
+		t.Fatalf("unexpected read stats: %#v", versions["2"])
+	}
+}
+
+func TestVersionedKV_ReadStats_NotTracked(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", operation, path, err, resp)
+		}
+		return resp
+	}
+	readCount := func(key string) interface{} {
+		t.Helper()
+		if err := kv.flushReadStats(ctx, storage); err != nil {
+			t.Fatal(err)
+		}
+		versions := request(logical.ReadOperation, "metadata/"+key, nil).Data["versions"].(map[string]interface{})
+		return versions["1"].(map[string]interface{})["read_count"]
+	}
+
+	request(logical.UpdateOperation, "config", map[string]interface{}{"healthcheck_path": "health/probe"})
+	for _, key := range []string{"foo", "health/probe"} {
+		request(logical.CreateOperation, "data/"+key, map[string]interface{}{
+			"data": map[string]interface{}{"bar": "baz"},
+		})
+	}
+
+	// Reads of the healthcheck key are not counted
+	request(logical.ReadOperation, "data/health/probe", nil)
+	if count := readCount("health/probe"); count != uint64(0) {
+		t.Fatalf("expected no reads of the healthcheck key to be counted, got %v", count)
+	}
+
+	// Nor are reads while read tracking is disabled
+	request(logical.UpdateOperation, "config/features", map[string]interface{}{
+		"features": map[string]interface{}{featureReadTracking: false},
+	})
+	request(logical.ReadOperation, "data/foo", nil)
+	if count := readCount("foo"); count != uint64(0) {
+		t.Fatalf("expected no reads to be counted, got %v", count)
+	}
+
+	request(logical.UpdateOperation, "config/features", map[string]interface{}{
+		"features": map[string]interface{}{featureReadTracking: true},
+	})
+	request(logical.ReadOperation, "data/foo", nil)
+	if count := readCount("foo"); count != uint64(1) {
+		t.Fatalf("expected the read to be counted, got %v", count)
+	}
+}
+
+func TestVersionedKV_ReadStats_Endpoints(t *testing.T) {
+	b, storage := getBackend(t)
+	kv := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	requests := []*logical.Request{
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "config",
+			Data: map[string]interface{}{
+				"v1_data_enabled": true,
+			},
+		},
+		{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "one"},
+			},
+		},
+		{
+			Operation: logical.UpdateOperation,
+			Path:      "data/foo",
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": "two"},
+			},
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "v1-data/foo",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "export/foo",
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "diff/foo",
+			Data: map[string]interface{}{
+				"include_values": true,
+			},
+		},
+		{
+			Operation: logical.ReadOperation,
+			Path:      "diff/foo",
+		},
+	}
+	for _, req := range requests {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	if err := kv.flushReadStats(ctx, storage); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := kv.getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for version, expected := range map[uint64]uint64{1: 2, 2: 3} {
+		if got := meta.Versions[version].ReadCount; got != expected {
+			t.Fatalf("expected %d reads of version %d, got %d", expected, version, got)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+)
+
+// maxVersionAge returns the age after which versions of the key are pruned,
+// preferring the key's setting over the backend's. Zero is returned if
+// versions are not pruned by age.
+func maxVersionAge(config *Configuration, meta *KeyMetadata) time.Duration {
+	if age := durationOrZero(meta.GetMaxVersionAge()); age > 0 {
+		return age
+	}
+	return durationOrZero(config.GetMaxVersionAge())
+}
+
+// minVersions returns the number of newest versions of the key that are kept
+// regardless of their age, preferring the key's setting over the backend's.
+func minVersions(config *Configuration, meta *KeyMetadata) uint32 {
+	if meta.MinVersions > 0 {
+		return meta.MinVersions
+	}
+	return config.MinVersions
+}
+
+// pruneAgedVersions removes the versions created more than maxAge before now
+// from the key metadata, oldest first, keeping at least the newest
+// minVersions versions and always the current version. It returns the newest
+// pruned version, whose data and the data of the versions before it are to be
+// deleted from storage, or 0 if no version was pruned. If retainPruned is
+// true, pruned versions that have a deletion time in the future are recorded
+// as retained, like versions pruned by max_versions.
+func (k *KeyMetadata) pruneAgedVersions(maxAge time.Duration, minVersions uint32, now time.Time, retainPruned bool) uint64 {
+	if maxAge <= 0 {
+		return 0
+	}
+	cutoff := now.Add(-maxAge)
+
+	var pruned uint64
+	for v := k.OldestVersion; v < k.CurrentVersion; v++ {
+		if k.CurrentVersion-v+1 <= uint64(minVersions) {
+			break
+		}
+
+		if vm := k.Versions[v]; vm != nil {
+			createdTime, err := ptypes.Timestamp(vm.CreatedTime)
+			if err != nil || createdTime.After(cutoff) {
+				break
+			}
+
+			if retainPruned {
+				k.retainVersion(v, now)
+			}
+			delete(k.Versions, v)
+		}
+		pruned = v
+	}
+
+	if pruned > 0 {
+		k.OldestVersion = pruned + 1
+	}
+	return pruned
+}
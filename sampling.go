@@ -0,0 +1,45 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// parseEventSampleRates validates the event_sample_rates setting of the
+// config. Each rate must be a number between 0 and 1.
+func parseEventSampleRates(raw map[string]interface{}) (map[string]float64, error) {
+	rates := make(map[string]float64, len(raw))
+	for prefix, v := range raw {
+		rate, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid event_sample_rates for prefix %q: must be a number between 0 and 1", prefix)
+		}
+		rates[prefix] = rate
+	}
+
+	return rates, nil
+}
+
+// eventSampleRate returns the fraction of events about the key that are
+// sent. The most specific prefix of the event_sample_rates matching the key
+// applies, and events about keys matching no prefix are always sent.
+func (c *Configuration) eventSampleRate(key string) float64 {
+	rate, matched := 1.0, ""
+	for prefix, r := range c.EventSampleRates {
+		if strings.HasPrefix(key, prefix) && len(prefix) >= len(matched) {
+			rate, matched = r, prefix
+		}
+	}
+
+	return rate
+}
+
+// sampled randomly returns true with the probability of rate.
+func sampled(rate float64) bool {
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate)
+}
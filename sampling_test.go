@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_EventSampleRates(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"event_sample_rates": map[string]interface{}{
+				"ci-scratch/":      0,
+				"ci-scratch/keep/": 1,
+				"sampled/":         "0.5",
+			},
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config UpdateOperation request failed, err: %s, resp %#v", err, resp)
+	}
+
+	for _, key := range []string{"prod/foo", "ci-scratch/foo", "ci-scratch/keep/foo"} {
+		req = &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/" + key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{
+					"bar": "baz",
+				},
+			},
+		}
+
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/config-write", "config", "config"},
+		{"kv-v2/data-write", "data/prod/foo", "data/prod/foo"},
+		{"kv-v2/data-write", "data/ci-scratch/keep/foo", "data/ci-scratch/keep/foo"},
+	})
+
+	// Unsampled changes are still recorded in the changelog
+	meta, err := b.(*versionedKVBackend).getKeyMetadata(context.Background(), storage, "ci-scratch/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.EventSequence != 1 {
+		t.Fatalf("expected event sequence 1, got %d", meta.EventSequence)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "config",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("config ReadOperation request failed, err: %s, resp %#v", err, resp)
+	}
+	if rates := resp.Data["event_sample_rates"].(map[string]float64); rates["sampled/"] != 0.5 || len(rates) != 3 {
+		t.Fatalf("unexpected event_sample_rates: %#v", rates)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"event_sample_rates": map[string]interface{}{
+				"prod/": 2,
+			},
+		},
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected a sample rate over 1 to be rejected, err: %s, resp %#v", err, resp)
+	}
+}
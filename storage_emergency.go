@@ -0,0 +1,184 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// storageUsageScanInterval is how often the periodic function recounts the
+// storage used by the version data of the mount, if the
+// storage_emergency_threshold of the config is set.
+const storageUsageScanInterval = time.Hour
+
+// storageUsage tracks the total size of the version data of the mount, as
+// counted by the last usage scan and adjusted by the writes and deletes on
+// this instance since, and whether it is over the
+// storage_emergency_threshold of the config. Writes on other nodes of the
+// cluster are only accounted for by the next scan.
+type storageUsage struct {
+	lock      sync.Mutex
+	bytes     uint64
+	lastScan  time.Time
+	emergency bool
+}
+
+// usageState returns the current usage in bytes and whether the mount is in
+// a storage emergency.
+func (b *versionedKVBackend) usageState() (uint64, bool) {
+	b.usage.lock.Lock()
+	defer b.usage.lock.Unlock()
+	return b.usage.bytes, b.usage.emergency
+}
+
+// checkStorageEmergency returns an error if growing the version data of the
+// mount by growth bytes would exceed the storage_emergency_threshold of the
+// config. Only writes adding data are rejected, so that reads, deletes and
+// destroys still work and can free storage. The mount enters the storage
+// emergency with the first rejected write.
+func (b *versionedKVBackend) checkStorageEmergency(ctx context.Context, s logical.Storage, config *Configuration, growth int64) error {
+	threshold := config.StorageEmergencyThreshold
+	if threshold == 0 || growth <= 0 {
+		return nil
+	}
+
+	b.usage.lock.Lock()
+	usage := b.usage.bytes
+	if usage+uint64(growth) <= threshold {
+		b.usage.lock.Unlock()
+		return nil
+	}
+	entered := !b.usage.emergency
+	b.usage.emergency = true
+	b.usage.lock.Unlock()
+
+	incrCounter(config, []string{"secrets", "kv", "storage_emergency_rejected"}, 1)
+	if entered {
+		b.Logger().Warn("storage usage exceeds the storage_emergency_threshold, rejecting writes of new versions", "usage_bytes", usage, "threshold_bytes", threshold)
+		b.emitStorageEmergencyEvent(ctx, s, "active", usage, threshold)
+	}
+
+	return logical.CodedError(http.StatusInsufficientStorage, fmt.Sprintf("storage emergency: the version data of the mount uses %d bytes, "+
+		"writing %d more bytes would exceed the storage_emergency_threshold of %d bytes; delete or destroy versions to free storage", usage, growth, threshold))
+}
+
+// adjustStorageUsage records a change of the size of the version data of
+// the mount by delta bytes, and ends the storage emergency once the usage is
+// back under the storage_emergency_threshold of the config.
+func (b *versionedKVBackend) adjustStorageUsage(ctx context.Context, s logical.Storage, config *Configuration, delta int64) {
+	if delta == 0 {
+		return
+	}
+
+	b.usage.lock.Lock()
+	switch {
+	case delta > 0:
+		b.usage.bytes += uint64(delta)
+	case uint64(-delta) > b.usage.bytes:
+		b.usage.bytes = 0
+	default:
+		b.usage.bytes -= uint64(-delta)
+	}
+	b.usage.lock.Unlock()
+
+	b.updateStorageEmergency(ctx, s, config)
+}
+
+// updateStorageEmergency ends the storage emergency if the usage is no
+// longer over the storage_emergency_threshold of the config, or the
+// threshold is disabled.
+func (b *versionedKVBackend) updateStorageEmergency(ctx context.Context, s logical.Storage, config *Configuration) {
+	threshold := config.StorageEmergencyThreshold
+
+	b.usage.lock.Lock()
+	usage := b.usage.bytes
+	cleared := b.usage.emergency && (threshold == 0 || usage <= threshold)
+	if cleared {
+		b.usage.emergency = false
+	}
+	b.usage.lock.Unlock()
+
+	if cleared {
+		b.Logger().Info("storage usage is back under the storage_emergency_threshold, accepting writes", "usage_bytes", usage, "threshold_bytes", threshold)
+		b.emitStorageEmergencyEvent(ctx, s, "cleared", usage, threshold)
+	}
+}
+
+// emitStorageEmergencyEvent sends the kv-v2/storage-emergency event of the
+// mount entering or leaving the storage emergency.
+func (b *versionedKVBackend) emitStorageEmergencyEvent(ctx context.Context, s logical.Storage, state string, usage, threshold uint64) {
+	b.emitEvent(ctx, s, "storage-emergency", configPath, "", false,
+		"state", state,
+		"usage_bytes", strconv.FormatUint(usage, 10),
+		"threshold_bytes", strconv.FormatUint(threshold, 10),
+	)
+}
+
+// resetStorageUsageScan makes the next periodic function recount the usage,
+// as when the storage_emergency_threshold is changed.
+func (b *versionedKVBackend) resetStorageUsageScan() {
+	b.usage.lock.Lock()
+	defer b.usage.lock.Unlock()
+	b.usage.lastScan = time.Time{}
+}
+
+// storageUsageScan counts the version data of every key in the mount, once
+// every storageUsageScanInterval if the storage_emergency_threshold of the
+// config is set, so that the usage includes the writes of other nodes of the
+// cluster and data written before the threshold was set. A scan also runs
+// after the threshold is changed.
+func (b *versionedKVBackend) storageUsageScan(ctx context.Context, s logical.Storage, now time.Time) error {
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+	if config.StorageEmergencyThreshold == 0 {
+		b.updateStorageEmergency(ctx, s, config)
+		return nil
+	}
+
+	b.usage.lock.Lock()
+	lastScan := b.usage.lastScan
+	b.usage.lock.Unlock()
+	if !lastScan.IsZero() && now.Sub(lastScan) < storageUsageScanInterval {
+		return nil
+	}
+
+	var total uint64
+	err = b.walkKeys(ctx, s, "", func(key string) error {
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.RLock()
+		defer lock.RUnlock()
+
+		meta, err := b.getKeyMetadata(ctx, s, key)
+		if err != nil {
+			b.Logger().Error("storage usage scan skipped key", "key", key, "error", err)
+			return nil
+		}
+		if meta != nil {
+			total += meta.storedBytes()
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("storage usage scan failed: %w", err)
+	}
+
+	b.usage.lock.Lock()
+	b.usage.bytes = total
+	b.usage.lastScan = now
+	b.usage.lock.Unlock()
+
+	setGauge(config, []string{"secrets", "kv", "storage_usage_bytes"}, float32(total))
+	b.updateStorageEmergency(ctx, s, config)
+	return nil
+}
@@ -0,0 +1,248 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_StorageEmergency(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	mustRequest := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := request(operation, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", operation, path, err, resp)
+		}
+		return resp
+	}
+	readConfig := func() map[string]interface{} {
+		t.Helper()
+		req := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "config",
+			Storage:   storage,
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("config read failed, err: %s, resp %#v", err, resp)
+		}
+		schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route(req.Path), req.Operation), resp, true)
+		return resp.Data
+	}
+	write := func(key string) error {
+		t.Helper()
+		resp, err := request(logical.UpdateOperation, "data/"+key, map[string]interface{}{
+			"data": map[string]interface{}{"value": "0123456789"},
+		})
+		if err == nil && resp != nil && resp.IsError() {
+			t.Fatalf("unexpected error response: %#v", resp)
+		}
+		return err
+	}
+
+	// Data written before the threshold is set is counted by the first scan
+	if err := write("existing"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := request(logical.UpdateOperation, "config", map[string]interface{}{"storage_emergency_threshold": -1})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a negative threshold to be rejected, err: %v, resp: %#v", err, resp)
+	}
+	mustRequest(logical.UpdateOperation, "config", map[string]interface{}{"storage_emergency_threshold": 100})
+	if err := kvb.storageUsageScan(ctx, storage, now); err != nil {
+		t.Fatal(err)
+	}
+	config := readConfig()
+	if config["storage_emergency_threshold"] != uint64(100) || config["storage_usage_bytes"] != uint64(22) || config["storage_emergency"] != false {
+		t.Fatalf("unexpected config: %#v", config)
+	}
+
+	events.eventsProcessed = nil
+	for i := 0; i < 3; i++ {
+		if err := write("foo"); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+
+	// The next write exceeds the threshold
+	err = write("foo")
+	var coded logical.HTTPCodedError
+	if !errors.As(err, &coded) || coded.Code() != http.StatusInsufficientStorage {
+		t.Fatalf("expected a 507 error, got %v", err)
+	}
+	if err := write("bar"); err == nil {
+		t.Fatal("expected writes to other keys to be rejected as well")
+	}
+	config = readConfig()
+	if config["storage_usage_bytes"] != uint64(88) || config["storage_emergency"] != true {
+		t.Fatalf("unexpected config: %#v", config)
+	}
+	resp = mustRequest(logical.ReadOperation, "metadata/foo", nil)
+	if resp.Data["current_version"] != uint64(3) {
+		t.Fatalf("expected the rejected write to add no version: %#v", resp.Data)
+	}
+
+	// Reads, metadata writes and deletes still work
+	resp = mustRequest(logical.ReadOperation, "data/foo", nil)
+	if resp.Data["data"].(map[string]interface{})["value"] != "0123456789" {
+		t.Fatalf("unexpected data: %#v", resp.Data)
+	}
+	mustRequest(logical.UpdateOperation, "metadata/foo", map[string]interface{}{"custom_metadata": map[string]interface{}{"a": "b"}})
+	mustRequest(logical.DeleteOperation, "data/foo", nil)
+
+	// Destroying versions frees storage and ends the emergency
+	mustRequest(logical.UpdateOperation, "destroy/foo", map[string]interface{}{"versions": []int{1, 2}})
+	config = readConfig()
+	if config["storage_usage_bytes"] != uint64(44) || config["storage_emergency"] != false {
+		t.Fatalf("unexpected config: %#v", config)
+	}
+	if err := write("bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/storage-emergency", configPath, ""},
+		{"kv-v2/metadata-write", "metadata/foo", "metadata/foo"},
+		{"kv-v2/data-delete", "data/foo", ""},
+		{"kv-v2/storage-emergency", configPath, ""},
+		{"kv-v2/destroy", "destroy/foo", ""},
+		{"kv-v2/data-write", "data/bar", "data/bar"},
+	})
+	for i, state := range map[int]string{3: "active", 6: "cleared"} {
+		if got := events.eventsProcessed[i].Event.Metadata.Fields["state"].GetStringValue(); got != state {
+			t.Fatalf("expected event %d to have state %q, got %q", i, state, got)
+		}
+	}
+
+	// The scan recounts the usage, including writes of other nodes
+	if err := kvb.storageUsageScan(ctx, storage, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	kvb.adjustStorageUsage(ctx, storage, &Configuration{}, 1000)
+	if err := kvb.storageUsageScan(ctx, storage, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if config := readConfig(); config["storage_usage_bytes"] != uint64(66) {
+		t.Fatalf("unexpected config: %#v", config)
+	}
+
+	// Lowering the threshold under the usage rejects writes, disabling it
+	// accepts them again
+	mustRequest(logical.UpdateOperation, "config", map[string]interface{}{"storage_emergency_threshold": 66})
+	if err := write("bar"); err == nil {
+		t.Fatal("expected the write to be rejected")
+	}
+	mustRequest(logical.UpdateOperation, "config", map[string]interface{}{"storage_emergency_threshold": 0})
+	if config := readConfig(); config["storage_emergency"] != false {
+		t.Fatalf("unexpected config: %#v", config)
+	}
+	if err := write("bar"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVersionedKV_StorageEmergency_Copy(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	for _, req := range []*logical.Request{
+		{Operation: logical.UpdateOperation, Path: "config", Data: map[string]interface{}{"storage_emergency_threshold": 30}},
+		{Operation: logical.UpdateOperation, Path: "data/foo", Data: map[string]interface{}{"data": map[string]interface{}{"value": "0123456789"}}},
+	} {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+	if err := kvb.storageUsageScan(ctx, storage, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The copy doubles the data of the key, exceeding the threshold
+	_, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "copy/foo",
+		Storage:   storage,
+		Data:      map[string]interface{}{"destination": "bar"},
+	})
+	var coded logical.HTTPCodedError
+	if !errors.As(err, &coded) || coded.Code() != http.StatusInsufficientStorage {
+		t.Fatalf("expected a 507 error, got %v", err)
+	}
+
+	meta, err := kvb.getKeyMetadata(ctx, storage, "bar")
+	if err != nil || meta != nil {
+		t.Fatalf("expected the destination not to be written, err: %v, meta: %#v", err, meta)
+	}
+	raw, _, err := kvb.getVersionEntry(ctx, storage, "bar", 1)
+	if err != nil || raw != nil {
+		t.Fatalf("expected the version data of the destination to be deleted, err: %v", err)
+	}
+	records, err := kvb.changeRecords(ctx, storage, "bar")
+	if err != nil || len(records) != 0 {
+		t.Fatalf("expected the change records of the destination to be deleted, err: %v, records: %v", err, records)
+	}
+}
+
+func TestVersionedKV_StorageUsageScan_CorruptKey(t *testing.T) {
+	b, storage := getBackend(t)
+	kvb := b.(*versionedKVBackend)
+	ctx := context.Background()
+
+	for _, req := range []*logical.Request{
+		{Operation: logical.UpdateOperation, Path: "config", Data: map[string]interface{}{"storage_emergency_threshold": 100}},
+		{Operation: logical.UpdateOperation, Path: "data/foo", Data: map[string]interface{}{"data": map[string]interface{}{"value": "0123456789"}}},
+	} {
+		req.Storage = storage
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", req.Operation, req.Path, err, resp)
+		}
+	}
+
+	// Metadata that cannot be decoded, sorted before the other key
+	wrapper, err := kvb.getKeyEncryptor(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wrapper.Wrap(storage).Put(ctx, &logical.StorageEntry{Key: "a-corrupt", Value: []byte{0xff}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kvb.storageUsageScan(ctx, storage, time.Now()); err != nil {
+		t.Fatalf("expected the corrupt key to be skipped, err: %s", err)
+	}
+	kvb.usage.lock.Lock()
+	bytes := kvb.usage.bytes
+	kvb.usage.lock.Unlock()
+	if bytes != 22 {
+		t.Fatalf("expected the usage of the other key to be counted, got %d", bytes)
+	}
+}
@@ -0,0 +1,508 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// trashPrefix is the prefix where the entries of keys deleted with a soft
+// metadata delete are stored by an opaque ID, and trashDataPrefix the prefix
+// where their version data and change records are kept meanwhile. Keeping
+// the data outside of the version and changelog prefixes lets a new key be
+// written at the path without overwriting it, and keeps tidy and
+// repair/rebuild-index from treating it as orphaned.
+const (
+	trashPrefix     = "trash/"
+	trashDataPrefix = "trash-data/"
+)
+
+// The kinds of data kept under trashDataPrefix for a trashed key.
+const (
+	trashVersions  = "versions"
+	trashArchived  = "archived"
+	trashChangelog = "changelog"
+)
+
+// pathMetadataUndelete returns the path configuration for restoring keys
+// deleted with a soft metadata delete.
+func pathMetadataUndelete(b *versionedKVBackend) *framework.Path {
+	return &framework.Path{
+		Pattern: "metadata-undelete/" + framework.MatchAllRegex("path"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixKVv2,
+			OperationVerb:   "undelete",
+			OperationSuffix: "metadata",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"path": {
+				Type:        framework.TypeString,
+				Description: "Location of the secret.",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.upgradeCheck(b.pathMetadataUndeleteWrite()),
+				Responses: map[int][]framework.Response{
+					http.StatusOK: {{
+						Description: http.StatusText(http.StatusOK),
+						Fields: map[string]*framework.FieldSchema{
+							"current_version": {
+								Type:        framework.TypeInt64, // uint64
+								Description: "The current version of the restored secret.",
+								Required:    true,
+							},
+							"deleted_time": {
+								Type:        framework.TypeTime,
+								Description: "When the secret was deleted.",
+								Required:    true,
+							},
+						},
+					}},
+				},
+			},
+		},
+
+		HelpSynopsis:    metadataUndeleteHelpSyn,
+		HelpDescription: metadataUndeleteHelpDesc,
+	}
+}
+
+// pathMetadataUndeleteWrite restores the most recently soft deleted key at
+// the path, unless a key was written there since.
+func (b *versionedKVBackend) pathMetadataUndeleteWrite() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		key := data.Get("path").(string)
+
+		lock := locksutil.LockForKey(b.locks, key)
+		lock.Lock()
+		defer lock.Unlock()
+
+		existing, err := b.getKeyMetadata(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return logical.ErrorResponse("secret %q exists, it must be deleted before the secret deleted from the path can be restored", key), logical.ErrInvalidRequest
+		}
+
+		id, entry, err := b.latestTrashEntry(ctx, req.Storage, key)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			return nil, nil
+		}
+
+		meta, err := b.restoreKey(ctx, req.Storage, id, entry)
+		if err != nil {
+			return nil, err
+		}
+
+		requestLogger(ctx, b.Logger()).Info("restored soft deleted key", "key", key, "current_version", meta.CurrentVersion)
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"current_version": meta.CurrentVersion,
+				"deleted_time":    ptypesTimestampToString(entry.DeletedTime),
+			},
+		}, nil
+	}
+}
+
+// trashKey moves the key to the trash: its version data and metadata are
+// stored under a new trash entry, then deleted from the path, followed by
+// its change records once the delete is recorded. It must be called while
+// holding the lock of the key.
+func (b *versionedKVBackend) trashKey(ctx context.Context, s logical.Storage, meta *KeyMetadata) (retErr error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+
+	// Until the entry is written, the copied data is not referenced and is
+	// deleted if trashing fails
+	entryWritten := false
+	defer func() {
+		if retErr != nil && !entryWritten {
+			if err := b.deleteTrashData(ctx, s, id); err != nil {
+				requestLogger(ctx, b.Logger()).Error("error deleting the data of a failed soft delete", "key", meta.Key, "error", err)
+			}
+		}
+	}()
+
+	var originals []string
+	for _, version := range meta.storedVersionIDs() {
+		versionKey, err := b.getVersionKey(ctx, meta.Key, version, s)
+		if err != nil {
+			return err
+		}
+		archivedKey, err := b.getArchivedVersionKey(ctx, meta.Key, version, s)
+		if err != nil {
+			return err
+		}
+
+		for kind, storageKey := range map[string]string{trashVersions: versionKey, trashArchived: archivedKey} {
+			moved, err := b.moveEntry(ctx, s, storageKey, b.trashDataKey(id, kind, strconv.FormatUint(version, 10)), false)
+			if err != nil {
+				return err
+			}
+			if moved {
+				originals = append(originals, storageKey)
+			}
+		}
+	}
+
+	sequence := meta.nextEventSequence()
+	if err := b.putTrashEntry(ctx, s, id, &TrashEntry{
+		Key:         meta.Key,
+		Metadata:    meta,
+		DeletedTime: b.timestampNow(),
+	}); err != nil {
+		return err
+	}
+	entryWritten = true
+
+	// The key is gone once its metadata is deleted, the remaining data is in
+	// the trash
+	wrapper, err := b.getKeyEncryptor(ctx, s)
+	if err != nil {
+		return err
+	}
+	if err := wrapper.Wrap(s).Delete(ctx, meta.Key); err != nil {
+		return err
+	}
+	for _, storageKey := range originals {
+		if err := s.Delete(ctx, storageKey); err != nil {
+			return err
+		}
+	}
+
+	config, err := b.config(ctx, s)
+	if err != nil {
+		return err
+	}
+	b.adjustStorageUsage(ctx, s, config, -int64(meta.storedBytes()))
+
+	b.emitKeyEvent(ctx, s, meta, sequence, "metadata-delete", "metadata/"+meta.Key, "", true,
+		"soft", "true",
+	)
+
+	return b.moveChangelog(ctx, s, meta.Key, id, false)
+}
+
+// restoreKey moves the version data and change records of the trashed key
+// back to its path, writes its metadata and removes it from the trash. It
+// returns the metadata of the restored key, and must be called while holding
+// the lock of the key.
+func (b *versionedKVBackend) restoreKey(ctx context.Context, s logical.Storage, id string, entry *TrashEntry) (_ *KeyMetadata, retErr error) {
+	meta := entry.Metadata
+	key := entry.Key
+
+	var versionsWritten []string
+	defer func() {
+		if retErr != nil {
+			b.deleteOrphanedVersions(ctx, s, key, versionsWritten...)
+		}
+	}()
+
+	for _, version := range meta.storedVersionIDs() {
+		versionKey, err := b.getVersionKey(ctx, key, version, s)
+		if err != nil {
+			return nil, err
+		}
+		archivedKey, err := b.getArchivedVersionKey(ctx, key, version, s)
+		if err != nil {
+			return nil, err
+		}
+
+		for kind, storageKey := range map[string]string{trashVersions: versionKey, trashArchived: archivedKey} {
+			moved, err := b.moveEntry(ctx, s, b.trashDataKey(id, kind, strconv.FormatUint(version, 10)), storageKey, false)
+			if err != nil {
+				return nil, err
+			}
+			if moved {
+				versionsWritten = append(versionsWritten, storageKey)
+			}
+		}
+	}
+
+	if err := b.moveChangelog(ctx, s, key, id, true); err != nil {
+		return nil, err
+	}
+
+	// The data of the key is counted again, and the write is rejected like
+	// any other in a storage emergency
+	meta.TotalBytes = 0
+	meta.UpdatedTime = b.timestampNow()
+	sequence := meta.nextEventSequence()
+	if err := b.writeKeyMetadata(ctx, s, meta); err != nil {
+		if err := b.deleteChangelog(ctx, s, key); err != nil {
+			requestLogger(ctx, b.Logger()).Error("error deleting the change records of a failed undelete", "key", key, "error", err)
+		}
+		return nil, err
+	}
+	if err := b.deleteTrashData(ctx, s, id); err != nil {
+		return nil, err
+	}
+	if err := s.Delete(ctx, b.trashEntryKey(id)); err != nil {
+		return nil, err
+	}
+
+	b.emitKeyEvent(ctx, s, meta, sequence, "metadata-undelete", "metadata-undelete/"+key, "data/"+key, true)
+	return meta, nil
+}
+
+// purgeTrash permanently deletes the keys that have been in the trash for
+// longer than the trash_retention of the config. Keys are kept while
+// trash_retention is zero, so that disabling soft deletes does not purge
+// them. It returns the number of keys purged.
+func (b *versionedKVBackend) purgeTrash(ctx context.Context, s logical.Storage, config *Configuration, now time.Time) (uint64, error) {
+	retention := durationOrZero(config.GetTrashRetention())
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	ids, err := s.List(ctx, path.Join(b.storagePrefix, trashPrefix)+"/")
+	if err != nil {
+		return 0, err
+	}
+
+	var purged uint64
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		ok, err := b.purgeTrashEntry(ctx, s, id, retention, now)
+		if err != nil {
+			return purged, err
+		}
+		if ok {
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// purgeTrashEntry deletes the trash entry and its data if the key was
+// deleted longer than retention ago. Returns true if it was purged.
+func (b *versionedKVBackend) purgeTrashEntry(ctx context.Context, s logical.Storage, id string, retention time.Duration, now time.Time) (bool, error) {
+	entry, err := b.getTrashEntry(ctx, s, id)
+	if err != nil || entry == nil {
+		return false, err
+	}
+
+	// The entry is read again under the lock of the key, as it may have
+	// been restored meanwhile
+	lock := locksutil.LockForKey(b.locks, entry.Key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err = b.getTrashEntry(ctx, s, id)
+	if err != nil || entry == nil {
+		return false, err
+	}
+	deletedTime, err := ptypes.Timestamp(entry.DeletedTime)
+	if err != nil {
+		return false, err
+	}
+	if deletedTime.Add(retention).After(now) {
+		return false, nil
+	}
+
+	if err := b.deleteTrashData(ctx, s, id); err != nil {
+		return false, err
+	}
+
+	// The ID entry still resolves to the path, unless a failed soft delete
+	// left the key there
+	current, err := b.getKeyMetadata(ctx, s, entry.Key)
+	if err != nil {
+		return false, err
+	}
+	if current == nil || current.Id != entry.Metadata.GetId() {
+		if err := b.deleteKeyID(ctx, s, entry.Metadata); err != nil {
+			return false, err
+		}
+	}
+
+	if err := s.Delete(ctx, b.trashEntryKey(id)); err != nil {
+		return false, err
+	}
+
+	b.Logger().Info("purged soft deleted key", "key", entry.Key, "deleted_time", deletedTime)
+	return true, nil
+}
+
+// latestTrashEntry returns the ID and the entry of the key most recently
+// deleted from the path, or a nil entry if there is none in the trash.
+func (b *versionedKVBackend) latestTrashEntry(ctx context.Context, s logical.Storage, key string) (string, *TrashEntry, error) {
+	ids, err := s.List(ctx, path.Join(b.storagePrefix, trashPrefix)+"/")
+	if err != nil {
+		return "", nil, err
+	}
+
+	var latestID string
+	var latest *TrashEntry
+	for _, id := range ids {
+		entry, err := b.getTrashEntry(ctx, s, id)
+		if err != nil {
+			return "", nil, err
+		}
+		if entry == nil || entry.Key != key {
+			continue
+		}
+
+		if latest == nil || entry.DeletedTime.AsTime().After(latest.DeletedTime.AsTime()) {
+			latestID, latest = id, entry
+		}
+	}
+
+	return latestID, latest, nil
+}
+
+func (b *versionedKVBackend) trashEntryKey(id string) string {
+	return path.Join(b.storagePrefix, trashPrefix, id)
+}
+
+// trashDataKey returns the storage key of data of a kind kept for the
+// trashed key with the given ID.
+func (b *versionedKVBackend) trashDataKey(id, kind, name string) string {
+	return path.Join(b.storagePrefix, trashDataPrefix, id, kind, name)
+}
+
+func (b *versionedKVBackend) putTrashEntry(ctx context.Context, s logical.Storage, id string, entry *TrashEntry) error {
+	buf, err := proto.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, &logical.StorageEntry{
+		Key:   b.trashEntryKey(id),
+		Value: buf,
+	})
+}
+
+// getTrashEntry returns the trash entry with the given ID, or nil if there
+// is none.
+func (b *versionedKVBackend) getTrashEntry(ctx context.Context, s logical.Storage, id string) (*TrashEntry, error) {
+	raw, err := s.Get(ctx, b.trashEntryKey(id))
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	entry := &TrashEntry{}
+	if err := proto.Unmarshal(raw.Value, entry); err != nil {
+		return nil, fmt.Errorf("failed to decode trash entry: %w", err)
+	}
+	if entry.Metadata == nil {
+		entry.Metadata = &KeyMetadata{Key: entry.Key}
+	}
+	return entry, nil
+}
+
+// moveEntry copies the raw value stored at src to dst, deleting src if
+// deleteSrc is true. Returns false if there is no value at src.
+func (b *versionedKVBackend) moveEntry(ctx context.Context, s logical.Storage, src, dst string, deleteSrc bool) (bool, error) {
+	raw, err := s.Get(ctx, src)
+	if err != nil || raw == nil {
+		return false, err
+	}
+
+	if err := s.Put(ctx, &logical.StorageEntry{
+		Key:   dst,
+		Value: raw.Value,
+	}); err != nil {
+		return false, err
+	}
+	if deleteSrc {
+		if err := s.Delete(ctx, src); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// moveChangelog moves the change records of the key into the trash entry
+// with the given ID, or copies them back to the key if restore is true, the
+// trashed records being deleted with the rest of the data of the entry.
+func (b *versionedKVBackend) moveChangelog(ctx context.Context, s logical.Storage, key, id string, restore bool) error {
+	changelogPrefix, err := b.getChangelogPrefix(ctx, key, s)
+	if err != nil {
+		return err
+	}
+	trashedPrefix := path.Join(b.storagePrefix, trashDataPrefix, id, trashChangelog) + "/"
+
+	src, dst := changelogPrefix, trashedPrefix
+	if restore {
+		src, dst = trashedPrefix, changelogPrefix
+	}
+
+	entries, err := s.List(ctx, src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := b.moveEntry(ctx, s, src+e, dst+e, !restore); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteTrashData deletes the version data and change records kept for the
+// trashed key with the given ID.
+func (b *versionedKVBackend) deleteTrashData(ctx context.Context, s logical.Storage, id string) error {
+	for _, kind := range []string{trashVersions, trashArchived, trashChangelog} {
+		prefix := path.Join(b.storagePrefix, trashDataPrefix, id, kind) + "/"
+
+		entries, err := s.List(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := s.Delete(ctx, prefix+e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+const metadataUndeleteHelpSyn = `Restores a secret deleted with a soft metadata delete.`
+const metadataUndeleteHelpDesc = `
+A metadata delete with "soft" set to true moves the secret, with its metadata,
+the data of all of its versions and its history of changes, to the trash
+instead of deleting it permanently. The secret is then hidden from data reads,
+metadata reads and lists as if it had been deleted, and a new secret can be
+written at its path.
+
+This endpoint restores the secret most recently deleted from the path, with
+its versions, metadata and ID, as it was when it was deleted. The path must
+not have a secret: one written after the delete must be deleted first.
+
+Secrets are kept in the trash for the trash_retention of the config, after
+which tidy purges them permanently. Soft deletes are rejected while
+trash_retention is not set.
+`
@@ -0,0 +1,179 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/testhelpers/schema"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestVersionedKV_SoftDelete(t *testing.T) {
+	b, storage, events := getBackendWithEvents(t)
+	kvb := b.(*versionedKVBackend)
+	clock := &fakeClock{now: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	kvb.clock = clock
+	ctx := context.Background()
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+	mustRequest := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := request(operation, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s request to %s failed, err: %s, resp %#v", operation, path, err, resp)
+		}
+		return resp
+	}
+	write := func(value string) {
+		t.Helper()
+		mustRequest(logical.UpdateOperation, "data/foo", map[string]interface{}{
+			"data": map[string]interface{}{"value": value},
+		})
+	}
+	readValue := func(version int) interface{} {
+		t.Helper()
+		resp := mustRequest(logical.ReadOperation, "data/foo", map[string]interface{}{"version": version})
+		if resp == nil {
+			return nil
+		}
+		return resp.Data["data"].(map[string]interface{})["value"]
+	}
+	undelete := func() *logical.Response {
+		t.Helper()
+		req := &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "metadata-undelete/foo",
+			Storage:   storage,
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("undelete failed, err: %s, resp %#v", err, resp)
+		}
+		if resp != nil {
+			schema.ValidateResponse(t, schema.GetResponseSchema(t, kvb.Route(req.Path), req.Operation), resp, true)
+		}
+		return resp
+	}
+
+	write("one")
+	write("two")
+	mustRequest(logical.UpdateOperation, "metadata/foo", map[string]interface{}{"custom_metadata": map[string]interface{}{"team": "a"}})
+	id := mustRequest(logical.ReadOperation, "metadata/foo", nil).Data["id"]
+
+	// Soft deletes are disabled until trash_retention is set
+	resp, err := request(logical.DeleteOperation, "metadata/foo", map[string]interface{}{"soft": true})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the soft delete to be rejected, err: %v, resp: %#v", err, resp)
+	}
+	mustRequest(logical.UpdateOperation, "config", map[string]interface{}{"trash_retention": "24h"})
+	if resp, _ := request(logical.UpdateOperation, "config", map[string]interface{}{"trash_retention": -1}); resp == nil || !resp.IsError() {
+		t.Fatalf("expected a negative trash_retention to be rejected, resp: %#v", resp)
+	}
+
+	// The soft deleted key is hidden
+	events.eventsProcessed = nil
+	mustRequest(logical.DeleteOperation, "metadata/foo", map[string]interface{}{"soft": true})
+	if readValue(0) != nil {
+		t.Fatal("expected the soft deleted key to be hidden from data reads")
+	}
+	if resp := mustRequest(logical.ReadOperation, "metadata/foo", nil); resp != nil {
+		t.Fatalf("expected the soft deleted key to be hidden from metadata reads: %#v", resp.Data)
+	}
+	if resp := mustRequest(logical.ListOperation, "metadata/", nil); len(resp.Data) != 0 {
+		t.Fatalf("expected the soft deleted key to be hidden from lists: %#v", resp.Data)
+	}
+	if resp := mustRequest(logical.ReadOperation, "by-id/"+id.(string), nil); resp != nil {
+		t.Fatalf("expected the id of the soft deleted key not to resolve: %#v", resp.Data)
+	}
+
+	// A key written at the path since must be deleted before the undelete,
+	// without affecting the trashed versions
+	write("new")
+	resp, err = request(logical.UpdateOperation, "metadata-undelete/foo", nil)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the undelete to be rejected, err: %v, resp: %#v", err, resp)
+	}
+	mustRequest(logical.DeleteOperation, "metadata/foo", nil)
+
+	clock.advance(time.Hour)
+	resp = undelete()
+	if resp.Data["current_version"] != uint64(2) || resp.Data["deleted_time"] != "2030-01-01T00:00:00Z" {
+		t.Fatalf("unexpected undelete response: %#v", resp.Data)
+	}
+	if readValue(1) != "one" || readValue(2) != "two" {
+		t.Fatal("expected the versions to be restored")
+	}
+	meta := mustRequest(logical.ReadOperation, "metadata/foo", nil).Data
+	if meta["id"] != id || meta["custom_metadata"].(map[string]string)["team"] != "a" {
+		t.Fatalf("expected the metadata to be restored: %#v", meta)
+	}
+	if resp := mustRequest(logical.ReadOperation, "by-id/"+id.(string), nil); resp == nil || resp.Data["path"] != "foo" {
+		t.Fatalf("expected the id to resolve again: %#v", resp)
+	}
+
+	// The change records are kept through the trash
+	resp = mustRequest(logical.ReadOperation, "changelog/foo", nil)
+	var operations []string
+	for _, change := range resp.Data["changes"].([]interface{}) {
+		operations = append(operations, change.(map[string]interface{})["operation"].(string))
+	}
+	if len(operations) < 2 || operations[len(operations)-2] != "metadata-delete" || operations[len(operations)-1] != "metadata-undelete" {
+		t.Fatalf("unexpected changelog: %v", operations)
+	}
+
+	events.expectEvents(t, []expectedEvent{
+		{"kv-v2/metadata-delete", "metadata/foo", ""},
+		{"kv-v2/data-write", "data/foo", "data/foo"},
+		{"kv-v2/metadata-delete", "metadata/foo", ""},
+		{"kv-v2/metadata-undelete", "metadata-undelete/foo", "data/foo"},
+	})
+	if soft := events.eventsProcessed[0].Event.Metadata.Fields["soft"].GetStringValue(); soft != "true" {
+		t.Fatalf("expected the first delete event to be soft, got %q", soft)
+	}
+
+	expectEmpty := func(prefixes ...string) {
+		t.Helper()
+		for _, prefix := range prefixes {
+			keys, err := logical.CollectKeysWithPrefix(ctx, storage, path.Join(kvb.storagePrefix, prefix))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != 0 {
+				t.Fatalf("expected nothing left under %s, got %v", prefix, keys)
+			}
+		}
+	}
+	expectEmpty(trashPrefix, trashDataPrefix)
+
+	// Tidy purges the key once the trash_retention passes
+	mustRequest(logical.DeleteOperation, "metadata/foo", map[string]interface{}{"soft": true})
+	resp = mustRequest(logical.UpdateOperation, "tidy", nil)
+	if resp.Data["purged_keys"] != uint64(0) {
+		t.Fatalf("unexpected tidy response: %#v", resp.Data)
+	}
+	clock.advance(25 * time.Hour)
+	resp = mustRequest(logical.UpdateOperation, "tidy", nil)
+	if resp.Data["purged_keys"] != uint64(1) {
+		t.Fatalf("unexpected tidy response: %#v", resp.Data)
+	}
+	if resp := undelete(); resp != nil {
+		t.Fatalf("expected the purged key not to be restored: %#v", resp.Data)
+	}
+	if resp := mustRequest(logical.ReadOperation, "by-id/"+id.(string), nil); resp != nil {
+		t.Fatalf("expected the id of the purged key not to resolve: %#v", resp.Data)
+	}
+	expectEmpty(trashPrefix, trashDataPrefix, versionPrefix, archivedVersionPrefix, changelogPrefix, keyIDPrefix)
+}
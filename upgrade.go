@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"path"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -21,6 +22,20 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// errUpgradeTakenOver is returned when the upgrade canary names another
+// instance as the owner of the upgrade.
+var errUpgradeTakenOver = errors.New("upgrade was taken over by another instance")
+
+const (
+	// upgradeHeartbeatInterval is how often the instance running the upgrade
+	// renews the heartbeat in the upgrade canary.
+	upgradeHeartbeatInterval = 30 * time.Second
+
+	// upgradeLeaseTimeout is how long after the last heartbeat an unfinished
+	// upgrade is considered abandoned and may be taken over.
+	upgradeLeaseTimeout = 2 * time.Minute
+)
+
 func (b *versionedKVBackend) perfSecondaryCheck() bool {
 	replState := b.System().ReplicationState()
 	if (!b.System().LocalMount() && replState.HasState(consts.ReplicationPerformanceSecondary)) ||
@@ -53,20 +68,113 @@ func (b *versionedKVBackend) upgradeCheck(next framework.OperationFunc) framewor
 }
 
 func (b *versionedKVBackend) upgradeDone(ctx context.Context, s logical.Storage) (bool, error) {
-	upgradeEntry, err := s.Get(ctx, path.Join(b.storagePrefix, "upgrading"))
+	upgradeInfo, err := b.upgradeInfo(ctx, s)
 	if err != nil {
 		return false, err
 	}
 
-	var upgradeInfo UpgradeInfo
+	return upgradeInfo.Done, nil
+}
+
+// upgradeInfo reads the upgrade canary from storage. If no canary exists an
+// empty UpgradeInfo is returned.
+func (b *versionedKVBackend) upgradeInfo(ctx context.Context, s logical.Storage) (*UpgradeInfo, error) {
+	upgradeEntry, err := s.Get(ctx, path.Join(b.storagePrefix, "upgrading"))
+	if err != nil {
+		return nil, err
+	}
+
+	upgradeInfo := &UpgradeInfo{}
 	if upgradeEntry != nil {
-		err := proto.Unmarshal(upgradeEntry.Value, &upgradeInfo)
+		err := proto.Unmarshal(upgradeEntry.Value, upgradeInfo)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
 	}
 
-	return upgradeInfo.Done, nil
+	return upgradeInfo, nil
+}
+
+// upgradeOwnedElsewhere returns true if the upgrade canary belongs to an
+// unfinished upgrade run by another instance whose heartbeat has not gone
+// stale. Canaries written without a heartbeat are never considered owned.
+func (b *versionedKVBackend) upgradeOwnedElsewhere(upgradeInfo *UpgradeInfo, now time.Time) bool {
+	if upgradeInfo.Done || upgradeInfo.OwnerId == "" || upgradeInfo.OwnerId == b.upgradeOwnerID || upgradeInfo.HeartbeatTime == nil {
+		return false
+	}
+
+	heartbeat, err := ptypes.Timestamp(upgradeInfo.HeartbeatTime)
+	if err != nil {
+		return false
+	}
+
+	return now.Sub(heartbeat) < upgradeLeaseTimeout
+}
+
+// waitForUpgrade blocks until the upgrade canary is marked as done, then
+// clears the upgrading flag. If the context is closed we are shutting down,
+// so stop waiting and clear the flag for good measure.
+func (b *versionedKVBackend) waitForUpgrade(ctx context.Context, s logical.Storage) {
+	for {
+		time.Sleep(time.Second)
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		done, err := b.upgradeDone(ctx, s)
+		if err != nil {
+			b.Logger().Error("upgrading resulted in error", "error", err)
+		}
+
+		if done {
+			break
+		}
+	}
+
+	atomic.StoreUint32(b.upgrading, 0)
+}
+
+// acquireUpgrade waits while another instance holds the lease on an
+// unfinished upgrade, for example after a failover. It returns true once the
+// upgrade may be run by this instance, either because no other instance owns
+// it or because the owner's heartbeat went stale. It returns false, with the
+// upgrading flag cleared, if the upgrade finished elsewhere or the context is
+// closed.
+func (b *versionedKVBackend) acquireUpgrade(ctx context.Context, s logical.Storage) bool {
+	logged := false
+	for {
+		upgradeInfo, err := b.upgradeInfo(ctx, s)
+		if err != nil {
+			b.Logger().Error("reading upgrade info resulted in an error", "error", err)
+			atomic.StoreUint32(b.upgrading, 0)
+			return false
+		}
+
+		if upgradeInfo.Done {
+			atomic.StoreUint32(b.upgrading, 0)
+			return false
+		}
+
+		if !b.upgradeOwnedElsewhere(upgradeInfo, b.now()) {
+			if logged {
+				b.Logger().Info("taking over abandoned upgrade", "owner", upgradeInfo.OwnerId)
+			}
+			return true
+		}
+
+		if !logged {
+			b.Logger().Info("upgrade is running on another instance, waiting for it to finish", "owner", upgradeInfo.OwnerId)
+			logged = true
+		}
+
+		time.Sleep(time.Second)
+
+		if ctx.Err() != nil {
+			atomic.StoreUint32(b.upgrading, 0)
+			return false
+		}
+	}
 }
 
 func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) error {
@@ -93,30 +201,7 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 	if b.perfSecondaryCheck() {
 		b.Logger().Info("upgrade not running on performance replication secondary or performance standby")
 
-		go func() {
-			for {
-				time.Sleep(time.Second)
-
-				// If we failed because the context is closed we are
-				// shutting down. Close this go routine and set the upgrade
-				// flag back to 0 for good measure.
-				if ctx.Err() != nil {
-					atomic.StoreUint32(b.upgrading, 0)
-					return
-				}
-
-				done, err := b.upgradeDone(ctx, s)
-				if err != nil {
-					b.Logger().Error("upgrading resulted in error", "error", err)
-				}
-
-				if done {
-					break
-				}
-			}
-
-			atomic.StoreUint32(b.upgrading, 0)
-		}()
+		go b.waitForUpgrade(ctx, s)
 
 		return nil
 	}
@@ -124,7 +209,7 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 	// If we have 0 keys, it's either a new mount or one that's trivial to upgrade,
 	// so we should do the upgrade synchronously
 	upgradeSynchronously := false
-	keys, err := logical.CollectKeys(ctx, s)
+	keys, err := b.collectUpgradeKeys(ctx, s)
 	if err != nil {
 		b.Logger().Error("upgrading resulted in error", "error", err)
 		return err
@@ -133,17 +218,16 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 		upgradeSynchronously = true
 	}
 
+	now := b.timestampNow()
 	upgradeInfo := &UpgradeInfo{
-		StartedTime: ptypes.TimestampNow(),
-	}
-
-	// Encode the canary
-	info, err := proto.Marshal(upgradeInfo)
-	if err != nil {
-		return err
+		StartedTime:   now,
+		OwnerId:       b.upgradeOwnerID,
+		HeartbeatTime: now,
 	}
 
-	// Because this is a long-running process we need a new context.
+	// Because this is a long-running process we need a new context. The
+	// original context is kept to stop waiting on other instances.
+	upgradeCtx := ctx
 	ctx = context.Background()
 
 	upgradeKey := func(key string) error {
@@ -161,8 +245,9 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 		defer locksutil.LockForKey(b.locks, key).Unlock()
 
 		meta := &KeyMetadata{
-			Key:      key,
-			Versions: map[uint64]*VersionMetadata{},
+			Key:            key,
+			Versions:       map[uint64]*VersionMetadata{},
+			CreationSource: creationSourceUpgrade,
 		}
 
 		versionKey, err := b.getVersionKey(ctx, key, 1, s)
@@ -172,7 +257,9 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 
 		version := &Version{
 			Data:        data.Value,
-			CreatedTime: ptypes.TimestampNow(),
+			CreatedTime: b.timestampNow(),
+			Key:         key,
+			Version:     1,
 		}
 
 		buf, err := proto.Marshal(version)
@@ -189,7 +276,8 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 		}
 
 		// Store the metadata
-		meta.AddVersion(version.CreatedTime, nil, 1)
+		vm, _ := meta.AddVersion(version.CreatedTime, nil, 1)
+		vm.DataBytes = uint64(len(data.Value))
 		err = b.writeKeyMetadata(ctx, s, meta)
 		if err != nil {
 			return err
@@ -206,6 +294,8 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 
 	prepareUpgradeInfoDoneFunc := func() ([]byte, error) {
 		upgradeInfo.Done = true
+		upgradeInfo.KeysTotal = atomic.LoadUint64(b.upgradeKeysTotal)
+		upgradeInfo.KeysProcessed = atomic.LoadUint64(b.upgradeKeysProcessed)
 		info, err := proto.Marshal(upgradeInfo)
 		if err != nil {
 			b.Logger().Error("encoding upgrade info resulted in an error", "error", err)
@@ -233,6 +323,29 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 	}
 
 	upgradeFunc := func() {
+		if !b.acquireUpgrade(upgradeCtx, s) {
+			return
+		}
+
+		// Resume after the last checkpoint of an unfinished upgrade, which
+		// may have been run by another instance.
+		previous, err := b.upgradeInfo(ctx, s)
+		if err != nil {
+			b.Logger().Error("reading upgrade info resulted in an error", "error", err)
+			return
+		}
+		if !previous.Done && previous.LastKey != "" {
+			b.Logger().Info("resuming upgrade from checkpoint", "previous_owner", previous.OwnerId, "previous_keys_processed", previous.KeysProcessed)
+			upgradeInfo.LastKey = previous.LastKey
+		}
+		resumeAfter := upgradeInfo.LastKey
+
+		info, err := proto.Marshal(upgradeInfo)
+		if err != nil {
+			b.Logger().Error("encoding upgrade info resulted in an error", "error", err)
+			return
+		}
+
 		// Write the canary value and if we are read only wait until the setup
 		// process has finished.
 	READONLY_LOOP:
@@ -252,27 +365,161 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 			}
 		}
 
+		// writeProgressFunc renews the heartbeat and records the progress in
+		// the canary, along with lastKey as the checkpoint if set. The
+		// canary is written by the heartbeat and the upgrade loop, so writes
+		// are serialized.
+		var progressLock sync.Mutex
+		writeProgressFunc := func(lastKey string) error {
+			progressLock.Lock()
+			defer progressLock.Unlock()
+
+			current, err := b.upgradeInfo(ctx, s)
+			if err != nil {
+				return err
+			}
+			if current.OwnerId != b.upgradeOwnerID {
+				return fmt.Errorf("%w: %s", errUpgradeTakenOver, current.OwnerId)
+			}
+
+			upgradeInfo.HeartbeatTime = b.timestampNow()
+			upgradeInfo.KeysTotal = atomic.LoadUint64(b.upgradeKeysTotal)
+			upgradeInfo.KeysProcessed = atomic.LoadUint64(b.upgradeKeysProcessed)
+			if lastKey != "" {
+				upgradeInfo.LastKey = lastKey
+			}
+			buf, err := proto.Marshal(upgradeInfo)
+			if err != nil {
+				return err
+			}
+			return s.Put(ctx, &logical.StorageEntry{
+				Key:   path.Join(b.storagePrefix, "upgrading"),
+				Value: buf,
+			})
+		}
+
+		// Renew the heartbeat while the upgrade runs so that other instances
+		// do not take it over. If another instance has taken it over anyway,
+		// stop upgrading and wait for it to finish.
+		var lost uint32
+		stopHeartbeat := make(chan struct{})
+		heartbeatStopped := make(chan struct{})
+		go func() {
+			defer close(heartbeatStopped)
+
+			ticker := time.NewTicker(upgradeHeartbeatInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-stopHeartbeat:
+					return
+				case <-ticker.C:
+				}
+
+				err := writeProgressFunc("")
+				switch {
+				case errors.Is(err, errUpgradeTakenOver):
+					b.Logger().Error("upgrade was taken over by another instance", "error", err)
+					atomic.StoreUint32(&lost, 1)
+					return
+				case err != nil:
+					b.Logger().Error("writing upgrade heartbeat resulted in an error", "error", err)
+				}
+			}
+		}()
+		stopHeartbeatFunc := func() {
+			select {
+			case <-heartbeatStopped:
+			default:
+				close(stopHeartbeat)
+				<-heartbeatStopped
+			}
+		}
+		defer stopHeartbeatFunc()
+
+		// Record the error stopping the upgrade in the canary, so that it is
+		// returned by the upgrade status on every instance, along with the
+		// last key upgraded to resume after.
+		writeUpgradeErrorFunc := func(upgradeErr error, lastKey string) {
+			stopHeartbeatFunc()
+
+			progressLock.Lock()
+			upgradeInfo.LastError = upgradeErr.Error()
+			progressLock.Unlock()
+			if err := writeProgressFunc(lastKey); err != nil {
+				b.Logger().Error("writing upgrade error resulted in an error", "error", err)
+			}
+		}
+
 		b.Logger().Info("collecting keys to upgrade")
-		keys, err := logical.CollectKeys(ctx, s)
+		keys, err := b.collectUpgradeKeys(ctx, s)
 		if err != nil {
 			b.Logger().Error("upgrading resulted in error", "error", err)
+			writeUpgradeErrorFunc(err, "")
 			return
 		}
+		if resumeAfter != "" {
+			remaining := keys[:0]
+			for _, key := range keys {
+				if key > resumeAfter {
+					remaining = append(remaining, key)
+				}
+			}
+			keys = remaining
+		}
 
 		b.Logger().Info("done collecting keys", "num_keys", len(keys))
+		atomic.StoreUint64(b.upgradeKeysTotal, uint64(len(keys)))
+		atomic.StoreUint64(b.upgradeKeysProcessed, 0)
+		start := time.Now()
 		for i, key := range keys {
+			if atomic.LoadUint32(&lost) == 1 {
+				b.waitForUpgrade(upgradeCtx, s)
+				return
+			}
+
 			if b.Logger().IsDebug() && i%500 == 0 {
 				b.Logger().Debug("upgrading keys", "progress", fmt.Sprintf("%d/%d", i, len(keys)))
 			}
 			err := upgradeKey(key)
 			if err != nil {
 				b.Logger().Error("upgrading resulted in error", "error", err, "progress", fmt.Sprintf("%d/%d", i+1, len(keys)))
+				var lastKey string
+				if i > 0 {
+					lastKey = keys[i-1]
+				}
+				writeUpgradeErrorFunc(fmt.Errorf("upgrading key %d of %d: %w", i+1, len(keys), err), lastKey)
+				return
+			}
+			atomic.AddUint64(b.upgradeKeysProcessed, 1)
+
+			// Checkpoint the progress after each batch, and throttle the
+			// upgrade to its rate limit
+			if (i+1)%b.upgradeBatchSize != 0 || i+1 == len(keys) {
+				continue
+			}
+			err = writeProgressFunc(key)
+			switch {
+			case errors.Is(err, errUpgradeTakenOver):
+				b.Logger().Error("upgrade was taken over by another instance", "error", err)
+				stopHeartbeatFunc()
+				b.waitForUpgrade(upgradeCtx, s)
+				return
+			case err != nil:
+				b.Logger().Error("writing upgrade checkpoint resulted in an error", "error", err)
+			}
+			if !b.paceUpgrade(upgradeCtx, start, i+1) {
+				b.Logger().Info("upgrade stopped, it resumes from the last checkpoint on the next start", "progress", fmt.Sprintf("%d/%d", i+1, len(keys)))
 				return
 			}
 		}
 
 		b.Logger().Info("upgrading keys finished")
 
+		// Stop renewing the heartbeat before marking the upgrade as done
+		stopHeartbeatFunc()
+
 		// We do this now so that we ensure it's written by the primary before
 		// secondaries unblock
 		b.l.Lock()
@@ -281,7 +528,7 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 		}
 		b.l.Unlock()
 
-		info, err := prepareUpgradeInfoDoneFunc()
+		info, err = prepareUpgradeInfoDoneFunc()
 		if err != nil {
 			b.Logger().Error("error marshalling upgrade info after upgrade", "error", err)
 			return
@@ -301,9 +548,17 @@ func (b *versionedKVBackend) Upgrade(ctx context.Context, s logical.Storage) err
 		// during the mount process, so we cannot do it now
 		go writeUpgradeInfoDoneFunc(info)
 	} else {
+		// The functions of the upgrade use s, so counting its operations
+		// in the background load replaces it before the upgrade starts.
+		var done func()
+		s, done = b.startBackgroundTask(s)
+
 		// We run the actual upgrade in a go routine, so we don't block the client on a
 		// potentially long process.
-		go upgradeFunc()
+		go func() {
+			defer done()
+			upgradeFunc()
+		}()
 	}
 
 	return nil
@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// The mount options tuning the upgrade from non-versioned data. The upgrade
+// runs before the config of the mount can be written, so they are mount
+// options.
+const (
+	// mountOptionUpgradeBatchSize is the number of keys upgraded between
+	// checkpoints of the progress of the upgrade.
+	mountOptionUpgradeBatchSize = "upgrade_batch_size"
+
+	// mountOptionUpgradeRateLimit is the maximum number of keys upgraded per
+	// second. If not set, the upgrade is not throttled.
+	mountOptionUpgradeRateLimit = "upgrade_rate_limit"
+)
+
+// defaultUpgradeBatchSize is the batch size of upgrades that do not set the
+// upgrade_batch_size mount option.
+const defaultUpgradeBatchSize = 1000
+
+// parseUpgradeOptions returns the batch size and rate limit of the upgrade
+// set by the mount options.
+func parseUpgradeOptions(options map[string]string) (int, float64, error) {
+	batchSize := defaultUpgradeBatchSize
+	if raw, ok := options[mountOptionUpgradeBatchSize]; ok && raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size <= 0 {
+			return 0, 0, fmt.Errorf("invalid %s %q: must be a positive integer", mountOptionUpgradeBatchSize, raw)
+		}
+		batchSize = size
+	}
+
+	var rateLimit float64
+	if raw, ok := options[mountOptionUpgradeRateLimit]; ok && raw != "" {
+		limit, err := strconv.ParseFloat(raw, 64)
+		if err != nil || limit < 0 {
+			return 0, 0, fmt.Errorf("invalid %s %q: must be a non-negative number of keys per second", mountOptionUpgradeRateLimit, raw)
+		}
+		rateLimit = limit
+	}
+
+	return batchSize, rateLimit, nil
+}
+
+// collectUpgradeKeys returns the sorted keys of the non-versioned data of
+// the mount. The versioned data under the storage prefix is not listed, so
+// resuming an upgrade does not walk the keys already upgraded.
+func (b *versionedKVBackend) collectUpgradeKeys(ctx context.Context, s logical.Storage) ([]string, error) {
+	var keys []string
+	frontier := []string{""}
+	for len(frontier) > 0 {
+		current := frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+
+		contents, err := s.List(ctx, current)
+		if err != nil {
+			return nil, fmt.Errorf("list failed at path %q: %w", current, err)
+		}
+
+		for _, c := range contents {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			fullPath := current + c
+			switch {
+			case current == "" && c == b.storagePrefix+"/":
+			case strings.HasSuffix(c, "/"):
+				frontier = append(frontier, fullPath)
+			default:
+				keys = append(keys, fullPath)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// paceUpgrade sleeps until upgrading the keys upgraded since start is within
+// the rate limit of the upgrade. It returns false if the context is closed
+// while sleeping.
+func (b *versionedKVBackend) paceUpgrade(ctx context.Context, start time.Time, upgraded int) bool {
+	if b.upgradeRateLimit == 0 {
+		return true
+	}
+
+	expected := time.Duration(float64(upgraded) / b.upgradeRateLimit * float64(time.Second))
+	wait := expected - time.Since(start)
+	if wait <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -5,12 +5,15 @@ package kv
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/helper/logging"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -77,7 +80,7 @@ func TestVersionedKV_Upgrade(t *testing.T) {
 
 	for i := 0; i < 1024*1024; i++ {
 		data := map[string]interface{}{
-			"bar": float64(i),
+			"bar": json.Number(fmt.Sprint(i)),
 		}
 
 		req := &logical.Request{
@@ -96,3 +99,318 @@ func TestVersionedKV_Upgrade(t *testing.T) {
 		}
 	}
 }
+
+func TestVersionedKV_Upgrade_OwnedElsewhere(t *testing.T) {
+	b, storage := testPassthroughBackendWithStorage()
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"bar": "baz",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+
+	// Simulate an upgrade being run by another instance with a fresh heartbeat
+	writeCanary := func(info *UpgradeInfo) {
+		t.Helper()
+		buf, err := proto.Marshal(info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.Put(context.Background(), &logical.StorageEntry{
+			Key:   "test/upgrading",
+			Value: buf,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := ptypes.TimestampNow()
+	writeCanary(&UpgradeInfo{
+		StartedTime:   now,
+		OwnerId:       "other",
+		HeartbeatTime: now,
+		KeysTotal:     4,
+		KeysProcessed: 1,
+	})
+
+	config := &logical.BackendConfig{
+		Logger:      logging.NewVaultLogger(log.Trace),
+		System:      &logical.StaticSystemView{},
+		StorageView: storage,
+		BackendUUID: "test",
+		Config: map[string]string{
+			"version": "2",
+		},
+	}
+
+	b, err = Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The key must not have been upgraded by this instance
+	entry, err := storage.Get(context.Background(), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		t.Fatal("expected key to be left for the owning instance to upgrade")
+	}
+	if atomic.LoadUint32(b.(*versionedKVBackend).upgrading) != 1 {
+		t.Fatal("expected backend to wait for the upgrade to finish")
+	}
+
+	// The status is served during the upgrade, with the progress recorded
+	// by the owner
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "upgrade/status",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	if resp.Data["upgrading"] != true || resp.Data["done"] != false || resp.Data["keys_total"] != uint64(4) || resp.Data["keys_processed"] != uint64(1) {
+		t.Fatalf("unexpected upgrade status %#v", resp.Data)
+	}
+	if resp.Data["estimated_time_remaining"] == "" {
+		t.Fatalf("expected an estimate of the time remaining %#v", resp.Data)
+	}
+
+	// Once the owner finishes, the backend becomes available
+	writeCanary(&UpgradeInfo{
+		StartedTime:   now,
+		OwnerId:       "other",
+		HeartbeatTime: now,
+		Done:          true,
+	})
+
+	deadline := time.Now().Add(10 * time.Second)
+	for atomic.LoadUint32(b.(*versionedKVBackend).upgrading) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the upgrade to finish")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestVersionedKV_Upgrade_TakeOverStale(t *testing.T) {
+	b, storage := testPassthroughBackendWithStorage()
+
+	req := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"bar": "baz",
+		},
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+
+	stale, err := ptypes.TimestampProto(time.Now().Add(-2 * upgradeLeaseTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := proto.Marshal(&UpgradeInfo{
+		StartedTime:   stale,
+		OwnerId:       "other",
+		HeartbeatTime: stale,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(context.Background(), &logical.StorageEntry{
+		Key:   "test/upgrading",
+		Value: buf,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &logical.BackendConfig{
+		Logger:      logging.NewVaultLogger(log.Trace),
+		System:      &logical.StaticSystemView{},
+		StorageView: storage,
+		BackendUUID: "test",
+		Config: map[string]string{
+			"version": "2",
+		},
+	}
+
+	b, err = Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for atomic.LoadUint32(b.(*versionedKVBackend).upgrading) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the upgrade to finish")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	req = &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/foo",
+		Storage:   storage,
+	}
+
+	resp, err = b.HandleRequest(context.Background(), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+
+	if resp.Data["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("bad response %#v", resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "upgrade/status",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+	if resp.Data["upgrading"] != false || resp.Data["done"] != true || resp.Data["keys_total"] == uint64(0) ||
+		resp.Data["keys_processed"] != resp.Data["keys_total"] || resp.Data["last_error"] != "" {
+		t.Fatalf("unexpected upgrade status %#v", resp.Data)
+	}
+}
+
+func TestParseUpgradeOptions(t *testing.T) {
+	batchSize, rateLimit, err := parseUpgradeOptions(map[string]string{})
+	if err != nil || batchSize != defaultUpgradeBatchSize || rateLimit != 0 {
+		t.Fatalf("unexpected defaults %d, %f, err: %s", batchSize, rateLimit, err)
+	}
+
+	batchSize, rateLimit, err = parseUpgradeOptions(map[string]string{
+		mountOptionUpgradeBatchSize: "50",
+		mountOptionUpgradeRateLimit: "2.5",
+	})
+	if err != nil || batchSize != 50 || rateLimit != 2.5 {
+		t.Fatalf("unexpected options %d, %f, err: %s", batchSize, rateLimit, err)
+	}
+
+	for _, options := range []map[string]string{
+		{mountOptionUpgradeBatchSize: "0"},
+		{mountOptionUpgradeBatchSize: "many"},
+		{mountOptionUpgradeRateLimit: "-1"},
+	} {
+		if _, _, err := parseUpgradeOptions(options); err == nil {
+			t.Fatalf("expected options %v to be rejected", options)
+		}
+	}
+}
+
+func TestVersionedKV_Upgrade_Resume(t *testing.T) {
+	b, storage := testPassthroughBackendWithStorage()
+	ctx := context.Background()
+
+	keys := []string{"a", "b", "c/foo", "d", "e/foo", "f", "g"}
+	for _, key := range keys {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      key,
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"bar": key,
+			},
+		}
+
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%s resp:%#v\n", err, resp)
+		}
+	}
+
+	// Simulate an abandoned upgrade that checkpointed the keys up to c/foo
+	stale, err := ptypes.TimestampProto(time.Now().Add(-2 * upgradeLeaseTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := proto.Marshal(&UpgradeInfo{
+		StartedTime:   stale,
+		OwnerId:       "other",
+		HeartbeatTime: stale,
+		LastKey:       "c/foo",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(ctx, &logical.StorageEntry{
+		Key:   "test/upgrading",
+		Value: buf,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pausing after every second key, four keys take at least 100ms
+	start := time.Now()
+	b, err = Factory(ctx, &logical.BackendConfig{
+		Logger:      logging.NewVaultLogger(log.Trace),
+		System:      &logical.StaticSystemView{},
+		StorageView: storage,
+		BackendUUID: "test",
+		Config: map[string]string{
+			"version":                   "2",
+			mountOptionUpgradeBatchSize: "2",
+			mountOptionUpgradeRateLimit: "20",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for atomic.LoadUint32(b.(*versionedKVBackend).upgrading) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the upgrade to finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("expected the upgrade to be throttled, it took %s", elapsed)
+	}
+
+	info, err := b.(*versionedKVBackend).upgradeInfo(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Done || info.KeysTotal != 4 || info.KeysProcessed != 4 || info.LastKey != "e/foo" {
+		t.Fatalf("unexpected upgrade info %#v", info)
+	}
+
+	// The keys up to the checkpoint are left as they were
+	for _, key := range keys {
+		entry, err := storage.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (entry != nil) != (key <= "c/foo") {
+			t.Fatalf("unexpected entry for key %s after resuming the upgrade: %#v", key, entry)
+		}
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "data/g",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() || resp.Data["data"].(map[string]interface{})["bar"] != "g" {
+		t.Fatalf("err:%s resp:%#v\n", err, resp)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// versionChunkSize is the number of versions of a delete, undelete or destroy
+// request changed while holding the lock of the key once.
+const versionChunkSize = 100
+
+// defaultMaxVersionsPerRequest is the largest number of versions a delete,
+// undelete or destroy request can name if the config does not set
+// max_versions_per_request.
+const defaultMaxVersionsPerRequest = 1000
+
+// maxVersionsPerRequest returns the largest number of versions a delete,
+// undelete or destroy request can name.
+func (c *Configuration) maxVersionsPerRequest() uint32 {
+	if c.MaxVersionsPerRequest > 0 {
+		return c.MaxVersionsPerRequest
+	}
+	return defaultMaxVersionsPerRequest
+}
+
+// processVersionChunks calls fn with the versions of a delete, undelete or
+// destroy request in chunks of versionChunkSize. fn takes the lock of the key
+// itself, so that the lock is released between chunks and a request naming
+// many versions does not block the key for its whole duration. latest is
+// passed to the first chunk only.
+//
+// Requests naming more versions than the max_versions_per_request of the
+// config are rejected. If a chunk after the first fails, or the request is
+// canceled between chunks, the error names the versions of the chunks that
+// completed. Requests spanning several chunks return the number of chunks.
+func processVersionChunks(ctx context.Context, config *Configuration, versions []int, latest bool, fn func(chunk []int, latest bool) (*logical.Response, error)) (*logical.Response, error) {
+	requested := len(versions)
+	if latest {
+		requested++
+	}
+	if max := config.maxVersionsPerRequest(); requested > int(max) {
+		return logical.ErrorResponse("cannot process %d versions in a single request, the limit is %d", requested, max), logical.ErrInvalidRequest
+	}
+
+	var chunks [][]int
+	for len(versions) > versionChunkSize {
+		chunks = append(chunks, versions[:versionChunkSize:versionChunkSize])
+		versions = versions[versionChunkSize:]
+	}
+	chunks = append(chunks, versions)
+
+	if len(chunks) == 1 {
+		return fn(chunks[0], latest)
+	}
+
+	for i, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", chunksCompleted(chunks[:i], len(chunks)), err)
+		}
+
+		resp, err := fn(chunk, latest && i == 0)
+		if i == 0 {
+			if err != nil || (resp != nil && resp.IsError()) {
+				return resp, err
+			}
+			continue
+		}
+
+		switch {
+		case resp != nil && resp.IsError():
+			resp.Data["error"] = fmt.Sprintf("%s; %s", resp.Error(), chunksCompleted(chunks[:i], len(chunks)))
+			return resp, err
+		case err != nil:
+			return nil, fmt.Errorf("%s: %w", chunksCompleted(chunks[:i], len(chunks)), err)
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"chunks": len(chunks),
+		},
+	}, nil
+}
+
+// chunksCompleted describes the chunks of a request that completed before it
+// was interrupted.
+func chunksCompleted(completed [][]int, total int) string {
+	var versions []int
+	for _, chunk := range completed {
+		versions = append(versions, chunk...)
+	}
+	return fmt.Sprintf("%d of %d chunks completed, processing versions %v", len(completed), total, versions)
+}
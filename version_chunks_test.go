@@ -0,0 +1,151 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package kv
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestProcessVersionChunks(t *testing.T) {
+	ctx := context.Background()
+	versions := make([]int, 250)
+	for i := range versions {
+		versions[i] = i + 1
+	}
+
+	var sizes []int
+	var latestChunks int
+	resp, err := processVersionChunks(ctx, &Configuration{}, versions, true, func(chunk []int, latest bool) (*logical.Response, error) {
+		if latest {
+			latestChunks++
+			chunk = append(chunk, 0)
+		}
+		sizes = append(sizes, len(chunk))
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || resp.Data["chunks"] != 3 {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+	if latestChunks != 1 || len(sizes) != 3 || sizes[0] != 101 || sizes[1] != 100 || sizes[2] != 50 {
+		t.Fatalf("unexpected chunks, sizes: %v, latest: %d", sizes, latestChunks)
+	}
+
+	// Appending latest to the first chunk must not overwrite the second
+	if versions[100] != 101 {
+		t.Fatalf("expected the versions to be unchanged, got %d", versions[100])
+	}
+
+	// A failure after the first chunk names the versions completed
+	failure := errors.New("storage failure")
+	calls := 0
+	_, err = processVersionChunks(ctx, &Configuration{}, versions, false, func(chunk []int, latest bool) (*logical.Response, error) {
+		calls++
+		if calls == 2 {
+			return nil, failure
+		}
+		return nil, nil
+	})
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), "1 of 3 chunks completed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected processing to stop after the failed chunk, got %d calls", calls)
+	}
+
+	// Requests over the limit are rejected without calling fn
+	resp, err = processVersionChunks(ctx, &Configuration{MaxVersionsPerRequest: 250}, versions, true, func(chunk []int, latest bool) (*logical.Response, error) {
+		t.Fatal("unexpected call")
+		return nil, nil
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the request to be rejected, err: %v, resp: %#v", err, resp)
+	}
+}
+
+func TestVersionedKV_Delete_Chunked(t *testing.T) {
+	b, storage := getBackend(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		req := &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "data/foo",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"data": map[string]interface{}{"bar": strconv.Itoa(i)},
+			},
+		}
+		resp, err := b.HandleRequest(ctx, req)
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("data CreateOperation request failed, err: %s, resp %#v", err, resp)
+		}
+	}
+
+	// Name versions 1 to 150, of which only the first three exist
+	versions := make([]string, 150)
+	for i := range versions {
+		versions[i] = strconv.Itoa(i + 1)
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "delete/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"versions": versions,
+		},
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("delete request failed, err: %s, resp %#v", err, resp)
+	}
+	if resp.Data["chunks"] != 2 {
+		t.Fatalf("unexpected response: %#v", resp.Data)
+	}
+
+	meta, err := b.(*versionedKVBackend).getKeyMetadata(ctx, storage, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for verNum, vm := range meta.Versions {
+		if vm.DeletionTime == nil {
+			t.Fatalf("expected version %d to be deleted", verNum)
+		}
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"max_versions_per_request": 100,
+		},
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("config request failed, err: %s, resp %#v", err, resp)
+	}
+
+	req = &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "destroy/foo",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"versions": versions,
+		},
+	}
+	resp, err = b.HandleRequest(ctx, req)
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected destroy over the limit to be rejected, err: %s, resp %#v", err, resp)
+	}
+}